@@ -0,0 +1,59 @@
+package apidocs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSpec(t *testing.T) {
+	var spec struct {
+		OpenAPI string                                `json:"openapi"`
+		Servers []struct{ URL string }                `json:"servers"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(Spec, &spec); err != nil {
+		t.Fatalf("openapi.json: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") || len(spec.Servers) == 0 || spec.Servers[0].URL != "/api/v1" {
+		t.Errorf("openapi %q, servers %v, want OpenAPI 3 served from /api/v1", spec.OpenAPI, spec.Servers)
+	}
+	for path, ops := range spec.Paths {
+		for method, raw := range ops {
+			// Path items also carry shared parameters and server overrides.
+			if method == "parameters" || method == "servers" {
+				continue
+			}
+			var op struct {
+				Responses map[string]any `json:"responses"`
+			}
+			if err := json.Unmarshal(raw, &op); err != nil || len(op.Responses) == 0 {
+				t.Errorf("%s %s: no responses (%v)", method, path, err)
+			}
+		}
+	}
+}
+
+func TestServe(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/openapi.json", ServeSpec)
+	r.GET("/docs", ServeDocs)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" || w.Body.Len() != len(Spec) {
+		t.Errorf("GET /openapi.json = %d %s, %d bytes", w.Code, w.Header().Get("Content-Type"), w.Body.Len())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") ||
+		!strings.Contains(w.Body.String(), `url: "openapi.json"`) {
+		t.Errorf("GET /docs = %d %s, want the Swagger UI page loading openapi.json", w.Code, w.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,233 @@
+// Package backup dumps the bookings and the tables around them to gzipped JSON files in a
+// directory, keeping the newest few. It is a copy the venue holds itself, on top of whatever
+// the database host keeps.
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Tables are what a backup holds: the bookings and everything they refer to or the venue is
+// set up with. Idempotency keys and summary sends are bookkeeping, safe to lose.
+var Tables = []string{
+	"bookings", "booking_addons", "payments", "packages", "rooms", "addons",
+	"blackout_dates", "waitlist", "settings", "audit_log",
+}
+
+// Backup files are named after the time they were taken, in UTC, so they sort oldest first.
+const (
+	filePrefix = "miniparty-"
+	fileSuffix = ".json.gz"
+	fileStamp  = "20060102T150405Z"
+)
+
+// Result is what one backup wrote: its file name in the directory and the rows in each table.
+type Result struct {
+	File      string         `json:"file"`
+	CreatedAt time.Time      `json:"created_at"`
+	Rows      map[string]int `json:"rows"`
+}
+
+// Dir is a directory of backups, of which the newest Keep are kept.
+type Dir struct {
+	Path string
+	Keep int
+
+	// mu keeps backups one at a time; last is when the newest one was taken.
+	mu   sync.Mutex
+	last atomic.Pointer[time.Time]
+}
+
+// Open returns the backups in path, creating the directory if need be, and picks up when the
+// newest backup already in it was taken.
+func Open(path string, keep int) (*Dir, error) {
+	if err := os.MkdirAll(path, 0o700); err != nil {
+		return nil, err
+	}
+	d := &Dir{Path: path, Keep: keep}
+	files, err := d.files()
+	if err != nil {
+		return nil, err
+	}
+	if len(files) > 0 {
+		newest := files[len(files)-1]
+		if at, err := time.Parse(fileStamp, strings.TrimSuffix(strings.TrimPrefix(newest, filePrefix), fileSuffix)); err == nil {
+			d.last.Store(&at)
+		}
+	}
+	return d, nil
+}
+
+// Last is when the newest backup was taken, or the zero time if there is none.
+func (d *Dir) Last() time.Time {
+	if at := d.last.Load(); at != nil {
+		return *at
+	}
+	return time.Time{}
+}
+
+// Run backs up Tables from tx as of at, then deletes all but the newest Keep backups. The
+// tables are read in one read-only transaction, so the backup is consistent across them,
+// and streamed to the file a row at a time. The file only appears under its name once it
+// is complete: a backup that fails part way leaves nothing behind.
+func (d *Dir) Run(ctx context.Context, tx *gorm.DB, at time.Time) (Result, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	at = at.UTC().Truncate(time.Second)
+	result := Result{File: filePrefix + at.Format(fileStamp) + fileSuffix, CreatedAt: at, Rows: map[string]int{}}
+
+	f, err := os.CreateTemp(d.Path, ".backup-*")
+	if err != nil {
+		return Result{}, err
+	}
+	defer os.Remove(f.Name())
+
+	opts := &sql.TxOptions{ReadOnly: true}
+	if tx.Dialector.Name() == "postgres" {
+		opts.Isolation = sql.LevelRepeatableRead
+	}
+	err = tx.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return write(f, tx, at, result.Rows)
+	}, opts)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	if err := os.Rename(f.Name(), filepath.Join(d.Path, result.File)); err != nil {
+		return Result{}, err
+	}
+	d.last.Store(&at)
+
+	if err := d.prune(); err != nil {
+		// The backup itself is safe; the old ones go next time.
+		slog.Warn("failed to delete old backups", "dir", d.Path, "error", err)
+	}
+	return result, nil
+}
+
+// write streams the backup to f as one JSON object:
+//
+//	{"created_at": "...", "tables": {"bookings": [{...}, ...], ...}}
+//
+// with a row per line, counting the rows of each table into rows.
+func write(f *os.File, tx *gorm.DB, at time.Time, rows map[string]int) error {
+	buf := bufio.NewWriter(f)
+	gz := gzip.NewWriter(buf)
+	enc := json.NewEncoder(gz)
+
+	stamp, _ := json.Marshal(at)
+	fmt.Fprintf(gz, "{\"created_at\":%s,\"tables\":{", stamp)
+	for i, table := range Tables {
+		if i > 0 {
+			gz.Write([]byte(","))
+		}
+		fmt.Fprintf(gz, "\n%q:[", table)
+		n, err := writeTable(gz, enc, tx, table)
+		if err != nil {
+			return fmt.Errorf("backing up %s: %w", table, err)
+		}
+		rows[table] = n
+		gz.Write([]byte("]"))
+	}
+	if _, err := gz.Write([]byte("}}\n")); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+// writeTable writes every row of table as a JSON object keyed by column, separated by commas,
+// and returns how many there were.
+func writeTable(gz *gzip.Writer, enc *json.Encoder, tx *gorm.DB, table string) (int, error) {
+	rows, err := tx.Table(table).Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	values := make([]any, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	n := 0
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, err
+		}
+		row := make(map[string]any, len(columns))
+		for i, column := range columns {
+			// Text can come back as bytes, which JSON would otherwise encode as base64.
+			if b, ok := values[i].([]byte); ok {
+				row[column] = string(b)
+			} else {
+				row[column] = values[i]
+			}
+		}
+		sep := "\n"
+		if n > 0 {
+			sep = ",\n"
+		}
+		gz.Write([]byte(sep))
+		// Encode ends the row with a newline of its own; the comma goes on the next line.
+		if err := enc.Encode(row); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
+
+// files lists the backups in the directory, oldest first.
+func (d *Dir) files() ([]string, error) {
+	entries, err := os.ReadDir(d.Path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if name := e.Name(); !e.IsDir() && strings.HasPrefix(name, filePrefix) && strings.HasSuffix(name, fileSuffix) {
+			files = append(files, name)
+		}
+	}
+	slices.Sort(files)
+	return files, nil
+}
+
+// prune deletes all but the newest Keep backups.
+func (d *Dir) prune() error {
+	files, err := d.files()
+	if err != nil || len(files) <= d.Keep {
+		return err
+	}
+	for _, name := range files[:len(files)-d.Keep] {
+		if err := os.Remove(filepath.Join(d.Path, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"miniparty-backend/db"
+	"miniparty-backend/db/dbtest"
+	"miniparty-backend/models"
+)
+
+// read decodes the backup file name in dir.
+func read(t *testing.T, dir, name string) (backup struct {
+	CreatedAt time.Time                   `json:"created_at"`
+	Tables    map[string][]map[string]any `json:"tables"`
+}) {
+	t.Helper()
+	f, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.NewDecoder(gz).Decode(&backup); err != nil {
+		t.Fatal(err)
+	}
+	return backup
+}
+
+func TestRun(t *testing.T) {
+	dbtest.Init(t)
+	room := uint(1)
+	for _, name := range []string{"Ann", "Bob"} {
+		b := models.Booking{
+			Name: name, Email: name + "@example.com", Phone: "+14155550100", Date: "2026-07-10", Time: "14:00",
+			Duration: 2, Guests: 4, Status: models.StatusConfirmed, RoomID: &room, Reference: "MP-" + name, CancelToken: name,
+		}
+		if err := db.DB.Create(&b).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir := filepath.Join(t.TempDir(), "backups")
+	d, err := Open(dir, 2)
+	if err != nil || !d.Last().IsZero() {
+		t.Fatalf("Open = %v, last %v", err, d.Last())
+	}
+	at := time.Date(2026, 7, 1, 9, 30, 15, 500, time.UTC)
+	result, err := d.Run(context.Background(), db.DB, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.File != "miniparty-20260701T093015Z.json.gz" || result.Rows["bookings"] != 2 || len(result.Rows) != len(Tables) {
+		t.Errorf("result %+v", result)
+	}
+	backup := read(t, dir, result.File)
+	if !backup.CreatedAt.Equal(at.Truncate(time.Second)) || len(backup.Tables) != len(Tables) {
+		t.Errorf("backup of %v has tables %v", backup.CreatedAt, len(backup.Tables))
+	}
+	if got := backup.Tables["bookings"]; len(got) != 2 || got[0]["name"] != "Ann" || got[1]["reference"] != "MP-Bob" {
+		t.Errorf("bookings %v", got)
+	}
+
+	// Only the newest two are kept, and a reopened directory knows when the last was taken.
+	for i := 1; i <= 2; i++ {
+		if _, err := d.Run(context.Background(), db.DB, at.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Name() != "miniparty-20260701T103015Z.json.gz" {
+		t.Errorf("%d files left, the oldest %s; want the newest two", len(entries), entries[0].Name())
+	}
+	reopened, err := Open(dir, 2)
+	if err != nil || !reopened.Last().Equal(at.Add(2*time.Hour).Truncate(time.Second)) {
+		t.Errorf("reopened: last %v, %v", reopened.Last(), err)
+	}
+}
+
+func TestRunFailureLeavesNothing(t *testing.T) {
+	dbtest.Init(t)
+	dir := t.TempDir()
+	d, err := Open(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := Tables
+	Tables = append(append([]string{}, old...), "no_such_table")
+	t.Cleanup(func() { Tables = old })
+
+	if _, err := d.Run(context.Background(), db.DB, time.Now()); err == nil {
+		t.Fatal("backed up a table that doesn't exist")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 || !d.Last().IsZero() {
+		t.Errorf("failed backup left %d files, last %v", len(entries), d.Last())
+	}
+}
@@ -0,0 +1,74 @@
+// Package calendar keeps confirmed bookings on the owner's calendar. Handlers depend on the
+// Provider interface rather than on Google Calendar directly.
+package calendar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// ErrNotFound is returned by Update when the event no longer exists, e.g. because someone
+// deleted it from the calendar by hand.
+var ErrNotFound = errors.New("calendar event not found")
+
+// Event is a booking as it appears on the calendar. It has no attendees: it is for the
+// venue's eyes only, and the customer is never invited.
+type Event struct {
+	BookingID   int64
+	Summary     string
+	Description string
+	Start       time.Time
+	End         time.Time
+}
+
+// Listed is an event Provider.List found, with the booking it was made for.
+type Listed struct {
+	ID        string
+	BookingID int64
+}
+
+// Provider puts booking events on one calendar.
+type Provider interface {
+	// Create adds an event and returns its ID.
+	Create(ctx context.Context, e Event) (string, error)
+	// Update replaces the event with ID id, or returns ErrNotFound if it is gone.
+	Update(ctx context.Context, id string, e Event) error
+	// Delete removes the event with ID id. Deleting an event that is already gone succeeds.
+	Delete(ctx context.Context, id string) error
+	// List returns every event Create has made on the calendar.
+	List(ctx context.Context) ([]Listed, error)
+}
+
+// FromEnv returns a Google Calendar provider for GOOGLE_CALENDAR_ID, authenticated as the
+// service account in GOOGLE_CREDENTIALS_FILE (a path) or GOOGLE_CREDENTIALS_JSON (the key
+// file's contents). It returns nil when GOOGLE_CALENDAR_ID is unset, and an error when the
+// credentials are missing or unreadable.
+func FromEnv() (Provider, error) {
+	calendarID := os.Getenv("GOOGLE_CALENDAR_ID")
+	if calendarID == "" {
+		return nil, nil
+	}
+	key := []byte(os.Getenv("GOOGLE_CREDENTIALS_JSON"))
+	if path := os.Getenv("GOOGLE_CREDENTIALS_FILE"); len(key) == 0 && path != "" {
+		var err error
+		if key, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("GOOGLE_CREDENTIALS_FILE: %w", err)
+		}
+	}
+	if len(key) == 0 {
+		return nil, errors.New("GOOGLE_CALENDAR_ID is set but neither GOOGLE_CREDENTIALS_FILE nor GOOGLE_CREDENTIALS_JSON is")
+	}
+	account, err := ParseServiceAccount(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Google{
+		CalendarID: calendarID,
+		Account:    account,
+		Client:     &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
@@ -0,0 +1,284 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// googleAPI is the Google Calendar REST endpoint. Google.BaseURL overrides it.
+const googleAPI = "https://www.googleapis.com"
+
+// googleScope lets the service account manage events on calendars shared with it, and nothing else.
+const googleScope = "https://www.googleapis.com/auth/calendar.events"
+
+// Every event Create makes carries private extended properties marking it as MiniParty's
+// and naming its booking. List filters on the mark, so events on the calendar for other
+// reasons are never touched.
+const (
+	markProperty    = "miniparty"
+	markValue       = "booking"
+	bookingProperty = "miniparty_booking_id"
+)
+
+// ServiceAccount is the part of a Google service account key file used to get access tokens.
+type ServiceAccount struct {
+	Email    string
+	Key      *rsa.PrivateKey
+	TokenURL string
+}
+
+// ParseServiceAccount reads a service account's JSON key file.
+func ParseServiceAccount(keyFile []byte) (ServiceAccount, error) {
+	var f struct {
+		Type        string `json:"type"`
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(keyFile, &f); err != nil {
+		return ServiceAccount{}, fmt.Errorf("google credentials: %w", err)
+	}
+	if f.Type != "service_account" || f.ClientEmail == "" || f.PrivateKey == "" {
+		return ServiceAccount{}, errors.New("google credentials: not a service account key file")
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(f.PrivateKey))
+	if err != nil {
+		return ServiceAccount{}, fmt.Errorf("google credentials: %w", err)
+	}
+	if f.TokenURI == "" {
+		f.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	return ServiceAccount{Email: f.ClientEmail, Key: key, TokenURL: f.TokenURI}, nil
+}
+
+// Google keeps events on a Google calendar through the Calendar API, as a service account
+// the calendar has been shared with ("Make changes to events").
+type Google struct {
+	CalendarID string
+	Account    ServiceAccount
+	BaseURL    string
+	Client     *http.Client
+
+	// The access token is reused until shortly before it expires.
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+type googleTime struct {
+	DateTime string `json:"dateTime"`
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+type googleEvent struct {
+	ID                 string     `json:"id,omitempty"`
+	Summary            string     `json:"summary"`
+	Description        string     `json:"description"`
+	Start              googleTime `json:"start"`
+	End                googleTime `json:"end"`
+	ExtendedProperties struct {
+		Private map[string]string `json:"private"`
+	} `json:"extendedProperties"`
+}
+
+type googleError struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (g *Google) Create(ctx context.Context, e Event) (string, error) {
+	var created googleEvent
+	if err := g.do(ctx, http.MethodPost, g.eventsPath(""), nil, toGoogle(e), &created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+func (g *Google) Update(ctx context.Context, id string, e Event) error {
+	return g.do(ctx, http.MethodPut, g.eventsPath(id), nil, toGoogle(e), nil)
+}
+
+func (g *Google) Delete(ctx context.Context, id string) error {
+	err := g.do(ctx, http.MethodDelete, g.eventsPath(id), nil, nil, nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (g *Google) List(ctx context.Context) ([]Listed, error) {
+	var listed []Listed
+	query := url.Values{"privateExtendedProperty": {markProperty + "=" + markValue}, "maxResults": {"2500"}}
+	for {
+		var page struct {
+			Items         []googleEvent `json:"items"`
+			NextPageToken string        `json:"nextPageToken"`
+		}
+		if err := g.do(ctx, http.MethodGet, g.eventsPath(""), query, nil, &page); err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			id, err := strconv.ParseInt(item.ExtendedProperties.Private[bookingProperty], 10, 64)
+			if err == nil {
+				listed = append(listed, Listed{ID: item.ID, BookingID: id})
+			}
+		}
+		if page.NextPageToken == "" {
+			return listed, nil
+		}
+		query.Set("pageToken", page.NextPageToken)
+	}
+}
+
+func toGoogle(e Event) googleEvent {
+	ge := googleEvent{
+		Summary:     e.Summary,
+		Description: e.Description,
+		Start:       googleTime{DateTime: e.Start.Format(time.RFC3339)},
+		End:         googleTime{DateTime: e.End.Format(time.RFC3339)},
+	}
+	// The offset in the time is enough on its own; the zone name makes the calendar show the
+	// venue's timezone. "Local" isn't a name Google knows, so it is left out.
+	if zone := e.Start.Location().String(); zone != "Local" {
+		ge.Start.TimeZone, ge.End.TimeZone = zone, zone
+	}
+	ge.ExtendedProperties.Private = map[string]string{
+		markProperty:    markValue,
+		bookingProperty: strconv.FormatInt(e.BookingID, 10),
+	}
+	return ge
+}
+
+func (g *Google) eventsPath(id string) string {
+	path := "/calendar/v3/calendars/" + url.PathEscape(g.CalendarID) + "/events"
+	if id != "" {
+		path += "/" + url.PathEscape(id)
+	}
+	return path
+}
+
+// do sends an authenticated request to the Calendar API and decodes the response into out.
+// A 404 or 410 is ErrNotFound.
+func (g *Google) do(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	token, err := g.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	var body io.Reader
+	if in != nil {
+		raw, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(raw)
+	}
+	base := g.BaseURL
+	if base == "" {
+		base = googleAPI
+	}
+	endpoint := base + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := g.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return ErrNotFound
+	}
+	if resp.StatusCode >= 300 {
+		var e googleError
+		if json.NewDecoder(resp.Body).Decode(&e) == nil && e.Error.Message != "" {
+			return fmt.Errorf("google calendar %s %s: %s (%d)", method, path, e.Error.Message, resp.StatusCode)
+		}
+		return fmt.Errorf("google calendar %s %s: status %d", method, path, resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// accessToken returns a current OAuth access token, exchanging a JWT signed with the service
+// account's key for a new one when the last has (nearly) expired.
+func (g *Google) accessToken(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.token != "" && time.Now().Before(g.expires) {
+		return g.token, nil
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   g.Account.Email,
+		"scope": googleScope,
+		"aud":   g.Account.TokenURL,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(g.Account.Key)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.Account.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := g.client().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var t struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int    `json:"expires_in"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil || resp.StatusCode >= 300 || t.AccessToken == "" {
+		if t.ErrorDescription != "" {
+			return "", fmt.Errorf("google token: %s (%d)", t.ErrorDescription, resp.StatusCode)
+		}
+		return "", fmt.Errorf("google token: status %d", resp.StatusCode)
+	}
+	// A minute's margin so a token never expires between here and the request that uses it.
+	g.token, g.expires = t.AccessToken, now.Add(time.Duration(t.ExpiresIn)*time.Second-time.Minute)
+	return g.token, nil
+}
+
+func (g *Google) client() *http.Client {
+	if g.Client == nil {
+		return http.DefaultClient
+	}
+	return g.Client
+}
@@ -0,0 +1,201 @@
+package calendar
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// fakeGoogle is the token endpoint and the Calendar API, keeping events in a map.
+type fakeGoogle struct {
+	t      *testing.T
+	key    *rsa.PrivateKey
+	tokens int
+	events map[string]googleEvent
+	lastID int
+}
+
+func (f *fakeGoogle) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/token" {
+		f.token(w, r)
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer access-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	const prefix = "/calendar/v3/calendars/party@group.calendar.google.com/events"
+	id, ok := strings.CutPrefix(r.URL.Path, prefix)
+	if !ok {
+		f.t.Errorf("request to %s", r.URL.Path)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	id = strings.TrimPrefix(id, "/")
+	var in googleEvent
+	if r.Body != nil {
+		_ = json.NewDecoder(r.Body).Decode(&in)
+	}
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		if r.URL.Query().Get("privateExtendedProperty") != "miniparty=booking" {
+			f.t.Errorf("list doesn't filter on the mark: %s", r.URL.RawQuery)
+		}
+		// One event to a page, to exercise the paging.
+		page := struct {
+			Items         []googleEvent `json:"items"`
+			NextPageToken string        `json:"nextPageToken,omitempty"`
+		}{Items: []googleEvent{}}
+		ids := []string{}
+		for id := range f.events {
+			ids = append(ids, id)
+		}
+		if len(ids) > 0 {
+			from := r.URL.Query().Get("pageToken")
+			for _, id := range ids {
+				if id > from && (len(page.Items) == 0 || id < page.Items[0].ID) {
+					page.Items = []googleEvent{f.events[id]}
+				}
+			}
+			if len(page.Items) == 1 {
+				page.NextPageToken = page.Items[0].ID
+			}
+		}
+		json.NewEncoder(w).Encode(page)
+	case r.Method == http.MethodPost && id == "":
+		f.lastID++
+		in.ID = fmt.Sprintf("evt%d", f.lastID)
+		f.events[in.ID] = in
+		json.NewEncoder(w).Encode(in)
+	case r.Method == http.MethodPut:
+		if _, ok := f.events[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		in.ID = id
+		f.events[id] = in
+		json.NewEncoder(w).Encode(in)
+	case r.Method == http.MethodDelete:
+		if _, ok := f.events[id]; !ok {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		delete(f.events, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeGoogle) token(w http.ResponseWriter, r *http.Request) {
+	f.tokens++
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(r.FormValue("assertion"), claims, func(*jwt.Token) (any, error) { return &f.key.PublicKey, nil })
+	if err != nil || r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" ||
+		claims["iss"] != "sync@party.iam.gserviceaccount.com" || claims["scope"] != googleScope {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error_description": "bad assertion"})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{"access_token": "access-token", "expires_in": 3600})
+}
+
+// keyFile is a service account key file for key, its tokens issued at tokenURL.
+func keyFile(t *testing.T, key *rsa.PrivateKey, tokenURL string) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "sync@party.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    tokenURL,
+	})
+	return raw
+}
+
+func TestParseServiceAccount(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := ParseServiceAccount(keyFile(t, key, ""))
+	if err != nil || account.Email != "sync@party.iam.gserviceaccount.com" || account.TokenURL != "https://oauth2.googleapis.com/token" {
+		t.Errorf("account %+v, %v", account, err)
+	}
+	for _, bad := range []string{`{`, `{"type":"authorized_user","client_email":"a","private_key":"b"}`, `{"type":"service_account","client_email":"a","private_key":"not a key"}`} {
+		if _, err := ParseServiceAccount([]byte(bad)); err == nil {
+			t.Errorf("parsed %s", bad)
+		}
+	}
+}
+
+func TestGoogle(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeGoogle{t: t, key: key, events: map[string]googleEvent{}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	account, err := ParseServiceAccount(keyFile(t, key, srv.URL+"/token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &Google{CalendarID: "party@group.calendar.google.com", Account: account, BaseURL: srv.URL, Client: srv.Client()}
+	ctx := context.Background()
+
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 7, 10, 14, 0, 0, 0, kolkata)
+	first, err := g.Create(ctx, Event{BookingID: 7, Summary: "Party", Start: start, End: start.Add(2 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := fake.events[first]
+	if got.Start.DateTime != "2026-07-10T14:00:00+05:30" || got.Start.TimeZone != "Asia/Kolkata" || got.ExtendedProperties.Private[bookingProperty] != "7" {
+		t.Errorf("created %+v", got)
+	}
+	second, err := g.Create(ctx, Event{BookingID: 8, Summary: "Party", Start: start, End: start.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.Update(ctx, first, Event{BookingID: 7, Summary: "Moved", Start: start, End: start.Add(3 * time.Hour)}); err != nil || fake.events[first].Summary != "Moved" {
+		t.Errorf("update: %v, event %+v", err, fake.events[first])
+	}
+	if err := g.Update(ctx, "missing", Event{BookingID: 9, Start: start, End: start}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("update missing: err = %v, want ErrNotFound", err)
+	}
+
+	listed, err := g.List(ctx)
+	if err != nil || len(listed) != 2 || listed[0] != (Listed{ID: first, BookingID: 7}) || listed[1] != (Listed{ID: second, BookingID: 8}) {
+		t.Errorf("list = %+v, %v", listed, err)
+	}
+
+	if err := g.Delete(ctx, second); err != nil || len(fake.events) != 1 {
+		t.Errorf("delete: %v, %d events left", err, len(fake.events))
+	}
+	if err := g.Delete(ctx, second); err != nil {
+		t.Errorf("delete again: %v, want success", err)
+	}
+	if fake.tokens != 1 {
+		t.Errorf("fetched %d access tokens, want 1 reused", fake.tokens)
+	}
+}
@@ -0,0 +1,53 @@
+// Package captcha checks that a booking form was filled in by a person. Handlers depend on
+// the Verifier interface rather than on a particular provider.
+package captcha
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Provider siteverify endpoints. Turnstile and reCAPTCHA take the same request and answer
+// in the same shape.
+const (
+	TurnstileURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+	RecaptchaURL = "https://www.google.com/recaptcha/api/siteverify"
+)
+
+var (
+	// ErrRejected means the provider looked at the token and said no: it is forged,
+	// expired or already used.
+	ErrRejected = errors.New("captcha token rejected")
+
+	// ErrUnavailable means the provider couldn't give an answer, e.g. it timed out.
+	ErrUnavailable = errors.New("captcha provider unavailable")
+)
+
+// Verifier checks captcha tokens.
+type Verifier interface {
+	// Verify checks token, solved by the client at remoteIP. It returns nil if the token
+	// is good, an error wrapping ErrRejected if it isn't, and one wrapping ErrUnavailable
+	// if the provider couldn't be asked.
+	Verify(ctx context.Context, token, remoteIP string) error
+}
+
+// FromEnv returns a verifier for Cloudflare Turnstile when TURNSTILE_SECRET is set, or
+// else Google reCAPTCHA when RECAPTCHA_SECRET is, or nil when neither is. Each check
+// gives up after CAPTCHA_TIMEOUT (default 3s).
+func FromEnv() Verifier {
+	timeout := 3 * time.Second
+	if d, err := time.ParseDuration(os.Getenv("CAPTCHA_TIMEOUT")); err == nil && d > 0 {
+		timeout = d
+	}
+	client := &http.Client{Timeout: timeout}
+	if secret := os.Getenv("TURNSTILE_SECRET"); secret != "" {
+		return &SiteVerify{URL: TurnstileURL, Secret: secret, Client: client}
+	}
+	if secret := os.Getenv("RECAPTCHA_SECRET"); secret != "" {
+		return &SiteVerify{URL: RecaptchaURL, Secret: secret, Client: client}
+	}
+	return nil
+}
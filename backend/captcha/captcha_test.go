@@ -0,0 +1,71 @@
+package captcha
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSiteVerify(t *testing.T) {
+	var form map[string]string
+	answer := func(status int, body string) *SiteVerify {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.ParseForm()
+			form = map[string]string{"secret": r.PostFormValue("secret"), "response": r.PostFormValue("response"), "remoteip": r.PostFormValue("remoteip")}
+			w.WriteHeader(status)
+			fmt.Fprint(w, body)
+		}))
+		t.Cleanup(srv.Close)
+		return &SiteVerify{URL: srv.URL, Secret: "site-secret", Client: srv.Client()}
+	}
+
+	if err := answer(http.StatusOK, `{"success": true}`).Verify(context.Background(), "token", "192.0.2.1"); err != nil {
+		t.Errorf("good token: %v", err)
+	}
+	if form["secret"] != "site-secret" || form["response"] != "token" || form["remoteip"] != "192.0.2.1" {
+		t.Errorf("posted %v, want the secret, token and client IP", form)
+	}
+
+	tests := []struct {
+		status int
+		body   string
+		err    error
+	}{
+		{http.StatusOK, `{"success": false, "error-codes": ["invalid-input-response"]}`, ErrRejected},
+		{http.StatusOK, `{"success": false, "error-codes": ["timeout-or-duplicate"]}`, ErrRejected},
+		{http.StatusOK, `{"success": false, "error-codes": ["invalid-input-secret"]}`, ErrUnavailable},
+		{http.StatusOK, `{"success": false, "error-codes": ["internal-error"]}`, ErrUnavailable},
+		{http.StatusOK, `not json`, ErrUnavailable},
+		{http.StatusBadGateway, ``, ErrUnavailable},
+	}
+	for _, tt := range tests {
+		if err := answer(tt.status, tt.body).Verify(context.Background(), "token", ""); !errors.Is(err, tt.err) {
+			t.Errorf("%d %s: err = %v, want %v", tt.status, tt.body, err, tt.err)
+		}
+	}
+
+	down := &SiteVerify{URL: "http://127.0.0.1:1", Secret: "site-secret", Client: http.DefaultClient}
+	if err := down.Verify(context.Background(), "token", ""); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("unreachable provider: err = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("TURNSTILE_SECRET", "")
+	t.Setenv("RECAPTCHA_SECRET", "")
+	if v := FromEnv(); v != nil {
+		t.Errorf("no secrets: %v, want no verifier", v)
+	}
+	t.Setenv("RECAPTCHA_SECRET", "recaptcha")
+	if v, ok := FromEnv().(*SiteVerify); !ok || v.URL != RecaptchaURL || v.Secret != "recaptcha" {
+		t.Errorf("RECAPTCHA_SECRET: %+v", v)
+	}
+	t.Setenv("TURNSTILE_SECRET", "turnstile")
+	t.Setenv("CAPTCHA_TIMEOUT", "500ms")
+	if v, ok := FromEnv().(*SiteVerify); !ok || v.URL != TurnstileURL || v.Secret != "turnstile" || v.Client.Timeout.String() != "500ms" {
+		t.Errorf("TURNSTILE_SECRET takes precedence: %+v", v)
+	}
+}
@@ -0,0 +1,58 @@
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SiteVerify checks tokens with a provider's siteverify endpoint over HTTP.
+type SiteVerify struct {
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+type siteVerifyResponse struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+func (s *SiteVerify) Verify(ctx context.Context, token, remoteIP string) error {
+	form := url.Values{"secret": {s.Secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: siteverify returned %s", ErrUnavailable, resp.Status)
+	}
+	var result siteVerifyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("%w: decode siteverify response: %v", ErrUnavailable, err)
+	}
+	if !result.Success {
+		codes := strings.Join(result.ErrorCodes, ", ")
+		for _, code := range result.ErrorCodes {
+			// The provider's own fault, or ours for a bad secret; either way not the customer's.
+			if code == "internal-error" || code == "missing-input-secret" || code == "invalid-input-secret" {
+				return fmt.Errorf("%w: %s", ErrUnavailable, codes)
+			}
+		}
+		return fmt.Errorf("%w: %s", ErrRejected, codes)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"miniparty-backend/models"
+)
+
+// client calls the admin API at base with token.
+type client struct {
+	base  string
+	token string
+	http  *http.Client
+}
+
+func newClient(base, token string) *client {
+	return &client{
+		base:  strings.TrimRight(base, "/") + "/api/v1",
+		token: token,
+		http:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// apiError is a response the API answered with an error status.
+type apiError struct {
+	status int
+	body   models.ErrorResponse
+}
+
+func (e *apiError) Error() string {
+	// Servers from before the error codes only send error and errors.
+	msg, fieldErrs := e.body.Message, e.body.Fields
+	if msg == "" {
+		msg, fieldErrs = e.body.Error, e.body.Errors
+	}
+	if len(fieldErrs) > 0 {
+		fields := make([]string, 0, len(fieldErrs))
+		for field := range fieldErrs {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		var lines []string
+		for _, field := range fields {
+			lines = append(lines, fmt.Sprintf("  %s: %s", field, strings.Join(fieldErrs[field], "; ")))
+		}
+		msg = "the request didn't pass validation:\n" + strings.Join(lines, "\n")
+	}
+	if msg == "" {
+		msg = http.StatusText(e.status)
+	}
+	switch {
+	case e.body.Code == models.CodeUnauthorized || e.status == http.StatusUnauthorized:
+		msg = "not authorized (" + msg + "); check -token or MINIPARTY_TOKEN"
+	case e.body.Code == models.CodeForbidden || e.status == http.StatusForbidden:
+		msg = "forbidden (" + msg + "); this needs an admin token, not a viewer one"
+	}
+	if e.body.RequestID != "" {
+		msg += " (request " + e.body.RequestID + ")"
+	}
+	return msg
+}
+
+// do sends a request to path with query and decodes a successful JSON response into out.
+func (c *client) do(method, path string, query url.Values, out any) error {
+	resp, err := c.send(method, path, query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("reading the response: %w", err)
+	}
+	return nil
+}
+
+// send sends a request to path with query. A response with an error status is an *apiError;
+// any other gets returned for the caller to read and close.
+func (c *client) send(method, path string, query url.Values) (*http.Response, error) {
+	endpoint := c.base + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Admin-Token", c.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	apiErr := &apiError{status: resp.StatusCode}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if json.Unmarshal(raw, &apiErr.body) != nil {
+		apiErr.body.Message = strings.TrimSpace(string(raw))
+	}
+	return nil, apiErr
+}
+
+// notFound reports whether err is the API answering 404.
+func notFound(err error) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.status == http.StatusNotFound
+}
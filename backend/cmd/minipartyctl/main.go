@@ -0,0 +1,237 @@
+// Command minipartyctl runs the common admin operations against a running server, so they
+// don't take hand-written curl commands:
+//
+//	minipartyctl bookings list -date 2026-03-15 -status pending
+//	minipartyctl bookings get 42
+//	minipartyctl bookings confirm 42
+//	minipartyctl bookings cancel 42
+//	minipartyctl export -csv bookings.csv
+//
+// The server and admin token come from -url and -token, or MINIPARTY_URL and MINIPARTY_TOKEN.
+// It exits 1 when the server turns a request down and 2 on a usage mistake, so it can be
+// used from scripts.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"miniparty-backend/models"
+)
+
+const usage = `usage: minipartyctl [-url URL] [-token TOKEN] <command>
+
+commands:
+  bookings list [-date YYYY-MM-DD] [-status STATUS] [-page N] [-per-page N] [-json]
+  bookings get [-json] <id>
+  bookings confirm [-json] <id>
+  bookings cancel [-json] <id>
+  export -csv FILE [-status STATUS] [-from YYYY-MM-DD] [-to YYYY-MM-DD]
+`
+
+// errUsage is a mistake in the command line; run has already said what it was.
+var errUsage = errors.New("usage")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr, os.Getenv))
+}
+
+// run runs the command in args and returns the exit code.
+func run(args []string, stdout, stderr io.Writer, getenv func(string) string) int {
+	flags := flag.NewFlagSet("minipartyctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { fmt.Fprint(stderr, usage) }
+	base := flags.String("url", envOr(getenv, "MINIPARTY_URL", "http://localhost:8080"), "server `URL`")
+	token := flags.String("token", getenv("MINIPARTY_TOKEN"), "admin `token`")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *token == "" {
+		fmt.Fprintln(stderr, "minipartyctl: no admin token; pass -token or set MINIPARTY_TOKEN")
+		return 2
+	}
+
+	c := newClient(*base, *token)
+	var err error
+	switch cmd := flags.Args(); {
+	case len(cmd) >= 2 && cmd[0] == "bookings" && cmd[1] == "list":
+		err = listBookings(c, cmd[2:], stdout, stderr)
+	case len(cmd) >= 2 && cmd[0] == "bookings" && cmd[1] == "get":
+		err = bookingCommand(c, "get", cmd[2:], stdout, stderr)
+	case len(cmd) >= 2 && cmd[0] == "bookings" && cmd[1] == "confirm":
+		err = bookingCommand(c, "confirm", cmd[2:], stdout, stderr)
+	case len(cmd) >= 2 && cmd[0] == "bookings" && cmd[1] == "cancel":
+		err = bookingCommand(c, "cancel", cmd[2:], stdout, stderr)
+	case len(cmd) >= 1 && cmd[0] == "export":
+		err = export(c, cmd[1:], stdout, stderr)
+	default:
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	switch {
+	case errors.Is(err, errUsage):
+		return 2
+	case err != nil:
+		fmt.Fprintln(stderr, "minipartyctl:", err)
+		return 1
+	}
+	return 0
+}
+
+func envOr(getenv func(string) string, key, def string) string {
+	if v := getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// subcommand returns a flag set for name that reports mistakes to stderr.
+func subcommand(name string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	return flags
+}
+
+func listBookings(c *client, args []string, stdout, stderr io.Writer) error {
+	flags := subcommand("bookings list", stderr)
+	date := flags.String("date", "", "only bookings on this `date` (YYYY-MM-DD)")
+	status := flags.String("status", "", "only bookings with this `status`: pending, confirmed, cancelled or no_show")
+	page := flags.Int("page", 1, "`page` to show")
+	perPage := flags.Int("per-page", 50, "bookings per `page`, at most 100")
+	asJSON := flags.Bool("json", false, "print the API's JSON instead of a table")
+	if flags.Parse(args) != nil || flags.NArg() > 0 {
+		return errUsage
+	}
+
+	query := url.Values{"page": {strconv.Itoa(*page)}, "per_page": {strconv.Itoa(*perPage)}}
+	if *date != "" {
+		query.Set("from", *date)
+		query.Set("to", *date)
+	}
+	if *status != "" {
+		query.Set("status", *status)
+	}
+	var list models.BookingList
+	if err := c.do(http.MethodGet, "/bookings", query, &list); err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(stdout, list)
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tREFERENCE\tDATE\tTIME\tHOURS\tGUESTS\tSTATUS\tNAME\tPHONE")
+	for _, b := range list.Bookings {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%d\t%s\t%s\t%s\n", b.ID, b.Reference, b.Date, b.Time, b.Duration, b.Guests, b.Status, b.Name, b.Phone)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if shown := len(list.Bookings); int64(shown) < list.Total {
+		fmt.Fprintf(stdout, "\n%d of %d bookings; -page %d for more\n", shown, list.Total, list.Page+1)
+	}
+	return nil
+}
+
+// bookingCommand gets, confirms or cancels the booking whose ID is in args and prints it.
+func bookingCommand(c *client, action string, args []string, stdout, stderr io.Writer) error {
+	flags := subcommand("bookings "+action, stderr)
+	asJSON := flags.Bool("json", false, "print the API's JSON instead of a summary")
+	if flags.Parse(args) != nil {
+		return errUsage
+	}
+	id, err := strconv.ParseInt(flags.Arg(0), 10, 64)
+	if flags.NArg() != 1 || err != nil || id <= 0 {
+		fmt.Fprintf(stderr, "usage: minipartyctl bookings %s [-json] <id>\n", action)
+		return errUsage
+	}
+
+	path := "/bookings/" + strconv.FormatInt(id, 10)
+	method := http.MethodGet
+	if action != "get" {
+		path, method = path+"/"+action, http.MethodPost
+	}
+	var b models.Booking
+	if err := c.do(method, path, nil, &b); err != nil {
+		if notFound(err) {
+			return fmt.Errorf("booking %d not found", id)
+		}
+		return err
+	}
+	if *asJSON {
+		return printJSON(stdout, b)
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ID\t%d\n", b.ID)
+	fmt.Fprintf(w, "Reference\t%s\n", b.Reference)
+	fmt.Fprintf(w, "Status\t%s\n", b.Status)
+	fmt.Fprintf(w, "When\t%s %s, %d hours\n", b.Date, b.Time, b.Duration)
+	fmt.Fprintf(w, "Guests\t%d\n", b.Guests)
+	fmt.Fprintf(w, "Name\t%s\n", b.Name)
+	fmt.Fprintf(w, "Email\t%s\n", b.Email)
+	fmt.Fprintf(w, "Phone\t%s\n", b.Phone)
+	if b.RoomName != "" {
+		fmt.Fprintf(w, "Room\t%s\n", b.RoomName)
+	}
+	if b.Notes != "" {
+		fmt.Fprintf(w, "Notes\t%s\n", b.Notes)
+	}
+	return w.Flush()
+}
+
+// export downloads the CSV export to a file, or to stdout for "-".
+func export(c *client, args []string, stdout, stderr io.Writer) error {
+	flags := subcommand("export", stderr)
+	file := flags.String("csv", "", "write the CSV to this `file`, or - for standard output")
+	status := flags.String("status", "", "only bookings with this `status`")
+	from := flags.String("from", "", "only bookings on or after this `date`")
+	to := flags.String("to", "", "only bookings on or before this `date`")
+	if flags.Parse(args) != nil || flags.NArg() > 0 {
+		return errUsage
+	}
+	if *file == "" {
+		fmt.Fprintln(stderr, "usage: minipartyctl export -csv FILE [-status STATUS] [-from DATE] [-to DATE]")
+		return errUsage
+	}
+
+	query := url.Values{}
+	for key, v := range map[string]string{"status": *status, "from": *from, "to": *to} {
+		if v != "" {
+			query.Set(key, v)
+		}
+	}
+	resp, err := c.send(http.MethodGet, "/bookings/export.csv", query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if *file == "-" {
+		_, err = io.Copy(stdout, resp.Body)
+		return err
+	}
+	out, err := os.Create(*file)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func printJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
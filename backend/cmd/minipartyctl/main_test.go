@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"miniparty-backend/models"
+)
+
+// fakeAPI answers the admin API's routes the way the server would, for token "tok".
+func fakeAPI(t *testing.T) *httptest.Server {
+	booking := models.Booking{
+		ID: 42, Reference: "MP-ABC123", Date: "2026-07-10", Time: "14:00", Duration: 2, Guests: 8,
+		Status: models.StatusPending, Name: "Ann Example", Email: "ann@example.com", Phone: "+14155550100",
+	}
+	fail := func(w http.ResponseWriter, status int, body models.ErrorResponse) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch token := r.Header.Get("X-Admin-Token"); {
+		case token == "viewer" && r.Method == http.MethodPost:
+			fail(w, http.StatusForbidden, models.ErrorResponse{Code: models.CodeForbidden, Message: "Your access does not allow this action"})
+			return
+		case token != "tok" && token != "viewer":
+			fail(w, http.StatusUnauthorized, models.ErrorResponse{Code: models.CodeUnauthorized, Message: "Unauthorized", RequestID: "req-1"})
+			return
+		}
+		q := r.URL.Query()
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v1/bookings":
+			if q.Get("status") == "lost" {
+				fail(w, http.StatusBadRequest, models.ErrorResponse{Code: models.CodeValidation, Message: "Invalid",
+					Fields: map[string][]string{"status": {"must be pending, confirmed, cancelled or no_show"}}})
+				return
+			}
+			if q.Get("from") != "2026-07-10" || q.Get("to") != "2026-07-10" || q.Get("per_page") != "1" {
+				t.Errorf("list query %s", r.URL.RawQuery)
+			}
+			json.NewEncoder(w).Encode(models.BookingList{Bookings: []models.Booking{booking}, Total: 3, Page: 1, PerPage: 1})
+		case "GET /api/v1/bookings/42":
+			json.NewEncoder(w).Encode(booking)
+		case "POST /api/v1/bookings/42/confirm":
+			confirmed := booking
+			confirmed.Status = models.StatusConfirmed
+			json.NewEncoder(w).Encode(confirmed)
+		case "GET /api/v1/bookings/export.csv":
+			if q.Get("status") != "confirmed" {
+				t.Errorf("export query %s", r.URL.RawQuery)
+			}
+			w.Write([]byte("id,reference\n42,MP-ABC123\n"))
+		default:
+			fail(w, http.StatusNotFound, models.ErrorResponse{Code: models.CodeNotFound, Message: "Booking not found"})
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// ctl runs minipartyctl against srv as token, returning its exit code and output.
+func ctl(srv *httptest.Server, token string, args ...string) (code int, stdout, stderr string) {
+	env := map[string]string{"MINIPARTY_URL": srv.URL + "/", "MINIPARTY_TOKEN": token}
+	var out, errOut strings.Builder
+	code = run(args, &out, &errOut, func(key string) string { return env[key] })
+	return code, out.String(), errOut.String()
+}
+
+func TestRun(t *testing.T) {
+	srv := fakeAPI(t)
+	tests := []struct {
+		name   string
+		token  string
+		args   []string
+		code   int
+		stdout []string
+		stderr []string
+	}{
+		{"list", "tok", []string{"bookings", "list", "-date", "2026-07-10", "-per-page", "1"}, 0,
+			[]string{"REFERENCE", "MP-ABC123", "Ann Example", "1 of 3 bookings; -page 2 for more"}, nil},
+		{"get", "tok", []string{"bookings", "get", "42"}, 0, []string{"Reference  MP-ABC123", "Status     pending"}, nil},
+		{"get as JSON", "tok", []string{"bookings", "get", "-json", "42"}, 0, []string{`"reference": "MP-ABC123"`}, nil},
+		{"confirm", "tok", []string{"bookings", "confirm", "42"}, 0, []string{"confirmed"}, nil},
+		{"missing", "tok", []string{"bookings", "cancel", "7"}, 1, nil, []string{"booking 7 not found"}},
+		{"viewer", "viewer", []string{"bookings", "confirm", "42"}, 1, nil, []string{"forbidden", "not a viewer one"}},
+		{"bad token", "wrong", []string{"bookings", "get", "42"}, 1, nil, []string{"not authorized", "MINIPARTY_TOKEN", "(request req-1)"}},
+		{"invalid", "tok", []string{"bookings", "list", "-status", "lost"}, 1, nil, []string{"didn't pass validation", "status: must be"}},
+		{"export", "tok", []string{"export", "-csv", "-", "-status", "confirmed"}, 0, []string{"42,MP-ABC123"}, nil},
+		{"no token", "", []string{"bookings", "list"}, 2, nil, []string{"no admin token"}},
+		{"no command", "tok", []string{"rooms"}, 2, nil, []string{"usage:"}},
+		{"no id", "tok", []string{"bookings", "get", "abc"}, 2, nil, []string{"usage: minipartyctl bookings get"}},
+		{"no file", "tok", []string{"export"}, 2, nil, []string{"usage: minipartyctl export"}},
+	}
+	for _, tt := range tests {
+		code, stdout, stderr := ctl(srv, tt.token, tt.args...)
+		if code != tt.code {
+			t.Errorf("%s: exit %d, want %d; stderr %s", tt.name, code, tt.code, stderr)
+		}
+		for _, want := range tt.stdout {
+			if !strings.Contains(stdout, want) {
+				t.Errorf("%s: stdout lacks %q:\n%s", tt.name, want, stdout)
+			}
+		}
+		for _, want := range tt.stderr {
+			if !strings.Contains(stderr, want) {
+				t.Errorf("%s: stderr lacks %q:\n%s", tt.name, want, stderr)
+			}
+		}
+	}
+}
+
+func TestExportToFile(t *testing.T) {
+	srv := fakeAPI(t)
+	file := filepath.Join(t.TempDir(), "bookings.csv")
+	if code, _, stderr := ctl(srv, "tok", "export", "-csv", file, "-status", "confirmed"); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	got, err := os.ReadFile(file)
+	if err != nil || string(got) != "id,reference\n42,MP-ABC123\n" {
+		t.Errorf("file holds %q, %v", got, err)
+	}
+}
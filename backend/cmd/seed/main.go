@@ -0,0 +1,65 @@
+// Command seed fills a database with made-up bookings for working on the admin dashboard
+// locally. It reads the same DATABASE_URL, SQLITE_PATH and DB_* variables as the server and
+// migrates the schema first, so it can start from an empty file:
+//
+//	go run ./cmd/seed -n 200 -seed 7
+//
+// The same -seed and -from give the same bookings. It won't add to a database that already
+// has bookings unless given -force, so pointing it at production by mistake does nothing.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"miniparty-backend/config"
+	"miniparty-backend/db"
+	"miniparty-backend/models"
+	"miniparty-backend/seed"
+	"miniparty-backend/settings"
+)
+
+func main() {
+	n := flag.Int("n", 50, "how many bookings to add")
+	seedValue := flag.Int64("seed", 1, "random seed; the same seed makes the same bookings")
+	from := flag.String("from", "", "spread bookings over the days after this date, YYYY-MM-DD (default today)")
+	days := flag.Int("days", 60, "how many days the bookings are spread over")
+	force := flag.Bool("force", false, "add bookings even if the database already has some")
+	flag.Parse()
+	if *n < 1 || *days < 1 {
+		log.Fatal("-n and -days must be at least 1")
+	}
+
+	start := time.Now().In(models.VenueLocation())
+	if *from != "" {
+		var err error
+		if start, err = time.ParseInLocation("2006-01-02", *from, models.VenueLocation()); err != nil {
+			log.Fatal("-from must be a date in YYYY-MM-DD format")
+		}
+	}
+
+	cfg, err := config.LoadDB()
+	if err != nil {
+		log.Fatal(err)
+	}
+	db.Init(cfg)
+	defer db.Close()
+
+	var existing int64
+	if err := db.DB.Unscoped().Model(&models.Booking{}).Count(&existing).Error; err != nil {
+		log.Fatalf("Failed to count bookings: %v", err)
+	}
+	if existing > 0 && !*force {
+		log.Fatalf("The database already has %d bookings; pass -force to add more anyway", existing)
+	}
+
+	g := seed.New(*seedValue, start, *days, settings.Current())
+	inserted, err := seed.Insert(context.Background(), db.DB, g, *n)
+	if err != nil {
+		log.Fatalf("Added %d bookings, then: %v", inserted, err)
+	}
+	log.Printf("Added %d bookings between %s and %s\n", inserted,
+		start.AddDate(0, 0, 1).Format("2006-01-02"), start.AddDate(0, 0, *days).Format("2006-01-02"))
+}
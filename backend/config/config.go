@@ -0,0 +1,631 @@
+// Package config reads the server's settings from the environment once, at startup.
+// Load checks every variable before anything is built and reports all the problems it
+// finds together, so a bad deploy fails on boot with the full list instead of one
+// variable at a time or on the first request that needs it.
+//
+// It covers the server itself: listening, CORS, the database, admin auth, check-in tickets,
+// timeouts, the email templates' directory, backups and the spam and captcha toggles. The integrations (SMTP, webhooks, Stripe, captcha secrets) and
+// the booking rules that seed the venue settings are still read by their own packages.
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"miniparty-backend/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Defaults for the settings that have one.
+const (
+	DefaultPort            = "8080"
+	DefaultDistPath        = "./dist"
+	DefaultSQLitePath      = "./miniparty.db"
+	DefaultDBTimeout       = 5 * time.Second
+	DefaultMaxOpenConns    = 10
+	DefaultMaxIdleConns    = 5
+	DefaultConnMaxLifetime = 30 * time.Minute
+	DefaultConnMaxIdleTime = 5 * time.Minute
+	DefaultShutdownTimeout = 10 * time.Second
+	// DefaultMaxBody fits every JSON body the API takes, a booking with notes included.
+	DefaultMaxBody         = 16 << 10
+	DefaultRateLimitRPM    = 5
+	DefaultLookupRateLimit = 2
+	DefaultQuoteRateLimit  = 60
+	DefaultMaxFailures     = 10
+	DefaultFailureWindow   = 15 * time.Minute
+	DefaultLockout         = 15 * time.Minute
+	DefaultSpamIPLimit     = 10
+	DefaultBackupInterval  = 24 * time.Hour
+	DefaultBackupKeep      = 7
+	DefaultNoShowThreshold = 2
+	DefaultErrorReportRate = 10
+)
+
+// How bookings past their retention are dealt with.
+const (
+	// RetentionAnonymize redacts their customer's details in place, keeping them for the stats.
+	RetentionAnonymize = "anonymize"
+	// RetentionArchive moves them to the bookings_archive table.
+	RetentionArchive = "archive"
+)
+
+// DefaultRetention anonymizes bookings two years after their date, 100 to a transaction and
+// at most 5000 a day.
+var DefaultRetention = Retention{Months: 24, Strategy: RetentionAnonymize, BatchSize: 100, MaxPerRun: 5000}
+
+// DefaultFeatures has every spam check on and the captcha failing closed.
+var DefaultFeatures = Features{
+	SpamHoneypot:      true,
+	SpamURLNames:      true,
+	SpamJunkDomains:   true,
+	SpamIPHourlyLimit: DefaultSpamIPLimit,
+}
+
+// DefaultNoShows leaves marking no-shows off and, once it is on, flags contacts with two.
+var DefaultNoShows = NoShows{FlagThreshold: DefaultNoShowThreshold}
+
+// DevOrigin is the Vite dev server, always allowed by CORS alongside CORS_ORIGINS.
+const DevOrigin = "http://localhost:5173"
+
+// DefaultTrustedProxies are loopback and the private ranges, where Render's and Docker's
+// proxies connect from. Nobody on the internet reaches the server from one of those, so
+// only the platform's own hop gets to say who the client is.
+var DefaultTrustedProxies = []string{"127.0.0.0/8", "::1/128", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"}
+
+// adminRoles are the roles an ADMIN_TOKENS entry may carry, middleware.RoleViewer and RoleAdmin.
+var adminRoles = []string{"viewer", "admin"}
+
+// Config is everything Load read, with defaults filled in.
+type Config struct {
+	Port string
+	// CORSOrigins are the browser origins allowed to call the API; DevOrigin comes first.
+	CORSOrigins []Origin
+	// TrustedProxies are the IPs and CIDR ranges allowed to set X-Forwarded-For; empty trusts none.
+	TrustedProxies []string
+	// DistPath is a React build to serve, ahead of one embedded in the binary.
+	DistPath string
+	// VenueTZ is the IANA timezone booking dates and times are written in.
+	VenueTZ string
+	// TemplatesDir overrides the built-in email templates file by file; empty uses them all.
+	TemplatesDir string
+
+	// ShutdownTimeout is how long shutdown waits for in-flight requests.
+	ShutdownTimeout time.Duration
+	// MaxBodyBytes caps request bodies, except on routes that set their own limit.
+	MaxBodyBytes int64
+	// RateLimitRPM is the per-IP rate for POST /book and admin login, LookupRateLimitRPM for
+	// GET /my-booking and QuoteRateLimitRPM for POST /quote.
+	RateLimitRPM       int
+	LookupRateLimitRPM int
+	QuoteRateLimitRPM  int
+	LogLevel           slog.Level
+	// DebugEndpoints mounts /debug/pprof/ and /debug/runtime, for admins only.
+	DebugEndpoints bool
+
+	DB        DB
+	Auth      Auth
+	Checkin   Checkin
+	NoShows   NoShows
+	Errors    ErrorReporting
+	Backup    Backup
+	Retention Retention
+	Features  Features
+}
+
+// DB says which database to connect to.
+type DB struct {
+	// Driver is "postgres" or "sqlite". It is sqlite when DB_DRIVER=sqlite or DATABASE_URL is unset.
+	Driver     string
+	URL        string
+	SQLitePath string
+	// Timeout bounds a single request's database work, and the ping at startup.
+	Timeout time.Duration
+
+	// The connection pool: at most MaxOpenConns connections, MaxIdleConns of them kept open
+	// while idle, each closed after ConnMaxLifetime or after ConnMaxIdleTime unused.
+	// SQLite always gets a single connection, kept open.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// Auth holds the admin credentials and the lockout after repeated failures.
+type Auth struct {
+	// Secret is the shared X-Admin-Token; SecretHash, when set, is checked instead of it.
+	Secret     string
+	SecretHash string
+	// Tokens maps each ADMIN_TOKENS token to its role.
+	Tokens map[string]string
+	// JWTSecret signs admin sessions, and PasswordHash is the bcrypt hash POST /admin/login checks.
+	JWTSecret    string
+	PasswordHash string
+	// MetricsToken protects /metrics; when empty the admin token is required instead.
+	MetricsToken string
+
+	MaxFailures   int
+	FailureWindow time.Duration
+	Lockout       time.Duration
+}
+
+// Checkin signs the check-in tokens on booking tickets.
+type Checkin struct {
+	// Secret keys the tokens' HMAC; empty turns tickets off.
+	Secret string
+	// URL is the page a ticket's QR code opens, with the token added as ?token=. It defaults
+	// to the dashboard's /admin/check-in under ADMIN_BASE_URL.
+	URL string
+}
+
+// ErrorReporting says where panics and server errors are sent besides the log. With
+// neither destination set they are only logged.
+type ErrorReporting struct {
+	// SentryDSN is a Sentry project's DSN, or that of a service that takes Sentry's events.
+	SentryDSN string
+	// WebhookURL receives each event as JSON.
+	WebhookURL string
+	// PerMinute caps how many events are sent a minute; the rest are only logged.
+	PerMinute int
+}
+
+// NoShows says how bookings nobody turned up for are counted against their customers.
+type NoShows struct {
+	// Mark turns on marking confirmed bookings whose day passed without a check-in as
+	// no-shows. Leave it off unless the door checks every party in.
+	Mark bool
+	// FlagThreshold is how many no-shows a contact's email or phone needs for their new
+	// bookings to be flagged; 0 never flags.
+	FlagThreshold int
+	// HoldFlagged keeps a flagged booking pending when its deposit is paid, for staff to
+	// confirm by hand.
+	HoldFlagged bool
+}
+
+// Backup says where and how often the database is backed up.
+type Backup struct {
+	// Dir is where backups are written; empty turns them off.
+	Dir string
+	// Interval is how long after one backup the next is taken, and Keep how many are kept.
+	Interval time.Duration
+	Keep     int
+}
+
+// Retention says how long bookings keep their customers' details.
+type Retention struct {
+	// Months is how long after its date a booking is kept as it is; 0 keeps them all.
+	Months int
+	// Strategy is RetentionAnonymize or RetentionArchive.
+	Strategy string
+	// BatchSize is how many bookings one transaction handles, and MaxPerRun how many a
+	// daily run handles in all, so neither holds the table for long.
+	BatchSize int
+	MaxPerRun int
+}
+
+// Features are the toggles on the booking form's spam and captcha checks.
+type Features struct {
+	SpamHoneypot    bool
+	SpamURLNames    bool
+	SpamJunkDomains bool
+	// DisposableBlocklist is a file of disposable email domains to check instead of the
+	// built-in list; empty keeps the built-in one.
+	DisposableBlocklist string
+	// VerifyMX rejects emails at domains with no MX records.
+	VerifyMX bool
+	// SpamIPHourlyLimit is how many bookings one IP may make in an hour; 0 means no limit.
+	SpamIPHourlyLimit int
+	// CaptchaFailOpen lets bookings through when the captcha provider can't be reached.
+	CaptchaFailOpen bool
+}
+
+// Error lists every problem Load found, one variable per line.
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// Load reads the configuration from the environment. When anything is missing or
+// malformed it returns an *Error listing all of it.
+func Load() (*Config, error) {
+	return Parse(os.Getenv)
+}
+
+// Parse is Load reading variables through getenv, so tests can pass a map's lookup instead
+// of setting the process environment. Empty values count as unset.
+func Parse(getenv func(string) string) (*Config, error) {
+	e := &env{getenv: getenv}
+	cfg := &Config{
+		Port:               e.port("PORT", DefaultPort),
+		CORSOrigins:        e.origins("CORS_ORIGINS"),
+		TrustedProxies:     e.proxies("TRUSTED_PROXIES"),
+		DistPath:           e.str("DIST_PATH", DefaultDistPath),
+		VenueTZ:            e.timezone("VENUE_TZ", models.DefaultVenueTZ),
+		TemplatesDir:       e.str("TEMPLATES_DIR", ""),
+		ShutdownTimeout:    e.duration("SHUTDOWN_TIMEOUT", DefaultShutdownTimeout),
+		MaxBodyBytes:       int64(e.positiveInt("MAX_BODY_BYTES", DefaultMaxBody)),
+		RateLimitRPM:       e.positiveInt("RATE_LIMIT_RPM", DefaultRateLimitRPM),
+		LookupRateLimitRPM: e.positiveInt("LOOKUP_RATE_LIMIT_RPM", DefaultLookupRateLimit),
+		QuoteRateLimitRPM:  e.positiveInt("QUOTE_RATE_LIMIT_RPM", DefaultQuoteRateLimit),
+		LogLevel:           e.logLevel("LOG_LEVEL"),
+		DebugEndpoints:     e.boolean("DEBUG_ENDPOINTS", false),
+	}
+	// Fail rather than ignore the old pair, which would quietly stop the frontend reaching the API.
+	for _, key := range []string{"CORS_ORIGIN", "CORS_ORIGIN_2"} {
+		if e.str(key, "") != "" {
+			e.fail(key, "replaced by CORS_ORIGINS, a comma-separated list of origins")
+		}
+	}
+
+	cfg.DB = e.database()
+
+	cfg.Auth = Auth{
+		Secret:        e.str("ADMIN_SECRET", ""),
+		SecretHash:    e.secretHash("ADMIN_SECRET_HASH"),
+		Tokens:        e.adminTokens("ADMIN_TOKENS"),
+		JWTSecret:     e.str("JWT_SECRET", ""),
+		PasswordHash:  e.bcryptHash("ADMIN_PASSWORD_HASH"),
+		MetricsToken:  e.str("METRICS_TOKEN", ""),
+		MaxFailures:   e.positiveInt("AUTH_MAX_FAILURES", DefaultMaxFailures),
+		FailureWindow: e.duration("AUTH_FAILURE_WINDOW", DefaultFailureWindow),
+		Lockout:       e.duration("AUTH_LOCKOUT", DefaultLockout),
+	}
+	if cfg.Auth.PasswordHash != "" && cfg.Auth.JWTSecret == "" {
+		e.fail("JWT_SECRET", "required when ADMIN_PASSWORD_HASH is set; it signs the sessions login hands out")
+	}
+	// Checked against the raw values, so a malformed variable isn't also reported as missing.
+	if e.str("ADMIN_SECRET", "") == "" && e.str("ADMIN_SECRET_HASH", "") == "" && e.str("ADMIN_TOKENS", "") == "" &&
+		e.str("ADMIN_PASSWORD_HASH", "") == "" {
+		e.fail("ADMIN_SECRET", "required unless ADMIN_SECRET_HASH, ADMIN_TOKENS or ADMIN_PASSWORD_HASH is set; nobody could use the admin routes")
+	}
+
+	cfg.Checkin = e.checkin()
+
+	cfg.Errors = e.errorReporting()
+
+	cfg.NoShows = NoShows{
+		Mark:          e.boolean("NO_SHOW_MARKING", DefaultNoShows.Mark),
+		FlagThreshold: e.nonNegativeInt("NO_SHOW_FLAG_THRESHOLD", DefaultNoShows.FlagThreshold),
+		HoldFlagged:   e.boolean("NO_SHOW_HOLD_FLAGGED", DefaultNoShows.HoldFlagged),
+	}
+
+	cfg.Backup = Backup{
+		Dir:      e.str("BACKUP_DIR", ""),
+		Interval: e.duration("BACKUP_INTERVAL", DefaultBackupInterval),
+		Keep:     e.positiveInt("BACKUP_KEEP", DefaultBackupKeep),
+	}
+
+	cfg.Retention = Retention{
+		Months:    e.nonNegativeInt("RETENTION_MONTHS", DefaultRetention.Months),
+		Strategy:  e.str("RETENTION_STRATEGY", DefaultRetention.Strategy),
+		BatchSize: e.positiveInt("RETENTION_BATCH_SIZE", DefaultRetention.BatchSize),
+		MaxPerRun: e.positiveInt("RETENTION_MAX_PER_RUN", DefaultRetention.MaxPerRun),
+	}
+	if s := cfg.Retention.Strategy; s != RetentionAnonymize && s != RetentionArchive {
+		e.fail("RETENTION_STRATEGY", "want anonymize or archive, got %q", s)
+	}
+
+	def := DefaultFeatures
+	cfg.Features = Features{
+		SpamHoneypot:        e.boolean("SPAM_HONEYPOT", def.SpamHoneypot),
+		SpamURLNames:        e.boolean("SPAM_URL_NAMES", def.SpamURLNames),
+		SpamJunkDomains:     e.boolean("SPAM_JUNK_DOMAINS", def.SpamJunkDomains),
+		DisposableBlocklist: e.str("DISPOSABLE_BLOCKLIST_PATH", ""),
+		VerifyMX:            e.boolean("VERIFY_MX", def.VerifyMX),
+		SpamIPHourlyLimit:   e.nonNegativeInt("SPAM_IP_HOURLY_LIMIT", def.SpamIPHourlyLimit),
+		CaptchaFailOpen:     e.boolean("CAPTCHA_FAIL_OPEN", def.CaptchaFailOpen),
+	}
+
+	if len(e.problems) > 0 {
+		return nil, &Error{Problems: e.problems}
+	}
+	return cfg, nil
+}
+
+// LoadDB reads only the database settings, for tools such as cmd/seed that connect to the
+// database without running the server.
+func LoadDB() (DB, error) {
+	e := &env{getenv: os.Getenv}
+	cfg := e.database()
+	if len(e.problems) > 0 {
+		return DB{}, &Error{Problems: e.problems}
+	}
+	return cfg, nil
+}
+
+// database reads the DB_* variables, DATABASE_URL and SQLITE_PATH.
+func (e *env) database() DB {
+	cfg := DB{
+		URL:        e.str("DATABASE_URL", ""),
+		SQLitePath: e.str("SQLITE_PATH", DefaultSQLitePath),
+		Timeout:    e.duration("DB_TIMEOUT", DefaultDBTimeout),
+
+		MaxOpenConns:    e.positiveInt("DB_MAX_OPEN_CONNS", DefaultMaxOpenConns),
+		MaxIdleConns:    e.nonNegativeInt("DB_MAX_IDLE_CONNS", DefaultMaxIdleConns),
+		ConnMaxLifetime: e.duration("DB_CONN_MAX_LIFETIME", DefaultConnMaxLifetime),
+		ConnMaxIdleTime: e.duration("DB_CONN_MAX_IDLE_TIME", DefaultConnMaxIdleTime),
+	}
+	if e.str("DB_MAX_IDLE_CONNS", "") == "" {
+		// Lowering DB_MAX_OPEN_CONNS alone shouldn't trip the check below.
+		cfg.MaxIdleConns = min(cfg.MaxIdleConns, cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > cfg.MaxOpenConns {
+		e.fail("DB_MAX_IDLE_CONNS", "can't be more than DB_MAX_OPEN_CONNS (%d), got %d", cfg.MaxOpenConns, cfg.MaxIdleConns)
+	}
+	switch driver := e.str("DB_DRIVER", ""); {
+	case driver == "sqlite", driver == "" && cfg.URL == "":
+		cfg.Driver = "sqlite"
+	case driver == "postgres", driver == "":
+		cfg.Driver = "postgres"
+		if cfg.URL == "" {
+			e.fail("DATABASE_URL", "required when DB_DRIVER=postgres")
+		}
+	default:
+		e.fail("DB_DRIVER", "want postgres or sqlite, got %q", driver)
+	}
+	return cfg
+}
+
+// checkin reads CHECKIN_SECRET and CHECKIN_URL, which falls back to ADMIN_BASE_URL.
+func (e *env) checkin() Checkin {
+	c := Checkin{Secret: e.str("CHECKIN_SECRET", ""), URL: e.str("CHECKIN_URL", "")}
+	key, raw := "CHECKIN_URL", c.URL
+	if c.URL == "" {
+		if base := e.str("ADMIN_BASE_URL", ""); base != "" {
+			key, raw = "ADMIN_BASE_URL", base
+			c.URL = strings.TrimRight(base, "/") + "/admin/check-in"
+		}
+	}
+	if c.Secret == "" {
+		return c
+	}
+	if c.URL == "" {
+		e.fail("CHECKIN_URL", "required when CHECKIN_SECRET is set and ADMIN_BASE_URL isn't; it is where the tickets' QR codes point")
+	} else if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		e.fail(key, "must be an http or https URL for the tickets' QR codes, got %q", raw)
+	}
+	return c
+}
+
+// errorReporting reads SENTRY_DSN, ERROR_WEBHOOK_URL and ERROR_REPORT_RATE.
+func (e *env) errorReporting() ErrorReporting {
+	r := ErrorReporting{
+		SentryDSN:  e.str("SENTRY_DSN", ""),
+		WebhookURL: e.str("ERROR_WEBHOOK_URL", ""),
+		PerMinute:  e.positiveInt("ERROR_REPORT_RATE", DefaultErrorReportRate),
+	}
+	if r.SentryDSN != "" {
+		u, err := url.Parse(r.SentryDSN)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User.Username() == "" ||
+			strings.Trim(u.Path, "/") == "" {
+			e.fail("SENTRY_DSN", "must look like https://<key>@<host>/<project>")
+		}
+	}
+	if r.WebhookURL != "" {
+		if u, err := url.Parse(r.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			e.fail("ERROR_WEBHOOK_URL", "must be an http or https URL, got %q", r.WebhookURL)
+		}
+	}
+	return r
+}
+
+// env reads variables and collects what is wrong with them. Each reader returns the
+// default for a bad value so Parse can carry on and report the rest.
+type env struct {
+	getenv   func(string) string
+	problems []string
+}
+
+func (e *env) fail(key, format string, args ...any) {
+	e.problems = append(e.problems, key+": "+fmt.Sprintf(format, args...))
+}
+
+func (e *env) str(key, def string) string {
+	if v := strings.TrimSpace(e.getenv(key)); v != "" {
+		return v
+	}
+	return def
+}
+
+func (e *env) positiveInt(key string, def int) int {
+	v := e.str(key, "")
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		e.fail(key, "want a positive whole number, got %q", v)
+		return def
+	}
+	return n
+}
+
+func (e *env) nonNegativeInt(key string, def int) int {
+	v := e.str(key, "")
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		e.fail(key, "want a whole number, 0 or more, got %q", v)
+		return def
+	}
+	return n
+}
+
+func (e *env) duration(key string, def time.Duration) time.Duration {
+	v := e.str(key, "")
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		e.fail(key, "want a positive duration such as 30s or 15m, got %q", v)
+		return def
+	}
+	return d
+}
+
+func (e *env) boolean(key string, def bool) bool {
+	v := e.str(key, "")
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		e.fail(key, "want true or false, got %q", v)
+		return def
+	}
+	return b
+}
+
+func (e *env) port(key, def string) string {
+	v := e.str(key, def)
+	if n, err := strconv.Atoi(v); err != nil || n < 1 || n > 65535 {
+		e.fail(key, "want a port number from 1 to 65535, got %q", v)
+		return def
+	}
+	return v
+}
+
+func (e *env) logLevel(key string) slog.Level {
+	var level slog.Level
+	if v := e.str(key, ""); v != "" {
+		if err := level.UnmarshalText([]byte(v)); err != nil {
+			e.fail(key, "want debug, info, warn or error, got %q", v)
+			return slog.LevelInfo
+		}
+	}
+	return level
+}
+
+func (e *env) timezone(key, def string) string {
+	v := e.str(key, def)
+	if _, err := time.LoadLocation(v); err != nil {
+		e.fail(key, "want an IANA timezone such as Europe/London, got %q", v)
+		return def
+	}
+	return v
+}
+
+// origins reads a comma-separated list of CORS origins, exact or wildcard, after DevOrigin.
+// Browsers send the origin as scheme://host[:port] exactly, so a path or trailing slash
+// would never match.
+func (e *env) origins(key string) []Origin {
+	dev, _ := ParseOrigin(DevOrigin)
+	out := []Origin{dev}
+	for _, v := range strings.Split(e.str(key, ""), ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		o, err := ParseOrigin(v)
+		if err != nil {
+			e.fail(key, "%v, got %q", err, v)
+			continue
+		}
+		out = append(out, o)
+	}
+	return out
+}
+
+// proxies reads a comma-separated list of IPs and CIDR ranges, DefaultTrustedProxies when
+// unset or none at all for "none", when the server faces the internet directly.
+func (e *env) proxies(key string) []string {
+	v := e.str(key, "")
+	switch v {
+	case "":
+		return slices.Clone(DefaultTrustedProxies)
+	case "none":
+		return []string{}
+	}
+	out := []string{}
+	for _, p := range strings.Split(v, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if net.ParseIP(p) == nil {
+			if _, _, err := net.ParseCIDR(p); err != nil {
+				e.fail(key, "want IP addresses or CIDR ranges, got %q", p)
+				continue
+			}
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// adminTokens reads a comma-separated list of token:role pairs such as "tok1:admin,tok2:viewer".
+func (e *env) adminTokens(key string) map[string]string {
+	tokens, err := ParseAdminTokens(e.str(key, ""))
+	if err != nil {
+		e.fail(key, "%v", err)
+		return map[string]string{}
+	}
+	return tokens
+}
+
+// ParseAdminTokens parses the ADMIN_TOKENS format: comma-separated token:role pairs.
+func ParseAdminTokens(s string) (map[string]string, error) {
+	tokens := map[string]string{}
+	for i, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		token, role, ok := strings.Cut(entry, ":")
+		token, role = strings.TrimSpace(token), strings.TrimSpace(role)
+		if !ok || token == "" {
+			return nil, fmt.Errorf("entry %d: want token:role", i+1)
+		}
+		if !slices.Contains(adminRoles, role) {
+			return nil, fmt.Errorf("entry %d: unknown role %q (want %s)", i+1, role, strings.Join(adminRoles, " or "))
+		}
+		if _, dup := tokens[token]; dup {
+			return nil, fmt.Errorf("entry %d: token listed twice", i+1)
+		}
+		tokens[token] = role
+	}
+	return tokens, nil
+}
+
+// secretHash reads ADMIN_SECRET_HASH: a bcrypt hash or a hex-encoded SHA-256 digest.
+func (e *env) secretHash(key string) string {
+	v := e.str(key, "")
+	if v == "" || strings.HasPrefix(v, "$2") && validBcrypt(v) {
+		return v
+	}
+	if b, err := hex.DecodeString(v); err != nil || len(b) != sha256.Size {
+		e.fail(key, "want a bcrypt hash or 64 hex characters of SHA-256")
+		return ""
+	}
+	return v
+}
+
+func (e *env) bcryptHash(key string) string {
+	v := e.str(key, "")
+	if v != "" && !validBcrypt(v) {
+		e.fail(key, "want a bcrypt hash, e.g. from htpasswd -bnBC 10 \"\" your-password")
+		return ""
+	}
+	return v
+}
+
+func validBcrypt(hash string) bool {
+	_, err := bcrypt.Cost([]byte(hash))
+	return err == nil
+}
@@ -0,0 +1,262 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// minimal is the least configuration Parse accepts.
+var minimal = map[string]string{"ADMIN_SECRET": "admin-secret"}
+
+// parse runs Parse on minimal with vars added, as name, value pairs.
+func parse(vars ...string) (*Config, error) {
+	env := map[string]string{}
+	for k, v := range minimal {
+		env[k] = v
+	}
+	for i := 0; i+1 < len(vars); i += 2 {
+		env[vars[i]] = vars[i+1]
+	}
+	return Parse(func(key string) string { return env[key] })
+}
+
+// problems returns the variables err's problems name, or fails the test if it isn't an *Error.
+func problems(t *testing.T, err error) string {
+	t.Helper()
+	var cfgErr *Error
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("err = %v, want an *Error", err)
+	}
+	return strings.Join(cfgErr.Problems, "\n")
+}
+
+func TestDatabaseDriver(t *testing.T) {
+	tests := []struct {
+		vars   []string
+		driver string
+	}{
+		{nil, "sqlite"},
+		{[]string{"DATABASE_URL", "postgres://db/miniparty"}, "postgres"},
+		{[]string{"DB_DRIVER", "sqlite", "DATABASE_URL", "postgres://db/miniparty"}, "sqlite"},
+		{[]string{"DB_DRIVER", "postgres", "DATABASE_URL", "postgres://db/miniparty"}, "postgres"},
+	}
+	for _, tt := range tests {
+		cfg, err := parse(tt.vars...)
+		if err != nil {
+			t.Errorf("%v: %v", tt.vars, err)
+			continue
+		}
+		if cfg.DB.Driver != tt.driver {
+			t.Errorf("%v: driver %s, want %s", tt.vars, cfg.DB.Driver, tt.driver)
+		}
+	}
+
+	cfg, err := parse()
+	if err != nil || cfg.DB.SQLitePath != DefaultSQLitePath {
+		t.Errorf("default SQLite path %q (%v), want %s", cfg.DB.SQLitePath, err, DefaultSQLitePath)
+	}
+	if cfg, err := parse("SQLITE_PATH", "/data/party.db"); err != nil || cfg.DB.SQLitePath != "/data/party.db" {
+		t.Errorf("SQLITE_PATH: %+v, %v", cfg, err)
+	}
+
+	_, err = parse("DB_DRIVER", "postgres")
+	if got := problems(t, err); !strings.Contains(got, "DATABASE_URL") {
+		t.Errorf("postgres without a URL: %s, want DATABASE_URL named", got)
+	}
+	_, err = parse("DB_DRIVER", "mysql")
+	if got := problems(t, err); !strings.Contains(got, "DB_DRIVER") {
+		t.Errorf("unknown driver: %s, want DB_DRIVER named", got)
+	}
+}
+
+func TestParseDefaults(t *testing.T) {
+	cfg, err := parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != DefaultPort || cfg.ShutdownTimeout != DefaultShutdownTimeout || cfg.MaxBodyBytes != DefaultMaxBody ||
+		cfg.RateLimitRPM != DefaultRateLimitRPM || cfg.Retention != DefaultRetention || cfg.Features != DefaultFeatures {
+		t.Errorf("defaults: %+v", cfg)
+	}
+	if len(cfg.CORSOrigins) != 1 || len(cfg.TrustedProxies) != len(DefaultTrustedProxies) {
+		t.Errorf("origins %v and proxies %v, want only the dev origin and the default proxies", cfg.CORSOrigins, cfg.TrustedProxies)
+	}
+
+	cfg, err = parse("PORT", "9000", "SHUTDOWN_TIMEOUT", "1m", "RATE_LIMIT_RPM", "20", "CORS_ORIGINS", "https://party.example.com, https://*.vercel.app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != "9000" || cfg.ShutdownTimeout != time.Minute || cfg.RateLimitRPM != 20 || len(cfg.CORSOrigins) != 3 {
+		t.Errorf("overrides: %+v", cfg)
+	}
+}
+
+func TestParseReportsEveryProblem(t *testing.T) {
+	_, err := parse("PORT", "70000", "SHUTDOWN_TIMEOUT", "soon", "RATE_LIMIT_RPM", "0", "DEBUG_ENDPOINTS", "maybe",
+		"CORS_ORIGINS", "https://party.example.com/", "CORS_ORIGIN", "https://party.example.com", "RETENTION_STRATEGY", "delete")
+	got := problems(t, err)
+	for _, key := range []string{"PORT", "SHUTDOWN_TIMEOUT", "RATE_LIMIT_RPM", "DEBUG_ENDPOINTS", "CORS_ORIGINS", "CORS_ORIGIN", "RETENTION_STRATEGY"} {
+		if !strings.Contains(got, key+": ") {
+			t.Errorf("%s not named in:\n%s", key, got)
+		}
+	}
+}
+
+func TestAdminCredentials(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("party"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	noSecret := func(vars ...string) (*Config, error) {
+		return Parse(func(key string) string {
+			for i := 0; i+1 < len(vars); i += 2 {
+				if vars[i] == key {
+					return vars[i+1]
+				}
+			}
+			return ""
+		})
+	}
+
+	_, err = noSecret()
+	if got := problems(t, err); !strings.Contains(got, "ADMIN_SECRET") {
+		t.Errorf("no credentials: %s, want ADMIN_SECRET named", got)
+	}
+	for _, vars := range [][]string{
+		{"ADMIN_SECRET_HASH", strings.Repeat("ab", 32)},
+		{"ADMIN_TOKENS", "tok1:admin,tok2:viewer"},
+		{"ADMIN_PASSWORD_HASH", string(hash), "JWT_SECRET", "jwt-secret"},
+	} {
+		if _, err := noSecret(vars...); err != nil {
+			t.Errorf("%v: %v", vars[0], err)
+		}
+	}
+
+	_, err = noSecret("ADMIN_PASSWORD_HASH", string(hash))
+	if got := problems(t, err); !strings.Contains(got, "JWT_SECRET") || strings.Contains(got, "ADMIN_SECRET") {
+		t.Errorf("password hash without JWT_SECRET: %s, want only JWT_SECRET named", got)
+	}
+	// A malformed value is reported as malformed, not also as missing credentials.
+	_, err = noSecret("ADMIN_SECRET_HASH", "secret")
+	if got := problems(t, err); got != "ADMIN_SECRET_HASH: want a bcrypt hash or 64 hex characters of SHA-256" {
+		t.Errorf("bad hash: %s", got)
+	}
+	_, err = parse("ADMIN_TOKENS", "tok1:owner")
+	if got := problems(t, err); !strings.Contains(got, "ADMIN_TOKENS") {
+		t.Errorf("bad role: %s, want ADMIN_TOKENS named", got)
+	}
+}
+
+func TestLoadDB(t *testing.T) {
+	t.Setenv("DB_DRIVER", "")
+	t.Setenv("DATABASE_URL", "postgres://db/miniparty")
+	t.Setenv("DB_TIMEOUT", "")
+	// Only the database settings are read, so the missing admin secret doesn't matter.
+	t.Setenv("ADMIN_SECRET", "")
+	if cfg, err := LoadDB(); err != nil || cfg.Driver != "postgres" || cfg.Timeout != DefaultDBTimeout {
+		t.Errorf("LoadDB = %+v, %v", cfg, err)
+	}
+	t.Setenv("DB_TIMEOUT", "never")
+	if _, err := LoadDB(); !strings.Contains(problems(t, err), "DB_TIMEOUT") {
+		t.Errorf("bad timeout: err = %v, want DB_TIMEOUT named", err)
+	}
+}
+
+func TestConnectionPool(t *testing.T) {
+	cfg, err := parse()
+	if err != nil || cfg.DB.MaxOpenConns != DefaultMaxOpenConns || cfg.DB.MaxIdleConns != DefaultMaxIdleConns ||
+		cfg.DB.ConnMaxLifetime != DefaultConnMaxLifetime || cfg.DB.ConnMaxIdleTime != DefaultConnMaxIdleTime {
+		t.Errorf("defaults: %+v, %v", cfg.DB, err)
+	}
+	// Lowering the cap alone takes the idle connections down with it.
+	if cfg, err := parse("DB_MAX_OPEN_CONNS", "2"); err != nil || cfg.DB.MaxOpenConns != 2 || cfg.DB.MaxIdleConns != 2 {
+		t.Errorf("DB_MAX_OPEN_CONNS=2: %+v, %v", cfg.DB, err)
+	}
+	if cfg, err := parse("DB_MAX_IDLE_CONNS", "0", "DB_CONN_MAX_LIFETIME", "1h"); err != nil || cfg.DB.MaxIdleConns != 0 || cfg.DB.ConnMaxLifetime != time.Hour {
+		t.Errorf("no idle connections: %+v, %v", cfg.DB, err)
+	}
+
+	_, err = parse("DB_MAX_OPEN_CONNS", "2", "DB_MAX_IDLE_CONNS", "3")
+	if got := problems(t, err); !strings.Contains(got, "DB_MAX_IDLE_CONNS") {
+		t.Errorf("more idle than open: %s, want DB_MAX_IDLE_CONNS named", got)
+	}
+	_, err = parse("DB_MAX_OPEN_CONNS", "0", "DB_CONN_MAX_IDLE_TIME", "-1m")
+	if got := problems(t, err); !strings.Contains(got, "DB_MAX_OPEN_CONNS") || !strings.Contains(got, "DB_CONN_MAX_IDLE_TIME") {
+		t.Errorf("bad pool settings: %s, want both named", got)
+	}
+}
+
+func TestTrustedProxies(t *testing.T) {
+	tests := map[string][]string{
+		"":                            DefaultTrustedProxies,
+		"none":                        {},
+		"10.0.0.1, 192.168.0.0/16 ,,": {"10.0.0.1", "192.168.0.0/16"},
+	}
+	for v, want := range tests {
+		cfg, err := parse("TRUSTED_PROXIES", v)
+		if err != nil || strings.Join(cfg.TrustedProxies, ",") != strings.Join(want, ",") || cfg.TrustedProxies == nil {
+			t.Errorf("TRUSTED_PROXIES=%q: %v, %v, want %v", v, cfg.TrustedProxies, err, want)
+		}
+	}
+	_, err := parse("TRUSTED_PROXIES", "10.0.0.1,render")
+	if got := problems(t, err); !strings.Contains(got, `TRUSTED_PROXIES: want IP addresses or CIDR ranges, got "render"`) {
+		t.Errorf("bad proxy: %s", got)
+	}
+}
+
+func TestEmailChecks(t *testing.T) {
+	cfg, err := parse("DISPOSABLE_BLOCKLIST_PATH", "/etc/miniparty/domains.txt", "VERIFY_MX", "true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Features.DisposableBlocklist != "/etc/miniparty/domains.txt" || !cfg.Features.VerifyMX {
+		t.Errorf("features %+v, want the blocklist path and VERIFY_MX on", cfg.Features)
+	}
+	_, err = parse("VERIFY_MX", "sometimes")
+	if got := problems(t, err); !strings.Contains(got, "VERIFY_MX") {
+		t.Errorf("bad VERIFY_MX: %s, want it named", got)
+	}
+}
+
+func TestNoShows(t *testing.T) {
+	cfg, err := parse()
+	if err != nil || cfg.NoShows != DefaultNoShows {
+		t.Fatalf("defaults: %+v, %v", cfg.NoShows, err)
+	}
+	cfg, err = parse("NO_SHOW_MARKING", "true", "NO_SHOW_FLAG_THRESHOLD", "0", "NO_SHOW_HOLD_FLAGGED", "true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.NoShows != (NoShows{Mark: true, HoldFlagged: true}) {
+		t.Errorf("overrides: %+v, want marking and holding on and flagging off", cfg.NoShows)
+	}
+	_, err = parse("NO_SHOW_FLAG_THRESHOLD", "-1")
+	if got := problems(t, err); !strings.Contains(got, "NO_SHOW_FLAG_THRESHOLD") {
+		t.Errorf("negative threshold: %s, want it named", got)
+	}
+}
+
+func TestErrorReporting(t *testing.T) {
+	cfg, err := parse()
+	if err != nil || cfg.Errors != (ErrorReporting{PerMinute: DefaultErrorReportRate}) {
+		t.Fatalf("defaults: %+v, %v", cfg.Errors, err)
+	}
+	cfg, err = parse("SENTRY_DSN", "https://abc123@o1.ingest.sentry.io/42", "ERROR_WEBHOOK_URL", "https://hooks.example/errors", "ERROR_REPORT_RATE", "30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Errors != (ErrorReporting{SentryDSN: "https://abc123@o1.ingest.sentry.io/42", WebhookURL: "https://hooks.example/errors", PerMinute: 30}) {
+		t.Errorf("overrides: %+v", cfg.Errors)
+	}
+	_, err = parse("SENTRY_DSN", "https://o1.ingest.sentry.io/42", "ERROR_WEBHOOK_URL", "ftp://hooks.example", "ERROR_REPORT_RATE", "0")
+	got := problems(t, err)
+	for _, name := range []string{"SENTRY_DSN", "ERROR_WEBHOOK_URL", "ERROR_REPORT_RATE"} {
+		if !strings.Contains(got, name) {
+			t.Errorf("bad error reporting: %s, want %s named", got, name)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// Origin is one CORS_ORIGINS entry: an exact origin such as https://example.com, or one
+// whose host starts with "*." to allow every subdomain, such as https://*.vercel.app for
+// Vercel's preview deployments. The scheme and port have to match exactly either way.
+type Origin struct {
+	Scheme string
+	// Host is lowercase, without the "*." of a wildcard.
+	Host string
+	// Port is explicit even when the origin leaves it out, so :443 and no port are the same.
+	Port     string
+	Wildcard bool
+}
+
+var errOrigin = errors.New("want an origin such as https://example.com or https://*.example.com, with no path or trailing slash")
+
+// ParseOrigin reads an exact or wildcard origin. A wildcard needs at least two labels after
+// it, so https://*.app, which would let in anyone's site, is refused.
+func ParseOrigin(s string) (Origin, error) {
+	var o Origin
+	scheme, rest, ok := strings.Cut(s, "://")
+	if !ok {
+		return o, errOrigin
+	}
+	if host, found := strings.CutPrefix(rest, "*."); found {
+		o.Wildcard, rest = true, host
+	}
+	u, err := url.Parse(scheme + "://" + rest)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" ||
+		u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil || strings.Contains(rest, "*") {
+		return o, errOrigin
+	}
+	o.Scheme, o.Host, o.Port = u.Scheme, strings.ToLower(u.Hostname()), portOf(u)
+	if o.Wildcard && strings.Count(o.Host, ".") < 1 {
+		return o, errors.New("a wildcard needs a domain after it, such as https://*.vercel.app, not a bare top-level domain")
+	}
+	return o, nil
+}
+
+// Match reports whether origin, as a browser sends it in the Origin header, is allowed by o.
+// A wildcard matches subdomains at any depth but not the domain itself.
+func (o Origin) Match(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme != o.Scheme || u.Path != "" || portOf(u) != o.Port {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if !o.Wildcard {
+		return host == o.Host
+	}
+	sub, ok := strings.CutSuffix(host, "."+o.Host)
+	return ok && sub != ""
+}
+
+func (o Origin) String() string {
+	s := o.Scheme + "://"
+	if o.Wildcard {
+		s += "*."
+	}
+	s += o.Host
+	if o.Port != defaultPort(o.Scheme) {
+		s += ":" + o.Port
+	}
+	return s
+}
+
+func portOf(u *url.URL) string {
+	if p := u.Port(); p != "" {
+		return p
+	}
+	return defaultPort(u.Scheme)
+}
+
+func defaultPort(scheme string) string {
+	if scheme == "http" {
+		return "80"
+	}
+	return "443"
+}
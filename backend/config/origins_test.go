@@ -0,0 +1,71 @@
+package config
+
+import "testing"
+
+func TestParseOrigin(t *testing.T) {
+	for s, want := range map[string]string{
+		"https://party.example.com":     "https://party.example.com",
+		"https://Party.Example.com:443": "https://party.example.com",
+		"http://localhost:5173":         "http://localhost:5173",
+		"https://*.vercel.app":          "https://*.vercel.app",
+	} {
+		o, err := ParseOrigin(s)
+		if err != nil || o.String() != want {
+			t.Errorf("ParseOrigin(%q) = %v, %v, want %s", s, o, err, want)
+		}
+	}
+	for _, s := range []string{
+		"party.example.com", "https://party.example.com/", "https://party.example.com/book", "ftp://example.com",
+		"https://*.app", "https://party.*.com", "https://user@example.com", "https://*", "https://example.com?x=1",
+	} {
+		if o, err := ParseOrigin(s); err == nil {
+			t.Errorf("ParseOrigin(%q) = %v, want an error", s, o)
+		}
+	}
+}
+
+func TestOriginMatch(t *testing.T) {
+	exact, _ := ParseOrigin("https://party.example.com")
+	wildcard, _ := ParseOrigin("https://*.vercel.app")
+	tests := []struct {
+		o      Origin
+		origin string
+		want   bool
+	}{
+		{exact, "https://party.example.com", true},
+		{exact, "https://PARTY.example.com:443", true},
+		{exact, "http://party.example.com", false},
+		{exact, "https://party.example.com:8443", false},
+		{exact, "https://evil-party.example.com", false},
+		{wildcard, "https://mini-party-git-main.vercel.app", true},
+		{wildcard, "https://a.b.vercel.app", true},
+		{wildcard, "https://vercel.app", false},
+		{wildcard, "https://evilvercel.app", false},
+		{wildcard, "https://preview.vercel.app.evil.com", false},
+		{wildcard, "http://preview.vercel.app", false},
+		{wildcard, "null", false},
+	}
+	for _, tt := range tests {
+		if got := tt.o.Match(tt.origin); got != tt.want {
+			t.Errorf("%s matching %s = %v, want %v", tt.o, tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestCORSOrigins(t *testing.T) {
+	cfg, err := parse("CORS_ORIGINS", " https://party.example.com ,, https://*.vercel.app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, o := range cfg.CORSOrigins {
+		got = append(got, o.String())
+	}
+	if len(got) != 3 || got[0] != DevOrigin || got[1] != "https://party.example.com" || got[2] != "https://*.vercel.app" {
+		t.Errorf("origins %v, want the dev origin and both configured", got)
+	}
+	_, err = parse("CORS_ORIGIN_2", "https://party.example.com")
+	if got := problems(t, err); got != "CORS_ORIGIN_2: replaced by CORS_ORIGINS, a comma-separated list of origins" {
+		t.Errorf("old variable: %s", got)
+	}
+}
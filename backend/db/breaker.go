@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	sqldriver "database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// ErrUnavailable is what queries fail with, without reaching the database, while it is
+// marked unreachable.
+var ErrUnavailable = errors.New("database temporarily unavailable")
+
+const (
+	// failureThreshold is how many queries in a row have to fail to connect before the
+	// database is marked unreachable. One dropped connection is retried by the pool anyway.
+	failureThreshold = 3
+	// probeInterval is the first wait between pings while the database is unreachable; it
+	// doubles up to maxProbeInterval.
+	probeInterval    = time.Second
+	maxProbeInterval = 30 * time.Second
+	probeTimeout     = 2 * time.Second
+)
+
+// breaker stops sending queries to a database that has stopped answering, so a short outage
+// turns into quick 503s instead of every request waiting out its timeout, and pings it in
+// the background until it answers again.
+type breaker struct {
+	ping func(context.Context) error
+
+	mu       sync.Mutex
+	failures int
+	down     bool
+	since    time.Time
+}
+
+// circuit guards DB; Init points its ping at the connection.
+var circuit = &breaker{}
+
+// Degraded reports whether the database is marked unreachable, and since when.
+func Degraded() (bool, time.Time) {
+	return circuit.state()
+}
+
+func (b *breaker) state() (bool, time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.down, b.since
+}
+
+// observe records the outcome of a query. Anything but a connection failure, a missing row
+// or a constraint violation included, shows the database answered.
+func (b *breaker) observe(err error) {
+	if errors.Is(err, ErrUnavailable) {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !Unreachable(err) {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.down || b.failures < failureThreshold {
+		return
+	}
+	b.down, b.since = true, time.Now()
+	slog.Warn("database unreachable; answering 503 until it is back", "error", err)
+	go b.probe()
+}
+
+// probe pings until the database answers, then lets queries through again.
+func (b *breaker) probe() {
+	wait := probeInterval
+	for {
+		time.Sleep(wait)
+		ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+		err := b.ping(ctx)
+		cancel()
+		if err == nil {
+			break
+		}
+		wait = min(2*wait, maxProbeInterval)
+	}
+
+	b.mu.Lock()
+	since := b.since
+	b.down, b.failures, b.since = false, 0, time.Time{}
+	b.mu.Unlock()
+	slog.Info("database reachable again", "down_for", time.Since(since).Round(time.Second).String())
+}
+
+// Unreachable reports whether err means the database couldn't be reached at all, as
+// opposed to a query it answered with an error. A request that ran out of time isn't one,
+// though context.DeadlineExceeded is a net.Error: a few slow queries mustn't mark the
+// database down for everyone.
+func Unreachable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrUnavailable), errors.Is(err, sqldriver.ErrBadConn), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.As(err, &connectErr):
+		return true
+	case errors.Is(err, context.DeadlineExceeded):
+		return false
+	}
+	return errors.As(err, &netErr)
+}
+
+// registerBreaker makes every GORM operation on gdb fail fast while the database is marked
+// unreachable, and feed its outcome to the breaker otherwise. The check runs first, before
+// GORM opens a transaction, and the observation last, after it commits.
+func registerBreaker(gdb *gorm.DB) error {
+	check := func(tx *gorm.DB) {
+		if down, _ := Degraded(); down {
+			tx.AddError(ErrUnavailable)
+		}
+	}
+	observe := func(tx *gorm.DB) { circuit.observe(tx.Error) }
+
+	cb := gdb.Callback()
+	return errors.Join(
+		cb.Query().Before("*").Register("breaker:check", check),
+		cb.Query().After("*").Register("breaker:observe", observe),
+		cb.Create().Before("*").Register("breaker:check", check),
+		cb.Create().After("*").Register("breaker:observe", observe),
+		cb.Update().Before("*").Register("breaker:check", check),
+		cb.Update().After("*").Register("breaker:observe", observe),
+		cb.Delete().Before("*").Register("breaker:check", check),
+		cb.Delete().After("*").Register("breaker:observe", observe),
+		cb.Row().Before("*").Register("breaker:check", check),
+		cb.Row().After("*").Register("breaker:observe", observe),
+		cb.Raw().Before("*").Register("breaker:check", check),
+		cb.Raw().After("*").Register("breaker:observe", observe),
+	)
+}
@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	sqldriver "database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"miniparty-backend/models"
+
+	"gorm.io/gorm"
+)
+
+func TestUnreachable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{ErrUnavailable, true},
+		{sqldriver.ErrBadConn, true},
+		{fmt.Errorf("read: %w", io.ErrUnexpectedEOF), true},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{context.DeadlineExceeded, false},
+		{fmt.Errorf("query: %w", context.DeadlineExceeded), false},
+		{context.Canceled, false},
+		{gorm.ErrRecordNotFound, false},
+		{gorm.ErrDuplicatedKey, false},
+	}
+	for _, tt := range tests {
+		if got := Unreachable(tt.err); got != tt.want {
+			t.Errorf("Unreachable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestBreakerTripsOnConnectionFailures(t *testing.T) {
+	// The probe's first ping, a second after tripping, finds the database back.
+	b := &breaker{ping: func(context.Context) error { return nil }}
+
+	for i := 0; i < 2*failureThreshold; i++ {
+		b.observe(context.DeadlineExceeded)
+	}
+	if down, _ := b.state(); down {
+		t.Fatal("timed-out queries marked the database down")
+	}
+
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	for i := 0; i < failureThreshold-1; i++ {
+		b.observe(refused)
+	}
+	b.observe(gorm.ErrRecordNotFound)
+	b.observe(refused)
+	if down, _ := b.state(); down {
+		t.Fatal("an answer between the failures didn't reset the count")
+	}
+	for i := 0; i < failureThreshold; i++ {
+		b.observe(refused)
+	}
+	if down, since := b.state(); !down || since.IsZero() {
+		t.Fatalf("%d failures in a row: down %v, want marked down", failureThreshold, down)
+	}
+}
+
+func TestBreakerFailsFastWhileDown(t *testing.T) {
+	testDB(t)
+
+	circuit.mu.Lock()
+	circuit.down = true
+	circuit.mu.Unlock()
+	t.Cleanup(func() {
+		circuit.mu.Lock()
+		circuit.down, circuit.failures = false, 0
+		circuit.mu.Unlock()
+	})
+
+	var n int64
+	if err := DB.Model(&models.Booking{}).Count(&n).Error; !errors.Is(err, ErrUnavailable) {
+		t.Errorf("query while down: err = %v, want ErrUnavailable", err)
+	}
+	if err := DB.Create(&models.Setting{Key: "k", Value: "v"}).Error; !errors.Is(err, ErrUnavailable) {
+		t.Errorf("create while down: err = %v, want ErrUnavailable", err)
+	}
+	if err := DB.Exec("DELETE FROM settings").Error; !errors.Is(err, ErrUnavailable) {
+		t.Errorf("exec while down: err = %v, want ErrUnavailable", err)
+	}
+	// Failing fast isn't another failure to count.
+	if circuit.failures != 0 {
+		t.Errorf("%d failures counted while down, want none", circuit.failures)
+	}
+}
+
+func TestBreakerProbeRecovers(t *testing.T) {
+	pinged := make(chan struct{}, 1)
+	b := &breaker{ping: func(context.Context) error {
+		pinged <- struct{}{}
+		return nil
+	}}
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	for i := 0; i < failureThreshold; i++ {
+		b.observe(refused)
+	}
+
+	select {
+	case <-pinged:
+	case <-time.After(probeInterval + 2*time.Second):
+		t.Fatal("the probe never pinged")
+	}
+	deadline := time.Now().Add(time.Second)
+	for down, _ := b.state(); down; down, _ = b.state() {
+		if time.Now().After(deadline) {
+			t.Fatal("still down after a successful ping")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if b.failures != 0 {
+		t.Errorf("%d failures left after recovering, want none", b.failures)
+	}
+}
@@ -1,34 +1,101 @@
 package db
 
 import (
+	"context"
 	"log"
-	"os"
+	"sync/atomic"
+	"time"
 
-	"miniparty-backend/models"
+	"miniparty-backend/config"
+	"miniparty-backend/settings"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
-func Init() {
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
-		log.Fatal("DATABASE_URL environment variable is required")
+// ready flips to true once the connection is open and the schema is migrated.
+var ready atomic.Bool
+
+// Ready reports whether Init has finished and DB is safe to use.
+func Ready() bool {
+	return ready.Load()
+}
+
+// driver is the active backend, "postgres" or "sqlite"; set by Init.
+var driver string
+
+// Driver reports which database backend Init connected to.
+func Driver() string {
+	return driver
+}
+
+// Init connects to the database cfg describes: PostgreSQL at cfg.URL, or a local SQLite file
+// at cfg.SQLitePath.
+func Init(cfg config.DB) {
+	var dialector gorm.Dialector
+	var description string
+	if cfg.Driver == "sqlite" {
+		driver, description = "sqlite", "SQLite at "+cfg.SQLitePath
+		dialector = sqlite.Open(cfg.SQLitePath)
+	} else {
+		driver, description = "postgres", "PostgreSQL"
+		dialector = postgres.Open(cfg.URL)
 	}
 
 	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	// GORM fills in created_at and updated_at itself, in UTC, rather than leaving it to
+	// column defaults that differ between the two databases.
+	DB, err = gorm.Open(dialector, &gorm.Config{
+		TranslateError: true,
+		NowFunc:        func() time.Time { return time.Now().UTC() },
+	})
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+
+	// Fail fast if the database is unreachable rather than hanging on the first query.
+	sqlDB, err := DB.DB()
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
+	if driver == "sqlite" {
+		// SQLite allows one writer at a time, and each connection to ":memory:" is a separate
+		// database, so the one connection is kept for good.
+		sqlDB.SetMaxOpenConns(1)
+	} else {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+		sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+		sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+	if err = sqlDB.PingContext(ctx); err != nil {
+		log.Fatal("Failed to reach database:", err)
+	}
+	circuit.ping = sqlDB.PingContext
+	if err = registerBreaker(DB); err != nil {
+		log.Fatal("Failed to set up the database breaker: ", err)
+	}
+	if err = registerWatch(DB); err != nil {
+		log.Fatal("Failed to watch database writes: ", err)
+	}
 
-	if err = DB.AutoMigrate(&models.Booking{}); err != nil {
-		log.Fatal("Failed to migrate database:", err)
+	if err = migrate(DB); err != nil {
+		log.Fatal("Failed to migrate database: ", err)
+	}
+	if err = settings.Seed(DB); err != nil {
+		log.Fatal("Failed to seed settings: ", err)
+	}
+	if _, err = settings.Load(DB); err != nil {
+		log.Fatal("Failed to load settings: ", err)
 	}
 
-	log.Println("Database initialized (PostgreSQL via GORM)")
+	ready.Store(true)
+	log.Printf("Database initialized (%s via GORM)\n", description)
 }
 
 func Close() {
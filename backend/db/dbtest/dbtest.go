@@ -0,0 +1,25 @@
+// Package dbtest sets up the database for other packages' tests.
+package dbtest
+
+import (
+	"io"
+	"log"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"miniparty-backend/config"
+	"miniparty-backend/db"
+
+	"gorm.io/gorm/logger"
+)
+
+// Init gives the test a fresh, migrated SQLite database as db.DB, closed when the test ends.
+// It silences the standard logger, which db.Init reports to, and GORM's.
+func Init(t testing.TB) {
+	t.Helper()
+	log.SetOutput(io.Discard)
+	db.Init(config.DB{Driver: "sqlite", SQLitePath: filepath.Join(t.TempDir(), "test.db"), Timeout: 5 * time.Second})
+	db.DB.Logger = logger.Discard
+	t.Cleanup(db.Close)
+}
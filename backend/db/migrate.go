@@ -0,0 +1,719 @@
+package db
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"miniparty-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// migration is one numbered schema change. Steps use their own snapshot structs rather than
+// the live models, so a migration keeps doing the same thing after the models move on.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *gorm.DB) error
+}
+
+// schemaMigration records an applied migration.
+type schemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// migrationLockID is the Postgres advisory lock key that serialises migrations across instances.
+const migrationLockID = 7_310_426
+
+// migrations are applied in order; append new steps, never edit applied ones.
+var migrations = []migration{
+	{1, "create_bookings", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&bookingV1{})
+	}},
+	{2, "add_booking_status_tokens_and_deposits", func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&bookingV2{}); err != nil {
+			return err
+		}
+		return backfillSlotKeys(tx)
+	}},
+	{3, "create_blackout_dates", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&blackoutV1{})
+	}},
+	{4, "add_booking_deleted_at", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&bookingV3{})
+	}},
+	{5, "create_idempotency_keys", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&idempotencyKeyV1{})
+	}},
+	{6, "add_booking_price", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&bookingV4{})
+	}},
+	{7, "create_packages", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&packageV1{}, &bookingV5{})
+	}},
+	{8, "add_booking_payment_holds", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&bookingV6{})
+	}},
+	{9, "create_rooms", func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&roomV1{}, &bookingV7{}); err != nil {
+			return err
+		}
+		return assignDefaultRoom(tx)
+	}},
+	{10, "create_addons", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&addonV1{}, &bookingAddonV1{})
+	}},
+	{11, "add_booking_confirmation_code", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&bookingV8{})
+	}},
+	{12, "add_booking_reschedule_history", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&bookingV9{})
+	}},
+	{13, "create_waitlist", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&waitlistEntryV1{})
+	}},
+	{14, "add_booking_reminded_at", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&bookingV10{})
+	}},
+	{15, "create_summary_sends", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&summarySendV1{})
+	}},
+	{16, "add_booking_starts_at", func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&bookingV11{}); err != nil {
+			return err
+		}
+		return backfillStartsAt(tx)
+	}},
+	{17, "create_settings", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&settingV1{})
+	}},
+	{18, "add_booking_notes", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&bookingV12{}, &waitlistEntryV2{})
+	}},
+	{19, "add_booking_reference", func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&bookingV13{}); err != nil {
+			return err
+		}
+		return backfillReferences(tx)
+	}},
+	{20, "create_audit_log", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&auditEntryV1{})
+	}},
+	{21, "add_booking_created_at", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&bookingV14{})
+	}},
+	{22, "add_booking_updated_at", func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&bookingV15{}); err != nil {
+			return err
+		}
+		return backfillTimestamps(tx)
+	}},
+	{23, "add_booking_sms_status", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&bookingV16{})
+	}},
+	{24, "add_booking_calendar_event", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&bookingV17{})
+	}},
+	{25, "add_booking_full_day", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&bookingV18{})
+	}},
+	{26, "add_booking_source", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&bookingV19{})
+	}},
+	{27, "add_booking_check_in", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&bookingV20{})
+	}},
+	{28, "add_booking_no_show_flag", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&bookingV21{})
+	}},
+	{29, "create_payments", func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&paymentV1{}, &bookingV22{}); err != nil {
+			return err
+		}
+		return backfillPayments(tx)
+	}},
+	{30, "add_booking_confirmation_resends", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&bookingV23{})
+	}},
+	{31, "create_bookings_archive", func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&bookingV24{}); err != nil {
+			return err
+		}
+		// A copy of the bookings columns, without their keys and unique indexes: an archived
+		// booking's slot and codes are free for new ones.
+		if err := tx.Exec("CREATE TABLE bookings_archive AS SELECT * FROM bookings WHERE 1 = 0").Error; err != nil {
+			return err
+		}
+		return tx.AutoMigrate(&bookingArchiveV1{})
+	}},
+	{32, "normalise_slot_minutes", normaliseSlotMinutes},
+	{33, "drop_booking_deposit_amount", dropDepositAmount},
+}
+
+// bookingV1 is the bookings table as first shipped.
+type bookingV1 struct {
+	ID       uint   `gorm:"primaryKey"`
+	Name     string `gorm:"not null"`
+	Email    string `gorm:"not null"`
+	Phone    string `gorm:"not null"`
+	Date     string `gorm:"not null"`
+	Time     string `gorm:"not null"`
+	Duration int    `gorm:"not null;default:2"`
+	Guests   int    `gorm:"not null"`
+}
+
+func (bookingV1) TableName() string { return "bookings" }
+
+// bookingV2 adds the booking lifecycle, cancel tokens, slot keys, series and deposits.
+type bookingV2 struct {
+	bookingV1
+	Status        string  `gorm:"not null;default:pending;index"`
+	CancelToken   string  `gorm:"not null;default:''"`
+	SlotKey       *string `gorm:"uniqueIndex"`
+	SeriesID      string  `gorm:"index"`
+	DepositAmount int     `gorm:"not null;default:0"`
+	DepositPaid   bool    `gorm:"not null;default:false"`
+	DepositPaidAt *time.Time
+}
+
+func (bookingV2) TableName() string { return "bookings" }
+
+// bookingV3 adds soft deletes.
+type bookingV3 struct {
+	bookingV2
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (bookingV3) TableName() string { return "bookings" }
+
+// bookingV4 adds the quoted price. Bookings made before pricing keep 0.
+type bookingV4 struct {
+	bookingV3
+	PriceCents int `gorm:"not null;default:0"`
+}
+
+func (bookingV4) TableName() string { return "bookings" }
+
+// bookingV5 links a booking to the package it was made with.
+type bookingV5 struct {
+	bookingV4
+	PackageID *uint `gorm:"index"`
+}
+
+func (bookingV5) TableName() string { return "bookings" }
+
+// bookingV6 tracks deposits paid online and how long an unpaid booking holds its slot.
+type bookingV6 struct {
+	bookingV5
+	PaymentIntentID string     `gorm:"not null;default:'';index"`
+	HoldExpiresAt   *time.Time `gorm:"index"`
+}
+
+func (bookingV6) TableName() string { return "bookings" }
+
+// bookingV7 puts each booking in a room.
+type bookingV7 struct {
+	bookingV6
+	RoomID *uint `gorm:"index"`
+}
+
+func (bookingV7) TableName() string { return "bookings" }
+
+// bookingV8 adds the code customers use to look up their booking. Older bookings have none.
+type bookingV8 struct {
+	bookingV7
+	ConfirmationCode string `gorm:"not null;default:''"`
+}
+
+func (bookingV8) TableName() string { return "bookings" }
+
+// bookingV9 records where a booking was last rescheduled from.
+type bookingV9 struct {
+	bookingV8
+	RescheduledFrom string `gorm:"not null;default:''"`
+	RescheduledAt   *time.Time
+}
+
+func (bookingV9) TableName() string { return "bookings" }
+
+// bookingV10 records when the customer was sent their reminder.
+type bookingV10 struct {
+	bookingV9
+	RemindedAt *time.Time `gorm:"index"`
+}
+
+func (bookingV10) TableName() string { return "bookings" }
+
+// bookingV11 stores each booking's start as a UTC timestamp next to the venue-local strings.
+type bookingV11 struct {
+	bookingV10
+	StartsAt *time.Time `gorm:"index"`
+}
+
+func (bookingV11) TableName() string { return "bookings" }
+
+// bookingV12 adds the customer's notes.
+type bookingV12 struct {
+	bookingV11
+	Notes string `gorm:"type:text;not null;default:''"`
+}
+
+func (bookingV12) TableName() string { return "bookings" }
+
+// bookingV13 adds the public booking reference. The column stays nullable so the unique
+// index can be built before the backfill; every booking has one afterwards.
+type bookingV13 struct {
+	bookingV12
+	Reference *string `gorm:"uniqueIndex"`
+}
+
+func (bookingV13) TableName() string { return "bookings" }
+
+// bookingV14 records when each booking was made. Older bookings are left NULL until
+// backfillTimestamps fills them in.
+type bookingV14 struct {
+	bookingV13
+	CreatedAt *time.Time `gorm:"index"`
+}
+
+func (bookingV14) TableName() string { return "bookings" }
+
+// bookingV15 adds when each booking last changed. Together with backfillTimestamps it leaves
+// no booking without either timestamp, so the model can use plain time.Time.
+type bookingV15 struct {
+	bookingV14
+	UpdatedAt *time.Time
+}
+
+func (bookingV15) TableName() string { return "bookings" }
+
+// bookingV16 records how the last text message to the customer went.
+type bookingV16 struct {
+	bookingV15
+	SMSStatus string `gorm:"not null;default:''"`
+}
+
+func (bookingV16) TableName() string { return "bookings" }
+
+// bookingV17 links each confirmed booking to its Google Calendar event.
+type bookingV17 struct {
+	bookingV16
+	CalendarEventID  string `gorm:"not null;default:''"`
+	CalendarSyncedAt *time.Time
+}
+
+func (bookingV17) TableName() string { return "bookings" }
+
+// bookingV18 marks whole-day buyouts of the venue.
+type bookingV18 struct {
+	bookingV17
+	FullDay bool `gorm:"not null;default:false"`
+}
+
+func (bookingV18) TableName() string { return "bookings" }
+
+// bookingV19 records where each booking came from, and its ad campaign's UTM tags.
+type bookingV19 struct {
+	bookingV18
+	Source      string `gorm:"not null;default:direct;index"`
+	UTMSource   string `gorm:"not null;default:''"`
+	UTMMedium   string `gorm:"not null;default:''"`
+	UTMCampaign string `gorm:"not null;default:''"`
+}
+
+func (bookingV19) TableName() string { return "bookings" }
+
+// bookingV20 records when each party checked in at the door, and how many came.
+type bookingV20 struct {
+	bookingV19
+	CheckedInAt  *time.Time `gorm:"index"`
+	ActualGuests *int
+}
+
+func (bookingV20) TableName() string { return "bookings" }
+
+// bookingV21 flags bookings from contacts who have missed earlier ones.
+type bookingV21 struct {
+	bookingV20
+	Flagged      bool `gorm:"not null;default:false;index"`
+	PriorNoShows int  `gorm:"not null;default:0"`
+}
+
+func (bookingV21) TableName() string { return "bookings" }
+
+// bookingV22 tracks what a booking owes and has been paid, alongside the payments table.
+type bookingV22 struct {
+	bookingV21
+	DepositDueCents int    `gorm:"not null;default:0"`
+	AmountPaidCents int    `gorm:"not null;default:0"`
+	PaymentMethod   string `gorm:"not null;default:''"`
+	PaidAt          *time.Time
+}
+
+func (bookingV22) TableName() string { return "bookings" }
+
+// bookingV23 records confirmations sent again on request.
+type bookingV23 struct {
+	bookingV22
+	ConfirmationResends  int `gorm:"not null;default:0"`
+	ConfirmationResentAt *time.Time
+}
+
+func (bookingV23) TableName() string { return "bookings" }
+
+// bookingV24 lets staff hold a booking back from the retention job.
+type bookingV24 struct {
+	bookingV23
+	RetentionHold bool `gorm:"not null;default:false"`
+}
+
+func (bookingV24) TableName() string { return "bookings" }
+
+// bookingArchiveV1 is what bookings_archive has on top of the bookings columns it was
+// created with: when the booking was moved, and an index to find one by. A later migration
+// that adds a bookings column adds it to bookings_archive too, or archiving leaves it behind.
+type bookingArchiveV1 struct {
+	ID         uint      `gorm:"index"`
+	ArchivedAt time.Time `gorm:"not null;index"`
+}
+
+func (bookingArchiveV1) TableName() string { return "bookings_archive" }
+
+type paymentV1 struct {
+	ID          uint   `gorm:"primaryKey"`
+	BookingID   uint   `gorm:"not null;index"`
+	AmountCents int    `gorm:"not null"`
+	Method      string `gorm:"not null"`
+	Reference   string `gorm:"type:text;not null;default:''"`
+	CreatedAt   time.Time
+}
+
+func (paymentV1) TableName() string { return "payments" }
+
+type blackoutV1 struct {
+	ID     uint   `gorm:"primaryKey"`
+	Date   string `gorm:"not null;uniqueIndex"`
+	Reason string `gorm:"not null;default:''"`
+}
+
+func (blackoutV1) TableName() string { return "blackout_dates" }
+
+type idempotencyKeyV1 struct {
+	Key         string `gorm:"primaryKey;size:255"`
+	RequestHash string `gorm:"size:64;not null"`
+	Status      int    `gorm:"not null;default:0"`
+	Response    []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time `gorm:"not null;index"`
+}
+
+func (idempotencyKeyV1) TableName() string { return "idempotency_keys" }
+
+type packageV1 struct {
+	ID             uint   `gorm:"primaryKey"`
+	Name           string `gorm:"not null;uniqueIndex"`
+	Description    string `gorm:"not null;default:''"`
+	DurationHours  int    `gorm:"not null"`
+	BasePriceCents int    `gorm:"not null;default:0"`
+	MaxGuests      int    `gorm:"not null"`
+	Active         bool   `gorm:"not null;default:true"`
+}
+
+func (packageV1) TableName() string { return "packages" }
+
+type roomV1 struct {
+	ID       uint   `gorm:"primaryKey"`
+	Name     string `gorm:"not null;uniqueIndex"`
+	Capacity int    `gorm:"not null"`
+	Active   bool   `gorm:"not null;default:true"`
+}
+
+func (roomV1) TableName() string { return "rooms" }
+
+type addonV1 struct {
+	ID         uint   `gorm:"primaryKey"`
+	Name       string `gorm:"not null;uniqueIndex"`
+	PriceCents int    `gorm:"not null;default:0"`
+	Active     bool   `gorm:"not null;default:true"`
+}
+
+func (addonV1) TableName() string { return "addons" }
+
+type bookingAddonV1 struct {
+	BookingID  uint   `gorm:"primaryKey;autoIncrement:false"`
+	AddonID    uint   `gorm:"primaryKey;autoIncrement:false"`
+	Name       string `gorm:"not null"`
+	PriceCents int    `gorm:"not null;default:0"`
+}
+
+func (bookingAddonV1) TableName() string { return "booking_addons" }
+
+type waitlistEntryV1 struct {
+	ID        uint   `gorm:"primaryKey"`
+	Name      string `gorm:"not null"`
+	Email     string `gorm:"not null"`
+	Phone     string `gorm:"not null"`
+	Date      string `gorm:"not null;index"`
+	Time      string `gorm:"not null"`
+	Duration  int    `gorm:"not null"`
+	Guests    int    `gorm:"not null"`
+	RoomID    *uint
+	PackageID *uint
+	AddonIDs  string `gorm:"type:text"`
+	CreatedAt time.Time
+}
+
+func (waitlistEntryV1) TableName() string { return "waitlist" }
+
+type waitlistEntryV2 struct {
+	waitlistEntryV1
+	Notes string `gorm:"type:text;not null;default:''"`
+}
+
+func (waitlistEntryV2) TableName() string { return "waitlist" }
+
+type summarySendV1 struct {
+	Date   string    `gorm:"primaryKey"`
+	SentAt time.Time `gorm:"not null"`
+}
+
+func (summarySendV1) TableName() string { return "summary_sends" }
+
+type settingV1 struct {
+	Key       string `gorm:"primaryKey"`
+	Value     string `gorm:"type:text;not null"`
+	UpdatedAt time.Time
+}
+
+func (settingV1) TableName() string { return "settings" }
+
+type auditEntryV1 struct {
+	ID        uint      `gorm:"primaryKey"`
+	CreatedAt time.Time `gorm:"index"`
+	Actor     string    `gorm:"not null"`
+	Role      string    `gorm:"not null"`
+	Action    string    `gorm:"not null;index"`
+	BookingID *uint     `gorm:"index"`
+	Before    string    `gorm:"type:text;not null;default:''"`
+	After     string    `gorm:"type:text;not null;default:''"`
+	ClientIP  string    `gorm:"not null;default:''"`
+}
+
+func (auditEntryV1) TableName() string { return "audit_log" }
+
+// migrate applies pending migrations in one transaction. On Postgres an advisory lock makes
+// a second instance starting at the same time wait, then find nothing left to do.
+func migrate(gdb *gorm.DB) error {
+	return gdb.Transaction(func(tx *gorm.DB) error {
+		if tx.Dialector.Name() == "postgres" {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockID).Error; err != nil {
+				return fmt.Errorf("acquire migration lock: %w", err)
+			}
+		}
+		if err := tx.AutoMigrate(&schemaMigration{}); err != nil {
+			return fmt.Errorf("create schema_migrations: %w", err)
+		}
+
+		var applied []int
+		if err := tx.Model(&schemaMigration{}).Pluck("version", &applied).Error; err != nil {
+			return fmt.Errorf("read schema_migrations: %w", err)
+		}
+		done := make(map[int]bool, len(applied))
+		for _, v := range applied {
+			done[v] = true
+		}
+
+		for _, m := range migrations {
+			if done[m.version] {
+				continue
+			}
+			log.Printf("Applying migration %03d_%s\n", m.version, m.name)
+			if err := m.up(tx); err != nil {
+				return fmt.Errorf("migration %03d_%s failed: %w", m.version, m.name, err)
+			}
+			record := schemaMigration{Version: m.version, Name: m.name, AppliedAt: time.Now().UTC()}
+			if err := tx.Create(&record).Error; err != nil {
+				return fmt.Errorf("record migration %03d_%s: %w", m.version, m.name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// backfillSlotKeys populates slot_key for active bookings created before the column existed.
+// Where a slot was already double-booked only the earliest booking gets the key.
+func backfillSlotKeys(tx *gorm.DB) error {
+	return tx.Exec(`
+		UPDATE bookings SET slot_key = "date" || ' ' || "time"
+		WHERE slot_key IS NULL AND status <> ?
+		AND id = (
+			SELECT MIN(b2.id) FROM bookings b2
+			WHERE b2."date" = bookings."date" AND b2."time" = bookings."time" AND b2.status <> ?
+		)`, models.StatusCancelled, models.StatusCancelled).Error
+}
+
+// backfillStartsAt fills in starts_at for existing bookings, reading their date and time in
+// the venue's timezone (VENUE_TZ). Rows whose date or time doesn't parse are left NULL.
+func backfillStartsAt(tx *gorm.DB) error {
+	type row struct {
+		ID   uint
+		Date string
+		Time string
+	}
+	var rows []row
+	if err := tx.Table("bookings").Select("id", "date", "time").Where("starts_at IS NULL").Find(&rows).Error; err != nil {
+		return err
+	}
+	for _, r := range rows {
+		start, err := models.StartTime(r.Date, r.Time)
+		if err != nil {
+			continue
+		}
+		if err := tx.Table("bookings").Where("id = ?", r.ID).Update("starts_at", start).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillReferences gives every booking without one, soft-deleted ones included, a
+// reference of the form "MP-" and six Crockford base32 characters, skipping any already taken.
+func backfillReferences(tx *gorm.DB) error {
+	const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+	var taken []string
+	if err := tx.Table("bookings").Where("reference IS NOT NULL").Pluck("reference", &taken).Error; err != nil {
+		return err
+	}
+	used := make(map[string]bool, len(taken))
+	for _, r := range taken {
+		used[r] = true
+	}
+
+	var ids []uint
+	if err := tx.Table("bookings").Where("reference IS NULL").Order("id ASC").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	buf := make([]byte, 6)
+	for _, id := range ids {
+		var ref string
+		for ref == "" || used[ref] {
+			if _, err := rand.Read(buf); err != nil {
+				return err
+			}
+			for i, b := range buf {
+				buf[i] = alphabet[b%32]
+			}
+			ref = "MP-" + string(buf)
+		}
+		used[ref] = true
+		if err := tx.Table("bookings").Where("id = ?", id).Update("reference", ref).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillTimestamps gives bookings made before created_at was recorded the migration's time,
+// soft-deleted ones included, and sets updated_at to created_at wherever it is missing.
+func backfillTimestamps(tx *gorm.DB) error {
+	if err := tx.Exec(`UPDATE bookings SET created_at = ? WHERE created_at IS NULL`, time.Now().UTC()).Error; err != nil {
+		return err
+	}
+	return tx.Exec(`UPDATE bookings SET updated_at = created_at WHERE updated_at IS NULL`).Error
+}
+
+// backfillPayments makes each booking's deposit its deposit due, and records the deposits
+// already marked paid as payments: by card for those paid online, "other" for the rest.
+func backfillPayments(tx *gorm.DB) error {
+	if err := tx.Exec(`UPDATE bookings SET deposit_due_cents = deposit_amount`).Error; err != nil {
+		return err
+	}
+	err := tx.Exec(`INSERT INTO payments (booking_id, amount_cents, method, reference, created_at)
+		SELECT id, deposit_amount, CASE WHEN payment_intent_id <> '' THEN 'card' ELSE 'other' END, payment_intent_id,
+			COALESCE(deposit_paid_at, updated_at)
+		FROM bookings WHERE deposit_paid AND deposit_amount > 0`).Error
+	if err != nil {
+		return err
+	}
+	return tx.Exec(`UPDATE bookings SET amount_paid_cents = deposit_amount, paid_at = COALESCE(deposit_paid_at, updated_at),
+		payment_method = CASE WHEN payment_intent_id <> '' THEN 'card' ELSE 'other' END
+		WHERE deposit_paid AND deposit_amount > 0`).Error
+}
+
+// dropDepositAmount retires deposit_amount, which deposit_due_cents has repeated since
+// payments were recorded. Deposits staff marked paid without a payment to cover them get
+// one for the rest, "other" like those backfillPayments made, so that deposit_paid again
+// follows from the amounts.
+func dropDepositAmount(tx *gorm.DB) error {
+	at := time.Now().UTC()
+	err := tx.Exec(`INSERT INTO payments (booking_id, amount_cents, method, reference, created_at)
+		SELECT id, deposit_due_cents - amount_paid_cents, 'other', '', COALESCE(deposit_paid_at, ?)
+		FROM bookings WHERE deposit_paid AND deposit_due_cents > amount_paid_cents`, at).Error
+	if err != nil {
+		return err
+	}
+	err = tx.Exec(`UPDATE bookings SET amount_paid_cents = deposit_due_cents, paid_at = COALESCE(deposit_paid_at, ?),
+		payment_method = 'other' WHERE deposit_paid AND deposit_due_cents > amount_paid_cents`, at).Error
+	if err != nil {
+		return err
+	}
+	err = tx.Exec(`UPDATE bookings SET deposit_paid = ?, deposit_paid_at = NULL
+		WHERE deposit_paid AND deposit_due_cents = 0`, false).Error
+	if err != nil {
+		return err
+	}
+	for _, table := range []string{"bookings", "bookings_archive"} {
+		if tx.Migrator().HasColumn(table, "deposit_amount") {
+			if err := tx.Exec("ALTER TABLE " + table + " DROP COLUMN deposit_amount").Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// normaliseSlotMinutes moves a slot_minutes setting other than 30 or 60, which are all it
+// can be now, to the nearer of them. Bookings keep the start times they were made with.
+func normaliseSlotMinutes(tx *gorm.DB) error {
+	var rows []settingV1
+	if err := tx.Where(&settingV1{Key: "slot_minutes"}).Limit(1).Find(&rows).Error; err != nil || len(rows) == 0 {
+		return err
+	}
+	row := rows[0]
+	minutes, err := strconv.Atoi(row.Value)
+	if err != nil {
+		// Not a number: Load would reject it anyway, so leave it for the admin to see.
+		return nil
+	}
+	slot := 30
+	if minutes > 45 {
+		slot = 60
+	}
+	if slot == minutes {
+		return nil
+	}
+	return tx.Model(&row).Updates(settingV1{Value: strconv.Itoa(slot), UpdatedAt: time.Now().UTC()}).Error
+}
+
+// assignDefaultRoom creates the venue's first room and moves every existing booking into it,
+// adding the room to their slot keys, so the venue keeps working as one room until more are added.
+func assignDefaultRoom(tx *gorm.DB) error {
+	room := roomV1{Name: "Main room", Capacity: 100, Active: true}
+	if err := tx.Create(&room).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec(`UPDATE bookings SET room_id = ?`, room.ID).Error; err != nil {
+		return err
+	}
+	return tx.Exec(`UPDATE bookings SET slot_key = slot_key || ? WHERE slot_key IS NOT NULL`, fmt.Sprintf(" #%d", room.ID)).Error
+}
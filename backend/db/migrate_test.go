@@ -0,0 +1,239 @@
+package db
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"miniparty-backend/config"
+	"miniparty-backend/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB gives the test a fresh, migrated SQLite database as DB, like dbtest.Init does for
+// other packages' tests; this package's own can't import it.
+func testDB(t *testing.T) {
+	t.Helper()
+	log.SetOutput(io.Discard)
+	Init(config.DB{Driver: "sqlite", SQLitePath: filepath.Join(t.TempDir(), "test.db"), Timeout: 5 * time.Second})
+	DB.Logger = logger.Discard
+	t.Cleanup(Close)
+}
+
+// TestModelsMatchSchema checks the models' gorm tags against the schema the migrations
+// build, since the tags are never migrated themselves: every column a model maps exists, a
+// not null tag is a NOT NULL column and each index and unique index the tags name is there.
+func TestModelsMatchSchema(t *testing.T) {
+	testDB(t)
+
+	for _, model := range []any{
+		&models.Addon{}, &models.AuditEntry{}, &models.Blackout{}, &models.Booking{}, &models.BookingAddon{},
+		&models.IdempotencyKey{}, &models.Package{}, &models.Payment{}, &models.Room{}, &models.Setting{},
+		&models.SummarySend{}, &models.WaitlistEntry{},
+	} {
+		stmt := &gorm.Statement{DB: DB}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatal(err)
+		}
+		table := stmt.Schema.Table
+		columnTypes, err := DB.Migrator().ColumnTypes(model)
+		if err != nil {
+			t.Fatal(err)
+		}
+		columns := map[string]gorm.ColumnType{}
+		for _, col := range columnTypes {
+			columns[col.Name()] = col
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" || field.IgnoreMigration {
+				continue
+			}
+			col, ok := columns[field.DBName]
+			if !ok {
+				t.Errorf("%s.%s: no column %s", table, field.Name, field.DBName)
+				continue
+			}
+			if nullable, ok := col.Nullable(); ok && field.NotNull && nullable {
+				t.Errorf("%s.%s: tagged not null but the column is nullable", table, field.Name)
+			}
+		}
+
+		indexes, err := DB.Migrator().GetIndexes(model)
+		if err != nil {
+			t.Fatal(err)
+		}
+		unique := map[string]bool{}
+		for _, idx := range indexes {
+			if cols := idx.Columns(); len(cols) == 1 {
+				u, _ := idx.Unique()
+				unique[cols[0]] = unique[cols[0]] || u
+			}
+		}
+		for _, idx := range stmt.Schema.ParseIndexes() {
+			if !DB.Migrator().HasIndex(model, idx.Name) {
+				t.Errorf("%s: no index %s", table, idx.Name)
+				continue
+			}
+			if idx.Class == "UNIQUE" && len(idx.Fields) == 1 && !unique[idx.Fields[0].DBName] {
+				t.Errorf("%s: index %s is tagged unique but isn't", table, idx.Name)
+			}
+		}
+	}
+}
+
+func TestNormaliseSlotMinutes(t *testing.T) {
+	testDB(t)
+
+	for stored, want := range map[string]string{"15": "30", "30": "30", "45": "30", "50": "60", "90": "60", "often": "often"} {
+		if err := DB.Save(&models.Setting{Key: "slot_minutes", Value: stored}).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := normaliseSlotMinutes(DB); err != nil {
+			t.Fatalf("%s: %v", stored, err)
+		}
+		var got models.Setting
+		if err := DB.Take(&got, "key = ?", "slot_minutes").Error; err != nil {
+			t.Fatal(err)
+		}
+		if got.Value != want {
+			t.Errorf("slot_minutes %s became %s, want %s", stored, got.Value, want)
+		}
+	}
+
+	if err := DB.Delete(&models.Setting{}, "key = ?", "slot_minutes").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := normaliseSlotMinutes(DB); err != nil {
+		t.Errorf("no slot_minutes row: %v", err)
+	}
+}
+
+func TestMigrationVersions(t *testing.T) {
+	for i, m := range migrations {
+		if m.version != i+1 || m.name == "" || m.up == nil {
+			t.Errorf("migration %d is {%d, %q}, want version %d with a name and a step", i, m.version, m.name, i+1)
+		}
+	}
+}
+
+func TestMigrateUpgradesOldDatabase(t *testing.T) {
+	log.SetOutput(io.Discard)
+	gdb, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "old.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The database as the first release left it: a double-booked slot and a cancelled booking.
+	all := migrations
+	migrations = all[:1]
+	err = migrate(gdb)
+	migrations = all
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range []bookingV1{
+		{Name: "First", Email: "a@example.com", Phone: "1", Date: "2026-07-10", Time: "14:00", Duration: 2, Guests: 4},
+		{Name: "Second", Email: "b@example.com", Phone: "2", Date: "2026-07-10", Time: "14:00", Duration: 2, Guests: 4},
+		{Name: "Third", Email: "c@example.com", Phone: "3", Date: "2026-07-11", Time: "10:00", Duration: 2, Guests: 4},
+	} {
+		if err := gdb.Create(&b).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := migrate(gdb); err != nil {
+		t.Fatalf("upgrade: %v", err)
+	}
+	if err := migrate(gdb); err != nil {
+		t.Fatalf("migrating again: %v", err)
+	}
+	var applied []int
+	if err := gdb.Model(&schemaMigration{}).Order("version").Pluck("version", &applied).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != len(migrations) || applied[len(applied)-1] != migrations[len(migrations)-1].version {
+		t.Errorf("applied %v, want each of the %d migrations once", applied, len(migrations))
+	}
+
+	var rows []struct {
+		Name      string
+		SlotKey   *string
+		Reference *string
+		RoomID    *uint
+		StartsAt  *time.Time
+		CreatedAt *time.Time
+	}
+	if err := gdb.Table("bookings").Order("id").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	refs := map[string]bool{}
+	for _, r := range rows {
+		if r.Reference == nil || refs[*r.Reference] || r.RoomID == nil || r.StartsAt == nil || r.CreatedAt == nil {
+			t.Errorf("%s after the upgrade: %+v, want a unique reference, a room, a start and a created_at", r.Name, r)
+		}
+		if r.Reference != nil {
+			refs[*r.Reference] = true
+		}
+	}
+	// Only the earlier of the double-booked pair holds the slot.
+	if rows[0].SlotKey == nil || rows[1].SlotKey != nil || rows[2].SlotKey == nil {
+		t.Errorf("slot keys %v %v %v, want the first and third set", rows[0].SlotKey, rows[1].SlotKey, rows[2].SlotKey)
+	}
+}
+
+func TestDropDepositAmount(t *testing.T) {
+	log.SetOutput(io.Discard)
+	gdb, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "old.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := migrations
+	migrations = all[:32]
+	err = migrate(gdb)
+	migrations = all
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Marked paid by hand with nothing recorded, marked paid with nothing due, and owing.
+	for i, b := range []map[string]any{
+		{"deposit_amount": 5000, "deposit_due_cents": 5000, "deposit_paid": true},
+		{"deposit_amount": 0, "deposit_due_cents": 0, "deposit_paid": true},
+		{"deposit_amount": 3000, "deposit_due_cents": 3000, "amount_paid_cents": 1000},
+	} {
+		b["name"], b["email"], b["phone"], b["date"], b["time"], b["duration"], b["guests"] =
+			"Customer", "c@example.com", "1", "2026-07-10", fmt.Sprintf("%d:00", 10+2*i), 2, 4
+		b["reference"] = fmt.Sprintf("MP-D%04d", i)
+		if err := gdb.Table("bookings").Create(b).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := migrate(gdb); err != nil {
+		t.Fatalf("upgrade: %v", err)
+	}
+	if gdb.Migrator().HasColumn("bookings", "deposit_amount") || gdb.Migrator().HasColumn("bookings_archive", "deposit_amount") {
+		t.Error("deposit_amount is still there")
+	}
+	var rows []struct {
+		AmountPaidCents int
+		DepositPaid     bool
+		PaymentMethod   string
+	}
+	if err := gdb.Table("bookings").Order("id").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	if rows[0].AmountPaidCents != 5000 || !rows[0].DepositPaid || rows[0].PaymentMethod != "other" || rows[1].DepositPaid ||
+		rows[2].AmountPaidCents != 1000 || rows[2].DepositPaid {
+		t.Errorf("bookings after the upgrade: %+v, want only the first paid, by a payment for it", rows)
+	}
+	var payments int64
+	if err := gdb.Table("payments").Where("amount_cents = ?", 5000).Count(&payments).Error; err != nil || payments != 1 {
+		t.Errorf("%d payments for the deposit marked paid (%v), want 1", payments, err)
+	}
+}
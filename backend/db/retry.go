@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	sqldriver "database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"miniparty-backend/metrics"
+
+	"gorm.io/gorm"
+)
+
+// RetryPolicy is how often and how patiently Retry and RetryTx try again.
+type RetryPolicy struct {
+	// Attempts is the total number of tries, the first included.
+	Attempts int
+	// BaseDelay is the wait before the second try; each later wait doubles, up to MaxDelay,
+	// and is jittered so callers that failed together don't retry together.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// Retries is the policy Retry and RetryTx use. Three tries with waits of roughly 50ms and
+// 100ms ride out a dropped connection or a busy SQLite file without a customer noticing.
+var Retries = RetryPolicy{Attempts: 3, BaseDelay: 50 * time.Millisecond, MaxDelay: time.Second}
+
+// Retry runs fn, trying again while it fails with a Retryable error. Use it for reads, and
+// for writes that are safe to repeat; a lone write that may have committed before its
+// connection dropped belongs in RetryTx instead.
+//
+// It never waits past ctx's deadline: when the next wait wouldn't fit, it returns the last error.
+func Retry(ctx context.Context, fn func() error) error {
+	return Retries.run(ctx, func() (bool, error) {
+		err := fn()
+		return Retryable(err), err
+	})
+}
+
+// RetryTx runs fn in a transaction on conn, retrying the whole transaction while it fails
+// with a Retryable error before COMMIT is sent. Those attempts were rolled back, so trying
+// again can't apply a write twice. A failed COMMIT is never retried: the database may have
+// committed it before the connection went, and only the caller can find out.
+func RetryTx(ctx context.Context, conn *gorm.DB, fn func(tx *gorm.DB) error) error {
+	return Retries.run(ctx, func() (bool, error) {
+		committing, err := transact(conn, fn)
+		return !committing && Retryable(err), err
+	})
+}
+
+// transact is gorm's Transaction, except it reports whether it got as far as COMMIT.
+func transact(conn *gorm.DB, fn func(tx *gorm.DB) error) (committing bool, err error) {
+	tx := conn.Begin()
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit().Error
+}
+
+// run calls try until it succeeds, fails for good, or the attempts or ctx's deadline run out.
+func (p RetryPolicy) run(ctx context.Context, try func() (retry bool, err error)) error {
+	delay := p.BaseDelay
+	for attempt := 1; ; attempt++ {
+		retry, err := try()
+		if err == nil || !retry || attempt >= p.Attempts {
+			return err
+		}
+
+		// Half the delay fixed and half random: spread out, but never retrying straight away.
+		wait := delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return err
+		}
+		metrics.DBRetries.Inc()
+		slog.WarnContext(ctx, "retrying database call", "attempt", attempt+1, "wait", wait.String(), "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		delay = min(delay*2, p.MaxDelay)
+	}
+}
+
+// Retryable reports whether err is a transient failure that may well succeed if tried again:
+// a dropped or refused connection, a Postgres connection error, serialization failure or
+// deadlock, or a busy or locked SQLite database. Errors about the query or the data, such as
+// a constraint violation or a missing row, are not, and neither is a cancelled context.
+func Retryable(err error) bool {
+	switch {
+	case err == nil,
+		errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, gorm.ErrDuplicatedKey),
+		errors.Is(err, gorm.ErrForeignKeyViolated), errors.Is(err, gorm.ErrCheckConstraintViolated):
+		return false
+	case errors.Is(err, sqldriver.ErrBadConn), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNABORTED), errors.Is(err, syscall.EPIPE):
+		return true
+	}
+
+	// Postgres (pgx) errors carry a SQLSTATE, and pgx marks errors raised before the query
+	// was sent as safe to retry.
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		state := pgErr.SQLState()
+		switch {
+		case strings.HasPrefix(state, "08"), // connection exception
+			state == "40001", // serialization_failure
+			state == "40P01", // deadlock_detected
+			state == "53300", // too_many_connections
+			state == "57P01": // admin_shutdown
+			return true
+		}
+		return false
+	}
+	var safe interface{ SafeToRetry() bool }
+	if errors.As(err, &safe) && safe.SafeToRetry() {
+		return true
+	}
+
+	// SQLite errors carry a result code; the low byte is the primary code.
+	var sqliteErr interface{ Code() int }
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() & 0xff {
+		case 5, 6: // SQLITE_BUSY, SQLITE_LOCKED
+			return true
+		}
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
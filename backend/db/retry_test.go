@@ -0,0 +1,128 @@
+package db
+
+import (
+	"context"
+	sqldriver "database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	"miniparty-backend/models"
+
+	"gorm.io/gorm"
+)
+
+type pgError string
+
+func (e pgError) Error() string    { return "pg error " + string(e) }
+func (e pgError) SQLState() string { return string(e) }
+
+type sqliteError int
+
+func (e sqliteError) Error() string { return fmt.Sprint("sqlite error ", int(e)) }
+func (e sqliteError) Code() int     { return int(e) }
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{sqldriver.ErrBadConn, true},
+		{fmt.Errorf("read: %w", io.ErrUnexpectedEOF), true},
+		{fmt.Errorf("write: %w", syscall.ECONNRESET), true},
+		{pgError("08006"), true},
+		{pgError("40001"), true},
+		{pgError("40P01"), true},
+		{pgError("23505"), false},
+		{sqliteError(5), true},
+		{sqliteError(5 | 2<<8), true}, // SQLITE_BUSY_SNAPSHOT
+		{sqliteError(19), false},
+		{context.Canceled, false},
+		{context.DeadlineExceeded, false},
+		{gorm.ErrRecordNotFound, false},
+		{gorm.ErrDuplicatedKey, false},
+		{errors.New("syntax error"), false},
+	}
+	for _, tt := range tests {
+		if got := Retryable(tt.err); got != tt.want {
+			t.Errorf("Retryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// fastRetries makes Retries wait a millisecond or two, for the length of the test.
+func fastRetries(t *testing.T) {
+	old := Retries
+	Retries = RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+	t.Cleanup(func() { Retries = old })
+}
+
+func TestRetry(t *testing.T) {
+	fastRetries(t)
+	ctx := context.Background()
+	calls := 0
+	err := Retry(ctx, func() error {
+		if calls++; calls < 3 {
+			return sqldriver.ErrBadConn
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("transient failures: err = %v after %d calls, want success on the third", err, calls)
+	}
+
+	calls = 0
+	err = Retry(ctx, func() error { calls++; return sqldriver.ErrBadConn })
+	if !errors.Is(err, sqldriver.ErrBadConn) || calls != Retries.Attempts {
+		t.Errorf("lasting failure: err = %v after %d calls, want the error after %d", err, calls, Retries.Attempts)
+	}
+
+	calls = 0
+	err = Retry(ctx, func() error { calls++; return gorm.ErrDuplicatedKey })
+	if !errors.Is(err, gorm.ErrDuplicatedKey) || calls != 1 {
+		t.Errorf("permanent failure: err = %v after %d calls, want it returned at once", err, calls)
+	}
+
+	// A wait that wouldn't fit before the deadline isn't started.
+	Retries.BaseDelay = time.Hour
+	short, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	calls = 0
+	start := time.Now()
+	err = Retry(short, func() error { calls++; return sqldriver.ErrBadConn })
+	if !errors.Is(err, sqldriver.ErrBadConn) || calls != 1 || time.Since(start) > 500*time.Millisecond {
+		t.Errorf("near the deadline: err = %v after %d calls in %v, want the error straight away", err, calls, time.Since(start))
+	}
+}
+
+func TestRetryTx(t *testing.T) {
+	testDB(t)
+	fastRetries(t)
+
+	calls := 0
+	err := RetryTx(context.Background(), DB, func(tx *gorm.DB) error {
+		calls++
+		if err := tx.Create(&models.Setting{Key: "retry_test", Value: fmt.Sprint(calls)}).Error; err != nil {
+			return err
+		}
+		if calls == 1 {
+			return sqliteError(5)
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Fatalf("err = %v after %d calls, want success on the second", err, calls)
+	}
+	// The first attempt was rolled back, so only the second's write is there.
+	var got []models.Setting
+	if err := DB.Find(&got, "key = ?", "retry_test").Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Value != "2" {
+		t.Errorf("settings %+v, want only the second attempt's", got)
+	}
+}
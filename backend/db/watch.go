@@ -0,0 +1,28 @@
+package db
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Written hears about every create, update, delete and raw statement GORM runs without
+// error, before any transaction it is in commits, so it suits dropping cached copies of the
+// data and nothing more. main sets it before Init, and it mustn't use the database.
+var Written func(stmt *gorm.Statement)
+
+// registerWatch passes gdb's writes on to Written.
+func registerWatch(gdb *gorm.DB) error {
+	written := func(tx *gorm.DB) {
+		if Written != nil && tx.Error == nil {
+			Written(tx.Statement)
+		}
+	}
+	cb := gdb.Callback()
+	return errors.Join(
+		cb.Create().After("*").Register("watch:written", written),
+		cb.Update().After("*").Register("watch:written", written),
+		cb.Delete().After("*").Register("watch:written", written),
+		cb.Raw().After("*").Register("watch:written", written),
+	)
+}
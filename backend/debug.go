@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"slices"
+	"time"
+
+	"miniparty-backend/db"
+	"miniparty-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerDebug mounts Go's profiler under /debug/pprof/ and a JSON snapshot of the runtime
+// at /debug/runtime, for working out where the memory went on a live instance:
+//
+//	curl -H "X-Admin-Token: $TOKEN" -o heap.pb.gz https://host/debug/pprof/heap
+//	go tool pprof -http=: heap.pb.gz
+//
+// main only calls it with DEBUG_ENDPOINTS=true, and even then it takes an admin token.
+// Viewer tokens are refused because a profile shows the code and data in memory.
+func registerDebug(r *gin.Engine) {
+	g := r.Group("/debug", middleware.AdminAuth(), middleware.RequireRole(middleware.RoleAdmin))
+	g.GET("/runtime", debugRuntime)
+	g.GET("/pprof/*profile", debugPprof)
+	g.POST("/pprof/symbol", gin.WrapF(pprof.Symbol))
+}
+
+// debugPprof serves the pprof index and every profile on it. The index and named profiles
+// such as heap and goroutine all go through pprof.Index, which reads the name off the path.
+func debugPprof(c *gin.Context) {
+	switch c.Param("profile") {
+	case "/cmdline":
+		pprof.Cmdline(c.Writer, c.Request)
+	case "/profile":
+		pprof.Profile(c.Writer, c.Request)
+	case "/symbol":
+		pprof.Symbol(c.Writer, c.Request)
+	case "/trace":
+		pprof.Trace(c.Writer, c.Request)
+	default:
+		pprof.Index(c.Writer, c.Request)
+	}
+}
+
+// debugRuntime reports goroutines, the heap, recent GC pauses and the database pool.
+// Reading the memory stats stops the world briefly, so it isn't something to poll.
+func debugRuntime(c *gin.Context) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	resp := gin.H{
+		"goroutines": runtime.NumGoroutine(),
+		"go_version": runtime.Version(),
+		"cpus":       runtime.NumCPU(),
+		"heap": gin.H{
+			"alloc_bytes":    mem.HeapAlloc,
+			"in_use_bytes":   mem.HeapInuse,
+			"idle_bytes":     mem.HeapIdle,
+			"released_bytes": mem.HeapReleased,
+			"objects":        mem.HeapObjects,
+			"sys_bytes":      mem.Sys,
+			"next_gc_bytes":  mem.NextGC,
+		},
+		"gc": gcSummary(&mem),
+	}
+	if db.Ready() {
+		if sqlDB, err := db.DB.DB(); err == nil {
+			resp["pool"] = poolStats(sqlDB.Stats())
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// gcSummary sums up the pauses runtime.MemStats still remembers, the last 256 at most.
+func gcSummary(mem *runtime.MemStats) gin.H {
+	recent := min(int(mem.NumGC), len(mem.PauseNs))
+	pauses := make([]time.Duration, 0, recent)
+	for i := 0; i < recent; i++ {
+		// PauseNs is a ring buffer with the latest pause at (NumGC+255)%256.
+		pauses = append(pauses, time.Duration(mem.PauseNs[(int(mem.NumGC)-1-i+len(mem.PauseNs))%len(mem.PauseNs)]))
+	}
+
+	summary := gin.H{
+		"cycles":         mem.NumGC,
+		"forced_cycles":  mem.NumForcedGC,
+		"pause_total_ms": ms(time.Duration(mem.PauseTotalNs)),
+		"cpu_fraction":   mem.GCCPUFraction,
+		"recent_pauses":  len(pauses),
+	}
+	if len(pauses) > 0 {
+		summary["last_pause_ms"] = ms(pauses[0])
+		summary["last_at"] = time.Unix(0, int64(mem.LastGC)).UTC()
+		slices.Sort(pauses)
+		summary["p50_pause_ms"] = ms(pauses[len(pauses)/2])
+		summary["p99_pause_ms"] = ms(pauses[len(pauses)*99/100])
+		summary["max_pause_ms"] = ms(pauses[len(pauses)-1])
+	}
+	return summary
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
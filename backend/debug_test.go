@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"miniparty-backend/config"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDebugEndpoints(t *testing.T) {
+	testDB(t)
+	middleware.ConfigureAuth(config.Auth{Secret: "admin-secret", Tokens: map[string]string{"viewer-token": middleware.RoleViewer},
+		MaxFailures: 1000, FailureWindow: time.Minute, Lockout: time.Minute})
+	r := gin.New()
+	r.Use(middleware.RequestLogger())
+	registerDebug(r)
+
+	get := func(target, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if token != "" {
+			req.Header.Set("X-Admin-Token", token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	for _, target := range []string{"/debug/runtime", "/debug/pprof/", "/debug/pprof/heap"} {
+		if w := get(target, ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("GET %s without a token = %d, want 401", target, w.Code)
+		}
+		if w := get(target, "viewer-token"); w.Code != http.StatusForbidden {
+			t.Errorf("GET %s as a viewer = %d, want 403", target, w.Code)
+		}
+	}
+
+	w := get("/debug/runtime", "admin-secret")
+	var snapshot struct {
+		Goroutines int                `json:"goroutines"`
+		GoVersion  string             `json:"go_version"`
+		Heap       map[string]float64 `json:"heap"`
+		GC         map[string]any     `json:"gc"`
+		Pool       map[string]any     `json:"pool"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &snapshot); w.Code != http.StatusOK || err != nil || snapshot.Goroutines == 0 ||
+		snapshot.GoVersion != runtime.Version() || snapshot.Heap["alloc_bytes"] == 0 || snapshot.GC["cycles"] == nil || snapshot.Pool == nil {
+		t.Errorf("GET /debug/runtime = %d %s, want goroutines, the heap, GC and the pool", w.Code, w.Body.String())
+	}
+	if w := get("/debug/pprof/", "admin-secret"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "goroutine") {
+		t.Errorf("GET /debug/pprof/ = %d, want the profile index", w.Code)
+	}
+	if w := get("/debug/pprof/goroutine?debug=1", "admin-secret"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "goroutine profile") {
+		t.Errorf("GET /debug/pprof/goroutine = %d, want the goroutine profile", w.Code)
+	}
+}
+
+func TestGCSummary(t *testing.T) {
+	var mem runtime.MemStats
+	if got := gcSummary(&mem); got["recent_pauses"] != 0 || got["last_pause_ms"] != nil {
+		t.Errorf("before any GC: %v, want no pauses", got)
+	}
+
+	// 258 cycles have wrapped the ring, leaving the latest pause at index 257%256.
+	mem.NumGC = 258
+	for i := range mem.PauseNs {
+		mem.PauseNs[i] = uint64(time.Millisecond)
+	}
+	mem.PauseNs[1] = uint64(5 * time.Millisecond)
+	got := gcSummary(&mem)
+	if got["recent_pauses"] != len(mem.PauseNs) || got["last_pause_ms"] != 5.0 || got["max_pause_ms"] != 5.0 || got["p50_pause_ms"] != 1.0 {
+		t.Errorf("gcSummary = %v, want 256 pauses, the last and longest 5ms", got)
+	}
+}
+
+func TestDebugEndpointsOff(t *testing.T) {
+	r := fallbackRouter(fstest.MapFS{"index.html": {Data: []byte(appIndex)}})
+	// Without DEBUG_ENDPOINTS a browser asking for a profile gets a 404, not the app.
+	w := fetch(r, http.MethodGet, "/debug/pprof/heap", "text/html")
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), `"code":"`+models.CodeNotFound+`"`) {
+		t.Errorf("GET /debug/pprof/heap = %d %s, want a %s envelope", w.Code, w.Body.String(), models.CodeNotFound)
+	}
+}
@@ -0,0 +1,19 @@
+package errreport
+
+import "miniparty-backend/config"
+
+// FromConfig builds the reporter for the configured destinations, Nop when there are none.
+func FromConfig(c config.ErrorReporting, release string) (Reporter, error) {
+	var reporters []Reporter
+	if c.SentryDSN != "" {
+		s, err := NewSentry(c.SentryDSN)
+		if err != nil {
+			return nil, err
+		}
+		reporters = append(reporters, s)
+	}
+	if c.WebhookURL != "" {
+		reporters = append(reporters, &Webhook{URL: c.WebhookURL})
+	}
+	return NewAsync(release, c.PerMinute, reporters...), nil
+}
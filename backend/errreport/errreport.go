@@ -0,0 +1,142 @@
+// Package errreport sends panics and server errors to somewhere people will see them: a
+// Sentry project, or anything that takes Sentry's events, and a plain JSON webhook.
+// Reporting never holds up a request; events are queued, sent in the background and,
+// past a rate limit, dropped, so an outage that fails every request doesn't flood the
+// destination as well.
+package errreport
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Levels of an event, as Sentry names them.
+const (
+	LevelError = "error"
+	// LevelFatal is a panic: the request was abandoned partway through.
+	LevelFatal = "fatal"
+)
+
+// Event is one error to report.
+type Event struct {
+	Level     string    `json:"level"`
+	Message   string    `json:"message"`
+	Stack     string    `json:"stack,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	Method    string    `json:"method,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	Time      time.Time `json:"time"`
+	// Release is the running build, from the version main was stamped with.
+	Release string `json:"release,omitempty"`
+}
+
+// Reporter delivers an event to one destination.
+type Reporter interface {
+	Report(ctx context.Context, e Event) error
+}
+
+// Nop is the Reporter used when nothing is configured: events are only logged.
+type Nop struct{}
+
+func (Nop) Report(context.Context, Event) error { return nil }
+
+// queueSize is how many events can wait to be sent before new ones are dropped.
+const queueSize = 64
+
+// Async sends events to its reporters from a background goroutine, at most perMinute
+// of them a minute.
+type Async struct {
+	reporters []Reporter
+	release   string
+	queue     chan Event
+	timeout   time.Duration
+
+	mu        sync.Mutex
+	tokens    float64
+	perMinute float64
+	last      time.Time
+	dropped   int
+}
+
+// NewAsync starts sending events to reporters, stamping them with release. It returns
+// Nop when there are no reporters.
+func NewAsync(release string, perMinute int, reporters ...Reporter) Reporter {
+	if len(reporters) == 0 {
+		return Nop{}
+	}
+	a := &Async{
+		reporters: reporters,
+		release:   release,
+		queue:     make(chan Event, queueSize),
+		timeout:   10 * time.Second,
+		tokens:    float64(perMinute),
+		perMinute: float64(perMinute),
+		last:      time.Now(),
+	}
+	go a.run()
+	return a
+}
+
+// Report queues e and returns at once. It never fails; an event over the rate limit, or
+// that finds the queue full, is counted and dropped.
+func (a *Async) Report(_ context.Context, e Event) error {
+	if e.Release == "" {
+		e.Release = a.release
+	}
+	if !a.allow() {
+		return nil
+	}
+	select {
+	case a.queue <- e:
+	default:
+		a.drop()
+	}
+	return nil
+}
+
+// allow takes a token from the bucket, which refills at perMinute a minute.
+func (a *Async) allow() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	a.tokens = min(a.perMinute, a.tokens+now.Sub(a.last).Minutes()*a.perMinute)
+	a.last = now
+	if a.tokens < 1 {
+		a.dropped++
+		return false
+	}
+	a.tokens--
+	return true
+}
+
+func (a *Async) drop() {
+	a.mu.Lock()
+	a.dropped++
+	a.mu.Unlock()
+}
+
+// takeDropped returns how many events were dropped since it was last called.
+func (a *Async) takeDropped() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	n := a.dropped
+	a.dropped = 0
+	return n
+}
+
+func (a *Async) run() {
+	for e := range a.queue {
+		if n := a.takeDropped(); n > 0 {
+			slog.Warn("error reports dropped over the rate limit", "events", n)
+		}
+		for _, r := range a.reporters {
+			ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+			if err := r.Report(ctx, e); err != nil {
+				slog.Warn("failed to report error", "error", err, "request_id", e.RequestID)
+			}
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package errreport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"miniparty-backend/config"
+)
+
+// collector hands the events it is sent to the test, and fails them while fail is set.
+type collector struct {
+	events chan Event
+	fail   bool
+}
+
+func newCollector() *collector { return &collector{events: make(chan Event, queueSize)} }
+
+func (c *collector) Report(_ context.Context, e Event) error {
+	c.events <- e
+	if c.fail {
+		return errors.New("destination down")
+	}
+	return nil
+}
+
+// next waits for the collector's next event.
+func (c *collector) next(t *testing.T) Event {
+	t.Helper()
+	select {
+	case e := <-c.events:
+		return e
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event reported")
+		return Event{}
+	}
+}
+
+func TestAsync(t *testing.T) {
+	if _, ok := NewAsync("v1", 10).(Nop); !ok {
+		t.Error("NewAsync without reporters isn't Nop")
+	}
+
+	a, b := newCollector(), newCollector()
+	a.fail = true
+	r := NewAsync("v1.2.3", 10, a, b)
+	if err := r.Report(context.Background(), Event{Level: LevelError, Message: "boom"}); err != nil {
+		t.Fatal(err)
+	}
+	// A failing destination doesn't keep the event from the others.
+	for _, c := range []*collector{a, b} {
+		if e := c.next(t); e.Message != "boom" || e.Release != "v1.2.3" {
+			t.Errorf("reported %+v, want the event stamped with the release", e)
+		}
+	}
+	_ = r.Report(context.Background(), Event{Message: "own release", Release: "v0"})
+	if e := b.next(t); e.Release != "v0" {
+		t.Errorf("release %q, want the event's own kept", e.Release)
+	}
+}
+
+// droppedLog collects what the default logger writes until the test ends, and sums the
+// events its warnings say were dropped.
+type droppedLog struct{ bytes.Buffer }
+
+func logDropped(t *testing.T) *droppedLog {
+	l := &droppedLog{}
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(l, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return l
+}
+
+func (l *droppedLog) total(t *testing.T) int {
+	t.Helper()
+	n := 0
+	for _, line := range strings.Split(strings.TrimSpace(l.String()), "\n") {
+		var record struct {
+			Msg    string `json:"msg"`
+			Events int    `json:"events"`
+		}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		if record.Msg == "error reports dropped over the rate limit" {
+			n += record.Events
+		}
+	}
+	return n
+}
+
+func TestAsyncRateLimit(t *testing.T) {
+	dropped := logDropped(t)
+	c := newCollector()
+	r := NewAsync("", 3, c).(*Async)
+	for i := 0; i < 10; i++ {
+		_ = r.Report(context.Background(), Event{Message: "flood"})
+	}
+	for i := 0; i < 3; i++ {
+		c.next(t)
+	}
+	select {
+	case e := <-c.events:
+		t.Errorf("reported %+v past the limit of 3 a minute", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// The bucket refills at the rate: a third of a minute is one more event's worth.
+	r.mu.Lock()
+	r.last = r.last.Add(-20 * time.Second)
+	r.mu.Unlock()
+	_ = r.Report(context.Background(), Event{Message: "later"})
+	if e := c.next(t); e.Message != "later" {
+		t.Errorf("after refilling: %+v", e)
+	}
+	// Every drop is logged by the time the next event that got through is sent.
+	if n := dropped.total(t); n != 7 {
+		t.Errorf("%d dropped, want 7", n)
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	if r, err := FromConfig(config.ErrorReporting{PerMinute: 10}, "v1"); err != nil {
+		t.Fatal(err)
+	} else if _, ok := r.(Nop); !ok {
+		t.Errorf("nothing configured: %T, want Nop", r)
+	}
+	r, err := FromConfig(config.ErrorReporting{SentryDSN: "https://key@sentry.example/42", WebhookURL: "https://hooks.example/errors", PerMinute: 10}, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, ok := r.(*Async); !ok || len(a.reporters) != 2 {
+		t.Errorf("both configured: %#v, want an Async sending to both", r)
+	}
+	if _, err := FromConfig(config.ErrorReporting{SentryDSN: "https://sentry.example/42"}, "v1"); err == nil {
+		t.Error("a DSN without a key was accepted")
+	}
+}
@@ -0,0 +1,114 @@
+package errreport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Sentry sends events to a Sentry project through its store endpoint. Self-hosted Sentry
+// and services that speak its protocol, such as GlitchTip, take them too.
+type Sentry struct {
+	endpoint string
+	key      string
+	Client   *http.Client
+}
+
+// NewSentry reads dsn, "https://<key>@<host>[/<path>]/<project>".
+func NewSentry(dsn string) (*Sentry, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Sentry DSN: %w", err)
+	}
+	path := strings.Trim(u.Path, "/")
+	key := u.User.Username()
+	if u.Host == "" || key == "" || path == "" {
+		return nil, fmt.Errorf("invalid Sentry DSN: want https://<key>@<host>/<project>")
+	}
+	prefix, project := "", path
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		prefix, project = "/"+path[:i], path[i+1:]
+	}
+	return &Sentry{
+		endpoint: fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, prefix, project),
+		key:      key,
+	}, nil
+}
+
+// sentryEvent is the part of Sentry's event payload an Event fills in.
+type sentryEvent struct {
+	EventID   string            `json:"event_id"`
+	Timestamp string            `json:"timestamp"`
+	Level     string            `json:"level"`
+	Platform  string            `json:"platform"`
+	Logger    string            `json:"logger"`
+	Message   string            `json:"message"`
+	Release   string            `json:"release,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Request   *sentryRequest    `json:"request,omitempty"`
+	Extra     map[string]string `json:"extra,omitempty"`
+}
+
+type sentryRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+func (s *Sentry) Report(ctx context.Context, e Event) error {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	payload := sentryEvent{
+		EventID:   hex.EncodeToString(id),
+		Timestamp: e.Time.UTC().Format("2006-01-02T15:04:05.000Z"),
+		Level:     e.Level,
+		Platform:  "go",
+		Logger:    "miniparty",
+		Message:   e.Message,
+		Release:   e.Release,
+	}
+	if e.RequestID != "" {
+		payload.Tags = map[string]string{"request_id": e.RequestID}
+	}
+	if e.Path != "" {
+		payload.Request = &sentryRequest{Method: e.Method, URL: e.Path}
+	}
+	if e.Stack != "" {
+		payload.Extra = map[string]string{"stack": e.Stack}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", "Sentry sentry_version=7, sentry_client=miniparty/1.0, sentry_key="+s.key)
+	return send(s.Client, req, "Sentry")
+}
+
+// send makes req and turns anything but a 2xx into an error naming the destination.
+func send(client *http.Client, req *http.Request, name string) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", name, resp.Status)
+	}
+	return nil
+}
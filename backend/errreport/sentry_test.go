@@ -0,0 +1,107 @@
+package errreport
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewSentry(t *testing.T) {
+	tests := []struct {
+		dsn, endpoint string // endpoint "" when dsn is rejected
+	}{
+		{"https://abc123@o1.ingest.sentry.io/42", "https://o1.ingest.sentry.io/api/42/store/"},
+		{"http://abc123@glitchtip.local:8000/errors/7", "http://glitchtip.local:8000/errors/api/7/store/"},
+		{"https://o1.ingest.sentry.io/42", ""},
+		{"https://abc123@o1.ingest.sentry.io/", ""},
+		{"abc123@/42", ""},
+	}
+	for _, tt := range tests {
+		s, err := NewSentry(tt.dsn)
+		switch {
+		case tt.endpoint == "" && err == nil:
+			t.Errorf("NewSentry(%q) = %s, want an error", tt.dsn, s.endpoint)
+		case tt.endpoint != "" && (err != nil || s.endpoint != tt.endpoint || s.key != "abc123"):
+			t.Errorf("NewSentry(%q) = %+v, %v; want %s with key abc123", tt.dsn, s, err, tt.endpoint)
+		}
+	}
+}
+
+// destination records the requests, up to a few, that an httptest server gets, answering
+// with status.
+func destination(t *testing.T, status int) (*httptest.Server, chan *http.Request, chan []byte) {
+	t.Helper()
+	reqs, bodies := make(chan *http.Request, 4), make(chan []byte, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		reqs <- r
+		bodies <- body
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, reqs, bodies
+}
+
+var panicEvent = Event{
+	Level: LevelFatal, Message: "nil booking", Stack: "goroutine 1 [running]:", RequestID: "req-1",
+	Method: http.MethodPost, Path: "/book", Time: time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC), Release: "v1.2.3",
+}
+
+func TestSentryReport(t *testing.T) {
+	srv, reqs, bodies := destination(t, http.StatusOK)
+	s, err := NewSentry(strings.Replace(srv.URL, "://", "://abc123@", 1) + "/42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Report(context.Background(), panicEvent); err != nil {
+		t.Fatal(err)
+	}
+
+	req := <-reqs
+	if req.URL.Path != "/api/42/store/" || !strings.Contains(req.Header.Get("X-Sentry-Auth"), "sentry_key=abc123") {
+		t.Errorf("posted to %s with auth %q", req.URL.Path, req.Header.Get("X-Sentry-Auth"))
+	}
+	var got sentryEvent
+	if err := json.Unmarshal(<-bodies, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.EventID) != 32 || got.Timestamp != "2026-07-01T09:00:00.000Z" || got.Level != LevelFatal || got.Message != "nil booking" ||
+		got.Release != "v1.2.3" || got.Tags["request_id"] != "req-1" || got.Request == nil || got.Request.URL != "/book" ||
+		got.Extra["stack"] != panicEvent.Stack {
+		t.Errorf("sent %+v", got)
+	}
+}
+
+func TestReportFailures(t *testing.T) {
+	srv, _, _ := destination(t, http.StatusTooManyRequests)
+	s, err := NewSentry(strings.Replace(srv.URL, "://", "://abc123@", 1) + "/42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Report(context.Background(), panicEvent); err == nil || !strings.Contains(err.Error(), "Sentry returned 429") {
+		t.Errorf("Sentry answering 429: %v", err)
+	}
+	w := &Webhook{URL: srv.URL}
+	if err := w.Report(context.Background(), panicEvent); err == nil || !strings.Contains(err.Error(), "error webhook") {
+		t.Errorf("webhook answering 429: %v", err)
+	}
+}
+
+func TestWebhookReport(t *testing.T) {
+	srv, reqs, bodies := destination(t, http.StatusNoContent)
+	if err := (&Webhook{URL: srv.URL}).Report(context.Background(), panicEvent); err != nil {
+		t.Fatal(err)
+	}
+	if req := <-reqs; req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("%s with %q", req.Method, req.Header.Get("Content-Type"))
+	}
+	var got Event
+	if err := json.Unmarshal(<-bodies, &got); err != nil || got != panicEvent {
+		t.Errorf("sent %+v (%v), want the event as it is", got, err)
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerFallbacks answers requests that match no route. API clients always get JSON,
+// 404 for an unknown path and 405 for a known path with the wrong method. Anything else
+// is served from files, the React build, when there is one (see frontendFS).
+func registerFallbacks(r *gin.Engine, files fs.FS) {
+	hasFrontend := files != nil
+
+	r.HandleMethodNotAllowed = true
+
+	r.NoRoute(func(c *gin.Context) {
+		if !hasFrontend || !isRead(c) || wantsJSON(c) {
+			middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Not found")
+			return
+		}
+		serveFrontend(c, files)
+	})
+
+	r.NoMethod(func(c *gin.Context) {
+		// SPA routes like /book share paths with the old unversioned API, so a browser
+		// navigating there still gets the app rather than a 405.
+		if hasFrontend && isRead(c) && !wantsJSON(c) {
+			c.Writer.Header().Del("Allow")
+			serveFrontend(c, files)
+			return
+		}
+		middleware.Fail(c, http.StatusMethodNotAllowed, models.CodeMethodNotAllowed, "Method not allowed")
+	})
+}
+
+// frontendFS returns the React build to serve: the directory at distPath when there is
+// one, so a fresh local build can be tried without recompiling, or else the copy embedded
+// with the embedfrontend build tag. It is nil when there is neither.
+func frontendFS(distPath string) fs.FS {
+	if info, err := os.Stat(distPath); err == nil && info.IsDir() {
+		log.Println("Serving frontend from", distPath)
+		return os.DirFS(distPath)
+	}
+	if embeddedFrontend != nil {
+		log.Println("Serving the frontend embedded in the binary")
+		return embeddedFrontend
+	}
+	return nil
+}
+
+// pageNavigations serves the app to browsers navigating to a path the old unversioned API
+// also has, such as /admin/settings, and passes everything else on to the API. Browsers
+// put text/html in the Accept header of a navigation and API clients don't, so this is
+// stricter than wantsJSON, which a client sending no Accept header would fail.
+func pageNavigations(files fs.FS) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if files == nil || !isRead(c) || !strings.Contains(c.GetHeader("Accept"), gin.MIMEHTML) || wantsJSON(c) {
+			c.Next()
+			return
+		}
+		serveFrontend(c, files)
+		c.Abort()
+	}
+}
+
+// isRead reports whether the request could be a browser loading a page or asset.
+func isRead(c *gin.Context) bool {
+	return c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead
+}
+
+// wantsJSON reports whether the request is for the API: an /api/ or /debug/ path, or an
+// Accept header that prefers JSON over HTML. /debug/ gets a 404 rather than the app when
+// the debug endpoints are off, so a profile request never saves index.html.
+func wantsJSON(c *gin.Context) bool {
+	if p := c.Request.URL.Path; strings.HasPrefix(p, "/api/") || strings.HasPrefix(p, "/debug/") {
+		return true
+	}
+	return c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON
+}
+
+// Vite fingerprints everything under /assets/, so those files never change once built.
+const (
+	assetsCacheControl = "public, max-age=31536000, immutable"
+	indexCacheControl  = "no-cache"
+)
+
+func init() {
+	// The system mime.types isn't always present in slim containers; pin the types the build ships.
+	for ext, typ := range map[string]string{
+		".js":    "text/javascript; charset=utf-8",
+		".css":   "text/css; charset=utf-8",
+		".svg":   "image/svg+xml",
+		".woff2": "font/woff2",
+	} {
+		mime.AddExtensionType(ext, typ)
+	}
+}
+
+// serveFrontend serves a file from the React build, falling back to index.html so
+// React Router can handle client-side routes. Paths with a ".." segment are refused
+// rather than cleaned, and dotfiles are never served.
+func serveFrontend(c *gin.Context, files fs.FS) {
+	if escapesRoot(c.Request.URL.Path) {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "Invalid path")
+		return
+	}
+	urlPath := path.Clean("/" + c.Request.URL.Path)
+	name := strings.TrimPrefix(urlPath, "/")
+	if hidden(name) {
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Not found")
+		return
+	}
+
+	// Try to serve the static file directly (JS, CSS, images, etc.)
+	if name != "" {
+		if info, err := fs.Stat(files, name); err == nil && info.Mode().IsRegular() {
+			cacheControl := ""
+			switch {
+			case strings.HasPrefix(urlPath, "/assets/"):
+				cacheControl = assetsCacheControl
+			case path.Base(name) == "index.html":
+				cacheControl = indexCacheControl
+			}
+			serveStatic(c, files, name, cacheControl)
+			return
+		}
+	}
+
+	// A missing bundle means a stale index.html; answering with HTML would only break it further.
+	if strings.HasPrefix(urlPath, "/assets/") {
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Not found")
+		return
+	}
+
+	// SPA fallback: serve index.html for all other paths (React Router handles routing)
+	serveStatic(c, files, "index.html", indexCacheControl)
+}
+
+// escapesRoot reports whether p has a ".." segment, which nothing the app links to does.
+// Cleaning would keep such a path inside the build, but a request for one is a probe.
+func escapesRoot(p string) bool {
+	for _, seg := range strings.FieldsFunc(p, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if seg == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// hidden reports whether a segment of name starts with a dot, like .env or .git/config.
+func hidden(name string) bool {
+	for _, seg := range strings.Split(name, "/") {
+		if strings.HasPrefix(seg, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// embeddedETags caches the content hashes of embedded files, which can't change while the
+// binary runs.
+var embeddedETags sync.Map
+
+// serveStatic sends a file with a weak ETag, so repeat requests with If-None-Match or
+// If-Modified-Since get a 304. Files on disk are tagged by size and modification time;
+// embedded ones have no modification time, so by a hash of their content.
+func serveStatic(c *gin.Context, files fs.FS, name, cacheControl string) {
+	f, err := files.Open(name)
+	if err != nil {
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Not found")
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Not found")
+		return
+	}
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Not found")
+		return
+	}
+
+	etag := fmt.Sprintf(`W/"%x-%x"`, info.Size(), info.ModTime().UnixNano())
+	if info.ModTime().IsZero() {
+		etag = embeddedETag(name, content)
+	}
+	if etag != "" {
+		c.Header("ETag", etag)
+	}
+	if cacheControl != "" {
+		c.Header("Cache-Control", cacheControl)
+	}
+	http.ServeContent(c.Writer, c.Request, info.Name(), info.ModTime(), content)
+}
+
+// embeddedETag returns the ETag for the embedded file name, hashing content the first
+// time. It is empty if the file can't be read, which leaves the response without one.
+func embeddedETag(name string, content io.ReadSeeker) string {
+	if tag, ok := embeddedETags.Load(name); ok {
+		return tag.(string)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, content); err != nil {
+		return ""
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return ""
+	}
+	tag := fmt.Sprintf(`W/"%x"`, h.Sum(nil)[:12])
+	embeddedETags.Store(name, tag)
+	return tag
+}
@@ -0,0 +1,24 @@
+//go:build embedfrontend
+
+package main
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// dist is the React build, copied into backend/dist before a build with
+// -tags embedfrontend (the Dockerfile does this). Files starting with "." or "_" are left
+// out, as go:embed does for directories by default.
+//
+//go:embed dist
+var dist embed.FS
+
+// embeddedFrontend is the build in the binary, rooted at its index.html.
+var embeddedFrontend = func() fs.FS {
+	sub, err := fs.Sub(dist, "dist")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}()
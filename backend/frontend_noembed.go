@@ -0,0 +1,9 @@
+//go:build !embedfrontend
+
+package main
+
+import "io/fs"
+
+// embeddedFrontend is nil without the embedfrontend build tag: the frontend is only
+// served from DIST_PATH.
+var embeddedFrontend fs.FS
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"miniparty-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// appIndex is the React build's index.html, as the tests' frontend serves it.
+const appIndex = "<html>app</html>"
+
+// fallbackRouter has POST /book and GET /bookings as its only routes and the fallbacks
+// over files.
+func fallbackRouter(files fs.FS) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/book", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	r.GET("/bookings", func(c *gin.Context) { c.Status(http.StatusOK) })
+	registerFallbacks(r, files)
+	return r
+}
+
+// fetch serves method target to r with the Accept header accept, if any.
+func fetch(r http.Handler, method, target, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestFallbacks(t *testing.T) {
+	const browser = "text/html,application/xhtml+xml,*/*;q=0.8"
+	app := fallbackRouter(fstest.MapFS{"index.html": {Data: []byte(appIndex)}})
+
+	tests := []struct {
+		method, target, accept string
+		status                 int
+		code                   string // empty when the app is served
+	}{
+		{http.MethodGet, "/admin", browser, http.StatusOK, ""},
+		{http.MethodGet, "/book", browser, http.StatusOK, ""},
+		{http.MethodHead, "/admin", browser, http.StatusOK, ""},
+		{http.MethodGet, "/nowhere", "application/json", http.StatusNotFound, models.CodeNotFound},
+		{http.MethodGet, "/api/v1/nowhere", browser, http.StatusNotFound, models.CodeNotFound},
+		{http.MethodPost, "/nowhere", browser, http.StatusNotFound, models.CodeNotFound},
+		{http.MethodGet, "/book", "application/json", http.StatusMethodNotAllowed, models.CodeMethodNotAllowed},
+		{http.MethodDelete, "/bookings", browser, http.StatusMethodNotAllowed, models.CodeMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		w := fetch(app, tt.method, tt.target, tt.accept)
+		if w.Code != tt.status {
+			t.Errorf("%s %s (%s) = %d, want %d", tt.method, tt.target, tt.accept, w.Code, tt.status)
+			continue
+		}
+		gotApp := strings.HasPrefix(w.Header().Get("Content-Type"), "text/html")
+		if tt.code == "" && (!gotApp || (tt.method == http.MethodGet && w.Body.String() != appIndex)) {
+			t.Errorf("%s %s = %s %q, want the app", tt.method, tt.target, w.Header().Get("Content-Type"), w.Body.String())
+		}
+		if tt.code != "" && !strings.Contains(w.Body.String(), `"code":"`+tt.code+`"`) {
+			t.Errorf("%s %s = %s, want a %s envelope", tt.method, tt.target, w.Body.String(), tt.code)
+		}
+	}
+	if allow := fetch(app, http.MethodDelete, "/bookings", "").Header().Get("Allow"); allow != http.MethodGet {
+		t.Errorf("405 Allow = %q, want GET", allow)
+	}
+
+	// Without a build every unmatched request is the API's.
+	bare := fallbackRouter(nil)
+	if w := fetch(bare, http.MethodGet, "/admin", browser); w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), models.CodeNotFound) {
+		t.Errorf("GET /admin without a build = %d %s, want a JSON 404", w.Code, w.Body.String())
+	}
+}
+
+func TestStaticCaching(t *testing.T) {
+	built := time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)
+	app := fallbackRouter(fstest.MapFS{
+		"index.html":        {Data: []byte(appIndex), ModTime: built},
+		"assets/app-123.js": {Data: []byte("console.log(1)"), ModTime: built},
+		"favicon.svg":       {Data: []byte("<svg/>"), ModTime: built},
+	})
+	const browser = "text/html,*/*;q=0.8"
+
+	tests := []struct {
+		target, cacheControl, contentType string
+	}{
+		{"/assets/app-123.js", assetsCacheControl, "text/javascript; charset=utf-8"},
+		{"/index.html", indexCacheControl, "text/html; charset=utf-8"},
+		{"/admin/settings", indexCacheControl, "text/html; charset=utf-8"},
+		{"/favicon.svg", "", "image/svg+xml"},
+	}
+	for _, tt := range tests {
+		w := fetch(app, http.MethodGet, tt.target, browser)
+		if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != tt.cacheControl || w.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("GET %s = %d, Cache-Control %q, Content-Type %q, want %q and %q", tt.target, w.Code,
+				w.Header().Get("Cache-Control"), w.Header().Get("Content-Type"), tt.cacheControl, tt.contentType)
+		}
+		etag := w.Header().Get("ETag")
+		if !strings.HasPrefix(etag, `W/"`) {
+			t.Errorf("GET %s: ETag %q, want a weak one", tt.target, etag)
+			continue
+		}
+		req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		req.Header.Set("Accept", browser)
+		req.Header.Set("If-None-Match", etag)
+		again := httptest.NewRecorder()
+		app.ServeHTTP(again, req)
+		if again.Code != http.StatusNotModified || again.Body.Len() != 0 {
+			t.Errorf("GET %s with its ETag = %d, want 304", tt.target, again.Code)
+		}
+	}
+
+	// Embedded files have no modification time, so their tag is a hash of the content.
+	embedded := fallbackRouter(fstest.MapFS{"index.html": {Data: []byte(appIndex)}})
+	first := fetch(embedded, http.MethodGet, "/", browser).Header().Get("ETag")
+	if first == "" || first != fetch(embedded, http.MethodGet, "/book", browser).Header().Get("ETag") {
+		t.Errorf("embedded index.html ETag %q, want the same tag each time", first)
+	}
+}
+
+func TestFrontendRefusesProbes(t *testing.T) {
+	app := fallbackRouter(fstest.MapFS{
+		"index.html":         {Data: []byte(appIndex)},
+		"assets/app-123.js":  {Data: []byte("console.log(1)")},
+		".env":               {Data: []byte("ADMIN_SECRET=hunter2")},
+		"assets/.git/config": {Data: []byte("[core]")},
+		"..data/leak.txt":    {Data: []byte("ADMIN_SECRET=hunter2")},
+	})
+	const browser = "text/html,*/*;q=0.8"
+
+	tests := []struct {
+		target string
+		status int
+		code   string
+	}{
+		// Traversal, however it is spelled, is refused outright.
+		{"/../etc/passwd", http.StatusBadRequest, models.CodeBadRequest},
+		{"/assets/../../etc/passwd", http.StatusBadRequest, models.CodeBadRequest},
+		{"/%2e%2e/etc/passwd", http.StatusBadRequest, models.CodeBadRequest},
+		{"/assets/%2E%2E%2f%2E%2E%2fetc/passwd", http.StatusBadRequest, models.CodeBadRequest},
+		{`/..%5c..%5cwindows/win.ini`, http.StatusBadRequest, models.CodeBadRequest},
+		{"/assets/..", http.StatusBadRequest, models.CodeBadRequest},
+		// Dotfiles and anything under a dot directory are never served, not even the app.
+		{"/.env", http.StatusNotFound, models.CodeNotFound},
+		{"/%2eenv", http.StatusNotFound, models.CodeNotFound},
+		{"/assets/.git/config", http.StatusNotFound, models.CodeNotFound},
+		{"/.well-known/security.txt", http.StatusNotFound, models.CodeNotFound},
+		{"/..data/leak.txt", http.StatusNotFound, models.CodeNotFound},
+	}
+	for _, tt := range tests {
+		w := fetch(app, http.MethodGet, tt.target, browser)
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), `"code":"`+tt.code+`"`) {
+			t.Errorf("GET %s = %d %s, want %d %s", tt.target, w.Code, w.Body.String(), tt.status, tt.code)
+		}
+		if body := w.Body.String(); strings.Contains(body, "hunter2") || strings.Contains(body, "[core]") || strings.Contains(body, appIndex) {
+			t.Errorf("GET %s served %q", tt.target, body)
+		}
+	}
+
+	// Dots inside a name are fine.
+	if w := fetch(app, http.MethodGet, "/assets/app-123.js", browser); w.Code != http.StatusOK {
+		t.Errorf("GET /assets/app-123.js = %d, want the file", w.Code)
+	}
+	if w := fetch(app, http.MethodGet, "/book../x", browser); w.Code != http.StatusOK || w.Body.String() != appIndex {
+		t.Errorf("GET /book../x = %d, want the app", w.Code)
+	}
+}
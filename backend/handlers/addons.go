@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"miniparty-backend/messages"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type addonRequest struct {
+	Name       string `json:"name"`
+	PriceCents int    `json:"price_cents"`
+	Active     *bool  `json:"active"`
+}
+
+// apply validates req and copies it onto a. Active defaults to true for new add-ons and is
+// left alone on updates that don't mention it.
+func (req addonRequest) apply(a *models.Addon) fieldErrors {
+	errs := fieldErrors{}
+	a.Name = strings.TrimSpace(req.Name)
+	a.PriceCents = req.PriceCents
+	if req.Active != nil {
+		a.Active = *req.Active
+	}
+
+	if a.Name == "" {
+		errs.add("name", messages.NameRequired)
+	}
+	if a.PriceCents < 0 {
+		errs.add("price_cents", messages.PriceNegative)
+	}
+	return errs
+}
+
+// GetAddons lists the active add-ons for the booking form.
+func GetAddons(c *gin.Context) {
+	addons := []models.Addon{}
+	if err := conn(c).Where("active = ?", true).Order("name ASC").Find(&addons).Error; err != nil {
+		serverError(c, err, "Failed to fetch add-ons")
+		return
+	}
+
+	c.JSON(http.StatusOK, addons)
+}
+
+// GetAllAddons lists every add-on, active or not, for the admin.
+func GetAllAddons(c *gin.Context) {
+	addons := []models.Addon{}
+	if err := conn(c).Order("id ASC").Find(&addons).Error; err != nil {
+		serverError(c, err, "Failed to fetch add-ons")
+		return
+	}
+
+	c.JSON(http.StatusOK, addons)
+}
+
+func CreateAddon(c *gin.Context) {
+	var req addonRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	addon := models.Addon{Active: true}
+	if errs := req.apply(&addon); len(errs) > 0 {
+		badFields(c, errs)
+		return
+	}
+
+	if err := conn(c).Create(&addon).Error; err != nil {
+		addonSaveError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, addon)
+}
+
+// UpdateAddon replaces an add-on's details. Bookings that already include it keep the name
+// and price they were made with.
+func UpdateAddon(c *gin.Context) {
+	id, ok := addonID(c)
+	if !ok {
+		return
+	}
+
+	var req addonRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	var addon models.Addon
+	if err := conn(c).First(&addon, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Add-on not found")
+			return
+		}
+		serverError(c, err, "Failed to fetch add-on")
+		return
+	}
+
+	if errs := req.apply(&addon); len(errs) > 0 {
+		badFields(c, errs)
+		return
+	}
+
+	// Naming the columns keeps Save from inserting the add-on again if it was deleted
+	// since it was read; that is a 404 instead.
+	result := conn(c).Select("*").Save(&addon)
+	if result.Error != nil {
+		addonSaveError(c, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Add-on not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, addon)
+}
+
+// DeleteAddon removes an add-on from the catalogue. Bookings that include it keep their
+// copy of its name and price.
+func DeleteAddon(c *gin.Context) {
+	id, ok := addonID(c)
+	if !ok {
+		return
+	}
+
+	result := conn(c).Delete(&models.Addon{}, id)
+	if result.Error != nil {
+		serverError(c, result.Error, "Failed to delete add-on")
+		return
+	}
+	if result.RowsAffected == 0 {
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Add-on not found")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// addonID parses the :id path parameter, writing a 400 and returning false if it isn't a positive integer.
+func addonID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil || id == 0 {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "Invalid add-on ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func addonSaveError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		middleware.Fail(c, http.StatusConflict, models.CodeNameTaken, "An add-on with this name already exists")
+		return
+	}
+	serverError(c, err, "Failed to save add-on")
+}
+
+// selectAddons looks up the requested add-on IDs in one query and returns them as booking
+// add-ons, with any that don't exist or aren't offered any more reported as errors.
+// Repeated IDs are ordered once.
+func selectAddons(tx *gorm.DB, ids []uint) ([]models.BookingAddon, fieldErrors, error) {
+	errs := fieldErrors{}
+	if len(ids) == 0 {
+		return nil, errs, nil
+	}
+
+	var found []models.Addon
+	if err := tx.Where("id IN ?", ids).Find(&found).Error; err != nil {
+		return nil, errs, err
+	}
+	byID := make(map[uint]models.Addon, len(found))
+	for _, a := range found {
+		byID[a.ID] = a
+	}
+
+	var selected []models.BookingAddon
+	seen := map[uint]bool{}
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		addon, ok := byID[id]
+		if !ok || !addon.Active {
+			errs.add("addon_ids", messages.AddonUnavailable, "id", id)
+			continue
+		}
+		selected = append(selected, models.BookingAddon{AddonID: addon.ID, Name: addon.Name, PriceCents: addon.PriceCents})
+	}
+	return selected, errs, nil
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"miniparty-backend/models"
+)
+
+func TestMissingAddonWrites(t *testing.T) {
+	testDB(t)
+	r := newRouter()
+	r.PUT("/addons/:id", UpdateAddon)
+	r.DELETE("/addons/:id", DeleteAddon)
+	r.POST("/addons", CreateAddon)
+	body := `{"name": "Balloons", "price_cents": 500}`
+
+	expectError(t, call(r, http.MethodPut, "/addons/999", body), http.StatusNotFound, models.CodeNotFound)
+	expectError(t, call(r, http.MethodDelete, "/addons/999", nil), http.StatusNotFound, models.CodeNotFound)
+	expectError(t, call(r, http.MethodPut, "/addons/abc", body), http.StatusBadRequest, models.CodeBadRequest)
+
+	// Deleted between being read and being saved, it is still a 404.
+	w := call(r, http.MethodPost, "/addons", body)
+	expect(t, w, http.StatusCreated)
+	created := decode[models.Addon](t, w)
+	vanishOnUpdate(t, "addons", created.ID)
+	expectError(t, call(r, http.MethodPut, fmt.Sprintf("/addons/%d", created.ID), body), http.StatusNotFound, models.CodeNotFound)
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"miniparty-backend/checkin"
+	"miniparty-backend/messages"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/settings"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// checkInRequest is the body of POST /checkin: the token from a ticket's QR code or, for
+// admins, the booking's ID, and the number of guests who came if the staff counted them.
+type checkInRequest struct {
+	Token        string `json:"token"`
+	BookingID    int64  `json:"booking_id"`
+	ActualGuests *int   `json:"actual_guests"`
+}
+
+var (
+	// errNotConfirmed rejects checking in, or marking as a no-show, a booking that isn't confirmed.
+	errNotConfirmed = errors.New("booking is not confirmed")
+	// errNotToday rejects checking in a booking on another day than its party.
+	errNotToday = errors.New("booking is not today")
+)
+
+// CheckIn marks a confirmed booking's party as arrived, on its day in venue time. The
+// ticket's token is proof enough on its own; a bare booking_id needs an admin. Pending and
+// cancelled bookings are refused. Checking in a booking again changes nothing and answers
+// with the first check-in and already_checked_in set, so a ticket scanned twice at the
+// door isn't an error.
+func CheckIn(c *gin.Context) {
+	var req checkInRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	id, ticketDate := req.BookingID, ""
+	switch {
+	case strings.TrimSpace(req.Token) != "":
+		var ok bool
+		if id, ticketDate, ok = checkin.Verify(strings.TrimSpace(req.Token)); !ok {
+			middleware.Fail(c, http.StatusUnauthorized, models.CodeUnauthorized, "This ticket isn't valid")
+			return
+		}
+	case req.BookingID == 0:
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "token or booking_id is required")
+		return
+	case middleware.Role(c) == "":
+		middleware.Fail(c, http.StatusUnauthorized, models.CodeUnauthorized, "Checking in by booking_id needs an admin token")
+		return
+	case !middleware.HasRole(c, middleware.RoleAdmin):
+		middleware.Fail(c, http.StatusForbidden, models.CodeForbidden, "Your access does not allow this action")
+		return
+	}
+
+	venue := settings.Current()
+	if req.ActualGuests != nil {
+		maxGuests := max(venue.MaxGuests, venue.FullDayMaxGuests)
+		if *req.ActualGuests < 1 || *req.ActualGuests > maxGuests {
+			errs := fieldErrors{}
+			errs.add("actual_guests", messages.ActualGuestsRange, "max", maxGuests)
+			badFields(c, errs)
+			return
+		}
+	}
+
+	today := now().In(venueLocation()).Format(dateLayout)
+	var booking models.Booking
+	already := false
+	err := inTx(c, func(ctx context.Context, tx *gorm.DB) error {
+		already = false
+		var before models.Booking
+		var err error
+		booking, err = Bookings.Modify(ctx, id, func(b *models.Booking) error {
+			before = *b
+			// A ticket names the day it was issued for; moving the booking voids it.
+			if ticketDate != "" && ticketDate != b.Date {
+				return store.ErrNotFound
+			}
+			if b.Status != models.StatusConfirmed {
+				return errNotConfirmed
+			}
+			if b.CheckedInAt != nil {
+				already = true
+				return nil
+			}
+			if b.Date != today {
+				return errNotToday
+			}
+			at := now().UTC()
+			b.CheckedInAt, b.ActualGuests = &at, req.ActualGuests
+			return nil
+		})
+		if err != nil || already {
+			return err
+		}
+		return audit(c, tx, models.AuditBookingCheckIn, &booking.ID, before, booking)
+	})
+
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		if ticketDate != "" {
+			middleware.Fail(c, http.StatusUnauthorized, models.CodeUnauthorized, "This ticket isn't valid")
+			return
+		}
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Booking not found")
+	case errors.Is(err, errNotConfirmed):
+		middleware.FailWith(c, http.StatusConflict, models.CodeNotConfirmed,
+			"Only confirmed bookings can be checked in; this one is "+booking.Status, gin.H{"status": booking.Status})
+	case errors.Is(err, errNotToday):
+		middleware.FailWith(c, http.StatusUnprocessableEntity, models.CodeNotToday,
+			"This booking is for "+dayName(booking.Date)+", not today", gin.H{"date": booking.Date, "today": today})
+	case err != nil:
+		serverError(c, err, "Failed to check in booking")
+	default:
+		c.JSON(http.StatusOK, gin.H{
+			"booking":            booking,
+			"checked_in_at":      booking.CheckedInAt,
+			"already_checked_in": already,
+		})
+	}
+}
+
+// dayName writes a booking date as "Friday 20 November 2026", or as it is if it doesn't parse.
+func dayName(date string) string {
+	day, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return date
+	}
+	return day.Format("Monday 2 January 2006")
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"miniparty-backend/db"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// inTx runs fn in one transaction that Bookings also uses through ctx, so a store call and
+// the audit entry for it commit together or not at all. A transaction that fails with a
+// transient error, such as a deadlock, a serialization failure or a locked SQLite database,
+// is rolled back and run again by db.RetryTx, so fn must start afresh each time it is called
+// rather than add to what an earlier attempt left behind.
+func inTx(c *gin.Context, fn func(ctx context.Context, tx *gorm.DB) error) error {
+	ctx := c.Request.Context()
+	return db.RetryTx(ctx, conn(c), func(tx *gorm.DB) error {
+		return fn(store.WithTx(ctx, tx), tx)
+	})
+}
+
+// actorCustomer is the actor and role audit records for a change a customer made through
+// their own token or code, without an admin credential.
+const actorCustomer = "customer"
+
+// audit logs a change made by the admin behind c, or by the customer when c carries no
+// admin credential, in tx so it commits with the change.
+// before and after are the thing as it was and as it is now, nil where it didn't exist;
+// bookings are safe to pass as they are, since their tokens and codes never marshal.
+func audit(c *gin.Context, tx *gorm.DB, action string, bookingID *int64, before, after any) error {
+	entry := models.AuditEntry{
+		// UTC like every other timestamp, so the ?from= and ?to= comparisons hold on SQLite too.
+		CreatedAt: now().UTC(),
+		Actor:     middleware.Actor(c),
+		Role:      middleware.Role(c),
+		Action:    action,
+		BookingID: bookingID,
+		ClientIP:  middleware.ClientIP(c),
+	}
+	if entry.Actor == "" {
+		entry.Actor, entry.Role = actorCustomer, actorCustomer
+	}
+	var err error
+	if entry.Before, err = snapshot(before); err != nil {
+		return err
+	}
+	if entry.After, err = snapshot(after); err != nil {
+		return err
+	}
+	return tx.Create(&entry).Error
+}
+
+// snapshot encodes v for the audit log; nil encodes as no snapshot.
+func snapshot(v any) (models.Snapshot, error) {
+	if v == nil {
+		return "", nil
+	}
+	raw, err := json.Marshal(v)
+	return models.Snapshot(raw), err
+}
+
+// GetAuditLog lists audit entries newest first, one page at a time like GET /bookings.
+// ?booking_id= narrows it to one booking, and ?from= and ?to= (YYYY-MM-DD, inclusive, in
+// the venue's timezone) to the days the changes were made.
+func GetAuditLog(c *gin.Context) {
+	page, perPage, err := pagination(c)
+	if err != nil {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, err.Error())
+		return
+	}
+
+	query := conn(c).Model(&models.AuditEntry{})
+	if v := c.Query("booking_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "booking_id must be a positive integer")
+			return
+		}
+		query = query.Where("booking_id = ?", id)
+	}
+	from, to := c.Query("from"), c.Query("to")
+	if from != "" {
+		start, err := models.StartTime(from, "00:00")
+		if err != nil {
+			middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "from must be a date in YYYY-MM-DD format")
+			return
+		}
+		query = query.Where("created_at >= ?", start)
+	}
+	if to != "" {
+		day, err := time.Parse(dateLayout, to)
+		if err != nil {
+			middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "to must be a date in YYYY-MM-DD format")
+			return
+		}
+		end, _ := models.StartTime(day.AddDate(0, 0, 1).Format(dateLayout), "00:00")
+		query = query.Where("created_at < ?", end)
+	}
+	if from != "" && to != "" && from > to {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "from must not be after to")
+		return
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		serverError(c, err, "Failed to fetch audit log")
+		return
+	}
+	entries := []models.AuditEntry{}
+	if err := query.Order("created_at DESC, id DESC").
+		Offset((page - 1) * perPage).Limit(perPage).Find(&entries).Error; err != nil {
+		serverError(c, err, "Failed to fetch audit log")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries":  entries,
+		"total":    total,
+		"page":     page,
+		"per_page": perPage,
+	})
+}
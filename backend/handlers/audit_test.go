@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+)
+
+func TestAuditLog(t *testing.T) {
+	testDB(t)
+	b := addBooking(t, models.Booking{Status: models.StatusPending})
+	other := addBooking(t, models.Booking{Date: "2026-07-11"})
+	r := newRouter()
+	admin := r.Group("", middleware.AdminAuth())
+	admin.PUT("/bookings/:id", UpdateBooking)
+	admin.POST("/bookings/:id/confirm", ConfirmBooking)
+	admin.POST("/bookings/:id/cancel", CancelBooking)
+	admin.GET("/admin/audit", GetAuditLog)
+
+	edit := b
+	edit.Guests = 8
+	expect(t, call(r, http.MethodPut, fmt.Sprintf("/bookings/%d", b.ID), edit, asAdmin...), http.StatusOK)
+	// A rejected change leaves nothing in the log.
+	edit.Guests = 0
+	fieldCodes(t, call(r, http.MethodPut, fmt.Sprintf("/bookings/%d", b.ID), edit, asAdmin...))
+	expect(t, call(r, http.MethodPost, fmt.Sprintf("/bookings/%d/confirm", b.ID), nil, asAdmin...), http.StatusOK)
+	expect(t, call(r, http.MethodPost, fmt.Sprintf("/bookings/%d/cancel", other.ID), nil, asAdmin...), http.StatusOK)
+
+	type entries struct {
+		Entries []struct {
+			models.AuditEntry
+			Before map[string]any `json:"before"`
+			After  map[string]any `json:"after"`
+		}
+		Total int64
+	}
+	w := call(r, http.MethodGet, fmt.Sprintf("/admin/audit?booking_id=%d", b.ID), nil, asViewer...)
+	expect(t, w, http.StatusOK)
+	got := decode[entries](t, w)
+	if got.Total != 2 || len(got.Entries) != 2 {
+		t.Fatalf("%d entries for the booking, want the update and the confirm", got.Total)
+	}
+	confirm, update := got.Entries[0], got.Entries[1]
+	if confirm.Action != models.AuditBookingConfirm || update.Action != models.AuditBookingUpdate {
+		t.Errorf("actions %s, %s, want the confirm newest first", confirm.Action, update.Action)
+	}
+	if update.Actor != "admin secret" || update.Role != middleware.RoleAdmin || update.ClientIP == "" || !update.CreatedAt.Equal(testNow) {
+		t.Errorf("update entry %+v, want the admin secret's, from the client, at %v", update.AuditEntry, testNow)
+	}
+	if update.Before["guests"] != 4.0 || update.After["guests"] != 8.0 {
+		t.Errorf("update guests %v → %v, want 4 → 8", update.Before["guests"], update.After["guests"])
+	}
+	if raw := w.Body.String(); strings.Contains(raw, b.CancelToken) || strings.Contains(raw, b.ConfirmationCode) {
+		t.Error("the audit log shows the booking's cancel token or confirmation code")
+	}
+
+	for query, want := range map[string]int64{
+		"": 3, "?from=2026-07-01&to=2026-07-01": 3, "?from=2026-07-02": 0, "?to=2026-06-30": 0, "?per_page=1&page=3": 3,
+	} {
+		w := call(r, http.MethodGet, "/admin/audit"+query, nil, asAdmin...)
+		expect(t, w, http.StatusOK)
+		if got := decode[entries](t, w).Total; got != want {
+			t.Errorf("GET /admin/audit%s: %d entries, want %d", query, got, want)
+		}
+	}
+	for _, query := range []string{"?booking_id=0", "?booking_id=x", "?from=July", "?to=2026-13-01", "?from=2026-07-02&to=2026-07-01"} {
+		expectError(t, call(r, http.MethodGet, "/admin/audit"+query, nil, asAdmin...), http.StatusBadRequest, models.CodeBadRequest)
+	}
+}
+
+func TestChangesRetryTransientFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target func(b models.Booking) string
+		body   func(b models.Booking) any
+		audit  []string
+		check  func(t *testing.T, b models.Booking)
+	}{
+		{"confirm", http.MethodPost, func(b models.Booking) string { return fmt.Sprintf("/bookings/%d/confirm", b.ID) }, nil,
+			[]string{models.AuditBookingConfirm}, func(t *testing.T, b models.Booking) {
+				if b.Status != models.StatusConfirmed {
+					t.Errorf("status %q, want confirmed", b.Status)
+				}
+			}},
+		{"customer cancel", http.MethodPost, func(models.Booking) string { return "/bookings/cancel" },
+			func(b models.Booking) any { return map[string]any{"id": b.ID, "token": b.CancelToken} },
+			nil, func(t *testing.T, b models.Booking) {
+				if b.Status != models.StatusCancelled {
+					t.Errorf("status %q, want cancelled", b.Status)
+				}
+			}},
+		{"reschedule", http.MethodPost, func(b models.Booking) string { return fmt.Sprintf("/bookings/%d/reschedule", b.ID) },
+			func(models.Booking) any { return map[string]any{"date": "2026-07-12", "time": "15:00"} },
+			[]string{models.AuditBookingReschedule}, func(t *testing.T, b models.Booking) {
+				if b.Date != "2026-07-12" || b.Time != "15:00" {
+					t.Errorf("booking at %s %s, want 2026-07-12 15:00", b.Date, b.Time)
+				}
+			}},
+		{"deposit", http.MethodPatch, func(b models.Booking) string { return fmt.Sprintf("/bookings/%d/deposit", b.ID) },
+			func(models.Booking) any { return map[string]any{"amount": 5000, "paid": true} },
+			[]string{models.AuditBookingDeposit}, func(t *testing.T, b models.Booking) {
+				if b.AmountPaidCents != 5000 || !b.DepositPaid {
+					t.Errorf("paid %d, deposit_paid %v; want 5000 and true", b.AmountPaidCents, b.DepositPaid)
+				}
+				if n := countRows(t, &models.Payment{}, "booking_id = ?", b.ID); n != 1 {
+					t.Errorf("%d payments, want the retried one only", n)
+				}
+			}},
+		{"payment", http.MethodPost, func(b models.Booking) string { return fmt.Sprintf("/bookings/%d/payments", b.ID) },
+			func(models.Booking) any { return map[string]any{"amount_cents": 2500, "method": models.PaymentCash} },
+			[]string{models.AuditBookingPayment}, func(t *testing.T, b models.Booking) {
+				if b.AmountPaidCents != 2500 {
+					t.Errorf("paid %d, want 2500", b.AmountPaidCents)
+				}
+				if n := countRows(t, &models.Payment{}, "booking_id = ?", b.ID); n != 1 {
+					t.Errorf("%d payments, want the retried one only", n)
+				}
+			}},
+		{"cancel series", http.MethodDelete, func(b models.Booking) string { return "/bookings/series/" + b.SeriesID }, nil,
+			[]string{models.AuditBookingCancel}, func(t *testing.T, b models.Booking) {
+				if b.Status != models.StatusCancelled {
+					t.Errorf("status %q, want cancelled", b.Status)
+				}
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testDB(t)
+			b := addBooking(t, models.Booking{Status: models.StatusPending, SeriesID: "retry-series"})
+			r := newRouter()
+			r.POST("/bookings/cancel", CancelBookingByToken)
+			admin := r.Group("", middleware.AdminAuth())
+			admin.POST("/bookings/:id/confirm", ConfirmBooking)
+			admin.POST("/bookings/:id/reschedule", RescheduleBooking)
+			admin.PATCH("/bookings/:id/deposit", UpdateDeposit)
+			admin.POST("/bookings/:id/payments", RecordPayment)
+			admin.DELETE("/bookings/series/:series_id", CancelSeries)
+
+			tried := failUpdateOnce(t)
+			var body any
+			if tt.body != nil {
+				body = tt.body(b)
+			}
+			w := call(r, tt.method, tt.target(b), body, asAdmin...)
+			if w.Code >= 300 {
+				t.Fatalf("status %d: %s", w.Code, w.Body.String())
+			}
+			if *tried < 2 {
+				t.Fatalf("%d UPDATEs tried, want the failed one and its retry", *tried)
+			}
+			tt.check(t, reload(t, b.ID))
+			if got := auditActions(t, b.ID); strings.Join(got, ",") != strings.Join(tt.audit, ",") {
+				t.Errorf("audit %v, want %v", got, tt.audit)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"miniparty-backend/messages"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/settings"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type slot struct {
+	Time      string `json:"time"`
+	Available bool   `json:"available"`
+	// Rooms lists the rooms free at this time when availability covers every room.
+	Rooms []uint `json:"rooms,omitempty"`
+}
+
+// GetAvailability lists the start slots for ?date=YYYY-MM-DD and whether each is free for a
+// booking of ?duration= hours (default the shortest allowed), within that weekday's opening
+// hours; start times from which it would run past closing aren't listed. A blacked-out date
+// is closed with the blackout's reason, even on a day the venue would be closed anyway.
+// With ?room_id= the slots are for that room; otherwise a slot is free while any active room
+// is, and lists which. A slot is taken when an active booking's interval in the room covers
+// it, using the same interval logic as the conflict check in CreateBooking.
+func GetAvailability(c *gin.Context) {
+	date := c.Query("date")
+	day, err := time.Parse(dateLayout, date)
+	if err != nil {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "date must be in YYYY-MM-DD format")
+		return
+	}
+
+	var rooms []models.Room
+	if v := c.Query("room_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 0)
+		if err != nil || id == 0 {
+			middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "room_id must be a positive integer")
+			return
+		}
+		var room models.Room
+		if err := conn(c).Where("active = ?", true).First(&room, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Room not found")
+				return
+			}
+			serverError(c, err, "Failed to fetch availability")
+			return
+		}
+		rooms = []models.Room{room}
+	} else {
+		var err error
+		if rooms, err = activeRooms(conn(c)); err != nil {
+			serverError(c, err, "Failed to fetch availability")
+			return
+		}
+	}
+
+	venue := settings.Current()
+	duration := venue.MinDurationHours
+	if v := c.Query("duration"); v != "" {
+		d, err := strconv.Atoi(v)
+		if err != nil || d < venue.MinDurationHours || d > venue.MaxDurationHours {
+			middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, fmt.Sprintf("duration must be between %d and %d hours", venue.MinDurationHours, venue.MaxDurationHours))
+			return
+		}
+		duration = d
+	}
+
+	blackout, err := findBlackout(conn(c), date)
+	if err != nil {
+		serverError(c, err, "Failed to fetch availability")
+		return
+	}
+	if blackout != nil {
+		slots := daySlots(venue, day.Weekday(), nil, duration)
+		for i := range slots {
+			slots[i].Available = false
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"date":   date,
+			"closed": true,
+			"reason": blackout.Reason,
+			"slots":  slots,
+		})
+		return
+	}
+	if _, _, open := venue.HoursOn(day.Weekday()); !open {
+		c.JSON(http.StatusOK, gin.H{
+			"date":   date,
+			"closed": true,
+			"reason": messages.New(messages.DateClosedDay, "day", day.Weekday().String()).Text(language(c)),
+			"slots":  []slot{},
+		})
+		return
+	}
+
+	bookings, err := bookingsOn(c.Request.Context(), date)
+	if err != nil {
+		serverError(c, err, "Failed to fetch availability")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"date":   date,
+		"closed": false,
+		"slots":  roomSlots(venue, day.Weekday(), rooms, bookings, c.Query("room_id") == "", duration),
+	})
+}
+
+// bookingsOn returns date's active bookings in the order they start.
+func bookingsOn(ctx context.Context, date string) ([]models.Booking, error) {
+	bookings, _, err := Bookings.List(ctx, store.ListOptions{Filter: store.Filter{Active: true, From: date, To: date}, Limit: -1})
+	return bookings, err
+}
+
+// roomSlots builds the slot grid for bookings of durationHours across rooms: a slot is
+// available while at least one of the rooms is free. With listRooms set each slot also
+// lists the free rooms.
+func roomSlots(v settings.Venue, day time.Weekday, rooms []models.Room, bookings []models.Booking, listRooms bool, durationHours int) []slot {
+	byRoom := map[uint][]models.Booking{}
+	for _, b := range bookings {
+		switch {
+		case b.FullDay:
+			// A buyout takes every room, whichever one it was filed under.
+			for _, room := range rooms {
+				byRoom[room.ID] = append(byRoom[room.ID], b)
+			}
+		case b.RoomID != nil:
+			byRoom[*b.RoomID] = append(byRoom[*b.RoomID], b)
+		}
+	}
+
+	slots := daySlots(v, day, nil, durationHours)
+	for i := range slots {
+		slots[i].Available = false
+	}
+	for _, room := range rooms {
+		for i, s := range daySlots(v, day, byRoom[room.ID], durationHours) {
+			if !s.Available {
+				continue
+			}
+			slots[i].Available = true
+			if listRooms {
+				slots[i].Rooms = append(slots[i].Rooms, room.ID)
+			}
+		}
+	}
+	return slots
+}
+
+// daySlots builds v's grid of start times on weekday day from which a booking of
+// durationHours finishes by closing, marking those whose whole duration would overlap any
+// of the bookings. A closed day has none.
+func daySlots(v settings.Venue, day time.Weekday, bookings []models.Booking, durationHours int) []slot {
+	step := v.SlotMinutes
+	openAt, closeAt, open := v.HoursOn(day)
+	length := durationHours * 60
+	slots := []slot{}
+	if !open {
+		return slots
+	}
+	for start := openAt; start <= latestStart(v, closeAt, durationHours); start += step {
+		available := true
+		for i := range bookings {
+			bStart, bEnd, ok := store.Interval(&bookings[i])
+			if ok && store.Overlaps(start, start+length, bStart, bEnd) {
+				available = false
+				break
+			}
+		}
+		slots = append(slots, slot{Time: formatMinutes(start), Available: available})
+	}
+	return slots
+}
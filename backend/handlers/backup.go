@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"miniparty-backend/backup"
+	"miniparty-backend/db"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Backups is where the database is backed up to. main sets it when BACKUP_DIR is
+// configured; nil turns backups off.
+var Backups *backup.Dir
+
+// RunBackups backs the database up whenever the newest backup is interval old, checking
+// every minute until ctx is done. Going by the newest backup rather than a timer means
+// restarts and deploys don't put the next backup off.
+func RunBackups(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(min(interval, time.Minute))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !db.Ready() || now().Sub(Backups.Last()) < interval {
+			continue
+		}
+		result, err := Backups.Run(ctx, db.DB, now())
+		if err != nil {
+			slog.Error("failed to back up database", "error", err)
+			continue
+		}
+		slog.Info("backed up database", "file", result.File, "rows", result.Rows)
+	}
+}
+
+// CreateBackup backs the database up now and answers with the file's name and how many
+// rows of each table it holds. A big database takes a while, so the backup isn't held to
+// the request's database timeout.
+func CreateBackup(c *gin.Context) {
+	if Backups == nil {
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Backups are not configured")
+		return
+	}
+	result, err := Backups.Run(context.WithoutCancel(c.Request.Context()), db.DB, now())
+	if err != nil {
+		serverError(c, err, "Failed to back up database")
+		return
+	}
+	middleware.Logger(c).Info("backed up database", "file", result.File, "rows", result.Rows)
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"miniparty-backend/backup"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+)
+
+func TestCreateBackup(t *testing.T) {
+	testDB(t)
+	r := newRouter()
+	r.POST("/admin/backup", middleware.AdminAuth(), CreateBackup)
+	expectError(t, call(r, http.MethodPost, "/admin/backup", nil, asAdmin...), http.StatusNotFound, models.CodeNotFound)
+
+	dir, err := backup.Open(filepath.Join(t.TempDir(), "backups"), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	swap(t, &Backups, dir)
+	addBooking(t, models.Booking{})
+	w := call(r, http.MethodPost, "/admin/backup", nil, asAdmin...)
+	expect(t, w, http.StatusOK)
+	if got := decode[backup.Result](t, w); got.File != "miniparty-20260701T090000Z.json.gz" || got.Rows["bookings"] != 1 {
+		t.Errorf("backup %+v, want one booking in a file named for the handlers' clock", got)
+	}
+	if !dir.Last().Equal(testNow) {
+		t.Errorf("last backup %v, want %v", dir.Last(), testNow)
+	}
+}
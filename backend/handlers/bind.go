@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"miniparty-backend/messages"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// unknownFieldPrefix starts the error encoding/json returns for a key the target has no field for.
+const unknownFieldPrefix = `json: unknown field "`
+
+// bindJSON decodes the request body into v like ShouldBindJSON, but rejects keys v has no
+// field for and anything after the JSON value, so typos and stuffed payloads fail instead of
+// being quietly dropped. On failure it writes the response and returns false: 413 past the
+// body limit, 400 naming the field for an unknown key, and 400 otherwise.
+func bindJSON(c *gin.Context, v any) bool {
+	dec := json.NewDecoder(c.Request.Body)
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if err == nil && dec.Decode(&json.RawMessage{}) != io.EOF {
+		err = errors.New("unexpected data after the JSON body")
+	}
+	if err == nil {
+		err = binding.Validator.ValidateStruct(v)
+	}
+	switch {
+	case err == nil:
+		return true
+	case middleware.BodyTooLarge(err):
+		middleware.Fail(c, http.StatusRequestEntityTooLarge, models.CodeTooLarge, "Request body too large")
+	case strings.HasPrefix(err.Error(), unknownFieldPrefix):
+		errs := fieldErrors{}
+		errs.add(strings.TrimSuffix(strings.TrimPrefix(err.Error(), unknownFieldPrefix), `"`), messages.FieldUnknown)
+		badFields(c, errs)
+	default:
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "Invalid request body")
+	}
+	return false
+}
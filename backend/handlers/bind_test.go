@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"miniparty-backend/messages"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBindJSON(t *testing.T) {
+	r := newRouter()
+	r.POST("/", middleware.BodyLimit(64), func(c *gin.Context) {
+		var req struct {
+			Name string `json:"name" binding:"required"`
+		}
+		if bindJSON(c, &req) {
+			c.String(http.StatusOK, req.Name)
+		}
+	})
+
+	w := call(r, http.MethodPost, "/", `{"name": "Ada"}`)
+	if w.Code != http.StatusOK || w.Body.String() != "Ada" {
+		t.Errorf("good body = %d %s", w.Code, w.Body.String())
+	}
+	if codes := fieldCodes(t, call(r, http.MethodPost, "/", `{"name": "Ada", "admin": true}`)); !hasCode(codes, "admin", messages.FieldUnknown) {
+		t.Errorf("unknown field: codes %v, want admin named", codes)
+	}
+	for _, body := range []string{`{"name": "Ada"} {"name": "Eve"}`, `{"name": 7}`, `{}`, `not json`} {
+		expectError(t, call(r, http.MethodPost, "/", body), http.StatusBadRequest, models.CodeBadRequest)
+	}
+	expectError(t, call(r, http.MethodPost, "/", `{"name": "`+strings.Repeat("a", 100)+`"}`), http.StatusRequestEntityTooLarge, models.CodeTooLarge)
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"miniparty-backend/messages"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type blackoutRequest struct {
+	Date   string `json:"date" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+// CreateBlackout closes a date for bookings. Existing bookings on that date are kept,
+// and returned so the admin knows who to contact.
+func CreateBlackout(c *gin.Context) {
+	var req blackoutRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	d, err := time.Parse(dateLayout, req.Date)
+	if err != nil {
+		badFields(c, fieldErrors{"date": {messages.New(messages.DateInvalid)}})
+		return
+	}
+
+	blackout := models.Blackout{Date: d.Format(dateLayout), Reason: strings.TrimSpace(req.Reason)}
+	if err := conn(c).Create(&blackout).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			middleware.Fail(c, http.StatusConflict, models.CodeDateClosed, "This date is already blacked out")
+			return
+		}
+		serverError(c, err, "Failed to save blackout")
+		return
+	}
+
+	affected, err := bookingsOn(c.Request.Context(), blackout.Date)
+	if err != nil {
+		serverError(c, err, "Failed to fetch affected bookings")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"blackout":          blackout,
+		"affected_bookings": affected,
+	})
+}
+
+// GetBlackouts lists all blackout dates in date order.
+func GetBlackouts(c *gin.Context) {
+	blackouts := []models.Blackout{}
+	if err := conn(c).Order("date ASC").Find(&blackouts).Error; err != nil {
+		serverError(c, err, "Failed to fetch blackouts")
+		return
+	}
+
+	c.JSON(http.StatusOK, blackouts)
+}
+
+// DeleteBlackout reopens a blacked-out date.
+func DeleteBlackout(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil || id == 0 {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "Invalid blackout ID")
+		return
+	}
+
+	result := conn(c).Delete(&models.Blackout{}, id)
+	if result.Error != nil {
+		serverError(c, result.Error, "Failed to delete blackout")
+		return
+	}
+	if result.RowsAffected == 0 {
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Blackout not found")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// findBlackout returns the blackout covering date, or nil if the venue is open that day.
+func findBlackout(tx *gorm.DB, date string) (*models.Blackout, error) {
+	var blackout models.Blackout
+	err := tx.Where("date = ?", date).Take(&blackout).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &blackout, nil
+}
+
+// blackoutMessage explains why a date can't be booked.
+func blackoutMessage(b *models.Blackout) string {
+	if b.Reason == "" {
+		return "We're closed on " + b.Date + ". Please choose another date."
+	}
+	return "We're closed on " + b.Date + " (" + b.Reason + "). Please choose another date."
+}
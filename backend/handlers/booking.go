@@ -1,154 +1,717 @@
 package handlers
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
-	"net/mail"
+	stdmail "net/mail"
+	"strconv"
 	"strings"
+	"time"
 
 	"miniparty-backend/db"
+	"miniparty-backend/mail"
+	"miniparty-backend/messages"
+	"miniparty-backend/metrics"
+	"miniparty-backend/middleware"
 	"miniparty-backend/models"
+	"miniparty-backend/notify"
+	"miniparty-backend/settings"
+	"miniparty-backend/store"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// Mailer delivers customer emails. main replaces it with a configured implementation.
+var Mailer mail.Mailer = mail.Nop{}
+
+// Bookings persists bookings. Tests can replace it with another store.BookingStore.
+var Bookings store.BookingStore = store.Gorm{}
+
+// Queries runs the booking reads and writes that don't fit Bookings, for the jobs and reports.
+var Queries store.BookingQueries = store.Gorm{}
+
+// Notifications dispatches booking events to webhooks. main replaces it with a configured dispatcher.
+var Notifications = notify.NewDispatcher()
+
+// slotTakenMessage is returned when the database rejects a booking for an already-taken slot.
+const slotTakenMessage = "This time slot is already taken. Please choose a different time."
+
+// duplicateMessage is returned when the customer already has a booking at the same time.
+const duplicateMessage = "You already have a booking for this slot."
+
+// bookingRequest is the body accepted by POST /book.
+type bookingRequest struct {
+	models.Booking
+	Recurrence *recurrence `json:"recurrence"`
+	AddonIDs   []uint      `json:"addon_ids"`
+	// Waitlist asks to queue the request, rather than fail, if its slot is taken.
+	Waitlist bool `json:"waitlist"`
+	// CaptchaToken is the form's Turnstile or reCAPTCHA response, checked when Captcha is set.
+	CaptchaToken string `json:"captcha_token"`
+	// Website is the honeypot: the form hides it, so only bots fill it in.
+	Website string `json:"website"`
+}
+
+// checkNewBooking validates a new booking and resolves what it asks for: its package (nil
+// if none), the rooms it may go in, and its add-ons. Problems with the request are returned
+// as field errors; err is only set if the database couldn't be read.
+func checkNewBooking(tx *gorm.DB, b *models.Booking, addonIDs []uint) (*models.Package, []models.Room, []models.BookingAddon, fieldErrors, error) {
+	pkg, errs, err := applyPackage(tx, b)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	errs.merge(validateBooking(b))
+	rooms, roomErrs, err := candidateRooms(tx, b)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	errs.merge(roomErrs)
+	addons, addonErrs, err := selectAddons(tx, addonIDs)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	errs.merge(addonErrs)
+	return pkg, rooms, addons, errs, nil
+}
+
 func CreateBooking(c *gin.Context) {
-	var booking models.Booking
+	var req bookingRequest
+
+	if bookingsPaused(c) {
+		return
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.Website != "" && Features.SpamHoneypot {
+		fakeBooking(c, req.Booking)
+		return
+	}
+	if !checkCaptcha(c, req.CaptchaToken) {
+		return
+	}
+	booking := req.Booking
+	clearServerFields(&booking)
+	setSource(c, &booking)
+	spam := spamErrors(&booking, middleware.ClientIP(c))
+	// Count every booking or waitlist place this client gets towards its hourly limit.
+	defer func() {
+		if status := c.Writer.Status(); status == http.StatusCreated || status == http.StatusAccepted {
+			recentBookings.record(middleware.ClientIP(c), now())
+		}
+	}()
 
-	if err := c.ShouldBindJSON(&booking); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+	pkg, rooms, addons, errs, err := checkNewBooking(conn(c), &booking, req.AddonIDs)
+	if err != nil {
+		serverError(c, err, "Failed to save booking")
+		return
+	}
+	// A blacked-out date is closed whatever else is wrong with the booking, even on a day
+	// the venue doesn't open anyway; the blackout's reason is the more useful answer.
+	blackout, err := findBlackout(conn(c), booking.Date)
+	if err != nil {
+		serverError(c, err, "Failed to save booking")
+		return
+	}
+	if blackout != nil && req.Recurrence == nil {
+		middleware.Fail(c, http.StatusUnprocessableEntity, models.CodeDateClosed, blackoutMessage(blackout))
 		return
 	}
 
-	if errs := validateBooking(&booking); len(errs) > 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"errors": errs})
+	if booking.FullDay && !settings.Current().FullDayPublic && !middleware.HasRole(c, middleware.RoleAdmin) {
+		errs.add("full_day", messages.FullDayNotPublic)
+	}
+	// Only an address that parsed is worth a DNS lookup.
+	if len(errs["email"]) == 0 {
+		spam.merge(emailErrors(c.Request.Context(), booking.Email))
+	}
+	errs.merge(spam)
+	if len(errs) > 0 {
+		reason := "validation"
+		if len(spam) > 0 {
+			reason = "spam"
+			middleware.Logger(c).Info("booking rejected as spam", "client_ip", middleware.ClientIP(c), "errors", spam)
+		}
+		metrics.BookingsRejected.WithLabelValues(reason).Inc()
+		badFields(c, errs)
 		return
 	}
 
-	// Check for time overlap with existing bookings on the same date
-	if conflict, endTime := checkTimeConflict(&booking); conflict {
-		c.JSON(http.StatusConflict, gin.H{
-			"error": fmt.Sprintf(
-				"This time slot is already taken. The current booking ends at %s. Please choose a different time.",
-				endTime,
-			),
-		})
+	duplicate, err := findDuplicate(c.Request.Context(), &booking)
+	if err != nil {
+		serverError(c, err, "Failed to save booking")
+		return
+	}
+	if duplicate != nil {
+		middleware.FailWith(c, http.StatusConflict, models.CodeDuplicate, duplicateMessage,
+			gin.H{"booking_id": duplicate.ID})
+		return
+	}
+
+	// Staff booking on a customer's behalf aren't held to the per-customer limit.
+	limited := !middleware.HasRole(c, middleware.RoleAdmin)
+
+	// A customer who has missed earlier parties still gets the booking, flagged for staff.
+	if err := flagNoShows(c.Request.Context(), &booking); err != nil {
+		serverError(c, err, "Failed to save booking")
 		return
 	}
 
-	if err := db.DB.Create(&booking).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save booking"})
+	if req.Recurrence != nil {
+		createSeries(c, booking, *req.Recurrence, pkg, rooms, addons, limited)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "Booking confirmed!",
-		"booking": booking,
+	price := priceBooking(&booking, pkg)
+	// Saved with the booking, in the same transaction.
+	booking.Addons = addons
+	if booking.CancelToken, err = randomHex(16); err != nil {
+		serverError(c, err, "Failed to save booking")
+		return
+	}
+	if booking.ConfirmationCode, err = confirmationCode(); err != nil {
+		serverError(c, err, "Failed to save booking")
+		return
+	}
+	if booking.Reference, err = newReference(c.Request.Context()); err != nil {
+		serverError(c, err, "Failed to save booking")
+		return
+	}
+	requestedRoom := booking.RoomID
+	// The limit is checked in the insert's transaction, so a customer's bookings sent at
+	// once can't all pass it before any of them is saved.
+	err = inTx(c, func(ctx context.Context, tx *gorm.DB) error {
+		if limited {
+			if err := checkBookingLimit(ctx, &booking); err != nil {
+				return err
+			}
+		}
+		return createInRoom(ctx, &booking, rooms)
 	})
+	if err != nil {
+		var limitErr *bookingLimitError
+		switch {
+		case errors.As(err, &limitErr):
+			limitError(c, err)
+		// A full day waits on every booking that date, so it isn't queued behind them.
+		case req.Waitlist && !booking.FullDay && slotUnavailable(err):
+			joinWaitlist(c, booking, requestedRoom, addons)
+		default:
+			storeError(c, err, "Failed to save booking")
+		}
+		return
+	}
+
+	response := gin.H{
+		"message":            "Booking received! We'll call you to confirm.",
+		"booking":            &booking,
+		"price":              price,
+		"addons_total_cents": models.AddonsTotal(booking.Addons),
+		"cancel_token":       booking.CancelToken,
+		"confirmation_code":  booking.ConfirmationCode,
+	}
+	if deadline, ok := settings.Current().CancelBy(booking); ok {
+		response["cancellation_deadline"] = deadline
+	}
+	if depositRequired() {
+		intent, err := requestDeposit(c, &booking)
+		if err != nil {
+			// Without a payment the booking would only hold the slot until it expired; drop it now.
+			if _, purgeErr := Bookings.Purge(c.Request.Context(), booking.ID); purgeErr != nil {
+				middleware.Logger(c).Error("failed to remove booking after deposit error", "booking_id", booking.ID, "error", purgeErr)
+			}
+			middleware.Logger(c).Error("failed to start deposit payment", "error", err)
+			middleware.Fail(c, http.StatusBadGateway, models.CodePaymentUnavailable, "We couldn't start the deposit payment. Please try again.")
+			return
+		}
+		response["message"] = "Booking received! Pay the deposit to secure your slot."
+		response["deposit"] = depositDetails(booking, intent)
+	}
+	metrics.BookingsCreated.Inc()
+
+	mail.SendAsync(Mailer, mail.Confirmation(booking))
+	dispatch(notify.Event{Type: notify.BookingCreated, Booking: booking})
+
+	c.JSON(http.StatusCreated, response)
 }
 
 func GetBookings(c *gin.Context) {
-	var bookings []models.Booking
+	page, perPage, err := pagination(c)
+	if err != nil {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, err.Error())
+		return
+	}
+	filter, err := bookingFilter(c)
+	if err != nil {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, err.Error())
+		return
+	}
+	sort, err := bookingSort(c)
+	if err != nil {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, err.Error())
+		return
+	}
 
-	if err := db.DB.Order("date ASC, time ASC").Find(&bookings).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
+	bookings, total, err := Bookings.List(c.Request.Context(), store.ListOptions{
+		Filter:         filter,
+		Sort:           sort,
+		Offset:         (page - 1) * perPage,
+		Limit:          perPage,
+		IncludeDeleted: c.Query("include_deleted") == "true",
+	})
+	key := c.Request.URL.Query().Encode()
+	if err != nil {
+		if list, ok := lastLists.get(key, now()); ok && db.Unreachable(err) {
+			middleware.Logger(c).Warn("serving a stale booking list", "error", err, "as_of", list.StaleAsOf)
+			c.Header("Warning", `110 - "Response is Stale"`)
+			c.JSON(http.StatusOK, list)
+			return
+		}
+		serverError(c, err, "Failed to fetch bookings")
 		return
 	}
+	if bookings == nil {
+		// Always a JSON array, whatever BookingStore is in use, so clients never see null.
+		bookings = []models.Booking{}
+	}
 
-	c.JSON(http.StatusOK, bookings)
+	list := models.BookingList{Bookings: bookings, Total: total, Page: page, PerPage: perPage}
+	lastLists.put(key, list, now())
+	c.JSON(http.StatusOK, list)
 }
 
-// checkTimeConflict checks if the new booking overlaps with any existing booking on the same date.
-// Returns (true, existingEndTime) if conflict found, (false, "") otherwise.
-func checkTimeConflict(booking *models.Booking) (bool, string) {
-	// Parse the new booking's start time (expected format "HH:MM" e.g. "14:00")
-	var newStartHour, newStartMin int
-	if _, err := fmt.Sscanf(booking.Time, "%d:%d", &newStartHour, &newStartMin); err != nil {
-		return false, ""
-	}
-	newStartTotal := newStartHour*60 + newStartMin
-	newEndTotal := newStartTotal + booking.Duration*60
+// conflictMessage describes the bookings that a requested slot overlaps.
+func conflictMessage(conflicts []models.Booking) string {
+	return fmt.Sprintf(
+		"This time slot is already booked (%s). Please choose a different time.",
+		strings.Join(conflictTimes(conflicts), ", "),
+	)
+}
 
-	// Get all existing bookings on the same date
-	var existing []models.Booking
-	if err := db.DB.Where("date = ?", booking.Date).Find(&existing).Error; err != nil {
-		return false, ""
+// conflictTimes renders each conflicting booking's interval, e.g. "2:00 PM - 5:00 PM".
+func conflictTimes(conflicts []models.Booking) []string {
+	times := make([]string, 0, len(conflicts))
+	for i := range conflicts {
+		start, end, ok := store.Interval(&conflicts[i])
+		if !ok {
+			continue
+		}
+		times = append(times, displayClock(start)+" - "+displayClock(end))
 	}
+	return times
+}
 
-	for _, ex := range existing {
-		var exStartHour, exStartMin int
-		if _, err := fmt.Sscanf(ex.Time, "%d:%d", &exStartHour, &exStartMin); err != nil {
-			continue
+// displayClock renders minutes since midnight on a 12-hour clock, e.g. "5:00 PM".
+func displayClock(minutes int) string {
+	hour := minutes / 60 % 24
+	period := "AM"
+	displayHour := hour
+	if displayHour >= 12 {
+		period = "PM"
+		if displayHour > 12 {
+			displayHour -= 12
 		}
-		exStartTotal := exStartHour*60 + exStartMin
-		exEndTotal := exStartTotal + ex.Duration*60
-
-		// Two intervals overlap if one starts before the other ends and vice versa
-		if newStartTotal < exEndTotal && exStartTotal < newEndTotal {
-			endHour := exEndTotal / 60
-			endMin := exEndTotal % 60
-			period := "AM"
-			displayHour := endHour
-			if displayHour >= 12 {
-				period = "PM"
-				if displayHour > 12 {
-					displayHour -= 12
-				}
-			}
-			if displayHour == 0 {
-				displayHour = 12
-			}
-			return true, fmt.Sprintf("%d:%02d %s", displayHour, endMin, period)
+	}
+	if displayHour == 0 {
+		displayHour = 12
+	}
+	return fmt.Sprintf("%d:%02d %s", displayHour, minutes%60, period)
+}
+
+// bookingID parses the :id path parameter, writing a 400 and returning false if it isn't a positive integer.
+func bookingID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "Invalid booking ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// findBooking loads a booking by ID, writing a 404 or 500 and returning false if it can't.
+func findBooking(c *gin.Context, id int64, booking *models.Booking) bool {
+	var err error
+	if *booking, err = Bookings.GetByID(c.Request.Context(), id); err != nil {
+		storeError(c, err, "Failed to fetch booking")
+		return false
+	}
+	return true
+}
+
+// storeError maps a BookingStore error to its response: 404 for a missing booking,
+// 409 for a taken or overlapping slot, and serverError with msg for anything else.
+func storeError(c *gin.Context, err error, msg string) {
+	var conflict *store.ConflictError
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Booking not found")
+	case errors.As(err, &conflict):
+		middleware.FailWith(c, http.StatusConflict, models.CodeSlotConflict, conflictMessage(conflict.Conflicts),
+			gin.H{"conflicts": conflictTimes(conflict.Conflicts)})
+	case errors.Is(err, store.ErrSlotTaken):
+		middleware.Fail(c, http.StatusConflict, models.CodeSlotConflict, slotTakenMessage)
+	default:
+		serverError(c, err, msg)
+	}
+}
+
+// clearServerFields resets fields that customers must not be able to set on POST /book.
+func clearServerFields(b *models.Booking) {
+	b.ID = 0
+	b.CreatedAt = time.Time{}
+	b.UpdatedAt = time.Time{}
+	b.Status = models.StatusPending
+	b.DepositPaid = false
+	b.DepositPaidAt = nil
+	b.DepositDueCents = 0
+	b.AmountPaidCents = 0
+	b.PaymentMethod = ""
+	b.PaidAt = nil
+	b.PaymentIntentID = ""
+	b.HoldExpiresAt = nil
+	b.CheckedInAt = nil
+	b.ActualGuests = nil
+	b.Flagged = false
+	b.PriorNoShows = 0
+	b.RetentionHold = false
+	b.PriceCents = 0
+	b.PackageName = ""
+	b.Addons = nil
+	b.SeriesID = ""
+	b.RescheduledFrom = ""
+	b.RescheduledAt = nil
+	b.RemindedAt = nil
+	b.ConfirmationResends = 0
+	b.ConfirmationResentAt = nil
+	b.StartsAt = nil
+	b.SlotKey = nil
+	b.CancelToken = ""
+	b.ConfirmationCode = ""
+	b.Reference = ""
+}
+
+// randomHex returns n random bytes encoded as hex.
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// GetBooking returns a single booking by ID for the admin detail view.
+func GetBooking(c *gin.Context) {
+	id, ok := bookingID(c)
+	if !ok {
+		return
+	}
+
+	var booking models.Booking
+	if !findBooking(c, id, &booking) {
+		return
+	}
+
+	c.JSON(http.StatusOK, booking)
+}
+
+// UpdateBooking replaces the customer-editable fields of a booking, applying the same
+// validation and overlap checks as CreateBooking.
+func UpdateBooking(c *gin.Context) {
+	id, ok := bookingID(c)
+	if !ok {
+		return
+	}
+
+	var input models.Booking
+	if !bindJSON(c, &input) {
+		return
+	}
+
+	var booking models.Booking
+	if !findBooking(c, id, &booking) {
+		return
+	}
+	before := booking
+
+	if input.Date != booking.Date || input.Time != booking.Time {
+		// The new time gets its own reminder.
+		booking.RemindedAt = nil
+	}
+	booking.Name = input.Name
+	booking.Email = input.Email
+	booking.Phone = input.Phone
+	booking.Date = input.Date
+	booking.Time = input.Time
+	booking.Duration = input.Duration
+	booking.Guests = input.Guests
+	booking.Notes = input.Notes
+	booking.FullDay = input.FullDay
+	errs := validateBooking(&booking)
+	keepStart(errs, booking, before)
+	if booking.Phone != before.Phone {
+		// Whatever went wrong texting the old number says nothing about the new one.
+		booking.SMSStatus = ""
+	}
+	var roomErrs fieldErrors
+	var err error
+	if input.RoomID != nil && (booking.RoomID == nil || *input.RoomID != *booking.RoomID) {
+		// Moving to another room is held to the same rules as a new booking there.
+		booking.RoomID = input.RoomID
+		_, roomErrs, err = candidateRooms(conn(c), &booking)
+	} else {
+		roomErrs, err = checkRoomCapacity(conn(c), &booking)
+	}
+	if err != nil {
+		serverError(c, err, "Failed to update booking")
+		return
+	}
+	errs.merge(roomErrs)
+	blackout, err := findBlackout(conn(c), booking.Date)
+	if err != nil {
+		serverError(c, err, "Failed to update booking")
+		return
+	}
+	if blackout != nil {
+		middleware.Fail(c, http.StatusUnprocessableEntity, models.CodeDateClosed, blackoutMessage(blackout))
+		return
+	}
+	if len(errs) > 0 {
+		badFields(c, errs)
+		return
+	}
+	pkg, err := findPackage(conn(c), &booking)
+	if err != nil {
+		serverError(c, err, "Failed to update booking")
+		return
+	}
+	priceBooking(&booking, pkg)
+
+	err = inTx(c, func(ctx context.Context, tx *gorm.DB) error {
+		if err := Bookings.Update(ctx, &booking); err != nil {
+			return err
 		}
+		return audit(c, tx, models.AuditBookingUpdate, &booking.ID, before, booking)
+	})
+	if err != nil {
+		storeError(c, err, "Failed to update booking")
+		return
 	}
+	calendarChanged(booking.ID)
 
-	return false, ""
+	c.JSON(http.StatusOK, booking)
 }
 
+// DeleteBooking soft-deletes a booking so it can be restored; ?permanent=true removes it for good.
+// Either way its slot is offered to the waitlist.
 func DeleteBooking(c *gin.Context) {
-	id := c.Param("id")
+	id, ok := bookingID(c)
+	if !ok {
+		return
+	}
+
+	if c.Query("permanent") == "true" {
+		var booking models.Booking
+		err := inTx(c, func(ctx context.Context, tx *gorm.DB) error {
+			var err error
+			if booking, err = Bookings.Purge(ctx, id); err != nil {
+				return err
+			}
+			return audit(c, tx, models.AuditBookingPurge, &booking.ID, booking, nil)
+		})
+		if err != nil {
+			storeError(c, err, "Failed to delete booking")
+			return
+		}
+		middleware.Logger(c).Warn("booking permanently deleted",
+			"booking_id", booking.ID, "name", booking.Name, "email", booking.Email,
+			"date", booking.Date, "time", booking.Time, "status", booking.Status)
+		if booking.Status != models.StatusCancelled && !booking.DeletedAt.Valid {
+			promoteWaitlist(c.Request.Context(), booking.Date)
+		}
+		calendarRemoved(booking)
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	var booking models.Booking
+	if !findBooking(c, id, &booking) {
+		return
+	}
+	err := inTx(c, func(ctx context.Context, tx *gorm.DB) error {
+		if err := Bookings.Delete(ctx, id); err != nil {
+			return err
+		}
+		return audit(c, tx, models.AuditBookingDelete, &booking.ID, booking, nil)
+	})
+	if err != nil {
+		storeError(c, err, "Failed to delete booking")
+		return
+	}
+	if booking.Status != models.StatusCancelled {
+		promoteWaitlist(c.Request.Context(), booking.Date)
+	}
+	calendarChanged(booking.ID)
+
+	c.Status(http.StatusNoContent)
+}
 
-	result := db.DB.Delete(&models.Booking{}, id)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete booking"})
+// RestoreBooking undoes a soft delete. It fails with 409 if the slot has been booked since.
+func RestoreBooking(c *gin.Context) {
+	id, ok := bookingID(c)
+	if !ok {
 		return
 	}
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+
+	var booking models.Booking
+	err := inTx(c, func(ctx context.Context, tx *gorm.DB) error {
+		found, _, err := Bookings.List(ctx, store.ListOptions{Filter: store.Filter{IDs: []int64{id}}, Limit: 1, IncludeDeleted: true})
+		if err != nil {
+			return err
+		}
+		if len(found) == 0 {
+			return store.ErrNotFound
+		}
+		before := found[0]
+		if booking, err = Bookings.Restore(ctx, id); err != nil || !before.DeletedAt.Valid {
+			return err
+		}
+		return audit(c, tx, models.AuditBookingRestore, &booking.ID, before, booking)
+	})
+	if err != nil {
+		storeError(c, err, "Failed to restore booking")
 		return
 	}
+	calendarChanged(booking.ID)
+
+	c.JSON(http.StatusOK, booking)
+}
+
+// fieldErrors maps a JSON field name (e.g. "email") to its validation messages.
+type fieldErrors map[string][]messages.Message
+
+// add records the message for code under field, with params as name, value pairs.
+func (e fieldErrors) add(field, code string, params ...any) {
+	e[field] = append(e[field], messages.New(code, params...))
+}
+
+// merge adds every message in other to e.
+func (e fieldErrors) merge(other fieldErrors) {
+	for field, msgs := range other {
+		e[field] = append(e[field], msgs...)
+	}
+}
+
+// text renders e's messages in lang, per field.
+func (e fieldErrors) text(lang string) map[string][]string {
+	text := make(map[string][]string, len(e))
+	for field, msgs := range e {
+		for _, m := range msgs {
+			text[field] = append(text[field], m.Text(lang))
+		}
+	}
+	return text
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Booking deleted successfully"})
+// badFields answers 400 validation_failed with errs in the language the request's
+// Accept-Language asks for. "fields" has the rendered text per field, which is what the
+// booking form shows, and "codes" the same messages as codes and parameters for clients
+// that render their own.
+func badFields(c *gin.Context, errs fieldErrors) {
+	lang := language(c)
+	text := errs.text(lang)
+	c.Header("Content-Language", lang)
+	c.Writer.Header().Add("Vary", "Accept-Language")
+	middleware.FailWith(c, http.StatusBadRequest, models.CodeValidation, messages.New(messages.ValidationFailed).Text(lang),
+		gin.H{"fields": text, "errors": text, "codes": errs})
 }
 
-func validateBooking(b *models.Booking) []string {
-	var errs []string
+// language is the language to render messages in for c, from its Accept-Language header.
+func language(c *gin.Context) string {
+	return messages.Negotiate(c.GetHeader("Accept-Language"))
+}
+
+// Longest values accepted for a booking's free-text fields, in characters. Email follows
+// the 254 the mail RFCs allow in a path; the others leave room for anything real.
+const (
+	maxNameLength  = 120
+	maxEmailLength = 254
+	maxPhoneLength = 32
+	maxNotesLength = 1000
+)
 
-	b.Name = strings.TrimSpace(b.Name)
-	b.Email = strings.TrimSpace(b.Email)
-	b.Phone = strings.TrimSpace(b.Phone)
+func validateBooking(b *models.Booking) fieldErrors {
+	errs := fieldErrors{}
+	venue := settings.Current()
 
-	if b.Name == "" {
-		errs = append(errs, "Name is required")
+	if checkText(errs, "name", messages.NameInvalidText, messages.NameTooLong, &b.Name, maxNameLength, true) && b.Name == "" {
+		errs.add("name", messages.NameRequired)
 	}
-	if _, err := mail.ParseAddress(b.Email); err != nil {
-		errs = append(errs, "Valid email is required")
+	if checkText(errs, "email", messages.EmailInvalidText, messages.EmailTooLong, &b.Email, maxEmailLength, false) {
+		if _, err := stdmail.ParseAddress(b.Email); err != nil {
+			errs.add("email", messages.EmailInvalid)
+		}
 	}
-	if len(b.Phone) < 7 {
-		errs = append(errs, "Valid phone number is required")
+	if checkText(errs, "phone", messages.PhoneInvalidText, messages.PhoneTooLong, &b.Phone, maxPhoneLength, false) {
+		if b.Phone == "" {
+			errs.add("phone", messages.PhoneRequired)
+		} else if phone, err := normalizePhone(b.Phone, defaultCountry()); err != nil {
+			errs.add("phone", messages.PhoneInvalid, "country", defaultCountry())
+		} else {
+			b.Phone = phone
+		}
 	}
 	if b.Date == "" {
-		errs = append(errs, "Date is required")
+		errs.add("date", messages.DateRequired)
+	} else if d, err := time.Parse(dateLayout, b.Date); err != nil {
+		errs.add("date", messages.DateInvalid)
+	} else {
+		b.Date = d.Format(dateLayout)
+		if b.FullDay {
+			fullDayHours(venue, b, d.Weekday())
+		}
 	}
 	if b.Time == "" {
-		errs = append(errs, "Time is required")
+		errs.add("time", messages.TimeRequired)
+	} else if t, err := parseClock(b.Time); err != nil {
+		errs.add("time", messages.TimeInvalid)
+	} else {
+		// Store zero-padded 24-hour times so string ordering matches chronological ordering.
+		b.Time = t.Format(timeLayout)
+	}
+	b.StartsAt = nil
+	if start, err := parseStart(b.Date, b.Time); err == nil {
+		utc := start.UTC()
+		b.StartsAt = &utc
+		if msg := checkBookingWindow(venue, start, now()); msg.Code != "" {
+			errs["date"] = append(errs["date"], msg)
+		}
+		// A full day's time and duration come from the opening hours, so only whether the
+		// venue opens that day is left to check.
+		if msg := checkSlotAlignment(venue, start); msg.Code != "" && !b.FullDay {
+			errs["time"] = append(errs["time"], msg)
+		}
+		if msg := checkOpeningHours(venue, start, b.Duration); msg.Code == messages.DateClosedDay {
+			errs["date"] = append(errs["date"], msg)
+		} else if msg.Code != "" && !b.FullDay {
+			errs["time"] = append(errs["time"], msg)
+		}
+	}
+	if !b.FullDay && (b.Duration < venue.MinDurationHours || b.Duration > venue.MaxDurationHours) {
+		errs.add("duration", messages.DurationRange, "min", venue.MinDurationHours, "max", venue.MaxDurationHours)
 	}
-	if b.Duration < 1 || b.Duration > 8 {
-		errs = append(errs, "Duration must be between 1 and 8 hours")
+	maxGuests := venue.MaxGuests
+	if b.FullDay {
+		maxGuests = venue.FullDayMaxGuests
 	}
-	if b.Guests < 1 || b.Guests > 100 {
-		errs = append(errs, "Guests must be between 1 and 100")
+	if b.Guests < 1 || b.Guests > maxGuests {
+		errs.add("guests", messages.GuestsRange, "max", maxGuests)
 	}
+	checkText(errs, "notes", messages.NotesInvalidText, messages.NotesTooLong, &b.Notes, maxNotesLength, false)
 
 	return errs
 }
@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"miniparty-backend/db"
+	"miniparty-backend/messages"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetBooking(t *testing.T) {
+	m := memoryStore(t)
+	b := addTo(t, m, models.Booking{Name: "Ada"})
+	r := newRouter()
+	r.GET("/bookings/:id", GetBooking)
+
+	w := call(r, http.MethodGet, fmt.Sprintf("/bookings/%d", b.ID), nil)
+	expect(t, w, http.StatusOK)
+	if got := decode[models.Booking](t, w); got.ID != b.ID || got.Name != "Ada" || got.Reference != b.Reference {
+		t.Errorf("got %+v, want booking %d", got, b.ID)
+	}
+
+	expectError(t, call(r, http.MethodGet, "/bookings/999", nil), http.StatusNotFound, models.CodeNotFound)
+	for _, id := range []string{"abc", "0", "-1", "9223372036854775808"} {
+		expectError(t, call(r, http.MethodGet, "/bookings/"+id, nil), http.StatusBadRequest, models.CodeBadRequest)
+	}
+	expectError(t, call(r, http.MethodGet, "/bookings/9223372036854775807", nil), http.StatusNotFound, models.CodeNotFound)
+
+	if err := m.Delete(context.Background(), b.ID); err != nil {
+		t.Fatal(err)
+	}
+	expectError(t, call(r, http.MethodGet, fmt.Sprintf("/bookings/%d", b.ID), nil), http.StatusNotFound, models.CodeNotFound)
+}
+
+func TestGetBookingsFiltersAndPages(t *testing.T) {
+	m := memoryStore(t)
+	lastLists.clear()
+	t.Cleanup(lastLists.clear)
+	for i, date := range []string{"2026-07-12", "2026-07-10", "2026-07-11"} {
+		status := models.StatusPending
+		if i == 0 {
+			status = models.StatusConfirmed
+		}
+		addTo(t, m, models.Booking{Date: date, Status: status, Name: fmt.Sprintf("Guest %c", 'A'+i)})
+	}
+	r := newRouter()
+	r.GET("/bookings", GetBookings)
+
+	tests := []struct {
+		query string
+		names []string
+		total int64
+	}{
+		{"", []string{"Guest B", "Guest C", "Guest A"}, 3},
+		{"?sort=date&order=desc", []string{"Guest A", "Guest C", "Guest B"}, 3},
+		{"?status=confirmed", []string{"Guest A"}, 1},
+		{"?from=2026-07-11", []string{"Guest C", "Guest A"}, 2},
+		{"?q=guest+c", []string{"Guest C"}, 1},
+		{"?per_page=2&page=2", []string{"Guest A"}, 3},
+		{"?from=2027-01-01", []string{}, 0},
+	}
+	for _, tt := range tests {
+		w := call(r, http.MethodGet, "/bookings"+tt.query, nil)
+		expect(t, w, http.StatusOK)
+		list := decode[models.BookingList](t, w)
+		names := []string{}
+		for _, b := range list.Bookings {
+			names = append(names, b.Name)
+		}
+		if fmt.Sprint(names) != fmt.Sprint(tt.names) || list.Total != tt.total {
+			t.Errorf("GET /bookings%s = %v of %d, want %v of %d", tt.query, names, list.Total, tt.names, tt.total)
+		}
+	}
+
+	for _, query := range []string{"?status=lost", "?page=0", "?from=July", "?from=2026-07-12&to=2026-07-01", "?sort=price"} {
+		expectError(t, call(r, http.MethodGet, "/bookings"+query, nil), http.StatusBadRequest, models.CodeBadRequest)
+	}
+}
+
+func TestStoreErrorResponses(t *testing.T) {
+	existing := newBooking(models.Booking{Time: "15:00"})
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{store.ErrNotFound, http.StatusNotFound, models.CodeNotFound},
+		{store.ErrSlotTaken, http.StatusConflict, models.CodeSlotConflict},
+		{&store.ConflictError{Conflicts: []models.Booking{existing}}, http.StatusConflict, models.CodeSlotConflict},
+		{errors.New("disk full"), http.StatusInternalServerError, models.CodeInternal},
+	}
+	for _, tt := range tests {
+		r := newRouter()
+		r.GET("/", func(c *gin.Context) { storeError(c, tt.err, "Failed to save booking") })
+		body := expectError(t, call(r, http.MethodGet, "/", nil), tt.status, tt.code)
+		if _, ok := tt.err.(*store.ConflictError); ok && fmt.Sprint(body["conflicts"]) != "[3:00 PM - 5:00 PM]" {
+			t.Errorf("conflicts = %v, want only the other booking's times", body["conflicts"])
+		}
+	}
+}
+
+func TestMissingBookingWrites(t *testing.T) {
+	testDB(t)
+	r := newRouter()
+	admin := r.Group("", middleware.AdminAuth())
+	admin.PUT("/bookings/:id", UpdateBooking)
+	admin.DELETE("/bookings/:id", DeleteBooking)
+	admin.PATCH("/bookings/:id/deposit", UpdateDeposit)
+	admin.POST("/bookings/:id/confirm", ConfirmBooking)
+	admin.DELETE("/bookings/:id/no-show", UndoNoShow)
+	admin.POST("/bookings/:id/restore", RestoreBooking)
+	admin.POST("/bookings/:id/retention-hold", HoldRetention)
+	update := newBooking(models.Booking{})
+	deposit := gin.H{"amount": 5000, "paid": true}
+
+	tests := []struct {
+		method, path string
+		body         any
+	}{
+		{http.MethodPut, "/bookings/999", update},
+		{http.MethodDelete, "/bookings/999", nil},
+		{http.MethodDelete, "/bookings/999?permanent=true", nil},
+		{http.MethodPatch, "/bookings/999/deposit", deposit},
+		{http.MethodPost, "/bookings/999/confirm", nil},
+		{http.MethodDelete, "/bookings/999/no-show", nil},
+		{http.MethodPost, "/bookings/999/restore", nil},
+		{http.MethodPost, "/bookings/999/retention-hold", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			expectError(t, call(r, tt.method, tt.path, tt.body, asAdmin...), http.StatusNotFound, models.CodeNotFound)
+		})
+	}
+
+	// A booking deleted between being read and being written is a 404 too, not a 200 for a
+	// write that changed nothing.
+	vanishing := []struct {
+		method, path string
+		body         any
+	}{
+		{http.MethodPut, "/bookings/%d", update},
+		{http.MethodPatch, "/bookings/%d/deposit", deposit},
+		{http.MethodPost, "/bookings/%d/confirm", nil},
+		{http.MethodPost, "/bookings/%d/retention-hold", nil},
+	}
+	for i, tt := range vanishing {
+		t.Run("vanished "+tt.method+" "+tt.path, func(t *testing.T) {
+			b := addBooking(t, models.Booking{Status: models.StatusPending, Date: fmt.Sprintf("2026-07-2%d", i)})
+			vanishOnUpdate(t, "bookings", b.ID)
+			expectError(t, call(r, tt.method, fmt.Sprintf(tt.path, b.ID), tt.body, asAdmin...), http.StatusNotFound, models.CodeNotFound)
+		})
+	}
+}
+
+func TestCreateBookingRejectsOverlaps(t *testing.T) {
+	testDB(t)
+	existing := addBooking(t, models.Booking{Date: "2026-07-10", Time: "14:00", Duration: 3})
+	r := newRouter()
+	r.POST("/book", middleware.OptionalAdminAuth(), CreateBooking)
+	other := func(clock string, hours int) map[string]any {
+		seedCount++
+		return bookBody("2026-07-10", clock, "duration", hours,
+			"email", fmt.Sprintf("guest%d@example.com", seedCount), "phone", fmt.Sprintf("+1415666%04d", seedCount))
+	}
+
+	// The same start is caught by the slot's unique index, before there is an overlap to list.
+	expectError(t, call(r, http.MethodPost, "/book", other("14:00", 2)), http.StatusConflict, models.CodeSlotConflict)
+	for _, tt := range []struct {
+		clock string
+		hours int
+	}{{"15:00", 1}, {"13:00", 2}, {"12:00", 6}} {
+		body := expectError(t, call(r, http.MethodPost, "/book", other(tt.clock, tt.hours)), http.StatusConflict, models.CodeSlotConflict)
+		if fmt.Sprint(body["conflicts"]) != "[2:00 PM - 5:00 PM]" {
+			t.Errorf("%s for %dh: conflicts %v, want the existing booking's times", tt.clock, tt.hours, body["conflicts"])
+		}
+		if strings.Contains(fmt.Sprint(body), existing.Email) || strings.Contains(fmt.Sprint(body), existing.Name) {
+			t.Errorf("%s for %dh: the conflict shows the other customer: %v", tt.clock, tt.hours, body)
+		}
+	}
+
+	// Touching at either end is not an overlap.
+	expect(t, call(r, http.MethodPost, "/book", other("17:00", 2)), http.StatusCreated)
+	expect(t, call(r, http.MethodPost, "/book", other("12:00", 2)), http.StatusCreated)
+	var count int64
+	if err := db.DB.Model(&models.Booking{}).Where("date = ?", "2026-07-10").Count(&count).Error; err != nil || count != 3 {
+		t.Errorf("%d bookings on the day (%v), want 3", count, err)
+	}
+}
+
+func TestDeleteBooking(t *testing.T) {
+	testDB(t)
+	soft := addBooking(t, models.Booking{Date: "2026-07-10"})
+	gone := addBooking(t, models.Booking{Date: "2026-07-11"})
+	r := newRouter()
+	r.DELETE("/bookings/:id", middleware.AdminAuth(), middleware.RequireRole(middleware.RoleAdmin), DeleteBooking)
+	del := func(target string, headers ...string) *httptest.ResponseRecorder {
+		return call(r, http.MethodDelete, target, nil, headers...)
+	}
+
+	expectError(t, del(fmt.Sprintf("/bookings/%d", soft.ID)), http.StatusUnauthorized, models.CodeUnauthorized)
+	expectError(t, del(fmt.Sprintf("/bookings/%d", soft.ID), asViewer...), http.StatusForbidden, models.CodeForbidden)
+	expectError(t, del("/bookings/abc", asAdmin...), http.StatusBadRequest, models.CodeBadRequest)
+
+	w := del(fmt.Sprintf("/bookings/%d", soft.ID), asAdmin...)
+	expect(t, w, http.StatusNoContent)
+	if w.Body.Len() != 0 {
+		t.Errorf("204 with a body: %s", w.Body.String())
+	}
+	if got := reload(t, soft.ID); !got.DeletedAt.Valid || got.SlotKey != nil {
+		t.Errorf("deleted booking: deleted_at %v, slot key %v, want soft-deleted with its slot freed", got.DeletedAt, got.SlotKey)
+	}
+	expectError(t, del(fmt.Sprintf("/bookings/%d", soft.ID), asAdmin...), http.StatusNotFound, models.CodeNotFound)
+	if got := auditActions(t, soft.ID); fmt.Sprint(got) != fmt.Sprint([]string{models.AuditBookingDelete}) {
+		t.Errorf("audit = %v, want one delete", got)
+	}
+
+	expect(t, del(fmt.Sprintf("/bookings/%d?permanent=true", gone.ID), asAdmin...), http.StatusNoContent)
+	var count int64
+	if err := db.DB.Unscoped().Model(&models.Booking{}).Where("id = ?", gone.ID).Count(&count).Error; err != nil || count != 0 {
+		t.Errorf("purged booking still stored: %d (%v)", count, err)
+	}
+	if got := auditActions(t, gone.ID); fmt.Sprint(got) != fmt.Sprint([]string{models.AuditBookingPurge}) {
+		t.Errorf("audit = %v, want one purge", got)
+	}
+	// A soft-deleted booking can still be purged.
+	expect(t, del(fmt.Sprintf("/bookings/%d?permanent=true", soft.ID), asAdmin...), http.StatusNoContent)
+}
+
+func TestCreateBookingStoresUTCStart(t *testing.T) {
+	testDB(t)
+	r := newRouter()
+	r.POST("/book", middleware.OptionalAdminAuth(), CreateBooking)
+
+	w := call(r, http.MethodPost, "/book", bookBody("2026-07-10", "14:00"))
+	expect(t, w, http.StatusCreated)
+	got := reload(t, decode[struct{ Booking models.Booking }](t, w).Booking.ID)
+	want, _ := models.StartTime("2026-07-10", "14:00")
+	if got.Date != "2026-07-10" || got.Time != "14:00" || got.StartsAt == nil || !got.StartsAt.Equal(want) {
+		t.Errorf("stored %s %s starting %v, want the venue's 14:00 kept and %v stored", got.Date, got.Time, got.StartsAt, want)
+	}
+}
+
+func TestGetBookingsSorts(t *testing.T) {
+	testDB(t)
+	made := func(day int) time.Time { return time.Date(2026, 6, day, 12, 0, 0, 0, time.UTC) }
+	addBooking(t, models.Booking{Name: "carol", Date: "2026-07-12", Guests: 6, CreatedAt: made(20)})
+	addBooking(t, models.Booking{Name: "Alice", Date: "2026-07-10", Time: "16:00", Guests: 9, CreatedAt: made(25)})
+	addBooking(t, models.Booking{Name: "Bob", Date: "2026-07-10", Time: "11:00", Guests: 6, CreatedAt: made(1)})
+	r := newRouter()
+	r.GET("/bookings", GetBookings)
+
+	for query, want := range map[string]string{
+		"":                            "[Bob Alice carol]",
+		"?order=desc":                 "[carol Alice Bob]",
+		"?sort=name":                  "[Alice Bob carol]",
+		"?sort=name&order=desc":       "[carol Bob Alice]",
+		"?sort=guests":                "[carol Bob Alice]",
+		"?sort=guests&order=desc":     "[Alice Bob carol]",
+		"?sort=created_at":            "[Bob carol Alice]",
+		"?sort=created_at&order=desc": "[Alice carol Bob]",
+	} {
+		w := call(r, http.MethodGet, "/bookings"+query, nil)
+		expect(t, w, http.StatusOK)
+		names := []string{}
+		for _, b := range decode[models.BookingList](t, w).Bookings {
+			names = append(names, b.Name)
+		}
+		if fmt.Sprint(names) != want {
+			t.Errorf("GET /bookings%s = %v, want %s", query, names, want)
+		}
+	}
+	expectError(t, call(r, http.MethodGet, "/bookings?order=up", nil), http.StatusBadRequest, models.CodeBadRequest)
+}
+
+// nilList is a store whose List finds nothing and says so with a nil slice.
+type nilList struct{ store.BookingStore }
+
+func (nilList) List(context.Context, store.ListOptions) ([]models.Booking, int64, error) {
+	return nil, 0, nil
+}
+
+func TestGetBookingsEmptyIsArray(t *testing.T) {
+	r := newRouter()
+	r.GET("/bookings", GetBookings)
+	for name, s := range map[string]store.BookingStore{"memory": store.NewMemory(), "nil list": nilList{store.NewMemory()}} {
+		useStore(t, s)
+		lastLists.clear()
+		w := call(r, http.MethodGet, "/bookings?status=confirmed", nil)
+		expect(t, w, http.StatusOK)
+		if !strings.Contains(w.Body.String(), `"bookings":[]`) {
+			t.Errorf("%s: body %s, want an empty bookings array", name, w.Body.String())
+		}
+	}
+	lastLists.clear()
+}
+
+func TestValidationMessagesLocalised(t *testing.T) {
+	testDB(t)
+	r := newRouter()
+	r.POST("/book", middleware.OptionalAdminAuth(), CreateBooking)
+	body := bookBody("2026-07-20", "14:00", "name", "", "guests", 500)
+
+	tests := []struct{ header, name, guests string }{
+		{"", "Name is required", "Guests must be between 1 and 100"},
+		{"hi-IN,hi;q=0.9", "नाम आवश्यक है", "मेहमानों की संख्या 1 से 100 के बीच होनी चाहिए"},
+		{"fr, ml;q=0.5", "പേര് നിർബന്ധമാണ്", "അതിഥികളുടെ എണ്ണം 1 മുതൽ 100 വരെ ആയിരിക്കണം"},
+	}
+	for _, tt := range tests {
+		w := call(r, http.MethodPost, "/book", body, "Accept-Language", tt.header)
+		codes := fieldCodes(t, w)
+		if !hasCode(codes, "name", messages.NameRequired) || !hasCode(codes, "guests", messages.GuestsRange) {
+			t.Errorf("%q: codes %v", tt.header, codes)
+		}
+		errs := decode[struct{ Errors map[string][]string }](t, w).Errors
+		if fmt.Sprint(errs["name"]) != "["+tt.name+"]" || len(errs["guests"]) == 0 || errs["guests"][0] != tt.guests {
+			t.Errorf("%q: errors %v", tt.header, errs)
+		}
+	}
+}
+
+func TestGetBookingsScanFailure(t *testing.T) {
+	r := newRouter()
+	r.GET("/bookings", GetBookings)
+	check := func(name string) {
+		t.Helper()
+		lastLists.clear()
+		w := call(r, http.MethodGet, "/bookings", nil)
+		body := expectError(t, w, http.StatusInternalServerError, models.CodeInternal)
+		if body["message"] != "Failed to fetch bookings" || body["bookings"] != nil {
+			t.Errorf("%s: body %s, want the fetch failure and no bookings", name, w.Body.String())
+		}
+		if strings.Contains(w.Body.String(), "Scan") {
+			t.Errorf("%s: the response leaks the scan error: %s", name, w.Body.String())
+		}
+	}
+
+	useStore(t, failingStore{err: fmt.Errorf(`sql: Scan error on column index 7, name "guests": converting driver.Value type string ("many") to a int: invalid syntax`)})
+	check("fake store")
+
+	// A row the real store can't scan fails the whole list rather than dropping out of it.
+	testDB(t)
+	addBooking(t, models.Booking{})
+	bad := addBooking(t, models.Booking{Time: "18:00"})
+	if err := db.DB.Exec("UPDATE bookings SET guests = 'many' WHERE id = ?", bad.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	check("gorm store")
+	lastLists.clear()
+}
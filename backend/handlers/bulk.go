@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/notify"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxBulkIDs caps how many bookings a single POST /bookings/bulk-status may touch.
+const maxBulkIDs = 500
+
+type bulkStatusRequest struct {
+	IDs    []int64 `json:"ids" binding:"required,min=1"`
+	Status string  `json:"status" binding:"required"`
+}
+
+// BulkUpdateStatus confirms or cancels several bookings at once, reporting IDs that don't exist
+// and bookings whose current status doesn't allow the change. It is the older form of
+// BulkAction, which does the work, with its results grouped the way this endpoint always
+// answered: a count of the bookings changed and the IDs of the rest.
+func BulkUpdateStatus(c *gin.Context) {
+	var req bulkStatusRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	action := map[string]string{models.StatusConfirmed: bulkConfirm, models.StatusCancelled: bulkCancel}[req.Status]
+	if action == "" {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "Invalid status")
+		return
+	}
+	if len(req.IDs) > maxBulkIDs {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "Too many bookings in one request")
+		return
+	}
+
+	results, changed, err := bulkRun(c, action, req.IDs)
+	if err != nil {
+		serverError(c, err, "Failed to update bookings")
+		return
+	}
+	grouped := map[string][]int64{bulkNotFound: {}, bulkInvalidTransition: {}, bulkSlotTaken: {}}
+	for _, id := range req.IDs {
+		key := strconv.FormatInt(id, 10)
+		if outcome := results[key]; outcome != bulkOK && outcome != "" {
+			grouped[outcome] = append(grouped[outcome], id)
+		}
+		delete(results, key) // report duplicates once
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"updated":            len(changed),
+		"not_found":          grouped[bulkNotFound],
+		"invalid_transition": grouped[bulkInvalidTransition],
+		"slot_taken":         grouped[bulkSlotTaken],
+	})
+}
+
+// maxBulkActionIDs caps how many bookings one POST /admin/bookings/bulk may name.
+const maxBulkActionIDs = 100
+
+// Actions POST /admin/bookings/bulk can apply.
+const (
+	bulkConfirm = "confirm"
+	bulkCancel  = "cancel"
+	bulkDelete  = "delete"
+)
+
+// What happened to each booking in a bulk action.
+const (
+	bulkOK                = "ok"
+	bulkNotFound          = "not_found"
+	bulkInvalidTransition = "invalid_transition"
+	bulkSlotTaken         = "slot_taken"
+)
+
+type bulkActionRequest struct {
+	Action string  `json:"action" binding:"required"`
+	IDs    []int64 `json:"ids" binding:"required,min=1"`
+}
+
+// BulkAction confirms, cancels or soft-deletes several bookings and reports what happened
+// to each, keyed by ID, instead of failing the batch over one bad ID: 200 when every booking
+// is "ok", 207 otherwise. Cancels and deletes run in one transaction, so a database error
+// leaves every booking as it was; confirms commit one by one, so a booking whose slot has
+// since been taken is reported as "slot_taken" without holding back the rest. Bookings
+// already in the requested status count as "ok" but aren't changed or logged.
+func BulkAction(c *gin.Context) {
+	var req bulkActionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.Action != bulkConfirm && req.Action != bulkCancel && req.Action != bulkDelete {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "action must be one of confirm, cancel, delete")
+		return
+	}
+	if len(req.IDs) > maxBulkActionIDs {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, fmt.Sprintf("At most %d bookings can be changed at once", maxBulkActionIDs))
+		return
+	}
+
+	results, _, err := bulkRun(c, req.Action, req.IDs)
+	if err != nil {
+		serverError(c, err, "Failed to update bookings")
+		return
+	}
+	status := http.StatusOK
+	for _, outcome := range results {
+		if outcome != bulkOK {
+			status = http.StatusMultiStatus
+			break
+		}
+	}
+	c.JSON(status, gin.H{"results": results})
+}
+
+// bulkRun applies action to the bookings with ids, each once, as BulkAction describes, then
+// promotes the waitlist for the slots it freed and publishes the changes. It returns each
+// booking's outcome keyed by ID and the bookings whose status it changed.
+func bulkRun(c *gin.Context, action string, ids []int64) (map[string]string, []models.Booking, error) {
+	results := make(map[string]string, len(ids))
+	var freed []string
+	var changed []models.Booking
+	apply := func(ctx context.Context, tx *gorm.DB, id int64) error {
+		outcome, date, booking, err := bulkApply(c, ctx, tx, action, id)
+		if err != nil {
+			return err
+		}
+		results[strconv.FormatInt(id, 10)] = outcome
+		if date != "" {
+			freed = append(freed, date)
+		}
+		if booking != nil {
+			changed = append(changed, *booking)
+		}
+		return nil
+	}
+
+	var err error
+	if action == bulkConfirm {
+		for _, id := range ids {
+			if _, seen := results[strconv.FormatInt(id, 10)]; seen {
+				continue
+			}
+			err = inTx(c, func(ctx context.Context, tx *gorm.DB) error { return apply(ctx, tx, id) })
+			if errors.Is(err, store.ErrSlotTaken) {
+				results[strconv.FormatInt(id, 10)], err = bulkSlotTaken, nil
+			}
+			if err != nil {
+				break
+			}
+		}
+	} else {
+		err = inTx(c, func(ctx context.Context, tx *gorm.DB) error {
+			clear(results)
+			freed, changed = nil, nil
+			for _, id := range ids {
+				if _, seen := results[strconv.FormatInt(id, 10)]; seen {
+					continue
+				}
+				if err := apply(ctx, tx, id); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	promoteWaitlist(c.Request.Context(), freed...)
+	calendarChanged(ids...)
+	for _, b := range changed {
+		if b.Status == models.StatusCancelled {
+			publish(notify.BookingCancelled, b)
+		} else {
+			publish(notify.BookingConfirmed, b)
+		}
+	}
+	return results, changed, nil
+}
+
+// bulkApply applies a bulk action to booking id in tx, whose ctx is from inTx, and logs it,
+// returning the outcome, the booking's date for the waitlist if the change freed a slot, and
+// the booking if its status changed.
+func bulkApply(c *gin.Context, ctx context.Context, tx *gorm.DB, action string, id int64) (outcome, freed string, changed *models.Booking, err error) {
+	if action == bulkDelete {
+		before, err := Bookings.GetByID(ctx, id)
+		if err == nil {
+			err = Bookings.Delete(ctx, id)
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			return bulkNotFound, "", nil, nil
+		} else if err != nil {
+			return "", "", nil, err
+		}
+		if before.Status != models.StatusCancelled {
+			freed = before.Date
+		}
+		return bulkOK, freed, nil, audit(c, tx, models.AuditBookingDelete, &before.ID, before, nil)
+	}
+
+	status, logged := models.StatusConfirmed, models.AuditBookingConfirm
+	if action == bulkCancel {
+		status, logged = models.StatusCancelled, models.AuditBookingCancel
+	}
+	var before models.Booking
+	booking, err := Bookings.Modify(ctx, id, func(b *models.Booking) error {
+		before = *b
+		if b.Status != status && !models.CanTransition(b.Status, status) {
+			return errInvalidTransition
+		}
+		b.Status = status
+		return nil
+	})
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return bulkNotFound, "", nil, nil
+	case errors.Is(err, errInvalidTransition):
+		return bulkInvalidTransition, "", nil, nil
+	case err != nil:
+		return "", "", nil, err
+	case before.Status == status:
+		return bulkOK, "", nil, nil
+	}
+	if status == models.StatusCancelled {
+		freed = booking.Date
+	}
+	return bulkOK, freed, &booking, audit(c, tx, logged, &booking.ID, before, booking)
+}
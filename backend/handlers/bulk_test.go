@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"miniparty-backend/db"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+)
+
+// bulkBookings adds the bookings the bulk tests act on: two pending, one confirmed, one
+// cancelled, and a pending one without a slot key whose slot the first confirmed booking
+// holds, as a booking imported over another would be.
+func bulkBookings(t *testing.T) (pending, pending2, confirmed, cancelled, clash models.Booking) {
+	t.Helper()
+	pending = addBooking(t, models.Booking{Status: models.StatusPending, Date: "2026-07-20"})
+	pending2 = addBooking(t, models.Booking{Status: models.StatusPending, Date: "2026-07-21"})
+	confirmed = addBooking(t, models.Booking{Status: models.StatusConfirmed, Date: "2026-07-22"})
+	cancelled = addBooking(t, models.Booking{Status: models.StatusCancelled, Date: "2026-07-23"})
+	clash = addBooking(t, models.Booking{Status: models.StatusPending, Date: "2026-07-24", Time: "16:00"})
+	if err := db.DB.Model(&clash).Updates(map[string]any{"slot_key": nil, "time": confirmed.Time, "date": confirmed.Date}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return pending, pending2, confirmed, cancelled, clash
+}
+
+func TestBulkAction(t *testing.T) {
+	testDB(t)
+	pending, pending2, confirmed, cancelled, clash := bulkBookings(t)
+	r := newRouter()
+	r.POST("/admin/bookings/bulk", middleware.AdminAuth(), BulkAction)
+	bulk := func(action string, ids ...int64) (int, map[string]string) {
+		w := call(r, http.MethodPost, "/admin/bookings/bulk", map[string]any{"action": action, "ids": ids}, asAdmin...)
+		return w.Code, decode[struct{ Results map[string]string }](t, w).Results
+	}
+	key := func(b models.Booking) string { return fmt.Sprint(b.ID) }
+
+	status, results := bulk("confirm", pending.ID, confirmed.ID, cancelled.ID, clash.ID, 999, pending.ID)
+	want := map[string]string{key(pending): bulkOK, key(confirmed): bulkOK, key(cancelled): bulkInvalidTransition,
+		key(clash): bulkSlotTaken, "999": bulkNotFound}
+	if status != http.StatusMultiStatus || fmt.Sprint(results) != fmt.Sprint(want) {
+		t.Errorf("confirm = %d %v, want 207 %v", status, results, want)
+	}
+	if got := reload(t, pending.ID); got.Status != models.StatusConfirmed || got.SlotKey == nil {
+		t.Errorf("confirmed booking = %s, slot key %v", got.Status, got.SlotKey)
+	}
+	if got := reload(t, clash.ID); got.Status != models.StatusPending {
+		t.Errorf("the booking whose slot was taken is %s, want still pending", got.Status)
+	}
+	if fmt.Sprint(auditActions(t, pending.ID)) != fmt.Sprint([]string{models.AuditBookingConfirm}) || len(auditActions(t, confirmed.ID)) != 0 {
+		t.Errorf("audit = %v and %v, want one confirm and nothing for the booking already confirmed",
+			auditActions(t, pending.ID), auditActions(t, confirmed.ID))
+	}
+
+	if status, results = bulk("cancel", pending.ID, pending2.ID); status != http.StatusOK || len(results) != 2 {
+		t.Errorf("cancel = %d %v, want 200 with both ok", status, results)
+	}
+	if got := reload(t, pending2.ID); got.Status != models.StatusCancelled || got.SlotKey != nil {
+		t.Errorf("cancelled booking = %s, slot key %v, want cancelled with its slot freed", got.Status, got.SlotKey)
+	}
+	if status, _ = bulk("delete", pending2.ID); status != http.StatusOK || !reload(t, pending2.ID).DeletedAt.Valid {
+		t.Errorf("delete = %d, want 200 and the booking soft-deleted", status)
+	}
+	if got := auditActions(t, pending2.ID); fmt.Sprint(got) != fmt.Sprint([]string{models.AuditBookingCancel, models.AuditBookingDelete}) {
+		t.Errorf("audit = %v, want cancel then delete", got)
+	}
+}
+
+func TestBulkUpdateStatus(t *testing.T) {
+	testDB(t)
+	pending, _, confirmed, cancelled, clash := bulkBookings(t)
+	r := newRouter()
+	r.POST("/bookings/bulk-status", middleware.AdminAuth(), BulkUpdateStatus)
+
+	w := call(r, http.MethodPost, "/bookings/bulk-status", map[string]any{
+		"status": models.StatusConfirmed, "ids": []int64{pending.ID, confirmed.ID, cancelled.ID, clash.ID, 999, 999},
+	}, asAdmin...)
+	expect(t, w, http.StatusOK)
+	got := decode[struct {
+		Updated           int
+		NotFound          []int64 `json:"not_found"`
+		InvalidTransition []int64 `json:"invalid_transition"`
+		SlotTaken         []int64 `json:"slot_taken"`
+	}](t, w)
+	if got.Updated != 1 || fmt.Sprint(got.NotFound) != "[999]" || fmt.Sprint(got.InvalidTransition) != fmt.Sprint([]int64{cancelled.ID}) ||
+		fmt.Sprint(got.SlotTaken) != fmt.Sprint([]int64{clash.ID}) {
+		t.Errorf("bulk-status = %+v, want 1 updated, 999 not found, %d invalid and %d taken", got, cancelled.ID, clash.ID)
+	}
+	// The same bookkeeping as BulkAction: logged once, and the clash left as it was.
+	if got := auditActions(t, pending.ID); fmt.Sprint(got) != fmt.Sprint([]string{models.AuditBookingConfirm}) {
+		t.Errorf("audit = %v, want one confirm", got)
+	}
+	if got := reload(t, clash.ID); got.Status != models.StatusPending {
+		t.Errorf("the booking whose slot was taken is %s, want still pending", got.Status)
+	}
+
+	w = call(r, http.MethodPost, "/bookings/bulk-status", map[string]any{"status": models.StatusCancelled, "ids": []int64{confirmed.ID}}, asAdmin...)
+	expect(t, w, http.StatusOK)
+	if got := reload(t, confirmed.ID); got.Status != models.StatusCancelled || got.SlotKey != nil {
+		t.Errorf("cancelled booking = %s, slot key %v", got.Status, got.SlotKey)
+	}
+	expectError(t, call(r, http.MethodPost, "/bookings/bulk-status", map[string]any{"status": models.StatusConfirmed, "ids": make([]int64, maxBulkIDs+1)}, asAdmin...),
+		http.StatusBadRequest, models.CodeBadRequest)
+}
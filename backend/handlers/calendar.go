@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"miniparty-backend/calendar"
+	"miniparty-backend/db"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Calendar puts confirmed bookings on the owner's Google Calendar. main sets it when
+// GOOGLE_CALENDAR_ID is configured; nil turns calendar sync off.
+var Calendar calendar.Provider
+
+const (
+	// calendarBatch caps how many bookings one pass of SyncCalendar takes on, so a backlog
+	// (e.g. turning sync on for the first time) is worked off over several passes.
+	calendarBatch = 100
+	// calendarCallTimeout bounds the Google calls for one booking.
+	calendarCallTimeout = 30 * time.Second
+)
+
+// calendarMu keeps syncs in this process one at a time, so the sync started by a handler
+// and the background pass never both create an event for the same booking.
+var calendarMu sync.Mutex
+
+// What a pass over the calendar did.
+type calendarResult struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Deleted int `json:"deleted"`
+	Failed  int `json:"failed"`
+}
+
+// calendarChanged syncs the calendar events of the bookings with ids in the background, so
+// the request that changed them doesn't wait on Google. A sync that fails is left to the
+// next pass of SyncCalendar.
+func calendarChanged(ids ...int64) {
+	if Calendar == nil || len(ids) == 0 {
+		return
+	}
+	go func() {
+		calendarMu.Lock()
+		defer calendarMu.Unlock()
+
+		ctx := context.Background()
+		at := now().UTC()
+		bookings, _, err := Bookings.List(ctx, store.ListOptions{Filter: store.Filter{IDs: ids}, Limit: -1, IncludeDeleted: true})
+		if err != nil {
+			slog.Warn("failed to load bookings for calendar sync; will retry", "error", err)
+			return
+		}
+		var result calendarResult
+		for _, b := range bookings {
+			if calendarDue(b, at) {
+				syncCalendarEvent(ctx, b, at, &result)
+			}
+		}
+	}()
+}
+
+// calendarRemoved deletes the event of a booking that no longer exists at all.
+func calendarRemoved(b models.Booking) {
+	if Calendar == nil || b.CalendarEventID == "" {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), calendarCallTimeout)
+		defer cancel()
+		if err := Calendar.Delete(ctx, b.CalendarEventID); err != nil {
+			// The booking is gone, so only a resync's check for orphaned events will find it now.
+			slog.Warn("failed to delete calendar event of purged booking", "booking_id", b.ID, "event_id", b.CalendarEventID, "error", err)
+		}
+	}()
+}
+
+// SyncCalendar brings the calendar up to date with the bookings every interval until ctx is
+// done, catching up on syncs started by handlers that failed, and on changes (such as
+// cancelling a series) that no handler syncs straight away.
+func SyncCalendar(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !db.Ready() {
+			continue
+		}
+		if _, err := syncDueCalendarEvents(context.WithoutCancel(ctx), now().UTC()); err != nil {
+			slog.Error("failed to sync calendar", "error", err)
+		}
+	}
+}
+
+// syncDueCalendarEvents syncs up to calendarBatch bookings whose event is out of date: an
+// upcoming confirmed booking without one, a cancelled or deleted booking that still has one,
+// and a booking changed since its event was last written.
+func syncDueCalendarEvents(ctx context.Context, at time.Time) (calendarResult, error) {
+	calendarMu.Lock()
+	defer calendarMu.Unlock()
+
+	var result calendarResult
+	due, err := Queries.CalendarDue(ctx, at, calendarBatch)
+	if err != nil {
+		return result, err
+	}
+	for _, b := range due {
+		syncCalendarEvent(ctx, b, at, &result)
+	}
+	return result, nil
+}
+
+// calendarWanted reports whether b should be on the calendar: it is confirmed and not deleted.
+func calendarWanted(b models.Booking) bool {
+	return b.Status == models.StatusConfirmed && !b.DeletedAt.Valid && b.StartsAt != nil
+}
+
+// calendarDue reports whether b's event needs syncing at at, by the same rules as the
+// query behind syncDueCalendarEvents.
+func calendarDue(b models.Booking, at time.Time) bool {
+	if b.CalendarEventID == "" {
+		return calendarWanted(b) && b.StartsAt.After(at)
+	}
+	return !calendarWanted(b) || b.CalendarSyncedAt == nil || b.UpdatedAt.After(*b.CalendarSyncedAt)
+}
+
+// syncCalendarEvent makes b's calendar event match b, counting what it did in result:
+// confirmed bookings that haven't started get an event, cancelled and deleted ones lose
+// theirs, and the rest have theirs rewritten. at is when the sync started; a booking updated
+// after it is synced again next time. Failures are logged and counted, not returned, so one
+// booking can't hold up the rest.
+func syncCalendarEvent(ctx context.Context, b models.Booking, at time.Time, result *calendarResult) {
+	ctx, cancel := context.WithTimeout(ctx, calendarCallTimeout)
+	defer cancel()
+
+	action, err := "", error(nil)
+	wanted := calendarWanted(b)
+	switch {
+	case !wanted && b.CalendarEventID == "":
+		return
+	case !wanted:
+		action = "delete"
+		if err = Calendar.Delete(ctx, b.CalendarEventID); err == nil {
+			_, err = Queries.SetCalendarEvent(ctx, b.ID, b.CalendarEventID, "", &at)
+		}
+		if err == nil {
+			result.Deleted++
+		}
+	case b.CalendarEventID != "":
+		action = "update"
+		err = Calendar.Update(ctx, b.CalendarEventID, calendarEvent(b))
+		if errors.Is(err, calendar.ErrNotFound) {
+			// Deleted from the calendar by hand; put it back.
+			if _, err = Queries.SetCalendarEvent(ctx, b.ID, b.CalendarEventID, "", nil); err == nil {
+				b.CalendarEventID = ""
+				syncCalendarEvent(ctx, b, at, result)
+			}
+			break
+		}
+		if err == nil {
+			err = Queries.MarkCalendarSynced(ctx, b.ID, at)
+		}
+		if err == nil {
+			result.Updated++
+		}
+	case b.StartsAt.After(at):
+		action = "create"
+		var id string
+		if id, err = Calendar.Create(ctx, calendarEvent(b)); err != nil {
+			break
+		}
+		// Only record the event if no other instance has given the booking one meanwhile;
+		// if one has, this event is a duplicate.
+		claimed, claimErr := Queries.SetCalendarEvent(ctx, b.ID, "", id, &at)
+		switch {
+		case claimErr != nil:
+			err = claimErr
+		case !claimed:
+			err = Calendar.Delete(ctx, id)
+		default:
+			result.Created++
+		}
+	}
+	if err != nil {
+		result.Failed++
+		slog.Warn("failed to sync calendar event; will retry", "booking_id", b.ID, "action", action, "error", err)
+	}
+}
+
+// fullDayPrefix marks a buyout in a calendar event's title.
+func fullDayPrefix(b models.Booking) string {
+	if b.FullDay {
+		return "Full day - "
+	}
+	return ""
+}
+
+// calendarEvent is b as it appears on the calendar, in the venue's timezone.
+func calendarEvent(b models.Booking) calendar.Event {
+	loc := models.VenueLocation()
+	var desc strings.Builder
+	fmt.Fprintf(&desc, "Reference: %s\nName: %s\nPhone: %s\nGuests: %d", b.Reference, b.Name, b.Phone, b.Guests)
+	if b.Notes != "" {
+		fmt.Fprintf(&desc, "\nNotes: %s", b.Notes)
+	}
+	return calendar.Event{
+		BookingID:   b.ID,
+		Summary:     fmt.Sprintf("Party: %s%s (%d guests)", fullDayPrefix(b), b.Name, b.Guests),
+		Description: desc.String(),
+		Start:       b.StartsAt.In(loc),
+		End:         b.EndsAt().In(loc),
+	}
+}
+
+// ResyncCalendar repairs the calendar against the bookings: events deleted from the calendar
+// by hand are recreated, events whose booking is gone or holds another event are deleted,
+// and every booking that is due is synced like a pass of SyncCalendar, without its batch
+// limit. It answers with what it did; failures are left for the next pass to retry.
+//
+// It can take a while on a big calendar, so it isn't held to the request's database timeout.
+func ResyncCalendar(c *gin.Context) {
+	if Calendar == nil {
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Calendar sync is not configured")
+		return
+	}
+	calendarMu.Lock()
+	defer calendarMu.Unlock()
+
+	ctx := context.WithoutCancel(c.Request.Context())
+	at := now().UTC()
+
+	listCtx, cancel := context.WithTimeout(ctx, calendarCallTimeout)
+	events, err := Calendar.List(listCtx)
+	cancel()
+	if err != nil {
+		middleware.Logger(c).Error("failed to list calendar events", "error", err)
+		middleware.Fail(c, http.StatusBadGateway, models.CodeCalendarUnavailable, "Couldn't read the calendar. Please try again.")
+		return
+	}
+	onCalendar := make(map[string]bool, len(events))
+	for _, e := range events {
+		onCalendar[e.ID] = true
+	}
+
+	bookings, err := Queries.CalendarBookings(ctx, at)
+	if err != nil {
+		serverError(c, err, "Failed to resync calendar")
+		return
+	}
+
+	var result calendarResult
+	held := make(map[string]bool, len(bookings))
+	for _, b := range bookings {
+		if b.CalendarEventID != "" && !onCalendar[b.CalendarEventID] {
+			// Gone from the calendar: forget it, so a booking that should have one gets a new one.
+			if _, err := Queries.SetCalendarEvent(ctx, b.ID, b.CalendarEventID, "", nil); err != nil {
+				serverError(c, err, "Failed to resync calendar")
+				return
+			}
+			b.CalendarEventID = ""
+		}
+		held[b.CalendarEventID] = true
+		if calendarDue(b, at) {
+			syncCalendarEvent(ctx, b, at, &result)
+		}
+	}
+	for _, e := range events {
+		if held[e.ID] {
+			continue
+		}
+		if err := Calendar.Delete(ctx, e.ID); err != nil {
+			result.Failed++
+			middleware.Logger(c).Warn("failed to delete orphaned calendar event", "event_id", e.ID, "booking_id", e.BookingID, "error", err)
+			continue
+		}
+		result.Deleted++
+	}
+
+	middleware.Logger(c).Info("resynced calendar", "created", result.Created, "updated", result.Updated, "deleted", result.Deleted, "failed", result.Failed)
+	c.JSON(http.StatusOK, result)
+}
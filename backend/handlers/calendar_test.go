@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"miniparty-backend/calendar"
+	"miniparty-backend/db"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+)
+
+// fakeCalendar is a calendar.Provider keeping events in a map.
+type fakeCalendar struct {
+	mu     sync.Mutex
+	events map[string]calendar.Event
+	lastID int
+}
+
+func (f *fakeCalendar) Create(_ context.Context, e calendar.Event) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lastID++
+	id := fmt.Sprintf("evt%d", f.lastID)
+	f.events[id] = e
+	return id, nil
+}
+
+func (f *fakeCalendar) Update(_ context.Context, id string, e calendar.Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.events[id]; !ok {
+		return calendar.ErrNotFound
+	}
+	f.events[id] = e
+	return nil
+}
+
+func (f *fakeCalendar) Delete(_ context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.events, id)
+	return nil
+}
+
+func (f *fakeCalendar) List(context.Context) ([]calendar.Listed, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var listed []calendar.Listed
+	for id, e := range f.events {
+		listed = append(listed, calendar.Listed{ID: id, BookingID: e.BookingID})
+	}
+	return listed, nil
+}
+
+func useCalendar(t *testing.T) *fakeCalendar {
+	f := &fakeCalendar{events: map[string]calendar.Event{}}
+	swap[calendar.Provider](t, &Calendar, f)
+	return f
+}
+
+// syncCalendar runs one pass of SyncCalendar now, failing the test unless it did want.
+func syncCalendar(t *testing.T, want calendarResult) {
+	t.Helper()
+	// The real clock, since GORM stamps updated_at with it.
+	got, err := syncDueCalendarEvents(context.Background(), time.Now().UTC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("sync did %+v, want %+v", got, want)
+	}
+}
+
+func TestSyncCalendar(t *testing.T) {
+	testDB(t)
+	cal := useCalendar(t)
+	party := addBooking(t, models.Booking{Date: "2030-07-10", Name: "Ann", Guests: 9})
+	addBooking(t, models.Booking{Date: "2030-07-11", Status: models.StatusPending})
+	addBooking(t, models.Booking{Date: "2026-07-10"})
+
+	syncCalendar(t, calendarResult{Created: 1})
+	party = reload(t, party.ID)
+	e, ok := cal.events[party.CalendarEventID]
+	if !ok || e.BookingID != party.ID || e.Summary != "Party: Ann (9 guests)" || !e.Start.Equal(*party.StartsAt) {
+		t.Fatalf("event %q: %+v", party.CalendarEventID, e)
+	}
+	syncCalendar(t, calendarResult{})
+
+	if err := db.DB.Model(&party).Update("guests", 12).Error; err != nil {
+		t.Fatal(err)
+	}
+	syncCalendar(t, calendarResult{Updated: 1})
+	if got := cal.events[party.CalendarEventID].Summary; got != "Party: Ann (12 guests)" {
+		t.Errorf("summary after the change: %q", got)
+	}
+
+	// Deleted from the calendar by hand, it is put back on the next change.
+	delete(cal.events, party.CalendarEventID)
+	if err := db.DB.Model(&party).Update("guests", 10).Error; err != nil {
+		t.Fatal(err)
+	}
+	syncCalendar(t, calendarResult{Created: 1})
+	party = reload(t, party.ID)
+	if _, ok := cal.events[party.CalendarEventID]; !ok || len(cal.events) != 1 {
+		t.Errorf("events %v, want the booking's new one %q", cal.events, party.CalendarEventID)
+	}
+
+	if _, err := Bookings.Modify(context.Background(), party.ID, func(b *models.Booking) error {
+		b.Status = models.StatusCancelled
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	syncCalendar(t, calendarResult{Deleted: 1})
+	if got := reload(t, party.ID); len(cal.events) != 0 || got.CalendarEventID != "" {
+		t.Errorf("after cancelling: events %v, booking's event %q", cal.events, got.CalendarEventID)
+	}
+}
+
+func TestResyncCalendar(t *testing.T) {
+	testDB(t)
+	r := newRouter()
+	r.POST("/admin/calendar/resync", middleware.AdminAuth(), ResyncCalendar)
+	expectError(t, call(r, http.MethodPost, "/admin/calendar/resync", nil, asAdmin...), http.StatusNotFound, models.CodeNotFound)
+
+	cal := useCalendar(t)
+	kept := addBooking(t, models.Booking{Date: "2030-07-10"})
+	lost := addBooking(t, models.Booking{Date: "2030-07-11"})
+	syncCalendar(t, calendarResult{Created: 2})
+	lost = reload(t, lost.ID)
+	delete(cal.events, lost.CalendarEventID)
+	cal.events["stray"] = calendar.Event{BookingID: 999}
+
+	w := call(r, http.MethodPost, "/admin/calendar/resync", nil, asAdmin...)
+	expect(t, w, http.StatusOK)
+	if got := decode[calendarResult](t, w); got != (calendarResult{Created: 1, Deleted: 1}) {
+		t.Errorf("resync did %+v, want the lost event recreated and the stray one deleted", got)
+	}
+	ids := map[int64]bool{}
+	for _, e := range cal.events {
+		ids[e.BookingID] = true
+	}
+	if len(cal.events) != 2 || !ids[kept.ID] || !ids[lost.ID] {
+		t.Errorf("events %v, want one each for %d and %d", cal.events, kept.ID, lost.ID)
+	}
+}
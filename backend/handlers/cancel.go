@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"miniparty-backend/mail"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/notify"
+	"miniparty-backend/settings"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type cancelRequest struct {
+	ID    int64  `json:"id" binding:"required"`
+	Token string `json:"token" binding:"required"`
+}
+
+// CancelBookingByToken lets a customer cancel their own booking with the token issued at creation,
+// until the cancellation cutoff before it starts. Cancelling an already-cancelled booking
+// succeeds without changing anything.
+func CancelBookingByToken(c *gin.Context) {
+	var req cancelRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	var booking models.Booking
+	var deadline time.Time
+	cancelled := false
+	err := inTx(c, func(ctx context.Context, _ *gorm.DB) error {
+		cancelled = false
+		var err error
+		booking, err = Bookings.Modify(ctx, req.ID, func(b *models.Booking) error {
+			if b.CancelToken == "" || subtle.ConstantTimeCompare([]byte(b.CancelToken), []byte(req.Token)) != 1 {
+				return errWrongToken
+			}
+			if b.Status == models.StatusCancelled {
+				return nil
+			}
+			var late bool
+			if deadline, late = pastCutoff(*b); late {
+				return errCutoffPassed
+			}
+			b.Status = models.StatusCancelled
+			cancelled = true
+			return nil
+		})
+		return err
+	})
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Booking not found")
+		return
+	case errors.Is(err, errWrongToken):
+		middleware.Fail(c, http.StatusForbidden, models.CodeForbidden, "Invalid cancellation token")
+		return
+	case errors.Is(err, errCutoffPassed):
+		cutoffPassed(c, "cancelled", deadline)
+		return
+	case err != nil:
+		serverError(c, err, "Failed to cancel booking")
+		return
+	}
+
+	if cancelled {
+		promoteWaitlist(c.Request.Context(), booking.Date)
+		mail.SendAsync(Mailer, mail.Cancellation(booking))
+		dispatch(notify.Event{Type: notify.BookingCancelled, Booking: booking})
+		calendarChanged(booking.ID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Booking cancelled"})
+}
+
+// pastCutoff reports whether it is too late for the customer to cancel or move b
+// themselves, and the deadline they had.
+func pastCutoff(b models.Booking) (time.Time, bool) {
+	deadline, ok := settings.Current().CancelBy(b)
+	return deadline, ok && !now().Before(deadline)
+}
+
+// cutoffPassed answers 422 to a customer asking after the deadline for their booking to be
+// changed as done says, e.g. "cancelled".
+func cutoffPassed(c *gin.Context, done string, deadline time.Time) {
+	hours := settings.Current().CancellationCutoffHours
+	middleware.FailWith(c, http.StatusUnprocessableEntity, models.CodeCutoffPassed,
+		fmt.Sprintf("Bookings can't be %s online less than %d hours before they start. The deadline for this one was %s; please call us.",
+			done, hours, deadline.Format(deadlineLayout)),
+		gin.H{"deadline": deadline, "cutoff_hours": hours})
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/notify"
+	"miniparty-backend/settings"
+)
+
+func TestCancelByTokenNotifies(t *testing.T) {
+	testDB(t)
+	events := catchEvents(t)
+	mails := catchMail(t)
+	b := addBooking(t, models.Booking{})
+	r := newRouter()
+	r.POST("/bookings/cancel", CancelBookingByToken)
+
+	expect(t, call(r, http.MethodPost, "/bookings/cancel", map[string]any{"id": b.ID, "token": "wrong"}), http.StatusForbidden)
+	expect(t, call(r, http.MethodPost, "/bookings/cancel", map[string]any{"id": b.ID, "token": b.CancelToken}), http.StatusOK)
+	if e := events.next(t); e.Type != notify.BookingCancelled || e.Booking.ID != b.ID {
+		t.Errorf("event %s for booking %d, want booking.cancelled for %d", e.Type, e.Booking.ID, b.ID)
+	}
+	if msg := mails.next(t); msg.To != b.Email {
+		t.Errorf("cancellation emailed to %s, want %s", msg.To, b.Email)
+	}
+	if got := reload(t, b.ID); got.Status != models.StatusCancelled {
+		t.Errorf("status %s, want cancelled", got.Status)
+	}
+
+	// Cancelling again changes nothing and tells nobody.
+	expect(t, call(r, http.MethodPost, "/bookings/cancel", map[string]any{"id": b.ID, "token": b.CancelToken}), http.StatusOK)
+	select {
+	case e := <-events:
+		t.Errorf("second cancel sent %s", e.Type)
+	default:
+	}
+}
+
+func TestCancellationCutoff(t *testing.T) {
+	testDB(t)
+	// testNow is 14:30 in Kolkata, so a party at 14:00 tomorrow is inside the default
+	// 24-hour cutoff and one at 16:00 isn't.
+	late := addBooking(t, models.Booking{Date: "2026-07-02", Time: "14:00"})
+	early := addBooking(t, models.Booking{Date: "2026-07-02", Time: "16:00"})
+	room := uint(2)
+	moved := addBooking(t, models.Booking{Date: "2026-07-02", Time: "14:00", RoomID: &room})
+	r := newRouter()
+	r.POST("/bookings/cancel", CancelBookingByToken)
+	r.POST("/bookings/:id/reschedule", middleware.OptionalAdminAuth(), RescheduleBooking)
+
+	body := expectError(t, call(r, http.MethodPost, "/bookings/cancel", map[string]any{"id": late.ID, "token": late.CancelToken}),
+		http.StatusUnprocessableEntity, models.CodeCutoffPassed)
+	if body["cutoff_hours"] != 24.0 || body["deadline"] != "2026-07-01T14:00:00+05:30" {
+		t.Errorf("cutoff passed: %v, want the 24-hour cutoff and the deadline in venue time", body)
+	}
+	if got := reload(t, late.ID); got.Status != models.StatusConfirmed {
+		t.Errorf("status %s after a late cancel, want it kept", got.Status)
+	}
+	expect(t, call(r, http.MethodPost, "/bookings/cancel", map[string]any{"id": early.ID, "token": early.CancelToken}), http.StatusOK)
+
+	target := fmt.Sprintf("/bookings/%d/reschedule", moved.ID)
+	move := map[string]any{"date": "2026-07-03", "time": "14:00", "token": moved.CancelToken}
+	expectError(t, call(r, http.MethodPost, target, move), http.StatusUnprocessableEntity, models.CodeCutoffPassed)
+	// Staff aren't held to it.
+	expect(t, call(r, http.MethodPost, target, move, asAdmin...), http.StatusOK)
+
+	setVenue(t, func(v *settings.Venue) { v.CancellationCutoffHours = 0 })
+	expect(t, call(r, http.MethodPost, "/bookings/cancel", map[string]any{"id": late.ID, "token": late.CancelToken}), http.StatusOK)
+}
+
+func TestBookingGivesCancellationDeadline(t *testing.T) {
+	testDB(t)
+	r := newRouter()
+	r.POST("/book", middleware.OptionalAdminAuth(), CreateBooking)
+
+	w := call(r, http.MethodPost, "/book", bookBody("2026-07-10", "14:00"))
+	expect(t, w, http.StatusCreated)
+	if got := decode[map[string]any](t, w)["cancellation_deadline"]; got != "2026-07-09T14:00:00+05:30" {
+		t.Errorf("cancellation_deadline = %v, want a day before the party in venue time", got)
+	}
+}
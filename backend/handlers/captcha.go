@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"miniparty-backend/captcha"
+	"miniparty-backend/metrics"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Captcha checks the booking form's captcha token. main sets it when a captcha secret is
+// configured; while it is nil no check is made.
+var Captcha captcha.Verifier
+
+// checkCaptcha verifies token with Captcha, responding with 403 if it is missing or
+// rejected and 503 if the provider can't say (unless Features.CaptchaFailOpen). It reports whether the
+// request may go on.
+func checkCaptcha(c *gin.Context, token string) bool {
+	if Captcha == nil {
+		return true
+	}
+	token = strings.TrimSpace(token)
+	if token == "" {
+		metrics.BookingsRejected.WithLabelValues("captcha").Inc()
+		middleware.Fail(c, http.StatusForbidden, models.CodeCaptchaRequired, "Please complete the captcha check.")
+		return false
+	}
+
+	err := Captcha.Verify(c.Request.Context(), token, middleware.ClientIP(c))
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, captcha.ErrRejected):
+		metrics.BookingsRejected.WithLabelValues("captcha").Inc()
+		middleware.Fail(c, http.StatusForbidden, models.CodeCaptchaFailed, "The captcha check failed. Please try again.")
+		return false
+	case Features.CaptchaFailOpen:
+		middleware.Logger(c).Warn("captcha check skipped: provider unavailable", "error", err)
+		return true
+	}
+	middleware.Logger(c).Error("captcha check failed", "error", err)
+	middleware.Fail(c, http.StatusServiceUnavailable, models.CodeCaptchaUnavailable,
+		"We couldn't run the captcha check just now. Please try again in a minute.")
+	return false
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"miniparty-backend/captcha"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+)
+
+// fakeCaptcha accepts the token "good", rejects "bad" and can't be reached for anything else.
+type fakeCaptcha struct{}
+
+func (fakeCaptcha) Verify(_ context.Context, token, _ string) error {
+	switch token {
+	case "good":
+		return nil
+	case "bad":
+		return fmt.Errorf("%w: invalid-input-response", captcha.ErrRejected)
+	}
+	return fmt.Errorf("%w: timeout", captcha.ErrUnavailable)
+}
+
+func TestCaptcha(t *testing.T) {
+	testDB(t)
+	swap[captcha.Verifier](t, &Captcha, fakeCaptcha{})
+	r := newRouter()
+	r.POST("/book", middleware.OptionalAdminAuth(), CreateBooking)
+	book := func(token string) map[string]any {
+		seedCount++
+		return bookBody(fmt.Sprintf("2026-07-%02d", 10+seedCount%15), "14:00", "captcha_token", token,
+			"email", fmt.Sprintf("guest%d@example.com", seedCount), "phone", fmt.Sprintf("+1415777%04d", seedCount))
+	}
+
+	expect(t, call(r, http.MethodPost, "/book", book("good")), http.StatusCreated)
+	expectError(t, call(r, http.MethodPost, "/book", book(" ")), http.StatusForbidden, models.CodeCaptchaRequired)
+	expectError(t, call(r, http.MethodPost, "/book", book("bad")), http.StatusForbidden, models.CodeCaptchaFailed)
+	expectError(t, call(r, http.MethodPost, "/book", book("slow")), http.StatusServiceUnavailable, models.CodeCaptchaUnavailable)
+
+	// Failing open only forgives an unreachable provider, never a rejected token.
+	swap(t, &Features.CaptchaFailOpen, true)
+	expect(t, call(r, http.MethodPost, "/book", book("slow")), http.StatusCreated)
+	expectError(t, call(r, http.MethodPost, "/book", book("bad")), http.StatusForbidden, models.CodeCaptchaFailed)
+
+	// With no captcha configured nothing is checked.
+	swap[captcha.Verifier](t, &Captcha, nil)
+	expect(t, call(r, http.MethodPost, "/book", book("")), http.StatusCreated)
+}
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"miniparty-backend/checkin"
+	"miniparty-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-pdf/fpdf"
+	"gorm.io/gorm"
+)
+
+// venueName heads the printed confirmation, as it signs the emails.
+const venueName = "MiniParty"
+
+// confirmationField is one labelled line of the booking confirmation. Its value is a
+// text/template over confirmationData; a line whose value comes out empty is left off.
+type confirmationField struct {
+	label string
+	value string
+}
+
+// confirmationLayout is the wording of the booking confirmation, top to bottom. Text and
+// order change here; renderConfirmation only draws whatever it lists.
+var confirmationLayout = struct {
+	title  string
+	intro  string
+	fields []confirmationField
+	ticket string
+	footer string
+}{
+	title: "{{.Venue}} booking confirmation",
+	intro: "Please show this page at the entrance. Your reference and code identify the booking if you call us.",
+	fields: []confirmationField{
+		{"Reference", "{{.Booking.Reference}}"},
+		{"Confirmation code", "{{.Booking.ConfirmationCode}}"},
+		{"Status", "{{.Status}}"},
+		{"Name", "{{.Booking.Name}}"},
+		{"Date", "{{.Date}}"},
+		{"Time", "{{.Time}}"},
+		{"Duration", "{{.Duration}}"},
+		{"Guests", "{{.Booking.Guests}}"},
+		{"Room", "{{.Booking.RoomName}}"},
+		{"Package", "{{.Booking.PackageName}}"},
+		{"Add-ons", "{{.Addons}}"},
+		{"Price", "{{.Price}}"},
+		{"Notes", "{{.Booking.Notes}}"},
+	},
+	ticket: "Show this QR code at the entrance to check in.",
+	footer: "Issued {{.Issued}} by {{.Venue}}.",
+}
+
+// confirmationData is what the layout's templates see: the booking, and its fields worded
+// for print. Ticket is the check-in QR code as a PNG, for a booking that has one.
+type confirmationData struct {
+	Venue    string
+	Booking  models.Booking
+	Status   string
+	Date     string
+	Time     string
+	Duration string
+	Addons   string
+	Price    string
+	Issued   string
+	Ticket   []byte
+}
+
+// confirmationTemplates are confirmationLayout's texts, parsed once.
+var confirmationTemplates = func() (t struct {
+	title, intro, ticket, footer *template.Template
+	fields                       []*template.Template
+}) {
+	parse := func(text string) *template.Template {
+		return template.Must(template.New("confirmation").Parse(text))
+	}
+	t.title, t.intro = parse(confirmationLayout.title), parse(confirmationLayout.intro)
+	t.ticket, t.footer = parse(confirmationLayout.ticket), parse(confirmationLayout.footer)
+	for _, f := range confirmationLayout.fields {
+		t.fields = append(t.fields, parse(f.value))
+	}
+	return t
+}()
+
+// pdfTicketPixels is the resolution the confirmation's QR code is drawn at, and
+// pdfTicketSize its printed size in mm.
+const (
+	pdfTicketPixels = 400
+	pdfTicketSize   = 40
+)
+
+// GetConfirmationPDF renders a booking as a one-page printable confirmation, for staff or
+// for the customer with their ?code= (see findOwnBooking).
+func GetConfirmationPDF(c *gin.Context) {
+	id, ok := bookingID(c)
+	if !ok {
+		return
+	}
+
+	var booking models.Booking
+	if !findOwnBooking(c, id, &booking) {
+		return
+	}
+	if err := bookingNames(conn(c), &booking); err != nil {
+		serverError(c, err, "Failed to fetch booking")
+		return
+	}
+
+	data := confirmationFor(booking, now().In(venueLocation()))
+	if checkin.Issued(booking) {
+		var err error
+		if data.Ticket, err = checkin.PNG(booking, pdfTicketPixels); err != nil {
+			serverError(c, err, "Failed to render confirmation")
+			return
+		}
+	}
+	var buf bytes.Buffer
+	if err := renderConfirmation(&buf, data); err != nil {
+		serverError(c, err, "Failed to render confirmation")
+		return
+	}
+
+	name := booking.Reference
+	if name == "" {
+		name = fmt.Sprint(booking.ID)
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="booking-%s.pdf"`, name))
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+// bookingNames fills in the room and package names the bookings list gets from its join.
+func bookingNames(tx *gorm.DB, b *models.Booking) error {
+	var names []string
+	if b.RoomID != nil {
+		if err := tx.Model(&models.Room{}).Where("id = ?", *b.RoomID).Pluck("name", &names).Error; err != nil {
+			return err
+		}
+		if len(names) > 0 {
+			b.RoomName = names[0]
+		}
+	}
+	names = nil
+	if b.PackageID != nil {
+		if err := tx.Model(&models.Package{}).Where("id = ?", *b.PackageID).Pluck("name", &names).Error; err != nil {
+			return err
+		}
+		if len(names) > 0 {
+			b.PackageName = names[0]
+		}
+	}
+	return nil
+}
+
+// confirmationFor words b's fields for print, as of issued.
+func confirmationFor(b models.Booking, issued time.Time) confirmationData {
+	d := confirmationData{
+		Venue:   venueName,
+		Booking: b,
+		Status:  b.Status,
+		Date:    b.Date,
+		Time:    timeRange(b),
+		Issued:  issued.Format("2 January 2006 at 15:04"),
+	}
+	if b.Status != "" {
+		d.Status = strings.ToUpper(b.Status[:1]) + b.Status[1:]
+	}
+	if day, err := time.Parse(dateLayout, b.Date); err == nil {
+		d.Date = day.Format("Monday, 2 January 2006")
+	}
+	if !b.FullDay {
+		d.Duration = fmt.Sprintf("%d hours", b.Duration)
+		if b.Duration == 1 {
+			d.Duration = "1 hour"
+		}
+	}
+	addons := make([]string, len(b.Addons))
+	for i, a := range b.Addons {
+		addons[i] = fmt.Sprintf("%s (%s)", a.Name, cents(a.PriceCents))
+	}
+	d.Addons = strings.Join(addons, ", ")
+	if total := b.PriceCents + models.AddonsTotal(b.Addons); total > 0 {
+		d.Price = cents(total)
+	}
+	return d
+}
+
+// cents writes an amount in cents as "1250.00".
+func cents(n int) string {
+	return fmt.Sprintf("%d.%02d", n/100, n%100)
+}
+
+func renderConfirmation(buf *bytes.Buffer, d confirmationData) error {
+	var err error
+	text := func(t *template.Template) string {
+		var out strings.Builder
+		if err == nil {
+			err = t.Execute(&out, d)
+		}
+		return strings.TrimSpace(out.String())
+	}
+	title, intro := text(confirmationTemplates.title), text(confirmationTemplates.intro)
+	ticket, footer := text(confirmationTemplates.ticket), text(confirmationTemplates.footer)
+	values := make([]string, len(confirmationTemplates.fields))
+	for i, t := range confirmationTemplates.fields {
+		values[i] = text(t)
+	}
+	if err != nil {
+		return err
+	}
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.MultiCell(0, 10, tr(title), "", "L", false)
+	pdf.Ln(2)
+	pdf.SetFont("Helvetica", "", 11)
+	pdf.MultiCell(0, 6, tr(intro), "", "L", false)
+	pdf.Ln(6)
+
+	// Values wrap within their column, so long names and notes push the lines below down
+	// rather than run off the page.
+	const labelWidth = 45
+	for i, f := range confirmationLayout.fields {
+		if values[i] == "" {
+			continue
+		}
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.CellFormat(labelWidth, 7, tr(f.label), "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.MultiCell(0, 7, tr(values[i]), "", "L", false)
+	}
+
+	if d.Ticket != nil {
+		pdf.Ln(6)
+		opts := fpdf.ImageOptions{ImageType: "PNG"}
+		pdf.RegisterImageOptionsReader("ticket", opts, bytes.NewReader(d.Ticket))
+		pdf.ImageOptions("ticket", -1, -1, pdfTicketSize, pdfTicketSize, true, opts, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.MultiCell(0, 6, tr(ticket), "", "L", false)
+	}
+
+	pdf.Ln(8)
+	pdf.SetFont("Helvetica", "I", 9)
+	pdf.MultiCell(0, 5, tr(footer), "", "L", false)
+	return pdf.Output(buf)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+)
+
+func TestConfirmationFor(t *testing.T) {
+	b := newBooking(models.Booking{Duration: 1, PriceCents: 12000,
+		Addons: []models.BookingAddon{{Name: "Cake", PriceCents: 2550}, {Name: "Balloons", PriceCents: 500}}})
+	d := confirmationFor(b, testNow)
+	if d.Status != "Confirmed" || d.Date != "Friday, 10 July 2026" || d.Time != "14:00 - 15:00" || d.Duration != "1 hour" {
+		t.Errorf("worded as %+v", d)
+	}
+	if d.Addons != "Cake (25.50), Balloons (5.00)" || d.Price != "150.50" || d.Issued != "1 July 2026 at 09:00" {
+		t.Errorf("add-ons %q, price %q, issued %q; want both add-ons, the total with them and the issue time", d.Addons, d.Price, d.Issued)
+	}
+
+	full := confirmationFor(newBooking(models.Booking{FullDay: true}), testNow)
+	if full.Time != "Full day" || full.Duration != "" || full.Price != "" {
+		t.Errorf("full day without a price: %+v, want no duration or price line", full)
+	}
+}
+
+func TestRenderConfirmationSkipsEmptyLines(t *testing.T) {
+	for _, b := range []models.Booking{
+		newBooking(models.Booking{}),
+		newBooking(models.Booking{Name: "Zoë Ålander", Notes: strings.Repeat("A long note about the cake and balloons. ", 20)}),
+	} {
+		var buf bytes.Buffer
+		if err := renderConfirmation(&buf, confirmationFor(b, testNow)); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF")) {
+			t.Errorf("%q: not a PDF", b.Name)
+		}
+	}
+}
+
+func TestGetConfirmationPDF(t *testing.T) {
+	testDB(t)
+	b := addBooking(t, models.Booking{})
+	r := newRouter()
+	r.GET("/bookings/:id/confirmation.pdf", middleware.OptionalAdminAuth(), GetConfirmationPDF)
+	target := fmt.Sprintf("/bookings/%d/confirmation.pdf", b.ID)
+
+	for name, w := range map[string]*httptest.ResponseRecorder{
+		"staff":    call(r, http.MethodGet, target, nil, asViewer...),
+		"customer": call(r, http.MethodGet, target+"?code="+strings.ToLower(b.ConfirmationCode), nil),
+	} {
+		expect(t, w, http.StatusOK)
+		if w.Header().Get("Content-Type") != "application/pdf" || !bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF")) {
+			t.Errorf("%s: %s, want a PDF", name, w.Header().Get("Content-Type"))
+		}
+		if got, want := w.Header().Get("Content-Disposition"), `attachment; filename="booking-`+b.Reference+`.pdf"`; got != want {
+			t.Errorf("%s: Content-Disposition %q, want %q", name, got, want)
+		}
+	}
+
+	// A wrong code looks the same as a booking that doesn't exist.
+	expectError(t, call(r, http.MethodGet, target+"?code=WRONG", nil), http.StatusNotFound, models.CodeNotFound)
+	expectError(t, call(r, http.MethodGet, target, nil), http.StatusNotFound, models.CodeNotFound)
+	expectError(t, call(r, http.MethodGet, "/bookings/999/confirmation.pdf?code="+b.ConfirmationCode, nil), http.StatusNotFound, models.CodeNotFound)
+}
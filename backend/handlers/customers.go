@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Ways EraseCustomer can erase a customer's bookings.
+const (
+	// eraseDelete removes the bookings for good.
+	eraseDelete = "delete"
+	// eraseAnonymize keeps the bookings, so stats still count them, with the customer's
+	// details replaced by models.Redacted.
+	eraseAnonymize = "anonymize"
+)
+
+// personalFields are the JSON fields that identify a customer, in bookings, waitlist entries
+// and anything that embeds them.
+var personalFields = map[string]bool{"name": true, "email": true, "phone": true, "notes": true}
+
+// erasure is what EraseCustomer found for an email, and then erased.
+type erasure struct {
+	Mode            string                 `json:"mode"`
+	DryRun          bool                   `json:"dry_run"`
+	Bookings        int                    `json:"bookings"`
+	WaitlistEntries int                    `json:"waitlist_entries"`
+	IdempotencyKeys int                    `json:"idempotency_keys"`
+	AuditEntries    int                    `json:"audit_entries"`
+	BookingIDs      []int64                `json:"booking_ids"`
+	Matched         []models.Booking       `json:"matched_bookings,omitempty"`
+	MatchedWaitlist []models.WaitlistEntry `json:"matched_waitlist,omitempty"`
+}
+
+// GetCustomerBookings lists every booking made with the :email in the path, in any case and
+// whatever its status, newest party first and paginated like GET /bookings, under a summary
+// of them all. ?phone= also matches the bookings made with that number; a booking matching
+// both is listed once. A customer with no bookings gets an empty list.
+func GetCustomerBookings(c *gin.Context) {
+	email := strings.ToLower(strings.TrimSpace(c.Param("email")))
+	if !strings.Contains(email, "@") {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "The customer's email is required")
+		return
+	}
+	filter := store.Filter{Email: email}
+	if v := c.Query("phone"); v != "" {
+		phone, err := normalizePhone(v, defaultCountry())
+		if err != nil {
+			middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "phone is not a valid phone number")
+			return
+		}
+		filter.Phone = phone
+	}
+	page, perPage, err := pagination(c)
+	if err != nil {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, err.Error())
+		return
+	}
+
+	bookings, total, err := Bookings.List(c.Request.Context(), store.ListOptions{
+		Filter: filter,
+		Sort:   store.Sort{Field: "date", Desc: true},
+		Offset: (page - 1) * perPage,
+		Limit:  perPage,
+	})
+	if err != nil {
+		serverError(c, err, "Failed to fetch bookings")
+		return
+	}
+	summary, err := Queries.CustomerSummary(c.Request.Context(), filter, now().In(venueLocation()).Format(dateLayout))
+	if err != nil {
+		serverError(c, err, "Failed to fetch bookings")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"email":    email,
+		"phone":    filter.Phone,
+		"summary":  summary,
+		"bookings": bookings,
+		"total":    total,
+		"page":     page,
+		"per_page": perPage,
+	})
+}
+
+// EraseCustomer honours a request to delete a customer's data. It finds every booking for
+// ?email=, soft-deleted ones included and ignoring case, and with ?mode=delete removes them
+// and their add-ons, or with ?mode=anonymize redacts their name, email, phone and notes but
+// keeps the dates, guests and prices. Either way the customer's waitlist entries and stored
+// idempotent responses are deleted, and their details are redacted from the audit log.
+//
+// ?dry_run=true reports what would go without touching anything. Bookings still to come are
+// refused with 409: they should be cancelled, and the customer told, first.
+func EraseCustomer(c *gin.Context) {
+	email := strings.ToLower(strings.TrimSpace(c.Query("email")))
+	if !strings.Contains(email, "@") {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "email is required")
+		return
+	}
+	mode := c.Query("mode")
+	if mode != eraseDelete && mode != eraseAnonymize {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "mode must be delete or anonymize")
+		return
+	}
+	result := erasure{Mode: mode, DryRun: c.Query("dry_run") == "true", BookingIDs: []int64{}}
+
+	var upcoming, bookings []models.Booking
+	err := inTx(c, func(ctx context.Context, tx *gorm.DB) error {
+		var err error
+		bookings, _, err = Bookings.List(ctx, store.ListOptions{Filter: store.Filter{Email: email},
+			Sort: store.Sort{Field: "created_at"}, Limit: -1, IncludeDeleted: true})
+		if err != nil {
+			return err
+		}
+		today := now().Format(dateLayout)
+		for _, b := range bookings {
+			result.BookingIDs = append(result.BookingIDs, b.ID)
+			if b.Status != models.StatusCancelled && !b.DeletedAt.Valid && b.Date >= today {
+				upcoming = append(upcoming, b)
+			}
+		}
+		if len(upcoming) > 0 {
+			return nil
+		}
+		var waitlist []models.WaitlistEntry
+		if err := tx.Where("LOWER(email) = ?", email).Order("id ASC").Find(&waitlist).Error; err != nil {
+			return err
+		}
+		keys, err := idempotencyKeysFor(tx, email)
+		if err != nil {
+			return err
+		}
+		var entries []models.AuditEntry
+		if len(bookings) > 0 {
+			if err := tx.Where("booking_id IN ?", result.BookingIDs).Find(&entries).Error; err != nil {
+				return err
+			}
+		}
+		result.Bookings, result.WaitlistEntries = len(bookings), len(waitlist)
+		result.IdempotencyKeys, result.AuditEntries = len(keys), len(entries)
+		if result.DryRun {
+			result.Matched, result.MatchedWaitlist = bookings, waitlist
+			return nil
+		}
+
+		if len(bookings) > 0 {
+			if err := eraseBookings(ctx, mode, result.BookingIDs); err != nil {
+				return err
+			}
+		}
+		if len(waitlist) > 0 {
+			if err := tx.Delete(&waitlist).Error; err != nil {
+				return err
+			}
+		}
+		if len(keys) > 0 {
+			if err := tx.Where("key IN ?", keys).Delete(&models.IdempotencyKey{}).Error; err != nil {
+				return err
+			}
+		}
+		if err := redactAudit(tx, entries); err != nil {
+			return err
+		}
+		// The entry says what was erased but not whose, or it would keep the email it erased.
+		return audit(c, tx, models.AuditCustomerErase, nil, nil, result)
+	})
+	if err != nil {
+		serverError(c, err, "Failed to erase customer")
+		return
+	}
+	if len(upcoming) > 0 {
+		middleware.FailWith(c, http.StatusConflict, models.CodeUpcomingBookings,
+			"This customer has bookings still to come; cancel them before erasing their data", gin.H{"upcoming": upcoming})
+		return
+	}
+	if result.DryRun {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	if mode == eraseDelete {
+		for _, b := range bookings {
+			calendarRemoved(b)
+		}
+	} else {
+		// A confirmed booking's calendar event names the customer; the sync rewrites it.
+		calendarChanged(result.BookingIDs...)
+	}
+	middleware.Logger(c).Warn("customer data erased", "mode", mode, "bookings", result.Bookings,
+		"waitlist_entries", result.WaitlistEntries, "booking_ids", result.BookingIDs)
+	// The remembered booking lists may show the customer; drop them rather than find out.
+	lastLists.clear()
+	c.JSON(http.StatusOK, result)
+}
+
+// eraseBookings deletes or anonymizes the bookings with ids, soft-deleted or not.
+func eraseBookings(ctx context.Context, mode string, ids []int64) error {
+	if mode == eraseDelete {
+		return Queries.DeleteBookings(ctx, ids)
+	}
+	return Queries.AnonymizeBookings(ctx, ids)
+}
+
+// redactAudit removes the customer's details from the snapshots in entries.
+func redactAudit(tx *gorm.DB, entries []models.AuditEntry) error {
+	for _, e := range entries {
+		before, after := redactSnapshot(e.Before), redactSnapshot(e.After)
+		if err := tx.Model(&e).Updates(map[string]any{"before": before, "after": after}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// idempotencyKeysFor returns the keys whose stored response names email, such as the
+// booking POST /book made with one. There are only a day's worth, so they are searched here
+// rather than by a query that would have to understand JSON on both databases.
+func idempotencyKeysFor(tx *gorm.DB, email string) ([]string, error) {
+	var stored []models.IdempotencyKey
+	if err := tx.Select("key", "response").Where("response IS NOT NULL").Find(&stored).Error; err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, k := range stored {
+		var v any
+		if json.Unmarshal(k.Response, &v) == nil && mentionsEmail(v, email) {
+			keys = append(keys, k.Key)
+		}
+	}
+	return keys, nil
+}
+
+// mentionsEmail reports whether any "email" field in the decoded JSON value v is email.
+func mentionsEmail(v any, email string) bool {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, field := range v {
+			if s, ok := field.(string); ok && k == "email" && strings.ToLower(strings.TrimSpace(s)) == email {
+				return true
+			}
+			if mentionsEmail(field, email) {
+				return true
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if mentionsEmail(item, email) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// redactSnapshot replaces the personal fields of every object in s with models.Redacted.
+// Snapshots that can't be decoded are dropped rather than kept as they are.
+func redactSnapshot(s models.Snapshot) models.Snapshot {
+	if s == "" {
+		return s
+	}
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return ""
+	}
+	raw, err := json.Marshal(redact(v))
+	if err != nil {
+		return ""
+	}
+	return models.Snapshot(raw)
+}
+
+func redact(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, field := range v {
+			if _, ok := field.(string); ok && personalFields[k] {
+				v[k] = models.Redacted
+			} else {
+				v[k] = redact(field)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redact(item)
+		}
+	}
+	return v
+}
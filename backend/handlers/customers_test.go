@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"miniparty-backend/db"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+)
+
+// erasable gives ada@example.com two past bookings, one soft-deleted, a waitlist entry, a
+// stored idempotent response and an audit entry naming the customer, and returns the bookings.
+func erasable(t *testing.T) (past, deleted models.Booking) {
+	t.Helper()
+	past = addBooking(t, models.Booking{Name: "Ada", Email: "Ada@Example.com", Date: "2026-06-20", Notes: "nut allergy"})
+	deleted = addBooking(t, models.Booking{Name: "Ada", Email: "ada@example.com", Date: "2026-06-21"})
+	if err := db.DB.Delete(&deleted).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range []any{
+		&models.WaitlistEntry{Name: "Ada", Email: "ADA@example.com", Phone: "+14155550100", Date: "2026-07-20", Time: "10:00", Duration: 2, Guests: 4},
+		&models.IdempotencyKey{Key: "ada-key", RequestHash: "h", Status: http.StatusCreated,
+			Response: []byte(`{"booking":{"email":"ada@example.com"}}`), ExpiresAt: testNow.Add(24 * time.Hour)},
+		&models.IdempotencyKey{Key: "other-key", RequestHash: "h", Status: http.StatusCreated,
+			Response: []byte(`{"booking":{"email":"grace@example.com"}}`), ExpiresAt: testNow.Add(24 * time.Hour)},
+		&models.AuditEntry{Actor: "session", Role: middleware.RoleAdmin, Action: models.AuditBookingUpdate, BookingID: &past.ID,
+			Before: `{"name":"Ada","email":"ada@example.com","guests":4}`, After: `{"name":"Ada","email":"ada@example.com","guests":6}`},
+	} {
+		if err := db.DB.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return past, deleted
+}
+
+// countRows counts the rows of model matching where, soft-deleted or not.
+func countRows(t *testing.T, model any, where string, args ...any) int64 {
+	t.Helper()
+	var n int64
+	if err := db.DB.Unscoped().Model(model).Where(where, args...).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestEraseCustomer(t *testing.T) {
+	testDB(t)
+	past, deleted := erasable(t)
+	r := newRouter()
+	r.DELETE("/admin/customers", middleware.AdminAuth(), EraseCustomer)
+
+	w := call(r, http.MethodDelete, "/admin/customers?email=ada@example.com&mode=anonymize&dry_run=true", nil, asAdmin...)
+	expect(t, w, http.StatusOK)
+	got := decode[erasure](t, w)
+	if !got.DryRun || got.Bookings != 2 || got.WaitlistEntries != 1 || got.IdempotencyKeys != 1 || got.AuditEntries != 1 || len(got.Matched) != 2 {
+		t.Errorf("dry run = %+v, want both bookings, the entry, the key and the audit entry", got)
+	}
+	if reload(t, past.ID).Name != "Ada" || countRows(t, &models.WaitlistEntry{}, "1 = 1") != 1 {
+		t.Fatal("the dry run erased something")
+	}
+
+	w = call(r, http.MethodDelete, "/admin/customers?email=ada@example.com&mode=anonymize", nil, asAdmin...)
+	expect(t, w, http.StatusOK)
+	for _, id := range []int64{past.ID, deleted.ID} {
+		b := reload(t, id)
+		if b.Name != models.Redacted || b.Email != models.Redacted || b.Phone != models.Redacted || b.CancelToken != "" || b.Guests != 4 {
+			t.Errorf("booking %d after anonymizing: %+v, want the customer redacted and the party kept", id, b)
+		}
+	}
+	if n := countRows(t, &models.WaitlistEntry{}, "1 = 1"); n != 0 {
+		t.Errorf("%d waitlist entries left, want none", n)
+	}
+	if countRows(t, &models.IdempotencyKey{}, "key = ?", "ada-key") != 0 || countRows(t, &models.IdempotencyKey{}, "key = ?", "other-key") != 1 {
+		t.Error("want only the customer's idempotency key deleted")
+	}
+	var entries []models.AuditEntry
+	if err := db.DB.Order("id").Find(&entries).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || strings.Contains(string(entries[0].Before+entries[0].After), "ada") || !strings.Contains(string(entries[0].After), `"guests":6`) {
+		t.Errorf("audit log %+v, want the customer redacted and an erase entry", entries)
+	}
+	if last := entries[len(entries)-1]; last.Action != models.AuditCustomerErase || strings.Contains(string(last.After), "ada") {
+		t.Errorf("erase entry %+v, want one that doesn't name the customer", last)
+	}
+}
+
+func TestEraseCustomerDeletes(t *testing.T) {
+	testDB(t)
+	past, deleted := erasable(t)
+	kept := addBooking(t, models.Booking{Email: "grace@example.com", Date: "2026-06-20", Time: "10:00"})
+	r := newRouter()
+	r.DELETE("/admin/customers", middleware.AdminAuth(), EraseCustomer)
+
+	w := call(r, http.MethodDelete, "/admin/customers?email=ada@example.com&mode=delete", nil, asAdmin...)
+	expect(t, w, http.StatusOK)
+	if got := decode[erasure](t, w); got.Bookings != 2 || len(got.BookingIDs) != 2 || got.Matched != nil {
+		t.Errorf("erasure = %+v, want both bookings listed by id", got)
+	}
+	if n := countRows(t, &models.Booking{}, "id IN ?", []int64{past.ID, deleted.ID}); n != 0 {
+		t.Errorf("%d of the customer's bookings left, want none", n)
+	}
+	if reload(t, kept.ID).Email != "grace@example.com" {
+		t.Error("another customer's booking changed")
+	}
+
+	// Nothing left to find is still a success.
+	w = call(r, http.MethodDelete, "/admin/customers?email=ada@example.com&mode=delete", nil, asAdmin...)
+	expect(t, w, http.StatusOK)
+	if got := decode[erasure](t, w); got.Bookings != 0 {
+		t.Errorf("erasing again = %+v, want nothing found", got)
+	}
+}
+
+func TestEraseCustomerRefused(t *testing.T) {
+	testDB(t)
+	upcoming := addBooking(t, models.Booking{Email: "ada@example.com", Date: "2026-07-10"})
+	addBooking(t, models.Booking{Email: "ada@example.com", Date: "2026-07-11", Status: models.StatusCancelled})
+	r := newRouter()
+	r.DELETE("/admin/customers", middleware.AdminAuth(), EraseCustomer)
+
+	body := expectError(t, call(r, http.MethodDelete, "/admin/customers?email=ada@example.com&mode=delete", nil, asAdmin...),
+		http.StatusConflict, models.CodeUpcomingBookings)
+	if list, _ := body["upcoming"].([]any); len(list) != 1 {
+		t.Errorf("upcoming = %v, want only the booking still to come", body["upcoming"])
+	}
+	if reload(t, upcoming.ID).Email != "ada@example.com" {
+		t.Error("a refused erasure changed the booking")
+	}
+
+	for _, query := range []string{"?mode=delete", "?email=ada&mode=delete", "?email=ada@example.com", "?email=ada@example.com&mode=forget"} {
+		expectError(t, call(r, http.MethodDelete, "/admin/customers"+query, nil, asAdmin...), http.StatusBadRequest, models.CodeBadRequest)
+	}
+}
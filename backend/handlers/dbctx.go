@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"miniparty-backend/db"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// conn returns the database handle bound to the request's context, so queries are
+// abandoned when the client goes away or the per-request timeout expires.
+func conn(c *gin.Context) *gorm.DB {
+	return db.DB.WithContext(c.Request.Context())
+}
+
+// affected returns result's error, or gorm.ErrRecordNotFound if it matched no rows: the row
+// it was meant to change was deleted after it was read.
+func affected(result *gorm.DB) error {
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+// unavailableRetryAfter is how long clients are asked to wait, in seconds, while the
+// database can't be reached.
+const unavailableRetryAfter = "30"
+
+// serverError logs err and responds with msg as a 500, or with a distinct 503/504 when the
+// database can't be reached or the request's context ran out or was cancelled.
+func serverError(c *gin.Context, err error, msg string) {
+	middleware.Logger(c).Error(msg, "error", err)
+
+	switch {
+	case db.Unreachable(err):
+		c.Header("Retry-After", unavailableRetryAfter)
+		middleware.Fail(c, http.StatusServiceUnavailable, models.CodeDBUnavailable,
+			"We're temporarily unable to take bookings. Please try again in a few minutes.")
+	case errors.Is(err, context.DeadlineExceeded):
+		middleware.Fail(c, http.StatusGatewayTimeout, models.CodeTimeout, "The database took too long to respond. Please try again.")
+	case errors.Is(err, context.Canceled):
+		middleware.Fail(c, http.StatusServiceUnavailable, models.CodeCancelled, "The request was cancelled before it completed.")
+	default:
+		// Recovery hands it to the error reporter.
+		_ = c.Error(fmt.Errorf("%s: %w", msg, err))
+		middleware.Fail(c, http.StatusInternalServerError, models.CodeInternal, msg)
+	}
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	sqldriver "database/sql/driver"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"miniparty-backend/db"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestServerErrorResponses(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{context.DeadlineExceeded, http.StatusGatewayTimeout, models.CodeTimeout},
+		{fmt.Errorf("query: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, models.CodeTimeout},
+		{context.Canceled, http.StatusServiceUnavailable, models.CodeCancelled},
+		{errors.New("disk full"), http.StatusInternalServerError, models.CodeInternal},
+		{db.ErrUnavailable, http.StatusServiceUnavailable, models.CodeDBUnavailable},
+		{fmt.Errorf("query: %w", sqldriver.ErrBadConn), http.StatusServiceUnavailable, models.CodeDBUnavailable},
+	}
+	for _, tt := range tests {
+		r := newRouter()
+		r.GET("/", func(c *gin.Context) { serverError(c, tt.err, "Failed to fetch bookings") })
+		w := call(r, http.MethodGet, "/", nil)
+		expectError(t, w, tt.status, tt.code)
+		if retry := w.Header().Get("Retry-After"); (tt.code == models.CodeDBUnavailable) != (retry == unavailableRetryAfter) {
+			t.Errorf("%v: Retry-After %q", tt.err, retry)
+		}
+	}
+}
+
+func TestRequestTimeoutReachesTheDatabase(t *testing.T) {
+	testDB(t)
+	addBooking(t, models.Booking{})
+	r := newRouter()
+	// Expired before the handler runs, so the query can only fail.
+	r.GET("/bookings", middleware.Timeout(-time.Second), GetBookings)
+	r.GET("/bookings/:id", middleware.Timeout(-time.Second), GetBooking)
+
+	expectError(t, call(r, http.MethodGet, "/bookings", nil), http.StatusGatewayTimeout, models.CodeTimeout)
+	expectError(t, call(r, http.MethodGet, "/bookings/1", nil), http.StatusGatewayTimeout, models.CodeTimeout)
+}
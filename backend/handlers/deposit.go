@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type depositRequest struct {
+	Amount int   `json:"amount" binding:"min=0"`
+	Paid   *bool `json:"paid" binding:"required"`
+}
+
+// errDepositCovered refuses to mark a deposit unpaid when the payments recorded cover it.
+var errDepositCovered = errors.New("deposit is covered by payments")
+
+// UpdateDeposit sets the deposit a booking has to pay, its deposit_due_cents. With paid true
+// whatever is still owed towards it is recorded as a payment, as RecordPayment would, so
+// amount_paid_cents, payment_status and deposit_paid all agree. Payments are never taken
+// back, so paid false for a deposit they already cover is a 409; that and a cancelled
+// booking answer like RecordPayment.
+func UpdateDeposit(c *gin.Context) {
+	id, ok := bookingID(c)
+	if !ok {
+		return
+	}
+
+	var req depositRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	var booking models.Booking
+	err := inTx(c, func(ctx context.Context, tx *gorm.DB) error {
+		var before models.Booking
+		var payment *models.Payment
+		var err error
+		booking, err = Bookings.Modify(ctx, id, func(b *models.Booking) error {
+			before = *b
+			if b.Status == models.StatusCancelled {
+				return errBookingCancelled
+			}
+			b.DepositDueCents = req.Amount
+			if owed := b.DepositDueCents - b.AmountPaidCents; *req.Paid && owed > 0 {
+				payment = &models.Payment{AmountCents: owed, Method: models.PaymentOther, Reference: "Deposit marked paid"}
+				if err := addPayment(b, payment); err != nil {
+					return err
+				}
+			} else {
+				b.SyncDeposit(now().UTC())
+			}
+			if !*req.Paid && b.DepositPaid {
+				return errDepositCovered
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if payment != nil {
+			if err := tx.Create(payment).Error; err != nil {
+				return err
+			}
+		}
+		return audit(c, tx, models.AuditBookingDeposit, &booking.ID, before, booking)
+	})
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Booking not found")
+		return
+	case errors.Is(err, errBookingCancelled):
+		middleware.Fail(c, http.StatusConflict, models.CodeBookingCancelled, "Deposits can't be recorded for a cancelled booking")
+		return
+	case errors.Is(err, errDepositCovered):
+		middleware.Fail(c, http.StatusConflict, models.CodeDepositPaid, "The payments recorded already cover this deposit")
+		return
+	case err != nil:
+		serverError(c, err, "Failed to update deposit")
+		return
+	}
+
+	c.JSON(http.StatusOK, booking)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/store"
+)
+
+func TestUpdateDeposit(t *testing.T) {
+	testDB(t)
+	b := addBooking(t, models.Booking{})
+	r := newRouter()
+	r.PATCH("/bookings/:id/deposit", middleware.AdminAuth(), UpdateDeposit)
+	r.POST("/bookings/:id/payments", middleware.AdminAuth(), RecordPayment)
+	r.GET("/bookings/:id/payments", middleware.AdminAuth(), GetPayments)
+	r.GET("/bookings", GetBookings)
+	r.GET("/admin/stats", GetStats)
+	deposit := fmt.Sprintf("/bookings/%d/deposit", b.ID)
+	paidList := func() int64 {
+		t.Helper()
+		lastLists.clear()
+		w := call(r, http.MethodGet, "/bookings?payment_status=paid", nil)
+		expect(t, w, http.StatusOK)
+		return decode[struct{ Total int64 }](t, w).Total
+	}
+	outstanding := func() store.OutstandingDeposits {
+		t.Helper()
+		return decode[struct {
+			Outstanding store.OutstandingDeposits `json:"outstanding_deposits"`
+		}](t, call(r, http.MethodGet, "/admin/stats", nil)).Outstanding
+	}
+
+	w := call(r, http.MethodPatch, deposit, map[string]any{"amount": 5000, "paid": false}, asAdmin...)
+	expect(t, w, http.StatusOK)
+	if got := decode[models.Booking](t, w); got.DepositDueCents != 5000 || got.DepositPaid || got.PaymentStatus != models.PaymentUnpaid {
+		t.Errorf("deposit set: %+v, want 5000 due and unpaid", got)
+	}
+	expect(t, call(r, http.MethodPost, fmt.Sprintf("/bookings/%d/payments", b.ID), map[string]any{"amount_cents": 2000, "method": "cash"}, asAdmin...),
+		http.StatusCreated)
+	if got := outstanding(); got != (store.OutstandingDeposits{Bookings: 1, AmountCents: 3000}) {
+		t.Errorf("after a part payment: outstanding %+v, want 3000 owed", got)
+	}
+
+	// Marking it paid records the rest, so the list, the stats and deposit_paid agree.
+	w = call(r, http.MethodPatch, deposit, map[string]any{"amount": 5000, "paid": true}, asAdmin...)
+	expect(t, w, http.StatusOK)
+	if got := decode[models.Booking](t, w); got.AmountPaidCents != 5000 || !got.DepositPaid || got.DepositPaidAt == nil || got.PaymentStatus != models.PaymentPaid {
+		t.Errorf("marked paid: %+v, want 5000 paid and the deposit paid", got)
+	}
+	payments := decode[struct{ Payments []models.Payment }](t, call(r, http.MethodGet, fmt.Sprintf("/bookings/%d/payments", b.ID), nil, asAdmin...)).Payments
+	if len(payments) != 2 || payments[1].AmountCents != 3000 || payments[1].Method != models.PaymentOther {
+		t.Errorf("payments %+v, want the cash and 3000 for the rest", payments)
+	}
+	if n := paidList(); n != 1 {
+		t.Errorf("?payment_status=paid lists %d, want the booking", n)
+	}
+	if got := outstanding(); got != (store.OutstandingDeposits{}) {
+		t.Errorf("marked paid: outstanding %+v, want none", got)
+	}
+
+	// Payments stay recorded, so they can't be marked unpaid; a larger deposit is owed again.
+	expectError(t, call(r, http.MethodPatch, deposit, map[string]any{"amount": 5000, "paid": false}, asAdmin...),
+		http.StatusConflict, models.CodeDepositPaid)
+	w = call(r, http.MethodPatch, deposit, map[string]any{"amount": 8000, "paid": false}, asAdmin...)
+	expect(t, w, http.StatusOK)
+	if got := decode[models.Booking](t, w); got.DepositPaid || got.DepositPaidAt != nil || got.PaymentStatus != models.PaymentPartial {
+		t.Errorf("deposit raised: %+v, want it partly paid", got)
+	}
+	if n := paidList(); n != 0 {
+		t.Errorf("?payment_status=paid lists %d after the deposit was raised, want none", n)
+	}
+	if got := auditActions(t, b.ID); len(got) != 4 || got[0] != models.AuditBookingDeposit || got[1] != models.AuditBookingPayment {
+		t.Errorf("audit %v, want the deposit changes and the payment", got)
+	}
+
+	cancelled := addBooking(t, models.Booking{Date: "2026-07-11", Status: models.StatusCancelled})
+	expectError(t, call(r, http.MethodPatch, fmt.Sprintf("/bookings/%d/deposit", cancelled.ID), map[string]any{"amount": 5000, "paid": true}, asAdmin...),
+		http.StatusConflict, models.CodeBookingCancelled)
+	if got := reload(t, cancelled.ID); got.DepositDueCents != 0 || got.AmountPaidCents != 0 {
+		t.Errorf("cancelled booking changed: %+v", got)
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net"
+	stdmail "net/mail"
+	"os"
+	"strings"
+	"time"
+
+	"miniparty-backend/messages"
+)
+
+// builtinDisposable is the blocklist used unless DISPOSABLE_BLOCKLIST_PATH names another.
+//
+//go:embed disposable_domains.txt
+var builtinDisposable string
+
+// disposableDomains are the disposable email services checked when SPAM_JUNK_DOMAINS is on.
+var disposableDomains = parseDomains(builtinDisposable)
+
+// mxTimeout bounds the MX lookup VERIFY_MX makes for each booking.
+const mxTimeout = 2 * time.Second
+
+// mxResolver is the part of net.Resolver the MX check uses.
+type mxResolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+}
+
+// resolver answers the MX check; tests swap it for one that doesn't touch the network.
+var resolver mxResolver = net.DefaultResolver
+
+// LoadDisposableDomains replaces the built-in blocklist with the domains in the file at
+// path, in the same format: one per line, with blank lines and # comments ignored.
+func LoadDisposableDomains(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	domains := parseDomains(string(raw))
+	if len(domains) == 0 {
+		return fmt.Errorf("%s lists no domains", path)
+	}
+	disposableDomains = domains
+	return nil
+}
+
+func parseDomains(list string) map[string]bool {
+	domains := map[string]bool{}
+	scanner := bufio.NewScanner(strings.NewReader(list))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if d := normalizeDomain(line); d != "" {
+			domains[d] = true
+		}
+	}
+	return domains
+}
+
+// normalizeDomain lowercases d and drops the trailing dot of a fully qualified name, so
+// "Mailinator.COM." and "mailinator.com" are the same domain.
+func normalizeDomain(d string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(d)), ".")
+}
+
+// isDisposable reports whether domain, or a domain it is under, is on the blocklist.
+func isDisposable(domain string) bool {
+	for d := normalizeDomain(domain); d != ""; {
+		if disposableDomains[d] {
+			return true
+		}
+		_, parent, ok := strings.Cut(d, ".")
+		if !ok {
+			break
+		}
+		d = parent
+	}
+	return false
+}
+
+// hasMailServers reports whether domain publishes MX records that accept mail. Only an
+// answer that the domain has none counts against it: a timeout or a failing resolver says
+// nothing about the address, and shouldn't turn a real customer away.
+func hasMailServers(ctx context.Context, domain string) bool {
+	ctx, cancel := context.WithTimeout(ctx, mxTimeout)
+	defer cancel()
+	records, err := resolver.LookupMX(ctx, normalizeDomain(domain)+".")
+	var dnsErr *net.DNSError
+	if err != nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+		return true
+	}
+	for _, mx := range records {
+		// A lone "." is a null MX (RFC 7505): the domain says it takes no mail.
+		if normalizeDomain(mx.Host) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// emailErrors checks that a syntactically valid email is one the customer can be reached
+// at: not at a disposable service, and, with VERIFY_MX=true, at a domain with mail servers.
+func emailErrors(ctx context.Context, email string) fieldErrors {
+	errs := fieldErrors{}
+	addr, err := stdmail.ParseAddress(email)
+	if err != nil {
+		return errs
+	}
+	domain := addr.Address[strings.LastIndex(addr.Address, "@")+1:]
+	switch {
+	case Features.SpamJunkDomains && isDisposable(domain):
+		errs.add("email", messages.EmailDisposable)
+	case Features.VerifyMX && !hasMailServers(ctx, domain):
+		errs.add("email", messages.EmailNoMailServers)
+	}
+	return errs
+}
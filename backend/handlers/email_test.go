@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"miniparty-backend/messages"
+	"miniparty-backend/middleware"
+)
+
+// fakeResolver answers MX lookups from a map of fully qualified names, and with a not
+// found error for any other.
+type fakeResolver map[string][]*net.MX
+
+func (f fakeResolver) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	if name == "flaky.example." {
+		return nil, &net.DNSError{Err: "i/o timeout", Name: name, IsTimeout: true}
+	}
+	records, ok := f[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+var mailHosts = fakeResolver{
+	"example.com.":    {{Host: "mx.example.com.", Pref: 10}},
+	"nomail.example.": {{Host: ".", Pref: 0}},
+}
+
+func TestIsDisposable(t *testing.T) {
+	for domain, want := range map[string]bool{
+		"mailinator.com":             true,
+		"Mailinator.COM.":            true,
+		"inbox.mailinator.com":       true,
+		"example.com":                false,
+		"mailinator.com.example.org": false,
+		"notmailinator.com":          false,
+	} {
+		if got := isDisposable(domain); got != want {
+			t.Errorf("isDisposable(%q) = %v, want %v", domain, got, want)
+		}
+	}
+}
+
+func TestLoadDisposableDomains(t *testing.T) {
+	swap(t, &disposableDomains, disposableDomains)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "domains.txt")
+	if err := os.WriteFile(path, []byte("# ours\nThrowaway.Example   # added last week\n\nburner.example.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadDisposableDomains(path); err != nil {
+		t.Fatal(err)
+	}
+	if !isDisposable("throwaway.example") || !isDisposable("x.burner.example") || isDisposable("mailinator.com") {
+		t.Errorf("after loading: %v, want only the file's domains", disposableDomains)
+	}
+
+	empty := filepath.Join(dir, "empty.txt")
+	if err := os.WriteFile(empty, []byte("# nothing yet\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{empty, filepath.Join(dir, "missing.txt")} {
+		if err := LoadDisposableDomains(p); err == nil {
+			t.Errorf("loading %s succeeded", filepath.Base(p))
+		}
+	}
+	if !isDisposable("throwaway.example") {
+		t.Error("a failed load replaced the blocklist")
+	}
+}
+
+func TestHasMailServers(t *testing.T) {
+	swap[mxResolver](t, &resolver, mailHosts)
+	for domain, want := range map[string]bool{
+		"example.com":     true,
+		"EXAMPLE.com.":    true,
+		"nomail.example":  false,
+		"nowhere.example": false,
+		// A resolver that can't answer doesn't turn the customer away.
+		"flaky.example": true,
+	} {
+		if got := hasMailServers(context.Background(), domain); got != want {
+			t.Errorf("hasMailServers(%q) = %v, want %v", domain, got, want)
+		}
+	}
+}
+
+func TestBookingVerifiesMX(t *testing.T) {
+	testDB(t)
+	swap[mxResolver](t, &resolver, mailHosts)
+	r := newRouter()
+	r.POST("/book", middleware.OptionalAdminAuth(), CreateBooking)
+
+	// Off by default, so no lookup is made.
+	swap[mxResolver](t, &resolver, failingResolver{t})
+	expect(t, call(r, http.MethodPost, "/book", bookBody("2026-07-10", "14:00", "email", "ada@nowhere.example")), http.StatusCreated)
+
+	swap(t, &Features.VerifyMX, true)
+	swap[mxResolver](t, &resolver, mailHosts)
+	for _, email := range []string{"bob@nowhere.example", "carol@nomail.example"} {
+		if codes := fieldCodes(t, call(r, http.MethodPost, "/book", bookBody("2026-07-11", "14:00", "email", email))); !hasCode(codes, "email", messages.EmailNoMailServers) {
+			t.Errorf("%s: codes %v, want %s", email, codes, messages.EmailNoMailServers)
+		}
+	}
+	expect(t, call(r, http.MethodPost, "/book", bookBody("2026-07-11", "14:00", "email", "dave@example.com")), http.StatusCreated)
+	expect(t, call(r, http.MethodPost, "/book", bookBody("2026-07-12", "14:00", "email", "erin@flaky.example")), http.StatusCreated)
+}
+
+// failingResolver fails the test if it is asked anything.
+type failingResolver struct{ t *testing.T }
+
+func (f failingResolver) LookupMX(context.Context, string) ([]*net.MX, error) {
+	f.t.Error("looked up MX records with VERIFY_MX off")
+	return nil, errors.New("unexpected lookup")
+}
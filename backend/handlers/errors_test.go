@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// failingStore is a BookingStore whose reads fail with err.
+type failingStore struct {
+	store.BookingStore
+	err error
+}
+
+func (s failingStore) GetByID(context.Context, int64) (models.Booking, error) {
+	return models.Booking{}, s.err
+}
+
+func (s failingStore) List(context.Context, store.ListOptions) ([]models.Booking, int64, error) {
+	return nil, 0, s.err
+}
+
+func TestErrorEnvelopes(t *testing.T) {
+	testDB(t)
+	taken := addBooking(t, models.Booking{Date: "2026-07-15", Time: "14:00"})
+	adminOnly := middleware.RequireRole(middleware.RoleAdmin)
+	r := newRouter()
+	r.POST("/book", middleware.BodyLimit(1<<10), middleware.OptionalAdminAuth(), CreateBooking)
+	r.GET("/bookings", middleware.AdminAuth(), GetBookings)
+	r.GET("/bookings/:id", middleware.AdminAuth(), GetBooking)
+	r.PUT("/bookings/:id", middleware.AdminAuth(), adminOnly, UpdateBooking)
+	r.GET("/availability/month", GetMonthAvailability)
+	r.GET("/bookings/:id/ticket.png", middleware.OptionalAdminAuth(), GetTicketPNG)
+	r.GET("/admin/calendar", middleware.AdminAuth(), GetOccupancyCalendar)
+	r.PUT("/admin/addons/:id", middleware.AdminAuth(), UpdateAddon)
+	r.DELETE("/admin/addons/:id", middleware.AdminAuth(), DeleteAddon)
+	r.POST("/bookings/bulk-status", middleware.AdminAuth(), BulkUpdateStatus)
+	r.POST("/admin/bookings/bulk", middleware.AdminAuth(), BulkAction)
+
+	book := map[string]any{"name": "Envelope", "email": "envelope@example.com", "phone": "+14155550142",
+		"date": taken.Date, "time": taken.Time, "duration": 2, "guests": 4}
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   any
+		auth   []string
+		status int
+		code   string
+	}{
+		{"unauthorized", http.MethodGet, "/bookings", nil, nil, http.StatusUnauthorized, models.CodeUnauthorized},
+		{"wrong token", http.MethodGet, "/bookings", nil, []string{"X-Admin-Token", "nope"}, http.StatusUnauthorized, models.CodeUnauthorized},
+		{"forbidden", http.MethodPut, "/bookings/1", map[string]any{}, asViewer, http.StatusForbidden, models.CodeForbidden},
+		{"bad query", http.MethodGet, "/bookings?per_page=1000", nil, asAdmin, http.StatusBadRequest, models.CodeBadRequest},
+		{"bad booking id", http.MethodGet, "/bookings/abc", nil, asAdmin, http.StatusBadRequest, models.CodeBadRequest},
+		{"not found", http.MethodGet, "/bookings/9999", nil, asAdmin, http.StatusNotFound, models.CodeNotFound},
+		{"validation", http.MethodPost, "/book", map[string]any{"name": "x"}, nil, http.StatusBadRequest, models.CodeValidation},
+		{"malformed body", http.MethodPost, "/book", "{", nil, http.StatusBadRequest, models.CodeBadRequest},
+		{"too large", http.MethodPost, "/book", `{"name":"` + strings.Repeat("x", 2<<10) + `"}`, nil, http.StatusRequestEntityTooLarge, models.CodeTooLarge},
+		{"slot conflict", http.MethodPost, "/book", book, nil, http.StatusConflict, models.CodeSlotConflict},
+		{"month year", http.MethodGet, "/availability/month?year=1900&month=1", nil, nil, http.StatusBadRequest, models.CodeBadRequest},
+		{"month", http.MethodGet, "/availability/month?year=2026&month=13", nil, nil, http.StatusBadRequest, models.CodeBadRequest},
+		{"ticket size", http.MethodGet, fmt.Sprintf("/bookings/%d/ticket.png?size=5", taken.ID), nil, asAdmin, http.StatusBadRequest, models.CodeBadRequest},
+		{"calendar from", http.MethodGet, "/admin/calendar?from=soon", nil, asAdmin, http.StatusBadRequest, models.CodeBadRequest},
+		{"calendar range", http.MethodGet, "/admin/calendar?from=2026-07-10&to=2026-07-01", nil, asAdmin, http.StatusBadRequest, models.CodeBadRequest},
+		{"calendar too long", http.MethodGet, "/admin/calendar?from=2026-01-01&to=2027-12-31", nil, asAdmin, http.StatusBadRequest, models.CodeBadRequest},
+		{"add-on id", http.MethodDelete, "/admin/addons/x", nil, asAdmin, http.StatusBadRequest, models.CodeBadRequest},
+		{"add-on missing", http.MethodDelete, "/admin/addons/9999", nil, asAdmin, http.StatusNotFound, models.CodeNotFound},
+		{"bulk status", http.MethodPost, "/bookings/bulk-status", map[string]any{"ids": []int{1}, "status": "lost"}, asAdmin, http.StatusBadRequest, models.CodeBadRequest},
+		{"bulk action", http.MethodPost, "/admin/bookings/bulk", map[string]any{"ids": []int{1}, "action": "explode"}, asAdmin, http.StatusBadRequest, models.CodeBadRequest},
+		{"bulk too many", http.MethodPost, "/admin/bookings/bulk", map[string]any{"ids": make([]int, maxBulkActionIDs+1), "action": "confirm"}, asAdmin, http.StatusBadRequest, models.CodeBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := call(r, tt.method, tt.target, tt.body, tt.auth...)
+			body := expectError(t, w, tt.status, tt.code)
+			if body["error"] != body["message"] {
+				t.Errorf("error %q doesn't repeat message %q", body["error"], body["message"])
+			}
+			if body["request_id"] != w.Header().Get("X-Request-ID") {
+				t.Errorf("request_id %v, header %q", body["request_id"], w.Header().Get("X-Request-ID"))
+			}
+			if tt.code == models.CodeValidation && body["fields"] == nil {
+				t.Errorf("validation error without fields: %v", body)
+			}
+		})
+	}
+}
+
+func TestServerErrorEnvelopes(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{errors.New("disk I/O error"), http.StatusInternalServerError, models.CodeInternal},
+		{fmt.Errorf("query: %w", driver.ErrBadConn), http.StatusServiceUnavailable, models.CodeDBUnavailable},
+		{context.Canceled, http.StatusServiceUnavailable, models.CodeCancelled},
+	}
+	for _, tt := range tests {
+		useStore(t, failingStore{err: tt.err})
+		r := newRouter()
+		r.GET("/bookings/:id", GetBooking)
+		w := call(r, http.MethodGet, "/bookings/1", nil)
+		expectError(t, w, tt.status, tt.code)
+		if tt.code == models.CodeInternal && strings.Contains(w.Body.String(), "disk") {
+			t.Errorf("internal error leaks its cause: %s", w.Body.String())
+		}
+	}
+}
+
+func TestRateLimitEnvelope(t *testing.T) {
+	r := newRouter()
+	r.GET("/", middleware.RateLimit(1), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	var w = call(r, http.MethodGet, "/", nil)
+	for i := 0; i < 10 && w.Code != http.StatusTooManyRequests; i++ {
+		w = call(r, http.MethodGet, "/", nil)
+	}
+	expectError(t, w, http.StatusTooManyRequests, models.CodeRateLimited)
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("429 without Retry-After")
+	}
+}
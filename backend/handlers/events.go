@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"miniparty-backend/live"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/notify"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Live streams booking events to the dashboards on GET /admin/events. main closes it on
+// shutdown, which ends their streams.
+var Live = &live.Broker{}
+
+// eventsHeartbeat is how often an idle stream gets a comment, so proxies that close quiet
+// connections leave it open.
+var eventsHeartbeat = 20 * time.Second
+
+// liveEvents are the events the dashboards are sent; reminders aren't news to staff.
+var liveEvents = map[string]bool{
+	notify.BookingCreated:     true,
+	notify.BookingPromoted:    true,
+	notify.BookingConfirmed:   true,
+	notify.BookingCancelled:   true,
+	notify.BookingRescheduled: true,
+}
+
+// dispatch sends e to the configured notifiers and the live dashboards.
+func dispatch(e notify.Event) {
+	Notifications.Dispatch(e)
+	if liveEvents[e.Type] {
+		publish(e.Type, e.Booking)
+	}
+}
+
+// publish sends b to the live dashboards as an event of type typ, for changes the
+// notifiers aren't told about, such as bulk updates.
+func publish(typ string, b models.Booking) {
+	if err := Live.Publish(typ, b); err != nil {
+		slog.Error("failed to publish live event", "event", typ, "booking_id", b.ID, "error", err)
+	}
+}
+
+// StreamEvents sends booking events as Server-Sent Events while the client stays
+// connected. The stream opens with a snapshot event naming the latest event ID; a client
+// reconnecting with Last-Event-ID (or ?last_event_id=) is then sent what it missed, and
+// complete is false in the snapshot if some of that is too old to replay, so it should
+// reload the list. Each event's data is the booking as GET /bookings/:id has it.
+func StreamEvents(c *gin.Context) {
+	after, err := lastEventID(c)
+	if err != nil {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "Last-Event-ID must be an event ID")
+		return
+	}
+	client, missed, lastID, complete, err := Live.Subscribe(after)
+	if err != nil {
+		c.Header("Retry-After", unavailableRetryAfter)
+		middleware.Fail(c, http.StatusServiceUnavailable, models.CodeNotReady, "The server is shutting down")
+		return
+	}
+	defer Live.Unsubscribe(client)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// nginx buffers responses unless told otherwise, which would hold events back.
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	fmt.Fprintf(c.Writer, "retry: 5000\nevent: snapshot\ndata: {\"last_event_id\":%d,\"complete\":%t}\n\n", lastID, complete)
+	for _, e := range missed {
+		writeEvent(c, e)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case e, ok := <-client.Events:
+			if !ok {
+				// Dropped for falling behind, or shutting down; the client reconnects.
+				return
+			}
+			writeEvent(c, e)
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
+
+func writeEvent(c *gin.Context, e live.Event) {
+	fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, e.Data)
+}
+
+// lastEventID reads the ID a reconnecting client last saw, 0 if it didn't say.
+func lastEventID(c *gin.Context) (uint64, error) {
+	v := c.GetHeader("Last-Event-ID")
+	if v == "" {
+		v = c.Query("last_event_id")
+	}
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(v, 10, 64)
+}
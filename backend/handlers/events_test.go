@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"miniparty-backend/live"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/notify"
+)
+
+// sseEvent is one message read off an event stream.
+type sseEvent struct {
+	id, event, data string
+}
+
+// stream opens GET target on srv as an event stream and returns its messages as they come.
+func stream(t *testing.T, srv *httptest.Server, target string, headers ...string) <-chan sseEvent {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, srv.URL+target, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("GET %s = %d %s, want an event stream", target, resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	events := make(chan sseEvent, 16)
+	go func() {
+		defer close(events)
+		var e sseEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			field, value, _ := strings.Cut(scanner.Text(), ": ")
+			switch field {
+			case "id":
+				e.id = value
+			case "event":
+				e.event = value
+			case "data":
+				e.data = value
+			case "":
+				if e.event != "" {
+					events <- e
+				}
+				e = sseEvent{}
+			}
+		}
+	}()
+	return events
+}
+
+// nextEvent waits for the next message on events.
+func nextEvent(t *testing.T, events <-chan sseEvent) sseEvent {
+	t.Helper()
+	select {
+	case e, ok := <-events:
+		if !ok {
+			t.Fatal("the stream ended")
+		}
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event arrived")
+		return sseEvent{}
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	testDB(t)
+	swap(t, &Live, &live.Broker{})
+	r := newRouter()
+	r.GET("/admin/events", middleware.AdminAuthFeed(), StreamEvents)
+	r.POST("/book", middleware.OptionalAdminAuth(), CreateBooking)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	t.Cleanup(Live.Close)
+
+	events := stream(t, srv, "/admin/events?token="+adminToken)
+	if e := nextEvent(t, events); e.event != "snapshot" || e.data != `{"last_event_id":0,"complete":true}` {
+		t.Errorf("stream opened with %+v, want an empty snapshot", e)
+	}
+
+	w := call(r, http.MethodPost, "/book", bookBody("2026-07-10", "14:00"))
+	expect(t, w, http.StatusCreated)
+	booked := decode[struct{ Booking models.Booking }](t, w).Booking
+	e := nextEvent(t, events)
+	var got models.Booking
+	if err := json.Unmarshal([]byte(e.data), &got); err != nil || e.id != "1" || e.event != notify.BookingCreated || got.ID != booked.ID {
+		t.Errorf("event %+v, want booking %d created", e, booked.ID)
+	}
+
+	// Reconnecting after the first event replays the rest.
+	w = call(r, http.MethodPost, "/book", bookBody("2026-07-11", "14:00"))
+	expect(t, w, http.StatusCreated)
+	nextEvent(t, events)
+	resumed := stream(t, srv, "/admin/events", "X-Admin-Token", adminToken, "Last-Event-ID", "1")
+	if e := nextEvent(t, resumed); e.data != `{"last_event_id":2,"complete":true}` {
+		t.Errorf("resumed stream opened with %+v", e)
+	}
+	if e := nextEvent(t, resumed); e.id != "2" || e.event != notify.BookingCreated {
+		t.Errorf("replayed %+v, want event 2", e)
+	}
+}
+
+func TestStreamEventsRefused(t *testing.T) {
+	swap(t, &Live, &live.Broker{})
+	r := newRouter()
+	r.GET("/admin/events", middleware.AdminAuthFeed(), StreamEvents)
+
+	expectError(t, call(r, http.MethodGet, "/admin/events", nil), http.StatusUnauthorized, models.CodeUnauthorized)
+	expectError(t, call(r, http.MethodGet, "/admin/events?last_event_id=soon", nil, asAdmin...), http.StatusBadRequest, models.CodeBadRequest)
+	Live.Close()
+	w := call(r, http.MethodGet, "/admin/events", nil, asAdmin...)
+	expectError(t, w, http.StatusServiceUnavailable, models.CodeNotReady)
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("no Retry-After while shutting down")
+	}
+}
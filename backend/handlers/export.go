@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+var csvHeader = []string{"id", "reference", "name", "email", "phone", "date", "time", "duration", "full_day", "guests", "notes",
+	"source", "utm_source", "utm_medium", "utm_campaign", "checked_in_at", "actual_guests",
+	"deposit_due_cents", "amount_paid_cents", "payment_status", "payment_method", "paid_at"}
+
+// ndjsonFlushEvery is how many bookings the NDJSON export writes between flushes, so a
+// client reading the stream sees progress without a flush per line.
+const ndjsonFlushEvery = 500
+
+// exportBookings passes each booking matching the list filters, in the list's order, to
+// write as it is read, rather than collecting them in memory. start sends the headers before
+// the first booking, or at the end if none match; a failure before that is answered like any
+// other, one after it can only be logged. The query runs with the request's context, so a
+// client that disconnects mid-export cancels it.
+func exportBookings(c *gin.Context, what string, start func(), write func(models.Booking) error) {
+	filter, err := bookingFilter(c)
+	if err != nil {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, err.Error())
+		return
+	}
+	sort, err := bookingSort(c)
+	if err != nil {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, err.Error())
+		return
+	}
+
+	started := false
+	err = Bookings.Each(c.Request.Context(), store.ListOptions{Filter: filter, Sort: sort}, func(b models.Booking) error {
+		if !started {
+			start()
+			started = true
+		}
+		return write(b)
+	})
+	switch {
+	case err != nil && !started:
+		serverError(c, err, "Failed to fetch bookings")
+	case err != nil:
+		// Headers are already sent, so all we can do is stop and log.
+		log.Println(what+" export: failed reading bookings:", err)
+	case !started:
+		start()
+	}
+}
+
+// ExportBookingsCSV streams bookings matching the list filters, in the list's order, as a CSV download.
+// Rows are read with a cursor and written as they arrive rather than collected in memory.
+func ExportBookingsCSV(c *gin.Context) {
+	w := csv.NewWriter(c.Writer)
+	start := func() {
+		filename := fmt.Sprintf("bookings-%s.csv", now().Format(dateLayout))
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		c.Status(http.StatusOK)
+		w.Write(csvHeader)
+	}
+	exportBookings(c, "CSV", start, func(b models.Booking) error {
+		record := []string{
+			strconv.FormatInt(b.ID, 10),
+			b.Reference,
+			b.Name,
+			b.Email,
+			b.Phone,
+			b.Date,
+			b.Time,
+			strconv.Itoa(b.Duration),
+			strconv.FormatBool(b.FullDay),
+			strconv.Itoa(b.Guests),
+			b.Notes,
+			b.Source,
+			b.UTMSource,
+			b.UTMMedium,
+			b.UTMCampaign,
+			"",
+			"",
+			strconv.Itoa(b.DepositDueCents),
+			strconv.Itoa(b.AmountPaidCents),
+			b.PaymentState(),
+			b.PaymentMethod,
+			"",
+		}
+		if b.CheckedInAt != nil {
+			record[15] = b.CheckedInAt.UTC().Format(time.RFC3339)
+		}
+		if b.ActualGuests != nil {
+			record[16] = strconv.Itoa(*b.ActualGuests)
+		}
+		if b.PaidAt != nil {
+			record[len(record)-1] = b.PaidAt.UTC().Format(time.RFC3339)
+		}
+		return w.Write(record)
+	})
+	w.Flush()
+}
+
+// ExportBookingsNDJSON streams bookings matching the list filters, in the list's order, as
+// newline-delimited JSON: one booking per line, as GET /bookings shows it but without its
+// add-ons, which would take a query per booking. Like the CSV export it reads rows with a
+// cursor and writes them as they arrive, so memory stays flat however many bookings match.
+func ExportBookingsNDJSON(c *gin.Context) {
+	start := func() {
+		filename := fmt.Sprintf("bookings-%s.ndjson", now().Format(dateLayout))
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		c.Status(http.StatusOK)
+	}
+	enc := json.NewEncoder(c.Writer)
+	n := 0
+	exportBookings(c, "NDJSON", start, func(b models.Booking) error {
+		// Encode ends each booking with the newline that delimits it.
+		if err := enc.Encode(b); err != nil {
+			return err
+		}
+		if n++; n%ndjsonFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+}
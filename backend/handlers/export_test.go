@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"miniparty-backend/db"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+)
+
+func TestExportBookings(t *testing.T) {
+	testDB(t)
+	pkg := models.Package{Name: "Deluxe", DurationHours: 3, MaxGuests: 20, Active: true}
+	if err := db.DB.Create(&pkg).Error; err != nil {
+		t.Fatal(err)
+	}
+	late := addBooking(t, models.Booking{Date: "2026-07-12", Name: "Late", PackageID: &pkg.ID})
+	early := addBooking(t, models.Booking{Date: "2026-07-11", Name: "Early, with a comma"})
+	addBooking(t, models.Booking{Date: "2026-07-13", Status: models.StatusCancelled})
+	r := newRouter()
+	r.GET("/bookings/export.csv", middleware.AdminAuth(), ExportBookingsCSV)
+	r.GET("/bookings/export.ndjson", middleware.AdminAuth(), ExportBookingsNDJSON)
+
+	w := call(r, http.MethodGet, "/bookings/export.ndjson?status=confirmed", nil, asAdmin...)
+	expect(t, w, http.StatusOK)
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type %s", ct)
+	}
+	var got []models.Booking
+	lines := bufio.NewScanner(w.Body)
+	for lines.Scan() {
+		var b models.Booking
+		if err := json.Unmarshal(lines.Bytes(), &b); err != nil {
+			t.Fatalf("line %q: %v", lines.Text(), err)
+		}
+		got = append(got, b)
+	}
+	if len(got) != 2 || got[0].ID != early.ID || got[1].ID != late.ID {
+		t.Fatalf("exported %+v, want the two confirmed bookings by date", got)
+	}
+	if got[1].PackageName != "Deluxe" || got[1].RoomName == "" {
+		t.Errorf("package %q, room %q, want the names filled in", got[1].PackageName, got[1].RoomName)
+	}
+
+	w = call(r, http.MethodGet, "/bookings/export.csv?status=confirmed&sort=date&order=desc", nil, asAdmin...)
+	expect(t, w, http.StatusOK)
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || strings.Join(records[0], ",") != strings.Join(csvHeader, ",") {
+		t.Fatalf("CSV %v, want the header and two bookings", records)
+	}
+	if records[1][2] != "Late" || records[2][2] != "Early, with a comma" {
+		t.Errorf("names %q and %q, want the later booking first", records[1][2], records[2][2])
+	}
+
+	for _, target := range []string{"/bookings/export.csv?status=lost", "/bookings/export.ndjson?sort=price"} {
+		expectError(t, call(r, http.MethodGet, target, nil, asAdmin...), http.StatusBadRequest, models.CodeBadRequest)
+	}
+}
@@ -0,0 +1,448 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"miniparty-backend/config"
+	"miniparty-backend/db"
+	"miniparty-backend/db/dbtest"
+	"miniparty-backend/mail"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/notify"
+	"miniparty-backend/payments"
+	"miniparty-backend/settings"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// The tokens the tests' admin routes accept.
+const (
+	adminToken  = "test-admin-secret"
+	viewerToken = "test-viewer-token"
+)
+
+// testNow is the clock the tests run at unless they set their own: a Wednesday morning in
+// the venue's timezone, well inside the default booking window.
+var testNow = time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	log.SetOutput(io.Discard)
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	middleware.ConfigureAuth(config.Auth{
+		Secret:        adminToken,
+		Tokens:        map[string]string{viewerToken: middleware.RoleViewer},
+		MaxFailures:   1000,
+		FailureWindow: time.Minute,
+		Lockout:       time.Minute,
+	})
+	os.Exit(m.Run())
+}
+
+// testDB gives the test a fresh, migrated SQLite database as db.DB, with the clock at
+// testNow and the default settings, and the bookings kept in it by store.Gorm. The per-IP
+// booking log starts empty, so earlier tests' bookings don't count against the limit.
+func testDB(t *testing.T) {
+	t.Helper()
+	dbtest.Init(t)
+	setNow(t, testNow)
+	useStore(t, store.Gorm{})
+	swap(t, &recentBookings, &ipLog{seen: map[string][]time.Time{}})
+	lastLists.clear()
+	monthViews.clear()
+}
+
+// memoryStore installs an empty store.Memory as Bookings for the test.
+func memoryStore(t *testing.T) *store.Memory {
+	t.Helper()
+	m := store.NewMemory()
+	useStore(t, m)
+	return m
+}
+
+func useStore(t *testing.T, s store.BookingStore) {
+	old := Bookings
+	Bookings = s
+	t.Cleanup(func() { Bookings = old })
+}
+
+// setNow fixes the handlers' clock at at for the test.
+func setNow(t *testing.T, at time.Time) {
+	old := now
+	now = func() time.Time { return at }
+	t.Cleanup(func() { now = old })
+}
+
+// setVenue saves the venue settings as change leaves them. It needs testDB.
+func setVenue(t *testing.T, change func(v *settings.Venue)) {
+	t.Helper()
+	v := settings.Current()
+	change(&v)
+	if errs := v.Validate(); len(errs) > 0 {
+		t.Fatalf("invalid settings: %v", errs)
+	}
+	if err := settings.Save(db.DB, v, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// swap sets *p to v for the test.
+func swap[T any](t *testing.T, p *T, v T) {
+	old := *p
+	*p = v
+	t.Cleanup(func() { *p = old })
+}
+
+// vanishOnUpdate deletes row id of table just before each UPDATE the database runs, as if
+// another request had deleted it after the handler read it. It needs testDB.
+func vanishOnUpdate(t *testing.T, table string, id any) {
+	t.Helper()
+	err := db.DB.Callback().Update().Before("gorm:update").Register("test:vanish", func(tx *gorm.DB) {
+		tx.Session(&gorm.Session{NewDB: true}).Exec("DELETE FROM "+table+" WHERE id = ?", id)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.DB.Callback().Update().Remove("test:vanish") })
+}
+
+// failUpdateOnce fails the first UPDATE the database runs with a dropped connection, as a
+// deadlock or a serialization failure would, so the transaction has to start over. It needs
+// testDB and returns how many UPDATEs have been tried.
+func failUpdateOnce(t *testing.T) *int {
+	t.Helper()
+	tried := 0
+	err := db.DB.Callback().Update().Before("gorm:update").Register("test:fail_once", func(tx *gorm.DB) {
+		if tried++; tried == 1 {
+			_ = tx.AddError(driver.ErrBadConn)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	swap(t, &db.Retries, db.RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond})
+	t.Cleanup(func() { db.DB.Callback().Update().Remove("test:fail_once") })
+	return &tried
+}
+
+// newRouter is a bare engine with the request logger, which sets the request IDs errors
+// carry. Tests mount the handlers they exercise on it with the middleware routes.go uses.
+func newRouter() *gin.Engine {
+	r := gin.New()
+	r.Use(middleware.RequestLogger())
+	return r
+}
+
+// call sends method target to h with body, JSON-encoded unless it is a string, and
+// headers as name, value pairs.
+func call(h http.Handler, method, target string, body any, headers ...string) *httptest.ResponseRecorder {
+	var r io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		r = strings.NewReader(b)
+	default:
+		buf, err := json.Marshal(b)
+		if err != nil {
+			panic(err)
+		}
+		r = bytes.NewReader(buf)
+	}
+	req := httptest.NewRequest(method, target, r)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+// asAdmin and asViewer are the headers of the admin and read-only tokens.
+var (
+	asAdmin  = []string{"X-Admin-Token", adminToken}
+	asViewer = []string{"X-Admin-Token", viewerToken}
+)
+
+// decode unmarshals w's body into a T, failing the test if it isn't one.
+func decode[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decode %s: %v", w.Body.String(), err)
+	}
+	return v
+}
+
+// expect fails the test unless w has status.
+func expect(t *testing.T, w *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status = %d, want %d; body %s", w.Code, status, w.Body.String())
+	}
+}
+
+// expectError fails the test unless w has status and an error envelope with code.
+func expectError(t *testing.T, w *httptest.ResponseRecorder, status int, code string) map[string]any {
+	t.Helper()
+	expect(t, w, status)
+	body := decode[map[string]any](t, w)
+	if body["code"] != code {
+		t.Fatalf("code = %v, want %s; body %s", body["code"], code, w.Body.String())
+	}
+	if body["message"] == "" || body["message"] == nil || body["request_id"] == nil {
+		t.Fatalf("incomplete error envelope: %s", w.Body.String())
+	}
+	return body
+}
+
+// fieldCodes fails the test unless w is a validation error, and returns its messages by field.
+func fieldCodes(t *testing.T, w *httptest.ResponseRecorder) fieldErrors {
+	t.Helper()
+	expectError(t, w, http.StatusBadRequest, models.CodeValidation)
+	return decode[struct{ Codes fieldErrors }](t, w).Codes
+}
+
+// hasCode reports whether errs has a message with code for field.
+func hasCode(errs fieldErrors, field, code string) bool {
+	for _, msg := range errs[field] {
+		if msg.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// bookBody is a POST /book body for a customer's two-hour party for four on date at
+// clock, with fields overriding or adding to it.
+func bookBody(date, clock string, fields ...any) map[string]any {
+	body := map[string]any{"name": "Ada Lovelace", "email": "ada@example.com", "phone": "+14155550123",
+		"date": date, "time": clock, "duration": 2, "guests": 4}
+	for i := 0; i+1 < len(fields); i += 2 {
+		body[fmt.Sprint(fields[i])] = fields[i+1]
+	}
+	return body
+}
+
+// seedCount numbers the bookings addBooking makes, to keep their references unique.
+var seedCount int
+
+// addBooking saves b, filled in by newBooking, straight to the database.
+func addBooking(t *testing.T, b models.Booking) models.Booking {
+	t.Helper()
+	b = newBooking(b)
+	if err := db.DB.Create(&b).Error; err != nil {
+		t.Fatalf("add booking: %v", err)
+	}
+	return b
+}
+
+// addTo saves b, filled in by newBooking, in the store s.
+func addTo(t *testing.T, s store.BookingStore, b models.Booking) models.Booking {
+	t.Helper()
+	b = newBooking(b)
+	if err := s.Create(context.Background(), &b); err != nil {
+		t.Fatalf("add booking: %v", err)
+	}
+	return b
+}
+
+// newBooking returns b as a confirmed two-hour party for four in room 1 on 2026-07-10 at
+// 14:00, except where b says otherwise, with unique codes and its slot key and start set.
+func newBooking(b models.Booking) models.Booking {
+	seedCount++
+	if b.Name == "" {
+		b.Name = "Test Customer"
+	}
+	if b.Email == "" {
+		b.Email = fmt.Sprintf("customer%d@example.com", seedCount)
+	}
+	if b.Phone == "" {
+		b.Phone = fmt.Sprintf("+1415555%04d", seedCount)
+	}
+	if b.Date == "" {
+		b.Date = "2026-07-10"
+	}
+	if b.Time == "" {
+		b.Time = "14:00"
+	}
+	if b.Duration == 0 {
+		b.Duration = 2
+	}
+	if b.Guests == 0 {
+		b.Guests = 4
+	}
+	if b.Status == "" {
+		b.Status = models.StatusConfirmed
+	}
+	if b.RoomID == nil {
+		room := uint(1)
+		b.RoomID = &room
+	}
+	if b.Reference == "" {
+		b.Reference = fmt.Sprintf("MP-T%05d", seedCount)
+	}
+	if b.CancelToken == "" {
+		b.CancelToken = fmt.Sprintf("cancel-token-%d", seedCount)
+	}
+	if b.ConfirmationCode == "" {
+		b.ConfirmationCode = fmt.Sprintf("C%07d", seedCount)
+	}
+	if start, err := parseStart(b.Date, b.Time); err == nil {
+		utc := start.UTC()
+		b.StartsAt = &utc
+	}
+	if b.Status != models.StatusCancelled {
+		b.SlotKey = models.SlotKey(b.Date, b.Time, b.RoomID)
+	}
+	return b
+}
+
+// reload reads booking id back from the database, soft-deleted or not.
+func reload(t *testing.T, id int64) models.Booking {
+	t.Helper()
+	var b models.Booking
+	if err := db.DB.Unscoped().First(&b, id).Error; err != nil {
+		t.Fatalf("reload booking %d: %v", id, err)
+	}
+	return b
+}
+
+// auditActions lists the audit log's actions for booking id, oldest first.
+func auditActions(t *testing.T, id int64) []string {
+	t.Helper()
+	var actions []string
+	if err := db.DB.Model(&models.AuditEntry{}).Where("booking_id = ?", id).Order("id").Pluck("action", &actions).Error; err != nil {
+		t.Fatal(err)
+	}
+	return actions
+}
+
+// fakePayments is a payments.Provider that starts deposits without Stripe, or fails to if
+// down is set, and accepts webhooks signed "valid". It records the bookings it was asked
+// to take deposits for.
+type fakePayments struct {
+	down     bool
+	deposits []int64
+}
+
+func (f *fakePayments) CreateDeposit(_ context.Context, bookingID int64, amount int, email string) (payments.Intent, error) {
+	if f.down {
+		return payments.Intent{}, errors.New("stripe unreachable")
+	}
+	f.deposits = append(f.deposits, bookingID)
+	return payments.Intent{ID: fmt.Sprintf("pi_%d", bookingID), ClientSecret: fmt.Sprintf("pi_%d_secret", bookingID)}, nil
+}
+
+func (f *fakePayments) CancelDeposit(context.Context, string) error { return nil }
+
+func (f *fakePayments) ParseWebhook(payload []byte, signature string, _ time.Time) (payments.Event, error) {
+	if signature != "valid" {
+		return payments.Event{}, payments.ErrInvalidSignature
+	}
+	var event struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			Object struct {
+				ID string `json:"id"`
+			} `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return payments.Event{}, err
+	}
+	return payments.Event{ID: event.ID, Type: event.Type, IntentID: event.Data.Object.ID}, nil
+}
+
+// takeDeposits has the handlers take deposits of amount cents through a fakePayments.
+func takeDeposits(t *testing.T, amount int) *fakePayments {
+	t.Helper()
+	f := &fakePayments{}
+	swap[payments.Provider](t, &Payments, f)
+	t.Setenv("DEPOSIT_AMOUNT", fmt.Sprint(amount))
+	return f
+}
+
+// fakeMailer is a mail.Mailer that hands what it is sent to the test.
+type fakeMailer chan mail.Message
+
+func (m fakeMailer) Send(msg mail.Message) error {
+	m <- msg
+	return nil
+}
+
+// catchMail has the handlers send their email to a fakeMailer for the test.
+func catchMail(t *testing.T) fakeMailer {
+	m := make(fakeMailer, 100)
+	swap[mail.Mailer](t, &Mailer, m)
+	return m
+}
+
+// next waits for the next email sent, failing the test if none comes.
+func (m fakeMailer) next(t *testing.T) mail.Message {
+	t.Helper()
+	select {
+	case msg := <-m:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("no email was sent")
+		return mail.Message{}
+	}
+}
+
+// none fails the test if an email comes within a moment.
+func (m fakeMailer) none(t *testing.T) {
+	t.Helper()
+	select {
+	case msg := <-m:
+		t.Errorf("unexpected email %q to %s", msg.Subject, msg.To)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// fakeNotifier is a notify.Notifier that hands the events it is sent to the test.
+type fakeNotifier chan notify.Event
+
+func (n fakeNotifier) Notify(_ context.Context, e notify.Event) error {
+	n <- e
+	return nil
+}
+
+// catchEvents has the handlers dispatch their booking events to a fakeNotifier for the test.
+func catchEvents(t *testing.T) fakeNotifier {
+	n := make(fakeNotifier, 100)
+	swap(t, &Notifications, notify.NewDispatcher(n))
+	return n
+}
+
+// next waits for the next event dispatched, failing the test if none comes.
+func (n fakeNotifier) next(t *testing.T) notify.Event {
+	t.Helper()
+	select {
+	case e := <-n:
+		return e
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event was dispatched")
+		return notify.Event{}
+	}
+}
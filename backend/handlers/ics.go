@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+const icsTimeLayout = "20060102T150405"
+
+// icsEscaper escapes TEXT values per RFC 5545 section 3.3.11.
+var icsEscaper = strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// GetCalendarFeed serves all bookings matching the list filters as an iCalendar feed.
+func GetCalendarFeed(c *gin.Context) {
+	filter, err := bookingFilter(c)
+	if err != nil {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, err.Error())
+		return
+	}
+
+	bookings, _, err := Bookings.List(c.Request.Context(), store.ListOptions{Filter: filter, Limit: -1})
+	if err != nil {
+		serverError(c, err, "Failed to fetch bookings")
+		return
+	}
+
+	writeCalendar(c, "bookings.ics", bookings)
+}
+
+// GetBookingICS serves a single booking as an iCalendar file.
+func GetBookingICS(c *gin.Context) {
+	id, ok := bookingID(c)
+	if !ok {
+		return
+	}
+
+	var booking models.Booking
+	if !findBooking(c, id, &booking) {
+		return
+	}
+
+	writeCalendar(c, fmt.Sprintf("booking-%d.ics", booking.ID), []models.Booking{booking})
+}
+
+func writeCalendar(c *gin.Context, filename string, bookings []models.Booking) {
+	c.Header("Content-Type", "text/calendar; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	w := &icsWriter{w: c.Writer}
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.line("PRODID:-//MiniParty//Bookings//EN")
+	w.line("CALSCALE:GREGORIAN")
+
+	stamp := now().UTC().Format(icsTimeLayout) + "Z"
+	for _, b := range bookings {
+		if b.StartsAt == nil {
+			continue
+		}
+		// UTC times, so calendars in other timezones show the party at the right moment.
+		start, end := b.StartsAt.UTC(), b.EndsAt().UTC()
+
+		w.line("BEGIN:VEVENT")
+		// The UID only depends on the booking ID so calendar clients update events in place.
+		w.line(fmt.Sprintf("UID:booking-%d@miniparty", b.ID))
+		w.line("DTSTAMP:" + stamp)
+		w.line("DTSTART:" + start.Format(icsTimeLayout) + "Z")
+		w.line("DTEND:" + end.Format(icsTimeLayout) + "Z")
+		w.line("SUMMARY:" + icsEscaper.Replace(fmt.Sprintf("%s%s (%d guests)", fullDayPrefix(b), b.Name, b.Guests)))
+		w.line("DESCRIPTION:" + icsEscaper.Replace(fmt.Sprintf("Phone: %s\nEmail: %s", b.Phone, b.Email)))
+		switch b.Status {
+		case models.StatusCancelled:
+			w.line("STATUS:CANCELLED")
+		case models.StatusPending:
+			w.line("STATUS:TENTATIVE")
+		default:
+			w.line("STATUS:CONFIRMED")
+		}
+		w.line("END:VEVENT")
+	}
+
+	w.line("END:VCALENDAR")
+}
+
+// icsWriter writes CRLF-terminated content lines, folding them at 75 octets as RFC 5545 requires.
+type icsWriter struct {
+	w io.Writer
+}
+
+func (iw *icsWriter) line(s string) {
+	const limit = 75
+	var b strings.Builder
+	width := 0
+	for _, r := range s {
+		n := utf8.RuneLen(r)
+		if width+n > limit {
+			// Continuation lines start with a space, which counts towards their length.
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += n
+	}
+	b.WriteString("\r\n")
+	io.WriteString(iw.w, b.String())
+}
@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"miniparty-backend/messages"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/settings"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MaxImportBody caps the CSV POST /admin/bookings/import reads, a few thousand bookings.
+const MaxImportBody = 2 << 20
+
+const (
+	// maxImportRows caps the bookings one import may hold.
+	maxImportRows = 5000
+	// importChunk is how many rows go in each transaction.
+	importChunk = 100
+)
+
+// What became of each imported row.
+const (
+	importCreated = "created"
+	// importValid is a row a dry run would have created.
+	importValid             = "valid"
+	importInvalid           = "invalid"
+	importDuplicateInFile   = "duplicate_in_file"
+	importDuplicateExisting = "duplicate_existing"
+	importConflict          = "conflict"
+	importClosed            = "closed"
+)
+
+// importColumns are the columns an import may have, which are the CSV export's. id and
+// reference are ignored, since imported bookings get their own, and so are the attendance
+// and the payments.
+var importColumns = map[string]bool{}
+
+// requiredImportColumns have to be in the header.
+var requiredImportColumns = []string{"name", "email", "phone", "date", "time", "guests"}
+
+func init() {
+	for _, col := range csvHeader {
+		importColumns[col] = true
+	}
+}
+
+// importRow reports one row of an import, numbered by the line it starts on, the header
+// being line 1. Errors and Codes are set for invalid rows, like a 400's fields and codes.
+type importRow struct {
+	Row            int                 `json:"row"`
+	Status         string              `json:"status"`
+	ID             int64               `json:"id,omitempty"`
+	Reference      string              `json:"reference,omitempty"`
+	Message        string              `json:"message,omitempty"`
+	Errors         map[string][]string `json:"errors,omitempty"`
+	Codes          fieldErrors         `json:"codes,omitempty"`
+	DuplicateOfRow int                 `json:"duplicate_of_row,omitempty"`
+	BookingID      int64               `json:"booking_id,omitempty"`
+}
+
+// importedRow is a parsed row waiting to be checked against the database.
+type importedRow struct {
+	line    int
+	booking models.Booking
+	errs    fieldErrors
+}
+
+// errDryRun rolls back a dry run's transaction once it has checked every row.
+var errDryRun = errors.New("dry run")
+
+// ImportBookings adds the bookings in a CSV, uploaded as the "file" field of a multipart
+// form or sent as the body, with the export's columns; only name, email, phone, date, time
+// and guests are required. Each row is validated like a new booking and checked for
+// conflicts, including with the rows before it, and the good ones are inserted, importChunk
+// to a transaction, each audited as booking.import. A row repeating an earlier row's email,
+// date and time is a duplicate_in_file, and one repeating an active booking's a
+// duplicate_existing; neither is a conflict. Imports send no email: the customers booked by
+// phone. ?dry_run=true checks everything in one transaction that is rolled back.
+//
+// Anything wrong with the file itself, such as a row with the wrong number of columns or
+// text that isn't UTF-8, fails the whole import with the line it is on.
+func ImportBookings(c *gin.Context) {
+	raw, err := importBody(c)
+	if middleware.BodyTooLarge(err) {
+		middleware.Fail(c, http.StatusRequestEntityTooLarge, models.CodeTooLarge,
+			fmt.Sprintf("The file can be at most %d MB", MaxImportBody>>20))
+		return
+	}
+	if err != nil {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, err.Error())
+		return
+	}
+	rows, line, err := parseImport(raw)
+	if err != nil {
+		middleware.FailWith(c, http.StatusBadRequest, models.CodeInvalidCSV, err.Error(), gin.H{"line": line})
+		return
+	}
+
+	dryRun := c.Query("dry_run") == "true"
+	lang := language(c)
+	report := make([]importRow, len(rows))
+	// seen has the line of the first row for each email, date and time.
+	seen := map[string]int{}
+	var created []int64
+	check := func(ctx context.Context, tx *gorm.DB, from, to int) error {
+		for i := from; i < to; i++ {
+			report[i] = importRow{Row: rows[i].line}
+			id, err := importOne(c, ctx, tx, &rows[i], &report[i], seen, dryRun)
+			if err != nil {
+				return err
+			}
+			if id != 0 {
+				created = append(created, id)
+			}
+		}
+		return nil
+	}
+	if dryRun {
+		err = inTx(c, func(ctx context.Context, tx *gorm.DB) error {
+			clear(seen)
+			if err := check(ctx, tx, 0, len(rows)); err != nil {
+				return err
+			}
+			return errDryRun
+		})
+		if errors.Is(err, errDryRun) {
+			err = nil
+		}
+	} else {
+		for from := 0; from < len(rows) && err == nil; from += importChunk {
+			before, seenBefore := len(created), maps.Clone(seen)
+			err = inTx(c, func(ctx context.Context, tx *gorm.DB) error {
+				// A retried chunk checks its rows again against the chunks before it only.
+				created, seen = created[:before], maps.Clone(seenBefore)
+				return check(ctx, tx, from, min(from+importChunk, len(rows)))
+			})
+			if err != nil {
+				// This chunk was rolled back, but the ones before it stay imported.
+				created = created[:before]
+			}
+		}
+		calendarChanged(created...)
+	}
+	if err != nil {
+		serverError(c, err, fmt.Sprintf("Failed to import bookings; %d were imported before the failure", len(created)))
+		return
+	}
+
+	counts := map[string]int{}
+	for i := range report {
+		if report[i].Status == importInvalid {
+			report[i].Errors, report[i].Codes = rows[i].errs.text(lang), rows[i].errs
+		}
+		counts[report[i].Status]++
+	}
+	if len(created) > 0 {
+		middleware.Logger(c).Info("bookings imported", "created", len(created), "rows", len(rows))
+	}
+	c.Header("Content-Language", lang)
+	c.JSON(http.StatusOK, gin.H{
+		"dry_run": dryRun,
+		"rows":    len(rows),
+		"counts":  counts,
+		"results": report,
+	})
+}
+
+// importOne checks one row in tx, whose ctx is from inTx, and, unless dryRun, inserts it,
+// noting in seen which line had each email, date and time first. It returns the new
+// booking's ID, or 0 if the row wasn't created; err is only for the database failing.
+func importOne(c *gin.Context, ctx context.Context, tx *gorm.DB, r *importedRow, report *importRow, seen map[string]int, dryRun bool) (int64, error) {
+	b := &r.booking
+	_, rooms, _, errs, err := checkNewBooking(tx, b, nil)
+	if err != nil {
+		return 0, err
+	}
+	if r.errs.merge(errs); len(r.errs) > 0 {
+		report.Status = importInvalid
+		return 0, nil
+	}
+	// Checked once validation has normalised the date and time.
+	key := strings.ToLower(b.Email) + " " + b.Date + " " + b.Time
+	if first, ok := seen[key]; ok {
+		report.Status, report.DuplicateOfRow = importDuplicateInFile, first
+		return 0, nil
+	}
+	seen[key] = r.line
+	blackout, err := findBlackout(tx, b.Date)
+	if err != nil {
+		return 0, err
+	}
+	if blackout != nil {
+		report.Status, report.Message = importClosed, blackoutMessage(blackout)
+		return 0, nil
+	}
+	duplicate, err := findDuplicate(ctx, b)
+	if err != nil {
+		return 0, err
+	}
+	if duplicate != nil {
+		report.Status, report.BookingID = importDuplicateExisting, duplicate.ID
+		return 0, nil
+	}
+	conflicts, err := findFreeRoom(tx, b, rooms, 0)
+	if err != nil {
+		return 0, err
+	}
+	if len(conflicts) > 0 {
+		report.Status, report.Message = importConflict, conflictMessage(conflicts)
+		return 0, nil
+	}
+
+	priceBooking(b, nil)
+	b.SlotKey = models.SlotKey(b.Date, b.Time, b.RoomID)
+	if b.CancelToken, err = randomHex(16); err != nil {
+		return 0, err
+	}
+	if b.ConfirmationCode, err = confirmationCode(); err != nil {
+		return 0, err
+	}
+	if b.Reference, err = newReference(ctx); err != nil {
+		return 0, err
+	}
+	// In its own savepoint, so a slot taken since the check only fails this row. In a dry
+	// run the row is inserted too, and rolled back with the rest, so the rows after it are
+	// checked against it.
+	err = tx.Transaction(func(tx *gorm.DB) error {
+		if err := Bookings.Create(store.WithTx(ctx, tx), b); err != nil {
+			return err
+		}
+		return audit(c, tx, models.AuditBookingImport, &b.ID, nil, b)
+	})
+	if slotUnavailable(err) {
+		report.Status, report.Message = importConflict, unavailableMessage(err)
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if dryRun {
+		report.Status = importValid
+		return 0, nil
+	}
+	report.Status, report.ID, report.Reference = importCreated, b.ID, b.Reference
+	return b.ID, nil
+}
+
+// importBody reads the CSV from the "file" field of a multipart form, or else the body.
+func importBody(c *gin.Context) ([]byte, error) {
+	if c.ContentType() != "multipart/form-data" {
+		return io.ReadAll(c.Request.Body)
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		if middleware.BodyTooLarge(err) {
+			return nil, err
+		}
+		return nil, errors.New(`Upload the CSV as the "file" field`)
+	}
+	f, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// parseImport reads the header and rows of an import. A row whose values don't parse is
+// returned with field errors rather than failing the file; line is where the file went
+// wrong if it did.
+func parseImport(raw []byte) (rows []importedRow, line int, err error) {
+	raw = bytes.TrimPrefix(raw, []byte("\ufeff")) // spreadsheets often start their CSV with a BOM
+	if !utf8.Valid(raw) {
+		for i, l := range bytes.Split(raw, []byte("\n")) {
+			if !utf8.Valid(l) {
+				return nil, i + 1, errors.New("The file isn't UTF-8 text; save it from the spreadsheet as CSV UTF-8")
+			}
+		}
+	}
+	r := csv.NewReader(bytes.NewReader(raw))
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, 1, errors.New("The file is empty")
+	}
+	if err != nil {
+		return nil, csvLine(err, 1), err
+	}
+	cols := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !importColumns[name] {
+			return nil, 1, fmt.Errorf("Unknown column %q; the columns are those of the CSV export", name)
+		}
+		cols[name] = i
+	}
+	for _, name := range requiredImportColumns {
+		if _, ok := cols[name]; !ok {
+			return nil, 1, fmt.Errorf("The %q column is missing", name)
+		}
+	}
+
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		start, _ := r.FieldPos(0)
+		if err != nil {
+			return nil, csvLine(err, start), err
+		}
+		if len(rows) == maxImportRows {
+			return nil, start, fmt.Errorf("An import can hold at most %d bookings", maxImportRows)
+		}
+		rows = append(rows, importRecord(record, cols, start))
+	}
+	if len(rows) == 0 {
+		return nil, 2, errors.New("The file has no bookings after its header")
+	}
+	return rows, 0, nil
+}
+
+// csvLine is the line a csv.Reader error is on, or fallback.
+func csvLine(err error, fallback int) int {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return parseErr.Line
+	}
+	return fallback
+}
+
+// importRecord turns a CSV record into a booking. Numbers that don't parse are left 0 for
+// validation to reject, and a full_day that isn't true or false is kept as a field error to
+// report with the row's others.
+func importRecord(record []string, cols map[string]int, line int) importedRow {
+	get := func(col string) string {
+		if i, ok := cols[col]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	row := importedRow{line: line, errs: fieldErrors{}}
+	b := &row.booking
+	b.Name, b.Email, b.Phone = get("name"), get("email"), get("phone")
+	b.Date, b.Time, b.Notes = get("date"), get("time"), get("notes")
+	b.Source, b.UTMSource, b.UTMMedium, b.UTMCampaign = get("source"), get("utm_source"), get("utm_medium"), get("utm_campaign")
+	clearServerFields(b)
+
+	b.Guests, _ = strconv.Atoi(get("guests"))
+	b.Duration = settings.Current().MinDurationHours
+	if v := get("duration"); v != "" {
+		b.Duration, _ = strconv.Atoi(v)
+	}
+	if v := get("full_day"); v != "" {
+		var err error
+		if b.FullDay, err = strconv.ParseBool(v); err != nil {
+			row.errs.add("full_day", messages.FullDayInvalid)
+		}
+	}
+	if b.Source == "" {
+		b.Source = "import"
+	}
+	for _, s := range []*string{&b.Source, &b.UTMSource, &b.UTMMedium, &b.UTMCampaign} {
+		*s = trackingText(*s)
+	}
+	b.Source = strings.ToLower(b.Source)
+	return row
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/settings"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBookingsPerCustomer is how many active upcoming bookings one customer may hold.
+func maxBookingsPerCustomer() int {
+	return settings.Current().MaxBookingsPerEmail
+}
+
+// bookingLimitError is a booking refused because its customer already holds Limit active
+// upcoming bookings, on Dates.
+type bookingLimitError struct {
+	Limit int
+	Dates []string
+}
+
+func (e *bookingLimitError) Error() string {
+	return fmt.Sprintf("customer already has %d upcoming bookings", len(e.Dates))
+}
+
+// message tells the customer what the limit is and which bookings count against it.
+func (e *bookingLimitError) message() string {
+	dates := make([]string, len(e.Dates))
+	for i, d := range e.Dates {
+		dates[i] = dayName(d)
+	}
+	list := strings.Join(dates, ", ")
+	if n := len(dates); n > 1 {
+		list = strings.Join(dates[:n-1], ", ") + " and " + dates[n-1]
+	}
+	return fmt.Sprintf("You already have %d upcoming bookings (%s), which is the most we can hold per customer. "+
+		"Cancel one to make another.", len(e.Dates), list)
+}
+
+// skipReason says why an occurrence of a series was left out for the limit.
+func (e *bookingLimitError) skipReason() string {
+	return fmt.Sprintf("You already have %d upcoming bookings, which is the most we can hold per customer", len(e.Dates))
+}
+
+// activeBookingDates returns the dates of the pending and confirmed bookings from today on,
+// in venue time, made with email, ignoring case, or with phone. Both are normalised before
+// they are saved, so a customer is counted whichever of the two they give.
+func activeBookingDates(ctx context.Context, email, phone string) ([]string, error) {
+	bookings, _, err := Bookings.List(ctx, store.ListOptions{
+		Filter: store.Filter{
+			Email: email, Phone: phone,
+			Statuses: []string{models.StatusPending, models.StatusConfirmed},
+			From:     now().In(venueLocation()).Format(dateLayout),
+		},
+		Limit: -1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	dates := make([]string, len(bookings))
+	for i, b := range bookings {
+		dates[i] = b.Date
+	}
+	return dates, nil
+}
+
+// checkBookingLimit fails with *bookingLimitError when b's customer already holds
+// maxBookingsPerCustomer active upcoming bookings. In a transaction from inTx it first locks
+// the customer until the transaction ends, so two of their bookings made at once are counted
+// one after the other rather than both slipping under the limit.
+func checkBookingLimit(ctx context.Context, b *models.Booking) error {
+	if err := Queries.LockCustomer(ctx, b.Email, b.Phone); err != nil {
+		return err
+	}
+	dates, err := activeBookingDates(ctx, b.Email, b.Phone)
+	if err != nil {
+		return err
+	}
+	if limit := maxBookingsPerCustomer(); len(dates) >= limit {
+		return &bookingLimitError{Limit: limit, Dates: dates}
+	}
+	return nil
+}
+
+// findDuplicate returns the customer's existing non-cancelled booking for the same date and time, if any.
+// Date and time must already be normalised by validateBooking.
+func findDuplicate(ctx context.Context, b *models.Booking) (*models.Booking, error) {
+	existing, _, err := Bookings.List(ctx, store.ListOptions{
+		Filter: store.Filter{Email: b.Email, Active: true, From: b.Date, To: b.Date, Time: b.Time},
+		Limit:  1,
+	})
+	if err != nil || len(existing) == 0 {
+		return nil, err
+	}
+	return &existing[0], nil
+}
+
+// limitError maps checkBookingLimit's error to its response: a 422 with the limit and the
+// dates of the bookings that count against it, or serverError for anything else.
+func limitError(c *gin.Context, err error) {
+	var limited *bookingLimitError
+	if !errors.As(err, &limited) {
+		serverError(c, err, "Failed to save booking")
+		return
+	}
+	middleware.FailWith(c, http.StatusUnprocessableEntity, models.CodeBookingLimit, limited.message(),
+		gin.H{"limit": limited.Limit, "dates": limited.Dates})
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"miniparty-backend/db"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/settings"
+)
+
+func TestDuplicateSubmissions(t *testing.T) {
+	testDB(t)
+	r := newRouter()
+	r.POST("/book", CreateBooking)
+
+	w := call(r, http.MethodPost, "/book", bookBody("2026-07-10", "14:00"))
+	expect(t, w, http.StatusCreated)
+	first := decode[struct{ Booking models.Booking }](t, w).Booking
+
+	// The same booking again, with the email in other case and the time typed another way.
+	again := bookBody("2026-07-10", "2pm", "email", "ADA@example.com")
+	body := expectError(t, call(r, http.MethodPost, "/book", again), http.StatusConflict, models.CodeDuplicate)
+	if body["booking_id"] != float64(first.ID) {
+		t.Errorf("duplicate: booking_id %v, want %d", body["booking_id"], first.ID)
+	}
+
+	// Once the first is cancelled, the customer may book the slot afresh.
+	if err := db.DB.Model(&models.Booking{}).Where("id = ?", first.ID).
+		Updates(map[string]any{"status": models.StatusCancelled, "slot_key": nil}).Error; err != nil {
+		t.Fatal(err)
+	}
+	expect(t, call(r, http.MethodPost, "/book", again), http.StatusCreated)
+}
+
+func TestBookingLimitPerCustomer(t *testing.T) {
+	testDB(t)
+	setVenue(t, func(v *settings.Venue) { v.MaxBookingsPerEmail = 2 })
+	// One under the email in another case and one under the phone count; cancelled and
+	// past bookings don't.
+	addBooking(t, models.Booking{Date: "2026-07-12", Email: "ADA@example.com"})
+	addBooking(t, models.Booking{Date: "2026-07-14", Phone: "+14155550123"})
+	addBooking(t, models.Booking{Date: "2026-07-15", Email: "ada@example.com", Status: models.StatusCancelled})
+	addBooking(t, models.Booking{Date: "2026-06-20", Email: "ada@example.com"})
+	r := newRouter()
+	r.POST("/book", middleware.OptionalAdminAuth(), CreateBooking)
+
+	body := expectError(t, call(r, http.MethodPost, "/book", bookBody("2026-07-10", "14:00")), http.StatusUnprocessableEntity, models.CodeBookingLimit)
+	if body["limit"] != 2.0 || fmt.Sprint(body["dates"]) != "[2026-07-12 2026-07-14]" {
+		t.Errorf("limit %v, dates %v; want 2 and the two upcoming bookings", body["limit"], body["dates"])
+	}
+	if msg, _ := body["message"].(string); !strings.Contains(msg, "2 upcoming bookings") {
+		t.Errorf("message %q, want it to say how many the customer has", msg)
+	}
+	// Another customer isn't affected, and staff aren't held to it.
+	expect(t, call(r, http.MethodPost, "/book", bookBody("2026-07-10", "14:00", "email", "grace@example.com", "phone", "+14155550999")), http.StatusCreated)
+	expect(t, call(r, http.MethodPost, "/book", bookBody("2026-07-10", "18:00"), asAdmin...), http.StatusCreated)
+}
+
+func TestWaitlistPromotionKeepsBookingLimit(t *testing.T) {
+	testDB(t)
+	setVenue(t, func(v *settings.Venue) { v.MaxBookingsPerEmail = 1 })
+	addBooking(t, models.Booking{Date: "2026-07-12", Phone: "+14155550123"})
+	entry := models.WaitlistEntry{Name: "Ada Lovelace", Email: "ada@example.com", Phone: "+14155550123",
+		Date: "2026-07-10", Time: "14:00", Duration: 2, Guests: 4}
+	if err := db.DB.Create(&entry).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	booking, err := promoteEntry(context.Background(), db.DB, entry)
+	if err != nil || booking.ID != 0 {
+		t.Fatalf("promote = booking %d, %v; want none made", booking.ID, err)
+	}
+	if err := db.DB.First(&models.WaitlistEntry{}, entry.ID).Error; err == nil {
+		t.Error("the entry was kept though its customer is at the limit")
+	}
+
+	setVenue(t, func(v *settings.Venue) { v.MaxBookingsPerEmail = 2 })
+	entry.ID = 0
+	if err := db.DB.Create(&entry).Error; err != nil {
+		t.Fatal(err)
+	}
+	if booking, err := promoteEntry(context.Background(), db.DB, entry); err != nil || booking.ID == 0 {
+		t.Errorf("under the limit: booking %d, %v; want the entry booked", booking.ID, err)
+	}
+}
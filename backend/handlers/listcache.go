@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+
+	"miniparty-backend/models"
+)
+
+const (
+	// staleListAge is how old a remembered page of GET /bookings may be and still be served
+	// while the database can't be reached.
+	staleListAge = 15 * time.Minute
+	// staleListEntries caps how many pages are remembered, one per distinct query string.
+	staleListEntries = 64
+)
+
+// lastLists is the latest page GET /bookings answered for each query string, so the admin
+// dashboard keeps showing something, marked stale, through a short database outage.
+var lastLists = &listCache{entries: map[string]models.BookingList{}}
+
+type listCache struct {
+	mu      sync.Mutex
+	entries map[string]models.BookingList
+}
+
+// put remembers list for key as of at, dropping the oldest page when full.
+func (l *listCache) put(key string, list models.BookingList, at time.Time) {
+	list.StaleAsOf = &at
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.entries[key]; !ok && len(l.entries) >= staleListEntries {
+		oldest := ""
+		for k, e := range l.entries {
+			if oldest == "" || e.StaleAsOf.Before(*l.entries[oldest].StaleAsOf) {
+				oldest = k
+			}
+		}
+		delete(l.entries, oldest)
+	}
+	l.entries[key] = list
+}
+
+// get returns the page remembered for key, with StaleAsOf saying when it was current, if
+// it is younger than staleListAge.
+func (l *listCache) get(key string, now time.Time) (models.BookingList, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	list, ok := l.entries[key]
+	if !ok || now.Sub(*list.StaleAsOf) > staleListAge {
+		return models.BookingList{}, false
+	}
+	return list, true
+}
+
+// clear forgets every remembered page.
+func (l *listCache) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	clear(l.entries)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"miniparty-backend/db"
+	"miniparty-backend/models"
+	"miniparty-backend/store"
+)
+
+// outage is a store whose List fails with db.ErrUnavailable while down is set.
+type outage struct {
+	store.BookingStore
+	down bool
+}
+
+func (o *outage) List(ctx context.Context, opts store.ListOptions) ([]models.Booking, int64, error) {
+	if o.down {
+		return nil, 0, db.ErrUnavailable
+	}
+	return o.BookingStore.List(ctx, opts)
+}
+
+func TestGetBookingsServesStaleListWhileDown(t *testing.T) {
+	s := &outage{BookingStore: store.NewMemory()}
+	useStore(t, s)
+	lastLists.clear()
+	t.Cleanup(lastLists.clear)
+	addTo(t, s, models.Booking{})
+	r := newRouter()
+	r.GET("/bookings", GetBookings)
+	setNow(t, testNow)
+
+	fresh := call(r, http.MethodGet, "/bookings?status=confirmed", nil)
+	expect(t, fresh, http.StatusOK)
+	if got := decode[models.BookingList](t, fresh); got.Total != 1 || got.StaleAsOf != nil || fresh.Header().Get("Warning") != "" {
+		t.Errorf("while up: %s, want the page unmarked", fresh.Body.String())
+	}
+
+	s.down = true
+	setNow(t, testNow.Add(staleListAge-time.Minute))
+	w := call(r, http.MethodGet, "/bookings?status=confirmed", nil)
+	expect(t, w, http.StatusOK)
+	if got := decode[models.BookingList](t, w); got.Total != 1 || got.StaleAsOf == nil || !got.StaleAsOf.Equal(testNow) || w.Header().Get("Warning") == "" {
+		t.Errorf("while down: %s (Warning %q), want the page as of %v", w.Body.String(), w.Header().Get("Warning"), testNow)
+	}
+
+	// Nothing remembered for another query, or too old, is a 503.
+	expectError(t, call(r, http.MethodGet, "/bookings?status=pending", nil), http.StatusServiceUnavailable, models.CodeDBUnavailable)
+	setNow(t, testNow.Add(staleListAge+time.Minute))
+	expectError(t, call(r, http.MethodGet, "/bookings?status=confirmed", nil), http.StatusServiceUnavailable, models.CodeDBUnavailable)
+}
+
+func TestListCacheDropsOldest(t *testing.T) {
+	l := &listCache{entries: map[string]models.BookingList{}}
+	for i := 0; i < staleListEntries; i++ {
+		l.put(string(rune('a'+i)), models.BookingList{Total: int64(i)}, testNow.Add(time.Duration(i)*time.Second))
+	}
+	l.put("a", models.BookingList{Total: 100}, testNow.Add(time.Hour))
+	l.put("new", models.BookingList{}, testNow.Add(time.Hour))
+	at := testNow.Add(time.Hour)
+	if _, ok := l.get("b", at); ok || len(l.entries) != staleListEntries {
+		t.Errorf("after overflowing: %d pages, b kept %v; want the oldest, b, dropped", len(l.entries), ok)
+	}
+	if got, ok := l.get("a", at); !ok || got.Total != 100 {
+		t.Errorf("a = %+v %v, want the page put again", got, ok)
+	}
+}
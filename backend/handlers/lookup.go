@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// codeAlphabet leaves out 0/O and 1/I so codes read back over the phone unambiguously.
+const codeAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// codeLength gives 32^8 (about 10^12) codes, so guessing one for a known email within the
+// lookup rate limit is hopeless.
+const codeLength = 8
+
+// referenceAlphabet is Crockford's base32. It has no I, L, O or U, and normalizeReference
+// reads the letters people mistake for 0 and 1 as those digits.
+const referenceAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// referencePrefix and referenceLength make references like "MP-7F3K9Q".
+const (
+	referencePrefix = "MP-"
+	referenceLength = 6
+)
+
+// referenceAttempts is how many references newReference draws before giving up. With 32^6
+// (about 10^9) to choose from, needing a second one is already rare.
+const referenceAttempts = 5
+
+var errNoReference = errors.New("no unused booking reference found")
+
+// confirmationCode returns a new random code such as "K7M2QX9P".
+func confirmationCode() (string, error) {
+	return randomCode(codeAlphabet, codeLength)
+}
+
+// newReference returns a booking reference no booking has yet, deleted ones included,
+// drawing again on a collision. The unique index on the column has the final say.
+func newReference(ctx context.Context) (string, error) {
+	for i := 0; i < referenceAttempts; i++ {
+		code, err := randomCode(referenceAlphabet, referenceLength)
+		if err != nil {
+			return "", err
+		}
+		ref := referencePrefix + code
+		taken, err := Bookings.Count(ctx, store.ListOptions{Filter: store.Filter{Reference: ref}, IncludeDeleted: true})
+		if err != nil {
+			return "", err
+		}
+		if taken == 0 {
+			return ref, nil
+		}
+	}
+	return "", errNoReference
+}
+
+// normalizeReference turns what someone typed or read out, such as "mp-7f3k9q" or "7F3K9O",
+// into the stored form "MP-7F3K9Q". ok is false if s can't be a reference.
+func normalizeReference(s string) (ref string, ok bool) {
+	s = strings.ToUpper(strings.Join(strings.Fields(s), ""))
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "MP"), "-")
+	s = strings.NewReplacer("O", "0", "I", "1", "L", "1").Replace(s)
+	if len(s) != referenceLength {
+		return "", false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune(referenceAlphabet, r) {
+			return "", false
+		}
+	}
+	return referencePrefix + s, true
+}
+
+// randomCode returns length characters drawn uniformly from alphabet.
+func randomCode(alphabet string, length int) (string, error) {
+	code := make([]byte, length)
+	max := big.NewInt(int64(len(alphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = alphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// GetMyBooking lets a customer fetch their own booking with ?code= and either ?email= or the
+// booking's ?reference=. The reference only says which booking; the code is still what
+// proves it's theirs. Any mismatch, including a booking that doesn't exist, is the same 404
+// so the endpoint reveals nothing about other bookings.
+func GetMyBooking(c *gin.Context) {
+	email := strings.ToLower(strings.TrimSpace(c.Query("email")))
+	reference := strings.TrimSpace(c.Query("reference"))
+	code := strings.ToUpper(strings.TrimSpace(c.Query("code")))
+	if (email == "" && reference == "") || code == "" {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "code and either email or reference are required")
+		return
+	}
+
+	// Compare the code against every booking for the email or reference in constant time,
+	// rather than looking it up, so response times don't hint at which codes exist.
+	filter := store.Filter{Email: email}
+	if reference != "" {
+		ref, ok := normalizeReference(reference)
+		if !ok {
+			middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "No booking matches these details")
+			return
+		}
+		filter.Reference = ref
+	}
+	candidates, _, err := Bookings.List(c.Request.Context(), store.ListOptions{Filter: filter, Limit: -1})
+	if err != nil {
+		serverError(c, err, "Failed to fetch booking")
+		return
+	}
+	var match *models.Booking
+	for i := range candidates {
+		// A booking without a code never matches: code isn't empty.
+		if subtle.ConstantTimeCompare([]byte(candidates[i].ConfirmationCode), []byte(code)) == 1 {
+			match = &candidates[i]
+		}
+	}
+	if match == nil {
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "No booking matches these details")
+		return
+	}
+
+	var booking models.Booking
+	if !findBooking(c, match.ID, &booking) {
+		return
+	}
+	c.JSON(http.StatusOK, booking)
+}
+
+// findOwnBooking loads booking id for staff, or for a customer whose ?code= is the booking's
+// confirmation code. Like GetMyBooking, a customer gets the same 404 for a wrong code as for
+// a booking that doesn't exist. It writes the response and returns false if it can't.
+func findOwnBooking(c *gin.Context, id int64, booking *models.Booking) bool {
+	if middleware.Role(c) != "" {
+		return findBooking(c, id, booking)
+	}
+	var err error
+	*booking, err = Bookings.GetByID(c.Request.Context(), id)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		serverError(c, err, "Failed to fetch booking")
+		return false
+	}
+	code := strings.ToUpper(strings.TrimSpace(c.Query("code")))
+	if err != nil || booking.ConfirmationCode == "" ||
+		subtle.ConstantTimeCompare([]byte(booking.ConfirmationCode), []byte(code)) != 1 {
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "No booking matches these details")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/settings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maintenanceStatus is maintenance mode as customers see it: switched off once its until
+// has passed, and with the default message if it was saved without one.
+type maintenanceStatus struct {
+	Enabled bool       `json:"enabled"`
+	Message string     `json:"message,omitempty"`
+	Until   *time.Time `json:"until,omitempty"`
+}
+
+func maintenanceAt(m settings.Maintenance, at time.Time) maintenanceStatus {
+	if !m.Active(at) {
+		return maintenanceStatus{}
+	}
+	s := maintenanceStatus{Enabled: true, Message: m.Message, Until: m.Until}
+	if s.Message == "" {
+		s.Message = settings.DefaultMaintenanceMessage
+	}
+	return s
+}
+
+// GetStatus tells the booking form whether bookings are being taken, so it can show the
+// maintenance message instead of the form. It is cheap to poll: it reads the cache.
+func GetStatus(c *gin.Context) {
+	m := maintenanceAt(settings.CurrentMaintenance(), now())
+	c.JSON(http.StatusOK, gin.H{"accepting_bookings": !m.Enabled, "maintenance": m})
+}
+
+// UpdateMaintenance switches maintenance mode on or off. While it is on, customers' POST
+// /book answers 503 with its message; everything else, admin bookings included, works as
+// usual. An until in the future switches it off by itself at that time.
+func UpdateMaintenance(c *gin.Context) {
+	var m settings.Maintenance
+	if !bindJSON(c, &m) {
+		return
+	}
+	m.Message = strings.TrimSpace(m.Message)
+	if m.Until != nil {
+		until := m.Until.UTC()
+		m.Until = &until
+	}
+	if errs := fieldErrors(m.Validate(now())); len(errs) > 0 {
+		badFields(c, errs)
+		return
+	}
+
+	if _, err := settings.Load(conn(c)); err != nil {
+		serverError(c, err, "Failed to update maintenance mode")
+		return
+	}
+	before := settings.CurrentMaintenance()
+	err := settings.SaveMaintenance(conn(c), m, func(tx *gorm.DB) error {
+		return audit(c, tx, models.AuditMaintenanceUpdate, nil, before, m)
+	})
+	if err != nil {
+		serverError(c, err, "Failed to update maintenance mode")
+		return
+	}
+	slog.Info("maintenance mode updated", "enabled", m.Enabled, "until", m.Until)
+
+	c.JSON(http.StatusOK, maintenanceAt(m, now()))
+}
+
+// bookingsPaused answers c with 503 and reports true while maintenance mode is on, unless
+// c is an admin's.
+func bookingsPaused(c *gin.Context) bool {
+	m := maintenanceAt(settings.CurrentMaintenance(), now())
+	if !m.Enabled || middleware.HasRole(c, middleware.RoleAdmin) {
+		return false
+	}
+	if m.Until != nil {
+		c.Header("Retry-After", strconv.Itoa(int(time.Until(*m.Until).Seconds())+1))
+	}
+	middleware.FailWith(c, http.StatusServiceUnavailable, models.CodeMaintenance, m.Message, gin.H{"until": m.Until})
+	return true
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"miniparty-backend/messages"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxPaymentReference caps the note staff can keep with a payment.
+const maxPaymentReference = 200
+
+// errBookingCancelled rejects a payment for a cancelled booking.
+var errBookingCancelled = errors.New("booking is cancelled")
+
+// paymentRequest is the body of POST /bookings/:id/payments.
+type paymentRequest struct {
+	AmountCents int    `json:"amount_cents"`
+	Method      string `json:"method"`
+	Reference   string `json:"reference"`
+}
+
+// RecordPayment adds a payment staff took by hand, such as cash at the venue or a bank
+// transfer, to a booking's paid total. Payments add up, so a deposit paid in parts is
+// recorded part by part. A payment that takes the total past the deposit due is still
+// recorded, and the response says by how much it was overpaid. Once the deposit due is
+// covered, the booking's deposit is marked paid too.
+func RecordPayment(c *gin.Context) {
+	id, ok := bookingID(c)
+	if !ok {
+		return
+	}
+	var req paymentRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	req.Method = strings.ToLower(strings.TrimSpace(req.Method))
+	errs := fieldErrors{}
+	if req.AmountCents < 1 {
+		errs.add("amount_cents", messages.PaymentAmount)
+	}
+	if !models.ValidPaymentMethod(req.Method) {
+		errs.add("method", messages.PaymentMethod)
+	}
+	checkText(errs, "reference", messages.ReferenceInvalidText, messages.ReferenceTooLong, &req.Reference, maxPaymentReference, false)
+	if len(errs) > 0 {
+		badFields(c, errs)
+		return
+	}
+
+	var booking models.Booking
+	payment := models.Payment{BookingID: id, AmountCents: req.AmountCents, Method: req.Method, Reference: req.Reference}
+	err := inTx(c, func(ctx context.Context, tx *gorm.DB) error {
+		var before models.Booking
+		var err error
+		booking, err = Bookings.Modify(ctx, id, func(b *models.Booking) error {
+			before = *b
+			return addPayment(b, &payment)
+		})
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+		return audit(c, tx, models.AuditBookingPayment, &booking.ID, before, gin.H{"booking": booking, "payment": payment})
+	})
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Booking not found")
+		return
+	case errors.Is(err, errBookingCancelled):
+		middleware.Fail(c, http.StatusConflict, models.CodeBookingCancelled, "Payments can't be recorded for a cancelled booking")
+		return
+	case err != nil:
+		serverError(c, err, "Failed to record payment")
+		return
+	}
+
+	overpaid := max(booking.AmountPaidCents-booking.DepositDueCents, 0)
+	if overpaid > 0 {
+		middleware.Logger(c).Warn("booking overpaid", "booking_id", booking.ID, "overpaid_cents", overpaid)
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"payment":        payment,
+		"booking":        booking,
+		"overpaid":       overpaid > 0,
+		"overpaid_cents": overpaid,
+	})
+}
+
+// addPayment adds payment to booking's paid total, marking its deposit paid if the payment
+// covers it, and readies payment to be recorded against booking. A cancelled booking takes
+// no payments.
+func addPayment(booking *models.Booking, payment *models.Payment) error {
+	if booking.Status == models.StatusCancelled {
+		return errBookingCancelled
+	}
+	at := now().UTC()
+	// A retried transaction inserts afresh rather than with the ID the rolled-back attempt was given.
+	payment.ID, payment.BookingID, payment.CreatedAt = 0, booking.ID, at
+	booking.AmountPaidCents += payment.AmountCents
+	booking.PaymentMethod, booking.PaidAt = payment.Method, &at
+	booking.SyncDeposit(at)
+	return nil
+}
+
+// GetPayments lists the payments recorded for a booking, oldest first.
+func GetPayments(c *gin.Context) {
+	id, ok := bookingID(c)
+	if !ok {
+		return
+	}
+	var booking models.Booking
+	if !findBooking(c, id, &booking) {
+		return
+	}
+	payments := []models.Payment{}
+	if err := conn(c).Where("booking_id = ?", id).Order("created_at, id").Find(&payments).Error; err != nil {
+		serverError(c, err, "Failed to fetch payments")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"payments":          payments,
+		"deposit_due_cents": booking.DepositDueCents,
+		"amount_paid_cents": booking.AmountPaidCents,
+		"payment_status":    booking.PaymentStatus,
+	})
+}
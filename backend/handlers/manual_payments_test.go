@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+)
+
+func TestWritesReturnPaymentStatus(t *testing.T) {
+	testDB(t)
+	r := newRouter()
+	r.POST("/book", middleware.OptionalAdminAuth(), CreateBooking)
+	r.PUT("/bookings/:id", middleware.AdminAuth(), UpdateBooking)
+	r.POST("/bookings/:id/reschedule", middleware.OptionalAdminAuth(), RescheduleBooking)
+
+	w := call(r, http.MethodPost, "/book", map[string]any{
+		"name": "Ada Lovelace", "email": "ada@example.com", "phone": "+14155550123",
+		"date": "2026-07-10", "time": "14:00", "duration": 2, "guests": 4,
+	})
+	expect(t, w, http.StatusCreated)
+	if got := decode[struct{ Booking models.Booking }](t, w).Booking; got.PaymentStatus != models.PaymentUnpaid {
+		t.Errorf("create: payment_status = %q, want unpaid", got.PaymentStatus)
+	}
+
+	partial := addBooking(t, models.Booking{Date: "2026-07-11", DepositDueCents: 5000, AmountPaidCents: 2000})
+	update := partial
+	update.Guests = 6
+	w = call(r, http.MethodPut, fmt.Sprintf("/bookings/%d", partial.ID), update, asAdmin...)
+	expect(t, w, http.StatusOK)
+	if got := decode[models.Booking](t, w); got.PaymentStatus != models.PaymentPartial {
+		t.Errorf("update: payment_status = %q, want partial", got.PaymentStatus)
+	}
+
+	w = call(r, http.MethodPost, fmt.Sprintf("/bookings/%d/reschedule", partial.ID),
+		map[string]any{"date": partial.Date, "time": "17:00", "token": partial.CancelToken})
+	expect(t, w, http.StatusOK)
+	if got := decode[struct{ Booking models.Booking }](t, w).Booking; got.PaymentStatus != models.PaymentPartial {
+		t.Errorf("reschedule: payment_status = %q, want partial", got.PaymentStatus)
+	}
+
+	w = call(r, http.MethodPost, "/book", seriesRequest(2))
+	expect(t, w, http.StatusCreated)
+	for _, o := range decode[seriesResponse](t, w).Created {
+		if o.PaymentStatus != models.PaymentUnpaid {
+			t.Errorf("series occurrence %s: payment_status = %q, want unpaid", o.Date, o.PaymentStatus)
+		}
+	}
+}
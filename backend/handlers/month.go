@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"miniparty-backend/messages"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/settings"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Day statuses in the month view.
+const (
+	dayOpen    = "open"
+	dayLimited = "limited"
+	dayFull    = "full"
+	dayClosed  = "closed"
+)
+
+// The years GET /availability/month answers for.
+const (
+	minMonthYear = 2000
+	maxMonthYear = 2100
+)
+
+// monthDay is one day of the month view. BookedHours adds up the active bookings' hours
+// across rooms, and RemainingHours the whole hours each active room still has free.
+type monthDay struct {
+	Date           string `json:"date"`
+	Status         string `json:"status"`
+	Reason         string `json:"reason,omitempty"`
+	Bookings       int64  `json:"bookings"`
+	BookedHours    int64  `json:"booked_hours"`
+	RemainingHours int64  `json:"remaining_hours"`
+}
+
+// GetMonthAvailability summarises ?year= and ?month= for the date picker, one entry per day:
+// closed on blackouts, with their reason, and on the weekdays the schedule closes; full once
+// no active room has the shortest allowed booking free; limited when it has bookings but
+// still room; open otherwise. The bookings are counted with one query for the whole month,
+// and the answer is kept for AVAILABILITY_CACHE_TTL, or until a booking that month changes.
+func GetMonthAvailability(c *gin.Context) {
+	year, err := strconv.Atoi(c.Query("year"))
+	if err != nil || year < minMonthYear || year > maxMonthYear {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, fmt.Sprintf("year must be between %d and %d", minMonthYear, maxMonthYear))
+		return
+	}
+	month, err := strconv.Atoi(c.Query("month"))
+	if err != nil || month < 1 || month > 12 {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "month must be between 1 and 12")
+		return
+	}
+
+	first := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	lang := language(c)
+	key := first.Format("2006-01") + " " + lang
+	days, ok := monthViews.get(key, now())
+	if !ok {
+		if days, err = monthAvailability(conn(c), first, lang); err != nil {
+			serverError(c, err, "Failed to fetch availability")
+			return
+		}
+		monthViews.put(key, days, now())
+	}
+	c.JSON(http.StatusOK, gin.H{"year": year, "month": month, "days": days})
+}
+
+// monthAvailability works out the month view for the month starting on first.
+func monthAvailability(tx *gorm.DB, first time.Time, lang string) ([]monthDay, error) {
+	last := first.AddDate(0, 1, -1)
+	from, to := first.Format(dateLayout), last.Format(dateLayout)
+
+	rows, err := Queries.RoomDays(tx.Statement.Context, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var blackouts []models.Blackout
+	if err := tx.Where("date >= ? AND date <= ?", from, to).Find(&blackouts).Error; err != nil {
+		return nil, err
+	}
+	rooms, err := activeRooms(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	byDate := map[string][]store.RoomDay{}
+	for _, r := range rows {
+		byDate[r.Date] = append(byDate[r.Date], r)
+	}
+	closedOn := make(map[string]string, len(blackouts))
+	for _, b := range blackouts {
+		closedOn[b.Date] = b.Reason
+	}
+
+	venue := settings.Current()
+	days := make([]monthDay, 0, last.Day())
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		date := day.Format(dateLayout)
+		d := monthDay{Date: date}
+		for _, r := range byDate[date] {
+			d.Bookings += r.Bookings
+			d.BookedHours += r.Hours
+		}
+		openAt, closeAt, open := venue.HoursOn(day.Weekday())
+		if reason, ok := closedOn[date]; ok {
+			d.Status, d.Reason = dayClosed, reason
+		} else if !open {
+			d.Status = dayClosed
+			d.Reason = messages.New(messages.DateClosedDay, "day", day.Weekday().String()).Text(lang)
+		} else {
+			d.Status, d.RemainingHours = dayStatus(venue, rooms, byDate[date], closeAt-openAt)
+		}
+		days = append(days, d)
+	}
+	return days, nil
+}
+
+// dayStatus rates an open day of openMinutes from its bookings, and returns the whole hours
+// left free across rooms. Hours are only added up, so a day whose free hours are scattered
+// may still show room for a booking no single gap fits; the day view has the exact slots.
+func dayStatus(v settings.Venue, rooms []models.Room, bookings []store.RoomDay, openMinutes int) (string, int64) {
+	busy := map[uint]int{}
+	for _, b := range bookings {
+		if b.FullDays > 0 {
+			// A buyout takes every room, whichever one it was filed under.
+			return dayFull, 0
+		}
+		if b.RoomID != nil {
+			busy[*b.RoomID] += int(b.Hours) * 60
+		}
+	}
+	var remaining int64
+	fits := false
+	for _, room := range rooms {
+		free := max(openMinutes-busy[room.ID], 0)
+		remaining += int64(free / 60)
+		fits = fits || free >= v.MinDurationHours*60
+	}
+	switch {
+	case !fits:
+		return dayFull, remaining
+	case len(bookings) > 0:
+		return dayLimited, remaining
+	}
+	return dayOpen, remaining
+}
+
+// monthCacheTTL is how long a month view is served from memory (AVAILABILITY_CACHE_TTL,
+// default 30s; 0 turns the cache off).
+func monthCacheTTL() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("AVAILABILITY_CACHE_TTL")); err == nil && d >= 0 {
+		return d
+	}
+	return 30 * time.Second
+}
+
+// monthViews are the month views GET /availability/month has worked out, keyed by month
+// and language, e.g. "2026-07 en".
+var monthViews = &monthCache{entries: map[string]monthEntry{}}
+
+type monthEntry struct {
+	days []monthDay
+	at   time.Time
+}
+
+type monthCache struct {
+	mu      sync.Mutex
+	entries map[string]monthEntry
+}
+
+// get returns the days remembered for key if they are younger than monthCacheTTL.
+func (m *monthCache) get(key string, now time.Time) ([]monthDay, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok || now.Sub(e.at) >= monthCacheTTL() {
+		return nil, false
+	}
+	return e.days, true
+}
+
+// put remembers days for key as of at, unless the cache is off. Every key is a month of
+// the bounded range in one of a few languages, so the map stays small.
+func (m *monthCache) put(key string, days []monthDay, at time.Time) {
+	if monthCacheTTL() == 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = monthEntry{days: days, at: at}
+}
+
+// drop forgets the views of month, as "2006-01", in every language.
+func (m *monthCache) drop(month string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key := range m.entries {
+		if strings.HasPrefix(key, month+" ") {
+			delete(m.entries, key)
+		}
+	}
+}
+
+// clear forgets every view.
+func (m *monthCache) clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clear(m.entries)
+}
+
+// DataWritten is told about every database write, for main to hand to db.Written. A new
+// booking only changes its own month, but an update may have moved a booking out of one
+// the statement doesn't say, and blackouts, rooms, settings and raw SQL can change any, so
+// those forget every month view. The write may yet be rolled back, which only costs a
+// query; a view cached again before it commits lasts no longer than the TTL.
+func DataWritten(stmt *gorm.Statement) {
+	sql := strings.TrimSpace(stmt.SQL.String())
+	switch stmt.Table {
+	case "bookings":
+		if b, ok := stmt.Dest.(*models.Booking); ok && strings.HasPrefix(sql, "INSERT") && len(b.Date) >= 7 {
+			monthViews.drop(b.Date[:7])
+			return
+		}
+		monthViews.clear()
+	case "blackout_dates", "rooms", "settings":
+		monthViews.clear()
+	case "":
+		if !strings.HasPrefix(strings.ToUpper(sql), "SELECT") {
+			monthViews.clear()
+		}
+	}
+}
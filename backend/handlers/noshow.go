@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"miniparty-backend/config"
+	"miniparty-backend/db"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// NoShows says how no-shows are marked and counted against customers. main sets it from
+// the config: NO_SHOW_MARKING=true marks them, NO_SHOW_FLAG_THRESHOLD (default 2) flags the
+// bookings of contacts with that many, and NO_SHOW_HOLD_FLAGGED=true keeps those pending
+// when their deposit is paid.
+var NoShows = config.DefaultNoShows
+
+var (
+	// errCheckedIn rejects marking a booking that checked in as a no-show.
+	errCheckedIn = errors.New("booking has checked in")
+	// errNotOver rejects marking a booking as a no-show before it has ended.
+	errNotOver = errors.New("booking has not ended")
+	// errNotNoShow rejects undoing a no-show on a booking that isn't one.
+	errNotNoShow = errors.New("booking is not a no-show")
+)
+
+// priorNoShows counts the no-shows of bookings made with email, ignoring case, or phone.
+// Both are normalised by validateBooking before they are saved, so a customer who books
+// again with the other one, or types it differently, is still counted.
+func priorNoShows(ctx context.Context, email, phone string) (int64, error) {
+	return Bookings.Count(ctx, store.ListOptions{Filter: store.Filter{Email: email, Phone: phone, Status: models.StatusNoShow}})
+}
+
+// flagNoShows records b's contact's no-shows on it, flagging it when they reach
+// NoShows.FlagThreshold.
+func flagNoShows(ctx context.Context, b *models.Booking) error {
+	count, err := priorNoShows(ctx, b.Email, b.Phone)
+	if err != nil {
+		return err
+	}
+	b.PriorNoShows = int(count)
+	b.Flagged = NoShows.FlagThreshold > 0 && count >= int64(NoShows.FlagThreshold)
+	return nil
+}
+
+// holdFlagged reports whether b has to wait for staff to confirm it rather than being
+// confirmed by its deposit.
+func holdFlagged(b models.Booking) bool {
+	return NoShows.HoldFlagged && b.Flagged
+}
+
+// MarkNoShow records that nobody came to a confirmed booking that has ended without a
+// check-in. Marking a no-show again is a no-op.
+func MarkNoShow(c *gin.Context) {
+	id, ok := bookingID(c)
+	if !ok {
+		return
+	}
+
+	var booking models.Booking
+	err := inTx(c, func(ctx context.Context, tx *gorm.DB) error {
+		var before models.Booking
+		var err error
+		booking, err = Bookings.Modify(ctx, id, func(b *models.Booking) error {
+			before = *b
+			switch {
+			case b.Status == models.StatusNoShow:
+				return nil
+			case b.Status != models.StatusConfirmed:
+				return errNotConfirmed
+			case b.CheckedInAt != nil:
+				return errCheckedIn
+			case !bookingOver(*b, now()):
+				return errNotOver
+			}
+			b.Status = models.StatusNoShow
+			return nil
+		})
+		if err != nil || before.Status == models.StatusNoShow {
+			return err
+		}
+		return audit(c, tx, models.AuditBookingNoShow, &booking.ID, before, booking)
+	})
+	noShowResponse(c, booking, err)
+}
+
+// UndoNoShow puts a booking marked as a no-show by mistake back to confirmed. The party
+// evidently came, so it is checked in as of now, which also keeps the marking from
+// marking it again.
+func UndoNoShow(c *gin.Context) {
+	id, ok := bookingID(c)
+	if !ok {
+		return
+	}
+
+	var booking models.Booking
+	err := inTx(c, func(ctx context.Context, tx *gorm.DB) error {
+		var before models.Booking
+		var err error
+		booking, err = Bookings.Modify(ctx, id, func(b *models.Booking) error {
+			before = *b
+			if b.Status != models.StatusNoShow {
+				return errNotNoShow
+			}
+			b.Status = models.StatusConfirmed
+			if b.CheckedInAt == nil {
+				at := now().UTC()
+				b.CheckedInAt = &at
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return audit(c, tx, models.AuditBookingNoShowUndo, &booking.ID, before, booking)
+	})
+	noShowResponse(c, booking, err)
+}
+
+// noShowResponse answers MarkNoShow and UndoNoShow with booking, or with what stopped them.
+func noShowResponse(c *gin.Context, booking models.Booking, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Booking not found")
+	case errors.Is(err, errNotConfirmed):
+		middleware.FailWith(c, http.StatusConflict, models.CodeNotConfirmed,
+			"Only confirmed bookings can be no-shows; this one is "+booking.Status, gin.H{"status": booking.Status})
+	case errors.Is(err, errCheckedIn):
+		middleware.FailWith(c, http.StatusConflict, models.CodeCheckedIn,
+			"This booking checked in, so it isn't a no-show", gin.H{"checked_in_at": booking.CheckedInAt})
+	case errors.Is(err, errNotOver):
+		middleware.FailWith(c, http.StatusUnprocessableEntity, models.CodeNotOver,
+			"This booking hasn't ended yet", gin.H{"ends_at": booking.EndsAt()})
+	case errors.Is(err, errNotNoShow):
+		middleware.FailWith(c, http.StatusConflict, models.CodeNotNoShow,
+			"This booking isn't marked as a no-show; it is "+booking.Status, gin.H{"status": booking.Status})
+	case err != nil:
+		serverError(c, err, "Failed to update booking")
+	default:
+		calendarChanged(booking.ID)
+		c.JSON(http.StatusOK, booking)
+	}
+}
+
+// bookingOver reports whether b has ended by at: its end has passed or, for a booking
+// without a start time, its day has.
+func bookingOver(b models.Booking, at time.Time) bool {
+	if end := b.EndsAt(); end != nil {
+		return at.After(*end)
+	}
+	return b.Date < at.In(venueLocation()).Format(dateLayout)
+}
+
+// MarkNoShows marks confirmed bookings whose day has passed without a check-in as
+// no-shows, checking every interval until ctx is done.
+func MarkNoShows(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !db.Ready() {
+			continue
+		}
+		if err := markPastNoShows(context.WithoutCancel(ctx), now()); err != nil {
+			slog.Error("failed to mark no-shows", "error", err)
+		}
+	}
+}
+
+// markPastNoShows marks the confirmed bookings dated before at's day in venue time that
+// never checked in.
+func markPastNoShows(ctx context.Context, at time.Time) error {
+	today := at.In(venueLocation()).Format(dateLayout)
+	marked, err := Queries.MarkPastNoShows(ctx, today)
+	if err != nil {
+		return err
+	}
+	if marked > 0 {
+		slog.Info("marked no-shows", "bookings", marked, "before", today)
+	}
+	return nil
+}
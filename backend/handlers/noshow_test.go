@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"miniparty-backend/config"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+)
+
+func TestMarkNoShow(t *testing.T) {
+	testDB(t)
+	missed := addBooking(t, models.Booking{Date: "2026-06-30"})
+	upcoming := addBooking(t, models.Booking{})
+	pending := addBooking(t, models.Booking{Date: "2026-06-29", Status: models.StatusPending})
+	came := testNow.Add(-24 * time.Hour)
+	checkedIn := addBooking(t, models.Booking{Date: "2026-06-28", CheckedInAt: &came})
+	r := newRouter()
+	admin := r.Group("", middleware.AdminAuth())
+	admin.POST("/bookings/:id/no-show", MarkNoShow)
+	admin.DELETE("/bookings/:id/no-show", UndoNoShow)
+	target := func(b models.Booking) string { return fmt.Sprintf("/bookings/%d/no-show", b.ID) }
+
+	for i := 0; i < 2; i++ {
+		w := call(r, http.MethodPost, target(missed), nil, asAdmin...)
+		expect(t, w, http.StatusOK)
+		if got := decode[models.Booking](t, w); got.Status != models.StatusNoShow {
+			t.Errorf("marking %d: status %s, want no_show", i+1, got.Status)
+		}
+	}
+	expectError(t, call(r, http.MethodPost, target(upcoming), nil, asAdmin...), http.StatusUnprocessableEntity, models.CodeNotOver)
+	expectError(t, call(r, http.MethodPost, target(pending), nil, asAdmin...), http.StatusConflict, models.CodeNotConfirmed)
+	expectError(t, call(r, http.MethodPost, target(checkedIn), nil, asAdmin...), http.StatusConflict, models.CodeCheckedIn)
+	expectError(t, call(r, http.MethodPost, "/bookings/999/no-show", nil, asAdmin...), http.StatusNotFound, models.CodeNotFound)
+
+	w := call(r, http.MethodDelete, target(missed), nil, asAdmin...)
+	expect(t, w, http.StatusOK)
+	if got := reload(t, missed.ID); got.Status != models.StatusConfirmed || got.CheckedInAt == nil || !got.CheckedInAt.Equal(testNow) {
+		t.Errorf("after undoing: status %s, checked in %v; want confirmed and checked in now", got.Status, got.CheckedInAt)
+	}
+	expectError(t, call(r, http.MethodDelete, target(missed), nil, asAdmin...), http.StatusConflict, models.CodeNotNoShow)
+	// Marking twice is one change in the log.
+	if got := fmt.Sprint(auditActions(t, missed.ID)); got != fmt.Sprint([]string{models.AuditBookingNoShow, models.AuditBookingNoShowUndo}) {
+		t.Errorf("audit log %s, want the mark and the undo", got)
+	}
+}
+
+func TestMarkPastNoShows(t *testing.T) {
+	testDB(t)
+	came := testNow.Add(-24 * time.Hour)
+	missed := addBooking(t, models.Booking{Date: "2026-06-30"})
+	checkedIn := addBooking(t, models.Booking{Date: "2026-06-30", Time: "10:00", CheckedInAt: &came})
+	pending := addBooking(t, models.Booking{Date: "2026-06-29", Status: models.StatusPending})
+	// Earlier today; the door may still check it in.
+	today := addBooking(t, models.Booking{Date: "2026-07-01", Time: "10:00"})
+
+	if err := markPastNoShows(context.Background(), testNow); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		b    models.Booking
+		want string
+	}{
+		{missed, models.StatusNoShow}, {checkedIn, models.StatusConfirmed}, {pending, models.StatusPending}, {today, models.StatusConfirmed},
+	} {
+		if got := reload(t, tt.b.ID).Status; got != tt.want {
+			t.Errorf("booking on %s %s: %s, want %s", tt.b.Date, tt.b.Time, got, tt.want)
+		}
+	}
+}
+
+func TestNoShowsFlagNewBookings(t *testing.T) {
+	testDB(t)
+	swap(t, &NoShows, config.DefaultNoShows)
+	// One missed party under the email in another case, one under the phone.
+	addBooking(t, models.Booking{Date: "2026-06-20", Email: "ADA@example.com", Status: models.StatusNoShow})
+	addBooking(t, models.Booking{Date: "2026-06-21", Phone: "+14155550123", Status: models.StatusNoShow})
+	r := newRouter()
+	r.POST("/book", middleware.OptionalAdminAuth(), CreateBooking)
+	r.GET("/bookings", middleware.AdminAuth(), GetBookings)
+
+	w := call(r, http.MethodPost, "/book", bookBody("2026-07-10", "14:00", "flagged", false, "prior_no_shows", 0))
+	expect(t, w, http.StatusCreated)
+	flagged := decode[struct{ Booking models.Booking }](t, w).Booking
+	if !flagged.Flagged || flagged.PriorNoShows != 2 {
+		t.Errorf("booked %+v, want flagged with 2 prior no-shows", flagged)
+	}
+	w = call(r, http.MethodGet, "/bookings?flagged=true", nil, asAdmin...)
+	expect(t, w, http.StatusOK)
+	if got := decode[models.BookingList](t, w); got.Total != 1 || got.Bookings[0].ID != flagged.ID {
+		t.Errorf("flagged bookings %+v, want only the new one", got)
+	}
+	expectError(t, call(r, http.MethodGet, "/bookings?flagged=often", nil, asAdmin...), http.StatusBadRequest, models.CodeBadRequest)
+
+	NoShows.FlagThreshold = 3
+	w = call(r, http.MethodPost, "/book", bookBody("2026-07-11", "14:00"))
+	expect(t, w, http.StatusCreated)
+	if got := decode[struct{ Booking models.Booking }](t, w).Booking; got.Flagged || got.PriorNoShows != 2 {
+		t.Errorf("under the threshold: %+v, want 2 prior no-shows but no flag", got)
+	}
+}
+
+func TestDepositHoldsFlaggedBookings(t *testing.T) {
+	testDB(t)
+	swap(t, &NoShows, config.NoShows{FlagThreshold: 1, HoldFlagged: true})
+	takeDeposits(t, 2500)
+	flagged := addBooking(t, models.Booking{Status: models.StatusPending, Flagged: true, PriorNoShows: 1, PaymentIntentID: "pi_flagged"})
+	other := addBooking(t, models.Booking{Time: "18:00", Status: models.StatusPending, PaymentIntentID: "pi_other"})
+	r := newRouter()
+	r.POST("/webhooks/stripe", StripeWebhook)
+
+	for _, intent := range []string{"pi_flagged", "pi_other"} {
+		body := fmt.Sprintf(`{"id":"evt_%s","type":"payment_intent.succeeded","data":{"object":{"id":%q}}}`, intent, intent)
+		expect(t, call(r, http.MethodPost, "/webhooks/stripe", body, "Stripe-Signature", "valid"), http.StatusOK)
+	}
+	if got := reload(t, flagged.ID); got.Status != models.StatusPending || got.DepositPaidAt == nil {
+		t.Errorf("flagged booking %s, deposit paid %v; want it paid but left pending", got.Status, got.DepositPaidAt)
+	}
+	if got := reload(t, other.ID); got.Status != models.StatusConfirmed {
+		t.Errorf("unflagged booking %s, want confirmed by its deposit", got.Status)
+	}
+}
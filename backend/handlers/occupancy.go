@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"miniparty-backend/messages"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/settings"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxCalendarDays caps the range GET /admin/calendar will cover.
+const maxCalendarDays = 31
+
+// calendarDay is one day of the occupancy calendar. Open and Close are the weekday's hours,
+// empty when the venue is closed; AvailableHours is those hours across the active rooms,
+// and Occupancy the share of them, in percent, the day's bookings take.
+type calendarDay struct {
+	Date           string            `json:"date"`
+	Weekday        string            `json:"weekday"`
+	Closed         bool              `json:"closed"`
+	Reason         string            `json:"reason,omitempty"`
+	Open           string            `json:"open,omitempty"`
+	Close          string            `json:"close,omitempty"`
+	Bookings       []calendarBooking `json:"bookings"`
+	BookedHours    float64           `json:"booked_hours"`
+	AvailableHours float64           `json:"available_hours"`
+	Occupancy      float64           `json:"occupancy"`
+}
+
+// calendarBooking is a booking as the occupancy calendar lists it, from Start to End in
+// venue time.
+type calendarBooking struct {
+	ID       int64  `json:"id"`
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Name     string `json:"name"`
+	Guests   int    `json:"guests"`
+	Status   string `json:"status"`
+	FullDay  bool   `json:"full_day,omitempty"`
+	RoomID   *uint  `json:"room_id,omitempty"`
+	RoomName string `json:"room_name,omitempty"`
+}
+
+// GetOccupancyCalendar lays out ?from= to ?to= (default the week from today, at most
+// maxCalendarDays) for the staff's week grid: every day in the range with its opening
+// hours, its bookings in start order, and how full it is. Blackouts and the weekdays the
+// schedule closes are closed with no available hours, though any bookings on them are still
+// listed. Cancelled bookings are left out. The whole range takes one query for the bookings,
+// one for the blackouts and one for the rooms.
+func GetOccupancyCalendar(c *gin.Context) {
+	today := now().In(venueLocation())
+	from := c.DefaultQuery("from", today.Format(dateLayout))
+	start, err := time.Parse(dateLayout, from)
+	if err != nil {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "from must be in YYYY-MM-DD format")
+		return
+	}
+	to := c.DefaultQuery("to", start.AddDate(0, 0, 6).Format(dateLayout))
+	end, err := time.Parse(dateLayout, to)
+	if err != nil {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "to must be in YYYY-MM-DD format")
+		return
+	}
+	if end.Before(start) {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "from must not be after to")
+		return
+	}
+	if end.Sub(start) >= maxCalendarDays*24*time.Hour {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, fmt.Sprintf("The range can cover at most %d days", maxCalendarDays))
+		return
+	}
+
+	days, err := occupancyCalendar(conn(c), start, end, language(c))
+	if err != nil {
+		serverError(c, err, "Failed to fetch calendar")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"from": from, "to": to, "days": days})
+}
+
+// occupancyCalendar works out the occupancy calendar from first to last, inclusive.
+func occupancyCalendar(tx *gorm.DB, first, last time.Time, lang string) ([]calendarDay, error) {
+	from, to := first.Format(dateLayout), last.Format(dateLayout)
+
+	bookings, _, err := Bookings.List(tx.Statement.Context, store.ListOptions{
+		Filter: store.Filter{Active: true, From: from, To: to},
+		Limit:  -1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var blackouts []models.Blackout
+	if err := tx.Where("date >= ? AND date <= ?", from, to).Find(&blackouts).Error; err != nil {
+		return nil, err
+	}
+	rooms, err := activeRooms(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	byDate := map[string][]models.Booking{}
+	for _, b := range bookings {
+		byDate[b.Date] = append(byDate[b.Date], b)
+	}
+	closedOn := make(map[string]string, len(blackouts))
+	for _, b := range blackouts {
+		closedOn[b.Date] = b.Reason
+	}
+
+	venue := settings.Current()
+	days := make([]calendarDay, 0, int(last.Sub(first).Hours()/24)+1)
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		date := day.Format(dateLayout)
+		d := calendarDay{Date: date, Weekday: day.Weekday().String(), Bookings: []calendarBooking{}}
+		openAt, closeAt, open := venue.HoursOn(day.Weekday())
+		if reason, ok := closedOn[date]; ok {
+			d.Closed, d.Reason = true, reason
+		} else if !open {
+			d.Closed = true
+			d.Reason = messages.New(messages.DateClosedDay, "day", day.Weekday().String()).Text(lang)
+		} else {
+			d.Open, d.Close = formatMinutes(openAt), formatMinutes(closeAt)
+		}
+
+		for _, b := range byDate[date] {
+			d.Bookings = append(d.Bookings, calendarEntry(b))
+		}
+		if !d.Closed {
+			d.BookedHours, d.AvailableHours, d.Occupancy = occupancy(byDate[date], openAt, closeAt, len(rooms))
+		}
+		days = append(days, d)
+	}
+	return days, nil
+}
+
+// calendarEntry is b as the occupancy calendar lists it.
+func calendarEntry(b models.Booking) calendarBooking {
+	entry := calendarBooking{
+		ID:       b.ID,
+		Start:    b.Time,
+		End:      b.Time,
+		Name:     b.Name,
+		Guests:   b.Guests,
+		Status:   b.Status,
+		FullDay:  b.FullDay,
+		RoomID:   b.RoomID,
+		RoomName: b.RoomName,
+	}
+	if t, err := time.Parse(timeLayout, b.Time); err == nil {
+		entry.End = t.Add(time.Duration(b.Duration) * time.Hour).Format(timeLayout)
+	}
+	return entry
+}
+
+// occupancy adds up the hours bookings take between openAt and closeAt, in minutes since
+// midnight, against those hours in each of rooms rooms, and the share they are in percent
+// to one decimal. A full-day booking takes every room. Only the part of a booking within
+// the hours counts, so one made before they changed can't push the day past 100%.
+func occupancy(bookings []models.Booking, openAt, closeAt, rooms int) (booked, available, percent float64) {
+	hours := closeAt - openAt
+	if hours <= 0 || rooms == 0 {
+		return 0, 0, 0
+	}
+	minutes := 0
+	for _, b := range bookings {
+		if b.FullDay {
+			minutes = hours * rooms
+			break
+		}
+		t, err := time.Parse(timeLayout, b.Time)
+		if err != nil {
+			continue
+		}
+		begin := t.Hour()*60 + t.Minute()
+		minutes += max(min(begin+b.Duration*60, closeAt)-max(begin, openAt), 0)
+	}
+	minutes = min(minutes, hours*rooms)
+	booked, available = float64(minutes)/60, float64(hours*rooms)/60
+	return booked, available, math.Round(booked/available*1000) / 10
+}
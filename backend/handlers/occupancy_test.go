@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"miniparty-backend/db"
+	"miniparty-backend/models"
+)
+
+func TestOccupancy(t *testing.T) {
+	const openAt, closeAt = 10 * 60, 22 * 60 // twelve hours a room
+	at := func(clock string, hours int) models.Booking {
+		return models.Booking{Time: clock, Duration: hours}
+	}
+	tests := []struct {
+		name                       string
+		bookings                   []models.Booking
+		rooms                      int
+		booked, available, percent float64
+	}{
+		{"empty", nil, 2, 0, 24, 0},
+		{"one booking", []models.Booking{at("14:00", 3)}, 1, 3, 12, 25},
+		{"across rooms", []models.Booking{at("14:00", 3)}, 2, 3, 24, 12.5},
+		{"overruns closing", []models.Booking{at("20:00", 4)}, 1, 2, 12, 16.7},
+		{"starts before opening", []models.Booking{at("09:00", 2)}, 1, 1, 12, 8.3},
+		{"outside the hours", []models.Booking{at("22:00", 1), at("08:00", 2)}, 1, 0, 12, 0},
+		{"full day", []models.Booking{at("14:00", 2), {Time: "10:00", Duration: 1, FullDay: true}}, 3, 36, 36, 100},
+		{"zero rooms", []models.Booking{at("14:00", 2)}, 0, 0, 0, 0},
+		{"back to back", []models.Booking{at("10:00", 2), at("12:00", 2), at("14:00", 2)}, 1, 6, 12, 50},
+		{"edges overlap", []models.Booking{at("10:00", 8), at("16:00", 6), at("18:00", 4)}, 1, 12, 12, 100},
+		{"unreadable time", []models.Booking{{Time: "soon", Duration: 2}, at("10:00", 1)}, 1, 1, 12, 8.3},
+	}
+	for _, tt := range tests {
+		booked, available, percent := occupancy(tt.bookings, openAt, closeAt, tt.rooms)
+		if booked != tt.booked || available != tt.available || percent != tt.percent {
+			t.Errorf("%s: occupancy = %v of %v hours, %v%%, want %v of %v, %v%%",
+				tt.name, booked, available, percent, tt.booked, tt.available, tt.percent)
+		}
+	}
+
+	if _, _, percent := occupancy([]models.Booking{at("12:00", 2)}, openAt, openAt, 1); percent != 0 {
+		t.Errorf("no opening hours: occupancy %v%%, want 0", percent)
+	}
+}
+
+func TestOccupancyCalendarBlackout(t *testing.T) {
+	testDB(t)
+	addBooking(t, models.Booking{Date: "2026-07-06", Time: "14:00", Duration: 3})
+	addBooking(t, models.Booking{Date: "2026-07-09", Time: "12:00", Duration: 2})
+	addBooking(t, models.Booking{Date: "2026-07-10", Time: "14:00", Duration: 6})
+	if err := db.DB.Create(&models.Blackout{Date: "2026-07-09", Reason: "Private event"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	first := time.Date(2026, 7, 6, 0, 0, 0, 0, time.UTC)
+	days, err := occupancyCalendar(db.DB, first, first.AddDate(0, 0, 6), "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, d := range days {
+		got = append(got, fmt.Sprintf("%s %v %d %v/%v %v%%", d.Date, d.Closed, len(d.Bookings), d.BookedHours, d.AvailableHours, d.Occupancy))
+	}
+	want := []string{
+		"2026-07-06 false 1 3/12 25%",
+		"2026-07-07 false 0 0/12 0%",
+		"2026-07-08 false 0 0/12 0%",
+		// Closed for the blackout, with its booking still listed but not counted.
+		"2026-07-09 true 1 0/0 0%",
+		"2026-07-10 false 1 6/12 50%",
+		"2026-07-11 false 0 0/12 0%",
+		"2026-07-12 false 0 0/12 0%",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("calendar:\n got %v\nwant %v", got, want)
+	}
+	if days[3].Reason != "Private event" {
+		t.Errorf("blackout reason %q, want Private event", days[3].Reason)
+	}
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"miniparty-backend/messages"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/settings"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type packageRequest struct {
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	DurationHours  int    `json:"duration_hours"`
+	BasePriceCents int    `json:"base_price_cents"`
+	MaxGuests      int    `json:"max_guests"`
+	Active         *bool  `json:"active"`
+}
+
+// apply validates req and copies it onto p. Active defaults to true for new packages and
+// is left alone on updates that don't mention it.
+func (req packageRequest) apply(p *models.Package) fieldErrors {
+	errs := fieldErrors{}
+	venue := settings.Current()
+	p.Name = strings.TrimSpace(req.Name)
+	p.Description = strings.TrimSpace(req.Description)
+	p.DurationHours = req.DurationHours
+	p.BasePriceCents = req.BasePriceCents
+	p.MaxGuests = req.MaxGuests
+	if req.Active != nil {
+		p.Active = *req.Active
+	}
+
+	if p.Name == "" {
+		errs.add("name", messages.NameRequired)
+	}
+	if p.DurationHours < venue.MinDurationHours || p.DurationHours > venue.MaxDurationHours {
+		errs.add("duration_hours", messages.DurationRange, "min", venue.MinDurationHours, "max", venue.MaxDurationHours)
+	}
+	if p.BasePriceCents < 0 {
+		errs.add("base_price_cents", messages.BasePriceNegative)
+	}
+	if p.MaxGuests < 1 || p.MaxGuests > venue.MaxGuests {
+		errs.add("max_guests", messages.MaxGuestsRange, "max", venue.MaxGuests)
+	}
+	return errs
+}
+
+// GetPackages lists the active packages for the booking form.
+func GetPackages(c *gin.Context) {
+	packages := []models.Package{}
+	if err := conn(c).Where("active = ?", true).Order("base_price_cents ASC, id ASC").Find(&packages).Error; err != nil {
+		serverError(c, err, "Failed to fetch packages")
+		return
+	}
+
+	c.JSON(http.StatusOK, packages)
+}
+
+// GetAllPackages lists every package, active or not, for the admin.
+func GetAllPackages(c *gin.Context) {
+	packages := []models.Package{}
+	if err := conn(c).Order("id ASC").Find(&packages).Error; err != nil {
+		serverError(c, err, "Failed to fetch packages")
+		return
+	}
+
+	c.JSON(http.StatusOK, packages)
+}
+
+func CreatePackage(c *gin.Context) {
+	var req packageRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	pkg := models.Package{Active: true}
+	if errs := req.apply(&pkg); len(errs) > 0 {
+		badFields(c, errs)
+		return
+	}
+
+	if err := conn(c).Create(&pkg).Error; err != nil {
+		packageSaveError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, pkg)
+}
+
+// UpdatePackage replaces a package's details. Existing bookings keep the price they were quoted.
+func UpdatePackage(c *gin.Context) {
+	id, ok := packageID(c)
+	if !ok {
+		return
+	}
+
+	var req packageRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	var pkg models.Package
+	if err := conn(c).First(&pkg, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Package not found")
+			return
+		}
+		serverError(c, err, "Failed to fetch package")
+		return
+	}
+
+	if errs := req.apply(&pkg); len(errs) > 0 {
+		badFields(c, errs)
+		return
+	}
+
+	// As in UpdateAddon, a package deleted meanwhile isn't recreated.
+	result := conn(c).Select("*").Save(&pkg)
+	if result.Error != nil {
+		packageSaveError(c, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Package not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, pkg)
+}
+
+// DeletePackage removes a package nobody has booked. Packages with bookings can only be
+// deactivated, so those bookings keep pointing at something.
+func DeletePackage(c *gin.Context) {
+	id, ok := packageID(c)
+	if !ok {
+		return
+	}
+
+	used, err := Bookings.Count(c.Request.Context(), store.ListOptions{Filter: store.Filter{PackageID: id}, IncludeDeleted: true})
+	if err != nil {
+		serverError(c, err, "Failed to delete package")
+		return
+	}
+	if used > 0 {
+		middleware.Fail(c, http.StatusConflict, models.CodeInUse, fmt.Sprintf("This package is used by %d booking(s). Deactivate it instead.", used))
+		return
+	}
+
+	result := conn(c).Delete(&models.Package{}, id)
+	if result.Error != nil {
+		serverError(c, result.Error, "Failed to delete package")
+		return
+	}
+	if result.RowsAffected == 0 {
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Package not found")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// packageID parses the :id path parameter, writing a 400 and returning false if it isn't a positive integer.
+func packageID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil || id == 0 {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "Invalid package ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func packageSaveError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		middleware.Fail(c, http.StatusConflict, models.CodeNameTaken, "A package with this name already exists")
+		return
+	}
+	serverError(c, err, "Failed to save package")
+}
+
+// findPackage returns the booking's package, or nil if it wasn't made with one.
+func findPackage(tx *gorm.DB, b *models.Booking) (*models.Package, error) {
+	if b.PackageID == nil {
+		return nil, nil
+	}
+	var pkg models.Package
+	err := tx.First(&pkg, *b.PackageID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pkg, nil
+}
+
+// applyPackage fixes a new booking's duration to its package and checks the package is still
+// offered and fits the party. It returns the package (nil if none was requested) and any
+// problems with the choice.
+func applyPackage(tx *gorm.DB, b *models.Booking) (*models.Package, fieldErrors, error) {
+	errs := fieldErrors{}
+	pkg, err := findPackage(tx, b)
+	if err != nil || b.PackageID == nil {
+		return nil, errs, err
+	}
+	if pkg == nil || !pkg.Active {
+		errs.add("package_id", messages.PackageUnavailable)
+		return nil, errs, nil
+	}
+	if b.FullDay {
+		errs.add("package_id", messages.PackageFullDay)
+		return nil, errs, nil
+	}
+
+	b.Duration = pkg.DurationHours
+	if b.Guests > pkg.MaxGuests {
+		errs.add("guests", messages.GuestsPackageMax, "package", pkg.Name, "max", pkg.MaxGuests)
+	}
+	return pkg, errs, nil
+}
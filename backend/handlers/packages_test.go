@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"miniparty-backend/messages"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+)
+
+func TestMissingPackageWrites(t *testing.T) {
+	testDB(t)
+	r := newRouter()
+	r.PUT("/packages/:id", UpdatePackage)
+	r.DELETE("/packages/:id", DeletePackage)
+	r.POST("/packages", CreatePackage)
+	body := `{"name": "Deluxe", "duration_hours": 3, "max_guests": 20}`
+
+	expectError(t, call(r, http.MethodPut, "/packages/999", body), http.StatusNotFound, models.CodeNotFound)
+	expectError(t, call(r, http.MethodDelete, "/packages/999", nil), http.StatusNotFound, models.CodeNotFound)
+	expectError(t, call(r, http.MethodPut, "/packages/abc", body), http.StatusBadRequest, models.CodeBadRequest)
+
+	// Deleted between being read and being saved, it is still a 404.
+	w := call(r, http.MethodPost, "/packages", body)
+	expect(t, w, http.StatusCreated)
+	created := decode[models.Package](t, w)
+	vanishOnUpdate(t, "packages", created.ID)
+	expectError(t, call(r, http.MethodPut, fmt.Sprintf("/packages/%d", created.ID), body), http.StatusNotFound, models.CodeNotFound)
+}
+
+func TestPackages(t *testing.T) {
+	testDB(t)
+	r := newRouter()
+	r.GET("/packages", GetPackages)
+	r.GET("/admin/packages", GetAllPackages)
+	r.POST("/admin/packages", CreatePackage)
+	r.PUT("/admin/packages/:id", UpdatePackage)
+	r.DELETE("/admin/packages/:id", DeletePackage)
+	r.POST("/book", middleware.OptionalAdminAuth(), CreateBooking)
+	create := func(body string) models.Package {
+		t.Helper()
+		w := call(r, http.MethodPost, "/admin/packages", body)
+		expect(t, w, http.StatusCreated)
+		return decode[models.Package](t, w)
+	}
+
+	deluxe := create(`{"name": " Deluxe ", "duration_hours": 3, "base_price_cents": 25000, "max_guests": 8}`)
+	basic := create(`{"name": "Basic", "duration_hours": 2, "base_price_cents": 15000, "max_guests": 20}`)
+	if deluxe.Name != "Deluxe" || !deluxe.Active {
+		t.Errorf("created %+v, want the name trimmed and the package active", deluxe)
+	}
+	expectError(t, call(r, http.MethodPost, "/admin/packages", `{"name": "Basic", "duration_hours": 2, "max_guests": 20}`),
+		http.StatusConflict, models.CodeNameTaken)
+	codes := fieldCodes(t, call(r, http.MethodPost, "/admin/packages", `{"duration_hours": 99, "base_price_cents": -1, "max_guests": 0}`))
+	for field, code := range map[string]string{
+		"name": messages.NameRequired, "duration_hours": messages.DurationRange,
+		"base_price_cents": messages.BasePriceNegative, "max_guests": messages.MaxGuestsRange,
+	} {
+		if !hasCode(codes, field, code) {
+			t.Errorf("%s: codes %v, want %s", field, codes[field], code)
+		}
+	}
+
+	// The booking takes the package's duration and price, whatever duration it asked for.
+	w := call(r, http.MethodPost, "/book", bookBody("2026-07-08", "14:00", "package_id", deluxe.ID, "duration", 5))
+	expect(t, w, http.StatusCreated)
+	booked := decode[struct{ Booking models.Booking }](t, w).Booking
+	if booked.Duration != 3 || booked.PackageID == nil || *booked.PackageID != deluxe.ID || booked.PriceCents != 25000 {
+		t.Errorf("booked %+v, want three hours of Deluxe at 25000", booked)
+	}
+	if codes := fieldCodes(t, call(r, http.MethodPost, "/book", bookBody("2026-07-09", "14:00", "package_id", deluxe.ID, "guests", 9,
+		"email", "eve@example.com", "phone", "+14155550999"))); !hasCode(codes, "guests", messages.GuestsPackageMax) {
+		t.Errorf("too many guests for the package: %v", codes)
+	}
+
+	// Deactivated, a package leaves the booking form and can't be booked, but stays listed for staff.
+	w = call(r, http.MethodPut, fmt.Sprintf("/admin/packages/%d", basic.ID),
+		`{"name": "Basic", "duration_hours": 2, "base_price_cents": 15000, "max_guests": 20, "active": false}`)
+	expect(t, w, http.StatusOK)
+	if got := decode[[]models.Package](t, call(r, http.MethodGet, "/packages", nil)); len(got) != 1 || got[0].ID != deluxe.ID {
+		t.Errorf("public packages = %+v, want only Deluxe", got)
+	}
+	if got := decode[[]models.Package](t, call(r, http.MethodGet, "/admin/packages", nil)); len(got) != 2 {
+		t.Errorf("admin packages = %+v, want both", got)
+	}
+	if codes := fieldCodes(t, call(r, http.MethodPost, "/book", bookBody("2026-07-09", "14:00", "package_id", basic.ID,
+		"email", "eve@example.com", "phone", "+14155550999"))); !hasCode(codes, "package_id", messages.PackageUnavailable) {
+		t.Errorf("inactive package: %v", codes)
+	}
+
+	// A package with bookings can only be deactivated.
+	expectError(t, call(r, http.MethodDelete, fmt.Sprintf("/admin/packages/%d", deluxe.ID), nil), http.StatusConflict, models.CodeInUse)
+	expect(t, call(r, http.MethodDelete, fmt.Sprintf("/admin/packages/%d", basic.ID), nil), http.StatusNoContent)
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"miniparty-backend/db"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/notify"
+	"miniparty-backend/payments"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Payments takes deposits online. It is nil, and bookings are taken without a deposit,
+// unless main configures a provider.
+var Payments payments.Provider
+
+// MaxWebhookBody caps the webhook payload read into memory. Stripe events are a few KB,
+// but can run past the default body limit, so the route sets its own.
+const MaxWebhookBody = 64 << 10
+
+// depositAmount is the deposit, in cents, taken online for new bookings (DEPOSIT_AMOUNT, default 0: none).
+func depositAmount() int {
+	return envInt("DEPOSIT_AMOUNT", 0)
+}
+
+// depositHold is how long a new booking keeps its slot while the deposit is unpaid (DEPOSIT_HOLD, default 30m).
+func depositHold() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("DEPOSIT_HOLD")); err == nil && d > 0 {
+		return d
+	}
+	return 30 * time.Minute
+}
+
+// depositRequired reports whether new bookings must pay a deposit online.
+func depositRequired() bool {
+	return Payments != nil && depositAmount() > 0
+}
+
+// requestDeposit starts the deposit payment for a newly created booking and records the
+// payment and hold deadline on it.
+func requestDeposit(c *gin.Context, b *models.Booking) (payments.Intent, error) {
+	amount := depositAmount()
+	intent, err := Payments.CreateDeposit(c.Request.Context(), b.ID, amount, b.Email)
+	if err != nil {
+		return intent, err
+	}
+
+	holdUntil := now().UTC().Add(depositHold())
+	*b, err = Bookings.Modify(c.Request.Context(), b.ID, func(b *models.Booking) error {
+		b.DepositDueCents = amount
+		b.PaymentIntentID = intent.ID
+		b.HoldExpiresAt = &holdUntil
+		return nil
+	})
+	return intent, err
+}
+
+// depositDetails is what the frontend needs to take b's deposit through intent.
+func depositDetails(b models.Booking, intent payments.Intent) gin.H {
+	return gin.H{
+		"amount_cents":    b.DepositDueCents,
+		"client_secret":   intent.ClientSecret,
+		"hold_expires_at": b.HoldExpiresAt,
+	}
+}
+
+// StripeWebhook confirms bookings whose deposit has been paid. Stripe redelivers anything
+// that doesn't get a 2xx, so events the server doesn't act on are acknowledged too.
+func StripeWebhook(c *gin.Context) {
+	if Payments == nil {
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Payments are not configured")
+		return
+	}
+
+	payload, err := io.ReadAll(c.Request.Body)
+	if middleware.BodyTooLarge(err) {
+		middleware.Fail(c, http.StatusRequestEntityTooLarge, models.CodeTooLarge, "Request body too large")
+		return
+	}
+	if err != nil {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "Invalid request body")
+		return
+	}
+	event, err := Payments.ParseWebhook(payload, c.GetHeader("Stripe-Signature"), time.Now())
+	if errors.Is(err, payments.ErrInvalidSignature) {
+		middleware.Logger(c).Warn("stripe webhook rejected", "reason", "invalid signature")
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "Invalid signature")
+		return
+	}
+	if err != nil {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "Invalid event payload")
+		return
+	}
+
+	if event.Type == payments.PaymentSucceeded && event.IntentID != "" {
+		if err := markDepositPaid(c, event.IntentID); err != nil {
+			serverError(c, err, "Failed to record payment")
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"received": true})
+}
+
+// markDepositPaid records the deposit for the booking with intentID as paid and confirms it
+// if it is still pending, unless it is flagged for repeat no-shows and NO_SHOW_HOLD_FLAGGED
+// leaves that to staff. A payment for a booking whose hold was already released is only
+// logged: its slot may have been rebooked, so staff have to sort it out (usually a refund).
+func markDepositPaid(c *gin.Context, intentID string) error {
+	var booking models.Booking
+	confirmed := false
+	err := inTx(c, func(ctx context.Context, tx *gorm.DB) error {
+		confirmed = false
+		var payment *models.Payment
+		// A booking deleted since its payment began still gets it: the money has been taken.
+		found, _, err := Bookings.List(ctx, store.ListOptions{Filter: store.Filter{PaymentIntentID: intentID}, Limit: 1, IncludeDeleted: true})
+		if err != nil {
+			return err
+		}
+		if len(found) == 0 {
+			middleware.Logger(c).Warn("payment for unknown booking", "payment_intent_id", intentID)
+			return nil
+		}
+		booking, err = Queries.ModifyAny(ctx, found[0].ID, func(b *models.Booking) error {
+			if b.DepositPaid {
+				return nil
+			}
+			switch {
+			case b.Status == models.StatusPending && !b.DeletedAt.Valid && holdFlagged(*b):
+				middleware.Logger(c).Info("deposit paid for a flagged booking; leaving it for staff to confirm",
+					"booking_id", b.ID, "prior_no_shows", b.PriorNoShows)
+			case b.Status == models.StatusPending && !b.DeletedAt.Valid:
+				b.Status = models.StatusConfirmed
+				confirmed = true
+			default:
+				middleware.Logger(c).Warn("deposit paid for a released booking",
+					"booking_id", b.ID, "status", b.Status, "payment_intent_id", intentID)
+			}
+			at := now().UTC()
+			b.DepositPaid, b.DepositPaidAt, b.HoldExpiresAt = true, &at, nil
+			b.AmountPaidCents += b.DepositDueCents
+			b.PaymentMethod, b.PaidAt = models.PaymentCard, &at
+			// Recorded like a payment taken by hand, so the paid total counts it.
+			payment = &models.Payment{BookingID: b.ID, AmountCents: b.DepositDueCents, Method: models.PaymentCard,
+				Reference: intentID, CreatedAt: at}
+			return nil
+		})
+		if err != nil || payment == nil {
+			return err
+		}
+		return tx.Create(payment).Error
+	})
+	if err == nil && confirmed {
+		calendarChanged(booking.ID)
+		publish(notify.BookingConfirmed, booking)
+	}
+	return err
+}
+
+// ReleaseUnpaidHolds cancels bookings whose deposit is still unpaid when their hold runs
+// out, freeing the slot for the waitlist, checking every interval until ctx is done.
+func ReleaseUnpaidHolds(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !db.Ready() {
+			continue
+		}
+		if err := releaseExpiredHolds(ctx, now().UTC()); err != nil {
+			slog.Error("failed to release unpaid bookings", "error", err)
+		}
+	}
+}
+
+func releaseExpiredHolds(ctx context.Context, at time.Time) error {
+	expired, err := Queries.ExpiredHolds(ctx, at)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range expired {
+		// Re-check the conditions so a payment that lands meanwhile isn't cancelled.
+		released := false
+		_, err := Bookings.Modify(ctx, b.ID, func(b *models.Booking) error {
+			if b.Status == models.StatusPending && !b.DepositPaid {
+				b.Status, b.HoldExpiresAt = models.StatusCancelled, nil
+				released = true
+			}
+			return nil
+		})
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !released {
+			continue
+		}
+		slog.Info("released unpaid booking", "booking_id", b.ID, "date", b.Date, "time", b.Time)
+		if err := Payments.CancelDeposit(ctx, b.PaymentIntentID); err != nil {
+			slog.Warn("failed to cancel deposit payment", "booking_id", b.ID, "payment_intent_id", b.PaymentIntentID, "error", err)
+		}
+		promoteWaitlist(ctx, b.Date)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"miniparty-backend/models"
+	"miniparty-backend/payments"
+)
+
+// stripeSigned is the Stripe-Signature header for payload signed under secret at t.
+func stripeSigned(secret string, t time.Time, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.%s", t.Unix(), payload)
+	return fmt.Sprintf("t=%d,v1=%s", t.Unix(), hex.EncodeToString(mac.Sum(nil)))
+}
+
+func TestStripeWebhook(t *testing.T) {
+	testDB(t)
+	swap[payments.Provider](t, &Payments, &payments.Stripe{WebhookSecret: "whsec_test"})
+	b := addBooking(t, models.Booking{Status: models.StatusPending, PaymentIntentID: "pi_1", DepositDueCents: 2500})
+	r := newRouter()
+	r.POST("/webhooks/stripe", StripeWebhook)
+	event := func(typ string) string {
+		return fmt.Sprintf(`{"id":"evt_%s","type":%q,"data":{"object":{"id":"pi_1","object":"payment_intent"}}}`, typ, typ)
+	}
+	untouched := func(when string) {
+		t.Helper()
+		if got := reload(t, b.ID); got.Status != models.StatusPending || got.DepositPaid || got.AmountPaidCents != 0 {
+			t.Errorf("%s: booking %+v, want it left pending and unpaid", when, got)
+		}
+	}
+
+	succeeded := event(payments.PaymentSucceeded)
+	for name, header := range map[string]string{
+		"wrong secret": stripeSigned("whsec_other", time.Now(), succeeded),
+		"too old":      stripeSigned("whsec_test", time.Now().Add(-payments.WebhookTolerance-time.Minute), succeeded),
+		"unsigned":     "",
+	} {
+		expectError(t, call(r, http.MethodPost, "/webhooks/stripe", succeeded, "Stripe-Signature", header), http.StatusBadRequest, models.CodeBadRequest)
+		untouched(name)
+	}
+
+	// Events the server doesn't act on are acknowledged, so Stripe stops sending them.
+	failed := event("payment_intent.payment_failed")
+	w := call(r, http.MethodPost, "/webhooks/stripe", failed, "Stripe-Signature", stripeSigned("whsec_test", time.Now(), failed))
+	expect(t, w, http.StatusOK)
+	untouched("payment_failed")
+
+	w = call(r, http.MethodPost, "/webhooks/stripe", succeeded, "Stripe-Signature", stripeSigned("whsec_test", time.Now(), succeeded))
+	expect(t, w, http.StatusOK)
+	if got := reload(t, b.ID); got.Status != models.StatusConfirmed || !got.DepositPaid || got.AmountPaidCents != 2500 || got.HoldExpiresAt != nil {
+		t.Errorf("after payment_intent.succeeded: %+v, want it confirmed with the deposit paid", got)
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// phoneCountry describes how national numbers are written in a country.
+type phoneCountry struct {
+	callingCode string
+	minDigits   int // national significant number, without the trunk prefix
+	maxDigits   int
+	trunkPrefix string
+}
+
+var phoneCountries = map[string]phoneCountry{
+	"IN": {callingCode: "91", minDigits: 10, maxDigits: 10, trunkPrefix: "0"},
+	"US": {callingCode: "1", minDigits: 10, maxDigits: 10, trunkPrefix: "1"},
+	"CA": {callingCode: "1", minDigits: 10, maxDigits: 10, trunkPrefix: "1"},
+	"GB": {callingCode: "44", minDigits: 9, maxDigits: 10, trunkPrefix: "0"},
+	"AE": {callingCode: "971", minDigits: 8, maxDigits: 9, trunkPrefix: "0"},
+	"AU": {callingCode: "61", minDigits: 9, maxDigits: 9, trunkPrefix: "0"},
+}
+
+// phoneSeparators are the formatting characters people type between digits.
+var phoneSeparators = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", ".", "")
+
+// defaultCountry is the country (DEFAULT_COUNTRY, ISO 3166 alpha-2) local numbers are assumed to be in.
+func defaultCountry() string {
+	if c := strings.ToUpper(strings.TrimSpace(os.Getenv("DEFAULT_COUNTRY"))); c != "" {
+		if _, ok := phoneCountries[c]; ok {
+			return c
+		}
+	}
+	return "IN"
+}
+
+// normalizePhone converts a user-entered phone number to E.164 ("+919876543210").
+// Numbers without a "+" or "00" prefix are treated as local to country.
+func normalizePhone(raw, country string) (string, error) {
+	invalid := fmt.Errorf("Phone number is not valid for country %s", country)
+
+	s := phoneSeparators.Replace(strings.TrimSpace(raw))
+	international := false
+	switch {
+	case strings.HasPrefix(s, "+"):
+		s, international = s[1:], true
+	case strings.HasPrefix(s, "00"):
+		s, international = s[2:], true
+	}
+	if s == "" || !isDigits(s) {
+		return "", invalid
+	}
+
+	if international {
+		// E.164 allows at most 15 digits including the calling code.
+		if len(s) < 8 || len(s) > 15 {
+			return "", invalid
+		}
+		for _, pc := range phoneCountries {
+			if national, ok := strings.CutPrefix(s, pc.callingCode); ok && len(national) >= pc.minDigits {
+				if len(national) > pc.maxDigits {
+					return "", invalid
+				}
+				break
+			}
+		}
+		return "+" + s, nil
+	}
+
+	pc := phoneCountries[country]
+	if len(s) > pc.maxDigits && pc.trunkPrefix != "" {
+		s = strings.TrimPrefix(s, pc.trunkPrefix)
+	}
+	if len(s) < pc.minDigits || len(s) > pc.maxDigits {
+		return "", invalid
+	}
+	return "+" + pc.callingCode + s, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
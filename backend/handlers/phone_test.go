@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"miniparty-backend/models"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		raw, country, want string // want "" when raw is rejected
+	}{
+		{"98765 43210", "IN", "+919876543210"},
+		{"098765-43210", "IN", "+919876543210"},
+		{"+91 98765 43210", "IN", "+919876543210"},
+		{"0091 9876543210", "IN", "+919876543210"},
+		{"(415) 555-0123", "US", "+14155550123"},
+		{"1 415 555 0123", "US", "+14155550123"},
+		{"020 7946 0958", "GB", "+442079460958"},
+		{"+44 20 7946 0958", "US", "+442079460958"},
+		{"050 123 4567", "AE", "+971501234567"},
+		{"0412 345 678", "AU", "+61412345678"},
+		{"12345", "IN", ""},
+		{"98765432101234", "IN", ""},
+		{"+91 98765 432109", "IN", ""},
+		{"+1234", "IN", ""},
+		{"call me", "IN", ""},
+		{"+", "IN", ""},
+		{"", "IN", ""},
+	}
+	for _, tt := range tests {
+		got, err := normalizePhone(tt.raw, tt.country)
+		switch {
+		case tt.want == "" && err == nil:
+			t.Errorf("normalizePhone(%q, %s) = %s, want an error", tt.raw, tt.country, got)
+		case tt.want != "" && (err != nil || got != tt.want):
+			t.Errorf("normalizePhone(%q, %s) = %q, %v, want %s", tt.raw, tt.country, got, err, tt.want)
+		}
+	}
+}
+
+func TestDefaultCountry(t *testing.T) {
+	for env, want := range map[string]string{"": "IN", "us": "US", " GB ": "GB", "FR": "IN"} {
+		t.Setenv("DEFAULT_COUNTRY", env)
+		if got := defaultCountry(); got != want {
+			t.Errorf("DEFAULT_COUNTRY=%q: %s, want %s", env, got, want)
+		}
+	}
+}
+
+func TestPhonesStoredAndSearchedAsE164(t *testing.T) {
+	testDB(t)
+	t.Setenv("DEFAULT_COUNTRY", "IN")
+	r := newRouter()
+	r.POST("/book", CreateBooking)
+	r.GET("/bookings", GetBookings)
+
+	w := call(r, http.MethodPost, "/book", bookBody("2026-07-10", "14:00", "phone", "098765-43210"))
+	expect(t, w, http.StatusCreated)
+	if got := decode[struct{ Booking models.Booking }](t, w).Booking; got.Phone != "+919876543210" {
+		t.Errorf("stored phone %q, want +919876543210", got.Phone)
+	}
+	if codes := fieldCodes(t, call(r, http.MethodPost, "/book", bookBody("2026-07-11", "14:00", "phone", "12345"))); len(codes["phone"]) == 0 {
+		t.Errorf("a short number: %v, want a phone error", codes)
+	}
+
+	for _, q := range []string{"98765 43210", "+91 98765-43210", "43210"} {
+		w := call(r, http.MethodGet, "/bookings?q="+url.QueryEscape(q), nil)
+		expect(t, w, http.StatusOK)
+		if list := decode[models.BookingList](t, w); list.Total != 1 {
+			t.Errorf("search %q: %d bookings, want the one", q, list.Total)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"math"
+	"time"
+
+	"miniparty-backend/models"
+	"miniparty-backend/settings"
+)
+
+// basisPoints is the fixed-point scale for multipliers: 1.25 is stored as 12500.
+const basisPoints = 10000
+
+// pricing is the rate card. Amounts are integer cents.
+type pricing struct {
+	HourlyRate     int
+	PerGuestRate   int
+	GuestThreshold int
+	// WeekendMultiplier applies to Saturday and Sunday bookings, in basis points.
+	WeekendMultiplier int
+	// FullDayRate is the charge for the whole venue for a day, in place of the hours.
+	FullDayRate int
+}
+
+// priceBreakdown itemises a quote. A package's base price, or a full day's rate, replaces the
+// hourly charge; the weekend surcharge applies to the base and guest charges together.
+type priceBreakdown struct {
+	Hours            int  `json:"hours"`
+	HourlyRate       int  `json:"hourly_rate_cents"`
+	HourlyCharge     int  `json:"hourly_charge_cents"`
+	PackagePrice     int  `json:"package_price_cents,omitempty"`
+	FullDayRate      int  `json:"full_day_rate_cents,omitempty"`
+	ExtraGuests      int  `json:"extra_guests"`
+	PerGuestRate     int  `json:"per_guest_rate_cents"`
+	GuestCharge      int  `json:"guest_charge_cents"`
+	Weekend          bool `json:"weekend"`
+	WeekendSurcharge int  `json:"weekend_surcharge_cents"`
+	Total            int  `json:"total_cents"`
+}
+
+// venuePricing is the rate card from the venue settings.
+func venuePricing(v settings.Venue) pricing {
+	return pricing{
+		HourlyRate:        v.HourlyRateCents,
+		PerGuestRate:      v.PerGuestRateCents,
+		GuestThreshold:    v.GuestThreshold,
+		WeekendMultiplier: int(math.Round(v.WeekendMultiplier * basisPoints)),
+		FullDayRate:       v.FullDayRateCents,
+	}
+}
+
+// quote prices a booking of hours and guests on date, made with pkg if it isn't nil, or
+// of the whole day at the full-day rate if fullDay is set. It only uses integer arithmetic,
+// and the surcharge rounds half up to the nearest cent, so the same inputs always give the
+// same total.
+func (p pricing) quote(date time.Time, hours, guests int, fullDay bool, pkg *models.Package) priceBreakdown {
+	b := priceBreakdown{Hours: hours, PerGuestRate: p.PerGuestRate}
+	switch {
+	case pkg != nil:
+		b.PackagePrice = pkg.BasePriceCents
+	case fullDay:
+		b.FullDayRate = p.FullDayRate
+	default:
+		b.HourlyRate = p.HourlyRate
+		b.HourlyCharge = p.HourlyRate * hours
+	}
+	if guests > p.GuestThreshold {
+		b.ExtraGuests = guests - p.GuestThreshold
+		b.GuestCharge = b.ExtraGuests * p.PerGuestRate
+	}
+
+	subtotal := b.HourlyCharge + b.PackagePrice + b.FullDayRate + b.GuestCharge
+	if wd := date.Weekday(); wd == time.Saturday || wd == time.Sunday {
+		b.Weekend = true
+		b.WeekendSurcharge = (subtotal*(p.WeekendMultiplier-basisPoints) + basisPoints/2) / basisPoints
+	}
+	b.Total = subtotal + b.WeekendSurcharge
+	return b
+}
+
+// priceBooking quotes b, made with pkg if it isn't nil, at the configured rates and stores
+// the total on it. b's date must already be validated.
+func priceBooking(b *models.Booking, pkg *models.Package) priceBreakdown {
+	date, _ := time.Parse(dateLayout, b.Date)
+	breakdown := venuePricing(settings.Current()).quote(date, b.Duration, b.Guests, b.FullDay, pkg)
+	b.PriceCents = breakdown.Total
+	return breakdown
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/settings"
+)
+
+func TestQuote(t *testing.T) {
+	p := pricing{HourlyRate: 5000, PerGuestRate: 500, GuestThreshold: 10, WeekendMultiplier: 12500, FullDayRate: 60000}
+	wednesday := time.Date(2026, 7, 8, 0, 0, 0, 0, time.UTC)
+	saturday := time.Date(2026, 7, 11, 0, 0, 0, 0, time.UTC)
+	pkg := &models.Package{BasePriceCents: 20000}
+
+	tests := []struct {
+		name    string
+		date    time.Time
+		hours   int
+		guests  int
+		fullDay bool
+		pkg     *models.Package
+		want    priceBreakdown
+	}{
+		{"weekday", wednesday, 2, 10, false, nil,
+			priceBreakdown{Hours: 2, HourlyRate: 5000, HourlyCharge: 10000, PerGuestRate: 500, Total: 10000}},
+		{"extra guests", wednesday, 2, 13, false, nil,
+			priceBreakdown{Hours: 2, HourlyRate: 5000, HourlyCharge: 10000, ExtraGuests: 3, PerGuestRate: 500, GuestCharge: 1500, Total: 11500}},
+		{"weekend", saturday, 3, 11, false, nil,
+			priceBreakdown{Hours: 3, HourlyRate: 5000, HourlyCharge: 15000, ExtraGuests: 1, PerGuestRate: 500, GuestCharge: 500,
+				Weekend: true, WeekendSurcharge: 3875, Total: 19375}},
+		{"package", saturday, 2, 4, false, pkg,
+			priceBreakdown{Hours: 2, PackagePrice: 20000, PerGuestRate: 500, Weekend: true, WeekendSurcharge: 5000, Total: 25000}},
+		{"full day", wednesday, 0, 12, true, nil,
+			priceBreakdown{FullDayRate: 60000, ExtraGuests: 2, PerGuestRate: 500, GuestCharge: 1000, Total: 61000}},
+	}
+	for _, tt := range tests {
+		if got := p.quote(tt.date, tt.hours, tt.guests, tt.fullDay, tt.pkg); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	// The surcharge rounds half a cent up: 1.5% of 100 cents is 1.5.
+	odd := pricing{HourlyRate: 100, WeekendMultiplier: 10150}
+	if got := odd.quote(saturday, 1, 0, false, nil); got.WeekendSurcharge != 2 || got.Total != 102 {
+		t.Errorf("half a cent: surcharge %d, total %d, want 2 and 102", got.WeekendSurcharge, got.Total)
+	}
+}
+
+func TestBookingStoresItsPrice(t *testing.T) {
+	testDB(t)
+	setVenue(t, func(v *settings.Venue) {
+		v.HourlyRateCents, v.PerGuestRateCents, v.GuestThreshold, v.WeekendMultiplier = 4000, 300, 4, 1.5
+	})
+	r := newRouter()
+	r.POST("/book", middleware.OptionalAdminAuth(), CreateBooking)
+
+	w := call(r, http.MethodPost, "/book", bookBody("2026-07-11", "14:00", "guests", 6))
+	expect(t, w, http.StatusCreated)
+	got := decode[struct {
+		Booking models.Booking `json:"booking"`
+		Price   priceBreakdown `json:"price"`
+	}](t, w)
+	// Two hours and two extra guests on a Saturday: (8000 + 600) * 1.5.
+	if got.Price.Total != 12900 || !got.Price.Weekend {
+		t.Errorf("quoted %+v, want 12900 with the weekend surcharge", got.Price)
+	}
+	if b := reload(t, got.Booking.ID); b.PriceCents != 12900 {
+		t.Errorf("stored price %d, want 12900", b.PriceCents)
+	}
+}
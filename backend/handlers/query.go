@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"miniparty-backend/models"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPerPage = 50
+	maxPerPage     = 200
+
+	// minSearchLength keeps one-letter searches from matching most of the table.
+	minSearchLength = 2
+)
+
+// bookingFilter reads and validates the GET /bookings filters from the query string.
+func bookingFilter(c *gin.Context) (store.Filter, error) {
+	q := strings.ToLower(strings.TrimSpace(c.Query("q")))
+	from, to := c.Query("from"), c.Query("to")
+	status := c.Query("status")
+
+	if status != "" && !models.ValidStatus(status) {
+		return store.Filter{}, fmt.Errorf("status must be one of pending, confirmed, cancelled, no_show")
+	}
+
+	if q != "" && utf8.RuneCountInString(q) < minSearchLength {
+		return store.Filter{}, fmt.Errorf("q must be at least %d characters", minSearchLength)
+	}
+
+	if from != "" {
+		if _, err := time.Parse(dateLayout, from); err != nil {
+			return store.Filter{}, fmt.Errorf("from must be a date in YYYY-MM-DD format")
+		}
+	}
+	if to != "" {
+		if _, err := time.Parse(dateLayout, to); err != nil {
+			return store.Filter{}, fmt.Errorf("to must be a date in YYYY-MM-DD format")
+		}
+	}
+	// ISO dates compare correctly as strings.
+	if from != "" && to != "" && from > to {
+		return store.Filter{}, fmt.Errorf("from must not be after to")
+	}
+
+	var roomID uint64
+	if v := c.Query("room_id"); v != "" {
+		var err error
+		if roomID, err = strconv.ParseUint(v, 10, 0); err != nil || roomID == 0 {
+			return store.Filter{}, fmt.Errorf("room_id must be a positive integer")
+		}
+	}
+
+	filter := store.Filter{Query: q, Status: status, RoomID: uint(roomID), SeriesID: c.Query("series_id"), From: from, To: to}
+	if v := c.Query("checked_in"); v != "" {
+		checkedIn, err := strconv.ParseBool(v)
+		if err != nil {
+			return store.Filter{}, fmt.Errorf("checked_in must be true or false")
+		}
+		filter.CheckedIn = &checkedIn
+	}
+	if v := c.Query("flagged"); v != "" {
+		flagged, err := strconv.ParseBool(v)
+		if err != nil {
+			return store.Filter{}, fmt.Errorf("flagged must be true or false")
+		}
+		filter.Flagged = &flagged
+	}
+	switch v := c.Query("payment_status"); v {
+	case "", models.PaymentUnpaid, models.PaymentPartial, models.PaymentPaid:
+		filter.PaymentStatus = v
+	default:
+		return store.Filter{}, fmt.Errorf("payment_status must be unpaid, partial or paid")
+	}
+	// Phones are stored in E.164, so match "+91 98765-43210" by its digits alone.
+	if digits := phoneSeparators.Replace(strings.TrimPrefix(q, "+")); q != "" && isDigits(digits) {
+		filter.PhoneQuery = digits
+	}
+	// A booking reference ("MP-7F3K9Q") also finds its booking, however it was typed.
+	if ref, ok := normalizeReference(q); ok {
+		filter.Reference = ref
+	}
+	return filter, nil
+}
+
+// bookingSort reads ?sort= (one of store.SortFields, default date) and ?order= (asc or
+// desc, default asc) for the bookings list.
+func bookingSort(c *gin.Context) (store.Sort, error) {
+	sort := store.Sort{Field: "date"}
+	if v := c.Query("sort"); v != "" {
+		if !slices.Contains(store.SortFields, v) {
+			return store.Sort{}, fmt.Errorf("sort must be one of %s", strings.Join(store.SortFields, ", "))
+		}
+		sort.Field = v
+	}
+	switch c.Query("order") {
+	case "", "asc":
+	case "desc":
+		sort.Desc = true
+	default:
+		return store.Sort{}, fmt.Errorf("order must be asc or desc")
+	}
+	return sort, nil
+}
+
+// pagination reads ?page= and ?per_page= (both 1-based, defaulting to the first page of 50).
+func pagination(c *gin.Context) (page, perPage int, err error) {
+	page, perPage = 1, defaultPerPage
+	if v := c.Query("page"); v != "" {
+		if page, err = strconv.Atoi(v); err != nil || page < 1 {
+			return 0, 0, fmt.Errorf("page must be a positive integer")
+		}
+	}
+	if v := c.Query("per_page"); v != "" {
+		if perPage, err = strconv.Atoi(v); err != nil || perPage < 1 || perPage > maxPerPage {
+			return 0, 0, fmt.Errorf("per_page must be between 1 and %d", maxPerPage)
+		}
+	}
+	return page, perPage, nil
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+
+	"miniparty-backend/messages"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/settings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// quoteRequest is the body of POST /quote: the parts of a booking that decide its price.
+type quoteRequest struct {
+	Date      string `json:"date"`
+	Time      string `json:"time"`
+	Duration  int    `json:"duration"`
+	Guests    int    `json:"guests"`
+	FullDay   bool   `json:"full_day"`
+	PackageID *uint  `json:"package_id"`
+	RoomID    *uint  `json:"room_id"`
+	AddonIDs  []uint `json:"addon_ids"`
+}
+
+// contactFields are the booking fields a quote leaves out, so their errors don't apply.
+var contactFields = []string{"name", "email", "phone", "notes"}
+
+// GetQuote prices a booking without making it, for the form to show the cost as the
+// customer fills it in. The request goes through the same checks and priceBooking as
+// POST /book, so the quote is what the booking will cost; whether the slot is free is
+// only found out by booking it.
+func GetQuote(c *gin.Context) {
+	var req quoteRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	booking := models.Booking{
+		Date:      req.Date,
+		Time:      req.Time,
+		Duration:  req.Duration,
+		Guests:    req.Guests,
+		FullDay:   req.FullDay,
+		PackageID: req.PackageID,
+		RoomID:    req.RoomID,
+	}
+
+	pkg, _, addons, errs, err := checkNewBooking(conn(c), &booking, req.AddonIDs)
+	if err != nil {
+		serverError(c, err, "Failed to price booking")
+		return
+	}
+	for _, field := range contactFields {
+		delete(errs, field)
+	}
+	if booking.FullDay && !settings.Current().FullDayPublic && !middleware.HasRole(c, middleware.RoleAdmin) {
+		errs.add("full_day", messages.FullDayNotPublic)
+	}
+	if len(errs) > 0 {
+		badFields(c, errs)
+		return
+	}
+
+	price := priceBooking(&booking, pkg)
+	addonsTotal := models.AddonsTotal(addons)
+	if addons == nil {
+		addons = []models.BookingAddon{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"date":               booking.Date,
+		"time":               booking.Time,
+		"duration":           booking.Duration,
+		"full_day":           booking.FullDay,
+		"price":              price,
+		"addons":             addons,
+		"addons_total_cents": addonsTotal,
+		"total_cents":        price.Total + addonsTotal,
+	})
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"miniparty-backend/db"
+	"miniparty-backend/messages"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/settings"
+)
+
+// quoted is the body of a quote.
+type quoted struct {
+	Price            priceBreakdown        `json:"price"`
+	Addons           []models.BookingAddon `json:"addons"`
+	AddonsTotalCents int                   `json:"addons_total_cents"`
+	TotalCents       int                   `json:"total_cents"`
+}
+
+func TestGetQuote(t *testing.T) {
+	testDB(t)
+	setVenue(t, func(v *settings.Venue) {
+		v.HourlyRateCents, v.PerGuestRateCents, v.GuestThreshold, v.WeekendMultiplier = 4000, 300, 4, 1.5
+	})
+	balloons := models.Addon{Name: "Balloons", PriceCents: 1500, Active: true}
+	if err := db.DB.Create(&balloons).Error; err != nil {
+		t.Fatal(err)
+	}
+	r := newRouter()
+	r.POST("/quote", middleware.OptionalAdminAuth(), GetQuote)
+	r.POST("/book", middleware.OptionalAdminAuth(), CreateBooking)
+
+	// Only what decides the price is needed, and nothing is booked.
+	w := call(r, http.MethodPost, "/quote", map[string]any{"date": "2026-07-11", "time": "14:00", "duration": 2, "guests": 6,
+		"addon_ids": []uint{balloons.ID}})
+	expect(t, w, http.StatusOK)
+	quote := decode[quoted](t, w)
+	// Two hours and two extra guests on a Saturday: (8000 + 600) * 1.5, and the balloons.
+	if quote.Price.Total != 12900 || !quote.Price.Weekend || len(quote.Addons) != 1 || quote.AddonsTotalCents != 1500 || quote.TotalCents != 14400 {
+		t.Errorf("quoted %+v, want 12900 and 1500 for the balloons", quote)
+	}
+	if n := countRows(t, &models.Booking{}, "1 = 1"); n != 0 {
+		t.Errorf("%d bookings after a quote, want none", n)
+	}
+
+	w = call(r, http.MethodPost, "/book", bookBody("2026-07-11", "14:00", "guests", 6, "addon_ids", []uint{balloons.ID}))
+	expect(t, w, http.StatusCreated)
+	if booked := decode[struct{ Booking models.Booking }](t, w).Booking; booked.PriceCents != quote.Price.Total {
+		t.Errorf("booked at %d, quoted %d", booked.PriceCents, quote.Price.Total)
+	}
+	// The slot is taken now, but a quote doesn't check.
+	expect(t, call(r, http.MethodPost, "/quote", map[string]any{"date": "2026-07-11", "time": "14:00", "duration": 2, "guests": 6}), http.StatusOK)
+	if got := decode[quoted](t, call(r, http.MethodPost, "/quote", map[string]any{"date": "2026-07-08", "time": "14:00", "duration": 2, "guests": 4})); got.Addons == nil || got.TotalCents != 8000 {
+		t.Errorf("weekday quote %+v, want 8000 and an empty list of add-ons", got)
+	}
+}
+
+func TestGetQuoteRejected(t *testing.T) {
+	testDB(t)
+	setVenue(t, func(v *settings.Venue) { v.FullDayPublic = false })
+	r := newRouter()
+	r.POST("/quote", middleware.OptionalAdminAuth(), GetQuote)
+
+	codes := fieldCodes(t, call(r, http.MethodPost, "/quote", map[string]any{"time": "14:00", "duration": 2, "guests": 0}))
+	if !hasCode(codes, "date", messages.DateRequired) || !hasCode(codes, "guests", messages.GuestsRange) {
+		t.Errorf("codes %v, want the date and guests", codes)
+	}
+	for _, field := range contactFields {
+		if _, ok := codes[field]; ok {
+			t.Errorf("codes %v name %s, which a quote doesn't ask for", codes, field)
+		}
+	}
+
+	fullDay := map[string]any{"date": "2026-07-08", "full_day": true, "guests": 4}
+	if codes := fieldCodes(t, call(r, http.MethodPost, "/quote", fullDay)); !hasCode(codes, "full_day", messages.FullDayNotPublic) {
+		t.Errorf("full day for a customer: %v, want %s", codes, messages.FullDayNotPublic)
+	}
+	expect(t, call(r, http.MethodPost, "/quote", fullDay, asAdmin...), http.StatusOK)
+	expectError(t, call(r, http.MethodPost, "/quote", "{"), http.StatusBadRequest, models.CodeBadRequest)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"miniparty-backend/db"
+	"miniparty-backend/mail"
+	"miniparty-backend/notify"
+)
+
+// reminderLead is how long before a party the customer is reminded (REMINDER_LEAD_HOURS, default 24).
+func reminderLead() time.Duration {
+	return time.Duration(envInt("REMINDER_LEAD_HOURS", 24)) * time.Hour
+}
+
+// SendReminders emails customers whose confirmed booking starts within reminderLead,
+// checking every interval until ctx is done. A check already under way when ctx is
+// cancelled is allowed to finish, so no booking is left claimed but unsent.
+func SendReminders(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !db.Ready() {
+			continue
+		}
+		if err := sendDueReminders(context.WithoutCancel(ctx), Mailer, now()); err != nil {
+			slog.Error("failed to send reminders", "error", err)
+		}
+	}
+}
+
+// sendDueReminders reminds every confirmed booking that starts after at but within
+// reminderLead of it and hasn't been reminded yet. Each booking is claimed by setting
+// reminded_at before the email goes out, so when several instances run only the one that
+// wins the claim sends it; if sending fails the claim is released for the next check to retry.
+func sendDueReminders(ctx context.Context, mailer mail.Mailer, at time.Time) error {
+	due, err := Queries.DueReminders(ctx, at, at.Add(reminderLead()))
+	if err != nil {
+		return err
+	}
+
+	for _, b := range due {
+		claimed, err := Queries.ClaimReminder(ctx, b.ID, at)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := mailer.Send(mail.Reminder(b)); err != nil {
+			slog.Warn("failed to send reminder; will retry", "booking_id", b.ID, "error", err)
+			if err := Queries.ReleaseReminder(ctx, b.ID); err != nil {
+				return err
+			}
+			continue
+		}
+		slog.Info("sent reminder", "booking_id", b.ID, "date", b.Date, "time", b.Time)
+		dispatch(notify.Event{Type: notify.BookingReminder, Booking: b})
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"miniparty-backend/mail"
+	"miniparty-backend/models"
+	"miniparty-backend/notify"
+)
+
+// downMailer fails every send, as an unreachable mail server would.
+type downMailer struct{}
+
+func (downMailer) Send(mail.Message) error { return errors.New("connection refused") }
+
+func TestSendDueReminders(t *testing.T) {
+	testDB(t)
+	events := catchEvents(t)
+	due := addBooking(t, models.Booking{Date: "2026-07-10", Time: "14:00"})
+	// 23 hours before the party, in the venue's timezone.
+	at := due.StartsAt.Add(-23 * time.Hour)
+	addBooking(t, models.Booking{Date: "2026-07-10", Time: "16:00"})                                      // more than a day off
+	addBooking(t, models.Booking{Date: "2026-07-10", Time: "10:00", Status: models.StatusPending})        // not confirmed
+	addBooking(t, models.Booking{Date: "2026-07-09", Time: "14:00"})                                      // already started
+	addBooking(t, models.Booking{Date: "2026-07-10", Time: "12:00", RemindedAt: &time.Time{}, Guests: 5}) // already reminded
+
+	// A failed send releases the claim for the next check.
+	if err := sendDueReminders(context.Background(), downMailer{}, at); err != nil {
+		t.Fatal(err)
+	}
+	if got := reload(t, due.ID); got.RemindedAt != nil {
+		t.Fatalf("reminded_at %v after a failed send, want it released", got.RemindedAt)
+	}
+
+	sent := make(fakeMailer, 10)
+	if err := sendDueReminders(context.Background(), sent, at); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 {
+		t.Fatalf("%d reminders sent, want one", len(sent))
+	}
+	if msg := sent.next(t); msg.To != due.Email {
+		t.Errorf("reminder to %s, want %s", msg.To, due.Email)
+	}
+	if got := reload(t, due.ID); got.RemindedAt == nil || !got.RemindedAt.Equal(at) || !got.UpdatedAt.Equal(due.UpdatedAt) {
+		t.Errorf("reminded_at %v, updated_at %v, want %v and unchanged", got.RemindedAt, got.UpdatedAt, at)
+	}
+	if e := events.next(t); e.Type != notify.BookingReminder || e.Booking.ID != due.ID {
+		t.Errorf("event %s for %d, want a reminder for %d", e.Type, e.Booking.ID, due.ID)
+	}
+
+	// Each booking is reminded once.
+	if err := sendDueReminders(context.Background(), sent, at.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 0 {
+		t.Errorf("%d reminders sent on the next check, want none", len(sent))
+	}
+}
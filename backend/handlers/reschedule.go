@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/notify"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type rescheduleRequest struct {
+	Date     string `json:"date"`
+	Time     string `json:"time"`
+	Duration int    `json:"duration"`
+	DryRun   bool   `json:"dry_run"`
+	// Token is the customer's cancellation token or confirmation code. Admins don't need one.
+	Token string `json:"token"`
+}
+
+var (
+	// errSlotConflict aborts a reschedule transaction when the new slot clashes with other bookings.
+	errSlotConflict = errors.New("slot conflict")
+	// errRescheduleCancelled rejects moving a booking that has been cancelled.
+	errRescheduleCancelled = errors.New("booking is cancelled")
+	// errWrongToken rejects a customer reschedule whose token doesn't match the booking.
+	errWrongToken = errors.New("invalid booking token")
+	// errNotMoved leaves a booking where it is after checking the move: a dry run, a move
+	// to where it already is, or one refused with a response of its own.
+	errNotMoved = errors.New("booking not moved")
+	// errCutoffPassed rejects a customer reschedule after the booking's cancellation deadline.
+	errCutoffPassed = errors.New("cancellation cutoff passed")
+)
+
+// RescheduleBooking moves a booking to a new date/time/duration after checking the new slot
+// with the same rules as a new booking. It stays in its room if that is free, and otherwise
+// moves to the first other room that is. Admins may move any booking; customers pass the
+// token or code they got when booking, until the cancellation cutoff before it starts. With dry_run set, the checks run and conflicts are
+// reported but nothing is saved: in full to admins, and only as their times to customers. Moving a booking to the slot it already has changes nothing.
+func RescheduleBooking(c *gin.Context) {
+	id, ok := bookingID(c)
+	if !ok {
+		return
+	}
+
+	customer := middleware.Role(c) == ""
+	if !customer && !middleware.HasRole(c, middleware.RoleAdmin) {
+		middleware.Fail(c, http.StatusForbidden, models.CodeForbidden, "Your access does not allow this action")
+		return
+	}
+
+	var req rescheduleRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	var booking models.Booking
+	var conflicts []models.Booking
+	var deadline time.Time
+	var validation fieldErrors
+	var blackout *models.Blackout
+	unchanged := false
+	err := inTx(c, func(ctx context.Context, tx *gorm.DB) error {
+		conflicts, validation, blackout, unchanged = nil, nil, nil, false
+		var previous models.Booking
+		var err error
+		booking, err = Bookings.Modify(ctx, id, func(b *models.Booking) error {
+			previous = *b
+			if customer && !bookingTokenMatches(b, req.Token) {
+				return errWrongToken
+			}
+			if b.Status == models.StatusCancelled {
+				return errRescheduleCancelled
+			}
+			if customer {
+				var late bool
+				if deadline, late = pastCutoff(*b); late {
+					return errCutoffPassed
+				}
+			}
+
+			b.Date = req.Date
+			b.Time = req.Time
+			if req.Duration != 0 {
+				b.Duration = req.Duration
+			}
+			validation = validateBooking(b)
+			keepStart(validation, *b, previous)
+			// Checked after validation normalises the date and time, but before its errors count,
+			// so a booking that is already too close to move can still be "moved" to where it is.
+			if b.Date == previous.Date && b.Time == previous.Time && b.Duration == previous.Duration {
+				unchanged, validation = true, nil
+				return errNotMoved
+			}
+			// A blackout wins over the booking's other errors, as it does for new bookings.
+			var err error
+			if blackout, err = findBlackout(tx, b.Date); err != nil {
+				return err
+			}
+			if blackout != nil || len(validation) > 0 {
+				return errNotMoved
+			}
+
+			pkg, err := findPackage(tx, b)
+			if err != nil {
+				return err
+			}
+			priceBooking(b, pkg)
+			rooms, err := rescheduleRooms(tx, b)
+			if err != nil {
+				return err
+			}
+			if conflicts, err = findFreeRoom(tx, b, rooms, b.ID); err != nil {
+				return err
+			}
+			if len(conflicts) > 0 {
+				return errSlotConflict
+			}
+			if req.DryRun {
+				return errNotMoved
+			}
+
+			movedAt := now().UTC()
+			b.RescheduledFrom = previous.Date + " " + previous.Time
+			b.RescheduledAt = &movedAt
+			b.RemindedAt = nil
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return audit(c, tx, models.AuditBookingReschedule, &booking.ID, previous, booking)
+	})
+	if errors.Is(err, errNotMoved) {
+		err = nil
+	}
+
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Booking not found")
+	case errors.Is(err, errWrongToken):
+		middleware.Fail(c, http.StatusForbidden, models.CodeForbidden, "Invalid booking token")
+	case errors.Is(err, errCutoffPassed):
+		cutoffPassed(c, "moved", deadline)
+	case errors.Is(err, errRescheduleCancelled):
+		middleware.Fail(c, http.StatusConflict, models.CodeBookingCancelled, "A cancelled booking cannot be rescheduled")
+	case errors.Is(err, errSlotConflict):
+		// Customers only learn when the other bookings are, not whose they are.
+		details := gin.H{"conflicts": conflictTimes(conflicts)}
+		if !customer {
+			details["conflicts"] = conflicts
+		}
+		middleware.FailWith(c, http.StatusConflict, models.CodeSlotConflict, "The new time clashes with existing bookings", details)
+	case errors.Is(err, store.ErrSlotTaken):
+		middleware.Fail(c, http.StatusConflict, models.CodeSlotConflict, slotTakenMessage)
+	case err != nil:
+		serverError(c, err, "Failed to reschedule booking")
+	case unchanged:
+		c.JSON(http.StatusOK, gin.H{
+			"message": "The booking is already at this time",
+			"booking": booking,
+		})
+	case blackout != nil:
+		middleware.Fail(c, http.StatusUnprocessableEntity, models.CodeDateClosed, blackoutMessage(blackout))
+	case len(validation) > 0:
+		badFields(c, validation)
+	case req.DryRun:
+		c.JSON(http.StatusOK, gin.H{
+			"message":   "The new time is available",
+			"dry_run":   true,
+			"conflicts": []models.Booking{},
+		})
+	default:
+		dispatch(notify.Event{Type: notify.BookingRescheduled, Booking: booking})
+		calendarChanged(booking.ID)
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Booking rescheduled",
+			"booking": booking,
+		})
+	}
+}
+
+// bookingTokenMatches reports whether token is the booking's cancellation token or
+// (ignoring case) its confirmation code, comparing in constant time.
+func bookingTokenMatches(b *models.Booking, token string) bool {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return false
+	}
+	cancelOK := b.CancelToken != "" && subtle.ConstantTimeCompare([]byte(b.CancelToken), []byte(token)) == 1
+	codeOK := b.ConfirmationCode != "" && subtle.ConstantTimeCompare([]byte(b.ConfirmationCode), []byte(strings.ToUpper(token))) == 1
+	return cancelOK || codeOK
+}
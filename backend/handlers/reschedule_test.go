@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"miniparty-backend/db"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/notify"
+)
+
+func TestRescheduleConflictsForCustomers(t *testing.T) {
+	testDB(t)
+	other := addBooking(t, models.Booking{Time: "14:00", Email: "other@example.com"})
+	mine := addBooking(t, models.Booking{Time: "18:00"})
+	r := newRouter()
+	r.POST("/bookings/:id/reschedule", middleware.OptionalAdminAuth(), RescheduleBooking)
+	target := fmt.Sprintf("/bookings/%d/reschedule", mine.ID)
+	move := map[string]any{"date": mine.Date, "time": "15:00", "token": mine.CancelToken}
+
+	body := expectError(t, call(r, http.MethodPost, target, move), http.StatusConflict, models.CodeSlotConflict)
+	if fmt.Sprint(body["conflicts"]) != "[2:00 PM - 4:00 PM]" {
+		t.Errorf("customer sees conflicts %v, want only the other booking's times", body["conflicts"])
+	}
+
+	body = expectError(t, call(r, http.MethodPost, target, move, asAdmin...), http.StatusConflict, models.CodeSlotConflict)
+	conflicts, _ := body["conflicts"].([]any)
+	if len(conflicts) != 1 || conflicts[0].(map[string]any)["email"] != other.Email {
+		t.Errorf("admin sees conflicts %v, want the other booking", body["conflicts"])
+	}
+
+	move["token"] = "wrong"
+	expect(t, call(r, http.MethodPost, target, move), http.StatusForbidden)
+	expect(t, call(r, http.MethodPost, target, move, asViewer...), http.StatusForbidden)
+
+	move["token"] = mine.ConfirmationCode
+	move["time"] = "16:00"
+	w := call(r, http.MethodPost, target, move)
+	expect(t, w, http.StatusOK)
+	if got := reload(t, mine.ID); got.Time != "16:00" || got.RescheduledFrom != "2026-07-10 18:00" {
+		t.Errorf("after reschedule: time %s, from %q", got.Time, got.RescheduledFrom)
+	}
+	var entry models.AuditEntry
+	if err := db.DB.Where("booking_id = ? AND action = ?", mine.ID, models.AuditBookingReschedule).Take(&entry).Error; err != nil {
+		t.Fatalf("no audit entry for the customer's reschedule: %v", err)
+	}
+	if entry.Actor != actorCustomer || entry.Role != actorCustomer || !strings.Contains(string(entry.After), `"time":"16:00"`) {
+		t.Errorf("audit entry by %q (%q), after %s; want the customer and the new time", entry.Actor, entry.Role, entry.After)
+	}
+}
+
+func TestRescheduleNotifiesStaff(t *testing.T) {
+	testDB(t)
+	events := catchEvents(t)
+	b := addBooking(t, models.Booking{Time: "10:00"})
+	r := newRouter()
+	r.POST("/bookings/:id/reschedule", middleware.OptionalAdminAuth(), RescheduleBooking)
+	target := fmt.Sprintf("/bookings/%d/reschedule", b.ID)
+
+	expect(t, call(r, http.MethodPost, target, map[string]any{"date": b.Date, "time": "12:00", "dry_run": true}, asAdmin...), http.StatusOK)
+	expect(t, call(r, http.MethodPost, target, map[string]any{"date": b.Date, "time": "12:00"}, asAdmin...), http.StatusOK)
+	e := events.next(t)
+	if e.Type != notify.BookingRescheduled || e.Booking.Time != "12:00" || e.Booking.RescheduledFrom != "2026-07-10 10:00" {
+		t.Errorf("event %s for %s from %q, want booking.rescheduled to 12:00 from 10:00", e.Type, e.Booking.Time, e.Booking.RescheduledFrom)
+	}
+	select {
+	case e := <-events:
+		t.Errorf("another event %s; the dry run shouldn't send one", e.Type)
+	default:
+	}
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"miniparty-backend/mail"
+	"miniparty-backend/messages"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/notify"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// The channels POST /bookings/:id/resend-confirmation sends the confirmation by.
+const (
+	channelEmail = "email"
+	channelSMS   = "sms"
+	channelAll   = "all"
+)
+
+// maxResendsPerDay is how often a booking's confirmation can be sent again in 24 hours,
+// so the endpoint can't be used to flood a customer's inbox.
+const maxResendsPerDay = 3
+
+// errResendLimit rejects a resend once maxResendsPerDay is used up.
+var errResendLimit = errors.New("confirmation resent too often")
+
+// resendRequest is the body of POST /bookings/:id/resend-confirmation. Channel defaults to
+// all, which is whichever of email and SMS are set up.
+type resendRequest struct {
+	Channel string `json:"channel"`
+}
+
+// ResendConfirmation sends a booking's confirmation again, by email, by text or both, for
+// a customer who says it never arrived. Admins can resend any booking's; the customer
+// needs its ?code= (see findOwnBooking). Cancelled bookings have nothing to confirm. Each
+// resend is counted on the booking and audited, and the audit log is what caps them at
+// maxResendsPerDay.
+func ResendConfirmation(c *gin.Context) {
+	id, ok := bookingID(c)
+	if !ok {
+		return
+	}
+	if middleware.Role(c) != "" && !middleware.HasRole(c, middleware.RoleAdmin) {
+		middleware.Fail(c, http.StatusForbidden, models.CodeForbidden, "Your access does not allow this action")
+		return
+	}
+	var req resendRequest
+	// The body is optional; without one, the confirmation goes out every way it can.
+	if c.Request.ContentLength != 0 && !bindJSON(c, &req) {
+		return
+	}
+	req.Channel = strings.ToLower(strings.TrimSpace(req.Channel))
+	if req.Channel == "" {
+		req.Channel = channelAll
+	}
+	if req.Channel != channelEmail && req.Channel != channelSMS && req.Channel != channelAll {
+		errs := fieldErrors{}
+		errs.add("channel", messages.ChannelInvalid)
+		badFields(c, errs)
+		return
+	}
+
+	var booking models.Booking
+	if !findOwnBooking(c, id, &booking) {
+		return
+	}
+	if booking.Status == models.StatusCancelled {
+		middleware.Fail(c, http.StatusConflict, models.CodeBookingCancelled, "This booking is cancelled, so there's no confirmation to send")
+		return
+	}
+	channels := resendChannels(req.Channel, booking)
+	if len(channels) == 0 {
+		errs := fieldErrors{}
+		errs.add("channel", messages.ChannelUnavailable, "channel", req.Channel)
+		badFields(c, errs)
+		return
+	}
+
+	at := now().UTC()
+	var retryAt time.Time
+	err := inTx(c, func(ctx context.Context, tx *gorm.DB) error {
+		// The booking stays locked until this resend's audit entry is in, so a second resend
+		// waits for it before counting.
+		var err error
+		booking, err = Bookings.Modify(ctx, booking.ID, func(b *models.Booking) error {
+			if b.Status == models.StatusCancelled {
+				return errBookingCancelled
+			}
+			var recent []time.Time
+			err := tx.Model(&models.AuditEntry{}).
+				Where("booking_id = ? AND action = ? AND created_at > ?", b.ID, models.AuditBookingResend, at.Add(-24*time.Hour)).
+				Order("created_at").Pluck("created_at", &recent).Error
+			if err != nil {
+				return err
+			}
+			if len(recent) >= maxResendsPerDay {
+				retryAt = recent[len(recent)-maxResendsPerDay].Add(24 * time.Hour)
+				return errResendLimit
+			}
+			b.ConfirmationResends++
+			b.ConfirmationResentAt = &at
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return audit(c, tx, models.AuditBookingResend, &booking.ID, nil, gin.H{"channels": channels})
+	})
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Booking not found")
+		return
+	case errors.Is(err, errBookingCancelled):
+		middleware.Fail(c, http.StatusConflict, models.CodeBookingCancelled, "This booking is cancelled, so there's no confirmation to send")
+		return
+	case errors.Is(err, errResendLimit):
+		c.Header("Retry-After", strconv.Itoa(int(retryAt.Sub(at).Seconds())+1))
+		middleware.FailWith(c, http.StatusTooManyRequests, models.CodeRateLimited,
+			"This confirmation has been sent again too often today; please try again later", gin.H{"retry_at": retryAt})
+		return
+	case err != nil:
+		serverError(c, err, "Failed to resend confirmation")
+		return
+	}
+
+	for _, ch := range channels {
+		switch ch {
+		case channelEmail:
+			mail.SendAsync(Mailer, mail.Confirmation(booking))
+		case channelSMS:
+			Notifications.Text(notify.Event{Type: notify.BookingConfirmationResent, Booking: booking})
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"channels":               channels,
+		"confirmation_resends":   booking.ConfirmationResends,
+		"confirmation_resent_at": booking.ConfirmationResentAt,
+	})
+}
+
+// resendChannels lists the channels of channel that can reach b's customer: email once a
+// mailer is set up, and SMS once Twilio is, unless b's number has turned out not to take texts.
+func resendChannels(channel string, b models.Booking) []string {
+	_, noMail := Mailer.(mail.Nop)
+	texts := Notifications.CanText() && b.SMSStatus != models.SMSInvalidNumber && b.SMSStatus != models.SMSUnsubscribed
+	channels := []string{}
+	if (channel == channelEmail || channel == channelAll) && !noMail {
+		channels = append(channels, channelEmail)
+	}
+	if (channel == channelSMS || channel == channelAll) && texts {
+		channels = append(channels, channelSMS)
+	}
+	return channels
+}
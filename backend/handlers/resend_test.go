@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"miniparty-backend/messages"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/notify"
+)
+
+// catchTexts has the handlers text through a Twilio whose API is an httptest server, and
+// returns the messages it is asked to send.
+func catchTexts(t *testing.T) <-chan url.Values {
+	t.Helper()
+	texts := make(chan url.Values, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err == nil {
+			texts <- r.PostForm
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(srv.Close)
+	swap(t, &Notifications, notify.NewDispatcher(&notify.Twilio{AccountSID: "AC1", AuthToken: "secret", From: "+14155550000", BaseURL: srv.URL}))
+	return texts
+}
+
+// nextText waits for the next text sent through catchTexts.
+func nextText(t *testing.T, texts <-chan url.Values) url.Values {
+	t.Helper()
+	select {
+	case form := <-texts:
+		return form
+	case <-time.After(5 * time.Second):
+		t.Fatal("no text sent")
+		return nil
+	}
+}
+
+func TestResendConfirmation(t *testing.T) {
+	testDB(t)
+	mails := catchMail(t)
+	texts := catchTexts(t)
+	b := addBooking(t, models.Booking{Email: "ada@example.com", Phone: "+14155550123"})
+	r := newRouter()
+	r.POST("/bookings/:id/resend-confirmation", middleware.OptionalAdminAuth(), ResendConfirmation)
+	target := fmt.Sprintf("/bookings/%d/resend-confirmation", b.ID)
+	resent := func(w *httptest.ResponseRecorder, channels string, count int) {
+		t.Helper()
+		expect(t, w, http.StatusOK)
+		got := decode[struct {
+			Channels            []string   `json:"channels"`
+			ConfirmationResends int        `json:"confirmation_resends"`
+			ConfirmationResent  *time.Time `json:"confirmation_resent_at"`
+		}](t, w)
+		if strings.Join(got.Channels, ",") != channels || got.ConfirmationResends != count || got.ConfirmationResent == nil {
+			t.Errorf("resent %+v, want %s and a count of %d", got, channels, count)
+		}
+		if n := reload(t, b.ID).ConfirmationResends; n != count {
+			t.Errorf("stored count %d, want %d", n, count)
+		}
+	}
+
+	resent(call(r, http.MethodPost, target, map[string]string{"channel": "email"}, asAdmin...), "email", 1)
+	if msg := mails.next(t); msg.To != "ada@example.com" || !strings.Contains(msg.Body, b.Reference) {
+		t.Errorf("mailed %s: %q, want the confirmation to ada@example.com", msg.To, msg.Subject)
+	}
+
+	// The customer resends with their code.
+	resent(call(r, http.MethodPost, target+"?code="+b.ConfirmationCode, map[string]string{"channel": "SMS"}), "sms", 2)
+	if form := nextText(t, texts); form.Get("To") != "+14155550123" || !strings.Contains(form.Get("Body"), b.Reference) {
+		t.Errorf("texted %v, want the booking to +14155550123", form)
+	}
+	mails.none(t)
+
+	// Without a body it goes every way it can.
+	resent(call(r, http.MethodPost, target, nil, asAdmin...), "email,sms", 3)
+	mails.next(t)
+	nextText(t, texts)
+	if got := auditActions(t, b.ID); len(got) != 3 || got[0] != models.AuditBookingResend {
+		t.Errorf("audit %v, want the three resends", got)
+	}
+
+	// A fourth within the day is refused and isn't counted or sent.
+	w := call(r, http.MethodPost, target, nil, asAdmin...)
+	expectError(t, w, http.StatusTooManyRequests, models.CodeRateLimited)
+	if w.Header().Get("Retry-After") == "" || reload(t, b.ID).ConfirmationResends != 3 {
+		t.Errorf("over the limit: Retry-After %q, count %d; want a wait and the count kept", w.Header().Get("Retry-After"), reload(t, b.ID).ConfirmationResends)
+	}
+	mails.none(t)
+}
+
+func TestResendConfirmationRefused(t *testing.T) {
+	testDB(t)
+	mails := catchMail(t)
+	catchTexts(t)
+	b := addBooking(t, models.Booking{SMSStatus: models.SMSUnsubscribed})
+	cancelled := addBooking(t, models.Booking{Time: "18:00", Status: models.StatusCancelled})
+	r := newRouter()
+	r.POST("/bookings/:id/resend-confirmation", middleware.OptionalAdminAuth(), ResendConfirmation)
+	target := fmt.Sprintf("/bookings/%d/resend-confirmation", b.ID)
+
+	expectError(t, call(r, http.MethodPost, target, nil, asViewer...), http.StatusForbidden, models.CodeForbidden)
+	expectError(t, call(r, http.MethodPost, target+"?code=WRONG", nil), http.StatusNotFound, models.CodeNotFound)
+	expectError(t, call(r, http.MethodPost, fmt.Sprintf("/bookings/%d/resend-confirmation", cancelled.ID), nil, asAdmin...),
+		http.StatusConflict, models.CodeBookingCancelled)
+	if codes := fieldCodes(t, call(r, http.MethodPost, target, map[string]string{"channel": "pigeon"}, asAdmin...)); !hasCode(codes, "channel", messages.ChannelInvalid) {
+		t.Errorf("unknown channel: %v", codes)
+	}
+	// The customer unsubscribed from texts.
+	if codes := fieldCodes(t, call(r, http.MethodPost, target, map[string]string{"channel": "sms"}, asAdmin...)); !hasCode(codes, "channel", messages.ChannelUnavailable) {
+		t.Errorf("texting an unsubscribed number: %v", codes)
+	}
+	if n := reload(t, b.ID).ConfirmationResends; n != 0 {
+		t.Errorf("refused resends counted %d", n)
+	}
+	mails.none(t)
+}
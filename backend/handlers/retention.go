@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"miniparty-backend/config"
+	"miniparty-backend/db"
+	"miniparty-backend/metrics"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Retention says how long bookings keep their customers' details. main sets it from the
+// config: RETENTION_MONTHS (default 24, 0 keeps everything), RETENTION_STRATEGY,
+// RETENTION_BATCH_SIZE and RETENTION_MAX_PER_RUN.
+var Retention = config.DefaultRetention
+
+// retentionInterval is how long after one retention run the next is due.
+const retentionInterval = 24 * time.Hour
+
+// maxRetentionPreview caps the booking IDs GET /admin/retention lists.
+const maxRetentionPreview = 100
+
+// RetentionRun is what a run of the retention job did, or in a dry run what it would do.
+// Bookings is how many it handled, or for a dry run how many are due in all, of which the
+// next run takes at most MaxPerRun; More says some are left for the run after. Held
+// counts the old bookings kept back by a retention hold.
+type RetentionRun struct {
+	At         time.Time `json:"at"`
+	Strategy   string    `json:"strategy"`
+	Before     string    `json:"before"`
+	DryRun     bool      `json:"dry_run"`
+	Bookings   int64     `json:"bookings"`
+	Held       int64     `json:"held"`
+	More       bool      `json:"more"`
+	BookingIDs []int64   `json:"booking_ids,omitempty"`
+}
+
+// lastRetention is the latest run this process made, for GET /admin/retention and
+// /health/ready.
+var lastRetention struct {
+	sync.Mutex
+	run *RetentionRun
+}
+
+// LastRetention returns this process's latest retention run, nil before the first.
+func LastRetention() *RetentionRun {
+	lastRetention.Lock()
+	defer lastRetention.Unlock()
+	return lastRetention.run
+}
+
+// RunRetention runs the retention job once a day, checking every interval until ctx is
+// done. The first run is an interval after startup.
+func RunRetention(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if last := LastRetention(); !db.Ready() || last != nil && now().Sub(last.At) < retentionInterval {
+			continue
+		}
+		run, err := applyRetention(context.WithoutCancel(ctx), now(), false)
+		if err != nil {
+			slog.Error("retention run failed", "handled", run.Bookings, "error", err)
+		}
+		lastRetention.Lock()
+		lastRetention.run = &run
+		lastRetention.Unlock()
+	}
+}
+
+// retentionCutoff is the first booking date Retention keeps as of at, in venue time.
+func retentionCutoff(at time.Time) string {
+	return at.In(venueLocation()).AddDate(0, -Retention.Months, 0).Format(dateLayout)
+}
+
+// retentionDue selects the bookings dated before before that the retention job hasn't
+// dealt with yet; held picks the ones on hold instead.
+func retentionDue(before string, held bool) store.Retained {
+	return store.Retained{Before: before, Held: held, Anonymized: Retention.Strategy == config.RetentionAnonymize}
+}
+
+// applyRetention anonymizes or archives the bookings past Retention as of at, BatchSize to
+// a transaction and at most MaxPerRun in all. A dry run only counts them and lists the
+// next run's first maxRetentionPreview. On an error, run says how many were already done.
+func applyRetention(ctx context.Context, at time.Time, dryRun bool) (run RetentionRun, err error) {
+	run = RetentionRun{At: at.UTC(), Strategy: Retention.Strategy, Before: retentionCutoff(at), DryRun: dryRun}
+	if run.Held, err = Queries.CountRetained(ctx, retentionDue(run.Before, true)); err != nil {
+		return run, err
+	}
+	if dryRun {
+		if run.Bookings, err = Queries.CountRetained(ctx, retentionDue(run.Before, false)); err != nil {
+			return run, err
+		}
+		run.More = run.Bookings > int64(Retention.MaxPerRun)
+		limit := min(Retention.MaxPerRun, maxRetentionPreview)
+		run.BookingIDs, err = Queries.RetainedIDs(ctx, retentionDue(run.Before, false), limit)
+		return run, err
+	}
+
+	defer func() {
+		if run.Bookings > 0 {
+			metrics.RetentionBookings.WithLabelValues(run.Strategy).Add(float64(run.Bookings))
+			// The remembered booking lists may show the customers.
+			lastLists.clear()
+		}
+	}()
+	for run.Bookings < int64(Retention.MaxPerRun) {
+		batch := min(Retention.BatchSize, Retention.MaxPerRun-int(run.Bookings))
+		ids, err := Queries.RetainedIDs(ctx, retentionDue(run.Before, false), batch)
+		if err != nil {
+			return run, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		err = db.RetryTx(ctx, db.DB.WithContext(ctx), func(tx *gorm.DB) error { return retire(store.WithTx(ctx, tx), tx, ids, at) })
+		if err != nil {
+			return run, err
+		}
+		run.Bookings += int64(len(ids))
+		if len(ids) < batch {
+			break
+		}
+	}
+	if run.Bookings == int64(Retention.MaxPerRun) {
+		left, err := Queries.CountRetained(ctx, retentionDue(run.Before, false))
+		if err != nil {
+			return run, err
+		}
+		run.More = left > 0
+	}
+	slog.Info("retention run", "strategy", run.Strategy, "before", run.Before, "bookings", run.Bookings,
+		"held", run.Held, "more", run.More)
+	return run, nil
+}
+
+// retire anonymizes or archives the bookings with ids in tx, whose ctx is from store.WithTx.
+// Anonymizing redacts them as erasing a customer does. Archiving moves them to
+// bookings_archive; their add-ons and payments stay where they are, under the same IDs.
+// Either way their audit entries are redacted, since the log outlives the bookings.
+func retire(ctx context.Context, tx *gorm.DB, ids []int64, at time.Time) error {
+	var entries []models.AuditEntry
+	if err := tx.Where("booking_id IN ?", ids).Find(&entries).Error; err != nil {
+		return err
+	}
+	if Retention.Strategy == config.RetentionAnonymize {
+		if err := eraseBookings(ctx, eraseAnonymize, ids); err != nil {
+			return err
+		}
+		return redactAudit(tx, entries)
+	}
+
+	if err := Queries.ArchiveBookings(ctx, ids, at); err != nil {
+		return err
+	}
+	return redactAudit(tx, entries)
+}
+
+// GetRetention reports the retention policy, what its next run would do, and what this
+// process's last run did.
+func GetRetention(c *gin.Context) {
+	policy := gin.H{
+		"months":      Retention.Months,
+		"strategy":    Retention.Strategy,
+		"batch_size":  Retention.BatchSize,
+		"max_per_run": Retention.MaxPerRun,
+	}
+	if Retention.Months == 0 {
+		c.JSON(http.StatusOK, gin.H{"enabled": false, "policy": policy})
+		return
+	}
+	due, err := applyRetention(c.Request.Context(), now(), true)
+	if err != nil {
+		serverError(c, err, "Failed to check retention")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "policy": policy, "due": due, "last_run": LastRetention()})
+}
+
+// HoldRetention keeps a booking from the retention job, for as long as staff need its
+// customer's details. Holding a held booking again is a no-op.
+func HoldRetention(c *gin.Context) {
+	setRetentionHold(c, true, models.AuditRetentionHold)
+}
+
+// ReleaseRetention lets the retention job deal with a booking again.
+func ReleaseRetention(c *gin.Context) {
+	setRetentionHold(c, false, models.AuditRetentionRelease)
+}
+
+func setRetentionHold(c *gin.Context, hold bool, action string) {
+	id, ok := bookingID(c)
+	if !ok {
+		return
+	}
+	var booking models.Booking
+	err := inTx(c, func(ctx context.Context, tx *gorm.DB) error {
+		var before models.Booking
+		var err error
+		booking, err = Queries.ModifyAny(ctx, id, func(b *models.Booking) error {
+			before = *b
+			b.RetentionHold = hold
+			return nil
+		})
+		if err != nil || before.RetentionHold == hold {
+			return err
+		}
+		return audit(c, tx, action, &booking.ID, before, booking)
+	})
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Booking not found")
+	case err != nil:
+		serverError(c, err, "Failed to update booking")
+	default:
+		c.JSON(http.StatusOK, booking)
+	}
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"miniparty-backend/config"
+	"miniparty-backend/db"
+	"miniparty-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// oldBookings adds a booking on each date, each with an audit entry holding its customer's
+// details, and returns their IDs.
+func oldBookings(t *testing.T, dates ...string) []int64 {
+	t.Helper()
+	var ids []int64
+	for i, date := range dates {
+		b := addBooking(t, models.Booking{Date: date, Time: fmt.Sprintf("%02d:00", 10+i), Notes: "Allergic to nuts"})
+		entry := models.AuditEntry{Actor: "session", Role: "admin", Action: models.AuditBookingUpdate, BookingID: &b.ID,
+			Before: models.Snapshot(fmt.Sprintf(`{"id":%d,"name":%q,"email":%q,"guests":4}`, b.ID, b.Name, b.Email))}
+		if err := db.DB.Create(&entry).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, b.ID)
+	}
+	return ids
+}
+
+// auditMentions reports whether any audit entry of booking id still holds s.
+func auditMentions(t *testing.T, id int64, s string) bool {
+	t.Helper()
+	var entries []models.AuditEntry
+	if err := db.DB.Where("booking_id = ?", id).Find(&entries).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if strings.Contains(string(e.Before)+string(e.After), s) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRetentionCutoffAndHolds(t *testing.T) {
+	for _, strategy := range []string{config.RetentionAnonymize, config.RetentionArchive} {
+		t.Run(strategy, func(t *testing.T) {
+			testDB(t)
+			swap(t, &Retention, config.Retention{Months: 24, Strategy: strategy, BatchSize: 100, MaxPerRun: 5000})
+			// At testNow, 2026-07-01, the cutoff is 2024-07-01: that day is kept, the one before isn't.
+			ids := oldBookings(t, "2024-06-30", "2024-07-01", "2024-01-15")
+			due, kept, held := ids[0], ids[1], ids[2]
+			if err := db.DB.Model(&models.Booking{}).Where("id = ?", held).Update("retention_hold", true).Error; err != nil {
+				t.Fatal(err)
+			}
+			emails := map[int64]string{}
+			for _, id := range ids {
+				emails[id] = reload(t, id).Email
+			}
+
+			run, err := applyRetention(context.Background(), testNow, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if run.Before != "2024-07-01" || run.Bookings != 1 || run.Held != 1 || run.More {
+				t.Errorf("run = %+v, want one booking before 2024-07-01 and one held", run)
+			}
+
+			var archived int64
+			if err := db.DB.Table("bookings_archive").Where("id = ?", due).Count(&archived).Error; err != nil {
+				t.Fatal(err)
+			}
+			var left models.Booking
+			found := db.DB.Unscoped().Take(&left, due).Error == nil
+			switch {
+			case strategy == config.RetentionArchive && (found || archived != 1):
+				t.Errorf("archive: booking still in bookings = %v, in the archive = %d, want moved", found, archived)
+			case strategy == config.RetentionAnonymize && (!found || left.Email != models.Redacted || archived != 0):
+				t.Errorf("anonymize: booking %+v, archived %d, want it kept with its email redacted", left, archived)
+			}
+			if auditMentions(t, due, emails[due]) {
+				t.Errorf("the audit log still has the retired booking's email")
+			}
+
+			for _, id := range []int64{kept, held} {
+				if got := reload(t, id); got.Email != emails[id] || got.Notes == models.Redacted {
+					t.Errorf("booking %d was retired: %+v", id, got)
+				}
+				if !auditMentions(t, id, emails[id]) {
+					t.Errorf("booking %d's audit entries were redacted", id)
+				}
+			}
+		})
+	}
+}
+
+func TestRetentionBatches(t *testing.T) {
+	testDB(t)
+	swap(t, &Retention, config.Retention{Months: 24, Strategy: config.RetentionArchive, BatchSize: 2, MaxPerRun: 3})
+	ids := oldBookings(t, "2024-01-01", "2024-01-02", "2024-01-03", "2024-01-04")
+
+	preview, err := applyRetention(context.Background(), testNow, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Bookings != 4 || !preview.More || fmt.Sprint(preview.BookingIDs) != fmt.Sprint(ids[:3]) {
+		t.Errorf("dry run = %+v, want 4 due, the first three next", preview)
+	}
+	var count int64
+	if err := db.DB.Table("bookings_archive").Count(&count).Error; err != nil || count != 0 {
+		t.Fatalf("dry run archived %d bookings (%v)", count, err)
+	}
+
+	// Three of the four, two to a transaction and then one.
+	var batches []int
+	err = db.DB.Callback().Delete().After("gorm:delete").Register("test:batches", func(tx *gorm.DB) {
+		if tx.Statement.Table == "bookings" {
+			batches = append(batches, int(tx.RowsAffected))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.DB.Callback().Delete().Remove("test:batches") })
+
+	run, err := applyRetention(context.Background(), testNow, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Bookings != 3 || !run.More {
+		t.Errorf("first run = %+v, want 3 handled and more left", run)
+	}
+	if fmt.Sprint(batches) != "[2 1]" {
+		t.Errorf("batches = %v, want [2 1]", batches)
+	}
+	run, err = applyRetention(context.Background(), testNow, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Bookings != 1 || run.More {
+		t.Errorf("second run = %+v, want the last one and none left", run)
+	}
+	if err := db.DB.Table("bookings_archive").Count(&count).Error; err != nil || count != 4 {
+		t.Errorf("archived %d bookings (%v), want 4", count, err)
+	}
+}
@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"miniparty-backend/messages"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/settings"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type roomRequest struct {
+	Name     string `json:"name"`
+	Capacity int    `json:"capacity"`
+	Active   *bool  `json:"active"`
+}
+
+// apply validates req and copies it onto r. Active defaults to true for new rooms and is
+// left alone on updates that don't mention it.
+func (req roomRequest) apply(r *models.Room) fieldErrors {
+	errs := fieldErrors{}
+	r.Name = strings.TrimSpace(req.Name)
+	r.Capacity = req.Capacity
+	if req.Active != nil {
+		r.Active = *req.Active
+	}
+
+	if r.Name == "" {
+		errs.add("name", messages.NameRequired)
+	}
+	if maxGuests := settings.Current().MaxGuests; r.Capacity < 1 || r.Capacity > maxGuests {
+		errs.add("capacity", messages.CapacityRange, "max", maxGuests)
+	}
+	return errs
+}
+
+// GetRooms lists the active rooms for the booking form.
+func GetRooms(c *gin.Context) {
+	rooms, err := activeRooms(conn(c))
+	if err != nil {
+		serverError(c, err, "Failed to fetch rooms")
+		return
+	}
+
+	c.JSON(http.StatusOK, rooms)
+}
+
+// GetAllRooms lists every room, active or not, for the admin.
+func GetAllRooms(c *gin.Context) {
+	rooms := []models.Room{}
+	if err := conn(c).Order("id ASC").Find(&rooms).Error; err != nil {
+		serverError(c, err, "Failed to fetch rooms")
+		return
+	}
+
+	c.JSON(http.StatusOK, rooms)
+}
+
+func CreateRoom(c *gin.Context) {
+	var req roomRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	room := models.Room{Active: true}
+	if errs := req.apply(&room); len(errs) > 0 {
+		badFields(c, errs)
+		return
+	}
+
+	if err := conn(c).Create(&room).Error; err != nil {
+		roomSaveError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, room)
+}
+
+// UpdateRoom replaces a room's details. Deactivating a room stops new bookings for it;
+// its existing bookings are kept.
+func UpdateRoom(c *gin.Context) {
+	id, ok := roomID(c)
+	if !ok {
+		return
+	}
+
+	var req roomRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	var room models.Room
+	if err := conn(c).First(&room, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Room not found")
+			return
+		}
+		serverError(c, err, "Failed to fetch room")
+		return
+	}
+
+	if errs := req.apply(&room); len(errs) > 0 {
+		badFields(c, errs)
+		return
+	}
+
+	// As in UpdateAddon, a room deleted meanwhile isn't recreated.
+	result := conn(c).Select("*").Save(&room)
+	if result.Error != nil {
+		roomSaveError(c, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Room not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, room)
+}
+
+// DeleteRoom removes a room that has never been booked. Rooms with bookings can only be deactivated.
+func DeleteRoom(c *gin.Context) {
+	id, ok := roomID(c)
+	if !ok {
+		return
+	}
+
+	used, err := Bookings.Count(c.Request.Context(), store.ListOptions{Filter: store.Filter{RoomID: id}, IncludeDeleted: true})
+	if err != nil {
+		serverError(c, err, "Failed to delete room")
+		return
+	}
+	if used > 0 {
+		middleware.Fail(c, http.StatusConflict, models.CodeInUse, fmt.Sprintf("This room has %d booking(s). Deactivate it instead.", used))
+		return
+	}
+
+	result := conn(c).Delete(&models.Room{}, id)
+	if result.Error != nil {
+		serverError(c, result.Error, "Failed to delete room")
+		return
+	}
+	if result.RowsAffected == 0 {
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Room not found")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// roomID parses the :id path parameter, writing a 400 and returning false if it isn't a positive integer.
+func roomID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil || id == 0 {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "Invalid room ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func roomSaveError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		middleware.Fail(c, http.StatusConflict, models.CodeNameTaken, "A room with this name already exists")
+		return
+	}
+	serverError(c, err, "Failed to save room")
+}
+
+// activeRooms returns the rooms taking bookings, in the order auto-assignment tries them.
+func activeRooms(tx *gorm.DB) ([]models.Room, error) {
+	rooms := []models.Room{}
+	err := tx.Where("active = ?", true).Order("id ASC").Find(&rooms).Error
+	return rooms, err
+}
+
+// candidateRooms returns the rooms a new booking may go in: the one it asked for, or else
+// every active room big enough for the party, in the order they should be tried.
+func candidateRooms(tx *gorm.DB, b *models.Booking) ([]models.Room, fieldErrors, error) {
+	errs := fieldErrors{}
+	if b.RoomID != nil {
+		var room models.Room
+		err := tx.First(&room, *b.RoomID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !room.Active) {
+			errs.add("room_id", messages.RoomUnavailable)
+			return nil, errs, nil
+		}
+		if err != nil {
+			return nil, errs, err
+		}
+		if b.Guests > room.Capacity && !b.FullDay {
+			errs.add("guests", messages.GuestsRoomCapacity, "room", room.Name, "max", room.Capacity)
+		}
+		return []models.Room{room}, errs, nil
+	}
+
+	rooms, err := activeRooms(tx)
+	if err != nil {
+		return nil, errs, err
+	}
+	// A full day has the run of the venue, so any room carries it whatever its capacity.
+	if b.FullDay && len(rooms) > 0 {
+		return rooms[:1], errs, nil
+	}
+	fitting := rooms[:0]
+	for _, room := range rooms {
+		if room.Capacity >= b.Guests {
+			fitting = append(fitting, room)
+		}
+	}
+	if len(fitting) == 0 {
+		errs.add("guests", messages.GuestsNoRoom, "guests", b.Guests)
+	}
+	return fitting, errs, nil
+}
+
+// rescheduleRooms lists the rooms a booking can move into: its own room first, even if that
+// has been deactivated since, then every other active room big enough for the party.
+func rescheduleRooms(tx *gorm.DB, b *models.Booking) ([]models.Room, error) {
+	var rooms []models.Room
+	if b.RoomID != nil {
+		var current models.Room
+		err := tx.First(&current, *b.RoomID).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if err == nil {
+			rooms = append(rooms, current)
+		}
+	}
+
+	active, err := activeRooms(tx)
+	if err != nil {
+		return nil, err
+	}
+	for _, room := range active {
+		if room.Capacity >= b.Guests && (b.RoomID == nil || room.ID != *b.RoomID) {
+			rooms = append(rooms, room)
+		}
+	}
+	return rooms, nil
+}
+
+// checkRoomCapacity reports whether an existing booking's party still fits its room.
+func checkRoomCapacity(tx *gorm.DB, b *models.Booking) (fieldErrors, error) {
+	errs := fieldErrors{}
+	if b.RoomID == nil {
+		return errs, nil
+	}
+	var room models.Room
+	if err := tx.First(&room, *b.RoomID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs, nil
+		}
+		return errs, err
+	}
+	if b.Guests > room.Capacity {
+		errs.add("guests", messages.GuestsRoomCapacity, "room", room.Name, "max", room.Capacity)
+	}
+	return errs, nil
+}
+
+// createInRoom saves b in the first of rooms where its slot is free. Each attempt re-checks
+// for overlaps inside the store's transaction, so a room taken by a concurrent request is
+// skipped rather than double-booked. The last room's error is returned if none is free.
+func createInRoom(ctx context.Context, b *models.Booking, rooms []models.Room) error {
+	var err error
+	for i := range rooms {
+		id := rooms[i].ID
+		b.RoomID = &id
+		b.RoomName = rooms[i].Name
+		b.SlotKey = models.SlotKey(b.Date, b.Time, b.RoomID)
+		if err = Bookings.Create(ctx, b); !slotUnavailable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// slotUnavailable reports whether err means the booking's slot is already taken.
+func slotUnavailable(err error) bool {
+	var conflict *store.ConflictError
+	return errors.As(err, &conflict) || errors.Is(err, store.ErrSlotTaken)
+}
+
+// unavailableMessage explains an error slotUnavailable accepts: the bookings in the way, or
+// that the slot has been taken.
+func unavailableMessage(err error) string {
+	var conflict *store.ConflictError
+	if errors.As(err, &conflict) {
+		return conflictMessage(conflict.Conflicts)
+	}
+	return slotTakenMessage
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"miniparty-backend/models"
+)
+
+func TestMissingRoomWrites(t *testing.T) {
+	testDB(t)
+	r := newRouter()
+	r.PUT("/rooms/:id", UpdateRoom)
+	r.DELETE("/rooms/:id", DeleteRoom)
+	r.POST("/rooms", CreateRoom)
+	body := `{"name": "Garden", "capacity": 30}`
+
+	expectError(t, call(r, http.MethodPut, "/rooms/999", body), http.StatusNotFound, models.CodeNotFound)
+	expectError(t, call(r, http.MethodDelete, "/rooms/999", nil), http.StatusNotFound, models.CodeNotFound)
+	expectError(t, call(r, http.MethodPut, "/rooms/abc", body), http.StatusBadRequest, models.CodeBadRequest)
+
+	// Deleted between being read and being saved, it is still a 404.
+	w := call(r, http.MethodPost, "/rooms", body)
+	expect(t, w, http.StatusCreated)
+	created := decode[models.Room](t, w)
+	vanishOnUpdate(t, "rooms", created.ID)
+	expectError(t, call(r, http.MethodPut, fmt.Sprintf("/rooms/%d", created.ID), body), http.StatusNotFound, models.CodeNotFound)
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"miniparty-backend/messages"
+	"miniparty-backend/models"
+	"miniparty-backend/settings"
+)
+
+const (
+	dateLayout = "2006-01-02"
+	timeLayout = "15:04"
+	// deadlineLayout is how a cancellation deadline reads to a customer, in venue time.
+	deadlineLayout = "Mon 2 Jan 2006 at 15:04"
+)
+
+// now is the clock used by the date rules. Overridable so the rules can be exercised with a fixed time.
+var now = time.Now
+
+// venueLocation is the timezone booking dates and times are interpreted in (VENUE_TZ).
+func venueLocation() *time.Location {
+	return models.VenueLocation()
+}
+
+// parseStart combines a booking's date ("YYYY-MM-DD") and time ("HH:MM") into a venue-local start time.
+func parseStart(date, clock string) (time.Time, error) {
+	return time.ParseInLocation(dateLayout+" "+timeLayout, date+" "+clock, venueLocation())
+}
+
+// parseClock reads a start time as customers type it: "15:00", "15.00", "3:00 PM", "3pm"
+// or "3.30 p.m.". The result has only its hour and minute set.
+func parseClock(s string) (time.Time, error) {
+	s = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(s), ".", ":"))
+	meridiem := ""
+	for _, m := range []string{"AM", "PM", "A:M:", "P:M:"} {
+		if rest, ok := strings.CutSuffix(s, m); ok {
+			meridiem, s = strings.ReplaceAll(m, ":", ""), strings.TrimSpace(rest)
+			break
+		}
+	}
+	if meridiem == "" {
+		return time.Parse(timeLayout, s)
+	}
+	if !strings.Contains(s, ":") {
+		s += ":00"
+	}
+	return time.Parse("3:04PM", s+meridiem)
+}
+
+// checkBookingWindow applies v's date rules to a parsed start time.
+// Returns the message if the booking falls outside the allowed window, or one with no Code otherwise.
+func checkBookingWindow(v settings.Venue, start, current time.Time) messages.Message {
+	// Compare the full start time so "today, two hours ago" is rejected but "today, in three hours" isn't.
+	if !start.After(current) {
+		return messages.New(messages.DatePast)
+	}
+
+	leadHours := v.MinLeadHours
+	if start.Before(current.Add(time.Duration(leadHours) * time.Hour)) {
+		return messages.New(messages.DateTooSoon, "hours", leadHours)
+	}
+
+	// The cutoff day itself is bookable, so compare calendar days in the venue's timezone.
+	maxDays := v.MaxAdvanceDays
+	local := current.In(start.Location())
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, start.Location())
+	startDay := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	if cutoff := today.AddDate(0, 0, maxDays); startDay.After(cutoff) {
+		return messages.New(messages.DateTooFar, "days", maxDays, "until", cutoff.Format(dateLayout))
+	}
+
+	return messages.Message{}
+}
+
+// checkSlotAlignment rejects start times that aren't on v's slot grid, suggesting the valid
+// ones either side: nearest is the closer of them, times both.
+func checkSlotAlignment(v settings.Venue, start time.Time) messages.Message {
+	slot := v.SlotMinutes
+	minutes := start.Hour()*60 + start.Minute()
+	if start.Second() == 0 && minutes%slot == 0 {
+		return messages.Message{}
+	}
+
+	lower := minutes / slot * slot
+	nearest, times := lower, formatMinutes(lower)
+	if upper := lower + slot; upper < 24*60 {
+		times += " or " + formatMinutes(upper)
+		if upper-minutes < minutes-lower {
+			nearest = upper
+		}
+	}
+	return messages.New(messages.TimeOffSlot, "slot", slot, "nearest", formatMinutes(nearest), "times", times)
+}
+
+// keepStart drops the slot error from errs when b still starts when before did, so a
+// booking made on an older slot grid can be edited without moving it.
+func keepStart(errs fieldErrors, b, before models.Booking) {
+	if b.Date != before.Date || b.Time != before.Time {
+		return
+	}
+	kept := errs["time"][:0]
+	for _, msg := range errs["time"] {
+		if msg.Code != messages.TimeOffSlot {
+			kept = append(kept, msg)
+		}
+	}
+	if len(kept) == 0 {
+		delete(errs, "time")
+	} else {
+		errs["time"] = kept
+	}
+}
+
+// formatMinutes renders minutes since midnight as "HH:MM".
+func formatMinutes(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// checkOpeningHours rejects bookings that start before opening or run past closing or
+// midnight, by the hours of start's weekday. A booking ending exactly at closing is fine.
+// Overruns name the latest start on the slot grid that fits the duration. A day the venue
+// is closed is rejected as a whole.
+func checkOpeningHours(v settings.Venue, start time.Time, durationHours int) messages.Message {
+	openAt, closeAt, open := v.HoursOn(start.Weekday())
+	if !open {
+		return messages.New(messages.DateClosedDay, "day", start.Weekday().String())
+	}
+	begin := start.Hour()*60 + start.Minute()
+	end := begin + durationHours*60
+	if begin < openAt {
+		return messages.New(messages.TimeOutsideHours, "open", formatMinutes(openAt), "close", formatMinutes(closeAt))
+	}
+	if end <= closeAt {
+		return messages.Message{}
+	}
+
+	latest := latestStart(v, closeAt, durationHours)
+	if latest < openAt {
+		return messages.New(messages.TimeTooLongForDay, "hours", durationHours, "open", formatMinutes(openAt), "close", formatMinutes(closeAt))
+	}
+	if end > 24*60 {
+		return messages.New(messages.TimePastMidnight, "hours", durationHours, "latest", formatMinutes(latest))
+	}
+	return messages.New(messages.TimePastClosing, "hours", durationHours, "latest", formatMinutes(latest), "close", formatMinutes(closeAt))
+}
+
+// fullDayHours sets a full-day booking's time and duration to the opening hours on weekday
+// day, with the duration rounded up to the hour. On a day the venue is closed it takes the
+// usual hours, so the booking is refused for its date rather than its time.
+func fullDayHours(v settings.Venue, b *models.Booking, day time.Weekday) {
+	openAt, closeAt, open := v.HoursOn(day)
+	if !open {
+		openAt, closeAt = v.OpenMinutes(), v.CloseMinutes()
+	}
+	b.Time = formatMinutes(openAt)
+	b.Duration = (closeAt - openAt + 59) / 60
+}
+
+// latestStart is the last start time on the slot grid, in minutes since midnight, from
+// which a booking of durationHours finishes by closeAt. It is below opening time when the
+// duration doesn't fit at all.
+func latestStart(v settings.Venue, closeAt, durationHours int) int {
+	slot := v.SlotMinutes
+	latest := closeAt - durationHours*60
+	if latest < 0 {
+		return latest
+	}
+	return latest / slot * slot
+}
+
+// envInt reads a positive integer from the environment, falling back to def when unset or invalid.
+func envInt(key string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-pdf/fpdf"
+)
+
+// scheduleColumns are the table headings and widths (mm) of the printable day schedule,
+// which fill the 190mm between an A4 page's margins. Notes is last; see noteLines.
+var scheduleColumns = []struct {
+	title string
+	width float64
+}{
+	{"Time", 30},
+	{"Name", 45},
+	{"Guests", 17},
+	{"Phone", 38},
+	{"Notes", 60},
+}
+
+// A row of the schedule table is scheduleLineHeight mm high, growing by noteLineHeight for
+// each line of notes past the first, of which it shows at most maxNoteLines.
+const (
+	scheduleLineHeight = 8
+	noteLineHeight     = 5
+	maxNoteLines       = 3
+)
+
+// GetSchedulePDF renders the bookings for ?date=YYYY-MM-DD as a printable PDF, ordered by start time.
+func GetSchedulePDF(c *gin.Context) {
+	date := c.Query("date")
+	day, err := time.Parse(dateLayout, date)
+	if err != nil {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "date must be in YYYY-MM-DD format")
+		return
+	}
+
+	bookings, err := bookingsOn(c.Request.Context(), date)
+	if err != nil {
+		serverError(c, err, "Failed to fetch bookings")
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := renderSchedule(&buf, day, bookings); err != nil {
+		serverError(c, err, "Failed to render schedule")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="schedule-%s.pdf"`, date))
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+func renderSchedule(buf *bytes.Buffer, day time.Time, bookings []models.Booking) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 10, "Schedule for "+day.Format("Monday, 2 January 2006"), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	if len(bookings) == 0 {
+		pdf.SetFont("Helvetica", "", 12)
+		pdf.CellFormat(0, 8, "No bookings for this day.", "", 1, "L", false, 0, "")
+		return pdf.Output(buf)
+	}
+
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.SetFillColor(235, 235, 235)
+	for _, col := range scheduleColumns {
+		pdf.CellFormat(col.width, scheduleLineHeight, col.title, "1", 0, "L", true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("Helvetica", "", 11)
+	_, pageHeight := pdf.GetPageSize()
+	_, _, _, bottomMargin := pdf.GetMargins()
+	notesWidth := scheduleColumns[len(scheduleColumns)-1].width
+	for _, b := range bookings {
+		notes := noteLines(pdf, tr, b.Notes, notesWidth)
+		height := float64(scheduleLineHeight)
+		if len(notes) > 1 {
+			height = float64(len(notes)*noteLineHeight + scheduleLineHeight - noteLineHeight)
+		}
+		// Start a taller row on a new page rather than let the page break split it.
+		if pdf.GetY()+height > pageHeight-bottomMargin {
+			pdf.AddPage()
+		}
+		cells := []string{
+			timeRange(b),
+			tr(b.Name),
+			strconv.Itoa(b.Guests),
+			tr(b.Phone),
+		}
+		for i, col := range scheduleColumns[:len(cells)] {
+			pdf.CellFormat(col.width, height, cells[i], "1", 0, "L", false, 0, "")
+		}
+		x, y := pdf.GetXY()
+		pdf.Rect(x, y, notesWidth, height, "D")
+		pdf.SetXY(x, y+(scheduleLineHeight-noteLineHeight)/2)
+		pdf.MultiCell(notesWidth, noteLineHeight, strings.Join(notes, "\n"), "", "L", false)
+		pdf.SetY(y + height)
+	}
+
+	return pdf.Output(buf)
+}
+
+// noteLines wraps notes, translated with tr, into lines that fit a column width mm wide in
+// the current font. Past maxNoteLines they are cut, the last line ending in "..." to say so.
+func noteLines(pdf *fpdf.Fpdf, tr func(string) string, notes string, width float64) []string {
+	width -= 2 * pdf.GetCellMargin()
+	fits := func(s string) bool { return pdf.GetStringWidth(tr(s)) <= width }
+
+	var lines []string
+	for _, para := range strings.Split(notes, "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			if line != "" && fits(line+" "+word) {
+				line += " " + word
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+			// A word wider than the column is broken wherever it has to be.
+			for line = word; !fits(line) && len([]rune(line)) > 1; {
+				runes := []rune(line)
+				n := len(runes) - 1
+				for n > 1 && !fits(string(runes[:n])) {
+					n--
+				}
+				lines = append(lines, string(runes[:n]))
+				line = string(runes[n:])
+			}
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) > maxNoteLines {
+		lines = lines[:maxNoteLines]
+		last := []rune(lines[maxNoteLines-1])
+		for len(last) > 0 && !fits(string(last)+"...") {
+			last = last[:len(last)-1]
+		}
+		lines[maxNoteLines-1] = strings.TrimRight(string(last), " ") + "..."
+	}
+	for i, line := range lines {
+		lines[i] = tr(line)
+	}
+	return lines
+}
+
+// timeRange renders a booking's start and end as "14:00 - 17:00", or "Full day" for a buyout.
+func timeRange(b models.Booking) string {
+	if b.FullDay {
+		return "Full day"
+	}
+	start, err := time.Parse(timeLayout, b.Time)
+	if err != nil {
+		return b.Time
+	}
+	end := start.Add(time.Duration(b.Duration) * time.Hour)
+	return start.Format(timeLayout) + " - " + end.Format(timeLayout)
+}
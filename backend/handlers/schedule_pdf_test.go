@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"miniparty-backend/models"
+
+	"github.com/go-pdf/fpdf"
+)
+
+func TestNoteLines(t *testing.T) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "", 11)
+	width := scheduleColumns[len(scheduleColumns)-1].width
+
+	if got := noteLines(pdf, tr, "", width); len(got) != 0 {
+		t.Errorf("no notes: got %q", got)
+	}
+	if got := noteLines(pdf, tr, "Nut allergy", width); len(got) != 1 || got[0] != "Nut allergy" {
+		t.Errorf("short notes: got %q", got)
+	}
+	if got := noteLines(pdf, tr, "Crème brûlée cake", width); len(got) != 1 || got[0] != tr("Crème brûlée cake") {
+		t.Errorf("accented notes: got %q, want them translated", got)
+	}
+
+	long := strings.Repeat("Bring the birthday banner and extra chairs. ", 10)
+	got := noteLines(pdf, tr, long, width)
+	if len(got) != maxNoteLines || !strings.HasSuffix(got[len(got)-1], "...") {
+		t.Errorf("long notes: got %q, want %d lines ending in ...", got, maxNoteLines)
+	}
+	for _, text := range []string{long, strings.Repeat("x", 200), "First line\nSecond line"} {
+		for _, line := range noteLines(pdf, tr, text, width) {
+			if w := pdf.GetStringWidth(line); w > width-2*pdf.GetCellMargin() {
+				t.Errorf("line %q is %.1fmm, wider than the column", line, w)
+			}
+		}
+	}
+	if got := noteLines(pdf, tr, "First line\nSecond line", width); len(got) != 2 {
+		t.Errorf("two lines of notes: got %q", got)
+	}
+}
+
+func TestRenderScheduleWithNotes(t *testing.T) {
+	var bookings []models.Booking
+	for i := 0; i < 40; i++ {
+		bookings = append(bookings, newBooking(models.Booking{Notes: strings.Repeat("Balloons and a cake, ", i%6)}))
+	}
+	var buf bytes.Buffer
+	if err := renderSchedule(&buf, time.Date(2026, 7, 10, 0, 0, 0, 0, time.UTC), bookings); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF")) {
+		t.Error("not a PDF")
+	}
+}
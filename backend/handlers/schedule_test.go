@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"miniparty-backend/messages"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/settings"
+)
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string // "" when in is rejected
+	}{
+		{"15:00", "15:00"},
+		{"09:30", "09:30"},
+		{"9:30", "09:30"},
+		{"15.00", "15:00"},
+		{"3pm", "15:00"},
+		{"3PM", "15:00"},
+		{"3:00 PM", "15:00"},
+		{"3:30pm", "15:30"},
+		{"3.30 p.m.", "15:30"},
+		{"10 a.m.", "10:00"},
+		{"12am", "00:00"},
+		{"12pm", "12:00"},
+		{" 15:00 ", "15:00"},
+		{"15:00 PM", ""},
+		{"25:00", ""},
+		{"13pm", ""},
+		{"15:60", ""},
+		{"noon", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got, err := parseClock(tt.in)
+		switch {
+		case tt.want == "" && err == nil:
+			t.Errorf("parseClock(%q) = %s, want an error", tt.in, got.Format(timeLayout))
+		case tt.want != "" && err != nil:
+			t.Errorf("parseClock(%q): %v, want %s", tt.in, err, tt.want)
+		case tt.want != "" && got.Format(timeLayout) != tt.want:
+			t.Errorf("parseClock(%q) = %s, want %s", tt.in, got.Format(timeLayout), tt.want)
+		}
+	}
+}
+
+func TestCheckSlotAlignment(t *testing.T) {
+	tests := []struct {
+		slot           int
+		clock          string
+		nearest, times string // "" when clock is on the grid
+	}{
+		{30, "14:00", "", ""},
+		{30, "14:30", "", ""},
+		{30, "14:07", "14:00", "14:00 or 14:30"},
+		{30, "14:20", "14:30", "14:00 or 14:30"},
+		{30, "23:45", "23:30", "23:30"},
+		{60, "14:00", "", ""},
+		{60, "14:30", "14:00", "14:00 or 15:00"},
+		{60, "14:40", "15:00", "14:00 or 15:00"},
+	}
+	for _, tt := range tests {
+		v := settings.Defaults()
+		v.SlotMinutes = tt.slot
+		start, err := time.Parse(timeLayout, tt.clock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := checkSlotAlignment(v, start)
+		if tt.nearest == "" {
+			if msg.Code != "" {
+				t.Errorf("%s on a %d-minute grid: %s, want no error", tt.clock, tt.slot, msg)
+			}
+			continue
+		}
+		if msg.Code != messages.TimeOffSlot || msg.Params["nearest"] != tt.nearest || msg.Params["times"] != tt.times || msg.Params["slot"] != tt.slot {
+			t.Errorf("%s on a %d-minute grid: %s %v, want off_slot near %s (%s)", tt.clock, tt.slot, msg.Code, msg.Params, tt.nearest, tt.times)
+		}
+	}
+}
+
+func TestKeepStart(t *testing.T) {
+	before := models.Booking{Date: "2026-07-10", Time: "14:15"}
+	errs := func() fieldErrors {
+		return fieldErrors{"time": {messages.New(messages.TimeOffSlot), messages.New(messages.TimePastClosing)}, "guests": {messages.New(messages.GuestsRange)}}
+	}
+
+	kept := errs()
+	keepStart(kept, before, before)
+	if len(kept["time"]) != 1 || kept["time"][0].Code != messages.TimePastClosing || len(kept["guests"]) != 1 {
+		t.Errorf("unmoved booking: errors %v, want only the slot error dropped", kept)
+	}
+
+	onlySlot := fieldErrors{"time": {messages.New(messages.TimeOffSlot)}}
+	keepStart(onlySlot, before, before)
+	if _, ok := onlySlot["time"]; ok {
+		t.Errorf("unmoved booking: errors %v, want no time errors left", onlySlot)
+	}
+
+	for _, moved := range []models.Booking{{Date: "2026-07-10", Time: "14:45"}, {Date: "2026-07-11", Time: "14:15"}} {
+		got := errs()
+		keepStart(got, moved, before)
+		if len(got["time"]) != 2 {
+			t.Errorf("moved to %s %s: errors %v, want the slot error kept", moved.Date, moved.Time, got)
+		}
+	}
+}
+
+func TestSlotGridOnBookings(t *testing.T) {
+	testDB(t)
+	setVenue(t, func(v *settings.Venue) { v.SlotMinutes = 60 })
+	r := newRouter()
+	r.POST("/book", middleware.OptionalAdminAuth(), CreateBooking)
+	r.PUT("/bookings/:id", middleware.AdminAuth(), UpdateBooking)
+	book := func(clock string) map[string]any {
+		return map[string]any{"name": "Ada Lovelace", "email": "ada@example.com", "phone": "+14155550123",
+			"date": "2026-07-10", "time": clock, "duration": 2, "guests": 4}
+	}
+
+	w := call(r, http.MethodPost, "/book", book("2:30 PM"))
+	expectError(t, w, http.StatusBadRequest, models.CodeValidation)
+	if codes := decode[struct{ Codes fieldErrors }](t, w).Codes; len(codes["time"]) != 1 || codes["time"][0].Code != messages.TimeOffSlot || codes["time"][0].Params["nearest"] != "14:00" {
+		t.Errorf("off-grid start: codes %v, want time.off_slot near 14:00", codes)
+	}
+	w = call(r, http.MethodPost, "/book", book("3 p.m."))
+	expect(t, w, http.StatusCreated)
+	if got := decode[struct{ Booking models.Booking }](t, w).Booking; got.Time != "15:00" {
+		t.Errorf("stored time %q, want 15:00", got.Time)
+	}
+
+	// A booking made on the old 30-minute grid can still be edited where it is, not moved off the new one.
+	old := addBooking(t, models.Booking{Date: "2026-07-11", Time: "14:30"})
+	edit := old
+	edit.Guests = 8
+	expect(t, call(r, http.MethodPut, fmt.Sprintf("/bookings/%d", old.ID), edit, asAdmin...), http.StatusOK)
+	edit.Time = "16:30"
+	expectError(t, call(r, http.MethodPut, fmt.Sprintf("/bookings/%d", old.ID), edit, asAdmin...), http.StatusBadRequest, models.CodeValidation)
+}
+
+func TestAdvanceBookingWindow(t *testing.T) {
+	v := settings.Defaults()
+	v.MaxAdvanceDays = 30
+	current := time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		start time.Time
+		code  string
+	}{
+		{time.Date(2026, 7, 15, 14, 0, 0, 0, time.UTC), ""},
+		// The cutoff day is bookable as a whole, late in the evening too.
+		{time.Date(2026, 7, 31, 21, 0, 0, 0, time.UTC), ""},
+		{time.Date(2026, 8, 1, 10, 0, 0, 0, time.UTC), messages.DateTooFar},
+		{time.Date(2027, 1, 1, 10, 0, 0, 0, time.UTC), messages.DateTooFar},
+	}
+	for _, tt := range tests {
+		msg := checkBookingWindow(v, tt.start, current)
+		if msg.Code != tt.code {
+			t.Errorf("%s: %q, want %q", tt.start.Format(time.DateTime), msg.Code, tt.code)
+		}
+		if tt.code == messages.DateTooFar && (msg.Params["days"] != 30 || msg.Params["until"] != "2026-07-31") {
+			t.Errorf("%s: params %v, want 30 days until 2026-07-31", tt.start.Format(time.DateTime), msg.Params)
+		}
+	}
+
+	testDB(t)
+	setVenue(t, func(v *settings.Venue) { v.MaxAdvanceDays = 30 })
+	r := newRouter()
+	r.POST("/book", middleware.OptionalAdminAuth(), CreateBooking)
+	if codes := fieldCodes(t, call(r, http.MethodPost, "/book", bookBody("2026-08-01", "14:00"))); !hasCode(codes, "date", messages.DateTooFar) {
+		t.Errorf("a booking past the window: %v, want date.too_far", codes)
+	}
+	expect(t, call(r, http.MethodPost, "/book", bookBody("2026-07-31", "14:00")), http.StatusCreated)
+}
+
+func TestMinimumLeadTime(t *testing.T) {
+	v := settings.Defaults()
+	v.MinLeadHours = 4
+	current := time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		start time.Time
+		code  string
+	}{
+		{current.Add(-2 * time.Hour), messages.DatePast},
+		{current, messages.DatePast},
+		{current.Add(3*time.Hour + 59*time.Minute), messages.DateTooSoon},
+		{current.Add(4 * time.Hour), ""},
+		{current.Add(48 * time.Hour), ""},
+	}
+	for _, tt := range tests {
+		msg := checkBookingWindow(v, tt.start, current)
+		if msg.Code != tt.code {
+			t.Errorf("%s: %q, want %q", tt.start.Format(time.DateTime), msg.Code, tt.code)
+		}
+		if tt.code == messages.DateTooSoon && msg.Params["hours"] != 4 {
+			t.Errorf("%s: params %v, want hours 4", tt.start.Format(time.DateTime), msg.Params)
+		}
+	}
+
+	v.MinLeadHours = 0
+	if msg := checkBookingWindow(v, current.Add(time.Minute), current); msg.Code != "" {
+		t.Errorf("no lead time: a booking a minute from now got %q", msg.Code)
+	}
+}
+
+func TestCheckOpeningHours(t *testing.T) {
+	friday := func(clock string) time.Time {
+		start, _ := time.Parse("2006-01-02 15:04", "2026-07-10 "+clock)
+		return start
+	}
+	late := settings.Defaults()
+	late.CloseTime = "23:30"
+	tests := []struct {
+		venue  settings.Venue
+		clock  string
+		hours  int
+		code   string
+		latest string
+	}{
+		{settings.Defaults(), "20:00", 2, "", ""},
+		{settings.Defaults(), "10:00", 12, "", ""},
+		{settings.Defaults(), "09:30", 2, messages.TimeOutsideHours, ""},
+		{settings.Defaults(), "21:00", 2, messages.TimePastClosing, "20:00"},
+		{settings.Defaults(), "20:30", 3, messages.TimePastClosing, "19:00"},
+		{settings.Defaults(), "10:00", 13, messages.TimeTooLongForDay, ""},
+		{late, "22:30", 2, messages.TimePastMidnight, "21:30"},
+	}
+	for _, tt := range tests {
+		got := checkOpeningHours(tt.venue, friday(tt.clock), tt.hours)
+		if got.Code != tt.code || (tt.latest != "" && got.Params["latest"] != tt.latest) {
+			t.Errorf("%s for %dh closing %s: %+v, want %q with the latest start %q", tt.clock, tt.hours, tt.venue.CloseTime, got, tt.code, tt.latest)
+		}
+	}
+}
+
+func TestAvailabilityFitsTheDuration(t *testing.T) {
+	testDB(t)
+	r := newRouter()
+	r.GET("/availability", GetAvailability)
+	latest := func(query string) string {
+		w := call(r, http.MethodGet, "/availability?date=2026-07-10"+query, nil)
+		expect(t, w, http.StatusOK)
+		slots := decode[struct{ Slots []slot }](t, w).Slots
+		if len(slots) == 0 {
+			t.Fatalf("no slots for %s", query)
+		}
+		return slots[len(slots)-1].Time
+	}
+
+	// The venue closes at 22:00, so the last start is the duration before it.
+	for query, want := range map[string]string{"": "21:00", "&duration=3": "19:00", "&duration=8": "14:00"} {
+		if got := latest(query); got != want {
+			t.Errorf("latest start for %q = %s, want %s", query, got, want)
+		}
+	}
+	expectError(t, call(r, http.MethodGet, "/availability?date=2026-07-10&duration=9", nil), http.StatusBadRequest, models.CodeBadRequest)
+}
+
+func TestWeeklySchedule(t *testing.T) {
+	testDB(t)
+	r := newRouter()
+	r.GET("/schedule", GetSchedule)
+	r.PUT("/admin/schedule", middleware.AdminAuth(), UpdateSchedule)
+	r.GET("/availability", GetAvailability)
+	r.POST("/book", middleware.OptionalAdminAuth(), CreateBooking)
+
+	week := settings.Week{Monday: settings.Hours{Closed: true}, Friday: settings.Hours{Open: "12:00", Close: "18:00"}}
+	w := call(r, http.MethodPut, "/admin/schedule", week, asAdmin...)
+	expect(t, w, http.StatusOK)
+	w = call(r, http.MethodGet, "/schedule", nil)
+	expect(t, w, http.StatusOK)
+	got := decode[settings.Week](t, w)
+	if got.Monday != (settings.Hours{Closed: true}) || got.Friday != (settings.Hours{Open: "12:00", Close: "18:00"}) ||
+		got.Tuesday != (settings.Hours{Open: "10:00", Close: "22:00"}) {
+		t.Errorf("GET /schedule = %+v, want Monday closed, Friday short and the rest the usual hours", got)
+	}
+
+	// 2026-07-13 is a Monday and 2026-07-10 a Friday.
+	w = call(r, http.MethodGet, "/availability?date=2026-07-13", nil)
+	expect(t, w, http.StatusOK)
+	closed := decode[struct {
+		Closed bool
+		Reason string
+		Slots  []slot
+	}](t, w)
+	if !closed.Closed || closed.Reason == "" || len(closed.Slots) != 0 {
+		t.Errorf("Monday availability = %+v, want closed with a reason", closed)
+	}
+	w = call(r, http.MethodGet, "/availability?date=2026-07-10&duration=2", nil)
+	expect(t, w, http.StatusOK)
+	if slots := decode[struct{ Slots []slot }](t, w).Slots; len(slots) == 0 || slots[0].Time != "12:00" || slots[len(slots)-1].Time != "16:00" {
+		t.Errorf("Friday slots %v, want two-hour starts from 12:00 to 16:00", slots)
+	}
+
+	if codes := fieldCodes(t, call(r, http.MethodPost, "/book", bookBody("2026-07-13", "14:00"))); !hasCode(codes, "date", messages.DateClosedDay) {
+		t.Errorf("booking a Monday: %v, want date %s", codes, messages.DateClosedDay)
+	}
+	if codes := fieldCodes(t, call(r, http.MethodPost, "/book", bookBody("2026-07-10", "17:00"))); !hasCode(codes, "time", messages.TimePastClosing) {
+		t.Errorf("booking past Friday's closing: %v, want time %s", codes, messages.TimePastClosing)
+	}
+	expect(t, call(r, http.MethodPost, "/book", bookBody("2026-07-10", "16:00")), http.StatusCreated)
+
+	codes := fieldCodes(t, call(r, http.MethodPut, "/admin/schedule", settings.Week{Sunday: settings.Hours{Open: "20:00", Close: "19:00"}}, asAdmin...))
+	if !hasCode(codes, "sunday.close", messages.SettingsCloseFirst) {
+		t.Errorf("closing before opening: %v, want sunday.close %s", codes, messages.SettingsCloseFirst)
+	}
+	if got := settings.Current().WeeklySchedule; got != week {
+		t.Errorf("schedule after a rejected change = %+v, want %+v", got, week)
+	}
+}
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"miniparty-backend/mail"
+	"miniparty-backend/messages"
+	"miniparty-backend/metrics"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/notify"
+	"miniparty-backend/settings"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxOccurrences caps how many bookings a single recurrence can expand into.
+	maxOccurrences = 26
+	// maxInterval is the most weeks a recurrence can leave between bookings.
+	maxInterval = 12
+)
+
+// recurrence describes how a booking repeats, e.g. {"frequency": "weekly", "count": 8} or
+// {"frequency": "weekly", "interval": 2, "until": "2025-12-16"} for every other week until
+// then. Interval defaults to 1.
+type recurrence struct {
+	Frequency string `json:"frequency"`
+	Interval  int    `json:"interval"`
+	Count     int    `json:"count"`
+	Until     string `json:"until"`
+}
+
+// occurrences checks rec and returns how many bookings it makes from the first on date,
+// with any error keyed like a booking's.
+func (rec *recurrence) occurrences(date string) (int, fieldErrors) {
+	if rec.Frequency != "weekly" {
+		return 0, fieldErrors{"recurrence": {messages.New(messages.RecurrenceFrequency)}}
+	}
+	if rec.Interval == 0 {
+		rec.Interval = 1
+	}
+	if rec.Interval < 1 || rec.Interval > maxInterval {
+		return 0, fieldErrors{"recurrence": {messages.New(messages.RecurrenceInterval, "max", maxInterval)}}
+	}
+	if (rec.Count == 0) == (rec.Until == "") {
+		return 0, fieldErrors{"recurrence": {messages.New(messages.RecurrenceEnd)}}
+	}
+	count := rec.Count
+	if rec.Until != "" {
+		first, err := time.Parse(dateLayout, date)
+		if err != nil {
+			return 0, fieldErrors{"date": {messages.New(messages.DateTimeInvalid)}}
+		}
+		until, err := time.Parse(dateLayout, rec.Until)
+		if err != nil {
+			return 0, fieldErrors{"recurrence": {messages.New(messages.RecurrenceUntil)}}
+		}
+		// Both dates are UTC midnights, so there is no DST hour to round away.
+		count = int(until.Sub(first).Hours()/24)/(7*rec.Interval) + 1
+		if until.Before(first) || count < 2 {
+			return 0, fieldErrors{"recurrence": {messages.New(messages.RecurrenceUntil)}}
+		}
+		if count > maxOccurrences {
+			return 0, fieldErrors{"recurrence": {messages.New(messages.RecurrenceTooLong, "max", maxOccurrences)}}
+		}
+	}
+	if count < 2 || count > maxOccurrences {
+		return 0, fieldErrors{"recurrence": {messages.New(messages.RecurrenceCount, "max", maxOccurrences)}}
+	}
+	return count, nil
+}
+
+type seriesOccurrence struct {
+	ID          int64  `json:"id,omitempty"`
+	Date        string `json:"date"`
+	CancelToken string `json:"cancel_token,omitempty"`
+	Code        string `json:"confirmation_code,omitempty"`
+	Reference   string `json:"reference,omitempty"`
+	RoomID      uint   `json:"room_id,omitempty"`
+	PriceCents  int    `json:"price_cents,omitempty"`
+	// PaymentStatus is that of a created occurrence, as on the booking.
+	PaymentStatus string `json:"payment_status,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+	// Deposit is the payment that secures the occurrence, when deposits are required.
+	Deposit gin.H `json:"deposit,omitempty"`
+}
+
+var errNothingCreated = errors.New("no occurrences could be booked")
+
+// createSeries expands a recurring booking into individual bookings sharing a series ID.
+// Occurrences that fall outside the booking window or clash with existing bookings are skipped.
+// Each occurrence goes in the first of rooms that is free that week. When limited, each also
+// counts against the customer's limit on upcoming bookings, checked in the transaction that
+// saves them: those past it are skipped, and the series is refused if none are left. When a
+// deposit is required each occurrence takes its own, as a single booking would; if any can't
+// be started the whole series is removed again. The customer gets one confirmation email
+// listing every booking made, while a created event goes out for each of them.
+func createSeries(c *gin.Context, base models.Booking, rec recurrence, pkg *models.Package, rooms []models.Room, addons []models.BookingAddon, limited bool) {
+	count, errs := rec.occurrences(base.Date)
+	if len(errs) > 0 {
+		badFields(c, errs)
+		return
+	}
+
+	first, err := parseStart(base.Date, base.Time)
+	if err != nil {
+		badFields(c, fieldErrors{"date": {messages.New(messages.DateTimeInvalid)}})
+		return
+	}
+
+	seriesID, err := randomHex(8)
+	if err != nil {
+		serverError(c, err, "Failed to save booking")
+		return
+	}
+
+	venue, lang := settings.Current(), language(c)
+	var created, skipped []seriesOccurrence
+	var bookings []models.Booking
+	err = inTx(c, func(ctx context.Context, tx *gorm.DB) error {
+		created, skipped, bookings = nil, nil, nil
+		for i := 0; i < count; i++ {
+			start := first.AddDate(0, 0, 7*rec.Interval*i)
+			booking := base
+			booking.Date = start.Format(dateLayout)
+			// AddDate keeps the wall-clock time, so across a DST change this is still the same local time.
+			startsAt := start.UTC()
+			booking.StartsAt = &startsAt
+			booking.SeriesID = seriesID
+			// Each occurrence gets its own copy, since Create fills in the booking ID.
+			booking.Addons = append([]models.BookingAddon(nil), addons...)
+			priceBooking(&booking, pkg)
+
+			if msg := checkBookingWindow(venue, start, now()); msg.Code != "" {
+				skipped = append(skipped, seriesOccurrence{Date: booking.Date, Reason: msg.Text(lang)})
+				continue
+			}
+			blackout, err := findBlackout(tx, booking.Date)
+			if err != nil {
+				return err
+			}
+			if blackout != nil {
+				skipped = append(skipped, seriesOccurrence{Date: booking.Date, Reason: blackoutMessage(blackout)})
+				continue
+			}
+			// The customer may have booked one of the later dates already; the first was checked
+			// with the rest of the booking.
+			duplicate, err := findDuplicate(ctx, &booking)
+			if err != nil {
+				return err
+			}
+			if duplicate != nil {
+				skipped = append(skipped, seriesOccurrence{Date: booking.Date, Reason: duplicateMessage})
+				continue
+			}
+			if limited {
+				// The occurrences already saved are in the transaction, so they count too.
+				var limitErr *bookingLimitError
+				if err := checkBookingLimit(ctx, &booking); errors.As(err, &limitErr) && len(created) > 0 {
+					skipped = append(skipped, seriesOccurrence{Date: booking.Date, Reason: limitErr.skipReason()})
+					continue
+				} else if err != nil {
+					return err
+				}
+			}
+			if booking.CancelToken, err = randomHex(16); err != nil {
+				return err
+			}
+			if booking.ConfirmationCode, err = confirmationCode(); err != nil {
+				return err
+			}
+			if booking.Reference, err = newReference(ctx); err != nil {
+				return err
+			}
+			// Through the store, like a single booking, so the occurrence is checked against
+			// overlapping bookings as it is inserted; one that fails only rolls back itself.
+			if err := createInRoom(ctx, &booking, rooms); slotUnavailable(err) {
+				skipped = append(skipped, seriesOccurrence{Date: booking.Date, Reason: unavailableMessage(err)})
+				continue
+			} else if err != nil {
+				return err
+			}
+			bookings = append(bookings, booking)
+			created = append(created, seriesOccurrence{ID: booking.ID, Date: booking.Date, CancelToken: booking.CancelToken, Code: booking.ConfirmationCode, Reference: booking.Reference, RoomID: *booking.RoomID, PriceCents: booking.PriceCents, PaymentStatus: booking.PaymentStatus})
+		}
+		if len(created) == 0 {
+			return errNothingCreated
+		}
+		return nil
+	})
+	if errors.Is(err, errNothingCreated) {
+		middleware.FailWith(c, http.StatusConflict, models.CodeSlotConflict, "None of the requested dates are available",
+			gin.H{"skipped": skipped})
+		return
+	}
+	if err != nil {
+		limitError(c, err)
+		return
+	}
+
+	message := "Recurring booking received! We'll call you to confirm."
+	if depositRequired() {
+		for i := range bookings {
+			intent, err := requestDeposit(c, &bookings[i])
+			if err != nil {
+				// As with a single booking, the slots would only be held until they expired.
+				for _, b := range bookings {
+					if _, purgeErr := Bookings.Purge(c.Request.Context(), b.ID); purgeErr != nil {
+						middleware.Logger(c).Error("failed to remove booking after deposit error", "booking_id", b.ID, "error", purgeErr)
+					}
+				}
+				middleware.Logger(c).Error("failed to start deposit payment", "series_id", seriesID, "error", err)
+				middleware.Fail(c, http.StatusBadGateway, models.CodePaymentUnavailable, "We couldn't start the deposit payments. Please try again.")
+				return
+			}
+			created[i].Deposit = depositDetails(bookings[i], intent)
+		}
+		message = "Recurring booking received! Pay each deposit to secure its slot."
+	}
+
+	metrics.BookingsCreated.Add(float64(len(created)))
+
+	// One email for the series, rather than one per booking arriving at once, but the
+	// notifiers and dashboards hear of each booking as they would of any other.
+	mail.SendAsync(Mailer, mail.SeriesConfirmation(bookings))
+	for _, b := range bookings {
+		dispatch(notify.Event{Type: notify.BookingCreated, Booking: b})
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   message,
+		"series_id": seriesID,
+		"created":   created,
+		"skipped":   skipped,
+	})
+}
+
+// findFreeRoom puts b in the first of rooms with nothing overlapping it, ignoring the booking
+// with ID excludeID. If every room is busy it returns the conflicts in the last one.
+func findFreeRoom(tx *gorm.DB, b *models.Booking, rooms []models.Room, excludeID int64) ([]models.Booking, error) {
+	var conflicts []models.Booking
+	for i := range rooms {
+		id := rooms[i].ID
+		b.RoomID = &id
+		var err error
+		if conflicts, err = store.FindConflicts(tx, b, excludeID); err != nil || len(conflicts) == 0 {
+			return nil, err
+		}
+	}
+	return conflicts, nil
+}
+
+// CancelSeries cancels the occurrences of a recurring series that haven't started yet.
+// Past ones are left as they are, so the series' history stays in the list and the stats.
+func CancelSeries(c *gin.Context) {
+	seriesID := c.Param("series_id")
+
+	var bookings []models.Booking
+	var cancelled []int64
+	var dates []string
+	err := inTx(c, func(ctx context.Context, tx *gorm.DB) error {
+		cancelled, dates = nil, nil
+		var err error
+		bookings, _, err = Bookings.List(ctx, store.ListOptions{Filter: store.Filter{SeriesID: seriesID}, Limit: -1})
+		if err != nil {
+			return err
+		}
+		if len(bookings) == 0 {
+			return store.ErrNotFound
+		}
+		current := now()
+		for _, b := range bookings {
+			start, err := parseStart(b.Date, b.Time)
+			if b.Status == models.StatusCancelled || err != nil || !start.After(current) {
+				continue
+			}
+			before := b
+			b, err := Bookings.Modify(ctx, b.ID, func(b *models.Booking) error {
+				b.Status = models.StatusCancelled
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if err := audit(c, tx, models.AuditBookingCancel, &b.ID, before, b); err != nil {
+				return err
+			}
+			cancelled = append(cancelled, b.ID)
+			dates = append(dates, b.Date)
+		}
+		return nil
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Series not found")
+		return
+	}
+	if err != nil {
+		serverError(c, err, "Failed to cancel series")
+		return
+	}
+	// No cancellation email per occurrence: the admin cancels a series with the customer,
+	// who would otherwise get one for every week left.
+	promoteWaitlist(c.Request.Context(), dates...)
+	calendarChanged(cancelled...)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Series cancelled successfully",
+		"cancelled":     len(cancelled),
+		"cancelled_ids": cancelled,
+		"kept":          len(bookings) - len(cancelled),
+	})
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"miniparty-backend/db"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/notify"
+	"miniparty-backend/settings"
+)
+
+// seriesRequest is a POST /book body for a weekly series of count parties from 2026-07-08.
+func seriesRequest(count int) map[string]any {
+	return map[string]any{
+		"name": "Series Customer", "email": "series@example.com", "phone": "+14155550199",
+		"date": "2026-07-08", "time": "14:00", "duration": 2, "guests": 6,
+		"recurrence": map[string]any{"frequency": "weekly", "count": count},
+	}
+}
+
+type seriesResponse struct {
+	SeriesID string             `json:"series_id"`
+	Created  []seriesOccurrence `json:"created"`
+	Skipped  []seriesOccurrence `json:"skipped"`
+}
+
+func TestSeriesCountsAgainstBookingLimit(t *testing.T) {
+	testDB(t)
+	setVenue(t, func(v *settings.Venue) { v.MaxBookingsPerEmail = 3 })
+	r := newRouter()
+	r.POST("/book", middleware.OptionalAdminAuth(), CreateBooking)
+
+	w := call(r, http.MethodPost, "/book", seriesRequest(5))
+	expect(t, w, http.StatusCreated)
+	got := decode[seriesResponse](t, w)
+	if len(got.Created) != 3 || len(got.Skipped) != 2 {
+		t.Fatalf("created %d, skipped %d; want 3 and 2", len(got.Created), len(got.Skipped))
+	}
+	if got.Skipped[0].Date != "2026-07-29" || got.Skipped[0].Reason == "" {
+		t.Errorf("first skipped = %+v, want 2026-07-29 with a reason", got.Skipped[0])
+	}
+
+	// Already at the limit, the customer can't start another series.
+	body := seriesRequest(2)
+	body["time"] = "18:00"
+	expectError(t, call(r, http.MethodPost, "/book", body), http.StatusUnprocessableEntity, models.CodeBookingLimit)
+
+	// Staff booking for the customer aren't held to it.
+	w = call(r, http.MethodPost, "/book", body, asAdmin...)
+	expect(t, w, http.StatusCreated)
+	if got := decode[seriesResponse](t, w); len(got.Created) != 2 {
+		t.Errorf("admin series created %d, want 2", len(got.Created))
+	}
+}
+
+func TestSeriesTakesDepositPerOccurrence(t *testing.T) {
+	testDB(t)
+	stripe := takeDeposits(t, 2500)
+	r := newRouter()
+	r.POST("/book", middleware.OptionalAdminAuth(), CreateBooking)
+
+	w := call(r, http.MethodPost, "/book", seriesRequest(3))
+	expect(t, w, http.StatusCreated)
+	got := decode[seriesResponse](t, w)
+	if len(got.Created) != 3 || len(stripe.deposits) != 3 {
+		t.Fatalf("created %d, deposits %v; want a deposit for each of 3", len(got.Created), stripe.deposits)
+	}
+	for i, o := range got.Created {
+		if o.Deposit["client_secret"] != fmt.Sprintf("pi_%d_secret", o.ID) || o.Deposit["amount_cents"] != float64(2500) {
+			t.Errorf("occurrence %d deposit = %v", i, o.Deposit)
+		}
+		if b := reload(t, o.ID); b.PaymentIntentID != fmt.Sprintf("pi_%d", o.ID) || b.DepositDueCents != 2500 || b.HoldExpiresAt == nil {
+			t.Errorf("occurrence %d saved as intent %q, due %d, hold %v", i, b.PaymentIntentID, b.DepositDueCents, b.HoldExpiresAt)
+		}
+	}
+
+	// Without a way to take the deposits, none of the series is kept.
+	stripe.down = true
+	body := seriesRequest(2)
+	body["time"], body["email"], body["phone"] = "18:00", "other@example.com", "+14155550198"
+	expectError(t, call(r, http.MethodPost, "/book", body), http.StatusBadGateway, models.CodePaymentUnavailable)
+	var count int64
+	if err := db.DB.Unscoped().Model(&models.Booking{}).Where("time = ?", "18:00").Count(&count).Error; err != nil || count != 0 {
+		t.Errorf("%d bookings kept after the deposits failed (%v)", count, err)
+	}
+}
+
+func TestSeriesSendsOneConfirmation(t *testing.T) {
+	testDB(t)
+	sent := catchMail(t)
+	r := newRouter()
+	r.POST("/book", middleware.OptionalAdminAuth(), CreateBooking)
+
+	w := call(r, http.MethodPost, "/book", seriesRequest(3))
+	expect(t, w, http.StatusCreated)
+	got := decode[seriesResponse](t, w)
+
+	msg := sent.next(t)
+	if msg.To != "series@example.com" || !strings.Contains(msg.Subject, "3") {
+		t.Errorf("email %q to %s", msg.Subject, msg.To)
+	}
+	for _, o := range got.Created {
+		if !strings.Contains(msg.Body, o.Date) || !strings.Contains(msg.Body, o.Reference) || !strings.Contains(msg.HTML, o.Code) {
+			t.Errorf("email doesn't list %s (%s, %s):\n%s", o.Date, o.Reference, o.Code, msg.Body)
+		}
+	}
+	sent.none(t)
+}
+
+func TestSeriesDispatchesEachBooking(t *testing.T) {
+	testDB(t)
+	events := catchEvents(t)
+	dashboard, _, _, _, err := Live.Subscribe(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Live.Unsubscribe(dashboard)
+	r := newRouter()
+	r.POST("/book", middleware.OptionalAdminAuth(), CreateBooking)
+
+	w := call(r, http.MethodPost, "/book", seriesRequest(3))
+	expect(t, w, http.StatusCreated)
+	want := map[int64]bool{}
+	for _, o := range decode[seriesResponse](t, w).Created {
+		want[o.ID] = true
+	}
+
+	got := map[int64]bool{}
+	for range want {
+		e := events.next(t)
+		if e.Type != notify.BookingCreated || e.Booking.Reference == "" {
+			t.Errorf("event %s for %+v", e.Type, e.Booking)
+		}
+		got[e.Booking.ID] = true
+		select {
+		case <-dashboard.Events:
+		case <-time.After(2 * time.Second):
+			t.Fatal("the dashboards weren't told")
+		}
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("events for %v, want one for each of %v", got, want)
+	}
+}
+
+func TestSeriesSkipsTakenDates(t *testing.T) {
+	testDB(t)
+	mine := addBooking(t, models.Booking{Date: "2026-07-15", Time: "14:00", Email: "series@example.com"})
+	addBooking(t, models.Booking{Date: "2026-07-22", Time: "15:00"})
+	r := newRouter()
+	r.POST("/book", middleware.OptionalAdminAuth(), CreateBooking)
+
+	w := call(r, http.MethodPost, "/book", seriesRequest(4))
+	expect(t, w, http.StatusCreated)
+	got := decode[seriesResponse](t, w)
+	var dates []string
+	for _, o := range got.Created {
+		dates = append(dates, o.Date)
+	}
+	if strings.Join(dates, ",") != "2026-07-08,2026-07-29" {
+		t.Errorf("created %v, want the weeks nobody has booked", dates)
+	}
+	if len(got.Skipped) != 2 || got.Skipped[0].Date != "2026-07-15" || got.Skipped[0].Reason != duplicateMessage ||
+		got.Skipped[1].Date != "2026-07-22" || !strings.Contains(got.Skipped[1].Reason, "3:00 PM - 5:00 PM") {
+		t.Errorf("skipped %+v, want the customer's own booking and the overlapping one", got.Skipped)
+	}
+	if n := countRows(t, &models.Booking{}, "email = ? AND date = ?", mine.Email, mine.Date); n != 1 {
+		t.Errorf("%d bookings for the customer on %s, want their own one only", n, mine.Date)
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type loginRequest struct {
+	Password string `json:"password"`
+}
+
+// Login exchanges the admin password (checked against the bcrypt hash in ADMIN_PASSWORD_HASH)
+// for a session token to send as "Authorization: Bearer <token>".
+func Login(c *gin.Context) {
+	var req loginRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.Password == "" {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "Password is required")
+		return
+	}
+
+	hash := middleware.Auth.PasswordHash
+	if hash == "" || middleware.Auth.JWTSecret == "" {
+		middleware.Fail(c, http.StatusInternalServerError, models.CodeInternal, "Admin login is not configured")
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(req.Password)); err != nil {
+		if !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			middleware.Logger(c).Error("ADMIN_PASSWORD_HASH is not a valid bcrypt hash", "error", err)
+		}
+		middleware.Fail(c, http.StatusUnauthorized, models.CodeUnauthorized, "Incorrect password")
+		return
+	}
+
+	issueSession(c, middleware.RoleAdmin)
+}
+
+// RefreshSession issues a fresh session token to an already authenticated admin,
+// extending the session by another middleware.SessionTTL. The new session keeps the caller's role.
+func RefreshSession(c *gin.Context) {
+	issueSession(c, middleware.Role(c))
+}
+
+func issueSession(c *gin.Context, role string) {
+	token, expiresAt, err := middleware.NewSession(now(), role)
+	if err != nil {
+		middleware.Logger(c).Error("failed to sign admin session", "error", err)
+		middleware.Fail(c, http.StatusInternalServerError, models.CodeInternal, "Failed to start session")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":      token,
+		"expires_at": expiresAt.UTC(),
+		"role":       role,
+	})
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"miniparty-backend/config"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLogin(t *testing.T) {
+	setNow(t, time.Now())
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	swap(t, &middleware.Auth, config.Auth{PasswordHash: string(hash), JWTSecret: "jwt-secret"})
+	r := newRouter()
+	r.POST("/admin/login", Login)
+	r.POST("/admin/refresh", middleware.AdminAuth(), RefreshSession)
+
+	expectError(t, call(r, http.MethodPost, "/admin/login", map[string]string{}), http.StatusBadRequest, models.CodeBadRequest)
+	expectError(t, call(r, http.MethodPost, "/admin/login", map[string]string{"password": "wrong"}), http.StatusUnauthorized, models.CodeUnauthorized)
+
+	w := call(r, http.MethodPost, "/admin/login", map[string]string{"password": "correct horse"})
+	expect(t, w, http.StatusOK)
+	session := decode[struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+		Role      string    `json:"role"`
+	}](t, w)
+	if session.Token == "" || session.Role != middleware.RoleAdmin || session.ExpiresAt.Before(time.Now().Add(middleware.SessionTTL-time.Minute)) {
+		t.Fatalf("session %+v, want an admin token for %v", session, middleware.SessionTTL)
+	}
+
+	w = call(r, http.MethodPost, "/admin/refresh", nil, "Authorization", "Bearer "+session.Token)
+	expect(t, w, http.StatusOK)
+	if got := decode[map[string]any](t, w); got["token"] == "" || got["role"] != middleware.RoleAdmin {
+		t.Errorf("refresh = %v, want a new admin session", got)
+	}
+	expectError(t, call(r, http.MethodPost, "/admin/refresh", nil, "Authorization", "Bearer forged"), http.StatusUnauthorized, models.CodeUnauthorized)
+
+	// Without a password hash there is no login to check against.
+	swap(t, &middleware.Auth, config.Auth{JWTSecret: "jwt-secret"})
+	expectError(t, call(r, http.MethodPost, "/admin/login", map[string]string{"password": "correct horse"}), http.StatusInternalServerError, models.CodeInternal)
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"time"
+
+	"miniparty-backend/db"
+	"miniparty-backend/messages"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/settings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetSettings returns the venue settings, read fresh from the database.
+func GetSettings(c *gin.Context) {
+	venue, err := settings.Load(conn(c))
+	if err != nil {
+		serverError(c, err, "Failed to fetch settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, venue)
+}
+
+// UpdateSettings changes the venue settings named in the body; keys left out keep their
+// values. The result is validated as a whole, so open_time can move past the old
+// close_time as long as close_time moves with it, and nothing is saved if any key is invalid.
+func UpdateSettings(c *gin.Context) {
+	var body map[string]json.RawMessage
+	if !bindJSON(c, &body) {
+		return
+	}
+
+	venue, err := settings.Load(conn(c))
+	if err != nil {
+		serverError(c, err, "Failed to update settings")
+		return
+	}
+	before := venue
+	errs := fieldErrors{}
+	for key := range body {
+		if !settings.Known(key) {
+			errs.add(key, messages.SettingUnknown)
+		}
+	}
+	if len(errs) > 0 {
+		badFields(c, errs)
+		return
+	}
+	raw, err := json.Marshal(body)
+	if err == nil {
+		err = json.Unmarshal(raw, &venue)
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		errs.add(typeErr.Field, kindMessage(typeErr.Type.Kind()))
+		badFields(c, errs)
+		return
+	}
+	if err != nil {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "Invalid request body")
+		return
+	}
+	if errs := fieldErrors(venue.Validate()); len(errs) > 0 {
+		badFields(c, errs)
+		return
+	}
+
+	err = settings.Save(conn(c), venue, func(tx *gorm.DB) error {
+		return audit(c, tx, models.AuditSettingsUpdate, nil, before, venue)
+	})
+	if err != nil {
+		serverError(c, err, "Failed to update settings")
+		return
+	}
+	slog.Info("venue settings updated", "settings", venue)
+
+	c.JSON(http.StatusOK, venue)
+}
+
+// GetSchedule returns the opening hours for each day of the week, with every open day's
+// times filled in, so the booking form can grey out the days the venue is closed.
+func GetSchedule(c *gin.Context) {
+	venue := settings.Current()
+	c.JSON(http.StatusOK, venue.Resolve(venue.WeeklySchedule))
+}
+
+// UpdateSchedule replaces the weekly opening hours with the body. Days left out open at
+// open_time and close at close_time, as do the times an open day leaves empty. Existing
+// bookings on a day that closes are kept.
+func UpdateSchedule(c *gin.Context) {
+	var week settings.Week
+	if !bindJSON(c, &week) {
+		return
+	}
+
+	venue, err := settings.Load(conn(c))
+	if err != nil {
+		serverError(c, err, "Failed to update schedule")
+		return
+	}
+	if errs := fieldErrors(venue.ValidateWeek(week)); len(errs) > 0 {
+		badFields(c, errs)
+		return
+	}
+	before := venue
+	venue.WeeklySchedule = week
+	err = settings.Save(conn(c), venue, func(tx *gorm.DB) error {
+		return audit(c, tx, models.AuditSettingsUpdate, nil, before, venue)
+	})
+	if err != nil {
+		serverError(c, err, "Failed to update schedule")
+		return
+	}
+	slog.Info("weekly schedule updated", "schedule", week)
+
+	c.JSON(http.StatusOK, venue.Resolve(week))
+}
+
+// kindMessage is the message code naming the JSON type a setting of kind k takes.
+func kindMessage(k reflect.Kind) string {
+	switch k {
+	case reflect.String:
+		return messages.ValueString
+	case reflect.Float64:
+		return messages.ValueNumber
+	case reflect.Bool:
+		return messages.ValueBoolean
+	case reflect.Struct:
+		return messages.ValueObject
+	}
+	return messages.ValueWholeNumber
+}
+
+// RefreshSettings reloads the venue settings every interval until ctx is done, so a change
+// saved through another instance takes effect here too.
+func RefreshSettings(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !db.Ready() {
+			continue
+		}
+		if _, err := settings.Load(db.DB.WithContext(ctx)); err != nil {
+			slog.Error("failed to reload settings", "error", err)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+package handlers
+
+import (
+	"context"
+)
+
+// RecordSMSStatus stores how the last text to a booking's customer went; main hands it to
+// the Twilio notifier. Like the reminder claim it leaves updated_at alone, since a text
+// going out doesn't change the booking.
+func RecordSMSStatus(ctx context.Context, bookingID int64, status string) error {
+	return Queries.SetSMSStatus(ctx, bookingID, status)
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"miniparty-backend/models"
+)
+
+func TestRecordSMSStatus(t *testing.T) {
+	testDB(t)
+	b := addBooking(t, models.Booking{})
+	before := reload(t, b.ID)
+	if err := RecordSMSStatus(context.Background(), b.ID, models.SMSUnsubscribed); err != nil {
+		t.Fatal(err)
+	}
+	got := reload(t, b.ID)
+	if got.SMSStatus != models.SMSUnsubscribed || !got.UpdatedAt.Equal(before.UpdatedAt) {
+		t.Errorf("sms_status %q, updated_at %v (was %v), want unsubscribed and updated_at untouched", got.SMSStatus, got.UpdatedAt, before.UpdatedAt)
+	}
+}
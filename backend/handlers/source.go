@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"miniparty-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSourceLength is the longest source or UTM value kept, in characters. Longer values are
+// cut short rather than rejected: they only say where a booking came from.
+const maxSourceLength = 100
+
+// sourceDirect is the source of a booking that names no campaign and no other site.
+const sourceDirect = "direct"
+
+// trackingText tidies a source or UTM value like the form's other text, dropping whatever
+// isn't valid UTF-8 and cutting it to maxSourceLength characters.
+func trackingText(s string) string {
+	s = strings.ReplaceAll(strings.ToValidUTF8(s, ""), string(utf8.RuneError), "")
+	s = cleanText(s, true)
+	if r := []rune(s); len(r) > maxSourceLength {
+		s = strings.TrimSpace(string(r[:maxSourceLength]))
+	}
+	return s
+}
+
+// setSource tidies the source and UTM fields of a new booking and fills in its source when
+// the request didn't send one: from utm_source, else from a Referer on another site, else
+// "direct". Sources are lowercased so "Instagram" and "instagram" count as one.
+func setSource(c *gin.Context, b *models.Booking) {
+	b.Source = strings.ToLower(trackingText(b.Source))
+	b.UTMSource = trackingText(b.UTMSource)
+	b.UTMMedium = trackingText(b.UTMMedium)
+	b.UTMCampaign = trackingText(b.UTMCampaign)
+
+	referer, err := url.Parse(c.GetHeader("Referer"))
+	if err != nil || referer.Host == "" {
+		referer = nil
+	}
+	// The booking page's own address may still carry the campaign it was reached from.
+	if referer != nil && b.UTMSource == "" && b.UTMMedium == "" && b.UTMCampaign == "" {
+		q := referer.Query()
+		b.UTMSource = trackingText(q.Get("utm_source"))
+		b.UTMMedium = trackingText(q.Get("utm_medium"))
+		b.UTMCampaign = trackingText(q.Get("utm_campaign"))
+	}
+	if b.Source == "" {
+		b.Source = strings.ToLower(b.UTMSource)
+	}
+	if b.Source == "" && referer != nil && !sameSite(c, referer) {
+		b.Source = trackingText(strings.TrimPrefix(strings.ToLower(referer.Hostname()), "www."))
+	}
+	if b.Source == "" {
+		b.Source = sourceDirect
+	}
+}
+
+// sameSite reports whether referer is the page the request was sent from, the booking form
+// itself, which says nothing about where the customer came from.
+func sameSite(c *gin.Context, referer *url.URL) bool {
+	if referer.Host == c.Request.Host {
+		return true
+	}
+	origin, err := url.Parse(c.GetHeader("Origin"))
+	return err == nil && origin.Host == referer.Host
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"miniparty-backend/config"
+	"miniparty-backend/messages"
+	"miniparty-backend/metrics"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Features turns the spam checks and the captcha's fail-open on and off. main sets it from
+// the config: SPAM_HONEYPOT, SPAM_URL_NAMES and SPAM_JUNK_DOMAINS are on unless set to
+// false, SPAM_IP_HOURLY_LIMIT=0 lifts the per-IP limit, and VERIFY_MX=true turns the MX
+// lookup on.
+var Features = config.DefaultFeatures
+
+// urlPattern matches web addresses, which real names don't contain but spam names do.
+var urlPattern = regexp.MustCompile(`(?i)https?://|www\.|\b[a-z0-9-]+\.(com|net|org|info|biz|ru|xyz|top|io|co)\b`)
+
+// ipWindow is the span the per-IP limit counts bookings over.
+const ipWindow = time.Hour
+
+// ipLog remembers when each client IP made bookings, in memory like the rate limiter.
+type ipLog struct {
+	mu        sync.Mutex
+	seen      map[string][]time.Time
+	lastSweep time.Time
+}
+
+// recentBookings is the log the per-IP limit reads. CreateBooking adds to it.
+var recentBookings = &ipLog{seen: map[string][]time.Time{}}
+
+// count returns how many bookings ip made in the window before now.
+func (l *ipLog) count(ip string, now time.Time) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seen[ip] = recent(l.seen[ip], now)
+	return len(l.seen[ip])
+}
+
+// record notes a booking from ip at now, and now and then forgets IPs that have gone quiet.
+func (l *ipLog) record(ip string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seen[ip] = append(recent(l.seen[ip], now), now)
+	if now.Sub(l.lastSweep) < ipWindow {
+		return
+	}
+	for key, times := range l.seen {
+		if times = recent(times, now); len(times) == 0 {
+			delete(l.seen, key)
+		} else {
+			l.seen[key] = times
+		}
+	}
+	l.lastSweep = now
+}
+
+// recent drops the times that are out of the window before now.
+func recent(times []time.Time, now time.Time) []time.Time {
+	i := 0
+	for i < len(times) && now.Sub(times[i]) >= ipWindow {
+		i++
+	}
+	return times[i:]
+}
+
+// spamErrors runs the enabled heuristics on a booking request from ip, returning field
+// errors in the same shape as validation so a person caught by one knows what to change.
+// The email's domain is checked later, by emailErrors, once validation has parsed it.
+func spamErrors(b *models.Booking, ip string) fieldErrors {
+	errs := fieldErrors{}
+	if Features.SpamURLNames && urlPattern.MatchString(b.Name) {
+		errs.add("name", messages.NameWebAddress)
+	}
+	if limit := Features.SpamIPHourlyLimit; limit > 0 && recentBookings.count(ip, now()) >= limit {
+		errs.add("booking", messages.NetworkLimit)
+	}
+	return errs
+}
+
+// fakeBooking answers a request that filled in the honeypot field as if it had been booked,
+// so the bot sees nothing to adapt to, without storing anything.
+func fakeBooking(c *gin.Context, b models.Booking) {
+	clearServerFields(&b)
+	cancelToken, err := randomHex(16)
+	if err != nil {
+		serverError(c, err, "Failed to save booking")
+		return
+	}
+	code, err := confirmationCode()
+	if err != nil {
+		serverError(c, err, "Failed to save booking")
+		return
+	}
+	ref, err := randomCode(referenceAlphabet, referenceLength)
+	if err != nil {
+		serverError(c, err, "Failed to save booking")
+		return
+	}
+	b.Reference = referencePrefix + ref
+	b.CreatedAt = now().UTC()
+	b.UpdatedAt = b.CreatedAt
+
+	metrics.BookingsRejected.WithLabelValues("honeypot").Inc()
+	middleware.Logger(c).Info("booking dropped: honeypot field filled in", "client_ip", middleware.ClientIP(c))
+	c.JSON(http.StatusCreated, gin.H{
+		"message":           "Booking received! We'll call you to confirm.",
+		"booking":           &b,
+		"cancel_token":      cancelToken,
+		"confirmation_code": code,
+	})
+}
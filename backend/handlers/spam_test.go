@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"miniparty-backend/db"
+	"miniparty-backend/messages"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+)
+
+func TestIPLog(t *testing.T) {
+	l := &ipLog{seen: map[string][]time.Time{}}
+	start := time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)
+	l.record("a", start)
+	l.record("a", start.Add(30*time.Minute))
+	l.record("b", start.Add(30*time.Minute))
+	if got := l.count("a", start.Add(59*time.Minute)); got != 2 {
+		t.Errorf("within the hour: %d, want 2", got)
+	}
+	if got := l.count("a", start.Add(time.Hour)); got != 1 {
+		t.Errorf("an hour after the first: %d, want 1", got)
+	}
+	l.record("c", start.Add(3*time.Hour))
+	if len(l.seen) != 1 {
+		t.Errorf("%d IPs remembered after the sweep, want only c", len(l.seen))
+	}
+}
+
+func TestSpamChecks(t *testing.T) {
+	testDB(t)
+	r := newRouter()
+	r.POST("/book", middleware.OptionalAdminAuth(), CreateBooking)
+	stored := func() int64 {
+		var n int64
+		if err := db.DB.Model(&models.Booking{}).Count(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	book := func(fields ...any) map[string]any {
+		seedCount++
+		fields = append([]any{"email", fmt.Sprintf("guest%d@example.com", seedCount), "phone", fmt.Sprintf("+1415888%04d", seedCount)}, fields...)
+		return bookBody(fmt.Sprintf("2026-07-%02d", 5+seedCount%20), "14:00", fields...)
+	}
+
+	// A filled-in honeypot looks booked but nothing is stored.
+	w := call(r, http.MethodPost, "/book", book("website", "http://spam.example"))
+	expect(t, w, http.StatusCreated)
+	if got := decode[struct{ Booking models.Booking }](t, w).Booking; got.Reference == "" || got.ID != 0 || stored() != 0 {
+		t.Errorf("honeypot: answered %+v with %d stored, want a reference and nothing saved", got, stored())
+	}
+
+	tests := []struct {
+		name   string
+		body   map[string]any
+		field  string
+		code   string
+		toggle *bool
+	}{
+		{"web address name", book("name", "Cheap tickets www.example.com"), "name", messages.NameWebAddress, &Features.SpamURLNames},
+		{"disposable email", book("email", "ada@mailinator.com"), "email", messages.EmailDisposable, &Features.SpamJunkDomains},
+	}
+	for _, tt := range tests {
+		if codes := fieldCodes(t, call(r, http.MethodPost, "/book", tt.body)); !hasCode(codes, tt.field, tt.code) {
+			t.Errorf("%s: codes %v, want %s", tt.name, codes, tt.code)
+		}
+		swap(t, tt.toggle, false)
+		expect(t, call(r, http.MethodPost, "/book", tt.body), http.StatusCreated)
+	}
+	swap(t, &Features.SpamHoneypot, false)
+	expect(t, call(r, http.MethodPost, "/book", book("website", "http://spam.example")), http.StatusCreated)
+	if stored() != 3 {
+		t.Errorf("%d stored, want the three bookings made with the checks off", stored())
+	}
+
+	// Those three came from the test's IP; the fourth in the hour is one too many.
+	swap(t, &Features.SpamIPHourlyLimit, 3)
+	if codes := fieldCodes(t, call(r, http.MethodPost, "/book", book())); !hasCode(codes, "booking", messages.NetworkLimit) {
+		t.Errorf("past the IP limit: codes %v", codes)
+	}
+	swap(t, &Features.SpamIPHourlyLimit, 0)
+	expect(t, call(r, http.MethodPost, "/book", book()), http.StatusCreated)
+}
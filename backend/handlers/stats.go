@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxStatsDays caps the range GET /admin/stats will cover.
+const maxStatsDays = 731
+
+// busiestSlotsLimit is how many start times the busiest-slots list reports.
+const busiestSlotsLimit = 10
+
+// statsPoint is one bucket of a chart series, labelled with the first date it covers.
+type statsPoint struct {
+	Period string  `json:"period"`
+	Value  float64 `json:"value"`
+}
+
+// dayStats is one day of stats: the store's totals for bookings on that day, and Created,
+// the bookings made that day, whatever their party date.
+type dayStats struct {
+	store.DayStats
+	Created int64
+}
+
+// add sums d into s.
+func (s *dayStats) add(d dayStats) {
+	s.Bookings += d.Bookings
+	s.Cancelled += d.Cancelled
+	s.Guests += d.Guests
+	s.Hours += d.Hours
+	s.Created += d.Created
+	s.CheckedIn += d.CheckedIn
+	s.BookedGuests += d.BookedGuests
+	s.ActualGuests += d.ActualGuests
+	s.NoShows += d.NoShows
+}
+
+// GetStats aggregates bookings for the admin dashboard charts over ?from= to ?to=
+// (default the last 30 days), bucketed by ?granularity= day, week (starting Monday) or
+// month. Cancelled bookings are counted in their own series and left out of the rest, except
+// "created", which counts every booking made in each bucket by its created_at rather than its
+// party date, to show when people book. Every bucket in the range is present, with zeros
+// where nothing was booked. by_source counts the range's bookings by where they came from,
+// and totals.attendance compares the guests booked with those who came. outstanding_deposits
+// doesn't depend on the range: it covers every booking from today on that is still expected
+// and has a deposit due it hasn't paid, less whatever it has paid towards it.
+func GetStats(c *gin.Context) {
+	today := now().In(venueLocation())
+	from, to := today.AddDate(0, 0, -29).Format(dateLayout), today.Format(dateLayout)
+	if v := c.Query("from"); v != "" {
+		from = v
+	}
+	if v := c.Query("to"); v != "" {
+		to = v
+	}
+	start, err := time.Parse(dateLayout, from)
+	if err != nil {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "from must be in YYYY-MM-DD format")
+		return
+	}
+	end, err := time.Parse(dateLayout, to)
+	if err != nil {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "to must be in YYYY-MM-DD format")
+		return
+	}
+	if end.Before(start) {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "from must not be after to")
+		return
+	}
+	if end.Sub(start) >= maxStatsDays*24*time.Hour {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, fmt.Sprintf("The range can cover at most %d days", maxStatsDays))
+		return
+	}
+	granularity := c.DefaultQuery("granularity", "day")
+	if granularity != "day" && granularity != "week" && granularity != "month" {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, `granularity must be "day", "week" or "month"`)
+		return
+	}
+
+	ctx := c.Request.Context()
+	days, err := Queries.DayStats(ctx, from, to, today.Format(dateLayout))
+	if err != nil {
+		serverError(c, err, "Failed to fetch stats")
+		return
+	}
+	slots, err := Queries.BusiestSlots(ctx, from, to, busiestSlotsLimit)
+	if err != nil {
+		serverError(c, err, "Failed to fetch stats")
+		return
+	}
+	sources, err := Queries.BookingsBySource(ctx, from, to)
+	if err != nil {
+		serverError(c, err, "Failed to fetch stats")
+		return
+	}
+	deposits, err := Queries.OutstandingDeposits(ctx, today.Format(dateLayout))
+	if err != nil {
+		serverError(c, err, "Failed to fetch stats")
+		return
+	}
+
+	// Bookings made in the range, counted by the venue-local day they were made on.
+	rangeStart, _ := models.StartTime(from, "00:00")
+	rangeEnd, _ := models.StartTime(end.AddDate(0, 0, 1).Format(dateLayout), "00:00")
+	made, err := Queries.CreatedBetween(ctx, rangeStart, rangeEnd)
+	if err != nil {
+		serverError(c, err, "Failed to fetch stats")
+		return
+	}
+	created := map[string]int64{}
+	for _, t := range made {
+		created[t.In(venueLocation()).Format(dateLayout)]++
+	}
+
+	// Roll the days up into buckets. There is at most one row per day in the range, so
+	// this never walks more than maxStatsDays rows.
+	byDate := make(map[string]dayStats, len(days))
+	for _, d := range days {
+		byDate[d.Date] = dayStats{DayStats: d}
+	}
+	var buckets []dayStats
+	var total dayStats
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		period := statsPeriod(day, granularity)
+		if len(buckets) == 0 || buckets[len(buckets)-1].Date != period {
+			buckets = append(buckets, dayStats{DayStats: store.DayStats{Date: period}})
+		}
+		d := byDate[day.Format(dateLayout)]
+		d.Created = created[day.Format(dateLayout)]
+		buckets[len(buckets)-1].add(d)
+		total.add(d)
+	}
+
+	series := map[string][]statsPoint{
+		"bookings":               make([]statsPoint, len(buckets)),
+		"cancelled":              make([]statsPoint, len(buckets)),
+		"guests":                 make([]statsPoint, len(buckets)),
+		"average_duration_hours": make([]statsPoint, len(buckets)),
+		"created":                make([]statsPoint, len(buckets)),
+		"checked_in":             make([]statsPoint, len(buckets)),
+		"actual_guests":          make([]statsPoint, len(buckets)),
+	}
+	for i, b := range buckets {
+		series["bookings"][i] = statsPoint{b.Date, float64(b.Bookings)}
+		series["cancelled"][i] = statsPoint{b.Date, float64(b.Cancelled)}
+		series["guests"][i] = statsPoint{b.Date, float64(b.Guests)}
+		series["average_duration_hours"][i] = statsPoint{b.Date, averageHours(b)}
+		series["created"][i] = statsPoint{b.Date, float64(b.Created)}
+		series["checked_in"][i] = statsPoint{b.Date, float64(b.CheckedIn)}
+		series["actual_guests"][i] = statsPoint{b.Date, float64(b.ActualGuests)}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":        from,
+		"to":          to,
+		"granularity": granularity,
+		"series":      series,
+		"totals": gin.H{
+			"bookings":               total.Bookings,
+			"cancelled":              total.Cancelled,
+			"guests":                 total.Guests,
+			"average_duration_hours": averageHours(total),
+			"created":                total.Created,
+			"attendance": gin.H{
+				"checked_in":    total.CheckedIn,
+				"no_shows":      total.NoShows,
+				"booked_guests": total.BookedGuests,
+				"actual_guests": total.ActualGuests,
+			},
+		},
+		"busiest_slots":        slots,
+		"by_source":            sources,
+		"outstanding_deposits": deposits,
+	})
+}
+
+// statsPeriod returns the first date of the bucket day falls in.
+func statsPeriod(day time.Time, granularity string) string {
+	switch granularity {
+	case "week":
+		offset := (int(day.Weekday()) + 6) % 7 // days since Monday
+		return day.AddDate(0, 0, -offset).Format(dateLayout)
+	case "month":
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC).Format(dateLayout)
+	}
+	return day.Format(dateLayout)
+}
+
+// averageHours is the mean duration of the bookings in s, to two decimals; 0 when there are none.
+func averageHours(s dayStats) float64 {
+	if s.Bookings == 0 {
+		return 0
+	}
+	return math.Round(float64(s.Hours)/float64(s.Bookings)*100) / 100
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"miniparty-backend/models"
+	"miniparty-backend/store"
+)
+
+func TestStatsOutstandingDeposits(t *testing.T) {
+	testDB(t)
+	addBooking(t, models.Booking{Date: "2026-07-10", DepositDueCents: 5000, AmountPaidCents: 2000})
+	addBooking(t, models.Booking{Date: "2026-07-01", Time: "18:00", Status: models.StatusPending, DepositDueCents: 2000})
+	// Not owed: paid, no deposit, cancelled, or already past.
+	addBooking(t, models.Booking{Date: "2026-07-11", DepositDueCents: 3000, AmountPaidCents: 3000, DepositPaid: true})
+	addBooking(t, models.Booking{Date: "2026-07-12"})
+	addBooking(t, models.Booking{Date: "2026-07-13", Status: models.StatusCancelled, DepositDueCents: 3000})
+	addBooking(t, models.Booking{Date: "2026-06-30", DepositDueCents: 3000})
+	r := newRouter()
+	r.GET("/admin/stats", GetStats)
+
+	// The range doesn't matter: the deposits are those of upcoming bookings.
+	for _, query := range []string{"", "?from=2025-01-01&to=2025-01-31"} {
+		w := call(r, http.MethodGet, "/admin/stats"+query, nil)
+		expect(t, w, http.StatusOK)
+		got := decode[struct {
+			Outstanding store.OutstandingDeposits `json:"outstanding_deposits"`
+		}](t, w).Outstanding
+		if got != (store.OutstandingDeposits{Bookings: 2, AmountCents: 5000}) {
+			t.Errorf("GET /admin/stats%s: outstanding_deposits = %+v, want 2 bookings owing 5000", query, got)
+		}
+	}
+}
+
+func TestStatsSeries(t *testing.T) {
+	testDB(t)
+	booked := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+	addBooking(t, models.Booking{Date: "2026-06-30", Time: "10:00"}) // past and never checked in: a no-show
+	addBooking(t, models.Booking{Date: "2026-07-06", Time: "14:00", Duration: 2, Guests: 4, CreatedAt: booked})
+	addBooking(t, models.Booking{Date: "2026-07-07", Time: "14:00", Duration: 3, Guests: 6, CreatedAt: booked})
+	addBooking(t, models.Booking{Date: "2026-07-08", Time: "14:00", Status: models.StatusCancelled})
+	addBooking(t, models.Booking{Date: "2026-07-13", Time: "10:00", Duration: 1, Guests: 4})
+	addBooking(t, models.Booking{Date: "2026-07-20", Time: "10:00"}) // outside the range
+	r := newRouter()
+	r.GET("/admin/stats", GetStats)
+
+	w := call(r, http.MethodGet, "/admin/stats?from=2026-06-29&to=2026-07-14&granularity=week", nil)
+	expect(t, w, http.StatusOK)
+	got := decode[struct {
+		Series map[string][]statsPoint
+		Totals struct {
+			Bookings, Cancelled, Guests, Created int64
+			AverageDurationHours                 float64 `json:"average_duration_hours"`
+			Attendance                           struct {
+				NoShows int64 `json:"no_shows"`
+			}
+		}
+		BusiestSlots []store.SlotCount `json:"busiest_slots"`
+	}](t, w)
+
+	series := func(name string) string {
+		values := []float64{}
+		for _, p := range got.Series[name] {
+			values = append(values, p.Value)
+		}
+		return fmt.Sprint(values)
+	}
+	// Weeks start on Monday: 29 June, 6 July and 13 July.
+	if periods := got.Series["bookings"]; len(periods) != 3 || periods[0].Period != "2026-06-29" || periods[2].Period != "2026-07-13" {
+		t.Fatalf("periods %+v, want the weeks of 29 June to 13 July", periods)
+	}
+	for name, want := range map[string]string{
+		"bookings": "[1 2 1]", "cancelled": "[0 1 0]", "guests": "[4 10 4]",
+		"average_duration_hours": "[2 2.5 1]", "created": "[2 0 0]",
+	} {
+		if got := series(name); got != want {
+			t.Errorf("%s = %s, want %s", name, got, want)
+		}
+	}
+	if tt := got.Totals; tt.Bookings != 4 || tt.Cancelled != 1 || tt.Guests != 18 || tt.Created != 2 || tt.AverageDurationHours != 2 || tt.Attendance.NoShows != 1 {
+		t.Errorf("totals %+v", tt)
+	}
+	// The cancelled 14:00 doesn't count, so the slots tie and come in time order.
+	if fmt.Sprint(got.BusiestSlots) != "[{10:00 2} {14:00 2}]" {
+		t.Errorf("busiest slots %v, want 10:00 then 14:00, twice each", got.BusiestSlots)
+	}
+
+	// Every day of the range is present, with zeros where nothing was booked.
+	w = call(r, http.MethodGet, "/admin/stats?from=2026-07-06&to=2026-07-09", nil)
+	expect(t, w, http.StatusOK)
+	if got := decode[struct{ Series map[string][]statsPoint }](t, w).Series["bookings"]; fmt.Sprint(got) != "[{2026-07-06 1} {2026-07-07 1} {2026-07-08 0} {2026-07-09 0}]" {
+		t.Errorf("daily bookings %v", got)
+	}
+
+	for _, query := range []string{"?from=July", "?to=2026-13-01", "?from=2026-07-10&to=2026-07-01", "?from=2024-01-01&to=2026-07-01", "?granularity=year"} {
+		expectError(t, call(r, http.MethodGet, "/admin/stats"+query, nil), http.StatusBadRequest, models.CodeBadRequest)
+	}
+}
+
+func TestStatsPeriod(t *testing.T) {
+	for _, tt := range []struct{ day, granularity, want string }{
+		{"2026-07-08", "day", "2026-07-08"},
+		{"2026-07-08", "week", "2026-07-06"},
+		{"2026-07-06", "week", "2026-07-06"},
+		{"2026-07-12", "week", "2026-07-06"},
+		{"2026-03-01", "week", "2026-02-23"},
+		{"2026-07-31", "month", "2026-07-01"},
+	} {
+		day, _ := time.Parse(dateLayout, tt.day)
+		if got := statsPeriod(day, tt.granularity); got != tt.want {
+			t.Errorf("statsPeriod(%s, %s) = %s, want %s", tt.day, tt.granularity, got, tt.want)
+		}
+	}
+}
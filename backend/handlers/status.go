@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"miniparty-backend/mail"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/notify"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errInvalidTransition aborts a status change the booking lifecycle doesn't allow.
+var errInvalidTransition = errors.New("invalid status transition")
+
+// ConfirmBooking moves a pending booking to confirmed.
+func ConfirmBooking(c *gin.Context) {
+	changeStatus(c, models.StatusConfirmed, models.AuditBookingConfirm)
+}
+
+// CancelBooking cancels a pending or confirmed booking, freeing its slot.
+func CancelBooking(c *gin.Context) {
+	changeStatus(c, models.StatusCancelled, models.AuditBookingCancel)
+}
+
+// changeStatus applies a lifecycle transition to the booking in :id, logging it as action.
+// Repeating the current status is a no-op; a transition the lifecycle forbids is a 409.
+// Cancelling offers the freed slot to the waitlist.
+func changeStatus(c *gin.Context, status, action string) {
+	id, ok := bookingID(c)
+	if !ok {
+		return
+	}
+
+	var booking models.Booking
+	changed := false
+	err := inTx(c, func(ctx context.Context, tx *gorm.DB) error {
+		changed = false
+		var before models.Booking
+		var err error
+		booking, err = Bookings.Modify(ctx, id, func(b *models.Booking) error {
+			before = *b
+			if b.Status != status && !models.CanTransition(b.Status, status) {
+				return errInvalidTransition
+			}
+			b.Status = status
+			return nil
+		})
+		if err != nil || before.Status == status {
+			return err
+		}
+		changed = true
+		return audit(c, tx, action, &booking.ID, before, booking)
+	})
+
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Booking not found")
+	case errors.Is(err, errInvalidTransition):
+		middleware.Fail(c, http.StatusConflict, models.CodeInvalidTransition, "A "+booking.Status+" booking cannot be "+status)
+	case err != nil:
+		serverError(c, err, "Failed to update booking")
+	default:
+		if changed && status == models.StatusCancelled {
+			promoteWaitlist(c.Request.Context(), booking.Date)
+			mail.SendAsync(Mailer, mail.Cancellation(booking))
+			dispatch(notify.Event{Type: notify.BookingCancelled, Booking: booking})
+		}
+		if changed && status == models.StatusConfirmed {
+			dispatch(notify.Event{Type: notify.BookingConfirmed, Booking: booking})
+		}
+		if changed {
+			calendarChanged(booking.ID)
+		}
+		c.JSON(http.StatusOK, booking)
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/notify"
+)
+
+func TestStatusChangesNotify(t *testing.T) {
+	testDB(t)
+	events := catchEvents(t)
+	catchMail(t)
+	b := addBooking(t, models.Booking{Status: models.StatusPending})
+	r := newRouter()
+	r.POST("/bookings/:id/confirm", middleware.AdminAuth(), ConfirmBooking)
+	r.POST("/bookings/:id/cancel", middleware.AdminAuth(), CancelBooking)
+
+	for _, step := range []struct{ action, event string }{
+		{"confirm", notify.BookingConfirmed},
+		{"cancel", notify.BookingCancelled},
+	} {
+		expect(t, call(r, http.MethodPost, fmt.Sprintf("/bookings/%d/%s", b.ID, step.action), nil, asAdmin...), http.StatusOK)
+		if e := events.next(t); e.Type != step.event || e.Booking.ID != b.ID {
+			t.Errorf("%s: event %s for booking %d, want %s", step.action, e.Type, e.Booking.ID, step.event)
+		}
+	}
+
+	// A booking already cancelled isn't cancelled again, and nobody hears of it.
+	expect(t, call(r, http.MethodPost, fmt.Sprintf("/bookings/%d/cancel", b.ID), nil, asAdmin...), http.StatusOK)
+	expectError(t, call(r, http.MethodPost, fmt.Sprintf("/bookings/%d/confirm", b.ID), nil, asAdmin...), http.StatusConflict, models.CodeInvalidTransition)
+	select {
+	case e := <-events:
+		t.Errorf("unchanged booking sent %s", e.Type)
+	default:
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"miniparty-backend/db"
+	"miniparty-backend/mail"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// summaryEmail is where the daily summary goes (SUMMARY_EMAIL). No summary is sent when it is unset.
+func summaryEmail() string {
+	return os.Getenv("SUMMARY_EMAIL")
+}
+
+// summaryHour is the venue-local hour from which the day's summary is sent (SUMMARY_HOUR, 0-23, default 7).
+func summaryHour() int {
+	if h, err := strconv.Atoi(os.Getenv("SUMMARY_HOUR")); err == nil && h >= 0 && h < 24 {
+		return h
+	}
+	return 7
+}
+
+// summarySkipEmpty reports whether days without bookings get no summary (SUMMARY_SKIP_EMPTY=true).
+func summarySkipEmpty() bool {
+	return os.Getenv("SUMMARY_SKIP_EMPTY") == "true"
+}
+
+// GetDailySummary previews the summary email for ?date= (default today).
+func GetDailySummary(c *gin.Context) {
+	date := c.Query("date")
+	if date == "" {
+		date = now().In(venueLocation()).Format(dateLayout)
+	} else if _, err := time.Parse(dateLayout, date); err != nil {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "date must be in YYYY-MM-DD format")
+		return
+	}
+
+	bookings, err := bookingsOn(c.Request.Context(), date)
+	if err != nil {
+		serverError(c, err, "Failed to build summary")
+		return
+	}
+
+	msg := mail.DailySummary(summaryEmail(), date, bookings)
+	c.JSON(http.StatusOK, gin.H{
+		"date":     date,
+		"to":       msg.To,
+		"subject":  msg.Subject,
+		"body":     msg.Body,
+		"bookings": bookings,
+	})
+}
+
+// SendDailySummaries emails the owner each day's summary once SUMMARY_HOUR has passed,
+// checking every interval until ctx is done.
+func SendDailySummaries(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !db.Ready() {
+			continue
+		}
+		if err := sendSummaryIfDue(db.DB.WithContext(context.WithoutCancel(ctx)), Mailer, now()); err != nil {
+			slog.Error("failed to send daily summary", "error", err)
+		}
+	}
+}
+
+// sendSummaryIfDue sends today's summary if it is past summaryHour and it hasn't gone yet.
+// The day is claimed in summary_sends before sending, so a restart or a second instance
+// doesn't send it again; if sending fails the claim is dropped and the next check retries.
+func sendSummaryIfDue(tx *gorm.DB, mailer mail.Mailer, at time.Time) error {
+	to := summaryEmail()
+	local := at.In(venueLocation())
+	if to == "" || local.Hour() < summaryHour() {
+		return nil
+	}
+	date := local.Format(dateLayout)
+
+	bookings, err := bookingsOn(tx.Statement.Context, date)
+	if err != nil {
+		return err
+	}
+	if len(bookings) == 0 && summarySkipEmpty() {
+		return nil
+	}
+
+	err = tx.Create(&models.SummarySend{Date: date, SentAt: at.UTC()}).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := mailer.Send(mail.DailySummary(to, date, bookings)); err != nil {
+		slog.Warn("failed to send daily summary; will retry", "date", date, "error", err)
+		return tx.Delete(&models.SummarySend{}, "date = ?", date).Error
+	}
+	slog.Info("sent daily summary", "date", date, "bookings", len(bookings))
+	return nil
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// cleanText tidies free text typed into a form: line breaks and tabs become spaces, other
+// control characters are dropped and the ends are trimmed, so the text stays on one line in
+// the CSV export and the admin table. With collapse set, runs of whitespace inside it are
+// squeezed to a single space too.
+func cleanText(s string, collapse bool) string {
+	s = strings.ReplaceAll(s, "\r\n", " ")
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\r' || r == '\t':
+			return ' '
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, s)
+	if collapse {
+		return strings.Join(strings.Fields(s), " ")
+	}
+	return strings.TrimSpace(s)
+}
+
+// checkText cleans *s with cleanText and reports whether it is usable, adding an error under
+// field when it isn't valid UTF-8 (the invalid code) or is longer than max characters (tooLong).
+// JSON decoding turns invalid UTF-8 into U+FFFD, so that character counts as invalid too.
+func checkText(errs fieldErrors, field, invalid, tooLong string, s *string, max int, collapse bool) bool {
+	*s = cleanText(*s, collapse)
+	switch {
+	case !utf8.ValidString(*s) || strings.ContainsRune(*s, utf8.RuneError):
+		errs.add(field, invalid)
+	case utf8.RuneCountInString(*s) > max:
+		errs.add(field, tooLong, "max", max)
+	default:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"miniparty-backend/messages"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+)
+
+func TestCleanText(t *testing.T) {
+	tests := []struct {
+		in       string
+		collapse bool
+		want     string
+	}{
+		{"  Ada  Lovelace ", false, "Ada  Lovelace"},
+		{"  Ada \t Lovelace ", true, "Ada Lovelace"},
+		{"line one\r\nline two\nthree", false, "line one line two three"},
+		{"bell\a and null\x00 gone", false, "bell and null gone"},
+		{"tab\there", false, "tab here"},
+		{"émilie 🎉", true, "émilie 🎉"},
+	}
+	for _, tt := range tests {
+		if got := cleanText(tt.in, tt.collapse); got != tt.want {
+			t.Errorf("cleanText(%q, %v) = %q, want %q", tt.in, tt.collapse, got, tt.want)
+		}
+	}
+}
+
+func TestBookingTextFields(t *testing.T) {
+	testDB(t)
+	r := newRouter()
+	r.POST("/book", middleware.OptionalAdminAuth(), CreateBooking)
+
+	w := call(r, http.MethodPost, "/book", bookBody("2026-07-10", "14:00", "name", "  Ada \n  Lovelace ", "notes", "Cake at 3\r\nBalloons"))
+	expect(t, w, http.StatusCreated)
+	if got := decode[struct{ Booking models.Booking }](t, w).Booking; got.Name != "Ada Lovelace" || got.Notes != "Cake at 3 Balloons" {
+		t.Errorf("stored name %q, notes %q, want them cleaned onto one line", got.Name, got.Notes)
+	}
+
+	tests := []struct {
+		field, value, code string
+	}{
+		{"name", strings.Repeat("a", maxNameLength+1), messages.NameTooLong},
+		{"name", "Ada �", messages.NameInvalidText},
+		{"name", " \t ", messages.NameRequired},
+		{"notes", strings.Repeat("n", maxNotesLength+1), messages.NotesTooLong},
+		{"email", strings.Repeat("a", maxEmailLength) + "@example.com", messages.EmailTooLong},
+	}
+	for _, tt := range tests {
+		codes := fieldCodes(t, call(r, http.MethodPost, "/book", bookBody("2026-07-11", "14:00", tt.field, tt.value)))
+		if !hasCode(codes, tt.field, tt.code) {
+			t.Errorf("%s %.20q: codes %v, want %s", tt.field, tt.value, codes, tt.code)
+		}
+	}
+	// The limit counts characters, not bytes.
+	expect(t, call(r, http.MethodPost, "/book", bookBody("2026-07-12", "14:00", "name", strings.Repeat("é", maxNameLength),
+		"email", "eve@example.com", "phone", "+14155550999")), http.StatusCreated)
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"miniparty-backend/checkin"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The sizes, in pixels, GET /bookings/:id/ticket.png draws its QR code at.
+const (
+	defaultTicketSize = 300
+	minTicketSize     = 100
+	maxTicketSize     = 1000
+)
+
+// GetTicketPNG serves a confirmed booking's ticket: a QR code of its check-in URL, ?size=
+// pixels square, for staff or for the customer with their ?code= (see findOwnBooking).
+func GetTicketPNG(c *gin.Context) {
+	id, ok := bookingID(c)
+	if !ok {
+		return
+	}
+	var booking models.Booking
+	if !findOwnBooking(c, id, &booking) {
+		return
+	}
+
+	size := defaultTicketSize
+	if s := c.Query("size"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < minTicketSize || n > maxTicketSize {
+			middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, fmt.Sprintf("size must be between %d and %d", minTicketSize, maxTicketSize))
+			return
+		}
+		size = n
+	}
+	if !checkin.Enabled() {
+		middleware.Fail(c, http.StatusServiceUnavailable, models.CodeTicketsUnavailable, "Check-in tickets are not set up")
+		return
+	}
+	if !checkin.Issued(booking) {
+		middleware.Fail(c, http.StatusConflict, models.CodeNotConfirmed, "Tickets are issued once the booking is confirmed")
+		return
+	}
+
+	png, err := checkin.PNG(booking, size)
+	if err != nil {
+		serverError(c, err, "Failed to render ticket")
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="ticket-%d.png"`, booking.ID))
+	c.Data(http.StatusOK, "image/png", png)
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"miniparty-backend/db"
+	"miniparty-backend/mail"
+	"miniparty-backend/metrics"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+	"miniparty-backend/notify"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	// errStillFull means a waitlisted request's slot hasn't freed up; the entry keeps waiting.
+	errStillFull = errors.New("waitlisted slot still full")
+
+	// errEntryGone means the entry was promoted or removed by someone else first.
+	errEntryGone = errors.New("waitlist entry already gone")
+)
+
+// joinWaitlist queues a valid booking request whose slot is full and responds 202 with its
+// place in the queue. room is the room the customer asked for, nil for any.
+func joinWaitlist(c *gin.Context, b models.Booking, room *uint, addons []models.BookingAddon) {
+	var existing models.WaitlistEntry
+	err := conn(c).Where("LOWER(email) = ? AND date = ? AND time = ?", strings.ToLower(b.Email), b.Date, b.Time).
+		Order("id ASC").Take(&existing).Error
+	if err == nil {
+		if err := waitlistPosition(conn(c), &existing); err != nil {
+			serverError(c, err, "Failed to join waitlist")
+			return
+		}
+		middleware.FailWith(c, http.StatusConflict, models.CodeAlreadyWaitlisted, "You're already on the waitlist for this slot.",
+			gin.H{"position": existing.Position})
+		return
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		serverError(c, err, "Failed to join waitlist")
+		return
+	}
+
+	entry := models.WaitlistEntry{
+		Name:      b.Name,
+		Email:     b.Email,
+		Phone:     b.Phone,
+		Date:      b.Date,
+		Time:      b.Time,
+		Duration:  b.Duration,
+		Guests:    b.Guests,
+		Notes:     b.Notes,
+		RoomID:    room,
+		PackageID: b.PackageID,
+	}
+	for _, a := range addons {
+		entry.AddonIDs = append(entry.AddonIDs, a.AddonID)
+	}
+	if err := conn(c).Create(&entry).Error; err != nil {
+		serverError(c, err, "Failed to join waitlist")
+		return
+	}
+	if err := waitlistPosition(conn(c), &entry); err != nil {
+		serverError(c, err, "Failed to join waitlist")
+		return
+	}
+	metrics.BookingsRejected.WithLabelValues("waitlisted").Inc()
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":        fmt.Sprintf("This slot is full, so you're number %d on the waitlist. We'll email you if it frees up.", entry.Position),
+		"position":       entry.Position,
+		"waitlist_entry": entry,
+	})
+}
+
+// waitlistPosition fills in e's place in the queue for its date and time.
+func waitlistPosition(tx *gorm.DB, e *models.WaitlistEntry) error {
+	var ahead int64
+	err := tx.Model(&models.WaitlistEntry{}).Where("date = ? AND time = ? AND id < ?", e.Date, e.Time, e.ID).Count(&ahead).Error
+	e.Position = int(ahead) + 1
+	return err
+}
+
+// GetWaitlist lists waiting requests for the admin, by slot and then queue position.
+// ?date= limits it to one day.
+func GetWaitlist(c *gin.Context) {
+	query := conn(c).Order("date ASC, time ASC, id ASC")
+	if date := c.Query("date"); date != "" {
+		query = query.Where("date = ?", date)
+	}
+	entries := []models.WaitlistEntry{}
+	if err := query.Find(&entries).Error; err != nil {
+		serverError(c, err, "Failed to fetch waitlist")
+		return
+	}
+
+	// Entries come grouped by slot in queue order, so positions count up within each group.
+	for i := range entries {
+		entries[i].Position = 1
+		if i > 0 && entries[i-1].Date == entries[i].Date && entries[i-1].Time == entries[i].Time {
+			entries[i].Position = entries[i-1].Position + 1
+		}
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// DeleteWaitlistEntry takes a request off the waitlist.
+func DeleteWaitlistEntry(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil || id == 0 {
+		middleware.Fail(c, http.StatusBadRequest, models.CodeBadRequest, "Invalid waitlist entry ID")
+		return
+	}
+
+	result := conn(c).Delete(&models.WaitlistEntry{}, id)
+	if result.Error != nil {
+		serverError(c, result.Error, "Failed to delete waitlist entry")
+		return
+	}
+	if result.RowsAffected == 0 {
+		middleware.Fail(c, http.StatusNotFound, models.CodeNotFound, "Waitlist entry not found")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// promoteWaitlist books waitlisted requests on dates where a booking has just been cancelled
+// or deleted, oldest first. Every entry that fits now is booked and the customer told; the
+// rest keep waiting. It runs after the cancellation has been saved and only logs failures,
+// since the cancellation itself succeeded.
+func promoteWaitlist(ctx context.Context, dates ...string) {
+	if len(dates) == 0 {
+		return
+	}
+	// Finish even if the client that cancelled goes away.
+	ctx = context.WithoutCancel(ctx)
+	tx := db.DB.WithContext(ctx)
+
+	var entries []models.WaitlistEntry
+	if err := tx.Where("date IN ?", dates).Order("id ASC").Find(&entries).Error; err != nil {
+		slog.Error("failed to read waitlist", "error", err)
+		return
+	}
+	for _, entry := range entries {
+		booking, err := promoteEntry(ctx, tx, entry)
+		if errors.Is(err, errStillFull) || errors.Is(err, errEntryGone) {
+			continue
+		}
+		if err != nil {
+			slog.Error("failed to promote waitlist entry", "waitlist_id", entry.ID, "error", err)
+			continue
+		}
+		if booking.ID == 0 {
+			continue
+		}
+
+		slog.Info("promoted waitlist entry", "waitlist_id", entry.ID, "booking_id", booking.ID, "date", booking.Date, "time", booking.Time)
+		metrics.BookingsCreated.Inc()
+		mail.SendAsync(Mailer, mail.WaitlistPromoted(booking))
+		dispatch(notify.Event{Type: notify.BookingPromoted, Booking: booking})
+	}
+}
+
+// promoteEntry re-runs a new booking's checks on entry and, if its slot is free, books it
+// and removes the entry in one transaction. Deleting the entry first means two promotions
+// racing for it can't both book it: the second finds nothing to delete and gets errEntryGone.
+// An entry that no longer passes the checks is dropped and a zero booking returned.
+func promoteEntry(ctx context.Context, tx *gorm.DB, entry models.WaitlistEntry) (models.Booking, error) {
+	booking := entry.Booking()
+	booking.Status = models.StatusPending
+
+	pkg, rooms, addons, errs, err := checkNewBooking(tx, &booking, entry.AddonIDs)
+	if err != nil {
+		return booking, err
+	}
+	reason := ""
+	if len(errs) > 0 {
+		reason = fmt.Sprint(errs)
+	} else if blackout, err := findBlackout(tx, booking.Date); err != nil {
+		return booking, err
+	} else if blackout != nil {
+		reason = blackoutMessage(blackout)
+	} else if duplicate, err := findDuplicate(ctx, &booking); err != nil {
+		return booking, err
+	} else if duplicate != nil {
+		reason = "customer already has a booking for this slot"
+	} else if err := checkBookingLimit(ctx, &booking); err != nil {
+		var limited *bookingLimitError
+		if !errors.As(err, &limited) {
+			return booking, err
+		}
+		reason = "customer has reached the booking limit"
+	}
+	if reason != "" {
+		slog.Info("dropped waitlist entry", "waitlist_id", entry.ID, "reason", reason)
+		return models.Booking{}, tx.Delete(&models.WaitlistEntry{}, entry.ID).Error
+	}
+
+	priceBooking(&booking, pkg)
+	booking.Addons = addons
+	if booking.CancelToken, err = randomHex(16); err != nil {
+		return booking, err
+	}
+	if booking.ConfirmationCode, err = confirmationCode(); err != nil {
+		return booking, err
+	}
+	if booking.Reference, err = newReference(ctx); err != nil {
+		return booking, err
+	}
+
+	err = tx.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.WaitlistEntry{}, entry.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errEntryGone
+		}
+
+		return createInRoom(store.WithTx(ctx, tx), &booking, rooms)
+	})
+	if slotUnavailable(err) {
+		err = errStillFull
+	}
+	if err != nil {
+		booking.ID = 0
+	}
+	return booking, err
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sync"
+	"time"
+
+	"miniparty-backend/db"
+	"miniparty-backend/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// version and commit identify the build. The Dockerfile sets them with
+// -ldflags "-X main.version=... -X main.commit=..."; local builds report "dev".
+var (
+	version = "dev"
+	commit  = "unknown"
+)
+
+// started is when the process came up, for the uptime /health/ready reports.
+var started = time.Now()
+
+const (
+	// dbPingTimeout bounds the readiness check's ping, so a hung database fails the check
+	// instead of hanging the health checker.
+	dbPingTimeout = 2 * time.Second
+	// dbCheckTTL is how long a ping result is reused. Health checks from Render, Docker and
+	// any uptime monitor all land here; caching keeps them from adding up to load on the database.
+	dbCheckTTL = 2 * time.Second
+)
+
+// dbCheck is the last database ping, shared by every readiness request until it expires.
+var dbCheck struct {
+	mu        sync.Mutex
+	checkedAt time.Time
+	latency   time.Duration
+	err       error
+}
+
+// pingDB pings the database, or returns the previous result if it is younger than dbCheckTTL.
+// The lock is held across the ping so concurrent checks wait for one ping rather than each sending their own.
+func pingDB(ctx context.Context) (time.Duration, error) {
+	dbCheck.mu.Lock()
+	defer dbCheck.mu.Unlock()
+	if !dbCheck.checkedAt.IsZero() && time.Since(dbCheck.checkedAt) < dbCheckTTL {
+		return dbCheck.latency, dbCheck.err
+	}
+
+	sqlDB, err := db.DB.DB()
+	if err == nil {
+		ctx, cancel := context.WithTimeout(ctx, dbPingTimeout)
+		start := time.Now()
+		err = sqlDB.PingContext(ctx)
+		dbCheck.latency = time.Since(start)
+		cancel()
+	}
+	dbCheck.checkedAt, dbCheck.err = time.Now(), err
+	return dbCheck.latency, err
+}
+
+// healthLive reports that the process is up. It never touches the database, so a brief
+// database outage doesn't get the container restarted; Render and Docker point here.
+func healthLive(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// healthReady reports whether the server can take traffic: 503 while starting or shutting
+// down, while the database is marked unreachable, or when it doesn't answer a ping. /health is an alias for it. With
+// backups on it also says when the last one succeeded, without failing when that was long ago,
+// and with retention on what its last run did.
+func healthReady(c *gin.Context) {
+	build := gin.H{
+		"version": version,
+		"commit":  commit,
+		"uptime":  time.Since(started).Round(time.Second).String(),
+	}
+	if shuttingDown.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "shutting down", "build": build})
+		return
+	}
+	if !db.Ready() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "starting", "build": build})
+		return
+	}
+
+	// The breaker's background probe decides when this clears, rather than one lucky ping.
+	if down, since := db.Degraded(); down {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":   "degraded",
+			"database": db.Driver(),
+			"since":    since.UTC(),
+			"build":    build,
+		})
+		return
+	}
+
+	latency, err := pingDB(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":   "unhealthy",
+			"error":    err.Error(),
+			"database": db.Driver(),
+			"build":    build,
+		})
+		return
+	}
+
+	resp := gin.H{
+		"status":     "ok",
+		"database":   db.Driver(),
+		"latency_ms": float64(latency.Microseconds()) / 1000,
+		"build":      build,
+	}
+	if sqlDB, err := db.DB.DB(); err == nil {
+		resp["pool"] = poolStats(sqlDB.Stats())
+	}
+	if handlers.Backups != nil {
+		// Null until the first backup; alert when it stops moving.
+		var last *time.Time
+		if at := handlers.Backups.Last(); !at.IsZero() {
+			last = &at
+		}
+		resp["backup"] = gin.H{"last_success": last}
+	}
+	if handlers.Retention.Months > 0 {
+		// Null until the first run, a day after startup.
+		resp["retention"] = gin.H{"last_run": handlers.LastRetention()}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// poolStats is the database connection pool's state, as /health/ready and /debug/runtime report it.
+func poolStats(stats sql.DBStats) gin.H {
+	return gin.H{
+		"max_open":             stats.MaxOpenConnections,
+		"open":                 stats.OpenConnections,
+		"in_use":               stats.InUse,
+		"idle":                 stats.Idle,
+		"wait_count":           stats.WaitCount,
+		"wait_duration_ms":     stats.WaitDuration.Milliseconds(),
+		"max_idle_closed":      stats.MaxIdleClosed,
+		"max_idle_time_closed": stats.MaxIdleTimeClosed,
+		"max_lifetime_closed":  stats.MaxLifetimeClosed,
+	}
+}
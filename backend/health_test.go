@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReadyFailsOnceShuttingDown(t *testing.T) {
+	testDB(t)
+	r := gin.New()
+	r.GET("/health/ready", healthReady)
+	ready := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+		return w
+	}
+
+	if w := ready(); w.Code != http.StatusOK {
+		t.Fatalf("before shutdown: %d %s, want 200", w.Code, w.Body.String())
+	}
+	shuttingDown.Store(true)
+	t.Cleanup(func() { shuttingDown.Store(false) })
+	if w := ready(); w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), `"shutting down"`) {
+		t.Errorf("shutting down: %d %s, want 503 shutting down", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,139 @@
+// Package live pushes booking events to the admin dashboards connected to GET
+// /admin/events. It is in-process: each instance streams the events its own handlers
+// publish, and a client that reconnects can pick up where it left off from the recent
+// history kept in memory.
+package live
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// clientBuffer is how many events a client can fall behind before it is dropped. A
+// dashboard that stops reading reconnects with Last-Event-ID and catches up from history.
+const clientBuffer = 32
+
+// historySize is how many recent events are kept for clients resuming with Last-Event-ID.
+const historySize = 256
+
+// ErrClosed is returned by Subscribe once the broker has shut down.
+var ErrClosed = errors.New("live: broker closed")
+
+// Event is one message on the stream. IDs start at 1 and only go up for the life of the
+// process.
+type Event struct {
+	ID   uint64
+	Type string
+	Data []byte
+}
+
+// Client is one connected stream. Events arrives closed when the client was dropped for
+// falling behind or the broker shut down.
+type Client struct {
+	Events <-chan Event
+	ch     chan Event
+}
+
+// Broker fans published events out to subscribed clients. The zero Broker is ready to use.
+type Broker struct {
+	mu      sync.Mutex
+	clients map[*Client]struct{}
+	history []Event
+	lastID  uint64
+	closed  bool
+}
+
+// Publish encodes v as an event of type typ, keeps it in the history and sends it to
+// every client, dropping any whose buffer is full.
+func (b *Broker) Publish(typ string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.lastID++
+	e := Event{ID: b.lastID, Type: typ, Data: data}
+	b.history = append(b.history, e)
+	if len(b.history) > historySize {
+		b.history = b.history[len(b.history)-historySize:]
+	}
+	for c := range b.clients {
+		select {
+		case c.ch <- e:
+		default:
+			b.drop(c)
+		}
+	}
+	return nil
+}
+
+// Subscribe connects a client. With after set, it also returns the events since that ID,
+// and complete is false if some of them are no longer in the history, or after is from
+// before the process started. lastID is the
+// latest event published so far.
+func (b *Broker) Subscribe(after uint64) (c *Client, missed []Event, lastID uint64, complete bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, nil, 0, false, ErrClosed
+	}
+	// An ID past the latest was handed out before a restart, and nothing since is known.
+	complete = after <= b.lastID
+	if after > 0 && after < b.lastID {
+		if len(b.history) == 0 || b.history[0].ID > after+1 {
+			complete = false
+		}
+		for _, e := range b.history {
+			if e.ID > after {
+				missed = append(missed, e)
+			}
+		}
+	}
+
+	ch := make(chan Event, clientBuffer)
+	c = &Client{Events: ch, ch: ch}
+	if b.clients == nil {
+		b.clients = map[*Client]struct{}{}
+	}
+	b.clients[c] = struct{}{}
+	return c, missed, b.lastID, complete, nil
+}
+
+// Unsubscribe disconnects c. It is safe to call after c was dropped.
+func (b *Broker) Unsubscribe(c *Client) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.clients[c]; ok {
+		b.drop(c)
+	}
+}
+
+// Clients is how many clients are connected.
+func (b *Broker) Clients() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.clients)
+}
+
+// Close disconnects every client and refuses new ones, so streams end on shutdown rather
+// than hold it up.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for c := range b.clients {
+		b.drop(c)
+	}
+}
+
+// drop removes c and closes its channel. b.mu must be held.
+func (b *Broker) drop(c *Client) {
+	delete(b.clients, c)
+	close(c.ch)
+}
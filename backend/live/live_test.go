@@ -0,0 +1,93 @@
+package live
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPublishSubscribe(t *testing.T) {
+	var b Broker
+	c, missed, lastID, complete, err := b.Subscribe(0)
+	if err != nil || len(missed) != 0 || lastID != 0 || !complete {
+		t.Fatalf("first subscriber: missed %v, last %d, complete %t, %v", missed, lastID, complete, err)
+	}
+	if err := b.Publish("booking.created", map[string]int{"id": 7}); err != nil {
+		t.Fatal(err)
+	}
+	if e := <-c.Events; e.ID != 1 || e.Type != "booking.created" || string(e.Data) != `{"id":7}` {
+		t.Errorf("got %+v, want event 1 with the booking", e)
+	}
+	if err := b.Publish("booking.created", func() {}); err == nil {
+		t.Error("published something that doesn't encode")
+	}
+
+	b.Unsubscribe(c)
+	if _, ok := <-c.Events; ok || b.Clients() != 0 {
+		t.Error("an unsubscribed client is still connected")
+	}
+	b.Unsubscribe(c)
+}
+
+func TestSubscribeResumes(t *testing.T) {
+	var b Broker
+	for i := 0; i < historySize+10; i++ {
+		_ = b.Publish("booking.confirmed", i)
+	}
+	last := uint64(historySize + 10)
+
+	tests := []struct {
+		after    uint64
+		missed   int
+		complete bool
+	}{
+		{last - 3, 3, true},
+		{last, 0, true},
+		// Older than the history: some events can't be replayed.
+		{5, historySize, false},
+		// From before a restart, when IDs were higher.
+		{last + 100, 0, false},
+	}
+	for _, tt := range tests {
+		c, missed, lastID, complete, err := b.Subscribe(tt.after)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(missed) != tt.missed || complete != tt.complete || lastID != last {
+			t.Errorf("after %d: %d missed, complete %t, last %d; want %d, %t, %d", tt.after, len(missed), complete, lastID, tt.missed, tt.complete, last)
+		}
+		if len(missed) > 0 && missed[len(missed)-1].ID != last {
+			t.Errorf("after %d: replay ends at %d, want %d", tt.after, missed[len(missed)-1].ID, last)
+		}
+		b.Unsubscribe(c)
+	}
+}
+
+func TestSlowClientDropped(t *testing.T) {
+	var b Broker
+	slow, _, _, _, _ := b.Subscribe(0)
+	for i := 0; i <= clientBuffer; i++ {
+		_ = b.Publish("booking.created", i)
+	}
+	n := 0
+	for range slow.Events {
+		n++
+	}
+	if n != clientBuffer || b.Clients() != 0 {
+		t.Errorf("slow client got %d events, %d clients left; want %d then dropped", n, b.Clients(), clientBuffer)
+	}
+}
+
+func TestClose(t *testing.T) {
+	var b Broker
+	c, _, _, _, _ := b.Subscribe(0)
+	b.Close()
+	if _, ok := <-c.Events; ok {
+		t.Error("a client's stream is still open after Close")
+	}
+	if _, _, _, _, err := b.Subscribe(0); !errors.Is(err, ErrClosed) {
+		t.Errorf("subscribe after Close: %v, want ErrClosed", err)
+	}
+	if err := b.Publish("booking.created", 1); err != nil {
+		t.Errorf("publish after Close: %v", err)
+	}
+}
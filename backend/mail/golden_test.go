@@ -0,0 +1,63 @@
+package mail
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"miniparty-backend/models"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata with what the templates render")
+
+// goldenBooking is the booking the golden files show: the sample, by a customer whose name
+// and notes carry markup the HTML part has to escape.
+func goldenBooking() models.Booking {
+	b := sampleBooking()
+	b.Name = `Ada <b>"Bold"</b> & Co`
+	b.Notes = "Nut allergy <script>alert(1)</script>"
+	return b
+}
+
+// checkGolden compares got with testdata/name, or rewrites the file with -update.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test ./mail -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs from what was rendered (go test ./mail -update rewrites it); got:\n%s", path, got)
+	}
+}
+
+func TestTemplatesGolden(t *testing.T) {
+	tmpl, err := LoadTemplates("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, kind := range kinds {
+		var msg Message
+		if kind == KindSeriesConfirmation {
+			series := sampleSeries()
+			series[0] = goldenBooking()
+			msg, err = tmpl.RenderSeries(kind, series)
+		} else {
+			msg, err = tmpl.Render(kind, goldenBooking())
+		}
+		if err != nil {
+			t.Errorf("%s: %v", kind, err)
+			continue
+		}
+		checkGolden(t, kind+".txt.golden", "Subject: "+msg.Subject+"\n\n"+msg.Body)
+		checkGolden(t, kind+".html.golden", msg.HTML)
+	}
+}
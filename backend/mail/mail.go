@@ -0,0 +1,166 @@
+package mail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+)
+
+// Message is an email with a plain-text body and, optionally, an HTML alternative. Inline
+// are images the HTML refers to as cid:<ID>.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	HTML    string
+	Inline  []Inline
+}
+
+// Inline is an image sent with the HTML part, such as a ticket's QR code.
+type Inline struct {
+	ID          string
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Mailer delivers messages. Handlers depend on this interface rather than on SMTP directly.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// SMTPMailer sends mail through an SMTP relay.
+type SMTPMailer struct {
+	Host string
+	Port string
+	User string
+	Pass string
+	From string
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	var auth smtp.Auth
+	if m.User != "" {
+		auth = smtp.PlainAuth("", m.User, m.Pass, m.Host)
+	}
+	return smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{msg.To}, Render(m.From, msg))
+}
+
+// Nop discards messages. It is used when SMTP isn't configured so local development needs no mail server.
+type Nop struct{}
+
+func (Nop) Send(msg Message) error {
+	log.Printf("Mail not configured; skipping %q to %s", msg.Subject, msg.To)
+	return nil
+}
+
+// FromEnv returns an SMTPMailer configured from SMTP_HOST, SMTP_PORT, SMTP_USER, SMTP_PASS
+// and FROM_ADDRESS, or Nop when SMTP_HOST is unset.
+func FromEnv() Mailer {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return Nop{}
+	}
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	return &SMTPMailer{
+		Host: host,
+		Port: port,
+		User: os.Getenv("SMTP_USER"),
+		Pass: os.Getenv("SMTP_PASS"),
+		From: os.Getenv("FROM_ADDRESS"),
+	}
+}
+
+// SendAsync delivers msg in the background, logging rather than returning any failure.
+func SendAsync(m Mailer, msg Message) {
+	go func() {
+		if err := m.Send(msg); err != nil {
+			log.Printf("Failed to send %q to %s: %v", msg.Subject, msg.To, err)
+		}
+	}()
+}
+
+// Render formats msg as an RFC 5322 message with UTF-8 plain-text body, or, when msg has
+// HTML, a multipart/alternative one with the plain text first for clients that can't show HTML.
+// Inline images go with the HTML in a multipart/related part.
+func Render(from string, msg Message) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	if msg.HTML == "" {
+		b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+		b.WriteString("\r\n")
+		b.WriteString(crlf(msg.Body))
+		return b.Bytes()
+	}
+
+	parts := multipart.NewWriter(&b)
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", parts.Boundary())
+	writeText(parts, "text/plain; charset=UTF-8", msg.Body)
+	if len(msg.Inline) == 0 {
+		writeText(parts, "text/html; charset=UTF-8", msg.HTML)
+		parts.Close()
+		return b.Bytes()
+	}
+
+	var html bytes.Buffer
+	related := multipart.NewWriter(&html)
+	writeText(related, "text/html; charset=UTF-8", msg.HTML)
+	for _, img := range msg.Inline {
+		w, _ := related.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {img.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-ID":                {"<" + img.ID + ">"},
+			"Content-Disposition":       {fmt.Sprintf("inline; filename=%q", img.Filename)},
+		})
+		writeBase64(w, img.Data)
+	}
+	related.Close()
+	w, _ := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {fmt.Sprintf("multipart/related; boundary=%q", related.Boundary())},
+	})
+	w.Write(html.Bytes())
+	parts.Close()
+	return b.Bytes()
+}
+
+// writeText adds a text part to parts. Quoted-printable keeps HTML's long lines inside
+// SMTP's line length limit.
+func writeText(parts *multipart.Writer, contentType, body string) {
+	w, _ := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	qp := quotedprintable.NewWriter(w)
+	qp.Write([]byte(crlf(body)))
+	qp.Close()
+}
+
+// writeBase64 writes data base64-encoded in 76-character lines, as MIME requires.
+func writeBase64(w io.Writer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		io.WriteString(w, encoded[:76]+"\r\n")
+		encoded = encoded[76:]
+	}
+	io.WriteString(w, encoded+"\r\n")
+}
+
+// crlf converts line endings to the CRLF that SMTP requires.
+func crlf(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\n", "\r\n")
+}
@@ -0,0 +1,62 @@
+package mail
+
+import (
+	"fmt"
+	"strings"
+
+	"miniparty-backend/models"
+)
+
+// Confirmation builds the email sent to a customer after they book.
+func Confirmation(b models.Booking) Message {
+	return render(KindConfirmation, b, nil)
+}
+
+// SeriesConfirmation builds the one email sent to a customer after they book a recurring
+// series, listing each of its bookings. series must not be empty.
+func SeriesConfirmation(series []models.Booking) Message {
+	return render(KindSeriesConfirmation, series[0], series)
+}
+
+// WaitlistPromoted builds the email sent when a customer's waitlisted request is booked.
+func WaitlistPromoted(b models.Booking) Message {
+	return render(KindWaitlistPromoted, b, nil)
+}
+
+// Reminder builds the email sent to a customer shortly before their party.
+func Reminder(b models.Booking) Message {
+	return render(KindReminder, b, nil)
+}
+
+// Cancellation builds the email sent to a customer when their booking is cancelled.
+func Cancellation(b models.Booking) Message {
+	return render(KindCancellation, b, nil)
+}
+
+// DailySummary builds the morning email to the venue owner listing date's parties. It is
+// for the owner rather than customers, so it stays plain text and isn't templated.
+func DailySummary(to, date string, bookings []models.Booking) Message {
+	var b strings.Builder
+	switch len(bookings) {
+	case 0:
+		fmt.Fprintf(&b, "No parties today (%s).\n", date)
+	case 1:
+		fmt.Fprintf(&b, "1 party today (%s):\n\n", date)
+	default:
+		fmt.Fprintf(&b, "%d parties today (%s):\n\n", len(bookings), date)
+	}
+	for _, booking := range bookings {
+		fmt.Fprintf(&b, "%s  %-20s  %-16s  %3d guests  %dh  %s\n",
+			booking.Time, booking.Name, booking.Phone, booking.Guests, booking.Duration, booking.Status)
+		if booking.Notes != "" {
+			fmt.Fprintf(&b, "       Notes: %s\n", booking.Notes)
+		}
+	}
+	b.WriteString("\nMiniParty\n")
+
+	return Message{
+		To:      to,
+		Subject: fmt.Sprintf("MiniParty schedule for %s", date),
+		Body:    b.String(),
+	}
+}
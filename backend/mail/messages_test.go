@@ -0,0 +1,27 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSeriesConfirmation(t *testing.T) {
+	series := sampleSeries()
+	msg := SeriesConfirmation(series)
+	if msg.To != series[0].Email {
+		t.Errorf("To = %q, want %q", msg.To, series[0].Email)
+	}
+	if want := "Your 3 MiniParty bookings from 2025-06-14"; msg.Subject != want {
+		t.Errorf("Subject = %q, want %q", msg.Subject, want)
+	}
+	for _, b := range series {
+		for _, part := range []string{msg.Body, msg.HTML} {
+			if !strings.Contains(part, b.Date) || !strings.Contains(part, b.Reference) || !strings.Contains(part, b.ConfirmationCode) {
+				t.Errorf("email doesn't list %s (%s):\n%s", b.Date, b.Reference, part)
+			}
+		}
+	}
+	if len(msg.Inline) != 0 {
+		t.Error("series confirmation attached a ticket")
+	}
+}
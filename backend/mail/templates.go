@@ -0,0 +1,286 @@
+package mail
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	"miniparty-backend/checkin"
+	"miniparty-backend/models"
+	"miniparty-backend/settings"
+)
+
+// The customer emails, each rendered from <kind>.txt (its subject and plain-text body) and
+// <kind>.html (its HTML body, wrapped in layout.html).
+const (
+	KindConfirmation     = "confirmation"
+	KindWaitlistPromoted = "waitlist_promoted"
+	KindReminder         = "reminder"
+	KindCancellation     = "cancellation"
+	// KindSeriesConfirmation is the one confirmation sent for a whole recurring series.
+	KindSeriesConfirmation = "series_confirmation"
+)
+
+var kinds = []string{KindConfirmation, KindWaitlistPromoted, KindReminder, KindCancellation, KindSeriesConfirmation}
+
+// layoutFile holds the HTML shared by every email: the frame around each body, and the
+// "details" table of the booking.
+const layoutFile = "layout.html"
+
+//go:embed templates
+var embedded embed.FS
+
+// defaultFS is the built-in templates, used for any file TEMPLATES_DIR doesn't override.
+var defaultFS, _ = fs.Sub(embedded, "templates")
+
+// TemplateData is what every template is executed with. CancelBy is the last moment the
+// customer can cancel or move the booking themselves, in venue time; it is zero for a
+// booking without a start time. Ticket is set when the booking has a check-in ticket,
+// which the HTML can show as <img src="cid:ticket">. Series is set for the series
+// confirmation: every booking of the series, in date order, the first of which is Booking.
+type TemplateData struct {
+	Booking  models.Booking
+	Venue    settings.Venue
+	CancelBy time.Time
+	Ticket   bool
+	Series   []models.Booking
+}
+
+func templateData(b models.Booking, series []models.Booking, v settings.Venue) TemplateData {
+	cancelBy, _ := v.CancelBy(b)
+	return TemplateData{Booking: b, Venue: v, CancelBy: cancelBy, Ticket: checkin.Issued(b), Series: series}
+}
+
+// ticketID is the Content-ID of the ticket's QR code, and ticketPixels its size.
+const (
+	ticketID     = "ticket"
+	ticketPixels = 240
+)
+
+var funcs = map[string]any{
+	// hours renders a duration such as "1 hour" or "3 hours".
+	"hours": func(n int) string {
+		if n == 1 {
+			return "1 hour"
+		}
+		return fmt.Sprintf("%d hours", n)
+	},
+}
+
+// Templates are the parsed email templates. html/template escapes the booking's fields in
+// the HTML part, so a name or note can't add markup to the email.
+type Templates struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+// LoadTemplates parses the email templates, taking each file from dir when it has one and
+// from the built-in defaults otherwise, so dir can override just the files it needs to. Every
+// template is also rendered once with a sample booking, so a template that refers to a field
+// that doesn't exist fails here rather than when the email is sent. An empty dir loads only
+// the defaults.
+func LoadTemplates(dir string) (*Templates, error) {
+	var overrides fs.FS
+	if dir != "" {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("TEMPLATES_DIR %q is not a directory", dir)
+		}
+		overrides = os.DirFS(dir)
+	}
+	read := func(name string) (string, error) {
+		if overrides != nil {
+			b, err := fs.ReadFile(overrides, name)
+			if err == nil {
+				return string(b), nil
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				return "", err
+			}
+		}
+		b, err := fs.ReadFile(defaultFS, name)
+		return string(b), err
+	}
+
+	layout, err := read(layoutFile)
+	if err != nil {
+		return nil, err
+	}
+	t := &Templates{text: map[string]*texttemplate.Template{}, html: map[string]*htmltemplate.Template{}}
+	var errs []error
+	for _, kind := range kinds {
+		if err := t.parse(kind, layout, read); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *Templates) parse(kind, layout string, read func(string) (string, error)) error {
+	src, err := read(kind + ".txt")
+	if err != nil {
+		return err
+	}
+	text, err := texttemplate.New(kind + ".txt").Funcs(funcs).Parse(src)
+	if err != nil {
+		return err
+	}
+	if text.Lookup("subject") == nil {
+		return fmt.Errorf("%s.txt: no {{define \"subject\"}} block", kind)
+	}
+
+	if src, err = read(kind + ".html"); err != nil {
+		return err
+	}
+	html, err := htmltemplate.New(kind + ".html").Funcs(funcs).Parse(src)
+	if err == nil {
+		_, err = html.New(layoutFile).Parse(layout)
+	}
+	if err != nil {
+		return err
+	}
+
+	sample := templateData(sampleBooking(), sampleSeries(), settings.Defaults())
+	for _, err := range []error{
+		text.ExecuteTemplate(io.Discard, "subject", sample),
+		text.Execute(io.Discard, sample),
+		html.Execute(io.Discard, sample),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	t.text[kind], t.html[kind] = text, html
+	return nil
+}
+
+// sampleBooking has every field a template might show filled in.
+func sampleBooking() models.Booking {
+	room := uint(1)
+	starts := time.Date(2025, 6, 14, 8, 30, 0, 0, time.UTC)
+	return models.Booking{
+		ID: 1, Name: "Sample Guest", Email: "guest@example.com", Phone: "+919876543210",
+		Date: "2025-06-14", Time: "14:00", Duration: 2, Guests: 12, Notes: "Sample notes",
+		Status: models.StatusPending, RoomID: &room, StartsAt: &starts,
+		Reference: "MP-SAMPLE", ConfirmationCode: "SAMPLE12", PriceCents: 10000,
+	}
+}
+
+// sampleSeries is a recurring series of three sample bookings a week apart.
+func sampleSeries() []models.Booking {
+	var series []models.Booking
+	for i := 0; i < 3; i++ {
+		b := sampleBooking()
+		b.ID += int64(i)
+		b.Date = time.Date(2025, 6, 14+7*i, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+		b.Reference, b.ConfirmationCode = fmt.Sprintf("MP-SAMPLE%d", i+1), fmt.Sprintf("SAMPLE%02d", i+1)
+		b.SeriesID = "sample"
+		series = append(series, b)
+	}
+	return series
+}
+
+// Render builds the kind email for b, addressed to b's email.
+func (t *Templates) Render(kind string, b models.Booking) (Message, error) {
+	return t.render(kind, b, nil)
+}
+
+// RenderSeries builds the kind email for the bookings of a recurring series, addressed to
+// the first one's email.
+func (t *Templates) RenderSeries(kind string, series []models.Booking) (Message, error) {
+	if len(series) == 0 {
+		return Message{}, errors.New("no bookings in the series")
+	}
+	return t.render(kind, series[0], series)
+}
+
+func (t *Templates) render(kind string, b models.Booking, series []models.Booking) (Message, error) {
+	text, html := t.text[kind], t.html[kind]
+	if text == nil {
+		return Message{}, fmt.Errorf("no %q email template", kind)
+	}
+	data := templateData(b, series, settings.Current())
+
+	var subject, body, htmlBody strings.Builder
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := text.Execute(&body, data); err != nil {
+		return Message{}, err
+	}
+	if err := html.Execute(&htmlBody, data); err != nil {
+		return Message{}, err
+	}
+	msg := Message{
+		To: b.Email,
+		// One line, whatever the template or the booking put in it, so it can't add headers.
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Body:    body.String(),
+		HTML:    htmlBody.String(),
+	}
+	// Only attach the QR code where the HTML shows it, so a custom template that leaves it
+	// out doesn't send it as a stray attachment.
+	if data.Ticket && strings.Contains(msg.HTML, "cid:"+ticketID) {
+		png, err := checkin.PNG(b, ticketPixels)
+		if err != nil {
+			return Message{}, err
+		}
+		msg.Inline = []Inline{{ID: ticketID, Filename: "ticket.png", ContentType: "image/png", Data: png}}
+	}
+	return msg, nil
+}
+
+// active are the templates the message builders use: the defaults until main calls UseTemplates.
+var active struct {
+	sync.RWMutex
+	*Templates
+}
+
+// builtin are the defaults, parsed once. render falls back to them if a custom template fails.
+var builtin = sync.OnceValue(func() *Templates {
+	t, err := LoadTemplates("")
+	if err != nil {
+		panic("mail: built-in templates: " + err.Error())
+	}
+	return t
+})
+
+// UseTemplates makes the message builders render with t.
+func UseTemplates(t *Templates) {
+	active.Lock()
+	defer active.Unlock()
+	active.Templates = t
+}
+
+// render builds the kind email with the active templates, for b or, when series is set, for
+// the series b is the first of. LoadTemplates has already rendered them once, so a failure
+// here comes from this booking's data; it is logged and the built-in template used instead,
+// so the customer still gets their email.
+func render(kind string, b models.Booking, series []models.Booking) Message {
+	active.RLock()
+	t := active.Templates
+	active.RUnlock()
+	if t != nil {
+		msg, err := t.render(kind, b, series)
+		if err == nil {
+			return msg
+		}
+		log.Printf("Failed to render the %s email for booking %d, using the built-in template: %v", kind, b.ID, err)
+	}
+	msg, err := builtin().render(kind, b, series)
+	if err != nil {
+		panic("mail: built-in templates: " + err.Error())
+	}
+	return msg
+}
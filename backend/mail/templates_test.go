@@ -0,0 +1,154 @@
+package mail
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// templatesDir writes files, name to contents, to a temporary directory and returns it.
+func templatesDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestBuiltinTemplates(t *testing.T) {
+	tmpl, err := LoadTemplates("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := sampleBooking()
+	for _, kind := range []string{KindConfirmation, KindWaitlistPromoted, KindReminder, KindCancellation} {
+		msg, err := tmpl.Render(kind, b)
+		if err != nil {
+			t.Errorf("%s: %v", kind, err)
+			continue
+		}
+		if msg.To != b.Email || msg.Subject == "" || strings.Contains(msg.Subject, "\n") {
+			t.Errorf("%s: to %q, subject %q", kind, msg.To, msg.Subject)
+		}
+		for _, part := range []string{msg.Body, msg.HTML} {
+			if !strings.Contains(part, b.Name) || !strings.Contains(part, b.Date) {
+				t.Errorf("%s doesn't show the booking:\n%s", kind, part)
+			}
+		}
+	}
+	if _, err := tmpl.Render("invoice", b); err == nil {
+		t.Error("rendered an unknown kind")
+	}
+}
+
+func TestTemplatesEscapeHTML(t *testing.T) {
+	tmpl, err := LoadTemplates("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := sampleBooking()
+	b.Name = `<script>alert("hi")</script>`
+	msg, err := tmpl.Render(KindConfirmation, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(msg.HTML, "<script>") || !strings.Contains(msg.HTML, "&lt;script&gt;") {
+		t.Errorf("name not escaped in the HTML:\n%s", msg.HTML)
+	}
+	if !strings.Contains(msg.Body, b.Name) {
+		t.Errorf("plain text changed the name:\n%s", msg.Body)
+	}
+}
+
+func TestTemplatesOverride(t *testing.T) {
+	dir := templatesDir(t, map[string]string{
+		"confirmation.txt": "{{define \"subject\"}}Booked:\n{{.Booking.Reference}}{{end}}See you, {{.Booking.Name}}.",
+	})
+	tmpl, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := sampleBooking()
+	msg, err := tmpl.Render(KindConfirmation, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The subject is folded onto one line, so a template can't add headers.
+	if msg.Subject != "Booked: MP-SAMPLE" || msg.Body != "See you, Sample Guest." {
+		t.Errorf("overridden confirmation: subject %q, body %q", msg.Subject, msg.Body)
+	}
+	if !strings.Contains(msg.HTML, "Thanks for booking") {
+		t.Error("the HTML part didn't fall back to the built-in template")
+	}
+	if msg, err := tmpl.Render(KindReminder, b); err != nil || strings.Contains(msg.Body, "See you, Sample Guest.") {
+		t.Errorf("the reminder changed too: %v", err)
+	}
+}
+
+func TestLoadTemplatesErrors(t *testing.T) {
+	tests := map[string]map[string]string{
+		"unknown field": {"reminder.html": `{{define "title"}}x{{end}}{{define "content"}}{{.Booking.Nickname}}{{end}}{{template "layout" .}}`},
+		"no subject":    {"reminder.txt": "Hi {{.Booking.Name}}"},
+		"syntax":        {"cancellation.txt": "{{define \"subject\"}}x{{end}}{{if .Booking.Name}}"},
+	}
+	for name, files := range tests {
+		if _, err := LoadTemplates(templatesDir(t, files)); err == nil {
+			t.Errorf("%s: loaded", name)
+		}
+	}
+	if _, err := LoadTemplates(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("loaded from a directory that doesn't exist")
+	}
+}
+
+func TestRenderFallsBackToBuiltin(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	// The sample booking renders, so this loads, but a booking named Boom fails.
+	tmpl, err := LoadTemplates(templatesDir(t, map[string]string{
+		"confirmation.txt": "{{define \"subject\"}}Custom{{end}}{{if eq .Booking.Name \"Boom\"}}{{index .Series 9}}{{end}}Custom body",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	UseTemplates(tmpl)
+	t.Cleanup(func() { UseTemplates(nil) })
+
+	b := sampleBooking()
+	if msg := Confirmation(b); msg.Subject != "Custom" {
+		t.Errorf("subject %q, want the custom template's", msg.Subject)
+	}
+	b.Name = "Boom"
+	if msg := Confirmation(b); msg.Subject != "Your MiniParty booking for 2025-06-14" || !strings.Contains(msg.Body, "Hi Boom") {
+		t.Errorf("failed custom template: subject %q, want the built-in email", msg.Subject)
+	}
+}
+
+func TestConfirmationShowsCancelDeadline(t *testing.T) {
+	tmpl, err := LoadTemplates("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := sampleBooking()
+	msg, err := tmpl.Render(KindConfirmation, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The party starts at 14:00 in Kolkata; the default cutoff is a day before.
+	for _, part := range []string{msg.Body, msg.HTML} {
+		if !strings.Contains(part, "Fri 13 Jun 2025 at 14:00") {
+			t.Errorf("confirmation doesn't give the cancellation deadline:\n%s", part)
+		}
+	}
+
+	b.StartsAt = nil
+	if msg, err = tmpl.Render(KindConfirmation, b); err != nil || strings.Contains(msg.Body, "cancel or move it yourself") {
+		t.Errorf("a booking without a start time: %v\n%s", err, msg.Body)
+	}
+}
@@ -1,89 +1,235 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"log"
+	"log/slog"
 	"net/http"
-	"os"
-	"path/filepath"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	// Timezone data for VENUE_TZ, since the Alpine runtime image doesn't ship it.
+	_ "time/tzdata"
 
+	"miniparty-backend/apidocs"
+	"miniparty-backend/backup"
+	"miniparty-backend/calendar"
+	"miniparty-backend/captcha"
+	"miniparty-backend/checkin"
+	"miniparty-backend/config"
 	"miniparty-backend/db"
+	"miniparty-backend/errreport"
 	"miniparty-backend/handlers"
+	"miniparty-backend/mail"
+	"miniparty-backend/messages"
+	"miniparty-backend/metrics"
 	"miniparty-backend/middleware"
+	"miniparty-backend/notify"
+	"miniparty-backend/payments"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	db.Init()
-	defer db.Close()
+	// Everything is checked before connecting, VENUE_TZ included: migrations read booking
+	// times in the venue's timezone.
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	r := gin.Default()
+	// Caches hear about writes from the start, since migrations make some too.
+	db.Written = handlers.DataWritten
+	// Connect in the background so the port is bound immediately on cold starts;
+	// API routes answer 503 until the database is ready.
+	go db.Init(cfg.DB)
 
-	// CORS — allow multiple origins (custom domain + Vercel + localhost)
-	origins := []string{"http://localhost:5173"}
-	if env := os.Getenv("CORS_ORIGIN"); env != "" {
-		origins = append(origins, env)
+	// Parsed now so a broken custom template stops the deploy rather than an email.
+	templates, err := mail.LoadTemplates(cfg.TemplatesDir)
+	if err != nil {
+		log.Fatalf("Invalid email templates: %v", err)
+	}
+	mail.UseTemplates(templates)
+	// A message missing from a catalogue would only show up as a bare code in some response.
+	if err := messages.Check(); err != nil {
+		log.Fatal(err)
+	}
+	handlers.Mailer = mail.FromEnv()
+	handlers.Notifications = notify.FromEnv(handlers.RecordSMSStatus)
+	handlers.Payments = payments.FromEnv()
+	handlers.Captcha = captcha.FromEnv()
+	if handlers.Calendar, err = calendar.FromEnv(); err != nil {
+		log.Fatalf("Invalid Google Calendar settings: %v", err)
+	}
+	if cfg.Backup.Dir != "" {
+		if handlers.Backups, err = backup.Open(cfg.Backup.Dir, cfg.Backup.Keep); err != nil {
+			log.Fatalf("Invalid BACKUP_DIR: %v", err)
+		}
+	}
+	handlers.Features = cfg.Features
+	handlers.NoShows = cfg.NoShows
+	handlers.Retention = cfg.Retention
+	if cfg.Features.DisposableBlocklist != "" {
+		if err := handlers.LoadDisposableDomains(cfg.Features.DisposableBlocklist); err != nil {
+			log.Fatalf("Invalid DISPOSABLE_BLOCKLIST_PATH: %v", err)
+		}
 	}
-	if env := os.Getenv("CORS_ORIGIN_2"); env != "" {
-		origins = append(origins, env)
+	middleware.ConfigureAuth(cfg.Auth)
+	checkin.Configure(cfg.Checkin)
+
+	slog.SetDefault(middleware.NewLogger(cfg.LogLevel))
+	reporter, err := errreport.FromConfig(cfg.Errors, version)
+	if err != nil {
+		log.Fatalf("Invalid SENTRY_DSN: %v", err)
 	}
 
-	r.Use(cors.New(cors.Config{
-		AllowOrigins:     origins,
-		AllowMethods:     []string{"GET", "POST", "DELETE"},
-		AllowHeaders:     []string{"Content-Type", "X-Admin-Token"},
-		AllowCredentials: true,
-	}))
+	r := gin.New()
+	inflight := middleware.NewInflight()
+	r.Use(middleware.RequestLogger(), middleware.Recovery(reporter), inflight.Track(), metrics.Middleware(), middleware.Compress())
+	// Routes that take bigger bodies raise the cap with their own BodyLimit.
+	r.Use(middleware.BodyLimit(cfg.MaxBodyBytes))
 
-	// Health check — used by Render and Docker HEALTHCHECK
-	r.GET("/health", func(c *gin.Context) {
-		sqlDB, err := db.DB.DB()
-		if err != nil {
-			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unhealthy", "error": err.Error()})
-			return
-		}
-		if err := sqlDB.Ping(); err != nil {
-			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unhealthy", "error": err.Error()})
-			return
+	metrics.RegisterDBStats(func() *sql.DB {
+		if !db.Ready() {
+			return nil
 		}
-		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+		sqlDB, _ := db.DB.DB()
+		return sqlDB
 	})
+	r.GET("/metrics", middleware.MetricsAuth(), metrics.Handler())
+	r.GET("/openapi.json", apidocs.ServeSpec)
+	r.GET("/docs", apidocs.ServeDocs)
 
-	// API routes
-	r.POST("/book", handlers.CreateBooking)
-	r.GET("/bookings", middleware.AdminAuth(), handlers.GetBookings)
-	r.DELETE("/bookings/:id", middleware.AdminAuth(), handlers.DeleteBooking)
+	// Behind Render's proxy the client IP comes from X-Forwarded-For; TRUSTED_PROXIES
+	// limits which hops may set it so clients can't spoof their way past the rate limit.
+	r.RemoteIPHeaders = []string{middleware.ForwardedForHeader}
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
-	// Serve React static files in production
-	distPath := "./dist"
-	if env := os.Getenv("DIST_PATH"); env != "" {
-		distPath = env
+	// CORS — the custom domain, Vercel and its previews, and localhost
+	r.Use(middleware.CORS(cfg.CORSOrigins))
+
+	// The health checks stay unversioned too: Render and Docker point at /health/live.
+	for _, prefix := range []string{"", apiPrefix} {
+		r.GET(prefix+"/health", healthReady)
+		r.GET(prefix+"/health/live", healthLive)
+		r.GET(prefix+"/health/ready", healthReady)
 	}
 
-	if info, err := os.Stat(distPath); err == nil && info.IsDir() {
-		log.Println("Serving frontend from", distPath)
-		r.NoRoute(func(c *gin.Context) {
-			// Try to serve the static file directly (JS, CSS, images, etc.)
-			filePath := filepath.Join(distPath, c.Request.URL.Path)
-			if info, err := os.Stat(filePath); err == nil && !info.IsDir() {
-				c.File(filePath)
-				return
-			}
+	frontend := frontendFS(cfg.DistPath)
+	limitBookings := middleware.RateLimit(cfg.RateLimitRPM)
+	limitLookups := middleware.RateLimit(cfg.LookupRateLimitRPM)
+	ready := []gin.HandlerFunc{middleware.Readiness(db.Ready), middleware.Timeout(cfg.DB.Timeout)}
+	v1 := r.Group(apiPrefix, ready...)
+	registerAPI(v1, limitBookings, limitLookups)
+	registerSessions(v1, cfg.RateLimitRPM)
+	registerLookups(v1, limitLookups)
+	registerQuotes(v1, cfg.QuoteRateLimitRPM)
+	registerWebhooks(v1)
+	registerEvents(r.Group(apiPrefix, middleware.Readiness(db.Ready)))
+	// Old unversioned paths, kept while clients move to /api/v1.
+	registerAPI(r.Group("", append([]gin.HandlerFunc{pageNavigations(frontend), middleware.Deprecated(apiPrefix)}, ready...)...), limitBookings, limitLookups)
 
-			// SPA fallback: serve index.html for all other paths (React Router handles routing)
-			c.File(filepath.Join(distPath, "index.html"))
-		})
+	if cfg.DebugEndpoints {
+		registerDebug(r)
 	}
 
-	// Port — configurable for cloud platforms
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// Serve React static files in production
+	registerFallbacks(r, frontend)
+
+	port := cfg.Port
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	// Live streams never finish on their own, so Shutdown ends them rather than wait.
+	srv.RegisterOnShutdown(handlers.Live.Close)
+	go func() {
+		log.Printf("Server starting on :%s\n", port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start server:", err)
+		}
+	}()
+
+	// Wait for Render (SIGTERM) or Ctrl-C (SIGINT), then let in-flight requests finish
+	// before closing the database.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	// Background workers stop with ctx; shutdown waits for them before closing the database.
+	var workers sync.WaitGroup
+	if handlers.Payments != nil {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			handlers.ReleaseUnpaidHolds(ctx, time.Minute)
+		}()
+	}
+	if handlers.Calendar != nil {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			handlers.SyncCalendar(ctx, 5*time.Minute)
+		}()
+	}
+	if handlers.Backups != nil {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			handlers.RunBackups(ctx, cfg.Backup.Interval)
+		}()
 	}
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		handlers.SendReminders(ctx, 10*time.Minute)
+	}()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		handlers.SendDailySummaries(ctx, 5*time.Minute)
+	}()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		handlers.RefreshSettings(ctx, time.Minute)
+	}()
+	if cfg.NoShows.Mark {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			handlers.MarkNoShows(ctx, time.Hour)
+		}()
+	}
+	if cfg.Retention.Months > 0 {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			handlers.RunRetention(ctx, time.Hour)
+		}()
+	}
+	<-ctx.Done()
+
+	shuttingDown.Store(true)
+	timeout := cfg.ShutdownTimeout
+	log.Printf("Shutting down (waiting up to %s for in-flight requests)\n", timeout)
 
-	log.Printf("Server starting on :%s\n", port)
-	if err := r.Run(":" + port); err != nil {
-		log.Fatal("Failed to start server:", err)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		for _, req := range inflight.Active() {
+			log.Printf("Request still active at shutdown: %s\n", req)
+		}
+		db.Close()
+		log.Fatal("Shutdown timed out:", err)
 	}
+
+	workers.Wait()
+	db.Close()
+	log.Println("Server stopped")
 }
+
+// shuttingDown makes /health/ready fail as soon as a shutdown signal arrives,
+// so the load balancer stops routing new traffic here.
+var shuttingDown atomic.Bool
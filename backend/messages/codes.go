@@ -0,0 +1,120 @@
+package messages
+
+// codes lists every code declared below, for Check.
+var codes []string
+
+func code(c string) string {
+	codes = append(codes, c)
+	return c
+}
+
+// Request bodies.
+var (
+	ValidationFailed = code("validation.failed")
+	FieldUnknown     = code("field.unknown")
+)
+
+// Bookings.
+var (
+	NameRequired    = code("name.required")
+	NameInvalidText = code("name.invalid_text")
+	NameTooLong     = code("name.too_long")
+	NameWebAddress  = code("name.web_address")
+
+	EmailInvalid       = code("email.invalid")
+	EmailInvalidText   = code("email.invalid_text")
+	EmailTooLong       = code("email.too_long")
+	EmailDisposable    = code("email.disposable")
+	EmailNoMailServers = code("email.no_mail_servers")
+
+	PhoneRequired    = code("phone.required")
+	PhoneInvalid     = code("phone.invalid")
+	PhoneInvalidText = code("phone.invalid_text")
+	PhoneTooLong     = code("phone.too_long")
+
+	NotesInvalidText = code("notes.invalid_text")
+	NotesTooLong     = code("notes.too_long")
+
+	DateRequired  = code("date.required")
+	DateInvalid   = code("date.invalid")
+	DatePast      = code("date.past")
+	DateTooSoon   = code("date.too_soon")
+	DateTooFar    = code("date.too_far")
+	DateClosedDay = code("date.closed_day")
+
+	TimeRequired      = code("time.required")
+	TimeInvalid       = code("time.invalid")
+	TimeOffSlot       = code("time.off_slot")
+	TimeOutsideHours  = code("time.outside_hours")
+	TimeTooLongForDay = code("time.too_long_for_day")
+	TimePastMidnight  = code("time.past_midnight")
+	TimePastClosing   = code("time.past_closing")
+	DateTimeInvalid   = code("datetime.invalid")
+
+	DurationRange      = code("duration.range")
+	GuestsRange        = code("guests.range")
+	GuestsPackageMax   = code("guests.package_max")
+	GuestsRoomCapacity = code("guests.room_capacity")
+	GuestsNoRoom       = code("guests.no_room")
+	ActualGuestsRange  = code("actual_guests.range")
+
+	PaymentAmount        = code("amount_cents.positive")
+	PaymentMethod        = code("method.invalid")
+	ReferenceInvalidText = code("reference.invalid_text")
+	ReferenceTooLong     = code("reference.too_long")
+
+	ChannelInvalid     = code("channel.invalid")
+	ChannelUnavailable = code("channel.unavailable")
+
+	RoomUnavailable    = code("room_id.unavailable")
+	PackageUnavailable = code("package_id.unavailable")
+	PackageFullDay     = code("package_id.full_day")
+	AddonUnavailable   = code("addon_ids.unavailable")
+	NetworkLimit       = code("booking.network_limit")
+	FullDayNotPublic   = code("full_day.not_public")
+	FullDayInvalid     = code("full_day.invalid")
+
+	RecurrenceFrequency = code("recurrence.frequency")
+	RecurrenceCount     = code("recurrence.count")
+	RecurrenceInterval  = code("recurrence.interval")
+	RecurrenceEnd       = code("recurrence.end")
+	RecurrenceUntil     = code("recurrence.until")
+	RecurrenceTooLong   = code("recurrence.too_long")
+)
+
+// Packages, rooms and add-ons.
+var (
+	PriceNegative     = code("price.negative")
+	BasePriceNegative = code("base_price.negative")
+	MaxGuestsRange    = code("max_guests.range")
+	CapacityRange     = code("capacity.range")
+)
+
+// Venue settings.
+var (
+	SettingUnknown     = code("setting.unknown")
+	ValueString        = code("value.string")
+	ValueNumber        = code("value.number")
+	ValueWholeNumber   = code("value.whole_number")
+	ValueBoolean       = code("value.boolean")
+	ValueObject        = code("value.object")
+	SettingsMaxGuests  = code("settings.max_guests")
+	SettingsMinDur     = code("settings.min_duration")
+	SettingsMaxDur     = code("settings.max_duration")
+	SettingsOpenTime   = code("settings.open_time")
+	SettingsCloseTime  = code("settings.close_time")
+	SettingsCloseFirst = code("settings.close_before_open")
+	SettingsSlot       = code("settings.slot_minutes")
+	SettingsLead       = code("settings.min_lead_hours")
+	SettingsAdvance    = code("settings.max_advance_days")
+	SettingsPerEmail   = code("settings.max_bookings_per_email")
+	SettingsHourly     = code("settings.hourly_rate")
+	SettingsPerGuest   = code("settings.per_guest_rate")
+	SettingsThreshold  = code("settings.guest_threshold")
+	SettingsWeekend    = code("settings.weekend_multiplier")
+	SettingsFullDayMax = code("settings.full_day_max_guests")
+	SettingsFullDay    = code("settings.full_day_rate")
+	SettingsCutoff     = code("settings.cancellation_cutoff_hours")
+	MaintenanceMessage = code("maintenance.message")
+	MaintenanceUntil   = code("maintenance.until")
+)
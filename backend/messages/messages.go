@@ -0,0 +1,167 @@
+// Package messages renders the API's validation messages in the customer's language. Each
+// message has a stable code, such as "guests.range", and parameters; the text for each code
+// comes from a catalogue per language in locales/, picked by the request's Accept-Language.
+//
+// Adding a message means declaring its code below and adding it to every catalogue; Check,
+// run at startup, refuses to start the server when a catalogue misses one.
+package messages
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Fallback is the language used when the request accepts none of the others, and for any
+// code a catalogue doesn't have.
+const Fallback = "en"
+
+// Languages are the languages there is a catalogue for, Fallback first.
+var Languages = []string{Fallback, "hi", "ml"}
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// catalogues holds each language's text by code.
+var catalogues = func() map[string]map[string]string {
+	all := make(map[string]map[string]string, len(Languages))
+	for _, lang := range Languages {
+		raw, err := locales.ReadFile("locales/" + lang + ".json")
+		if err != nil {
+			panic(err)
+		}
+		var catalogue map[string]string
+		if err := json.Unmarshal(raw, &catalogue); err != nil {
+			panic(fmt.Sprintf("messages: locales/%s.json: %v", lang, err))
+		}
+		all[lang] = catalogue
+	}
+	return all
+}()
+
+// Message is a validation message: a code and the values its text fills in. It marshals as
+// {"code": ..., "params": {...}} for clients that render messages themselves.
+type Message struct {
+	Code   string         `json:"code"`
+	Params map[string]any `json:"params,omitempty"`
+}
+
+// New returns the message for code, with params given as name, value pairs.
+func New(code string, params ...any) Message {
+	m := Message{Code: code}
+	if len(params) > 0 {
+		m.Params = make(map[string]any, len(params)/2)
+		for i := 0; i+1 < len(params); i += 2 {
+			m.Params[fmt.Sprint(params[i])] = params[i+1]
+		}
+	}
+	return m
+}
+
+// Text renders m in lang, falling back to Fallback and then to the code itself.
+func (m Message) Text(lang string) string {
+	text, ok := catalogues[lang][m.Code]
+	if !ok {
+		if text, ok = catalogues[Fallback][m.Code]; !ok {
+			return m.Code
+		}
+	}
+	if len(m.Params) == 0 {
+		return text
+	}
+	pairs := make([]string, 0, 2*len(m.Params))
+	for name, value := range m.Params {
+		pairs = append(pairs, "{"+name+"}", fmt.Sprint(value))
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+func (m Message) String() string {
+	return m.Text(Fallback)
+}
+
+// maxRanges caps how much of an Accept-Language header Negotiate reads.
+const maxRanges = 20
+
+// Negotiate picks the language to answer in from an Accept-Language header such as
+// "ml-IN,ml;q=0.9,en;q=0.8": the one with a catalogue and the highest weight, the first
+// listed on a tie. Regions are ignored, so "hi-IN" gets Hindi. Malformed entries are
+// skipped, and Fallback is the answer when nothing else matches, "*" included.
+func Negotiate(header string) string {
+	best, bestQ := Fallback, 0.0
+	for i, part := range strings.Split(header, ",") {
+		if i == maxRanges {
+			break
+		}
+		tag, params, _ := strings.Cut(part, ";")
+		q, ok := weight(params)
+		if !ok || q <= bestQ {
+			continue
+		}
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if _, known := catalogues[primary]; known {
+			best, bestQ = primary, q
+		}
+	}
+	return best
+}
+
+// weight reads the q parameter of one Accept-Language entry; ok is false when it is malformed.
+func weight(params string) (q float64, ok bool) {
+	q = 1
+	for _, param := range strings.Split(params, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if !strings.EqualFold(name, "q") {
+			continue
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil || v < 0 || v > 1 {
+			return 0, false
+		}
+		q = v
+	}
+	return q, true
+}
+
+var placeholder = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// Check reports every code missing from a catalogue, every entry in one that isn't a known
+// code, and every translation whose placeholders differ from the English text's.
+func Check() error {
+	var problems []string
+	for _, lang := range Languages {
+		catalogue := catalogues[lang]
+		for _, code := range codes {
+			text, ok := catalogue[code]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: no text for %s", lang, code))
+				continue
+			}
+			want := placeholders(catalogues[Fallback][code])
+			if got := placeholders(text); !slices.Equal(got, want) {
+				problems = append(problems, fmt.Sprintf("%s: %s fills in %v, want %v", lang, code, got, want))
+			}
+		}
+		for code := range catalogue {
+			if !slices.Contains(codes, code) {
+				problems = append(problems, fmt.Sprintf("%s: unknown code %s", lang, code))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		slices.Sort(problems)
+		return errors.New("message catalogues:\n  " + strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+func placeholders(text string) []string {
+	found := placeholder.FindAllString(text, -1)
+	slices.Sort(found)
+	return slices.Compact(found)
+}
@@ -0,0 +1,87 @@
+package messages
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCataloguesComplete(t *testing.T) {
+	if err := Check(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCheckFindsProblems(t *testing.T) {
+	hi := catalogues["hi"]
+	t.Cleanup(func() { catalogues["hi"] = hi })
+	broken := map[string]string{}
+	for code, text := range hi {
+		broken[code] = text
+	}
+	delete(broken, NameRequired)
+	broken[GuestsRange] = "मेहमान {min} से {max}"
+	broken["name.nickname"] = "उपनाम"
+	catalogues["hi"] = broken
+
+	err := Check()
+	want := "message catalogues:\n" +
+		"  hi: guests.range fills in [{max} {min}], want [{max}]\n" +
+		"  hi: no text for name.required\n" +
+		"  hi: unknown code name.nickname"
+	if err == nil || err.Error() != want {
+		t.Errorf("Check() = %v, want\n%s", err, want)
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		msg  Message
+		lang string
+		want string
+	}{
+		{New(NameRequired), "en", "Name is required"},
+		{New(GuestsRange, "max", 30), "en", "Guests must be between 1 and 30"},
+		{New(GuestsRange, "max", 30), "hi", "मेहमानों की संख्या 1 से 30 के बीच होनी चाहिए"},
+		{New(NameRequired), "fr", "Name is required"},
+		{New("no.such_code"), "hi", "no.such_code"},
+	}
+	for _, tt := range tests {
+		if got := tt.msg.Text(tt.lang); got != tt.want {
+			t.Errorf("%s in %s = %q, want %q", tt.msg.Code, tt.lang, got, tt.want)
+		}
+	}
+	if got := New(GuestsRange, "max", 30).String(); got != "Guests must be between 1 and 30" {
+		t.Errorf("String() = %q", got)
+	}
+
+	raw, err := json.Marshal(New(GuestsRange, "max", 30))
+	if err != nil || string(raw) != `{"code":"guests.range","params":{"max":30}}` {
+		t.Errorf("JSON %s, %v", raw, err)
+	}
+	if raw, _ := json.Marshal(New(NameRequired)); string(raw) != `{"code":"name.required"}` {
+		t.Errorf("JSON without params %s", raw)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := map[string]string{
+		"":                        "en",
+		"hi":                      "hi",
+		"hi-IN":                   "hi",
+		"ML-in,ml;q=0.9,en;q=0.8": "ml",
+		"en;q=0.5, hi;q=0.9":      "hi",
+		"fr-FR,fr;q=0.9":          "en",
+		"fr, ml;q=0.3":            "ml",
+		"*":                       "en",
+		"hi;q=0.8, ml;q=0.8":      "hi",
+		"hi;q=2, ml;q=0.1":        "ml",
+		"hi;q=abc":                "en",
+		"hi;q=0":                  "en",
+		"en;q=1 , ml ; Q=0.7, hi": "en",
+	}
+	for header, want := range tests {
+		if got := Negotiate(header); got != want {
+			t.Errorf("Negotiate(%q) = %s, want %s", header, got, want)
+		}
+	}
+}
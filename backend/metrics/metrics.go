@@ -0,0 +1,119 @@
+// Package metrics exposes Prometheus metrics for the HTTP server, bookings and the database pool.
+package metrics
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// registry holds only our metrics, so names and labels stay under our control.
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "miniparty_http_requests_total",
+		Help: "HTTP requests handled, by route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "miniparty_http_request_duration_seconds",
+		Help:    "HTTP request latency, by route and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// BookingsCreated counts bookings saved, including each occurrence of a series.
+	BookingsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "miniparty_bookings_created_total",
+		Help: "Bookings created.",
+	})
+
+	// BookingsRejected counts booking requests turned away, by reason.
+	BookingsRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "miniparty_bookings_rejected_total",
+		Help: "Booking requests rejected, by reason.",
+	}, []string{"reason"})
+
+	// RetentionBookings counts bookings the retention job anonymized or archived, by strategy.
+	RetentionBookings = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "miniparty_retention_bookings_total",
+		Help: "Bookings past their retention anonymized or archived, by strategy.",
+	}, []string{"strategy"})
+
+	// DBRetries counts database calls tried again after a transient error.
+	DBRetries = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "miniparty_db_retries_total",
+		Help: "Database calls retried after a transient error.",
+	})
+)
+
+func init() {
+	registry.MustRegister(httpRequests, httpDuration, BookingsCreated, BookingsRejected, RetentionBookings, DBRetries)
+}
+
+// Middleware records a count and latency for every request. Routes are labelled by their
+// pattern (e.g. "/bookings/:id") so IDs don't explode the label set.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		httpRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		httpDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// RegisterDBStats exposes the connection pool statistics of the database returned by pool.
+// pool may return nil until the database is connected.
+func RegisterDBStats(pool func() *sql.DB) {
+	stats := func() sql.DBStats {
+		if p := pool(); p != nil {
+			return p.Stats()
+		}
+		return sql.DBStats{}
+	}
+
+	registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "miniparty_db_open_connections",
+			Help: "Open database connections, in use or idle.",
+		}, func() float64 { return float64(stats().OpenConnections) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "miniparty_db_in_use_connections",
+			Help: "Database connections currently in use.",
+		}, func() float64 { return float64(stats().InUse) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "miniparty_db_idle_connections",
+			Help: "Open database connections currently idle.",
+		}, func() float64 { return float64(stats().Idle) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "miniparty_db_max_open_connections",
+			Help: "Cap on open database connections (DB_MAX_OPEN_CONNS).",
+		}, func() float64 { return float64(stats().MaxOpenConnections) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "miniparty_db_wait_count_total",
+			Help: "Times a request waited for a free database connection.",
+		}, func() float64 { return float64(stats().WaitCount) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "miniparty_db_wait_seconds_total",
+			Help: "Total time spent waiting for a free database connection.",
+		}, func() float64 { return stats().WaitDuration.Seconds() }),
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() gin.HandlerFunc {
+	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	return func(c *gin.Context) {
+		h.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/glebarez/sqlite" // registers the sqlite database/sql driver
+)
+
+// scrape returns the Prometheus text Handler serves.
+func scrape(t *testing.T) string {
+	t.Helper()
+	r := gin.New()
+	r.GET("/metrics", Handler())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /metrics = %d", w.Code)
+	}
+	return w.Body.String()
+}
+
+func TestMiddlewareLabelsRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware())
+	r.GET("/bookings/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	for _, target := range []string{"/bookings/1", "/bookings/2", "/nowhere"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	BookingsCreated.Inc()
+	BookingsRejected.WithLabelValues("spam").Inc()
+
+	got := scrape(t)
+	for _, want := range []string{
+		`miniparty_http_requests_total{method="GET",route="/bookings/:id",status="200"} 2`,
+		`miniparty_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`miniparty_http_request_duration_seconds_count{method="GET",route="/bookings/:id",status="200"} 2`,
+		`miniparty_bookings_created_total 1`,
+		`miniparty_bookings_rejected_total{reason="spam"} 1`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("metrics lack %s", want)
+		}
+	}
+	if strings.Contains(got, "/bookings/1") {
+		t.Error("a booking ID appears as a route label")
+	}
+	// Only our registry is served, not the Go runtime's default collectors.
+	if strings.Contains(got, "go_goroutines") {
+		t.Error("the default Go collectors are exposed")
+	}
+}
+
+func TestRegisterDBStats(t *testing.T) {
+	var pool *sql.DB
+	RegisterDBStats(func() *sql.DB { return pool })
+	// Before the database connects the gauges read zero rather than failing the scrape.
+	if got := scrape(t); !strings.Contains(got, "miniparty_db_open_connections 0") {
+		t.Errorf("no zero open connections gauge before the pool exists:\n%s", got)
+	}
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(4)
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	pool = db
+	got := scrape(t)
+	for _, want := range []string{
+		"miniparty_db_open_connections 1",
+		"miniparty_db_idle_connections 1",
+		"miniparty_db_in_use_connections 0",
+		"miniparty_db_max_open_connections 4",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("metrics lack %s", want)
+		}
+	}
+}
@@ -1,28 +1,184 @@
 package middleware
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"math"
 	"net/http"
-	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"miniparty-backend/config"
+	"miniparty-backend/models"
 
 	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// Auth is the admin credentials every admin route checks. main sets it with ConfigureAuth.
+var Auth config.Auth
+
+// ConfigureAuth installs the admin credentials and the lockout settings from the config.
+func ConfigureAuth(a config.Auth) {
+	Auth = a
+	AdminLockout = NewLockout(a.MaxFailures, a.FailureWindow, a.Lockout)
+}
+
+// AdminAuth accepts either an admin session ("Authorization: Bearer <jwt>" from
+// POST /admin/login) or a static token in X-Admin-Token: the admin secret
+// (ADMIN_SECRET or ADMIN_SECRET_HASH) or one of ADMIN_TOKENS.
 func AdminAuth() gin.HandlerFunc {
+	return adminAuth(false)
+}
+
+// AdminAuthFeed is AdminAuth that also accepts the token as ?token=, for calendar
+// subscriptions and other clients that cannot set custom headers.
+func AdminAuthFeed() gin.HandlerFunc {
+	return adminAuth(true)
+}
+
+// OptionalAdminAuth checks admin credentials like AdminAuth when the request carries any,
+// and otherwise lets it through with no role, for routes customers can also use with a
+// token of their own. The handler must authorize requests where Role is "".
+func OptionalAdminAuth() gin.HandlerFunc {
+	admin := adminAuth(false)
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" && c.GetHeader("X-Admin-Token") == "" {
+			c.Next()
+			return
+		}
+		admin(c)
+	}
+}
+
+// MetricsAuth protects /metrics. When METRICS_TOKEN is set, scrapers authenticate with
+// "Authorization: Bearer <token>"; otherwise the admin token is required.
+func MetricsAuth() gin.HandlerFunc {
+	admin := adminAuth(false)
+	return func(c *gin.Context) {
+		token := Auth.MetricsToken
+		if token == "" {
+			admin(c)
+			return
+		}
+
+		if !equalTokens(c.GetHeader("Authorization"), "Bearer "+token) {
+			Fail(c, http.StatusUnauthorized, models.CodeUnauthorized, "Unauthorized")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func adminAuth(allowQuery bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		secret := os.Getenv("ADMIN_SECRET")
-		if secret == "" {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Admin access is not configured"})
-			c.Abort()
+		ip := ClientIP(c)
+		if locked, wait := AdminLockout.Locked(ip, time.Now()); locked {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			Fail(c, http.StatusTooManyRequests, models.CodeRateLimited, "Too many failed attempts, please try again later")
+			return
+		}
+
+		if bearer, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+			role, err := verifySession(bearer)
+			switch {
+			case errors.Is(err, ErrSessionsDisabled):
+				Fail(c, http.StatusInternalServerError, models.CodeInternal, "Admin sessions are not configured")
+				return
+			case errors.Is(err, errSessionExpired):
+				// A genuine session that ran out, not a guess, so it doesn't count towards the lockout.
+				Fail(c, http.StatusUnauthorized, models.CodeUnauthorized, "Session expired, please log in again")
+				return
+			case err != nil:
+				authFailed(c, ip, "Invalid session token")
+				return
+			}
+			AdminLockout.Reset(ip)
+			c.Set(roleKey, role)
+			c.Set(actorKey, "session")
+			c.Next()
+			return
+		}
+
+		if Auth.Secret == "" && Auth.SecretHash == "" && len(Auth.Tokens) == 0 && Auth.JWTSecret == "" {
+			Fail(c, http.StatusInternalServerError, models.CodeInternal, "Admin access is not configured")
 			return
 		}
 
 		token := c.GetHeader("X-Admin-Token")
-		if token == "" || token != secret {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-			c.Abort()
+		if token == "" && allowQuery {
+			token = c.Query("token")
+		}
+		role, actor := tokenRole(token)
+		if role == "" {
+			authFailed(c, ip, "Unauthorized")
 			return
 		}
 
+		AdminLockout.Reset(ip)
+		c.Set(roleKey, role)
+		c.Set(actorKey, actor)
 		c.Next()
 	}
 }
+
+// authFailed answers 401 and counts the attempt against ip, logging when it tips into a lockout.
+// The attempted token is never logged.
+func authFailed(c *gin.Context, ip, msg string) {
+	Logger(c).Warn("admin auth failed", "client_ip", ip, "path", c.Request.URL.Path, "reason", msg)
+	if AdminLockout.Fail(ip, time.Now()) {
+		Logger(c).Warn("admin auth locked out after repeated failures",
+			"client_ip", ip, "max_failures", AdminLockout.MaxFailures, "cooldown", AdminLockout.Cooldown.String())
+	}
+	Fail(c, http.StatusUnauthorized, models.CodeUnauthorized, msg)
+}
+
+// tokenRole resolves a static token: the admin secret is a full admin, and ADMIN_TOKENS
+// entries carry their own role. Unknown tokens resolve to "". actor names the token for
+// the audit log without giving it away: "admin secret", or "token" and a short fingerprint.
+func tokenRole(token string) (role, actor string) {
+	if token == "" {
+		return "", ""
+	}
+	if adminSecretMatches(token) {
+		return RoleAdmin, "admin secret"
+	}
+
+	// Check every entry rather than indexing the map, so timing doesn't hint at near misses.
+	for t, r := range Auth.Tokens {
+		if equalTokens(token, t) {
+			role = r
+		}
+	}
+	if role == "" {
+		return "", ""
+	}
+	sum := sha256.Sum256([]byte(token))
+	return role, "token " + hex.EncodeToString(sum[:4])
+}
+
+// adminSecretMatches checks token against ADMIN_SECRET_HASH when it is set, so the plaintext
+// never has to be deployed, and against ADMIN_SECRET otherwise.
+func adminSecretMatches(token string) bool {
+	if hash := Auth.SecretHash; hash != "" {
+		if strings.HasPrefix(hash, "$2") {
+			return bcrypt.CompareHashAndPassword([]byte(hash), []byte(token)) == nil
+		}
+		want, err := hex.DecodeString(hash)
+		sum := sha256.Sum256([]byte(token))
+		return err == nil && subtle.ConstantTimeCompare(sum[:], want) == 1
+	}
+
+	return Auth.Secret != "" && equalTokens(token, Auth.Secret)
+}
+
+// equalTokens compares two secrets in constant time. Hashing first keeps the comparison
+// from leaking their lengths too.
+func equalTokens(a, b string) bool {
+	x, y := sha256.Sum256([]byte(a)), sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(x[:], y[:]) == 1
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"testing"
+	"time"
+
+	"miniparty-backend/config"
+	"miniparty-backend/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// useAuth installs a for the test, restoring the previous credentials after it.
+func useAuth(t *testing.T, a config.Auth) {
+	t.Helper()
+	old := Auth
+	if a.MaxFailures == 0 {
+		a.MaxFailures, a.FailureWindow, a.Lockout = 1000, time.Minute, time.Minute
+	}
+	ConfigureAuth(a)
+	t.Cleanup(func() { ConfigureAuth(old) })
+}
+
+func TestMetricsAuth(t *testing.T) {
+	r := newRouter()
+	r.GET("/metrics", MetricsAuth(), ok)
+
+	useAuth(t, config.Auth{Secret: "admin-secret"})
+	if w := send(r, http.MethodGet, "/metrics", "", "X-Admin-Token", "admin-secret"); w.Code != http.StatusOK {
+		t.Errorf("admin token without METRICS_TOKEN = %d, want 200", w.Code)
+	}
+	expectError(t, send(r, http.MethodGet, "/metrics", ""), http.StatusUnauthorized, models.CodeUnauthorized)
+
+	useAuth(t, config.Auth{Secret: "admin-secret", MetricsToken: "scrape"})
+	if w := send(r, http.MethodGet, "/metrics", "", "Authorization", "Bearer scrape"); w.Code != http.StatusOK {
+		t.Errorf("metrics token = %d, want 200", w.Code)
+	}
+	expectError(t, send(r, http.MethodGet, "/metrics", "", "Authorization", "Bearer wrong"), http.StatusUnauthorized, models.CodeUnauthorized)
+	// With a metrics token set, the admin token no longer opens /metrics.
+	expectError(t, send(r, http.MethodGet, "/metrics", "", "X-Admin-Token", "admin-secret"), http.StatusUnauthorized, models.CodeUnauthorized)
+}
+
+func TestAdminSecretHash(t *testing.T) {
+	sum := sha256.Sum256([]byte("admin-secret"))
+	bcrypted, err := bcrypt.GenerateFromPassword([]byte("admin-secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, hash := range []string{hex.EncodeToString(sum[:]), string(bcrypted)} {
+		useAuth(t, config.Auth{SecretHash: hash})
+		for token, want := range map[string]bool{"admin-secret": true, "admin-secre": false, "": false, hash: false} {
+			if got := adminSecretMatches(token); got != want {
+				t.Errorf("hash %.8s: adminSecretMatches(%q) = %v, want %v", hash, token, got, want)
+			}
+		}
+	}
+
+	// With a hash set, the plaintext ADMIN_SECRET is ignored.
+	useAuth(t, config.Auth{Secret: "plain", SecretHash: hex.EncodeToString(sum[:])})
+	if adminSecretMatches("plain") {
+		t.Error("ADMIN_SECRET accepted alongside ADMIN_SECRET_HASH")
+	}
+	useAuth(t, config.Auth{SecretHash: "not hex"})
+	if adminSecretMatches("admin-secret") {
+		t.Error("a malformed hash matched")
+	}
+}
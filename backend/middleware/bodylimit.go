@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rawBodyKey holds the request body as it was before BodyLimit wrapped it.
+const rawBodyKey = "bodylimit.raw"
+
+// BodyLimit caps request bodies at n bytes: reading past the cap fails with an error
+// BodyTooLarge recognises, which the handler turns into a 413. main applies MAX_BODY_BYTES
+// to everything; a route that takes bigger bodies, such as an upload, adds its own BodyLimit,
+// which replaces the default rather than nesting inside it. The cap is only checked as the
+// body is read so that a route's larger limit still applies to a big Content-Length.
+func BodyLimit(n int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw, ok := c.Get(rawBodyKey)
+		if !ok {
+			raw = c.Request.Body
+			c.Set(rawBodyKey, raw)
+		}
+		if body, ok := raw.(io.ReadCloser); ok && body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, body, n)
+		}
+		c.Next()
+	}
+}
+
+// BodyTooLarge reports whether err came from reading past BodyLimit's cap.
+func BodyTooLarge(err error) bool {
+	var tooLarge *http.MaxBytesError
+	return errors.As(err, &tooLarge)
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBodyLimit(t *testing.T) {
+	r := newRouter()
+	r.Use(BodyLimit(10))
+	read := func(c *gin.Context) {
+		data, err := io.ReadAll(c.Request.Body)
+		switch {
+		case BodyTooLarge(err):
+			c.String(http.StatusRequestEntityTooLarge, "too large")
+		case err != nil:
+			c.String(http.StatusBadRequest, err.Error())
+		default:
+			c.String(http.StatusOK, "%d", len(data))
+		}
+	}
+	r.POST("/small", read)
+	// The route's own, larger limit replaces the default rather than nesting inside it.
+	r.POST("/upload", BodyLimit(100), read)
+
+	post := func(target string, n int) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, target, strings.NewReader(strings.Repeat("x", n))))
+		return w
+	}
+	tests := []struct {
+		target string
+		size   int
+		status int
+	}{
+		{"/small", 10, http.StatusOK},
+		{"/small", 11, http.StatusRequestEntityTooLarge},
+		{"/upload", 50, http.StatusOK},
+		{"/upload", 101, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		if w := post(tt.target, tt.size); w.Code != tt.status {
+			t.Errorf("%d bytes to %s = %d %s, want %d", tt.size, tt.target, w.Code, w.Body.String(), tt.status)
+		}
+	}
+}
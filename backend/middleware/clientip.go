@@ -0,0 +1,23 @@
+package middleware
+
+import "github.com/gin-gonic/gin"
+
+// ForwardedForHeader is the only header a trusted proxy may name the client in. X-Real-IP
+// is ignored: Render doesn't set it, so a client could.
+const ForwardedForHeader = "X-Forwarded-For"
+
+const clientIPKey = "client_ip"
+
+// ClientIP is the address of whoever sent the request, for rate limits, spam checks, the
+// audit log and request logs. X-Forwarded-For counts only when the connection comes from
+// one of the engine's trusted proxies, and then only the hops after the last one that
+// isn't trusted, so a client can't spoof it by sending the header itself. It is worked out
+// once per request, so everything that records it agrees.
+func ClientIP(c *gin.Context) string {
+	if ip := c.GetString(clientIPKey); ip != "" {
+		return ip
+	}
+	ip := c.ClientIP()
+	c.Set(clientIPKey, ip)
+	return ip
+}
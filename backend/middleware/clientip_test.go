@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+
+	"miniparty-backend/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestClientIP(t *testing.T) {
+	// Set up as main does, with the default proxies.
+	r := gin.New()
+	r.RemoteIPHeaders = []string{ForwardedForHeader}
+	if err := r.SetTrustedProxies(config.DefaultTrustedProxies); err != nil {
+		t.Fatal(err)
+	}
+	r.GET("/", func(c *gin.Context) {
+		first := ClientIP(c)
+		c.Request.Header.Set(ForwardedForHeader, "198.51.100.99")
+		if again := ClientIP(c); again != first {
+			t.Errorf("ClientIP changed from %s to %s within one request", first, again)
+		}
+		c.String(http.StatusOK, first)
+	})
+
+	tests := []struct {
+		name    string
+		remote  string
+		headers []string
+		want    string
+	}{
+		{"direct", "203.0.113.7", nil, "203.0.113.7"},
+		{"spoofed by the client", "203.0.113.7", []string{ForwardedForHeader, "198.51.100.1"}, "203.0.113.7"},
+		{"through a private proxy", "10.0.0.5", []string{ForwardedForHeader, "198.51.100.1"}, "198.51.100.1"},
+		{"spoof behind the proxy", "10.0.0.5", []string{ForwardedForHeader, "192.0.2.66, 198.51.100.1"}, "198.51.100.1"},
+		{"two trusted hops", "127.0.0.1", []string{ForwardedForHeader, "198.51.100.1, 10.1.2.3"}, "198.51.100.1"},
+		{"X-Real-IP ignored", "10.0.0.5", []string{"X-Real-IP", "198.51.100.1"}, "10.0.0.5"},
+	}
+	for _, tt := range tests {
+		if got := send(r, http.MethodGet, "/", tt.remote, tt.headers...).Body.String(); got != tt.want {
+			t.Errorf("%s: client IP %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
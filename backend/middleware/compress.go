@@ -0,0 +1,192 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// minCompressSize is the smallest body worth compressing; below it gzip's overhead outweighs the saving.
+const minCompressSize = 1024
+
+var gzipWriters = sync.Pool{
+	New: func() any { return gzip.NewWriter(nil) },
+}
+
+// Compress gzips responses for clients that accept it. The first minCompressSize bytes are held
+// back so small bodies and already-compressed content types (images, PDFs, fonts) go out as is.
+func Compress() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead || c.Request.Header.Get("Range") != "" {
+			c.Next()
+			return
+		}
+
+		w := &compressWriter{ResponseWriter: c.Writer, acceptsGzip: acceptsGzip(c.Request.Header.Get("Accept-Encoding"))}
+		c.Writer = w
+		defer w.finish()
+
+		c.Next()
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip, honouring q=0.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "gzip" && name != "*" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// compressible reports whether a response of this content type benefits from gzip.
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// compressWriter buffers the start of a response until it knows whether to gzip it.
+type compressWriter struct {
+	gin.ResponseWriter
+	acceptsGzip bool
+	buf         []byte
+	decided     bool
+	gz          *gzip.Writer
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, data...)
+		if len(w.buf) < minCompressSize {
+			return len(data), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if w.gz != nil {
+		return w.gz.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Written also counts buffered bytes, so handlers don't write a second response on top.
+func (w *compressWriter) Written() bool {
+	return len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+// WriteHeaderNow sends the headers immediately, which rules out compressing the body.
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.decided {
+		w.decide(false)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Flush sends what has been buffered so far; streaming responses are compressed if their type allows.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(true)
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	return w.ResponseWriter.Hijack()
+}
+
+// decide picks plain or gzip output for the rest of the response and writes out the buffer.
+func (w *compressWriter) decide(allowGzip bool) error {
+	w.decided = true
+	header := w.Header()
+	contentType := header.Get("Content-Type")
+	if contentType == "" && len(w.buf) > 0 {
+		contentType = http.DetectContentType(w.buf)
+	}
+
+	if allowGzip && w.acceptsGzip && header.Get("Content-Encoding") == "" && compressible(contentType) {
+		header.Set("Content-Type", contentType)
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		addVary(header)
+
+		w.gz = gzipWriters.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	} else if compressible(contentType) {
+		// Caches must still know the response varies, even if this one went out plain.
+		addVary(header)
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.gz != nil {
+		_, err := w.gz.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// addVary marks the response as varying on Accept-Encoding, unless the handler already did.
+func addVary(header http.Header) {
+	for _, v := range header.Values("Vary") {
+		if strings.Contains(strings.ToLower(v), "accept-encoding") {
+			return
+		}
+	}
+	header.Add("Vary", "Accept-Encoding")
+}
+
+// finish writes out anything still buffered and closes the gzip stream.
+func (w *compressWriter) finish() {
+	if !w.decided {
+		// Too small to be worth compressing.
+		w.decide(false)
+	}
+	if w.gz != nil {
+		w.gz.Close()
+		gzipWriters.Put(w.gz)
+		w.gz = nil
+	}
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	for header, want := range map[string]bool{
+		"":                     false,
+		"gzip":                 true,
+		"deflate, gzip;q=0.5":  true,
+		"GZIP":                 true,
+		"*":                    true,
+		"gzip;q=0":             false,
+		"br, identity":         false,
+		"gzip;q=0, deflate, *": true,
+	} {
+		if got := acceptsGzip(header); got != want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	big := strings.Repeat(`{"name":"Ada Lovelace"},`, 100)
+	r := newRouter()
+	r.Use(Compress())
+	r.GET("/json", func(c *gin.Context) { c.Data(http.StatusOK, "application/json", []byte(big)) })
+	r.GET("/small", func(c *gin.Context) { c.Data(http.StatusOK, "application/json", []byte(`{"ok":true}`)) })
+	r.GET("/png", func(c *gin.Context) { c.Data(http.StatusOK, "image/png", []byte(big)) })
+	r.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/plain")
+		c.Writer.WriteString("first ")
+		c.Writer.Flush()
+		c.Writer.WriteString("second")
+	})
+
+	w := send(r, http.MethodGet, "/json", "", "Accept-Encoding", "gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("large JSON: Content-Encoding %q, Vary %q, want gzip", w.Header().Get("Content-Encoding"), w.Header().Get("Vary"))
+	}
+	if got := gunzip(t, w.Body); got != big || w.Body.Len() >= len(big) {
+		t.Errorf("large JSON decompressed to %d bytes from %d, want the %d byte body, smaller", len(got), w.Body.Len(), len(big))
+	}
+
+	tests := []struct {
+		target, encoding string
+		vary             bool
+	}{
+		{"/json", "", true},
+		{"/json", "gzip;q=0", true},
+		{"/small", "gzip", true},
+		{"/png", "gzip", false},
+	}
+	for _, tt := range tests {
+		w := send(r, http.MethodGet, tt.target, "", "Accept-Encoding", tt.encoding)
+		if w.Header().Get("Content-Encoding") != "" || w.Code != http.StatusOK {
+			t.Errorf("%s with %q: %d, Content-Encoding %q, want it plain", tt.target, tt.encoding, w.Code, w.Header().Get("Content-Encoding"))
+		}
+		if got := w.Header().Get("Vary") != ""; got != tt.vary {
+			t.Errorf("%s with %q: Vary %q, want set %v", tt.target, tt.encoding, w.Header().Get("Vary"), tt.vary)
+		}
+	}
+	if w := send(r, http.MethodHead, "/json", "", "Accept-Encoding", "gzip"); w.Header().Get("Content-Encoding") != "" {
+		t.Error("HEAD response marked gzip")
+	}
+	if w := send(r, http.MethodGet, "/json", "", "Accept-Encoding", "gzip", "Range", "bytes=0-9"); w.Header().Get("Content-Encoding") != "" {
+		t.Error("range request gzipped")
+	}
+
+	// A flush decides early, so a stream is compressed from its first chunk.
+	w = send(r, http.MethodGet, "/stream", "", "Accept-Encoding", "gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" || gunzip(t, w.Body) != "first second" {
+		t.Errorf("flushed stream: Content-Encoding %q", w.Header().Get("Content-Encoding"))
+	}
+}
+
+// gunzip decompresses body, failing the test if it isn't gzip.
+func gunzip(t *testing.T, body io.Reader) string {
+	t.Helper()
+	zr, err := gzip.NewReader(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+
+	"miniparty-backend/config"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// CORS lets browsers on origins call the API with the methods and headers the admin
+// dashboard and booking form use. Origins are matched per request, so one wildcard entry
+// covers every Vercel preview deployment.
+func CORS(origins []config.Origin) gin.HandlerFunc {
+	return cors.New(cors.Config{
+		AllowOriginFunc: AllowOrigin(origins),
+		AllowMethods: []string{
+			http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+		},
+		AllowHeaders: []string{
+			"Content-Type", "Authorization", "Accept-Language", "X-Admin-Token", RequestIDHeader, IdempotencyKeyHeader,
+		},
+		ExposeHeaders:    []string{RequestIDHeader, "Retry-After"},
+		AllowCredentials: true,
+	})
+}
+
+// AllowOrigin reports whether an Origin header matches any of origins.
+func AllowOrigin(origins []config.Origin) func(string) bool {
+	return func(origin string) bool {
+		for _, o := range origins {
+			if o.Match(origin) {
+				return true
+			}
+		}
+		return false
+	}
+}
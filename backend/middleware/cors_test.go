@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+
+	"miniparty-backend/config"
+)
+
+func TestCORS(t *testing.T) {
+	var origins []config.Origin
+	for _, s := range []string{"https://party.example.com", "https://*.vercel.app"} {
+		o, err := config.ParseOrigin(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		origins = append(origins, o)
+	}
+	r := newRouter()
+	r.Use(CORS(origins))
+	r.GET("/bookings", ok)
+
+	for origin, allowed := range map[string]bool{
+		"https://party.example.com":           true,
+		"https://mini-party-pr-12.vercel.app": true,
+		"https://vercel.app":                  false,
+		"https://evil.example.com":            false,
+	} {
+		w := send(r, http.MethodOptions, "/bookings", "", "Origin", origin,
+			"Access-Control-Request-Method", http.MethodGet, "Access-Control-Request-Headers", "X-Admin-Token")
+		got := w.Header().Get("Access-Control-Allow-Origin")
+		if allowed && (got != origin || w.Header().Get("Access-Control-Allow-Credentials") != "true") {
+			t.Errorf("preflight from %s: allow origin %q, want it echoed with credentials", origin, got)
+		}
+		if !allowed && got != "" {
+			t.Errorf("preflight from %s allowed as %q", origin, got)
+		}
+	}
+
+	w := send(r, http.MethodGet, "/bookings", "", "Origin", "https://party.example.com")
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Expose-Headers") == "" {
+		t.Errorf("GET: %d, exposed %q", w.Code, w.Header().Get("Access-Control-Expose-Headers"))
+	}
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDeprecated(t *testing.T) {
+	r := newRouter()
+	r.GET("/bookings/:id", Deprecated("/api/v1"), ok)
+	r.GET("/api/v1/bookings/:id", ok)
+
+	w := send(r, http.MethodGet, "/bookings/7?token=x", "")
+	if w.Code != http.StatusOK || w.Header().Get("Deprecation") != "true" ||
+		w.Header().Get("Link") != `</api/v1/bookings/7>; rel="successor-version"` {
+		t.Errorf("old route = %d, Deprecation %q, Link %q", w.Code, w.Header().Get("Deprecation"), w.Header().Get("Link"))
+	}
+	if w := send(r, http.MethodGet, "/api/v1/bookings/7", ""); w.Header().Get("Deprecation") != "" || w.Header().Get("Link") != "" {
+		t.Errorf("the current route is marked deprecated: %v", w.Header())
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import "github.com/gin-gonic/gin"
+
+// ErrorBody is the error envelope for c, as models.ErrorResponse lays it out, with code
+// and message. It is a map so that callers can add details, such as the conflicting times
+// of a taken slot, next to it.
+func ErrorBody(c *gin.Context, code, message string) gin.H {
+	body := gin.H{"code": code, "message": message, "error": message}
+	if id := RequestID(c); id != "" {
+		body["request_id"] = id
+	}
+	return body
+}
+
+// Fail aborts c with status and an ErrorBody of code and message.
+func Fail(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, ErrorBody(c, code, message))
+}
+
+// FailWith aborts c like Fail, with details added to the body.
+func FailWith(c *gin.Context, status int, code, message string, details gin.H) {
+	body := ErrorBody(c, code, message)
+	for k, v := range details {
+		body[k] = v
+	}
+	c.AbortWithStatusJSON(status, body)
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"miniparty-backend/models"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	maxIdempotencyKey    = 255
+
+	// idempotencyPoll is how often a retry re-checks a key whose first request is still running.
+	idempotencyPoll = 100 * time.Millisecond
+)
+
+// Idempotency makes a handler safe to retry. When a request carries an Idempotency-Key,
+// the first successful (201, or 202 for a waitlisted booking) response is stored and
+// replayed for the same key and body; the same key with a different body gets 422.
+// Requests without the header pass through.
+func Idempotency(keys store.IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := strings.TrimSpace(c.GetHeader(IdempotencyKeyHeader))
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKey {
+			Fail(c, http.StatusBadRequest, models.CodeBadRequest, "Idempotency-Key must be at most 255 characters")
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if BodyTooLarge(err) {
+			Fail(c, http.StatusRequestEntityTooLarge, models.CodeTooLarge, "Request body too large")
+			return
+		}
+		if err != nil {
+			Fail(c, http.StatusBadRequest, models.CodeBadRequest, "Invalid request body")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(body)
+		hash := hex.EncodeToString(sum[:])
+
+		stored, err := claimKey(c, keys, key, hash)
+		switch {
+		case errors.Is(err, store.ErrKeyMismatch):
+			Fail(c, http.StatusUnprocessableEntity, models.CodeIdempotencyReuse, "This Idempotency-Key was already used with a different request")
+			return
+		case errors.Is(err, store.ErrKeyInFlight):
+			Fail(c, http.StatusConflict, models.CodeIdempotencyBusy, "A request with this Idempotency-Key is still being processed")
+			return
+		case err != nil:
+			Logger(c).Error("idempotency key lookup failed", "error", err)
+			Fail(c, http.StatusInternalServerError, models.CodeInternal, "Failed to check Idempotency-Key")
+			return
+		case stored != nil:
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(stored.Status, "application/json; charset=utf-8", stored.Response)
+			c.Abort()
+			return
+		}
+
+		w := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+
+		// The request context may have timed out by now; the claim still has to be settled.
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), 5*time.Second)
+		defer cancel()
+		if w.Status() == http.StatusCreated || w.Status() == http.StatusAccepted {
+			err = keys.CompleteKey(ctx, key, w.Status(), w.body.Bytes())
+		} else {
+			err = keys.ReleaseKey(ctx, key)
+		}
+		if err != nil {
+			Logger(c).Error("failed to record idempotency key", "error", err)
+		}
+	}
+}
+
+// claimKey claims key, waiting for a concurrent request holding it to finish until the
+// request's own context runs out.
+func claimKey(c *gin.Context, keys store.IdempotencyStore, key, hash string) (*models.IdempotencyKey, error) {
+	ctx := c.Request.Context()
+	for {
+		stored, err := keys.ClaimKey(ctx, key, hash, time.Now())
+		if !errors.Is(err, store.ErrKeyInFlight) {
+			return stored, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(idempotencyPoll):
+		}
+	}
+}
+
+// recordingWriter keeps a copy of the response body as it is written.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"miniparty-backend/models"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// keyStore is an IdempotencyStore in a map. busy makes every claim find the key in flight.
+type keyStore struct {
+	mu   sync.Mutex
+	keys map[string]*models.IdempotencyKey
+	busy bool
+}
+
+func (s *keyStore) ClaimKey(_ context.Context, key, hash string, _ time.Time) (*models.IdempotencyKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.keys[key]
+	switch {
+	case s.busy:
+		return nil, store.ErrKeyInFlight
+	case !ok:
+		s.keys[key] = &models.IdempotencyKey{Key: key, RequestHash: hash}
+		return nil, nil
+	case existing.RequestHash != hash:
+		return nil, store.ErrKeyMismatch
+	case existing.Status == 0:
+		return nil, store.ErrKeyInFlight
+	}
+	return existing, nil
+}
+
+func (s *keyStore) CompleteKey(_ context.Context, key string, status int, response []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[key].Status, s.keys[key].Response = status, response
+	return nil
+}
+
+func (s *keyStore) ReleaseKey(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, key)
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	keys := &keyStore{keys: map[string]*models.IdempotencyKey{}}
+	calls := 0
+	r := newRouter()
+	r.POST("/book", Idempotency(keys), func(c *gin.Context) {
+		calls++
+		if strings.Contains(readBody(c), "invalid") {
+			Fail(c, http.StatusBadRequest, models.CodeValidation, "invalid")
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"booking": calls})
+	})
+	post := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/book", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := post("k1", `{"name":"Ada"}`)
+	again := post("k1", `{"name":"Ada"}`)
+	if first.Code != http.StatusCreated || again.Code != http.StatusCreated || again.Body.String() != first.Body.String() ||
+		again.Header().Get("Idempotent-Replayed") != "true" || calls != 1 {
+		t.Fatalf("retry = %d %s (replayed %q) after %d %s, %d calls, want the first response replayed",
+			again.Code, again.Body.String(), again.Header().Get("Idempotent-Replayed"), first.Code, first.Body.String(), calls)
+	}
+	expectError(t, post("k1", `{"name":"Grace"}`), http.StatusUnprocessableEntity, models.CodeIdempotencyReuse)
+
+	// A failed request releases its key, so the corrected retry goes through.
+	expectError(t, post("k2", `{"name":"invalid"}`), http.StatusBadRequest, models.CodeValidation)
+	expectError(t, post("k2", `{"name":"invalid"}`), http.StatusBadRequest, models.CodeValidation)
+	if calls != 3 {
+		t.Errorf("%d calls, want the failed request run again", calls)
+	}
+
+	for i := 0; i < 2; i++ {
+		post("", `{"name":"Ada"}`)
+	}
+	if calls != 5 {
+		t.Errorf("%d calls, want requests without a key to run each time", calls)
+	}
+	expectError(t, post(strings.Repeat("k", maxIdempotencyKey+1), "{}"), http.StatusBadRequest, models.CodeBadRequest)
+
+	// A key still held by another request is waited on until this one's context runs out.
+	keys.busy = true
+	ctx, cancel := context.WithTimeout(context.Background(), 2*idempotencyPoll)
+	defer cancel()
+	req := httptest.NewRequest(http.MethodPost, "/book", bytes.NewReader([]byte("{}"))).WithContext(ctx)
+	req.Header.Set(IdempotencyKeyHeader, "k3")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	expectError(t, w, http.StatusConflict, models.CodeIdempotencyBusy)
+}
+
+// readBody returns the request body, which Idempotency must leave for the handler.
+func readBody(c *gin.Context) string {
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(c.Request.Body); err != nil {
+		return fmt.Sprint(err)
+	}
+	return buf.String()
+}
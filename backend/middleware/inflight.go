@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Inflight keeps track of requests that are currently being handled,
+// so shutdown can report what was cut off if it times out.
+type Inflight struct {
+	mu     sync.Mutex
+	nextID uint64
+	active map[uint64]string
+}
+
+func NewInflight() *Inflight {
+	return &Inflight{active: map[uint64]string{}}
+}
+
+// Track registers each request for the duration of its handler.
+func (t *Inflight) Track() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		desc := fmt.Sprintf("%s %s (request_id=%s, started %s)",
+			c.Request.Method, c.Request.URL.Path, RequestID(c), time.Now().Format(time.RFC3339))
+
+		t.mu.Lock()
+		t.nextID++
+		id := t.nextID
+		t.active[id] = desc
+		t.mu.Unlock()
+
+		defer func() {
+			t.mu.Lock()
+			delete(t.active, id)
+			t.mu.Unlock()
+		}()
+
+		c.Next()
+	}
+}
+
+// Active describes the requests still in progress.
+func (t *Inflight) Active() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make([]string, 0, len(t.active))
+	for _, desc := range t.active {
+		out = append(out, desc)
+	}
+	return out
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestInflightTracksRequests(t *testing.T) {
+	inflight := NewInflight()
+	started, release := make(chan struct{}), make(chan struct{})
+	r := newRouter()
+	r.Use(inflight.Track())
+	r.POST("/book", func(c *gin.Context) {
+		close(started)
+		<-release
+		c.Status(http.StatusCreated)
+	})
+
+	done := make(chan int)
+	go func() { done <- send(r, http.MethodPost, "/book", "").Code }()
+	<-started
+	active := inflight.Active()
+	if len(active) != 1 || !strings.HasPrefix(active[0], "POST /book (request_id=") {
+		t.Errorf("while handling: active %q, want the POST", active)
+	}
+	close(release)
+	if code := <-done; code != http.StatusCreated {
+		t.Errorf("status %d, want 201", code)
+	}
+	if active := inflight.Active(); len(active) != 0 {
+		t.Errorf("after the response: active %q, want none", active)
+	}
+}
+
+func TestShutdownWaitsForInflight(t *testing.T) {
+	inflight := NewInflight()
+	started := make(chan struct{})
+	r := newRouter()
+	r.Use(inflight.Track())
+	r.POST("/book", func(c *gin.Context) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		c.Status(http.StatusCreated)
+	})
+	srv := httptest.NewServer(r)
+
+	done := make(chan error, 1)
+	go func() {
+		resp, err := http.Post(srv.URL+"/book", "application/json", nil)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusCreated {
+				err = fmt.Errorf("status %d", resp.StatusCode)
+			}
+		}
+		done <- err
+	}()
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Config.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v, still active %q", err, inflight.Active())
+	}
+	if err := <-done; err != nil {
+		t.Errorf("the request in flight at shutdown failed: %v", err)
+	}
+	if active := inflight.Active(); len(active) != 0 {
+		t.Errorf("active after shutdown: %q", active)
+	}
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+
+	"miniparty-backend/config"
+)
+
+// Lockout counts failed admin authentications per client IP. After MaxFailures within
+// Window, the IP is refused for Cooldown. State is kept in memory.
+type Lockout struct {
+	MaxFailures int
+	Window      time.Duration
+	Cooldown    time.Duration
+
+	mu        sync.Mutex
+	clients   map[string]*failures
+	lastSweep time.Time
+}
+
+type failures struct {
+	count       int
+	first       time.Time
+	lockedUntil time.Time
+}
+
+func NewLockout(maxFailures int, window, cooldown time.Duration) *Lockout {
+	return &Lockout{
+		MaxFailures: maxFailures,
+		Window:      window,
+		Cooldown:    cooldown,
+		clients:     map[string]*failures{},
+	}
+}
+
+// AdminLockout is shared by every admin route, so failures on one count towards all of them.
+// ConfigureAuth replaces it with one built from AUTH_MAX_FAILURES, AUTH_FAILURE_WINDOW and AUTH_LOCKOUT.
+var AdminLockout = NewLockout(config.DefaultMaxFailures, config.DefaultFailureWindow, config.DefaultLockout)
+
+// Locked reports whether ip is locked out, and for how much longer.
+func (l *Lockout) Locked(ip string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if f, ok := l.clients[ip]; ok && now.Before(f.lockedUntil) {
+		return true, f.lockedUntil.Sub(now)
+	}
+	return false, 0
+}
+
+// Fail records a failed attempt from ip and reports whether it has just been locked out.
+func (l *Lockout) Fail(ip string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	f, ok := l.clients[ip]
+	if !ok || now.Sub(f.first) > l.Window {
+		f = &failures{first: now}
+		l.clients[ip] = f
+	}
+	f.count++
+	if f.count < l.MaxFailures {
+		return false
+	}
+
+	f.count = 0
+	f.first = now
+	f.lockedUntil = now.Add(l.Cooldown)
+	return true
+}
+
+// Reset clears ip's failures after a successful authentication.
+func (l *Lockout) Reset(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.clients, ip)
+}
+
+// sweep drops entries whose window and lockout have both passed. It runs at most once per
+// Window, from Fail, so the map only needs cleaning while failures are coming in.
+func (l *Lockout) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.Window {
+		return
+	}
+	l.lastSweep = now
+
+	for ip, f := range l.clients {
+		if now.Sub(f.first) > l.Window && !now.Before(f.lockedUntil) {
+			delete(l.clients, ip)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"miniparty-backend/config"
+	"miniparty-backend/models"
+)
+
+func TestLockout(t *testing.T) {
+	l := NewLockout(3, time.Minute, 5*time.Minute)
+	start := time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)
+
+	if l.Fail("a", start) || l.Fail("a", start.Add(time.Second)) {
+		t.Fatal("locked out before the third failure")
+	}
+	if !l.Fail("a", start.Add(2*time.Second)) {
+		t.Fatal("not locked out on the third failure")
+	}
+	if locked, wait := l.Locked("a", start.Add(time.Minute)); !locked || wait != 5*time.Minute-time.Minute+2*time.Second {
+		t.Errorf("a minute in: locked %v for %v", locked, wait)
+	}
+	if locked, _ := l.Locked("b", start); locked {
+		t.Error("another IP is locked out")
+	}
+	if locked, _ := l.Locked("a", start.Add(6*time.Minute)); locked {
+		t.Error("still locked after the cooldown")
+	}
+
+	// Failures spread wider than the window never add up to a lockout.
+	for i := 0; i < 5; i++ {
+		if l.Fail("c", start.Add(time.Duration(i)*61*time.Second)) {
+			t.Fatalf("failure %d, a window after the last, locked out", i+1)
+		}
+	}
+	l.Fail("d", start)
+	l.Fail("d", start)
+	l.Reset("d")
+	if l.Fail("d", start) {
+		t.Error("failures before a successful login still counted")
+	}
+
+	l.sweep(start.Add(time.Hour))
+	if len(l.clients) != 0 {
+		t.Errorf("%d clients left after the sweep, want none", len(l.clients))
+	}
+}
+
+func TestAdminAuthLockout(t *testing.T) {
+	useAuth(t, config.Auth{Secret: "admin-secret", MaxFailures: 2, FailureWindow: time.Minute, Lockout: time.Minute})
+	r := newRouter()
+	r.GET("/admin", AdminAuth(), ok)
+
+	for i := 0; i < 2; i++ {
+		expectError(t, send(r, http.MethodGet, "/admin", "192.0.2.1", "X-Admin-Token", "guess"), http.StatusUnauthorized, models.CodeUnauthorized)
+	}
+	// Locked out, even the right token is refused until the cooldown ends.
+	w := send(r, http.MethodGet, "/admin", "192.0.2.1", "X-Admin-Token", "admin-secret")
+	expectError(t, w, http.StatusTooManyRequests, models.CodeRateLimited)
+	if after, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || after < 59 || after > 60 {
+		t.Errorf("Retry-After %q, want the minute's lockout", w.Header().Get("Retry-After"))
+	}
+	if w := send(r, http.MethodGet, "/admin", "192.0.2.2", "X-Admin-Token", "admin-secret"); w.Code != http.StatusOK {
+		t.Errorf("another IP = %d, want 200", w.Code)
+	}
+}
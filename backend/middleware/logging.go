@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the request ID in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+const (
+	requestIDKey = "request_id"
+	loggerKey    = "logger"
+)
+
+// maxRequestIDLength caps propagated IDs so a client can't stuff arbitrary data into the logs.
+const maxRequestIDLength = 64
+
+// NewLogger builds the process logger: JSON in release mode, human-readable text otherwise.
+// It logs at level and above.
+func NewLogger(level slog.Level) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+
+	if gin.Mode() == gin.ReleaseMode {
+		return slog.New(slog.NewJSONHandler(os.Stdout, opts))
+	}
+	return slog.New(slog.NewTextHandler(os.Stdout, opts))
+}
+
+// RequestLogger assigns each request an ID (reusing a sane X-Request-ID from the client),
+// echoes it in the response, exposes a request-scoped logger via Logger, and logs one line per request.
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		id := strings.TrimSpace(c.GetHeader(RequestIDHeader))
+		if id == "" || len(id) > maxRequestIDLength {
+			id = newRequestID()
+		}
+		logger := slog.Default().With("request_id", id)
+		c.Set(requestIDKey, id)
+		c.Set(loggerKey, logger)
+		c.Header(RequestIDHeader, id)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		}
+		logger.Log(c.Request.Context(), level, "request",
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", status,
+			"latency_ms", time.Since(start).Milliseconds(),
+			"client_ip", ClientIP(c),
+		)
+	}
+}
+
+// Logger returns the request-scoped logger, or the default logger outside RequestLogger.
+func Logger(c *gin.Context) *slog.Logger {
+	if logger, ok := c.Get(loggerKey); ok {
+		return logger.(*slog.Logger)
+	}
+	return slog.Default()
+}
+
+// RequestID returns the ID assigned by RequestLogger, or "" if none was.
+func RequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
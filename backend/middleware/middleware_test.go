@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+// newRouter is a bare engine with the request logger, which sets the request IDs errors carry.
+func newRouter() *gin.Engine {
+	r := gin.New()
+	r.Use(RequestLogger())
+	return r
+}
+
+// ok answers 200 "ok", as the handler behind the middleware under test.
+func ok(c *gin.Context) { c.String(http.StatusOK, "ok") }
+
+// send serves a request for method and target to h from the client at ip, with headers as
+// name, value pairs.
+func send(h http.Handler, method, target, ip string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	if ip != "" {
+		req.RemoteAddr = ip + ":40000"
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+// expectError fails the test unless w has status and an error envelope with code.
+func expectError(t *testing.T, w *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != status || body["code"] != code ||
+		body["message"] == "" || body["request_id"] == nil {
+		t.Fatalf("got %d %s, want %d with code %s", w.Code, w.Body.String(), status, code)
+	}
+}
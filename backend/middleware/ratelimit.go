@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"miniparty-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitBurst is how many requests a client may make back to back before the per-minute rate applies.
+const rateLimitBurst = 3
+
+// staleAfter is how long an idle client's bucket is kept before cleanup drops it.
+const staleAfter = 10 * time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	rate    float64 // tokens per second
+	burst   float64
+}
+
+// allow takes a token from key's bucket. When the bucket is empty it returns false
+// and how long until the next token is available.
+func (l *limiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// cleanup drops buckets that have been idle long enough to have refilled.
+func (l *limiter) cleanup(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > staleAfter {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// RateLimit throttles requests per client IP with a token bucket refilled at rpm requests
+// per minute. Each call keeps its own buckets, in memory.
+func RateLimit(rpm int) gin.HandlerFunc {
+	l := &limiter{
+		buckets: map[string]*bucket{},
+		rate:    float64(rpm) / 60,
+		burst:   rateLimitBurst,
+	}
+
+	go func() {
+		for range time.Tick(staleAfter) {
+			l.cleanup(time.Now())
+		}
+	}()
+
+	return func(c *gin.Context) {
+		ok, wait := l.allow(ClientIP(c), time.Now())
+		if !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			Fail(c, http.StatusTooManyRequests, models.CodeRateLimited, "Too many requests, please try again later")
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"miniparty-backend/models"
+)
+
+func TestLimiterRefills(t *testing.T) {
+	l := &limiter{buckets: map[string]*bucket{}, rate: 1.0 / 60, burst: rateLimitBurst}
+	start := time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < rateLimitBurst; i++ {
+		if ok, _ := l.allow("a", start); !ok {
+			t.Fatalf("request %d of the burst refused", i+1)
+		}
+	}
+	ok, wait := l.allow("a", start)
+	if ok || wait != time.Minute {
+		t.Errorf("past the burst: allowed %v, wait %v, want refused for a minute", ok, wait)
+	}
+	if ok, _ := l.allow("b", start); !ok {
+		t.Error("another client was refused")
+	}
+	if ok, _ := l.allow("a", start.Add(59*time.Second)); ok {
+		t.Error("allowed before a token was back")
+	}
+	if ok, _ := l.allow("a", start.Add(2*time.Minute)); !ok {
+		t.Error("refused after the bucket refilled")
+	}
+
+	l.cleanup(start.Add(2*time.Minute + staleAfter + time.Second))
+	if len(l.buckets) != 0 {
+		t.Errorf("%d buckets left after cleanup, want none", len(l.buckets))
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	r := newRouter()
+	r.POST("/book", RateLimit(6), ok)
+
+	for i := 0; i < rateLimitBurst; i++ {
+		if w := send(r, http.MethodPost, "/book", "192.0.2.1"); w.Code != http.StatusOK {
+			t.Fatalf("request %d = %d, want 200", i+1, w.Code)
+		}
+	}
+	w := send(r, http.MethodPost, "/book", "192.0.2.1")
+	expectError(t, w, http.StatusTooManyRequests, models.CodeRateLimited)
+	if after, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || after < 1 || after > 10 {
+		t.Errorf("Retry-After %q, want the seconds until the next of 6 a minute", w.Header().Get("Retry-After"))
+	}
+	if w := send(r, http.MethodPost, "/book", "192.0.2.2"); w.Code != http.StatusOK {
+		t.Errorf("another IP = %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	"miniparty-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// retryAfterSeconds is how long clients are asked to wait while the server is starting.
+const retryAfterSeconds = "5"
+
+// Readiness rejects requests with 503 until ready reports true.
+func Readiness(ready func() bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !ready() {
+			c.Header("Retry-After", retryAfterSeconds)
+			Fail(c, http.StatusServiceUnavailable, models.CodeNotReady, "Server is starting up, please try again shortly")
+			return
+		}
+
+		c.Next()
+	}
+}
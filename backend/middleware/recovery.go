@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"miniparty-backend/errreport"
+	"miniparty-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery turns a panic in a handler into the usual 500 envelope, with the request ID
+// so a customer's report can be matched to the log, and logs it with its stack. Errors
+// handlers attach with c.Error, which serverError does for every 500, are logged by their
+// handlers; Recovery passes both to reporter. It goes after RequestLogger, whose logger
+// and request ID it uses.
+func Recovery(reporter errreport.Reporter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			// A handler gave up on a response deliberately; net/http knows what to do.
+			if err, ok := p.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(p)
+			}
+			stack := string(debug.Stack())
+			message := fmt.Sprint(p)
+			Logger(c).Error("panic", "panic", message, "method", c.Request.Method, "path", c.Request.URL.Path, "stack", stack)
+			report(c, reporter, errreport.LevelFatal, message, stack)
+
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			Fail(c, http.StatusInternalServerError, models.CodeInternal, "Something went wrong on our side. Please try again.")
+		}()
+
+		c.Next()
+
+		for _, err := range c.Errors {
+			report(c, reporter, errreport.LevelError, err.Error(), "")
+		}
+	}
+}
+
+func report(c *gin.Context, reporter errreport.Reporter, level, message, stack string) {
+	_ = reporter.Report(c.Request.Context(), errreport.Event{
+		Level:     level,
+		Message:   message,
+		Stack:     stack,
+		RequestID: RequestID(c),
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		Time:      time.Now(),
+	})
+}
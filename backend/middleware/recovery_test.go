@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"miniparty-backend/errreport"
+	"miniparty-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// reported collects the events Recovery hands it.
+type reported []errreport.Event
+
+func (r *reported) Report(_ context.Context, e errreport.Event) error {
+	*r = append(*r, e)
+	return nil
+}
+
+func TestRecovery(t *testing.T) {
+	var events reported
+	r := newRouter()
+	r.Use(Recovery(&events))
+	r.GET("/panic", func(c *gin.Context) { panic("nil booking") })
+	r.GET("/late-panic", func(c *gin.Context) {
+		c.String(http.StatusOK, "partial")
+		panic("after writing")
+	})
+	r.GET("/error", func(c *gin.Context) {
+		_ = c.Error(errors.New("Failed to save booking: disk full"))
+		Fail(c, http.StatusInternalServerError, models.CodeInternal, "Failed to save booking")
+	})
+	r.GET("/ok", ok)
+
+	w := send(r, http.MethodGet, "/panic", "")
+	expectError(t, w, http.StatusInternalServerError, models.CodeInternal)
+	if len(events) != 1 {
+		t.Fatalf("%d events after a panic, want 1", len(events))
+	}
+	e := events[0]
+	if e.Level != errreport.LevelFatal || e.Message != "nil booking" || !strings.Contains(e.Stack, "recovery_test.go") ||
+		e.RequestID != w.Header().Get(RequestIDHeader) || e.Method != http.MethodGet || e.Path != "/panic" || e.Time.IsZero() {
+		t.Errorf("panic reported as %+v", e)
+	}
+
+	// The status line has gone out; all that can be done is stop.
+	if w := send(r, http.MethodGet, "/late-panic", ""); w.Code != http.StatusOK || w.Body.String() != "partial" {
+		t.Errorf("panic after writing: %d %q, want the partial response left alone", w.Code, w.Body.String())
+	}
+	expectError(t, send(r, http.MethodGet, "/error", ""), http.StatusInternalServerError, models.CodeInternal)
+	if last := events[len(events)-1]; len(events) != 3 || last.Level != errreport.LevelError || last.Message != "Failed to save booking: disk full" || last.Stack != "" {
+		t.Errorf("events %+v, want the handler's error reported last without a stack", events)
+	}
+
+	send(r, http.MethodGet, "/ok", "")
+	if len(events) != 3 {
+		t.Errorf("%d events after a request that went fine, want still 3", len(events))
+	}
+}
+
+func TestRecoveryLeavesAbortHandler(t *testing.T) {
+	var events reported
+	r := newRouter()
+	r.Use(Recovery(&events))
+	r.GET("/abort", func(c *gin.Context) { panic(http.ErrAbortHandler) })
+
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler passed on", p)
+		}
+		if len(events) != 0 {
+			t.Errorf("reported %+v for an aborted handler", events)
+		}
+	}()
+	send(r, http.MethodGet, "/abort", "")
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+
+	"miniparty-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Admin roles, from least to most privileged. Viewers can read bookings; admins can also change them.
+const (
+	RoleViewer = "viewer"
+	RoleAdmin  = "admin"
+)
+
+var roleRank = map[string]int{RoleViewer: 1, RoleAdmin: 2}
+
+const (
+	roleKey  = "admin_role"
+	actorKey = "admin_actor"
+)
+
+// Role returns the role AdminAuth resolved for the request, or "" if it didn't run.
+func Role(c *gin.Context) string {
+	return c.GetString(roleKey)
+}
+
+// Actor names the credential AdminAuth accepted, for the audit log, or "" if it didn't run.
+func Actor(c *gin.Context) string {
+	return c.GetString(actorKey)
+}
+
+// HasRole reports whether the request's admin role is at least role.
+func HasRole(c *gin.Context, role string) bool {
+	return roleRank[Role(c)] >= roleRank[role]
+}
+
+// RequireRole rejects requests whose admin role is below role with 403. It must run after AdminAuth.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasRole(c, role) {
+			Fail(c, http.StatusForbidden, models.CodeForbidden, "Your access does not allow this action")
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"miniparty-backend/config"
+	"miniparty-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRoles(t *testing.T) {
+	useAuth(t, config.Auth{Secret: "admin-secret", Tokens: map[string]string{"viewer-token": RoleViewer, "ops-token": RoleAdmin}})
+	var role, actor string
+	r := newRouter()
+	remember := func(c *gin.Context) { role, actor = Role(c), Actor(c) }
+	r.GET("/read", AdminAuth(), remember, ok)
+	r.POST("/write", AdminAuth(), RequireRole(RoleAdmin), remember, ok)
+
+	tests := []struct {
+		token, role, actor string
+	}{
+		{"admin-secret", RoleAdmin, "admin secret"},
+		{"ops-token", RoleAdmin, "token "},
+		{"viewer-token", RoleViewer, "token "},
+	}
+	for _, tt := range tests {
+		role, actor = "", ""
+		if w := send(r, http.MethodGet, "/read", "", "X-Admin-Token", tt.token); w.Code != http.StatusOK || role != tt.role || !strings.HasPrefix(actor, tt.actor) {
+			t.Errorf("%s: %d as %q by %q, want %s", tt.token, w.Code, role, actor, tt.role)
+		}
+		if strings.Contains(actor, tt.token) {
+			t.Errorf("%s: the audit actor %q gives the token away", tt.token, actor)
+		}
+		w := send(r, http.MethodPost, "/write", "", "X-Admin-Token", tt.token)
+		if tt.role == RoleViewer {
+			expectError(t, w, http.StatusForbidden, models.CodeForbidden)
+		} else if w.Code != http.StatusOK {
+			t.Errorf("%s writing = %d, want 200", tt.token, w.Code)
+		}
+	}
+	expectError(t, send(r, http.MethodGet, "/read", "", "X-Admin-Token", "viewer-token2"), http.StatusUnauthorized, models.CodeUnauthorized)
+}
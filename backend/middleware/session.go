@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SessionTTL is how long an admin session token stays valid after login or refresh.
+const SessionTTL = 12 * time.Hour
+
+// sessionSubject identifies admin session tokens, so a JWT_SECRET shared with other
+// services can't be used to mint tokens for this one.
+const sessionSubject = "miniparty-admin"
+
+// sessionClaims are the JWT claims of an admin session.
+type sessionClaims struct {
+	Role string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+var (
+	ErrSessionsDisabled = errors.New("JWT_SECRET is not set")
+	errSessionExpired   = errors.New("session expired")
+	errSessionInvalid   = errors.New("invalid session token")
+)
+
+// NewSession signs a session token for role that expires SessionTTL from now.
+func NewSession(now time.Time, role string) (token string, expiresAt time.Time, err error) {
+	secret := Auth.JWTSecret
+	if secret == "" {
+		return "", time.Time{}, ErrSessionsDisabled
+	}
+
+	expiresAt = now.Add(SessionTTL)
+	claims := sessionClaims{
+		Role: role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   sessionSubject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	token, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	return token, expiresAt, err
+}
+
+// verifySession checks a session token's signature and expiry and returns its role.
+func verifySession(token string) (string, error) {
+	secret := Auth.JWTSecret
+	if secret == "" {
+		return "", ErrSessionsDisabled
+	}
+
+	var claims sessionClaims
+	parsed, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (any, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired(), jwt.WithSubject(sessionSubject))
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return "", errSessionExpired
+	case err != nil || !parsed.Valid:
+		return "", errSessionInvalid
+	}
+	if _, ok := roleRank[claims.Role]; !ok {
+		return "", errSessionInvalid
+	}
+	return claims.Role, nil
+}
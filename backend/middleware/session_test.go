@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"miniparty-backend/config"
+	"miniparty-backend/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signed signs claims with secret and method, for tokens NewSession would never issue.
+func signed(t *testing.T, method jwt.SigningMethod, secret any, claims sessionClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestVerifySession(t *testing.T) {
+	useAuth(t, config.Auth{JWTSecret: "jwt-secret"})
+	now := time.Now()
+	token, expiresAt, err := NewSession(now, RoleViewer)
+	if err != nil || !expiresAt.Equal(now.Add(SessionTTL)) {
+		t.Fatalf("NewSession = %v, expires %v, want %v", err, expiresAt, now.Add(SessionTTL))
+	}
+	if role, err := verifySession(token); err != nil || role != RoleViewer {
+		t.Errorf("verifySession = %q, %v, want viewer", role, err)
+	}
+
+	expired, _, _ := NewSession(now.Add(-SessionTTL-time.Minute), RoleAdmin)
+	claims := func(subject, role string) sessionClaims {
+		return sessionClaims{Role: role, RegisteredClaims: jwt.RegisteredClaims{
+			Subject: subject, ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		}}
+	}
+	tests := []struct {
+		name, token string
+		err         error
+	}{
+		{"expired", expired, errSessionExpired},
+		{"other secret", signed(t, jwt.SigningMethodHS256, []byte("other"), claims(sessionSubject, RoleAdmin)), errSessionInvalid},
+		{"other subject", signed(t, jwt.SigningMethodHS256, []byte("jwt-secret"), claims("billing", RoleAdmin)), errSessionInvalid},
+		{"unknown role", signed(t, jwt.SigningMethodHS256, []byte("jwt-secret"), claims(sessionSubject, "owner")), errSessionInvalid},
+		{"no expiry", signed(t, jwt.SigningMethodHS256, []byte("jwt-secret"), sessionClaims{Role: RoleAdmin,
+			RegisteredClaims: jwt.RegisteredClaims{Subject: sessionSubject}}), errSessionInvalid},
+		{"unsigned", signed(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, claims(sessionSubject, RoleAdmin)), errSessionInvalid},
+		{"garbage", "not-a-jwt", errSessionInvalid},
+	}
+	for _, tt := range tests {
+		if _, err := verifySession(tt.token); !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+
+	useAuth(t, config.Auth{Secret: "admin-secret"})
+	if _, _, err := NewSession(now, RoleAdmin); !errors.Is(err, ErrSessionsDisabled) {
+		t.Errorf("NewSession without JWT_SECRET: err = %v", err)
+	}
+}
+
+func TestSessionAuth(t *testing.T) {
+	useAuth(t, config.Auth{JWTSecret: "jwt-secret", MaxFailures: 2, FailureWindow: time.Minute, Lockout: time.Minute})
+	r := newRouter()
+	r.GET("/admin", AdminAuth(), RequireRole(RoleAdmin), ok)
+	admin, _, _ := NewSession(time.Now(), RoleAdmin)
+	viewer, _, _ := NewSession(time.Now(), RoleViewer)
+	expired, _, _ := NewSession(time.Now().Add(-2*SessionTTL), RoleAdmin)
+
+	if w := send(r, http.MethodGet, "/admin", "192.0.2.1", "Authorization", "Bearer "+admin); w.Code != http.StatusOK {
+		t.Errorf("admin session = %d, want 200", w.Code)
+	}
+	expectError(t, send(r, http.MethodGet, "/admin", "192.0.2.1", "Authorization", "Bearer "+viewer), http.StatusForbidden, models.CodeForbidden)
+	// An expired session isn't a guess, so however many there are the IP isn't locked out.
+	for i := 0; i < 3; i++ {
+		expectError(t, send(r, http.MethodGet, "/admin", "192.0.2.1", "Authorization", "Bearer "+expired), http.StatusUnauthorized, models.CodeUnauthorized)
+	}
+	if w := send(r, http.MethodGet, "/admin", "192.0.2.1", "Authorization", "Bearer "+admin); w.Code != http.StatusOK {
+		t.Errorf("after expired sessions = %d, want 200", w.Code)
+	}
+	for i := 0; i < 2; i++ {
+		expectError(t, send(r, http.MethodGet, "/admin", "192.0.2.2", "Authorization", "Bearer forged"), http.StatusUnauthorized, models.CodeUnauthorized)
+	}
+	expectError(t, send(r, http.MethodGet, "/admin", "192.0.2.2", "Authorization", "Bearer "+admin), http.StatusTooManyRequests, models.CodeRateLimited)
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTimeoutBoundsTheContext(t *testing.T) {
+	r := newRouter()
+	var remaining time.Duration
+	var hasDeadline bool
+	r.GET("/bookings", Timeout(2*time.Second), func(c *gin.Context) {
+		var deadline time.Time
+		deadline, hasDeadline = c.Request.Context().Deadline()
+		remaining = time.Until(deadline)
+	})
+	send(r, http.MethodGet, "/bookings", "")
+	if !hasDeadline || remaining <= 0 || remaining > 2*time.Second {
+		t.Errorf("deadline set %v, %v away, want within 2s", hasDeadline, remaining)
+	}
+}
@@ -0,0 +1,36 @@
+package models
+
+// Addon is an extra a customer can order with a booking, such as catering or a cake.
+type Addon struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	Name       string `json:"name" gorm:"not null;uniqueIndex"`
+	PriceCents int    `json:"price_cents" gorm:"not null;default:0"`
+	Active     bool   `json:"active" gorm:"not null"`
+}
+
+func (Addon) TableName() string {
+	return "addons"
+}
+
+// BookingAddon is an add-on ordered with a booking. Name and price are copied from the
+// catalogue when the booking is made, so later price changes or removing the add-on
+// don't rewrite past bookings.
+type BookingAddon struct {
+	BookingID  int64  `json:"-" gorm:"primaryKey;autoIncrement:false"`
+	AddonID    uint   `json:"addon_id" gorm:"primaryKey;autoIncrement:false"`
+	Name       string `json:"name" gorm:"not null"`
+	PriceCents int    `json:"price_cents" gorm:"not null;default:0"`
+}
+
+func (BookingAddon) TableName() string {
+	return "booking_addons"
+}
+
+// AddonsTotal sums the prices of addons.
+func AddonsTotal(addons []BookingAddon) int {
+	total := 0
+	for _, a := range addons {
+		total += a.PriceCents
+	}
+	return total
+}
@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// Audited actions. Every booking action names the booking it changed.
+const (
+	AuditBookingUpdate     = "booking.update"
+	AuditBookingDelete     = "booking.delete"
+	AuditBookingPurge      = "booking.purge"
+	AuditBookingRestore    = "booking.restore"
+	AuditBookingConfirm    = "booking.confirm"
+	AuditBookingCancel     = "booking.cancel"
+	AuditBookingReschedule = "booking.reschedule"
+	AuditBookingDeposit    = "booking.deposit"
+	AuditBookingPayment    = "booking.payment"
+	AuditBookingImport     = "booking.import"
+	AuditBookingCheckIn    = "booking.checkin"
+	AuditBookingNoShow     = "booking.no_show"
+	AuditBookingResend     = "booking.resend_confirmation"
+	AuditRetentionHold     = "booking.retention_hold"
+	AuditRetentionRelease  = "booking.retention_release"
+	// AuditBookingNoShowUndo records a no-show put back to confirmed.
+	AuditBookingNoShowUndo = "booking.no_show_undo"
+	AuditSettingsUpdate    = "settings.update"
+	// AuditMaintenanceUpdate records maintenance mode switched on or off.
+	AuditMaintenanceUpdate = "maintenance.update"
+	// AuditCustomerErase records how much of a customer's data was erased, without the email.
+	AuditCustomerErase = "customer.erase"
+)
+
+// AuditEntry records one change an admin, or a customer with their own token, made: who,
+// from where, and the thing before and after. Entries are only ever inserted, and nothing in the API deletes them; erasing a
+// customer only redacts their details from the snapshots.
+type AuditEntry struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+	// Actor identifies the credential used, e.g. "session" or "token 3f9a1c2e", or is
+	// "customer" for a customer's own change; Role is its role, "customer" for a customer.
+	Actor     string `json:"actor" gorm:"not null"`
+	Role      string `json:"role" gorm:"not null"`
+	Action    string `json:"action" gorm:"not null;index"`
+	BookingID *int64 `json:"booking_id" gorm:"index"`
+	// Before is null for something the action created, After for something it removed.
+	Before   Snapshot `json:"before" gorm:"type:text;not null;default:''"`
+	After    Snapshot `json:"after" gorm:"type:text;not null;default:''"`
+	ClientIP string   `json:"client_ip" gorm:"not null;default:''"`
+}
+
+func (AuditEntry) TableName() string {
+	return "audit_log"
+}
+
+// Snapshot is a JSON document kept as text. It marshals as the document itself, or null when empty.
+type Snapshot string
+
+func (s Snapshot) MarshalJSON() ([]byte, error) {
+	if s == "" {
+		return []byte("null"), nil
+	}
+	return []byte(s), nil
+}
@@ -0,0 +1,12 @@
+package models
+
+// Blackout is a date the venue is closed and won't take bookings.
+type Blackout struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	Date   string `json:"date" gorm:"not null;uniqueIndex"`
+	Reason string `json:"reason" gorm:"not null;default:''"`
+}
+
+func (Blackout) TableName() string {
+	return "blackout_dates"
+}
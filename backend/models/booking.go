@@ -1,7 +1,77 @@
 package models
 
+import (
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Booking statuses. New bookings start pending until the venue calls the customer back.
+// A confirmed booking whose day passed without a check-in becomes a no-show.
+const (
+	StatusPending   = "pending"
+	StatusConfirmed = "confirmed"
+	StatusCancelled = "cancelled"
+	StatusNoShow    = "no_show"
+)
+
+// ValidStatus reports whether s is a known booking status.
+func ValidStatus(s string) bool {
+	return s == StatusPending || s == StatusConfirmed || s == StatusCancelled || s == StatusNoShow
+}
+
+// CanTransition reports whether a booking may move from one status to another:
+// pending -> confirmed, and pending or confirmed -> cancelled. No-shows are marked, and
+// undone, by their own endpoints rather than as a status change.
+func CanTransition(from, to string) bool {
+	switch to {
+	case StatusConfirmed:
+		return from == StatusPending
+	case StatusCancelled:
+		return from == StatusPending || from == StatusConfirmed
+	}
+	return false
+}
+
+// Redacted replaces a customer's name, email, phone and notes when their data is erased
+// but their bookings are kept.
+const Redacted = "[redacted]"
+
+// SMS delivery states recorded on a booking. A booking no text has been sent for has none.
+const (
+	SMSSent = "sent"
+	// SMSFailed is a text Twilio rejected for some other reason; the next one is still tried.
+	SMSFailed = "failed"
+	// SMSInvalidNumber and SMSUnsubscribed stop any more texts to the booking's number:
+	// Twilio can't deliver to it, or the customer replied STOP.
+	SMSInvalidNumber = "invalid_number"
+	SMSUnsubscribed  = "unsubscribed"
+)
+
+// SlotKey returns the normalized slot key for a booking in room starting at date and clock,
+// e.g. "2026-03-15 18:00 #2". Bookings without a room get the room-less "2026-03-15 18:00".
+func SlotKey(date, clock string, room *uint) *string {
+	key := date + " " + clock
+	if room != nil {
+		key += " #" + strconv.FormatUint(uint64(*room), 10)
+	}
+	return &key
+}
+
+// EndsAt returns when the booking finishes, or nil if StartsAt isn't set.
+func (b *Booking) EndsAt() *time.Time {
+	if b.StartsAt == nil {
+		return nil
+	}
+	end := b.StartsAt.Add(time.Duration(b.Duration) * time.Hour)
+	return &end
+}
+
+// Booking is a party booking. Its table is built by the versioned migrations in db, not from
+// these tags; db's tests check that the tags describe that table.
 type Booking struct {
-	ID       uint   `json:"id" gorm:"primaryKey"`
+	ID       int64  `json:"id" gorm:"primaryKey"`
 	Name     string `json:"name" gorm:"not null"`
 	Email    string `json:"email" gorm:"not null"`
 	Phone    string `json:"phone" gorm:"not null"`
@@ -9,4 +79,127 @@ type Booking struct {
 	Time     string `json:"time" gorm:"not null"`
 	Duration int    `json:"duration" gorm:"not null;default:2"`
 	Guests   int    `json:"guests" gorm:"not null"`
+	Status   string `json:"status" gorm:"not null;default:pending;index"`
+
+	// CreatedAt is when the booking was made and UpdatedAt when it last changed, both UTC.
+	// Bookings made before they were recorded carry the time of that upgrade instead.
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Notes are the customer's special requests, e.g. "nut allergy". They are "" when there
+	// are none, never null.
+	Notes string `json:"notes" gorm:"type:text;not null;default:''"`
+
+	// Date and Time are the venue-local wall clock shown to people. StartsAt is the same
+	// moment in UTC, set whenever they change, and is what time comparisons use.
+	StartsAt *time.Time `json:"starts_at" gorm:"index"`
+
+	// CancelToken lets the customer cancel their own booking. It is only ever returned once, on creation.
+	CancelToken string `json:"-" gorm:"not null;default:''"`
+
+	// ConfirmationCode lets the customer look up the booking together with their email. Like
+	// CancelToken it is only returned on creation and in the confirmation email.
+	ConfirmationCode string `json:"-" gorm:"not null;default:''"`
+
+	// Reference is the booking's public identifier, e.g. "MP-7F3K9Q", short enough to read
+	// over the phone. Unlike ConfirmationCode it isn't a secret: it says which booking, and
+	// looking one up by it still takes the code.
+	Reference string `json:"reference" gorm:"uniqueIndex"`
+
+	// SlotKey is the "YYYY-MM-DD HH:MM #room" start of an active booking. Its unique index makes the database
+	// reject two bookings for the same slot in a room; it is NULL for cancelled bookings so the slot can be rebooked.
+	SlotKey *string `json:"-" gorm:"uniqueIndex"`
+
+	// RescheduledFrom is the "YYYY-MM-DD HH:MM" slot the booking was last moved from, and
+	// RescheduledAt when. Both are empty for bookings that were never rescheduled.
+	RescheduledFrom string     `json:"rescheduled_from,omitempty" gorm:"not null;default:''"`
+	RescheduledAt   *time.Time `json:"rescheduled_at,omitempty"`
+
+	// RemindedAt is when the customer was emailed a reminder before the party. It is cleared
+	// when the booking moves, so the new time gets its own reminder.
+	RemindedAt *time.Time `json:"reminded_at,omitempty" gorm:"index"`
+
+	// SMSStatus is how the last text to the customer went, one of the SMS* states, or ""
+	// when none has been sent. It is cleared when the phone number changes.
+	SMSStatus string `json:"sms_status,omitempty" gorm:"not null;default:''"`
+
+	// ConfirmationResends counts the times staff or the customer had the confirmation sent
+	// again, and ConfirmationResentAt is the latest.
+	ConfirmationResends  int        `json:"confirmation_resends,omitempty" gorm:"not null;default:0"`
+	ConfirmationResentAt *time.Time `json:"confirmation_resent_at,omitempty"`
+
+	// CalendarEventID is the booking's event on the owner's Google Calendar, kept while the
+	// booking is confirmed. CalendarSyncedAt is when the event last matched the booking; a
+	// booking updated since is due to be synced again.
+	CalendarEventID  string     `json:"calendar_event_id,omitempty" gorm:"not null;default:''"`
+	CalendarSyncedAt *time.Time `json:"-"`
+
+	// FullDay marks a buyout of the whole venue for the day. Time and Duration are set to
+	// that day's opening hours, rounded up to the hour, and no other booking may share the
+	// date in any room.
+	FullDay bool `json:"full_day" gorm:"not null;default:false"`
+
+	// Source is where the customer came from, e.g. "instagram", and "direct" when neither the
+	// form nor the Referer said. The UTM fields are the ad campaign's tags, if the form sent
+	// them. None of them are checked: they are only for the stats.
+	Source      string `json:"source" gorm:"not null;default:direct;index"`
+	UTMSource   string `json:"utm_source,omitempty" gorm:"not null;default:''"`
+	UTMMedium   string `json:"utm_medium,omitempty" gorm:"not null;default:''"`
+	UTMCampaign string `json:"utm_campaign,omitempty" gorm:"not null;default:''"`
+
+	// CheckedInAt is when the party was checked in at the door, and ActualGuests how many
+	// came, if the staff counted them. Both are nil until then.
+	CheckedInAt  *time.Time `json:"checked_in_at,omitempty" gorm:"index"`
+	ActualGuests *int       `json:"actual_guests,omitempty"`
+
+	// Flagged marks a booking made by a contact with a record of not turning up, and
+	// PriorNoShows is how many no-shows their email or phone had when it was made.
+	Flagged      bool `json:"flagged" gorm:"not null;default:false;index"`
+	PriorNoShows int  `json:"prior_no_shows,omitempty" gorm:"not null;default:0"`
+
+	// RetentionHold keeps the booking out of the retention job while staff need its
+	// details, such as during a dispute over it.
+	RetentionHold bool `json:"retention_hold,omitempty" gorm:"not null;default:false"`
+
+	// SeriesID links the occurrences of a recurring booking.
+	SeriesID string `json:"series_id,omitempty" gorm:"index"`
+
+	// RoomID is the room the booking takes. RoomName is filled in by the bookings list from a
+	// join and is never written.
+	RoomID   *uint  `json:"room_id" gorm:"index"`
+	RoomName string `json:"room_name,omitempty" gorm:"->;-:migration"`
+
+	// PackageID is the package the booking was made with, if any. PackageName is filled in
+	// by the bookings list from a join and is never written.
+	PackageID   *uint  `json:"package_id,omitempty" gorm:"index"`
+	PackageName string `json:"package_name,omitempty" gorm:"->;-:migration"`
+
+	// Addons are the extras ordered with the booking; they are priced separately from PriceCents.
+	Addons []BookingAddon `json:"addons,omitempty" gorm:"foreignKey:BookingID"`
+
+	// PriceCents is the quoted total, recalculated whenever the date, duration or guest count changes.
+	PriceCents int `json:"price_cents" gorm:"not null;default:0"`
+
+	// DepositPaid is whether the deposit due is covered, kept in step with the two amounts
+	// below by SyncDeposit, and DepositPaidAt when it was first covered.
+	DepositPaid   bool       `json:"deposit_paid" gorm:"not null;default:false"`
+	DepositPaidAt *time.Time `json:"deposit_paid_at"`
+
+	// DepositDueCents is what the customer is expected to pay before the party, and
+	// AmountPaidCents the sum of their Payments so far, which may be more. PaymentMethod and
+	// PaidAt are those of the latest payment. PaymentStatus is derived from the two amounts,
+	// one of the Payment* states, and isn't stored.
+	DepositDueCents int        `json:"deposit_due_cents" gorm:"not null;default:0"`
+	AmountPaidCents int        `json:"amount_paid_cents" gorm:"not null;default:0"`
+	PaymentMethod   string     `json:"payment_method,omitempty" gorm:"not null;default:''"`
+	PaidAt          *time.Time `json:"paid_at,omitempty"`
+	PaymentStatus   string     `json:"payment_status" gorm:"-"`
+
+	// PaymentIntentID is the Stripe payment for a deposit taken online. HoldExpiresAt is when
+	// the booking is released if that deposit is still unpaid; it is cleared once paid.
+	PaymentIntentID string     `json:"payment_intent_id,omitempty" gorm:"not null;default:'';index"`
+	HoldExpiresAt   *time.Time `json:"hold_expires_at,omitempty" gorm:"index"`
+
+	// DeletedAt soft-deletes the booking: GORM leaves such rows out of every query unless Unscoped.
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
 }
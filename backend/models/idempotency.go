@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// IdempotencyKey remembers the response to a request sent with an Idempotency-Key header,
+// so a client retrying over a flaky connection gets the original result back.
+type IdempotencyKey struct {
+	Key         string `gorm:"primaryKey;size:255"`
+	RequestHash string `gorm:"size:64;not null"`
+	// Status is 0 while the first request is still being handled.
+	Status    int `gorm:"not null;default:0"`
+	Response  []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
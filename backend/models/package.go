@@ -0,0 +1,17 @@
+package models
+
+// Package is a bundle customers can pick when booking: a fixed duration and base price.
+// Inactive packages are hidden from the booking form but stay attached to existing bookings.
+type Package struct {
+	ID             uint   `json:"id" gorm:"primaryKey"`
+	Name           string `json:"name" gorm:"not null;uniqueIndex"`
+	Description    string `json:"description" gorm:"not null;default:''"`
+	DurationHours  int    `json:"duration_hours" gorm:"not null"`
+	BasePriceCents int    `json:"base_price_cents" gorm:"not null;default:0"`
+	MaxGuests      int    `json:"max_guests" gorm:"not null"`
+	Active         bool   `json:"active" gorm:"not null"`
+}
+
+func (Package) TableName() string {
+	return "packages"
+}
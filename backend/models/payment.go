@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Payment methods recorded on a payment.
+const (
+	PaymentCash     = "cash"
+	PaymentTransfer = "transfer"
+	PaymentCard     = "card"
+	PaymentOther    = "other"
+)
+
+// ValidPaymentMethod reports whether m is a known payment method.
+func ValidPaymentMethod(m string) bool {
+	return m == PaymentCash || m == PaymentTransfer || m == PaymentCard || m == PaymentOther
+}
+
+// Payment states of a booking, derived from what it has been paid against its deposit due.
+const (
+	PaymentUnpaid  = "unpaid"
+	PaymentPartial = "partial"
+	PaymentPaid    = "paid"
+)
+
+// Payment is money received for a booking: a deposit paid online, or cash or a transfer
+// staff took and recorded by hand. Payments are only ever added; the booking's
+// AmountPaidCents is their sum.
+type Payment struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	BookingID   int64  `json:"booking_id" gorm:"not null;index"`
+	AmountCents int    `json:"amount_cents" gorm:"not null"`
+	Method      string `json:"method" gorm:"not null"`
+	// Reference is the staff's note on the payment, e.g. a transfer's reference number.
+	Reference string    `json:"reference,omitempty" gorm:"type:text;not null;default:''"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (Payment) TableName() string {
+	return "payments"
+}
+
+// PaymentState is b's payment status: unpaid until anything is paid, then partial until
+// the deposit due is covered. A booking with nothing due is paid as soon as anything is.
+func (b *Booking) PaymentState() string {
+	switch {
+	case b.AmountPaidCents <= 0:
+		return PaymentUnpaid
+	case b.AmountPaidCents < b.DepositDueCents:
+		return PaymentPartial
+	}
+	return PaymentPaid
+}
+
+// SyncDeposit sets DepositPaid from the amounts: a booking with a deposit due has paid it
+// once AmountPaidCents covers it. DepositPaidAt becomes at when it is newly covered and is
+// cleared when it no longer is, as when the deposit due is raised.
+func (b *Booking) SyncDeposit(at time.Time) {
+	covered := b.DepositDueCents > 0 && b.AmountPaidCents >= b.DepositDueCents
+	switch {
+	case covered && !b.DepositPaid:
+		b.DepositPaidAt = &at
+	case !covered:
+		b.DepositPaidAt = nil
+	}
+	b.DepositPaid = covered
+}
+
+// AfterFind fills in PaymentStatus, which isn't stored.
+func (b *Booking) AfterFind(*gorm.DB) error {
+	b.PaymentStatus = b.PaymentState()
+	return nil
+}
+
+// AfterSave fills in PaymentStatus after a booking is created or updated, so one returned
+// straight after it is written shows the same status as one read back.
+func (b *Booking) AfterSave(*gorm.DB) error {
+	b.PaymentStatus = b.PaymentState()
+	return nil
+}
@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+// BookingList is a page of GET /bookings.
+type BookingList struct {
+	Bookings []Booking `json:"bookings"`
+	Total    int64     `json:"total"`
+	Page     int       `json:"page"`
+	PerPage  int       `json:"per_page"`
+	// StaleAsOf is set when the database couldn't be reached and this is the page as it was
+	// then, from memory.
+	StaleAsOf *time.Time `json:"stale_as_of,omitempty"`
+}
+
+// Error codes, in ErrorResponse.Code. They are stable: clients switch on them, so a code
+// is never renamed or reused for something else, only added.
+const (
+	CodeBadRequest          = "bad_request"
+	CodeValidation          = "validation_failed"
+	CodeUnauthorized        = "unauthorized"
+	CodeForbidden           = "forbidden"
+	CodeNotFound            = "not_found"
+	CodeMethodNotAllowed    = "method_not_allowed"
+	CodeTooLarge            = "payload_too_large"
+	CodeSlotConflict        = "slot_conflict"
+	CodeDuplicate           = "duplicate_booking"
+	CodeInvalidCSV          = "invalid_csv"
+	CodeBookingLimit        = "booking_limit"
+	CodeDateClosed          = "date_closed"
+	CodeCutoffPassed        = "cancellation_cutoff"
+	CodeNotConfirmed        = "not_confirmed"
+	CodeNotToday            = "not_today"
+	CodeCheckedIn           = "checked_in"
+	CodeNotOver             = "not_over"
+	CodeNotNoShow           = "not_no_show"
+	CodeBookingCancelled    = "booking_cancelled"
+	CodeDepositPaid         = "deposit_paid"
+	CodeAlreadyWaitlisted   = "already_waitlisted"
+	CodeNameTaken           = "name_taken"
+	CodeInvalidTransition   = "invalid_transition"
+	CodeInUse               = "in_use"
+	CodeUpcomingBookings    = "upcoming_bookings"
+	CodeIdempotencyReuse    = "idempotency_key_reused"
+	CodeIdempotencyBusy     = "idempotency_key_in_use"
+	CodeCaptchaRequired     = "captcha_required"
+	CodeCaptchaFailed       = "captcha_failed"
+	CodeRateLimited         = "rate_limited"
+	CodeNotReady            = "not_ready"
+	CodeDBUnavailable       = "db_unavailable"
+	CodeCaptchaUnavailable  = "captcha_unavailable"
+	CodePaymentUnavailable  = "payment_unavailable"
+	CodeTicketsUnavailable  = "tickets_unavailable"
+	CodeCalendarUnavailable = "calendar_unavailable"
+	CodeMaintenance         = "maintenance"
+	CodeTimeout             = "timeout"
+	CodeCancelled           = "cancelled"
+	CodeInternal            = "internal_error"
+)
+
+// ErrorResponse is the body of a failed request. Code says what went wrong for the client
+// to switch on and Message says it for people; Fields has a message list per JSON field
+// when the request didn't pass validation. RequestID is the X-Request-ID, for support.
+//
+// Error and Errors repeat Message and Fields under the names they had before the codes
+// were added, for clients that haven't moved on yet; they go in the next release.
+type ErrorResponse struct {
+	Code      string              `json:"code"`
+	Message   string              `json:"message"`
+	Fields    map[string][]string `json:"fields,omitempty"`
+	RequestID string              `json:"request_id,omitempty"`
+
+	Error  string              `json:"error"`
+	Errors map[string][]string `json:"errors,omitempty"`
+}
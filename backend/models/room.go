@@ -0,0 +1,14 @@
+package models
+
+// Room is a bookable party room. Each room takes its own bookings, so two parties can run
+// at once in different rooms. Inactive rooms take no new bookings but keep their existing ones.
+type Room struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Name     string `json:"name" gorm:"not null;uniqueIndex"`
+	Capacity int    `json:"capacity" gorm:"not null"`
+	Active   bool   `json:"active" gorm:"not null"`
+}
+
+func (Room) TableName() string {
+	return "rooms"
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Setting is one venue setting, stored as its JSON-encoded value. The settings package
+// reads the rows into a typed struct; see settings.Venue for the keys.
+type Setting struct {
+	Key       string    `json:"key" gorm:"primaryKey"`
+	Value     string    `json:"value" gorm:"type:text;not null"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Setting) TableName() string {
+	return "settings"
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// SummarySend marks the day whose summary email has gone to the owner. Its primary key
+// makes a second send for the same day fail, across restarts and instances.
+type SummarySend struct {
+	Date   string    `gorm:"primaryKey"`
+	SentAt time.Time `gorm:"not null"`
+}
+
+func (SummarySend) TableName() string {
+	return "summary_sends"
+}
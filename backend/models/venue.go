@@ -0,0 +1,45 @@
+package models
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultVenueTZ is the venue's timezone when VENUE_TZ is unset.
+const DefaultVenueTZ = "Asia/Kolkata"
+
+// LoadVenueLocation loads the timezone named by VENUE_TZ, an IANA name such as
+// "Europe/London", or DefaultVenueTZ when it is unset.
+func LoadVenueLocation() (*time.Location, error) {
+	name := os.Getenv("VENUE_TZ")
+	if name == "" {
+		name = DefaultVenueTZ
+	}
+	return time.LoadLocation(name)
+}
+
+var venue struct {
+	once sync.Once
+	loc  *time.Location
+}
+
+// VenueLocation is the timezone booking dates and times are written in, loaded once.
+// main rejects an invalid VENUE_TZ at startup, so the UTC fallback is only a safety net.
+func VenueLocation() *time.Location {
+	venue.once.Do(func() {
+		loc, err := LoadVenueLocation()
+		if err != nil {
+			loc = time.UTC
+		}
+		venue.loc = loc
+	})
+	return venue.loc
+}
+
+// StartTime returns the instant a booking on date ("YYYY-MM-DD") at clock ("HH:MM") starts
+// at the venue. The result is in UTC.
+func StartTime(date, clock string) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01-02 15:04", date+" "+clock, VenueLocation())
+	return t.UTC(), err
+}
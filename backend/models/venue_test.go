@@ -0,0 +1,40 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStartTime(t *testing.T) {
+	t.Setenv("VENUE_TZ", "")
+	if got := VenueLocation().String(); got != DefaultVenueTZ {
+		t.Fatalf("venue timezone %s, want %s", got, DefaultVenueTZ)
+	}
+	start, err := StartTime("2026-07-10", "14:00")
+	if want := time.Date(2026, 7, 10, 8, 30, 0, 0, time.UTC); err != nil || !start.Equal(want) || start.Location() != time.UTC {
+		t.Errorf("StartTime = %v, %v, want %v", start, err, want)
+	}
+	if _, err := StartTime("2026-07-10", "2pm"); err == nil {
+		t.Error("StartTime accepted 2pm")
+	}
+
+	b := Booking{Duration: 3}
+	if b.EndsAt() != nil {
+		t.Error("EndsAt without a start isn't nil")
+	}
+	b.StartsAt = &start
+	if end := b.EndsAt(); end == nil || !end.Equal(start.Add(3*time.Hour)) {
+		t.Errorf("EndsAt = %v, want three hours after %v", end, start)
+	}
+}
+
+func TestLoadVenueLocation(t *testing.T) {
+	t.Setenv("VENUE_TZ", "Europe/London")
+	if loc, err := LoadVenueLocation(); err != nil || loc.String() != "Europe/London" {
+		t.Errorf("LoadVenueLocation = %v, %v", loc, err)
+	}
+	t.Setenv("VENUE_TZ", "Mars/Olympus")
+	if _, err := LoadVenueLocation(); err == nil {
+		t.Error("an unknown timezone loaded")
+	}
+}
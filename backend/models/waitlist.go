@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// WaitlistEntry is a booking request for a slot that was full, kept so it can be booked
+// if the slot frees up. Entries for a slot are served oldest first.
+type WaitlistEntry struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Name     string `json:"name" gorm:"not null"`
+	Email    string `json:"email" gorm:"not null"`
+	Phone    string `json:"phone" gorm:"not null"`
+	Date     string `json:"date" gorm:"not null;index"`
+	Time     string `json:"time" gorm:"not null"`
+	Duration int    `json:"duration" gorm:"not null"`
+	Guests   int    `json:"guests" gorm:"not null"`
+	Notes    string `json:"notes" gorm:"type:text;not null;default:''"`
+
+	// RoomID is the room the customer asked for; nil means any room that fits.
+	RoomID    *uint  `json:"room_id"`
+	PackageID *uint  `json:"package_id,omitempty"`
+	AddonIDs  []uint `json:"addon_ids,omitempty" gorm:"type:text;serializer:json"`
+
+	// Position is the entry's place in the queue for its slot, counting from 1. It is
+	// worked out when the entry is read and never stored.
+	Position  int       `json:"position" gorm:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (WaitlistEntry) TableName() string {
+	return "waitlist"
+}
+
+// Booking returns the booking the entry asks for, before validation.
+func (w WaitlistEntry) Booking() Booking {
+	return Booking{
+		Name:      w.Name,
+		Email:     w.Email,
+		Phone:     w.Phone,
+		Date:      w.Date,
+		Time:      w.Time,
+		Duration:  w.Duration,
+		Guests:    w.Guests,
+		Notes:     w.Notes,
+		RoomID:    w.RoomID,
+		PackageID: w.PackageID,
+	}
+}
@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"net/http"
+	"os"
+	"time"
+)
+
+// FromEnv builds a Dispatcher with every notifier configured in the environment:
+// WEBHOOK_URL (signed with WEBHOOK_SECRET when set), the staff's Telegram chat when
+// TELEGRAM_BOT_TOKEN and TELEGRAM_CHAT_ID are both set, a Slack channel's SLACK_WEBHOOK_URL
+// (linking to the dashboard at ADMIN_BASE_URL when set), and texts to customers when
+// TWILIO_ACCOUNT_SID, TWILIO_AUTH_TOKEN and TWILIO_FROM are all set, recorded with smsStatus.
+func FromEnv(smsStatus SMSStatusFunc) *Dispatcher {
+	var notifiers []Notifier
+	if url := os.Getenv("WEBHOOK_URL"); url != "" {
+		notifiers = append(notifiers, &Webhook{
+			URL:    url,
+			Secret: os.Getenv("WEBHOOK_SECRET"),
+			Client: &http.Client{Timeout: 10 * time.Second},
+		})
+	}
+	if token, chat := os.Getenv("TELEGRAM_BOT_TOKEN"), os.Getenv("TELEGRAM_CHAT_ID"); token != "" && chat != "" {
+		notifiers = append(notifiers, &Telegram{
+			Token:  token,
+			ChatID: chat,
+			Client: &http.Client{Timeout: 10 * time.Second},
+		})
+	}
+	if url := os.Getenv("SLACK_WEBHOOK_URL"); url != "" {
+		notifiers = append(notifiers, &Slack{
+			URL:      url,
+			AdminURL: os.Getenv("ADMIN_BASE_URL"),
+			Client:   &http.Client{Timeout: 10 * time.Second},
+		})
+	}
+	sid, token, from := os.Getenv("TWILIO_ACCOUNT_SID"), os.Getenv("TWILIO_AUTH_TOKEN"), os.Getenv("TWILIO_FROM")
+	if sid != "" && token != "" && from != "" {
+		notifiers = append(notifiers, &Twilio{
+			AccountSID: sid,
+			AuthToken:  token,
+			From:       from,
+			Client:     &http.Client{Timeout: 10 * time.Second},
+			Status:     smsStatus,
+		})
+	}
+	return NewDispatcher(notifiers...)
+}
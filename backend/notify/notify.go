@@ -0,0 +1,129 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"miniparty-backend/models"
+)
+
+// Event types sent to notifiers.
+const (
+	BookingCreated = "booking.created"
+	// BookingPromoted is a booking made from the waitlist after its slot freed up.
+	BookingPromoted = "booking.promoted"
+	// BookingReminder is sent alongside the customer's reminder email before the party.
+	BookingReminder = "booking.reminder"
+	// BookingConfirmed is a pending booking the venue has confirmed with the customer.
+	BookingConfirmed = "booking.confirmed"
+	// BookingCancelled is a booking cancelled by the customer or an admin.
+	BookingCancelled = "booking.cancelled"
+	// BookingRescheduled is a booking moved to another time; its rescheduled_from is where it was.
+	BookingRescheduled = "booking.rescheduled"
+	// BookingConfirmationResent is the customer's confirmation text sent again on request.
+	// Only Dispatcher.Text sends it, so the staff's channels don't hear of it.
+	BookingConfirmationResent = "booking.confirmation_resent"
+)
+
+// Event is something that happened to a booking.
+type Event struct {
+	Type    string         `json:"event"`
+	Booking models.Booking `json:"booking"`
+}
+
+// Notifier delivers an event to one destination (webhook, chat, ...).
+type Notifier interface {
+	Notify(ctx context.Context, e Event) error
+}
+
+// RetryableError marks a delivery failure worth retrying, such as a network error or a 5xx.
+type RetryableError struct {
+	Err error
+	// After, when set, is how long the destination asked to be left alone before the retry,
+	// e.g. a rate limit's Retry-After. It replaces the usual backoff for that retry.
+	After time.Duration
+}
+
+func (e *RetryableError) Error() string { return e.Err.Error() }
+func (e *RetryableError) Unwrap() error { return e.Err }
+
+// Dispatcher fans events out to notifiers in the background, retrying retryable failures
+// with exponential backoff. A Dispatcher with no notifiers does nothing.
+type Dispatcher struct {
+	notifiers []Notifier
+	attempts  int
+	backoff   time.Duration
+	timeout   time.Duration
+}
+
+// NewDispatcher returns a Dispatcher that tries each delivery up to 3 times.
+func NewDispatcher(notifiers ...Notifier) *Dispatcher {
+	return &Dispatcher{
+		notifiers: notifiers,
+		attempts:  3,
+		backoff:   time.Second,
+		timeout:   10 * time.Second,
+	}
+}
+
+// Dispatch delivers e to every notifier without blocking the caller.
+func (d *Dispatcher) Dispatch(e Event) {
+	if d == nil {
+		return
+	}
+	for _, n := range d.notifiers {
+		go d.deliver(n, e)
+	}
+}
+
+// CanText reports whether texts to customers are configured.
+func (d *Dispatcher) CanText() bool {
+	if d == nil {
+		return false
+	}
+	for _, n := range d.notifiers {
+		if _, ok := n.(*Twilio); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Text delivers e to the notifiers that text the customer, and no others, without
+// blocking the caller.
+func (d *Dispatcher) Text(e Event) {
+	if d == nil {
+		return
+	}
+	for _, n := range d.notifiers {
+		if _, ok := n.(*Twilio); ok {
+			go d.deliver(n, e)
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(n Notifier, e Event) {
+	wait := d.backoff
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+		err := n.Notify(ctx, e)
+		cancel()
+		if err == nil {
+			return
+		}
+
+		var retryable *RetryableError
+		if !errors.As(err, &retryable) || attempt >= d.attempts {
+			log.Printf("Notification %s for booking #%d failed after %d attempt(s): %v", e.Type, e.Booking.ID, attempt, err)
+			return
+		}
+		if retryable.After > 0 {
+			time.Sleep(retryable.After)
+		} else {
+			time.Sleep(wait)
+		}
+		wait *= 2
+	}
+}
@@ -0,0 +1,156 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// slackNotesLimit is how many characters of a booking's notes a Slack message shows.
+const slackNotesLimit = 300
+
+// Slack posts new, confirmed and cancelled bookings to a channel through an incoming webhook,
+// as Block Kit messages. When AdminURL is set (ADMIN_BASE_URL) each message links to the
+// booking in the admin dashboard.
+//
+// Any failed post is retried by the Dispatcher, a 429 after Slack's Retry-After.
+type Slack struct {
+	URL      string
+	AdminURL string
+	Client   *http.Client
+}
+
+// slackBlock is one Block Kit block; only the fields a block type uses are set.
+type slackBlock struct {
+	Type     string       `json:"type"`
+	Text     *slackText   `json:"text,omitempty"`
+	Fields   []slackText  `json:"fields,omitempty"`
+	Elements []slackBlock `json:"elements,omitempty"`
+	URL      string       `json:"url,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackMessage struct {
+	// Text is the notification preview, and what clients that can't show blocks show instead.
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+func (s *Slack) Notify(ctx context.Context, e Event) error {
+	msg, ok := s.message(e)
+	if !ok {
+		return nil
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// The webhook URL is the secret, so leave it out of the error that gets logged.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return &RetryableError{Err: fmt.Errorf("slack: %w", err)}
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		retryAfter, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return &RetryableError{Err: fmt.Errorf("slack returned %s", resp.Status), After: time.Duration(retryAfter) * time.Second}
+	}
+	return nil
+}
+
+// message builds the Slack message for e; ok is false for events the channel doesn't get.
+func (s *Slack) message(e Event) (msg slackMessage, ok bool) {
+	b := e.Booking
+	var title string
+	switch e.Type {
+	case BookingCreated:
+		title = "New booking"
+	case BookingPromoted:
+		title = "New booking from the waitlist"
+	case BookingConfirmed:
+		title = "Booking confirmed"
+	case BookingCancelled:
+		title = "Booking cancelled"
+	default:
+		return slackMessage{}, false
+	}
+	title += " " + b.Reference
+
+	hours := "hours"
+	if b.Duration == 1 {
+		hours = "hour"
+	}
+	msg.Text = fmt.Sprintf("%s: %s, %s at %s, %d guests", title, slackEscape(b.Name), b.Date, b.Time, b.Guests)
+	msg.Blocks = []slackBlock{
+		{Type: "header", Text: &slackText{Type: "plain_text", Text: title}},
+		{Type: "section", Fields: []slackText{
+			{Type: "mrkdwn", Text: "*Date*\n" + b.Date + " " + b.Time},
+			{Type: "mrkdwn", Text: fmt.Sprintf("*Duration*\n%d %s", b.Duration, hours)},
+			{Type: "mrkdwn", Text: "*Guests*\n" + strconv.Itoa(b.Guests)},
+			{Type: "mrkdwn", Text: "*Name*\n" + slackEscape(b.Name)},
+			{Type: "mrkdwn", Text: "*Phone*\n" + slackEscape(b.Phone)},
+		}},
+	}
+	if b.Notes != "" {
+		msg.Blocks = append(msg.Blocks, slackBlock{
+			Type: "section",
+			Text: &slackText{Type: "mrkdwn", Text: "*Notes*\n" + slackEscape(truncate(b.Notes, slackNotesLimit))},
+		})
+	}
+	if s.AdminURL != "" {
+		link := strings.TrimRight(s.AdminURL, "/") + "/admin?booking=" + strconv.FormatInt(b.ID, 10)
+		msg.Blocks = append(msg.Blocks, slackBlock{
+			Type: "actions",
+			Elements: []slackBlock{{
+				Type: "button",
+				Text: &slackText{Type: "plain_text", Text: "Open in admin"},
+				URL:  link,
+			}},
+		})
+	}
+	return msg, true
+}
+
+// slackEscaper escapes the characters Slack reads as markup, so a name like "<!channel>"
+// can't ping everyone or make a link.
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func slackEscape(s string) string {
+	return slackEscaper.Replace(s)
+}
+
+// truncate shortens s to at most limit characters, ending it with "…" when cut.
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:limit-1])) + "…"
+}
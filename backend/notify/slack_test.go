@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"miniparty-backend/models"
+)
+
+func TestSlackMessage(t *testing.T) {
+	s := &Slack{AdminURL: "https://party.example.com/"}
+	b := models.Booking{
+		ID: 42, Reference: "MP-ABC123", Date: "2026-07-10", Time: "14:00", Duration: 2, Guests: 8,
+		Name: "<!channel> & co", Phone: "+919876543210", Notes: strings.Repeat("n", 400),
+	}
+	msg, ok := s.message(Event{Type: BookingConfirmed, Booking: b})
+	if !ok {
+		t.Fatal("no message for a confirmed booking")
+	}
+	if msg.Text != "Booking confirmed MP-ABC123: &lt;!channel&gt; &amp; co, 2026-07-10 at 14:00, 8 guests" {
+		t.Errorf("text %q", msg.Text)
+	}
+	if len(msg.Blocks) != 4 || msg.Blocks[0].Text.Text != "Booking confirmed MP-ABC123" {
+		t.Fatalf("blocks %+v, want a header, the details, the notes and a button", msg.Blocks)
+	}
+	if notes := msg.Blocks[2].Text.Text; len([]rune(notes)) > len("*Notes*\n")+slackNotesLimit || !strings.HasSuffix(notes, "…") {
+		t.Errorf("notes not cut short: %d characters", len([]rune(notes)))
+	}
+	if url := msg.Blocks[3].Elements[0].URL; url != "https://party.example.com/admin?booking=42" {
+		t.Errorf("button opens %s", url)
+	}
+
+	s.AdminURL, b.Notes = "", ""
+	if msg, _ := s.message(Event{Type: BookingCreated, Booking: b}); len(msg.Blocks) != 2 {
+		t.Errorf("without notes or an admin URL: %d blocks, want 2", len(msg.Blocks))
+	}
+	for _, typ := range []string{BookingReminder, BookingRescheduled, BookingConfirmationResent} {
+		if _, ok := s.message(Event{Type: typ, Booking: b}); ok {
+			t.Errorf("%s posted to Slack", typ)
+		}
+	}
+}
+
+func TestSlackNotify(t *testing.T) {
+	var posted slackMessage
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&posted)
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "30")
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+	s := &Slack{URL: srv.URL + "/services/T0/B0/secret", Client: srv.Client()}
+	e := Event{Type: BookingCreated, Booking: models.Booking{Reference: "MP-1"}}
+
+	if err := s.Notify(context.Background(), e); err != nil || !strings.HasPrefix(posted.Text, "New booking MP-1") {
+		t.Errorf("err = %v, posted %q", err, posted.Text)
+	}
+	status = http.StatusTooManyRequests
+	var retryable *RetryableError
+	if err := s.Notify(context.Background(), e); !errors.As(err, &retryable) || retryable.After != 30*time.Second {
+		t.Errorf("rate limited: err = %v, want a retry after 30s", err)
+	}
+
+	srv.Close()
+	if err := s.Notify(context.Background(), e); !errors.As(err, &retryable) || strings.Contains(err.Error(), "secret") {
+		t.Errorf("unreachable: err = %v, want a retryable error without the webhook URL", err)
+	}
+}
@@ -0,0 +1,163 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"miniparty-backend/models"
+)
+
+// telegramAPI is the Telegram Bot API endpoint. Telegram.BaseURL overrides it.
+const telegramAPI = "https://api.telegram.org"
+
+// Telegram posts new, cancelled and rescheduled bookings to the staff's group chat through
+// a bot. Messages are sent one at a time, since Telegram rate-limits each chat; when it
+// answers 429 the retry waits as long as its retry_after asks.
+type Telegram struct {
+	Token   string
+	ChatID  string
+	BaseURL string
+	Client  *http.Client
+
+	// sending holds the one message being sent; the zero Telegram is ready to use.
+	sending chan struct{}
+	once    sync.Once
+}
+
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+func (t *Telegram) Notify(ctx context.Context, e Event) error {
+	text := telegramMessage(e)
+	if text == "" {
+		return nil
+	}
+	body, err := json.Marshal(map[string]any{
+		"chat_id":                  t.ChatID,
+		"text":                     text,
+		"parse_mode":               "MarkdownV2",
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return err
+	}
+
+	t.once.Do(func() { t.sending = make(chan struct{}, 1) })
+	select {
+	case t.sending <- struct{}{}:
+		defer func() { <-t.sending }()
+	case <-ctx.Done():
+		return &RetryableError{Err: ctx.Err()}
+	}
+	return t.send(ctx, body)
+}
+
+func (t *Telegram) send(ctx context.Context, body []byte) error {
+	base := t.BaseURL
+	if base == "" {
+		base = telegramAPI
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/bot"+t.Token+"/sendMessage", bytes.NewReader(body))
+	if err != nil {
+		return errors.New("telegram: invalid bot token")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// The URL carries the bot token, so leave it out of the error that gets logged.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return &RetryableError{Err: fmt.Errorf("telegram: %w", err)}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 300 {
+		return nil
+	}
+
+	var r telegramResponse
+	json.NewDecoder(resp.Body).Decode(&r)
+	err = fmt.Errorf("telegram returned %s", resp.Status)
+	if r.Description != "" {
+		err = fmt.Errorf("telegram: %s", r.Description)
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return &RetryableError{Err: err, After: time.Duration(r.Parameters.RetryAfter) * time.Second}
+	case resp.StatusCode >= 500:
+		return &RetryableError{Err: err}
+	}
+	return err
+}
+
+// telegramMessage is the chat message for e, or "" for events the staff don't need to see.
+func telegramMessage(e Event) string {
+	b := e.Booking
+	var title string
+	switch e.Type {
+	case BookingCreated:
+		title = "New booking"
+	case BookingPromoted:
+		title = "New booking from the waitlist"
+	case BookingCancelled:
+		title = "Booking cancelled"
+	case BookingRescheduled:
+		title = "Booking rescheduled"
+	default:
+		return ""
+	}
+
+	var m strings.Builder
+	fmt.Fprintf(&m, "*%s* %s\n", title, markdownEscape(b.Reference))
+	if e.Type == BookingRescheduled && b.RescheduledFrom != "" {
+		fmt.Fprintf(&m, "*From:* %s\n", markdownEscape(b.RescheduledFrom))
+	}
+	hours := "hours"
+	if b.Duration == 1 {
+		hours = "hour"
+	}
+	fmt.Fprintf(&m, "*When:* %s\n", markdownEscape(fmt.Sprintf("%s %s (%d %s)", b.Date, b.Time, b.Duration, hours)))
+	fmt.Fprintf(&m, "*Guests:* %d\n", b.Guests)
+	fmt.Fprintf(&m, "*Name:* %s\n", markdownEscape(b.Name))
+	fmt.Fprintf(&m, "*Phone:* %s", markdownEscape(b.Phone))
+	if b.Notes != "" && e.Type != BookingCancelled {
+		fmt.Fprintf(&m, "\n*Notes:* %s", markdownEscape(b.Notes))
+	}
+	if b.Status == models.StatusPending && (e.Type == BookingCreated || e.Type == BookingPromoted) {
+		m.WriteString("\n_Call the customer to confirm_")
+	}
+	return m.String()
+}
+
+// markdownEscaper escapes every character MarkdownV2 gives a meaning to, so a customer
+// named "*" or "[x](y)" shows up as typed.
+var markdownEscaper = func() *strings.Replacer {
+	var pairs []string
+	for _, c := range `\_*[]()~` + "`" + `>#+-=|{}.!` {
+		pairs = append(pairs, string(c), `\`+string(c))
+	}
+	return strings.NewReplacer(pairs...)
+}()
+
+func markdownEscape(s string) string {
+	return markdownEscaper.Replace(s)
+}
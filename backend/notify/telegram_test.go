@@ -0,0 +1,95 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"miniparty-backend/models"
+)
+
+func TestTelegramMessage(t *testing.T) {
+	b := models.Booking{
+		Reference: "MP-ABC123", Date: "2026-07-10", Time: "14:00", Duration: 1, Guests: 8,
+		Name: "Ann *Bold* [x](y)", Phone: "+919876543210", Notes: "Cake.", Status: models.StatusPending,
+	}
+	got := telegramMessage(Event{Type: BookingCreated, Booking: b})
+	for _, want := range []string{
+		`*New booking* MP\-ABC123`, `*When:* 2026\-07\-10 14:00 \(1 hour\)`, `*Name:* Ann \*Bold\* \[x\]\(y\)`,
+		`*Phone:* \+919876543210`, `*Notes:* Cake\.`, "_Call the customer to confirm_",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("message lacks %s:\n%s", want, got)
+		}
+	}
+
+	b.RescheduledFrom = "2026-07-09 10:00"
+	b.Status = models.StatusConfirmed
+	got = telegramMessage(Event{Type: BookingRescheduled, Booking: b})
+	if !strings.Contains(got, `*From:* 2026\-07\-09 10:00`) || strings.Contains(got, "confirm_") {
+		t.Errorf("rescheduled message:\n%s", got)
+	}
+	if got := telegramMessage(Event{Type: BookingCancelled, Booking: b}); strings.Contains(got, "Notes") {
+		t.Errorf("cancellation shows the notes:\n%s", got)
+	}
+	for _, typ := range []string{BookingReminder, BookingConfirmed, BookingConfirmationResent} {
+		if got := telegramMessage(Event{Type: typ, Booking: b}); got != "" {
+			t.Errorf("%s posted %q, want nothing", typ, got)
+		}
+	}
+}
+
+func TestTelegramNotify(t *testing.T) {
+	var sent map[string]any
+	status, reply := http.StatusOK, `{"ok":true}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bot123:token/sendMessage" {
+			t.Errorf("posted to %s", r.URL.Path)
+		}
+		sent = nil
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(reply))
+	}))
+	defer srv.Close()
+	tg := &Telegram{Token: "123:token", ChatID: "-1001", BaseURL: srv.URL, Client: srv.Client()}
+	e := Event{Type: BookingCreated, Booking: models.Booking{Reference: "MP-1", Date: "2026-07-10", Time: "14:00", Duration: 2}}
+
+	if err := tg.Notify(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	if sent["chat_id"] != "-1001" || sent["parse_mode"] != "MarkdownV2" || !strings.Contains(sent["text"].(string), "MP\\-1") {
+		t.Errorf("sent %v", sent)
+	}
+
+	status, reply = http.StatusTooManyRequests, `{"ok":false,"description":"Too Many Requests","parameters":{"retry_after":3}}`
+	var retryable *RetryableError
+	if err := tg.Notify(context.Background(), e); !errors.As(err, &retryable) || retryable.After != 3*time.Second {
+		t.Errorf("rate limited: err = %v, want a retry after 3s", err)
+	}
+	status, reply = http.StatusBadRequest, `{"ok":false,"description":"Bad Request: chat not found"}`
+	if err := tg.Notify(context.Background(), e); err == nil || errors.As(err, &retryable) || !strings.Contains(err.Error(), "chat not found") {
+		t.Errorf("bad chat: err = %v, want Telegram's description, not retried", err)
+	}
+
+	sent = nil
+	if err := tg.Notify(context.Background(), Event{Type: BookingReminder, Booking: e.Booking}); err != nil || sent != nil {
+		t.Errorf("reminder: err = %v, sent %v, want nothing posted", err, sent)
+	}
+}
+
+func TestTelegramErrorHidesToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	srv.Close()
+	tg := &Telegram{Token: "123:secret-token", ChatID: "1", BaseURL: srv.URL}
+	err := tg.Notify(context.Background(), Event{Type: BookingCreated})
+	var retryable *RetryableError
+	if !errors.As(err, &retryable) || strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("err = %v, want a retryable error without the token", err)
+	}
+}
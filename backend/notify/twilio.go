@@ -0,0 +1,150 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"miniparty-backend/models"
+)
+
+// twilioAPI is the Twilio REST endpoint. Twilio.BaseURL overrides it.
+const twilioAPI = "https://api.twilio.com"
+
+// Twilio error codes that mean texting the number again won't work either.
+const (
+	twilioInvalidNumber   = 21211 // invalid "To" number
+	twilioNotMobile       = 21614 // "To" is not a mobile number
+	twilioUnsubscribed    = 21610 // the recipient replied STOP
+	twilioRegionForbidden = 21408 // texting that country isn't enabled on the account
+)
+
+// e164 is a phone number as bookings store it, e.g. "+919876543210".
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+// SMSStatusFunc records how a text to a booking's customer went, as one of the models.SMS* states.
+type SMSStatusFunc func(ctx context.Context, bookingID int64, status string) error
+
+// Twilio texts the customer a short confirmation when they book, again when they ask for
+// it, and a reminder before the party, through Twilio's Messages API. From is the sending number, or a Messaging Service
+// SID ("MG...").
+//
+// A text Twilio rejects is recorded with Status and not retried; once a number turns out to
+// be invalid or unsubscribed, the booking gets no more texts.
+type Twilio struct {
+	AccountSID string
+	AuthToken  string
+	From       string
+	BaseURL    string
+	Client     *http.Client
+	Status     SMSStatusFunc
+}
+
+type twilioError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (t *Twilio) Notify(ctx context.Context, e Event) error {
+	b := e.Booking
+	var body string
+	switch e.Type {
+	case BookingCreated:
+		body = fmt.Sprintf("MiniParty: we've got your booking %s for %s at %s, %d guests. We'll call to confirm. Lookup code: %s",
+			b.Reference, b.Date, b.Time, b.Guests, b.ConfirmationCode)
+	case BookingConfirmationResent:
+		state := "we'll call to confirm"
+		if b.Status == models.StatusConfirmed {
+			state = "it's confirmed"
+		}
+		body = fmt.Sprintf("MiniParty: your booking %s is for %s at %s, %d guests; %s. Lookup code: %s",
+			b.Reference, b.Date, b.Time, b.Guests, state, b.ConfirmationCode)
+	case BookingReminder:
+		body = fmt.Sprintf("MiniParty reminder: your party (%s) is on %s at %s, %d guests. See you soon!",
+			b.Reference, b.Date, b.Time, b.Guests)
+	default:
+		return nil
+	}
+	if b.SMSStatus == models.SMSInvalidNumber || b.SMSStatus == models.SMSUnsubscribed {
+		return nil
+	}
+	if !e164.MatchString(b.Phone) {
+		// Only bookings from before phone numbers were normalised can get here.
+		return t.record(ctx, b.ID, models.SMSInvalidNumber)
+	}
+
+	form := url.Values{"To": {b.Phone}, "Body": {body}}
+	if strings.HasPrefix(t.From, "MG") {
+		form.Set("MessagingServiceSid", t.From)
+	} else {
+		form.Set("From", t.From)
+	}
+	status, err := t.send(ctx, form)
+	if status == "" {
+		return err
+	}
+	if recordErr := t.record(ctx, b.ID, status); err == nil {
+		err = recordErr
+	}
+	return err
+}
+
+// send posts one message. It returns the state to record for the booking, or "" with a
+// RetryableError when the failure was Twilio's or the network's rather than the message's.
+func (t *Twilio) send(ctx context.Context, form url.Values) (string, error) {
+	base := t.BaseURL
+	if base == "" {
+		base = twilioAPI
+	}
+	endpoint := base + "/2010-04-01/Accounts/" + url.PathEscape(t.AccountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(t.AccountSID, t.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", &RetryableError{Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 300 {
+		return models.SMSSent, nil
+	}
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		return "", &RetryableError{Err: fmt.Errorf("twilio returned %s", resp.Status)}
+	}
+
+	var e twilioError
+	if json.NewDecoder(resp.Body).Decode(&e) != nil || e.Code == 0 {
+		return models.SMSFailed, fmt.Errorf("twilio returned %s", resp.Status)
+	}
+	err = fmt.Errorf("twilio: %s (%d)", e.Message, e.Code)
+	switch e.Code {
+	case twilioUnsubscribed:
+		return models.SMSUnsubscribed, err
+	case twilioInvalidNumber, twilioNotMobile, twilioRegionForbidden:
+		return models.SMSInvalidNumber, err
+	}
+	return models.SMSFailed, err
+}
+
+func (t *Twilio) record(ctx context.Context, bookingID int64, status string) error {
+	if t.Status == nil {
+		return nil
+	}
+	if err := t.Status(ctx, bookingID, status); err != nil {
+		return fmt.Errorf("recording SMS status %q: %w", status, err)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"miniparty-backend/models"
+)
+
+// twilioServer answers every message with status and body, and records the form it was sent.
+func twilioServer(t *testing.T, status int, body string) (*httptest.Server, *[]url.Values) {
+	t.Helper()
+	var forms []url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		if r.URL.Path != "/2010-04-01/Accounts/AC123/Messages.json" || user != "AC123" || pass != "secret" {
+			t.Errorf("request to %s as %s:%s", r.URL.Path, user, pass)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		forms = append(forms, r.PostForm)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &forms
+}
+
+func twilioBooking() models.Booking {
+	return models.Booking{
+		ID: 7, Phone: "+919876543210", Date: "2026-07-10", Time: "14:00", Guests: 8,
+		Reference: "MP-ABC123", ConfirmationCode: "CODE1234", Status: models.StatusPending,
+	}
+}
+
+// newTwilio is a Twilio notifier sending to srv, and the statuses it records.
+func newTwilio(srv *httptest.Server, from string) (*Twilio, *[]string) {
+	var recorded []string
+	return &Twilio{
+		AccountSID: "AC123", AuthToken: "secret", From: from, BaseURL: srv.URL, Client: srv.Client(),
+		Status: func(_ context.Context, id int64, status string) error {
+			recorded = append(recorded, status)
+			return nil
+		},
+	}, &recorded
+}
+
+func TestTwilioSends(t *testing.T) {
+	srv, forms := twilioServer(t, http.StatusCreated, `{"sid":"SM1"}`)
+	tw, recorded := newTwilio(srv, "+14155550100")
+	b := twilioBooking()
+
+	for _, typ := range []string{BookingCreated, BookingReminder, BookingConfirmationResent} {
+		if err := tw.Notify(context.Background(), Event{Type: typ, Booking: b}); err != nil {
+			t.Errorf("%s: %v", typ, err)
+		}
+	}
+	if err := tw.Notify(context.Background(), Event{Type: BookingCancelled, Booking: b}); err != nil {
+		t.Errorf("cancelled: %v", err)
+	}
+	if len(*forms) != 3 || strings.Join(*recorded, ",") != "sent,sent,sent" {
+		t.Fatalf("sent %d texts and recorded %v, want 3 sent", len(*forms), *recorded)
+	}
+	first := (*forms)[0]
+	if first.Get("To") != b.Phone || first.Get("From") != "+14155550100" || !strings.Contains(first.Get("Body"), b.ConfirmationCode) {
+		t.Errorf("confirmation text: %v", first)
+	}
+	if !strings.Contains((*forms)[1].Get("Body"), "reminder") {
+		t.Errorf("reminder text: %q", (*forms)[1].Get("Body"))
+	}
+
+	service, forms := twilioServer(t, http.StatusCreated, `{}`)
+	tw, _ = newTwilio(service, "MG0123")
+	if err := tw.Notify(context.Background(), Event{Type: BookingCreated, Booking: b}); err != nil {
+		t.Fatal(err)
+	}
+	if got := (*forms)[0]; got.Get("MessagingServiceSid") != "MG0123" || got.Get("From") != "" {
+		t.Errorf("messaging service text: %v", got)
+	}
+}
+
+func TestTwilioFailures(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		recorded  string
+		retryable bool
+	}{
+		{"unsubscribed", http.StatusBadRequest, `{"code":21610,"message":"unsubscribed"}`, models.SMSUnsubscribed, false},
+		{"invalid number", http.StatusBadRequest, `{"code":21211,"message":"invalid To"}`, models.SMSInvalidNumber, false},
+		{"other rejection", http.StatusBadRequest, `{"code":30007,"message":"filtered"}`, models.SMSFailed, false},
+		{"no error body", http.StatusForbidden, ``, models.SMSFailed, false},
+		{"outage", http.StatusServiceUnavailable, ``, "", true},
+		{"rate limited", http.StatusTooManyRequests, ``, "", true},
+	}
+	for _, tt := range tests {
+		srv, _ := twilioServer(t, tt.status, tt.body)
+		tw, recorded := newTwilio(srv, "+14155550100")
+		err := tw.Notify(context.Background(), Event{Type: BookingCreated, Booking: twilioBooking()})
+		var retryable *RetryableError
+		if err == nil || errors.As(err, &retryable) != tt.retryable {
+			t.Errorf("%s: err = %v, want retryable %v", tt.name, err, tt.retryable)
+		}
+		if got := strings.Join(*recorded, ","); got != tt.recorded {
+			t.Errorf("%s: recorded %q, want %q", tt.name, got, tt.recorded)
+		}
+	}
+}
+
+func TestTwilioSkipsDeadNumbers(t *testing.T) {
+	srv, forms := twilioServer(t, http.StatusCreated, `{}`)
+	tw, recorded := newTwilio(srv, "+14155550100")
+	for _, status := range []string{models.SMSInvalidNumber, models.SMSUnsubscribed} {
+		b := twilioBooking()
+		b.SMSStatus = status
+		if err := tw.Notify(context.Background(), Event{Type: BookingReminder, Booking: b}); err != nil {
+			t.Errorf("%s: %v", status, err)
+		}
+	}
+	b := twilioBooking()
+	b.Phone = "98765 43210"
+	if err := tw.Notify(context.Background(), Event{Type: BookingCreated, Booking: b}); err != nil {
+		t.Errorf("unnormalised phone: %v", err)
+	}
+	if len(*forms) != 0 || strings.Join(*recorded, ",") != models.SMSInvalidNumber {
+		t.Errorf("sent %d texts and recorded %v, want none sent and the bad number marked invalid", len(*forms), *recorded)
+	}
+}
@@ -0,0 +1,62 @@
+// Package payments collects booking deposits. Handlers depend on the Provider interface
+// rather than on Stripe directly.
+package payments
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Webhook event types the server acts on. Others are acknowledged and ignored.
+const (
+	PaymentSucceeded = "payment_intent.succeeded"
+)
+
+// ErrInvalidSignature is returned by ParseWebhook when a payload isn't signed with the
+// webhook secret, or its signature is too old to trust.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Intent is a started payment. The frontend completes it with ClientSecret.
+type Intent struct {
+	ID           string
+	ClientSecret string
+}
+
+// Event is a verified webhook notification. IntentID is set for payment intent events.
+type Event struct {
+	ID       string
+	Type     string
+	IntentID string
+}
+
+// Provider takes deposits through a payment processor.
+type Provider interface {
+	// CreateDeposit starts a payment of amount cents for a booking.
+	CreateDeposit(ctx context.Context, bookingID int64, amount int, email string) (Intent, error)
+	// CancelDeposit abandons an unpaid intent so the customer can no longer complete it.
+	CancelDeposit(ctx context.Context, intentID string) error
+	// ParseWebhook verifies a webhook's signature header and decodes its payload.
+	ParseWebhook(payload []byte, signature string, now time.Time) (Event, error)
+}
+
+// FromEnv returns a Stripe provider configured from STRIPE_SECRET_KEY, STRIPE_WEBHOOK_SECRET
+// and STRIPE_CURRENCY (default usd), or nil when STRIPE_SECRET_KEY is unset.
+func FromEnv() Provider {
+	key := os.Getenv("STRIPE_SECRET_KEY")
+	if key == "" {
+		return nil
+	}
+	currency := os.Getenv("STRIPE_CURRENCY")
+	if currency == "" {
+		currency = "usd"
+	}
+	return &Stripe{
+		SecretKey:     key,
+		WebhookSecret: os.Getenv("STRIPE_WEBHOOK_SECRET"),
+		Currency:      currency,
+		Client:        &http.Client{Timeout: 10 * time.Second},
+	}
+}
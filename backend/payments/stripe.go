@@ -0,0 +1,163 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stripeAPI is the Stripe REST endpoint. Stripe.BaseURL overrides it.
+const stripeAPI = "https://api.stripe.com"
+
+// WebhookTolerance is how old a signed webhook may be before it is rejected as a possible replay.
+const WebhookTolerance = 5 * time.Minute
+
+// Stripe takes deposits with Stripe PaymentIntents, over the REST API.
+type Stripe struct {
+	SecretKey     string
+	WebhookSecret string
+	Currency      string
+	BaseURL       string
+	Client        *http.Client
+}
+
+type stripeIntent struct {
+	ID           string `json:"id"`
+	ClientSecret string `json:"client_secret"`
+}
+
+type stripeError struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (s *Stripe) CreateDeposit(ctx context.Context, bookingID int64, amount int, email string) (Intent, error) {
+	id := strconv.FormatInt(bookingID, 10)
+	form := url.Values{
+		"amount":                             {strconv.Itoa(amount)},
+		"currency":                           {s.Currency},
+		"description":                        {"Deposit for booking #" + id},
+		"metadata[booking_id]":               {id},
+		"automatic_payment_methods[enabled]": {"true"},
+	}
+	if email != "" {
+		form.Set("receipt_email", email)
+	}
+
+	var intent stripeIntent
+	if err := s.post(ctx, "/v1/payment_intents", form, &intent); err != nil {
+		return Intent{}, err
+	}
+	return Intent{ID: intent.ID, ClientSecret: intent.ClientSecret}, nil
+}
+
+func (s *Stripe) CancelDeposit(ctx context.Context, intentID string) error {
+	return s.post(ctx, "/v1/payment_intents/"+url.PathEscape(intentID)+"/cancel", url.Values{}, nil)
+}
+
+// post sends a form-encoded request to the Stripe API and decodes the response into out.
+func (s *Stripe) post(ctx context.Context, path string, form url.Values, out any) error {
+	base := s.BaseURL
+	if base == "" {
+		base = stripeAPI
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(s.SecretKey, "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var e stripeError
+		if json.NewDecoder(resp.Body).Decode(&e) == nil && e.Error.Message != "" {
+			return fmt.Errorf("stripe %s: %s (%d)", path, e.Error.Message, resp.StatusCode)
+		}
+		return fmt.Errorf("stripe %s: status %d", path, resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+type stripeEvent struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object struct {
+			ID     string `json:"id"`
+			Object string `json:"object"`
+		} `json:"object"`
+	} `json:"data"`
+}
+
+// ParseWebhook checks the Stripe-Signature header: an HMAC-SHA256 of "timestamp.payload"
+// under the webhook secret, from within WebhookTolerance of now.
+func (s *Stripe) ParseWebhook(payload []byte, signature string, now time.Time) (Event, error) {
+	if s.WebhookSecret == "" {
+		return Event{}, ErrInvalidSignature
+	}
+
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(signature, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			timestamp = v
+		case "v1":
+			signatures = append(signatures, v)
+		}
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return Event{}, ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > WebhookTolerance || age < -WebhookTolerance {
+		return Event{}, ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(s.WebhookSecret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+	valid := false
+	for _, sig := range signatures {
+		if got, err := hex.DecodeString(sig); err == nil && hmac.Equal(got, expected) {
+			valid = true
+		}
+	}
+	if !valid {
+		return Event{}, ErrInvalidSignature
+	}
+
+	var e stripeEvent
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return Event{}, fmt.Errorf("decode webhook: %w", err)
+	}
+	event := Event{ID: e.ID, Type: e.Type}
+	if e.Data.Object.Object == "payment_intent" {
+		event.IntentID = e.Data.Object.ID
+	}
+	return event, nil
+}
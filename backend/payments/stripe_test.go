@@ -0,0 +1,93 @@
+package payments
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+const testSecret = "whsec_test"
+
+// signature is the v1 signature of payload sent at t under secret.
+func signature(secret string, t time.Time, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.%s", t.Unix(), payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sign is the Stripe-Signature header Stripe would send for payload at t under secret.
+func sign(secret string, t time.Time, payload string) string {
+	return fmt.Sprintf("t=%d,v1=%s", t.Unix(), signature(secret, t, payload))
+}
+
+func TestParseWebhook(t *testing.T) {
+	s := &Stripe{WebhookSecret: testSecret}
+	now := time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)
+	succeeded := `{"id":"evt_1","type":"payment_intent.succeeded","data":{"object":{"id":"pi_1","object":"payment_intent"}}}`
+
+	e, err := s.ParseWebhook([]byte(succeeded), sign(testSecret, now, succeeded), now)
+	if err != nil || e != (Event{ID: "evt_1", Type: PaymentSucceeded, IntentID: "pi_1"}) {
+		t.Errorf("signed event = %+v, %v", e, err)
+	}
+	// While Stripe rolls the secret it signs with both; either may be the current one.
+	rolled := sign("whsec_old", now, succeeded) + ",v1=" + signature(testSecret, now, succeeded)
+	if _, err := s.ParseWebhook([]byte(succeeded), rolled, now); err != nil {
+		t.Errorf("signed with the old and new secrets: %v", err)
+	}
+	// Signed within the tolerance either way is fine.
+	for _, at := range []time.Time{now.Add(-WebhookTolerance), now.Add(WebhookTolerance)} {
+		if _, err := s.ParseWebhook([]byte(succeeded), sign(testSecret, at, succeeded), now); err != nil {
+			t.Errorf("signed at %v: %v", at, err)
+		}
+	}
+
+	rejected := []struct {
+		name, payload, header string
+	}{
+		{"wrong secret", succeeded, sign("whsec_other", now, succeeded)},
+		{"changed payload", succeeded, sign(testSecret, now, `{"id":"evt_1"}`)},
+		{"too old", succeeded, sign(testSecret, now.Add(-WebhookTolerance-time.Second), succeeded)},
+		{"from the future", succeeded, sign(testSecret, now.Add(WebhookTolerance+time.Second), succeeded)},
+		{"no timestamp", succeeded, "v1=" + signature(testSecret, now, succeeded)},
+		{"no signature", succeeded, fmt.Sprintf("t=%d", now.Unix())},
+		{"not hex", succeeded, fmt.Sprintf("t=%d,v1=zz", now.Unix())},
+		{"empty header", succeeded, ""},
+	}
+	for _, tt := range rejected {
+		if _, err := s.ParseWebhook([]byte(tt.payload), tt.header, now); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: err = %v, want ErrInvalidSignature", tt.name, err)
+		}
+	}
+	if _, err := (&Stripe{}).ParseWebhook([]byte(succeeded), sign("", now, succeeded), now); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("no webhook secret configured: err = %v, want ErrInvalidSignature", err)
+	}
+
+	// A signed payload that isn't an event is an error, but not a bad signature.
+	if _, err := s.ParseWebhook([]byte("{"), sign(testSecret, now, "{"), now); err == nil || errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("signed garbage: err = %v, want a decode error", err)
+	}
+}
+
+func TestParseWebhookUnknownEvents(t *testing.T) {
+	s := &Stripe{WebhookSecret: testSecret}
+	now := time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		payload string
+		want    Event
+	}{
+		{`{"id":"evt_2","type":"payment_intent.payment_failed","data":{"object":{"id":"pi_2","object":"payment_intent"}}}`,
+			Event{ID: "evt_2", Type: "payment_intent.payment_failed", IntentID: "pi_2"}},
+		// Only a payment intent's ID is taken as one.
+		{`{"id":"evt_3","type":"charge.refunded","data":{"object":{"id":"ch_3","object":"charge"}}}`,
+			Event{ID: "evt_3", Type: "charge.refunded"}},
+	}
+	for _, tt := range tests {
+		if got, err := s.ParseWebhook([]byte(tt.payload), sign(testSecret, now, tt.payload), now); err != nil || got != tt.want {
+			t.Errorf("%s: got %+v, %v; want %+v", tt.want.Type, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"miniparty-backend/handlers"
+	"miniparty-backend/middleware"
+	"miniparty-backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiPrefix is where the current version of the API is mounted.
+const apiPrefix = "/api/v1"
+
+// registerAPI mounts the API routes on g. main mounts them twice: under apiPrefix, and at
+// the old unversioned paths for clients that haven't moved yet. limitBookings is shared
+// so both copies of POST /book draw on the same per-IP budget.
+//
+// Every admin route accepts viewer tokens; adminOnly additionally restricts the ones that change data.
+// Reschedule and check-in also take customers' own tokens, so their handlers check the role themselves.
+func registerAPI(g *gin.RouterGroup, limitBookings, limitLookups gin.HandlerFunc) {
+	adminOnly := middleware.RequireRole(middleware.RoleAdmin)
+
+	g.POST("/book", limitBookings, middleware.OptionalAdminAuth(), middleware.Idempotency(store.Gorm{}), handlers.CreateBooking)
+	g.GET("/availability", handlers.GetAvailability)
+	g.GET("/availability/month", handlers.GetMonthAvailability)
+	g.GET("/packages", handlers.GetPackages)
+	g.GET("/rooms", handlers.GetRooms)
+	g.GET("/addons", handlers.GetAddons)
+	g.GET("/schedule", handlers.GetSchedule)
+	g.GET("/status", handlers.GetStatus)
+	g.POST("/bookings/cancel", handlers.CancelBookingByToken)
+	g.POST("/checkin", middleware.OptionalAdminAuth(), handlers.CheckIn)
+	g.GET("/bookings", middleware.AdminAuth(), handlers.GetBookings)
+	g.GET("/bookings/schedule.pdf", middleware.AdminAuth(), handlers.GetSchedulePDF)
+	g.GET("/bookings/export.csv", middleware.AdminAuth(), handlers.ExportBookingsCSV)
+	g.GET("/bookings/export.ndjson", middleware.AdminAuth(), handlers.ExportBookingsNDJSON)
+	g.GET("/bookings/calendar.ics", middleware.AdminAuthFeed(), handlers.GetCalendarFeed)
+	g.GET("/bookings/:id", middleware.AdminAuth(), handlers.GetBooking)
+	g.GET("/bookings/:id/ics", middleware.AdminAuthFeed(), handlers.GetBookingICS)
+	g.PUT("/bookings/:id", middleware.AdminAuth(), adminOnly, handlers.UpdateBooking)
+	g.DELETE("/bookings/:id", middleware.AdminAuth(), adminOnly, handlers.DeleteBooking)
+	g.DELETE("/bookings/series/:series_id", middleware.AdminAuth(), adminOnly, handlers.CancelSeries)
+	g.PATCH("/bookings/:id/deposit", middleware.AdminAuth(), adminOnly, handlers.UpdateDeposit)
+	g.GET("/bookings/:id/payments", middleware.AdminAuth(), handlers.GetPayments)
+	g.POST("/bookings/:id/payments", middleware.AdminAuth(), adminOnly, handlers.RecordPayment)
+	g.POST("/bookings/bulk-status", middleware.AdminAuth(), adminOnly, handlers.BulkUpdateStatus)
+	// A customer's reschedule is a guess at a code too, so it shares the lookups' limit.
+	g.POST("/bookings/:id/reschedule", middleware.OptionalAdminAuth(), unlessStaff(limitLookups), handlers.RescheduleBooking)
+	g.POST("/bookings/:id/confirm", middleware.AdminAuth(), adminOnly, handlers.ConfirmBooking)
+	g.POST("/bookings/:id/cancel", middleware.AdminAuth(), adminOnly, handlers.CancelBooking)
+	g.POST("/bookings/:id/no-show", middleware.AdminAuth(), adminOnly, handlers.MarkNoShow)
+	g.DELETE("/bookings/:id/no-show", middleware.AdminAuth(), adminOnly, handlers.UndoNoShow)
+	g.POST("/bookings/:id/restore", middleware.AdminAuth(), adminOnly, handlers.RestoreBooking)
+	g.POST("/bookings/:id/retention-hold", middleware.AdminAuth(), adminOnly, handlers.HoldRetention)
+	g.DELETE("/bookings/:id/retention-hold", middleware.AdminAuth(), adminOnly, handlers.ReleaseRetention)
+
+	admin := g.Group("/admin", middleware.AdminAuth())
+	admin.POST("/blackouts", adminOnly, handlers.CreateBlackout)
+	admin.GET("/blackouts", handlers.GetBlackouts)
+	admin.DELETE("/blackouts/:id", adminOnly, handlers.DeleteBlackout)
+	admin.POST("/packages", adminOnly, handlers.CreatePackage)
+	admin.GET("/packages", handlers.GetAllPackages)
+	admin.PUT("/packages/:id", adminOnly, handlers.UpdatePackage)
+	admin.DELETE("/packages/:id", adminOnly, handlers.DeletePackage)
+	admin.POST("/rooms", adminOnly, handlers.CreateRoom)
+	admin.GET("/rooms", handlers.GetAllRooms)
+	admin.PUT("/rooms/:id", adminOnly, handlers.UpdateRoom)
+	admin.DELETE("/rooms/:id", adminOnly, handlers.DeleteRoom)
+	admin.POST("/addons", adminOnly, handlers.CreateAddon)
+	admin.GET("/addons", handlers.GetAllAddons)
+	admin.PUT("/addons/:id", adminOnly, handlers.UpdateAddon)
+	admin.DELETE("/addons/:id", adminOnly, handlers.DeleteAddon)
+	admin.GET("/stats", handlers.GetStats)
+	admin.GET("/summary", handlers.GetDailySummary)
+	admin.GET("/calendar", handlers.GetOccupancyCalendar)
+	admin.GET("/customers/:email/bookings", handlers.GetCustomerBookings)
+	admin.DELETE("/customers", adminOnly, handlers.EraseCustomer)
+	admin.GET("/retention", handlers.GetRetention)
+	admin.GET("/waitlist", handlers.GetWaitlist)
+	admin.DELETE("/waitlist/:id", adminOnly, handlers.DeleteWaitlistEntry)
+	admin.GET("/settings", handlers.GetSettings)
+	admin.PUT("/settings", adminOnly, handlers.UpdateSettings)
+	admin.PUT("/schedule", adminOnly, handlers.UpdateSchedule)
+	admin.PUT("/maintenance", adminOnly, handlers.UpdateMaintenance)
+	admin.GET("/audit", handlers.GetAuditLog)
+	admin.POST("/bookings/bulk", adminOnly, handlers.BulkAction)
+	admin.POST("/bookings/import", adminOnly, middleware.BodyLimit(handlers.MaxImportBody), handlers.ImportBookings)
+	admin.POST("/calendar/resync", adminOnly, handlers.ResyncCalendar)
+	admin.POST("/backup", adminOnly, handlers.CreateBackup)
+}
+
+// registerSessions mounts admin login and refresh. They are new, so they only exist under apiPrefix.
+// Login is limited to rpm attempts a minute per IP, like POST /book.
+func registerSessions(g *gin.RouterGroup, rpm int) {
+	g.POST("/admin/login", middleware.RateLimit(rpm), handlers.Login)
+	g.POST("/admin/refresh", middleware.AdminAuth(), handlers.RefreshSession)
+}
+
+// registerLookups mounts the customer's own-booking lookups and confirmation resends under
+// apiPrefix, behind limit, the LOOKUP_RATE_LIMIT_RPM limit per IP. That is tighter than
+// POST /book's, since every request is a guess at a code. Staff fetching confirmations
+// aren't guessing, so they skip the limit.
+func registerLookups(g *gin.RouterGroup, limit gin.HandlerFunc) {
+	g.GET("/my-booking", limit, handlers.GetMyBooking)
+	g.GET("/bookings/:id/confirmation.pdf", middleware.OptionalAdminAuth(), unlessStaff(limit), handlers.GetConfirmationPDF)
+	g.GET("/bookings/:id/ticket.png", middleware.OptionalAdminAuth(), unlessStaff(limit), handlers.GetTicketPNG)
+	g.POST("/bookings/:id/resend-confirmation", middleware.OptionalAdminAuth(), unlessStaff(limit), handlers.ResendConfirmation)
+}
+
+// registerQuotes mounts POST /quote under apiPrefix, limited to rpm requests a minute per
+// IP. The form asks for a quote whenever the booking changes, so it allows more than POST /book.
+func registerQuotes(g *gin.RouterGroup, rpm int) {
+	g.POST("/quote", middleware.RateLimit(rpm), middleware.OptionalAdminAuth(), handlers.GetQuote)
+}
+
+// registerEvents mounts the live booking feed. It goes on a group without
+// middleware.Timeout, which would end the stream, and only exists under apiPrefix.
+func registerEvents(g *gin.RouterGroup) {
+	g.GET("/admin/events", middleware.AdminAuthFeed(), handlers.StreamEvents)
+}
+
+// unlessStaff runs limit only for customers, the requests OptionalAdminAuth left without a role.
+func unlessStaff(limit gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if middleware.Role(c) != "" {
+			c.Next()
+			return
+		}
+		limit(c)
+	}
+}
+
+// registerWebhooks mounts the payment provider's webhook. Like the session routes it only exists under apiPrefix.
+func registerWebhooks(g *gin.RouterGroup) {
+	g.POST("/webhooks/stripe", middleware.BodyLimit(handlers.MaxWebhookBody), handlers.StripeWebhook)
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"miniparty-backend/apidocs"
+	"miniparty-backend/config"
+	"miniparty-backend/db"
+	"miniparty-backend/db/dbtest"
+	"miniparty-backend/middleware"
+	"miniparty-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testDB gives the test a fresh, migrated SQLite database as db.DB.
+func testDB(t *testing.T) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	dbtest.Init(t)
+}
+
+func TestCustomerReschedulesShareTheLookupLimit(t *testing.T) {
+	testDB(t)
+	middleware.ConfigureAuth(config.Auth{Secret: "admin-secret", MaxFailures: 1000, FailureWindow: time.Minute, Lockout: time.Minute})
+	r := gin.New()
+	limitLookups := middleware.RateLimit(1)
+	registerAPI(r.Group(""), func(c *gin.Context) {}, limitLookups)
+	registerLookups(r.Group("/lookups"), limitLookups)
+
+	send := func(target string, admin bool) int {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(`{"date":"2026-07-10","time":"15:00","token":"guess"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if admin {
+			req.Header.Set("X-Admin-Token", "admin-secret")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for i := 0; i < 10; i++ {
+		if code := send("/bookings/1/reschedule", true); code == http.StatusTooManyRequests {
+			t.Fatalf("admin reschedule %d was rate limited", i+1)
+		}
+	}
+	limited := 0
+	for i := 0; i < 4; i++ {
+		if send("/bookings/1/reschedule", false) == http.StatusTooManyRequests {
+			limited++
+		}
+	}
+	if limited == 0 {
+		t.Fatal("customer reschedules were never rate limited")
+	}
+	// The lookups draw on the same allowance, already spent.
+	if code := send("/lookups/bookings/1/resend-confirmation", false); code != http.StatusTooManyRequests {
+		t.Errorf("lookup after the reschedules: status %d, want 429", code)
+	}
+}
+
+func TestFallbackEnvelopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.RequestLogger())
+	r.GET("/bookings", func(c *gin.Context) {})
+	registerFallbacks(r, fstest.MapFS{"index.html": {Data: []byte("<html></html>")}})
+
+	tests := []struct {
+		method, target string
+		status         int
+		code           string
+	}{
+		{http.MethodGet, "/api/v1/nowhere", http.StatusNotFound, models.CodeNotFound},
+		{http.MethodGet, "/assets/app-123.js", http.StatusNotFound, models.CodeNotFound},
+		{http.MethodDelete, "/bookings", http.StatusMethodNotAllowed, models.CodeMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, nil)
+		req.Header.Set("Accept", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var body models.ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != tt.status || body.Code != tt.code || body.Message == "" || body.RequestID == "" {
+			t.Errorf("%s %s = %d %s, want %d %s", tt.method, tt.target, w.Code, w.Body.String(), tt.status, tt.code)
+		}
+	}
+}
+
+func TestBookingDetailRoute(t *testing.T) {
+	testDB(t)
+	middleware.ConfigureAuth(config.Auth{Secret: "admin-secret", Tokens: map[string]string{"viewer-token": middleware.RoleViewer},
+		MaxFailures: 1000, FailureWindow: time.Minute, Lockout: time.Minute})
+	b := models.Booking{Name: "Ada", Email: "ada@example.com", Phone: "+14155550123", Date: "2026-07-10", Time: "14:00",
+		Duration: 2, Guests: 4, Status: models.StatusConfirmed, Reference: "MP-DETAIL", CancelToken: "cancel", ConfirmationCode: "C1234567"}
+	if err := db.DB.Create(&b).Error; err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(middleware.RequestLogger())
+	noLimit := func(c *gin.Context) {}
+	registerAPI(r.Group(apiPrefix), noLimit, noLimit)
+	registerAPI(r.Group(""), noLimit, noLimit)
+
+	get := func(target, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if token != "" {
+			req.Header.Set("X-Admin-Token", token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	for _, prefix := range []string{apiPrefix, ""} {
+		target := prefix + "/bookings/" + strconv.FormatInt(b.ID, 10)
+		for _, token := range []string{"admin-secret", "viewer-token"} {
+			w := get(target, token)
+			var got models.Booking
+			if err := json.Unmarshal(w.Body.Bytes(), &got); w.Code != http.StatusOK || err != nil || got.ID != b.ID || got.Reference != b.Reference {
+				t.Errorf("GET %s as %s = %d %s, want booking %d", target, token, w.Code, w.Body.String(), b.ID)
+			}
+		}
+		if w := get(target, ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("GET %s without a token = %d, want 401", target, w.Code)
+		}
+		if w := get(prefix+"/bookings/12345", "viewer-token"); w.Code != http.StatusNotFound {
+			t.Errorf("GET a missing booking under %q = %d, want 404", prefix, w.Code)
+		}
+	}
+}
+
+// TestSpecCoversRoutes checks that every route under apiPrefix is described in the OpenAPI
+// document, so the spec can't fall behind a new route.
+func TestSpecCoversRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	v1 := r.Group(apiPrefix)
+	none := func(c *gin.Context) {}
+	registerAPI(v1, none, none)
+	registerSessions(v1, 6)
+	registerLookups(v1, none)
+	registerQuotes(v1, 6)
+	registerWebhooks(v1)
+	registerEvents(r.Group(apiPrefix))
+
+	var spec struct {
+		Paths map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(apidocs.Spec, &spec); err != nil {
+		t.Fatal(err)
+	}
+	param := regexp.MustCompile(`:([a-z_]+)`)
+	for _, route := range r.Routes() {
+		path := param.ReplaceAllString(strings.TrimPrefix(route.Path, apiPrefix), "{$1}")
+		if _, ok := spec.Paths[path][strings.ToLower(route.Method)]; !ok {
+			t.Errorf("%s %s is not in openapi.json", route.Method, path)
+		}
+	}
+}
+
+// TestViewerRoutes checks the role each route needs: viewers can read everything staff
+// can, and every admin route that changes data refuses them.
+func TestViewerRoutes(t *testing.T) {
+	testDB(t)
+	middleware.ConfigureAuth(config.Auth{Secret: "admin-secret", Tokens: map[string]string{"viewer-token": middleware.RoleViewer},
+		MaxFailures: 1000, FailureWindow: time.Minute, Lockout: time.Minute})
+	r := gin.New()
+	r.Use(middleware.RequestLogger())
+	none := func(c *gin.Context) {}
+	registerAPI(r.Group(""), none, none)
+	// Customers use these too, with codes of their own, so they don't need a staff token.
+	public := map[string]bool{
+		"POST /book": true, "POST /bookings/cancel": true, "POST /checkin": true, "POST /bookings/:id/reschedule": true,
+	}
+
+	checked := 0
+	for _, route := range r.Routes() {
+		name := route.Method + " " + route.Path
+		if public[name] || !strings.Contains(name, "/admin") && !strings.HasPrefix(route.Path, "/bookings") {
+			continue
+		}
+		checked++
+		target := strings.NewReplacer(":id", "1", ":series_id", "S1", ":email", "ada@example.com").Replace(route.Path)
+		do := func(token string) int {
+			req := httptest.NewRequest(route.Method, target, strings.NewReader("{}"))
+			req.Header.Set("Content-Type", "application/json")
+			if token != "" {
+				req.Header.Set("X-Admin-Token", token)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			return w.Code
+		}
+		if code := do(""); code != http.StatusUnauthorized {
+			t.Errorf("%s without a token = %d, want 401", name, code)
+		}
+		code := do("viewer-token")
+		if write := route.Method != http.MethodGet; write != (code == http.StatusForbidden) {
+			t.Errorf("%s as a viewer = %d, want 403 only for writes", name, code)
+		}
+	}
+	if checked < 40 {
+		t.Errorf("checked %d staff routes, want them all", checked)
+	}
+}
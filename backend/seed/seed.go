@@ -0,0 +1,223 @@
+// Package seed makes up plausible bookings for working on the admin dashboard locally and for
+// tests that want a populated database. A Generator with the same seed makes the same
+// bookings, so a bug seen in one data set can be reproduced.
+//
+// The people are fictional: emails are at the example domains, which never deliver, and
+// phone numbers come from the ranges kept for drama in the US and UK.
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"miniparty-backend/models"
+	"miniparty-backend/settings"
+	"miniparty-backend/store"
+
+	"gorm.io/gorm"
+)
+
+var (
+	firstNames = []string{
+		"Aarav", "Ananya", "Arjun", "Diya", "Ishaan", "Kavya", "Meera", "Rohan", "Saanvi", "Vikram",
+		"Amelia", "Oliver", "Sophie", "James", "Chloe", "Daniel", "Grace", "Lucas", "Maya", "Noah",
+	}
+	lastNames = []string{
+		"Sharma", "Patel", "Iyer", "Nair", "Reddy", "Gupta", "Menon", "Das", "Kapoor", "Rao",
+		"Smith", "Jones", "Taylor", "Brown", "Wilson", "Evans", "Walker", "Wright", "Hughes", "Clarke",
+	}
+	emailDomains = []string{"example.com", "example.org", "example.net"}
+	notes        = []string{
+		"Birthday party, 7th birthday",
+		"Need a high chair",
+		"Nut allergy in the group",
+		"Will bring our own cake",
+		"Surprise party, please call after 6pm",
+		"Office team outing",
+	}
+)
+
+// referenceAlphabet is the one real booking references use.
+const referenceAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// Statuses are drawn with these weights, out of 100.
+var statusWeights = []struct {
+	status string
+	weight int
+}{
+	{models.StatusPending, 40},
+	{models.StatusConfirmed, 45},
+	{models.StatusCancelled, 15},
+}
+
+// Generator draws bookings from a seeded random source.
+type Generator struct {
+	rand *rand.Rand
+	// From is the day before the first a booking can fall on, and Days how many days after it they are spread over.
+	From time.Time
+	Days int
+	// Venue supplies the opening hours, slot grid, duration limits and guest cap bookings keep to.
+	Venue settings.Venue
+}
+
+// New returns a Generator for seed that spreads bookings over the days days after from, within venue's rules.
+func New(seed int64, from time.Time, days int, venue settings.Venue) *Generator {
+	return &Generator{rand: rand.New(rand.NewSource(seed)), From: from, Days: days, Venue: venue}
+}
+
+// Booking makes up a booking. It has no ID, room or slot key; Insert gives it those.
+func (g *Generator) Booking() models.Booking {
+	first, last := pick(g.rand, firstNames), pick(g.rand, lastNames)
+	b := models.Booking{
+		Name:             first + " " + last,
+		Email:            fmt.Sprintf("%s.%s%d@%s", strings.ToLower(first), strings.ToLower(last), g.rand.Intn(100), pick(g.rand, emailDomains)),
+		Phone:            g.Phone(),
+		Guests:           1 + g.rand.Intn(g.Venue.MaxGuests),
+		Status:           g.status(),
+		CancelToken:      g.code("0123456789abcdef", 32),
+		ConfirmationCode: g.code("23456789ABCDEFGHJKLMNPQRSTUVWXYZ", 8),
+		Reference:        "MP-" + g.code(referenceAlphabet, 6),
+	}
+	if g.rand.Intn(4) == 0 {
+		b.Notes = pick(g.rand, notes)
+	}
+	g.schedule(&b)
+	return b
+}
+
+// Phone makes up an E.164 number from a range that is never given out: 555-0100 to 555-0199
+// in the US, or 07700 900000 to 900999 in the UK.
+func (g *Generator) Phone() string {
+	if g.rand.Intn(2) == 0 {
+		return fmt.Sprintf("+1%03d5550%03d", 200+g.rand.Intn(800), 100+g.rand.Intn(100))
+	}
+	return fmt.Sprintf("+447700900%03d", g.rand.Intn(1000))
+}
+
+// schedule puts b on a random day, start time and duration that fit the venue's opening hours
+// and slot grid, and sets its created time a few days before that.
+func (g *Generator) schedule(b *models.Booking) {
+	v := g.Venue
+	loc := models.VenueLocation()
+	day := g.From.In(loc).AddDate(0, 0, 1+g.rand.Intn(g.Days))
+	open, close, isOpen := v.HoursOn(day.Weekday())
+	// Move to the next day the venue opens, if it opens at all.
+	for i := 0; !isOpen && i < 7; i++ {
+		day = day.AddDate(0, 0, 1)
+		open, close, isOpen = v.HoursOn(day.Weekday())
+	}
+	if !isOpen {
+		open, close = v.OpenMinutes(), v.CloseMinutes()
+	}
+
+	b.Duration = v.MinDurationHours + g.rand.Intn(v.MaxDurationHours-v.MinDurationHours+1)
+	if span := close - open; b.Duration*60 > span {
+		b.Duration = max(span/60, 1)
+	}
+	slots := (close - open - b.Duration*60) / v.SlotMinutes
+	start := open + g.rand.Intn(slots+1)*v.SlotMinutes
+
+	at := time.Date(day.Year(), day.Month(), day.Day(), start/60, start%60, 0, 0, loc)
+	b.Date, b.Time = at.Format("2006-01-02"), at.Format("15:04")
+	starts := at.UTC()
+	b.StartsAt = &starts
+
+	created := g.From.Add(-time.Duration(g.rand.Intn(14*24)) * time.Hour).UTC()
+	b.CreatedAt, b.UpdatedAt = created, created
+}
+
+func (g *Generator) status() string {
+	n := g.rand.Intn(100)
+	for _, s := range statusWeights {
+		if n < s.weight {
+			return s.status
+		}
+		n -= s.weight
+	}
+	return models.StatusPending
+}
+
+func (g *Generator) code(alphabet string, n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = alphabet[g.rand.Intn(len(alphabet))]
+	}
+	return string(b)
+}
+
+func pick(r *rand.Rand, from []string) string {
+	return from[r.Intn(len(from))]
+}
+
+// maxMisses is how many times Insert moves a booking that doesn't fit before it takes the
+// venue's calendar to be full.
+const maxMisses = 200
+
+// ErrFull is returned by Insert when it can't find free slots for all the bookings asked for.
+var ErrFull = errors.New("not enough free slots left for the bookings")
+
+// Insert adds n bookings from g to tx, each in one of the venue's rooms. A booking that would
+// overlap another active one in its room is moved to another day and time, so the result is
+// a calendar the API itself could have taken. It returns how many it inserted, which is short
+// of n only with an error.
+func Insert(ctx context.Context, tx *gorm.DB, g *Generator, n int) (int, error) {
+	tx = tx.WithContext(ctx)
+	var rooms []models.Room
+	if err := tx.Order("id ASC").Find(&rooms).Error; err != nil {
+		return 0, err
+	}
+
+	for inserted := 0; inserted < n; inserted++ {
+		b := g.Booking()
+		if len(rooms) > 0 {
+			room := rooms[g.rand.Intn(len(rooms))]
+			b.RoomID = &room.ID
+		}
+		for {
+			var taken int64
+			if err := tx.Unscoped().Model(&models.Booking{}).Where("reference = ?", b.Reference).Count(&taken).Error; err != nil {
+				return inserted, err
+			}
+			if taken == 0 {
+				break
+			}
+			b.Reference = "MP-" + g.code(referenceAlphabet, 6)
+		}
+		for misses := 0; ; misses++ {
+			if misses == maxMisses {
+				return inserted, ErrFull
+			}
+			ok, err := fits(tx, &b)
+			if err != nil {
+				return inserted, err
+			}
+			if ok {
+				break
+			}
+			g.schedule(&b)
+		}
+		if err := tx.Create(&b).Error; err != nil {
+			return inserted, err
+		}
+	}
+	return n, nil
+}
+
+// fits reports whether b can go in its room: it is cancelled, or doesn't overlap an active
+// booking there. It gives an active booking its slot key.
+func fits(tx *gorm.DB, b *models.Booking) (bool, error) {
+	// Only active bookings hold their slot, as through the API.
+	if b.Status == models.StatusCancelled {
+		return true, nil
+	}
+	conflicts, err := store.FindConflicts(tx, b, 0)
+	if err != nil || len(conflicts) > 0 {
+		return false, err
+	}
+	b.SlotKey = models.SlotKey(b.Date, b.Time, b.RoomID)
+	return true, nil
+}
@@ -0,0 +1,106 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"miniparty-backend/db"
+	"miniparty-backend/db/dbtest"
+	"miniparty-backend/models"
+	"miniparty-backend/settings"
+	"miniparty-backend/store"
+)
+
+var from = time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+
+func TestGeneratorIsRepeatable(t *testing.T) {
+	a, b := New(7, from, 30, settings.Defaults()), New(7, from, 30, settings.Defaults())
+	other := New(8, from, 30, settings.Defaults())
+	same := true
+	for i := 0; i < 20; i++ {
+		x, y, z := a.Booking(), b.Booking(), other.Booking()
+		if x.Reference != y.Reference || x.Name != y.Name || x.Date != y.Date || x.Time != y.Time {
+			t.Fatalf("booking %d differs with the same seed: %+v and %+v", i, x, y)
+		}
+		same = same && x.Reference == z.Reference
+	}
+	if same {
+		t.Error("another seed made the same bookings")
+	}
+}
+
+func TestGeneratorKeepsToVenue(t *testing.T) {
+	v := settings.Defaults()
+	g := New(1, from, 60, v)
+	phone := regexp.MustCompile(`^\+(1[2-9][0-9]{2}5550(1[0-9]{2})|447700900[0-9]{3})$`)
+	loc := models.VenueLocation()
+	for i := 0; i < 500; i++ {
+		b := g.Booking()
+		day, err := time.ParseInLocation("2006-01-02", b.Date, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !day.After(from.In(loc).Truncate(24*time.Hour)) || day.After(from.AddDate(0, 0, 68)) {
+			t.Errorf("%s is outside the 60 days after %s", b.Date, from.Format("2006-01-02"))
+		}
+		open, close, _ := v.HoursOn(day.Weekday())
+		var h, m int
+		if _, err := fmt.Sscanf(b.Time, "%d:%d", &h, &m); err != nil {
+			t.Fatal(err)
+		}
+		start := h*60 + m
+		if start < open || start+b.Duration*60 > close || (start-open)%v.SlotMinutes != 0 {
+			t.Errorf("%s %s for %dh doesn't fit the opening hours and slot grid", b.Date, b.Time, b.Duration)
+		}
+		if b.Duration < v.MinDurationHours || b.Duration > v.MaxDurationHours || b.Guests < 1 || b.Guests > v.MaxGuests {
+			t.Errorf("%dh for %d guests is outside the venue's limits", b.Duration, b.Guests)
+		}
+		if !phone.MatchString(b.Phone) {
+			t.Errorf("phone %s isn't from a fictional range", b.Phone)
+		}
+		if want, _ := time.ParseInLocation("2006-01-02 15:04", b.Date+" "+b.Time, loc); b.StartsAt == nil || !b.StartsAt.Equal(want) {
+			t.Errorf("starts_at %v, want %v", b.StartsAt, want)
+		}
+		if !b.CreatedAt.Before(from.Add(time.Second)) {
+			t.Errorf("created %v, after the bookings start", b.CreatedAt)
+		}
+	}
+}
+
+func TestInsert(t *testing.T) {
+	dbtest.Init(t)
+	ctx := context.Background()
+
+	n, err := Insert(ctx, db.DB, New(3, from, 30, settings.Defaults()), 40)
+	if err != nil || n != 40 {
+		t.Fatalf("Insert = %d, %v", n, err)
+	}
+	var bookings []models.Booking
+	if err := db.DB.Find(&bookings).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(bookings) != 40 {
+		t.Fatalf("%d bookings in the database, want 40", len(bookings))
+	}
+	for _, b := range bookings {
+		if b.RoomID == nil {
+			t.Errorf("booking %d has no room", b.ID)
+		}
+		if b.Status == models.StatusCancelled {
+			continue
+		}
+		if conflicts, err := store.FindConflicts(db.DB, &b, b.ID); err != nil || len(conflicts) > 0 {
+			t.Errorf("booking %d overlaps %d others (%v)", b.ID, len(conflicts), err)
+		}
+	}
+
+	// One day can't take a thousand parties.
+	n, err = Insert(ctx, db.DB, New(4, from, 1, settings.Defaults()), 1000)
+	if !errors.Is(err, ErrFull) || n == 0 || n >= 1000 {
+		t.Errorf("Insert into one day = %d, %v, want some then ErrFull", n, err)
+	}
+}
@@ -0,0 +1,103 @@
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"miniparty-backend/messages"
+	"miniparty-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maintenanceKey is the settings row Maintenance is kept in. It isn't one of Venue's
+// settings: PUT /admin/maintenance changes it, not PUT /admin/settings.
+const maintenanceKey = "maintenance"
+
+// MaxMaintenanceMessage is the longest message customers can be shown, in characters.
+const MaxMaintenanceMessage = 500
+
+// DefaultMaintenanceMessage is shown when maintenance is switched on without a message.
+const DefaultMaintenanceMessage = "We're not taking new bookings right now. Please check back soon."
+
+// Maintenance pauses new bookings from customers without taking the site down. Until,
+// when set, ends it without anyone switching it off.
+type Maintenance struct {
+	Enabled bool       `json:"enabled"`
+	Message string     `json:"message,omitempty"`
+	Until   *time.Time `json:"until,omitempty"`
+}
+
+// Active reports whether m is pausing bookings at t.
+func (m Maintenance) Active(t time.Time) bool {
+	return m.Enabled && (m.Until == nil || t.Before(*m.Until))
+}
+
+// Validate checks m as of now, keyed like Venue.Validate.
+func (m Maintenance) Validate(now time.Time) map[string][]messages.Message {
+	errs := map[string][]messages.Message{}
+	if len([]rune(m.Message)) > MaxMaintenanceMessage {
+		errs["message"] = append(errs["message"], messages.New(messages.MaintenanceMessage, "max", MaxMaintenanceMessage))
+	}
+	if m.Enabled && m.Until != nil && !m.Until.After(now) {
+		errs["until"] = append(errs["until"], messages.New(messages.MaintenanceUntil))
+	}
+	return errs
+}
+
+// maintenance is the cached Maintenance, replaced by Load and SaveMaintenance.
+var maintenance atomic.Pointer[Maintenance]
+
+// CurrentMaintenance returns the cached maintenance mode, off before the first Load.
+func CurrentMaintenance() Maintenance {
+	if m := maintenance.Load(); m != nil {
+		return *m
+	}
+	return Maintenance{}
+}
+
+// loadMaintenance caches the maintenance row among rows, or switches maintenance off if
+// there is none.
+func loadMaintenance(rows []models.Setting) error {
+	var m Maintenance
+	for _, r := range rows {
+		if r.Key != maintenanceKey {
+			continue
+		}
+		if err := json.Unmarshal([]byte(r.Value), &m); err != nil {
+			return fmt.Errorf("decode maintenance: %w", err)
+		}
+	}
+	maintenance.Store(&m)
+	return nil
+}
+
+// SaveMaintenance writes m and then swaps it into the cache. with, if not nil, runs in
+// the same transaction, as in Save.
+func SaveMaintenance(tx *gorm.DB, m Maintenance, with func(tx *gorm.DB) error) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	value, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	err = tx.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+		}).Create(&models.Setting{Key: maintenanceKey, Value: string(value)}).Error
+		if err != nil || with == nil {
+			return err
+		}
+		return with(tx)
+	})
+	if err != nil {
+		return err
+	}
+	maintenance.Store(&m)
+	return nil
+}
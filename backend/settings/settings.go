@@ -0,0 +1,324 @@
+// Package settings holds the venue's booking rules and rates. They live in the settings
+// table, one row per key, so an admin can change them without a redeploy. The environment
+// variables that used to set them now only seed the table the first time a key is missing.
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"miniparty-backend/messages"
+	"miniparty-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const timeLayout = "15:04"
+
+// Venue is the typed view of the settings table. Each JSON name is a row's key.
+type Venue struct {
+	MaxGuests        int    `json:"max_guests"`
+	MinDurationHours int    `json:"min_duration_hours"`
+	MaxDurationHours int    `json:"max_duration_hours"`
+	OpenTime         string `json:"open_time"`
+	CloseTime        string `json:"close_time"`
+	SlotMinutes      int    `json:"slot_minutes"`
+	MinLeadHours     int    `json:"min_lead_hours"`
+	MaxAdvanceDays   int    `json:"max_advance_days"`
+	// MaxBookingsPerEmail is how many upcoming bookings one customer may hold, counting
+	// those made with their email or their phone. Its key predates the phone.
+	MaxBookingsPerEmail int     `json:"max_bookings_per_email"`
+	HourlyRateCents     int     `json:"hourly_rate_cents"`
+	PerGuestRateCents   int     `json:"per_guest_rate_cents"`
+	GuestThreshold      int     `json:"guest_threshold"`
+	WeekendMultiplier   float64 `json:"weekend_multiplier"`
+	// FullDayRateCents replaces the hourly charge for a booking of the whole venue for the
+	// day, and FullDayMaxGuests its guest cap in place of MaxGuests. Customers can only book
+	// a full day themselves with FullDayPublic; otherwise it takes an admin.
+	FullDayRateCents int  `json:"full_day_rate_cents"`
+	FullDayMaxGuests int  `json:"full_day_max_guests"`
+	FullDayPublic    bool `json:"full_day_public"`
+	// CancellationCutoffHours is how long before a booking starts customers stop being able
+	// to cancel or move it themselves; 0 lets them until it starts. Admins aren't held to it.
+	CancellationCutoffHours int `json:"cancellation_cutoff_hours"`
+	// WeeklySchedule is the opening hours by day of the week; days that don't set their
+	// own times use OpenTime and CloseTime.
+	WeeklySchedule Week `json:"weekly_schedule"`
+}
+
+// Known reports whether key is one of Venue's settings.
+func Known(key string) bool {
+	t := reflect.TypeOf(Venue{})
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("json") == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Defaults are the settings a key takes when the table doesn't have it yet: the old
+// environment variable if it is set, otherwise the built-in value.
+func Defaults() Venue {
+	v := Venue{
+		MaxGuests:           100,
+		MinDurationHours:    1,
+		MaxDurationHours:    8,
+		OpenTime:            envClock("OPEN_TIME", "10:00"),
+		CloseTime:           envClock("CLOSE_TIME", "22:00"),
+		SlotMinutes:         SlotGranularity(envInt("SLOT_GRANULARITY_MINUTES", envInt("SLOT_MINUTES", 30))),
+		MinLeadHours:        envInt("MIN_LEAD_HOURS", 2),
+		MaxAdvanceDays:      envInt("MAX_ADVANCE_DAYS", 90),
+		MaxBookingsPerEmail: envInt("MAX_ACTIVE_BOOKINGS_PER_CUSTOMER", envInt("MAX_BOOKINGS_PER_EMAIL", 3)),
+		HourlyRateCents:     envInt("HOURLY_RATE", 0),
+		PerGuestRateCents:   envInt("PER_GUEST_RATE", 0),
+		GuestThreshold:      envInt("GUEST_THRESHOLD", 20),
+		WeekendMultiplier:   1,
+		FullDayMaxGuests:    100,
+	}
+	if m, err := strconv.ParseFloat(os.Getenv("WEEKEND_MULTIPLIER"), 64); err == nil && m >= 1 {
+		v.WeekendMultiplier = m
+	}
+	// Unlike envInt's settings, a cutoff of 0 is allowed: it turns the cutoff off.
+	v.CancellationCutoffHours = 24
+	if h, err := strconv.Atoi(os.Getenv("CANCELLATION_CUTOFF_HOURS")); err == nil && h >= 0 {
+		v.CancellationCutoffHours = h
+	}
+	return v
+}
+
+// Validate checks every setting, keyed by its JSON name like a booking's field errors.
+func (v Venue) Validate() map[string][]messages.Message {
+	errs := map[string][]messages.Message{}
+	add := func(key, code string) { errs[key] = append(errs[key], messages.New(code)) }
+
+	if v.MaxGuests < 1 {
+		add("max_guests", messages.SettingsMaxGuests)
+	}
+	if v.MinDurationHours < 1 {
+		add("min_duration_hours", messages.SettingsMinDur)
+	}
+	if v.MaxDurationHours < v.MinDurationHours || v.MaxDurationHours > 24 {
+		add("max_duration_hours", messages.SettingsMaxDur)
+	}
+	openAt, openErr := parseClock(v.OpenTime)
+	if openErr != nil {
+		add("open_time", messages.SettingsOpenTime)
+	}
+	closeAt, closeErr := parseClock(v.CloseTime)
+	if closeErr != nil {
+		add("close_time", messages.SettingsCloseTime)
+	}
+	if openErr == nil && closeErr == nil && openAt >= closeAt {
+		add("close_time", messages.SettingsCloseFirst)
+	}
+	if openErr == nil && closeErr == nil {
+		v.validateWeek(v.WeeklySchedule, func(key, code string) { add("weekly_schedule."+key, code) })
+	}
+	if v.SlotMinutes != 30 && v.SlotMinutes != 60 {
+		add("slot_minutes", messages.SettingsSlot)
+	}
+	if v.MinLeadHours < 0 {
+		add("min_lead_hours", messages.SettingsLead)
+	}
+	if v.MaxAdvanceDays < 1 {
+		add("max_advance_days", messages.SettingsAdvance)
+	}
+	if v.MaxBookingsPerEmail < 1 {
+		add("max_bookings_per_email", messages.SettingsPerEmail)
+	}
+	if v.HourlyRateCents < 0 {
+		add("hourly_rate_cents", messages.SettingsHourly)
+	}
+	if v.PerGuestRateCents < 0 {
+		add("per_guest_rate_cents", messages.SettingsPerGuest)
+	}
+	if v.GuestThreshold < 0 {
+		add("guest_threshold", messages.SettingsThreshold)
+	}
+	if v.WeekendMultiplier < 1 || math.IsInf(v.WeekendMultiplier, 0) {
+		add("weekend_multiplier", messages.SettingsWeekend)
+	}
+	if v.FullDayRateCents < 0 {
+		add("full_day_rate_cents", messages.SettingsFullDay)
+	}
+	if v.FullDayMaxGuests < 1 {
+		add("full_day_max_guests", messages.SettingsFullDayMax)
+	}
+	if v.CancellationCutoffHours < 0 {
+		add("cancellation_cutoff_hours", messages.SettingsCutoff)
+	}
+	return errs
+}
+
+// OpenMinutes is the usual opening time as minutes since midnight; see HoursOn for a given day.
+func (v Venue) OpenMinutes() int {
+	m, _ := parseClock(v.OpenTime)
+	return m
+}
+
+// CloseMinutes is the usual closing time as minutes since midnight; see HoursOn for a given day.
+func (v Venue) CloseMinutes() int {
+	m, _ := parseClock(v.CloseTime)
+	return m
+}
+
+// CancelBy is the last moment, in the venue's timezone, a customer can cancel or move b
+// themselves: CancellationCutoffHours before it starts. It is false for a booking without a
+// start time.
+func (v Venue) CancelBy(b models.Booking) (time.Time, bool) {
+	if b.StartsAt == nil {
+		return time.Time{}, false
+	}
+	return b.StartsAt.In(models.VenueLocation()).Add(-time.Duration(v.CancellationCutoffHours) * time.Hour), true
+}
+
+// current is the cached settings. Load and Save replace the whole struct, so a reader sees
+// either the old settings or the new ones, never a mix.
+var current atomic.Pointer[Venue]
+
+// mu orders Load and Save within this instance, so a slow Load can't overwrite the cache
+// with rows read before a Save committed.
+var mu sync.Mutex
+
+// Current returns the cached settings, or the defaults before the first Load.
+func Current() Venue {
+	if v := current.Load(); v != nil {
+		return *v
+	}
+	return Defaults()
+}
+
+// Seed inserts the defaults for any keys the table doesn't have, leaving saved ones alone.
+func Seed(tx *gorm.DB) error {
+	rows, err := Defaults().rows()
+	if err != nil {
+		return err
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
+
+// Load reads the settings table and replaces the cache, so changes saved by another
+// instance show up. Keys missing from the table keep their defaults. The maintenance
+// mode is cached along with them.
+func Load(tx *gorm.DB) (Venue, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	var rows []models.Setting
+	if err := tx.Find(&rows).Error; err != nil {
+		return Venue{}, err
+	}
+	if err := loadMaintenance(rows); err != nil {
+		return Venue{}, err
+	}
+	values := make(map[string]json.RawMessage, len(rows))
+	for _, r := range rows {
+		if r.Key != maintenanceKey && json.Valid([]byte(r.Value)) {
+			values[r.Key] = json.RawMessage(r.Value)
+		}
+	}
+	raw, err := json.Marshal(values)
+	if err != nil {
+		return Venue{}, err
+	}
+	v := Defaults()
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return Venue{}, fmt.Errorf("decode settings: %w", err)
+	}
+	if errs := v.Validate(); len(errs) > 0 {
+		return Venue{}, fmt.Errorf("invalid settings in database: %v", errs)
+	}
+	current.Store(&v)
+	return v, nil
+}
+
+// Save writes every setting in v in one transaction and then swaps it into the cache.
+// v must already be valid. with, if not nil, runs in the same transaction, for writes that
+// must commit together with the settings.
+func Save(tx *gorm.DB, v Venue, with func(tx *gorm.DB) error) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	rows, err := v.rows()
+	if err != nil {
+		return err
+	}
+	err = tx.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+		}).Create(&rows).Error
+		if err != nil || with == nil {
+			return err
+		}
+		return with(tx)
+	})
+	if err != nil {
+		return err
+	}
+	current.Store(&v)
+	return nil
+}
+
+// rows encodes v as one settings row per key.
+func (v Venue) rows() ([]models.Setting, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil, err
+	}
+	rows := make([]models.Setting, 0, len(values))
+	for key, value := range values {
+		rows = append(rows, models.Setting{Key: key, Value: string(value)})
+	}
+	return rows, nil
+}
+
+// SlotGranularity rounds minutes to the nearer of the slot lengths bookings can start on,
+// 30 or 60 minutes, for a setting made before they were the only ones.
+func SlotGranularity(minutes int) int {
+	if minutes > 45 {
+		return 60
+	}
+	return 30
+}
+
+// parseClock reads a zero-padded "HH:MM" time as minutes since midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse(timeLayout, s)
+	if err != nil {
+		return 0, err
+	}
+	if len(s) != len(timeLayout) {
+		return 0, fmt.Errorf("time %q isn't zero-padded", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// envClock reads an "HH:MM" time from the environment, falling back to def when unset or invalid.
+func envClock(key, def string) string {
+	if t, err := time.Parse(timeLayout, os.Getenv(key)); err == nil {
+		return t.Format(timeLayout)
+	}
+	return def
+}
+
+// envInt reads a positive integer from the environment, falling back to def when unset or invalid.
+func envInt(key string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,69 @@
+package settings
+
+import "testing"
+
+func TestSlotMinutesDefault(t *testing.T) {
+	tests := []struct {
+		granularity, legacy string
+		want                int
+	}{
+		{"", "", 30},
+		{"60", "", 60},
+		{"", "60", 60},
+		{"30", "60", 30},
+		{"", "15", 30},
+		{"", "90", 60},
+		{"45", "", 30},
+		{"50", "", 60},
+	}
+	for _, tt := range tests {
+		t.Setenv("SLOT_GRANULARITY_MINUTES", tt.granularity)
+		t.Setenv("SLOT_MINUTES", tt.legacy)
+		if got := Defaults().SlotMinutes; got != tt.want {
+			t.Errorf("SLOT_GRANULARITY_MINUTES=%q SLOT_MINUTES=%q: slot_minutes %d, want %d", tt.granularity, tt.legacy, got, tt.want)
+		}
+	}
+}
+
+func TestValidateSlotMinutes(t *testing.T) {
+	for slot, ok := range map[int]bool{30: true, 60: true, 0: false, 15: false, 45: false, 90: false} {
+		v := Defaults()
+		v.SlotMinutes = slot
+		if _, bad := v.Validate()["slot_minutes"]; bad == ok {
+			t.Errorf("slot_minutes %d: rejected = %v, want %v", slot, bad, !ok)
+		}
+	}
+}
+
+func TestCancellationCutoffHours(t *testing.T) {
+	for env, want := range map[string]int{"": 24, "48": 48, "0": 0, "-1": 24, "soon": 24} {
+		t.Setenv("CANCELLATION_CUTOFF_HOURS", env)
+		if got := Defaults().CancellationCutoffHours; got != want {
+			t.Errorf("CANCELLATION_CUTOFF_HOURS=%q: %d, want %d", env, got, want)
+		}
+	}
+	v := Defaults()
+	v.CancellationCutoffHours = -1
+	if _, bad := v.Validate()["cancellation_cutoff_hours"]; !bad {
+		t.Error("a negative cutoff was accepted")
+	}
+}
+
+func TestMaxBookingsPerCustomer(t *testing.T) {
+	tests := []struct {
+		current, legacy string
+		want            int
+	}{
+		{"", "", 3},
+		{"4", "", 4},
+		{"", "6", 6},
+		{"4", "6", 4},
+	}
+	for _, tt := range tests {
+		t.Setenv("MAX_ACTIVE_BOOKINGS_PER_CUSTOMER", tt.current)
+		t.Setenv("MAX_BOOKINGS_PER_EMAIL", tt.legacy)
+		if got := Defaults().MaxBookingsPerEmail; got != tt.want {
+			t.Errorf("MAX_ACTIVE_BOOKINGS_PER_CUSTOMER=%q MAX_BOOKINGS_PER_EMAIL=%q: %d, want %d", tt.current, tt.legacy, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package settings
+
+import (
+	"strings"
+	"time"
+
+	"miniparty-backend/messages"
+)
+
+// Hours are one weekday's opening hours, as "HH:MM" times. Open and Close left empty mean
+// the venue's open_time and close_time, so a day only names what differs. A Closed day
+// takes no bookings at all.
+type Hours struct {
+	Closed bool   `json:"closed"`
+	Open   string `json:"open,omitempty"`
+	Close  string `json:"close,omitempty"`
+}
+
+// Week is the opening hours for each day of the week. The zero Week opens every day at
+// open_time and closes at close_time, which is how the venue worked before it had one.
+type Week struct {
+	Monday    Hours `json:"monday"`
+	Tuesday   Hours `json:"tuesday"`
+	Wednesday Hours `json:"wednesday"`
+	Thursday  Hours `json:"thursday"`
+	Friday    Hours `json:"friday"`
+	Saturday  Hours `json:"saturday"`
+	Sunday    Hours `json:"sunday"`
+}
+
+// Weekdays lists the days of the week in the order Week does, Monday first.
+var Weekdays = []time.Weekday{
+	time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday,
+}
+
+// DayName is d's JSON name in a Week, e.g. "monday".
+func DayName(d time.Weekday) string {
+	return strings.ToLower(d.String())
+}
+
+// Day returns the hours for weekday d.
+func (w *Week) Day(d time.Weekday) *Hours {
+	switch d {
+	case time.Monday:
+		return &w.Monday
+	case time.Tuesday:
+		return &w.Tuesday
+	case time.Wednesday:
+		return &w.Wednesday
+	case time.Thursday:
+		return &w.Thursday
+	case time.Friday:
+		return &w.Friday
+	case time.Saturday:
+		return &w.Saturday
+	}
+	return &w.Sunday
+}
+
+// Resolve returns w with every open day's empty times filled in from v's open_time and
+// close_time, and closed days without times, which is how GET /schedule shows it.
+func (v Venue) Resolve(w Week) Week {
+	for _, d := range Weekdays {
+		h := w.Day(d)
+		if h.Closed {
+			*h = Hours{Closed: true}
+			continue
+		}
+		if h.Open == "" {
+			h.Open = v.OpenTime
+		}
+		if h.Close == "" {
+			h.Close = v.CloseTime
+		}
+	}
+	return w
+}
+
+// HoursOn returns the opening and closing times on weekday d, as minutes since midnight,
+// and false if the venue is closed that day.
+func (v Venue) HoursOn(d time.Weekday) (openAt, closeAt int, open bool) {
+	week := v.Resolve(v.WeeklySchedule)
+	h := week.Day(d)
+	if h.Closed {
+		return 0, 0, false
+	}
+	openAt, _ = parseClock(h.Open)
+	closeAt, _ = parseClock(h.Close)
+	return openAt, closeAt, true
+}
+
+// validateWeek checks w against v's open_time and close_time, keyed like "monday.close".
+func (v Venue) validateWeek(w Week, add func(key, code string)) {
+	for _, d := range Weekdays {
+		h, name := w.Day(d), DayName(d)
+		if h.Closed {
+			continue
+		}
+		open, close := h.Open, h.Close
+		if open == "" {
+			open = v.OpenTime
+		}
+		if close == "" {
+			close = v.CloseTime
+		}
+		openAt, openErr := parseClock(open)
+		if openErr != nil {
+			add(name+".open", messages.SettingsOpenTime)
+		}
+		closeAt, closeErr := parseClock(close)
+		if closeErr != nil {
+			add(name+".close", messages.SettingsCloseTime)
+		}
+		if openErr == nil && closeErr == nil && openAt >= closeAt {
+			add(name+".close", messages.SettingsCloseFirst)
+		}
+	}
+}
+
+// ValidateWeek checks a schedule for PUT /admin/schedule, keyed by day, e.g. "friday.close".
+func (v Venue) ValidateWeek(w Week) map[string][]messages.Message {
+	errs := map[string][]messages.Message{}
+	v.validateWeek(w, func(key, code string) { errs[key] = append(errs[key], messages.New(code)) })
+	return errs
+}
@@ -0,0 +1,65 @@
+package settings
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHoursOn(t *testing.T) {
+	v := Defaults()
+	v.OpenTime, v.CloseTime = "10:00", "22:00"
+	v.WeeklySchedule.Monday = Hours{Closed: true, Open: "09:00"}
+	v.WeeklySchedule.Friday = Hours{Close: "23:00"}
+	v.WeeklySchedule.Sunday = Hours{Open: "12:00", Close: "18:00"}
+
+	tests := []struct {
+		day         time.Weekday
+		open, close int
+		isOpen      bool
+	}{
+		{time.Monday, 0, 0, false},
+		{time.Tuesday, 10 * 60, 22 * 60, true},
+		{time.Friday, 10 * 60, 23 * 60, true},
+		{time.Sunday, 12 * 60, 18 * 60, true},
+	}
+	for _, tt := range tests {
+		openAt, closeAt, open := v.HoursOn(tt.day)
+		if openAt != tt.open || closeAt != tt.close || open != tt.isOpen {
+			t.Errorf("%s: %d-%d open %v, want %d-%d open %v", tt.day, openAt, closeAt, open, tt.open, tt.close, tt.isOpen)
+		}
+	}
+
+	week := v.Resolve(v.WeeklySchedule)
+	if week.Monday != (Hours{Closed: true}) || week.Friday != (Hours{Open: "10:00", Close: "23:00"}) || week.Wednesday != (Hours{Open: "10:00", Close: "22:00"}) {
+		t.Errorf("Resolve = %+v, want closed days without times and open days filled in", week)
+	}
+	if v.WeeklySchedule.Friday.Open != "" {
+		t.Error("Resolve changed the venue's own schedule")
+	}
+}
+
+func TestValidateWeek(t *testing.T) {
+	v := Defaults()
+	v.OpenTime, v.CloseTime = "10:00", "22:00"
+	week := Week{
+		Monday:   Hours{Closed: true, Close: "nonsense"},
+		Tuesday:  Hours{Open: "23:00"},
+		Thursday: Hours{Open: "soon"},
+		Friday:   Hours{Open: "12:00", Close: "11:00"},
+	}
+	errs := v.ValidateWeek(week)
+	for _, key := range []string{"tuesday.close", "thursday.open", "friday.close"} {
+		if len(errs[key]) != 1 {
+			t.Errorf("%s: %v, want one error", key, errs[key])
+		}
+	}
+	if len(errs) != 3 {
+		t.Errorf("errors %v, want only tuesday, thursday and friday's", errs)
+	}
+
+	// A setting change goes through the same checks, the keys under weekly_schedule.
+	v.WeeklySchedule = Week{Saturday: Hours{Close: "09:00"}}
+	if errs := v.Validate(); len(errs["weekly_schedule.saturday.close"]) != 1 {
+		t.Errorf("Validate = %v, want weekly_schedule.saturday.close", errs)
+	}
+}
@@ -0,0 +1,504 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"miniparty-backend/db"
+	"miniparty-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Gorm is the BookingStore backed by the shared db.DB connection.
+type Gorm struct{}
+
+func (Gorm) conn(ctx context.Context) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx
+	}
+	return db.DB.WithContext(ctx)
+}
+
+type txKey struct{}
+
+// transaction runs fn in a transaction, retried with db.RetryTx after transient errors. Inside
+// a caller's transaction from WithTx it runs once as a nested transaction: a failure there has
+// already spoiled the caller's transaction, so only the caller can start over.
+func (s Gorm) transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.Transaction(fn)
+	}
+	return db.RetryTx(ctx, db.DB.WithContext(ctx), fn)
+}
+
+// retry runs fn, retried with db.Retry after transient errors unless it is part of a caller's
+// transaction. fn must be a read, or a write that does nothing more when repeated.
+func (s Gorm) retry(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return fn(tx)
+	}
+	return db.Retry(ctx, func() error { return fn(db.DB.WithContext(ctx)) })
+}
+
+// WithTx returns a ctx in which Gorm runs its queries in tx, so a caller can commit its own
+// writes, such as an audit entry, together with the store's. tx should already carry ctx.
+func WithTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// Create inserts and re-checks for overlaps inside one transaction. The unique slot_key index
+// stops two bookings at the same start time, and checkConflicts' lock stops two that only
+// partly overlap: the second waits for the first to commit, then sees it and rolls back.
+func (s Gorm) Create(ctx context.Context, b *models.Booking) error {
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		// A retry inserts afresh rather than with the ID the rolled-back attempt was given.
+		b.ID = 0
+		if err := tx.Create(b).Error; err != nil {
+			return err
+		}
+		return checkConflicts(tx, b)
+	})
+	if err != nil {
+		b.ID = 0
+	}
+	return mapError(err)
+}
+
+func (s Gorm) GetByID(ctx context.Context, id int64) (models.Booking, error) {
+	var b models.Booking
+	err := s.retry(ctx, func(conn *gorm.DB) error {
+		b = models.Booking{}
+		return conn.Preload("Addons").First(&b, id).Error
+	})
+	return b, mapError(err)
+}
+
+func (s Gorm) List(ctx context.Context, opts ListOptions) ([]models.Booking, int64, error) {
+	var bookings []models.Booking
+	var total int64
+	err := s.retry(ctx, func(conn *gorm.DB) error {
+		if opts.IncludeDeleted {
+			conn = conn.Unscoped()
+		}
+		if err := conn.Model(&models.Booking{}).Scopes(opts.Scope).Count(&total).Error; err != nil {
+			return err
+		}
+
+		bookings = []models.Booking{}
+		// Add-ons come from one batched query for the whole page rather than one per booking.
+		return conn.Scopes(opts.Scope, withNames).Preload("Addons").
+			Scopes(opts.Sort.Scope).
+			Offset(opts.Offset).Limit(opts.Limit).Find(&bookings).Error
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return bookings, total, nil
+}
+
+func (s Gorm) Count(ctx context.Context, opts ListOptions) (int64, error) {
+	var total int64
+	err := s.retry(ctx, func(conn *gorm.DB) error {
+		if opts.IncludeDeleted {
+			conn = conn.Unscoped()
+		}
+		return conn.Model(&models.Booking{}).Scopes(opts.Scope).Count(&total).Error
+	})
+	return total, err
+}
+
+// Each reads the bookings through a cursor, with their package and room names but not their
+// add-ons, which would take a query per booking. It isn't retried: fn may have acted on some
+// bookings by the time a read fails.
+func (s Gorm) Each(ctx context.Context, opts ListOptions, fn func(models.Booking) error) error {
+	conn := s.conn(ctx)
+	if opts.IncludeDeleted {
+		conn = conn.Unscoped()
+	}
+	query := conn.Model(&models.Booking{}).Scopes(opts.Scope, withNames, opts.Sort.Scope).Offset(opts.Offset)
+	if opts.Limit > 0 {
+		query = query.Limit(opts.Limit)
+	}
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b models.Booking
+		if err := conn.ScanRows(rows, &b); err != nil {
+			return err
+		}
+		if err := fn(b); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// withNames selects each booking's package and room names alongside it.
+func withNames(tx *gorm.DB) *gorm.DB {
+	return tx.Select("bookings.*, packages.name AS package_name, rooms.name AS room_name").
+		Joins("LEFT JOIN packages ON packages.id = bookings.package_id").
+		Joins("LEFT JOIN rooms ON rooms.id = bookings.room_id")
+}
+
+func (s Gorm) Update(ctx context.Context, b *models.Booking) error {
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		if err := checkConflicts(tx, b); err != nil {
+			return err
+		}
+		if b.Status != models.StatusCancelled {
+			b.SlotKey = models.SlotKey(b.Date, b.Time, b.RoomID)
+		}
+		// Naming the columns stops Save inserting b when the update matches no row, which
+		// would bring back a booking deleted since it was read.
+		return changed(tx.Select("*").Save(b))
+	})
+	return mapError(err)
+}
+
+// Modify locks the row for the rest of the transaction on Postgres; SQLite runs one write
+// transaction at a time, so it needs no lock.
+func (s Gorm) Modify(ctx context.Context, id int64, change func(b *models.Booking) error) (models.Booking, error) {
+	var b models.Booking
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		b = models.Booking{}
+		return modify(tx.Where("id = ?", id), &b, change)
+	})
+	return b, mapError(err)
+}
+
+// modify reads the one booking query selects into b, locked on Postgres, and saves what
+// change does to it, as Modify describes. A soft-deleted booking keeps its slot free.
+func modify(query *gorm.DB, b *models.Booking, change func(b *models.Booking) error) error {
+	if err := query.Clauses(clause.Locking{Strength: "UPDATE"}).Take(b).Error; err != nil {
+		return err
+	}
+	before := *b
+	if err := change(b); err != nil {
+		*b = before
+		return err
+	}
+	if reflect.DeepEqual(before, *b) {
+		return nil
+	}
+	b.SlotKey = nil
+	if b.Status != models.StatusCancelled && !b.DeletedAt.Valid {
+		b.SlotKey = models.SlotKey(b.Date, b.Time, b.RoomID)
+	}
+	save := query.Session(&gorm.Session{NewDB: true})
+	if b.DeletedAt.Valid {
+		save = save.Unscoped()
+	}
+	return changed(save.Select("*").Save(b))
+}
+
+// Delete soft-deletes in a transaction even though it is one statement, so a retry after a
+// dropped connection is only made when the first attempt certainly didn't commit; otherwise
+// the retry would find the booking already deleted and report it missing.
+func (s Gorm) Delete(ctx context.Context, id int64) error {
+	return s.transaction(ctx, func(tx *gorm.DB) error {
+		result := tx.Model(&models.Booking{}).Where("id = ?", id).Updates(SoftDelete())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (s Gorm) Restore(ctx context.Context, id int64) (models.Booking, error) {
+	var b models.Booking
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		b = models.Booking{}
+		if err := tx.Unscoped().First(&b, id).Error; err != nil {
+			return err
+		}
+		if !b.DeletedAt.Valid {
+			return nil
+		}
+
+		b.DeletedAt = gorm.DeletedAt{}
+		if b.Status != models.StatusCancelled {
+			if err := checkConflicts(tx, &b); err != nil {
+				return err
+			}
+			b.SlotKey = models.SlotKey(b.Date, b.Time, b.RoomID)
+		}
+		return changed(tx.Unscoped().Model(&b).Select("deleted_at", "slot_key", "updated_at").Updates(&b))
+	})
+	return b, mapError(err)
+}
+
+func (s Gorm) Purge(ctx context.Context, id int64) (models.Booking, error) {
+	var b models.Booking
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		b = models.Booking{}
+		tx = tx.Unscoped()
+		if err := tx.First(&b, id).Error; err != nil {
+			return err
+		}
+		return changed(tx.Delete(&b))
+	})
+	return b, mapError(err)
+}
+
+// changed returns result's error, or gorm.ErrRecordNotFound if the statement matched no row
+// because the booking went away after it was read.
+func changed(result *gorm.DB) error {
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+// SoftDelete is the column update that soft-deletes bookings. Clearing slot_key lets
+// the slot be booked again while the row is kept.
+func SoftDelete() map[string]interface{} {
+	return map[string]interface{}{"deleted_at": time.Now().UTC(), "slot_key": nil}
+}
+
+// sortColumns maps each of SortFields to the columns it orders by. Only these fixed strings
+// ever reach ORDER BY, never anything from the request.
+var sortColumns = map[string][]string{
+	"date":       {"bookings.date", "bookings.time"},
+	"created_at": {"bookings.created_at"},
+	"guests":     {"bookings.guests"},
+	"name":       {"LOWER(bookings.name)"},
+}
+
+// Scope applies the sort as ORDER BY, falling back to date order for an unknown Field.
+func (s Sort) Scope(tx *gorm.DB) *gorm.DB {
+	columns, ok := sortColumns[s.Field]
+	if !ok {
+		columns = sortColumns["date"]
+	}
+	dir := " ASC"
+	if s.Desc {
+		dir = " DESC"
+	}
+	for _, col := range columns {
+		tx = tx.Order(col + dir)
+	}
+	return tx.Order("bookings.id" + dir)
+}
+
+// Scope applies the filter as WHERE clauses. The same scope is used for a list and its COUNT
+// so both always agree.
+func (f Filter) Scope(tx *gorm.DB) *gorm.DB {
+	if f.Query != "" {
+		pattern := "%" + likeEscaper.Replace(f.Query) + "%"
+		phonePattern := pattern
+		if f.PhoneQuery != "" {
+			phonePattern = "%" + likeEscaper.Replace(f.PhoneQuery) + "%"
+		}
+		cond := `LOWER(bookings.name) LIKE ? ESCAPE '\' OR LOWER(bookings.email) LIKE ? ESCAPE '\' OR bookings.phone LIKE ? ESCAPE '\'`
+		args := []interface{}{pattern, pattern, phonePattern}
+		if f.Reference != "" {
+			cond += ` OR bookings.reference = ?`
+			args = append(args, f.Reference)
+		}
+		tx = tx.Where(cond, args...)
+	} else if f.Reference != "" {
+		tx = tx.Where("bookings.reference = ?", f.Reference)
+	}
+	if f.Status != "" {
+		tx = tx.Where("bookings.status = ?", f.Status)
+	}
+	if len(f.Statuses) > 0 {
+		tx = tx.Where("bookings.status IN ?", f.Statuses)
+	}
+	if f.Active {
+		tx = tx.Where("bookings.status <> ?", models.StatusCancelled)
+	}
+	if f.IDs != nil {
+		// An empty list matches nothing, as IN () would if SQL allowed it.
+		if len(f.IDs) == 0 {
+			return tx.Where("1 = 0")
+		}
+		tx = tx.Where("bookings.id IN ?", f.IDs)
+	}
+	if f.RoomID != 0 {
+		tx = tx.Where("bookings.room_id = ?", f.RoomID)
+	}
+	if f.PackageID != 0 {
+		tx = tx.Where("bookings.package_id = ?", f.PackageID)
+	}
+	if f.SeriesID != "" {
+		tx = tx.Where("bookings.series_id = ?", f.SeriesID)
+	}
+	if f.Email != "" {
+		if f.Phone != "" {
+			tx = tx.Where("LOWER(bookings.email) = ? OR bookings.phone = ?", strings.ToLower(f.Email), f.Phone)
+		} else {
+			tx = tx.Where("LOWER(bookings.email) = ?", strings.ToLower(f.Email))
+		}
+	}
+	if f.Flagged != nil {
+		tx = tx.Where("bookings.flagged = ?", *f.Flagged)
+	}
+	if f.PaymentIntentID != "" {
+		tx = tx.Where("bookings.payment_intent_id = ?", f.PaymentIntentID)
+	}
+	switch f.PaymentStatus {
+	case models.PaymentUnpaid:
+		tx = tx.Where("bookings.amount_paid_cents <= 0")
+	case models.PaymentPartial:
+		tx = tx.Where("bookings.amount_paid_cents > 0 AND bookings.amount_paid_cents < bookings.deposit_due_cents")
+	case models.PaymentPaid:
+		tx = tx.Where("bookings.amount_paid_cents > 0 AND bookings.amount_paid_cents >= bookings.deposit_due_cents")
+	}
+	if f.CheckedIn != nil {
+		if *f.CheckedIn {
+			tx = tx.Where("bookings.checked_in_at IS NOT NULL")
+		} else {
+			tx = tx.Where("bookings.checked_in_at IS NULL")
+		}
+	}
+	if f.From != "" {
+		tx = tx.Where("bookings.date >= ?", f.From)
+	}
+	if f.To != "" {
+		tx = tx.Where("bookings.date <= ?", f.To)
+	}
+	if f.Time != "" {
+		tx = tx.Where("bookings.time = ?", f.Time)
+	}
+	return tx
+}
+
+// checkConflicts fails with *ConflictError if b overlaps another active booking.
+func checkConflicts(tx *gorm.DB, b *models.Booking) error {
+	conflicts, err := FindConflicts(tx, b, b.ID)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return &ConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
+
+// lockDay takes a lock on b's room and day until tx ends. Postgres only: under READ COMMITTED
+// two transactions could otherwise each insert a booking and miss the other's in their checks.
+// SQLite needs none, as it runs one write transaction at a time.
+//
+// Every booking also holds the whole day's lock, shared, which a full-day booking takes
+// for itself, so it is checked against every room at once.
+func lockDay(tx *gorm.DB, b *models.Booking) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	day := "bookings " + b.Date
+	if b.FullDay {
+		return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", day).Error
+	}
+	if err := tx.Exec("SELECT pg_advisory_xact_lock_shared(hashtext(?))", day).Error; err != nil {
+		return err
+	}
+	key := day
+	if b.RoomID != nil {
+		key += fmt.Sprintf(" #%d", *b.RoomID)
+	}
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", key).Error
+}
+
+// FindConflicts returns the active bookings in the same room on the same date whose interval
+// overlaps booking's, ignoring the booking with ID excludeID (pass 0 to exclude nothing).
+// A full-day booking takes the whole venue, so it conflicts with every other booking that
+// day, in any room and at any time.
+//
+// In a transaction it first takes the lock for that room and day, held until the transaction
+// ends, so concurrent bookings for the same room and day are checked one at a time, each
+// after the one before it has committed.
+func FindConflicts(tx *gorm.DB, booking *models.Booking, excludeID int64) ([]models.Booking, error) {
+	if _, _, ok := Interval(booking); !ok {
+		return nil, nil
+	}
+	if err := lockDay(tx, booking); err != nil {
+		return nil, err
+	}
+
+	var existing []models.Booking
+	query := tx.Where("date = ? AND status <> ?", booking.Date, models.StatusCancelled)
+	switch {
+	case booking.FullDay:
+	case booking.RoomID != nil:
+		query = query.Where("(room_id = ? OR full_day = ?)", *booking.RoomID, true)
+	default:
+		query = query.Where("(room_id IS NULL OR full_day = ?)", true)
+	}
+	if excludeID != 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	if err := query.Order("time ASC").Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	return overlapping(booking, existing), nil
+}
+
+// overlapping returns those of existing, the active bookings that share booking's room and
+// day, that booking's interval overlaps.
+func overlapping(booking *models.Booking, existing []models.Booking) []models.Booking {
+	newStart, newEnd, _ := Interval(booking)
+	var conflicts []models.Booking
+	for _, ex := range existing {
+		if booking.FullDay || ex.FullDay {
+			conflicts = append(conflicts, ex)
+			continue
+		}
+		if booking.StartsAt != nil && ex.StartsAt != nil {
+			// Compare instants, so a booking spanning a DST change is as long as it really is.
+			if booking.StartsAt.Before(*ex.EndsAt()) && ex.StartsAt.Before(*booking.EndsAt()) {
+				conflicts = append(conflicts, ex)
+			}
+			continue
+		}
+		exStart, exEnd, ok := Interval(&ex)
+		if ok && Overlaps(newStart, newEnd, exStart, exEnd) {
+			conflicts = append(conflicts, ex)
+		}
+	}
+	return conflicts
+}
+
+// Overlaps reports whether [aStart, aEnd) and [bStart, bEnd) intersect.
+// Two intervals overlap if one starts before the other ends and vice versa,
+// so a booking ending exactly when another starts does not conflict.
+func Overlaps(aStart, aEnd, bStart, bEnd int) bool {
+	return aStart < bEnd && bStart < aEnd
+}
+
+// Interval returns a booking's start and end as minutes since midnight on its wall clock.
+func Interval(b *models.Booking) (start, end int, ok bool) {
+	var hour, minute int
+	if _, err := fmt.Sscanf(b.Time, "%d:%d", &hour, &minute); err != nil {
+		return 0, 0, false
+	}
+	start = hour*60 + minute
+	return start, start + b.Duration*60, true
+}
+
+// mapError converts GORM errors into the store's sentinel errors.
+func mapError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrSlotTaken
+	}
+	return err
+}
+
+// likeEscaper escapes LIKE wildcards so user input is matched literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
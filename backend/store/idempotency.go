@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"miniparty-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// IdempotencyTTL is how long a completed Idempotency-Key is replayed before it expires.
+const IdempotencyTTL = 24 * time.Hour
+
+func (s Gorm) ClaimKey(ctx context.Context, key, requestHash string, now time.Time) (*models.IdempotencyKey, error) {
+	conn := s.conn(ctx)
+	if err := conn.Where("expires_at <= ?", now).Delete(&models.IdempotencyKey{}).Error; err != nil {
+		return nil, err
+	}
+
+	// The primary key arbitrates between concurrent requests: only one insert succeeds.
+	claim := models.IdempotencyKey{Key: key, RequestHash: requestHash, ExpiresAt: now.Add(IdempotencyTTL)}
+	err := conn.Create(&claim).Error
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, err
+	}
+
+	var existing models.IdempotencyKey
+	if err := conn.First(&existing, "key = ?", key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Released between our insert and read; let the client retry.
+			return nil, ErrKeyInFlight
+		}
+		return nil, err
+	}
+	switch {
+	case existing.RequestHash != requestHash:
+		return nil, ErrKeyMismatch
+	case existing.Status == 0:
+		return nil, ErrKeyInFlight
+	}
+	return &existing, nil
+}
+
+// CompleteKey and ReleaseKey are retried after transient errors, since repeating either changes
+// nothing. ClaimKey isn't: a repeated insert that had committed would find its own claim and
+// report it in flight.
+
+func (s Gorm) CompleteKey(ctx context.Context, key string, status int, response []byte) error {
+	return s.retry(ctx, func(conn *gorm.DB) error {
+		return conn.Model(&models.IdempotencyKey{}).Where("key = ?", key).
+			Updates(map[string]any{"status": status, "response": response}).Error
+	})
+}
+
+func (s Gorm) ReleaseKey(ctx context.Context, key string) error {
+	return s.retry(ctx, func(conn *gorm.DB) error {
+		return conn.Where("key = ? AND status = 0", key).Delete(&models.IdempotencyKey{}).Error
+	})
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"miniparty-backend/db/dbtest"
+)
+
+func TestGormIdempotencyKeys(t *testing.T) {
+	dbtest.Init(t)
+	ctx, s := context.Background(), Gorm{}
+	now := time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)
+
+	if stored, err := s.ClaimKey(ctx, "k1", "hash", now); err != nil || stored != nil {
+		t.Fatalf("first claim = %v, %v, want it claimed", stored, err)
+	}
+	if _, err := s.ClaimKey(ctx, "k1", "hash", now); !errors.Is(err, ErrKeyInFlight) {
+		t.Errorf("claim while in flight: err = %v, want ErrKeyInFlight", err)
+	}
+	if _, err := s.ClaimKey(ctx, "k1", "other", now); !errors.Is(err, ErrKeyMismatch) {
+		t.Errorf("claim with another body: err = %v, want ErrKeyMismatch", err)
+	}
+	if err := s.CompleteKey(ctx, "k1", 201, []byte(`{"id":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if stored, err := s.ClaimKey(ctx, "k1", "hash", now); err != nil || stored == nil || stored.Status != 201 || string(stored.Response) != `{"id":1}` {
+		t.Errorf("claim after completing = %+v, %v, want the stored response", stored, err)
+	}
+	// Releasing only drops unfinished claims.
+	if err := s.ReleaseKey(ctx, "k1"); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := s.ClaimKey(ctx, "k1", "hash", now); stored == nil {
+		t.Error("released a completed key")
+	}
+
+	if _, err := s.ClaimKey(ctx, "k2", "hash", now); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ReleaseKey(ctx, "k2"); err != nil {
+		t.Fatal(err)
+	}
+	if stored, err := s.ClaimKey(ctx, "k2", "other", now); err != nil || stored != nil {
+		t.Errorf("claim after release = %v, %v, want it claimed afresh", stored, err)
+	}
+
+	// Past the TTL the key is forgotten and can be used for anything.
+	if stored, err := s.ClaimKey(ctx, "k1", "other", now.Add(IdempotencyTTL)); err != nil || stored != nil {
+		t.Errorf("claim after expiry = %v, %v, want it claimed afresh", stored, err)
+	}
+}
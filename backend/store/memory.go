@@ -0,0 +1,303 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"miniparty-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// Memory is a BookingStore that keeps bookings in a map, for handler tests that shouldn't
+// need a database. It applies the same slot and overlap rules as Gorm, and the same filters
+// and sorting, but doesn't fill in the package and room names a join would.
+type Memory struct {
+	mu       sync.Mutex
+	bookings map[int64]models.Booking
+	lastID   int64
+}
+
+// NewMemory returns an empty Memory store.
+func NewMemory() *Memory {
+	return &Memory{bookings: map[int64]models.Booking{}}
+}
+
+func (m *Memory) Create(_ context.Context, b *models.Booking) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkSlot(b); err != nil {
+		return err
+	}
+	if err := m.check(b, 0); err != nil {
+		return err
+	}
+	m.lastID++
+	b.ID = m.lastID
+	at := time.Now().UTC()
+	if b.CreatedAt.IsZero() {
+		b.CreatedAt = at
+	}
+	b.UpdatedAt = at
+	b.PaymentStatus = b.PaymentState()
+	m.bookings[b.ID] = *b
+	return nil
+}
+
+func (m *Memory) GetByID(_ context.Context, id int64) (models.Booking, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.bookings[id]
+	if !ok || b.DeletedAt.Valid {
+		return models.Booking{}, ErrNotFound
+	}
+	return b, nil
+}
+
+func (m *Memory) List(_ context.Context, opts ListOptions) ([]models.Booking, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	matches := []models.Booking{}
+	for _, b := range m.bookings {
+		if (opts.IncludeDeleted || !b.DeletedAt.Valid) && opts.Filter.Matches(b) {
+			matches = append(matches, b)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return opts.Sort.Less(matches[i], matches[j]) })
+
+	total := int64(len(matches))
+	start := min(opts.Offset, len(matches))
+	end := len(matches)
+	// Like SQL's LIMIT, a zero limit is an empty page.
+	if opts.Limit >= 0 {
+		end = min(start+opts.Limit, end)
+	}
+	return matches[start:end], total, nil
+}
+
+func (m *Memory) Count(ctx context.Context, opts ListOptions) (int64, error) {
+	opts.Offset, opts.Limit = 0, 0
+	_, total, err := m.List(ctx, opts)
+	return total, err
+}
+
+func (m *Memory) Each(ctx context.Context, opts ListOptions, fn func(models.Booking) error) error {
+	if opts.Limit == 0 {
+		opts.Limit = -1
+	}
+	bookings, _, err := m.List(ctx, opts)
+	if err != nil {
+		return err
+	}
+	for _, b := range bookings {
+		if err := fn(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Update(_ context.Context, b *models.Booking) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if old, ok := m.bookings[b.ID]; !ok || old.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	if err := m.check(b, b.ID); err != nil {
+		return err
+	}
+	if b.Status != models.StatusCancelled {
+		b.SlotKey = models.SlotKey(b.Date, b.Time, b.RoomID)
+		if err := m.checkSlot(b); err != nil {
+			return err
+		}
+	}
+	b.UpdatedAt = time.Now().UTC()
+	b.PaymentStatus = b.PaymentState()
+	m.bookings[b.ID] = *b
+	return nil
+}
+
+func (m *Memory) Modify(_ context.Context, id int64, change func(b *models.Booking) error) (models.Booking, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	before, ok := m.bookings[id]
+	if !ok || before.DeletedAt.Valid {
+		return models.Booking{}, ErrNotFound
+	}
+	b := before
+	if err := change(&b); err != nil {
+		return before, err
+	}
+	if reflect.DeepEqual(before, b) {
+		return b, nil
+	}
+	b.SlotKey = nil
+	if b.Status != models.StatusCancelled {
+		b.SlotKey = models.SlotKey(b.Date, b.Time, b.RoomID)
+		if err := m.checkSlot(&b); err != nil {
+			return before, err
+		}
+	}
+	b.UpdatedAt = time.Now().UTC()
+	b.PaymentStatus = b.PaymentState()
+	m.bookings[id] = b
+	return b, nil
+}
+
+func (m *Memory) Delete(_ context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.bookings[id]
+	if !ok || b.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	b.DeletedAt = gorm.DeletedAt{Time: time.Now().UTC(), Valid: true}
+	b.SlotKey = nil
+	m.bookings[id] = b
+	return nil
+}
+
+func (m *Memory) Restore(_ context.Context, id int64) (models.Booking, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.bookings[id]
+	if !ok {
+		return models.Booking{}, ErrNotFound
+	}
+	if !b.DeletedAt.Valid {
+		return b, nil
+	}
+	b.DeletedAt = gorm.DeletedAt{}
+	if b.Status != models.StatusCancelled {
+		if err := m.check(&b, b.ID); err != nil {
+			return models.Booking{}, err
+		}
+		b.SlotKey = models.SlotKey(b.Date, b.Time, b.RoomID)
+		if err := m.checkSlot(&b); err != nil {
+			return models.Booking{}, err
+		}
+	}
+	m.bookings[id] = b
+	return b, nil
+}
+
+func (m *Memory) Purge(_ context.Context, id int64) (models.Booking, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.bookings[id]
+	if !ok {
+		return models.Booking{}, ErrNotFound
+	}
+	delete(m.bookings, id)
+	return b, nil
+}
+
+// checkSlot fails with ErrSlotTaken if another booking holds b's slot key, as the unique
+// index on slot_key would.
+func (m *Memory) checkSlot(b *models.Booking) error {
+	if b.SlotKey == nil {
+		return nil
+	}
+	for _, ex := range m.bookings {
+		if ex.ID != b.ID && ex.SlotKey != nil && *ex.SlotKey == *b.SlotKey {
+			return ErrSlotTaken
+		}
+	}
+	return nil
+}
+
+// check fails with *ConflictError if b overlaps a live booking other than excludeID, as
+// FindConflicts would find them.
+func (m *Memory) check(b *models.Booking, excludeID int64) error {
+	if _, _, ok := Interval(b); !ok {
+		return nil
+	}
+	var sameDay []models.Booking
+	for _, ex := range m.bookings {
+		if ex.ID == excludeID || ex.DeletedAt.Valid || ex.Status == models.StatusCancelled || ex.Date != b.Date {
+			continue
+		}
+		if b.FullDay || ex.FullDay || sameRoom(ex.RoomID, b.RoomID) {
+			sameDay = append(sameDay, ex)
+		}
+	}
+	sort.Slice(sameDay, func(i, j int) bool { return sameDay[i].Time < sameDay[j].Time })
+	if conflicts := overlapping(b, sameDay); len(conflicts) > 0 {
+		return &ConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
+
+func sameRoom(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// Matches reports whether b passes the filter, as Scope would select it.
+func (f Filter) Matches(b models.Booking) bool {
+	if f.Query != "" {
+		phone := f.Query
+		if f.PhoneQuery != "" {
+			phone = f.PhoneQuery
+		}
+		if !strings.Contains(strings.ToLower(b.Name), f.Query) && !strings.Contains(strings.ToLower(b.Email), f.Query) &&
+			!strings.Contains(b.Phone, phone) && (f.Reference == "" || b.Reference != f.Reference) {
+			return false
+		}
+	} else if f.Reference != "" && b.Reference != f.Reference {
+		return false
+	}
+	switch {
+	case f.Status != "" && b.Status != f.Status,
+		len(f.Statuses) > 0 && !slices.Contains(f.Statuses, b.Status),
+		f.Active && b.Status == models.StatusCancelled,
+		f.IDs != nil && !slices.Contains(f.IDs, b.ID),
+		f.RoomID != 0 && (b.RoomID == nil || *b.RoomID != f.RoomID),
+		f.PackageID != 0 && (b.PackageID == nil || *b.PackageID != f.PackageID),
+		f.SeriesID != "" && b.SeriesID != f.SeriesID,
+		f.Flagged != nil && b.Flagged != *f.Flagged,
+		f.CheckedIn != nil && (b.CheckedInAt != nil) != *f.CheckedIn,
+		f.PaymentStatus != "" && b.PaymentState() != f.PaymentStatus,
+		f.PaymentIntentID != "" && b.PaymentIntentID != f.PaymentIntentID,
+		f.From != "" && b.Date < f.From,
+		f.To != "" && b.Date > f.To,
+		f.Time != "" && b.Time != f.Time:
+		return false
+	}
+	if f.Email != "" {
+		byEmail := strings.EqualFold(b.Email, f.Email)
+		if !byEmail && (f.Phone == "" || b.Phone != f.Phone) {
+			return false
+		}
+	}
+	return true
+}
+
+// Less reports whether a comes before b in the sort, as Scope would order them.
+func (s Sort) Less(a, b models.Booking) bool {
+	var ka, kb string
+	switch s.Field {
+	case "created_at":
+		ka, kb = a.CreatedAt.Format(time.RFC3339Nano), b.CreatedAt.Format(time.RFC3339Nano)
+	case "guests":
+		if a.Guests != b.Guests {
+			return (a.Guests < b.Guests) != s.Desc
+		}
+	case "name":
+		ka, kb = strings.ToLower(a.Name), strings.ToLower(b.Name)
+	default:
+		ka, kb = a.Date+" "+a.Time, b.Date+" "+b.Time
+	}
+	if ka != kb {
+		return (ka < kb) != s.Desc
+	}
+	return (a.ID < b.ID) != s.Desc
+}
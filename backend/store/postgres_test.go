@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"miniparty-backend/config"
+	"miniparty-backend/db"
+	"miniparty-backend/models"
+
+	"gorm.io/gorm/logger"
+)
+
+// TestPostgresConcurrentCreates races two Creates for one slot on the PostgreSQL database
+// in TEST_DATABASE_URL, which SQLite can't: it runs one write at a time. The database is
+// migrated and the bookings the test makes are purged again, but use a throwaway one.
+func TestPostgresConcurrentCreates(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	log.SetOutput(io.Discard)
+	db.Init(config.DB{Driver: "postgres", URL: url, Timeout: 10 * time.Second, MaxOpenConns: 4, MaxIdleConns: 4})
+	db.DB.Logger = logger.Discard
+	t.Cleanup(db.Close)
+
+	tests := []struct {
+		name                string
+		first, second       string
+		firstHrs, secondHrs int
+	}{
+		// The unique slot_key index decides this one.
+		{"same start", "14:00", "14:00", 2, 2},
+		// Here only the lock in checkConflicts stops both committing.
+		{"partial overlap", "14:00", "15:00", 2, 2},
+	}
+	s := Gorm{}
+	ctx := context.Background()
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for round := 0; round < 10; round++ {
+				// A far-off date of its own, so as not to meet another run's bookings.
+				date := time.Now().UTC().AddDate(50, 0, i*100+round).Format("2006-01-02")
+				bookings := []*models.Booking{booking(date, tt.first, tt.firstHrs), booking(date, tt.second, tt.secondHrs)}
+				errs := make([]error, len(bookings))
+				var start, done sync.WaitGroup
+				start.Add(1)
+				for j, b := range bookings {
+					done.Add(1)
+					go func(j int, b *models.Booking) {
+						defer done.Done()
+						start.Wait()
+						errs[j] = s.Create(ctx, b)
+					}(j, b)
+				}
+				start.Done()
+				done.Wait()
+
+				created := 0
+				for j, err := range errs {
+					var conflict *ConflictError
+					switch {
+					case err == nil:
+						created++
+						if _, err := s.Purge(ctx, bookings[j].ID); err != nil {
+							t.Fatal(err)
+						}
+					case !errors.Is(err, ErrSlotTaken) && !errors.As(err, &conflict):
+						t.Fatalf("round %d: create = %v, want a slot conflict", round, err)
+					}
+				}
+				if created != 1 {
+					t.Fatalf("round %d: %d of the two bookings were created, want one (%s)", round, created, fmt.Sprint(errs))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"miniparty-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// ModifyAny is Modify for a booking that may be soft-deleted, which stays deleted.
+func (s Gorm) ModifyAny(ctx context.Context, id int64, change func(b *models.Booking) error) (models.Booking, error) {
+	var b models.Booking
+	err := s.transaction(ctx, func(tx *gorm.DB) error {
+		b = models.Booking{}
+		return modify(tx.Unscoped().Where("id = ?", id), &b, change)
+	})
+	return b, mapError(err)
+}
+
+func (s Gorm) ExpiredHolds(ctx context.Context, at time.Time) ([]models.Booking, error) {
+	var expired []models.Booking
+	err := s.retry(ctx, func(conn *gorm.DB) error {
+		expired = nil
+		return conn.Where("status = ? AND deposit_paid = ? AND hold_expires_at < ?", models.StatusPending, false, at).
+			Find(&expired).Error
+	})
+	return expired, err
+}
+
+func (s Gorm) DueReminders(ctx context.Context, from, to time.Time) ([]models.Booking, error) {
+	var due []models.Booking
+	err := s.retry(ctx, func(conn *gorm.DB) error {
+		due = nil
+		return conn.Where("status = ? AND reminded_at IS NULL AND starts_at > ? AND starts_at <= ?",
+			models.StatusConfirmed, from.UTC(), to.UTC()).
+			Order("starts_at ASC, id ASC").Find(&due).Error
+	})
+	return due, err
+}
+
+// ClaimReminder and ReleaseReminder use UpdateColumn, which leaves updated_at alone: sending
+// a reminder doesn't change the booking.
+func (s Gorm) ClaimReminder(ctx context.Context, id int64, at time.Time) (bool, error) {
+	claim := s.conn(ctx).Model(&models.Booking{}).Where("id = ? AND reminded_at IS NULL", id).UpdateColumn("reminded_at", at.UTC())
+	return claim.RowsAffected > 0, claim.Error
+}
+
+func (s Gorm) ReleaseReminder(ctx context.Context, id int64) error {
+	return s.retry(ctx, func(conn *gorm.DB) error {
+		return conn.Model(&models.Booking{}).Where("id = ?", id).UpdateColumn("reminded_at", nil).Error
+	})
+}
+
+func (s Gorm) CalendarDue(ctx context.Context, at time.Time, limit int) ([]models.Booking, error) {
+	var due []models.Booking
+	err := s.retry(ctx, func(conn *gorm.DB) error {
+		due = nil
+		return conn.Unscoped().
+			Where("calendar_event_id = '' AND status = ? AND deleted_at IS NULL AND starts_at > ?", models.StatusConfirmed, at).
+			Or("calendar_event_id <> '' AND (status <> ? OR deleted_at IS NOT NULL)", models.StatusConfirmed).
+			Or("calendar_event_id <> '' AND (calendar_synced_at IS NULL OR updated_at > calendar_synced_at)").
+			Order("id ASC").Limit(limit).Find(&due).Error
+	})
+	return due, err
+}
+
+func (s Gorm) CalendarBookings(ctx context.Context, at time.Time) ([]models.Booking, error) {
+	var bookings []models.Booking
+	err := s.retry(ctx, func(conn *gorm.DB) error {
+		bookings = nil
+		return conn.Unscoped().
+			Where("calendar_event_id <> '' OR (status = ? AND deleted_at IS NULL AND starts_at > ?)", models.StatusConfirmed, at).
+			Order("id ASC").Find(&bookings).Error
+	})
+	return bookings, err
+}
+
+// SetCalendarEvent and MarkCalendarSynced use UpdateColumns, like the reminder claims: keeping
+// the calendar in step isn't a change to the booking, and would otherwise make it look due again.
+func (s Gorm) SetCalendarEvent(ctx context.Context, id int64, from, to string, syncedAt *time.Time) (bool, error) {
+	cols := map[string]any{"calendar_event_id": to}
+	if syncedAt != nil {
+		cols["calendar_synced_at"] = *syncedAt
+	}
+	result := s.conn(ctx).Unscoped().Model(&models.Booking{}).
+		Where("id = ? AND calendar_event_id = ?", id, from).UpdateColumns(cols)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (s Gorm) MarkCalendarSynced(ctx context.Context, id int64, at time.Time) error {
+	return s.retry(ctx, func(conn *gorm.DB) error {
+		return conn.Unscoped().Model(&models.Booking{}).Where("id = ?", id).UpdateColumn("calendar_synced_at", at).Error
+	})
+}
+
+func (r Retained) scope(tx *gorm.DB) *gorm.DB {
+	tx = tx.Unscoped().Model(&models.Booking{}).Where("date < ? AND retention_hold = ?", r.Before, r.Held)
+	if r.Anonymized {
+		tx = tx.Where("email <> ?", models.Redacted)
+	}
+	return tx
+}
+
+func (s Gorm) CountRetained(ctx context.Context, r Retained) (int64, error) {
+	var n int64
+	err := s.retry(ctx, func(conn *gorm.DB) error {
+		return conn.Scopes(r.scope).Count(&n).Error
+	})
+	return n, err
+}
+
+func (s Gorm) RetainedIDs(ctx context.Context, r Retained, limit int) ([]int64, error) {
+	ids := []int64{}
+	err := s.retry(ctx, func(conn *gorm.DB) error {
+		ids = ids[:0]
+		return conn.Scopes(r.scope).Order("id").Limit(limit).Pluck("id", &ids).Error
+	})
+	return ids, err
+}
+
+func (s Gorm) ArchiveBookings(ctx context.Context, ids []int64, at time.Time) error {
+	return s.transaction(ctx, func(tx *gorm.DB) error {
+		cols, err := archiveColumns(tx)
+		if err != nil {
+			return err
+		}
+		list := strings.Join(cols, ", ")
+		err = tx.Exec("INSERT INTO bookings_archive ("+list+", archived_at) SELECT "+list+", ? FROM bookings WHERE id IN ?", at.UTC(), ids).Error
+		if err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id IN ?", ids).Delete(&models.Booking{}).Error
+	})
+}
+
+func (s Gorm) DeleteBookings(ctx context.Context, ids []int64) error {
+	return s.transaction(ctx, func(tx *gorm.DB) error {
+		if err := tx.Where("booking_id IN ?", ids).Delete(&models.BookingAddon{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("booking_id IN ?", ids).Delete(&models.Payment{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id IN ?", ids).Delete(&models.Booking{}).Error
+	})
+}
+
+// AnonymizeBookings clears the cancel token and confirmation code too: with the email gone
+// nobody could use them, and they would only tie the booking back to the customer's inbox.
+// A payment's reference may be the customer's bank transfer, with their name on it.
+func (s Gorm) AnonymizeBookings(ctx context.Context, ids []int64) error {
+	return s.transaction(ctx, func(tx *gorm.DB) error {
+		err := tx.Model(&models.Payment{}).Where("booking_id IN ? AND reference <> ''", ids).Update("reference", models.Redacted).Error
+		if err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&models.Booking{}).Where("id IN ?", ids).Updates(map[string]any{
+			"name": models.Redacted, "email": models.Redacted, "phone": models.Redacted, "notes": models.Redacted,
+			"cancel_token": "", "confirmation_code": "",
+		}).Error
+	})
+}
+
+// archiveColumns lists, quoted, the columns bookings and bookings_archive share, which is
+// all of bookings' unless a migration has added one and forgotten the archive.
+func archiveColumns(tx *gorm.DB) ([]string, error) {
+	names := func(table string) (map[string]bool, error) {
+		types, err := tx.Migrator().ColumnTypes(table)
+		if err != nil {
+			return nil, err
+		}
+		set := map[string]bool{}
+		for _, t := range types {
+			set[t.Name()] = true
+		}
+		return set, nil
+	}
+	archive, err := names("bookings_archive")
+	if err != nil {
+		return nil, err
+	}
+	types, err := tx.Migrator().ColumnTypes("bookings")
+	if err != nil {
+		return nil, err
+	}
+	var cols []string
+	for _, t := range types {
+		if archive[t.Name()] {
+			cols = append(cols, tx.Statement.Quote(t.Name()))
+		}
+	}
+	if len(cols) == 0 {
+		return nil, errors.New("bookings_archive has none of the bookings columns")
+	}
+	return cols, nil
+}
+
+func (s Gorm) RoomDays(ctx context.Context, from, to string) ([]RoomDay, error) {
+	var rows []RoomDay
+	err := s.retry(ctx, func(conn *gorm.DB) error {
+		rows = nil
+		return conn.Model(&models.Booking{}).
+			Select(`date, room_id, COUNT(*) AS bookings, SUM(duration) AS hours,
+				SUM(CASE WHEN full_day = ? THEN 1 ELSE 0 END) AS full_days`, true).
+			Where("date >= ? AND date <= ? AND status <> ?", from, to, models.StatusCancelled).
+			Group("date, room_id").
+			Scan(&rows).Error
+	})
+	return rows, err
+}
+
+func (s Gorm) SetSMSStatus(ctx context.Context, id int64, status string) error {
+	return s.retry(ctx, func(conn *gorm.DB) error {
+		return conn.Model(&models.Booking{}).Where("id = ?", id).UpdateColumn("sms_status", status).Error
+	})
+}
+
+// LockCustomer takes Postgres advisory locks, which last until the caller's transaction ends,
+// so it only makes sense inside one from WithTx. SQLite runs one write transaction at a time,
+// so it needs no lock.
+func (s Gorm) LockCustomer(ctx context.Context, email, phone string) error {
+	tx := s.conn(ctx)
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	// Email before phone, always, so two customers sharing one can't deadlock.
+	for _, key := range []string{"customer email " + strings.ToLower(email), "customer phone " + phone} {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", key).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s Gorm) MarkPastNoShows(ctx context.Context, before string) (int64, error) {
+	var marked int64
+	err := s.retry(ctx, func(conn *gorm.DB) error {
+		res := conn.Model(&models.Booking{}).
+			Where("status = ? AND checked_in_at IS NULL AND date < ?", models.StatusConfirmed, before).
+			Update("status", models.StatusNoShow)
+		marked = res.RowsAffected
+		return res.Error
+	})
+	return marked, err
+}
+
+func (s Gorm) CustomerSummary(ctx context.Context, f Filter, today string) (CustomerSummary, error) {
+	var summary CustomerSummary
+	err := s.retry(ctx, func(conn *gorm.DB) error {
+		return conn.Model(&models.Booking{}).Scopes(f.Scope).
+			Select(`COUNT(*) AS bookings,
+				COALESCE(SUM(CASE WHEN status = ? AND (checked_in_at IS NOT NULL OR date < ?) THEN COALESCE(actual_guests, guests) ELSE 0 END), 0) AS guests,
+				COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0) AS cancellations,
+				COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0) AS no_shows`,
+				models.StatusConfirmed, today, models.StatusCancelled, models.StatusNoShow).
+			Scan(&summary).Error
+	})
+	return summary, err
+}
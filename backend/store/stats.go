@@ -0,0 +1,119 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"miniparty-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// DayStats totals one day's bookings. Bookings, Guests and Hours leave out the cancelled
+// ones, which Cancelled counts. CheckedIn counts the bookings checked in at the door,
+// BookedGuests their guests as booked and ActualGuests as counted (as booked where nobody
+// counted); NoShows counts the no-shows, marked or not yet: the confirmed bookings of past
+// days that never checked in.
+type DayStats struct {
+	Date         string
+	Bookings     int64
+	Cancelled    int64
+	Guests       int64
+	Hours        int64
+	CheckedIn    int64
+	BookedGuests int64
+	ActualGuests int64
+	NoShows      int64
+}
+
+// SlotCount is how many bookings start at one time of day.
+type SlotCount struct {
+	Time     string `json:"time"`
+	Bookings int64  `json:"bookings"`
+}
+
+// SourceCount is how many bookings came from one source.
+type SourceCount struct {
+	Source   string `json:"source"`
+	Bookings int64  `json:"bookings"`
+}
+
+// OutstandingDeposits is how many bookings still owe their deposit, and how much of it, in
+// cents, they owe between them.
+type OutstandingDeposits struct {
+	Bookings    int64 `json:"bookings"`
+	AmountCents int64 `json:"amount_cents"`
+}
+
+func (s Gorm) DayStats(ctx context.Context, from, to, today string) ([]DayStats, error) {
+	var days []DayStats
+	err := s.retry(ctx, func(conn *gorm.DB) error {
+		days = nil
+		return conn.Model(&models.Booking{}).
+			Select(`date,
+				SUM(CASE WHEN status <> ? THEN 1 ELSE 0 END) AS bookings,
+				SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS cancelled,
+				SUM(CASE WHEN status <> ? THEN guests ELSE 0 END) AS guests,
+				SUM(CASE WHEN status <> ? THEN duration ELSE 0 END) AS hours,
+				SUM(CASE WHEN checked_in_at IS NOT NULL THEN 1 ELSE 0 END) AS checked_in,
+				SUM(CASE WHEN checked_in_at IS NOT NULL THEN guests ELSE 0 END) AS booked_guests,
+				SUM(CASE WHEN checked_in_at IS NOT NULL THEN COALESCE(actual_guests, guests) ELSE 0 END) AS actual_guests,
+				SUM(CASE WHEN status = ? OR (status = ? AND checked_in_at IS NULL AND date < ?) THEN 1 ELSE 0 END) AS no_shows`,
+				models.StatusCancelled, models.StatusCancelled, models.StatusCancelled, models.StatusCancelled,
+				models.StatusNoShow, models.StatusConfirmed, today).
+			Where("date >= ? AND date <= ?", from, to).
+			Group("date").Order("date ASC").
+			Scan(&days).Error
+	})
+	return days, err
+}
+
+func (s Gorm) BusiestSlots(ctx context.Context, from, to string, limit int) ([]SlotCount, error) {
+	slots := []SlotCount{}
+	err := s.retry(ctx, func(conn *gorm.DB) error {
+		slots = slots[:0]
+		return conn.Model(&models.Booking{}).
+			Select(`"time", COUNT(*) AS bookings`).
+			Where("date >= ? AND date <= ? AND status <> ?", from, to, models.StatusCancelled).
+			Group("time").Order(`bookings DESC, "time" ASC`).Limit(limit).
+			Scan(&slots).Error
+	})
+	return slots, err
+}
+
+func (s Gorm) BookingsBySource(ctx context.Context, from, to string) ([]SourceCount, error) {
+	sources := []SourceCount{}
+	err := s.retry(ctx, func(conn *gorm.DB) error {
+		sources = sources[:0]
+		return conn.Model(&models.Booking{}).
+			Select("source, COUNT(*) AS bookings").
+			Where("date >= ? AND date <= ? AND status <> ?", from, to, models.StatusCancelled).
+			Group("source").Order("bookings DESC, source ASC").
+			Scan(&sources).Error
+	})
+	return sources, err
+}
+
+func (s Gorm) OutstandingDeposits(ctx context.Context, from string) (OutstandingDeposits, error) {
+	var deposits OutstandingDeposits
+	err := s.retry(ctx, func(conn *gorm.DB) error {
+		return conn.Model(&models.Booking{}).
+			Select(`COUNT(*) AS bookings,
+				COALESCE(SUM(deposit_due_cents - amount_paid_cents), 0) AS amount_cents`).
+			Where("date >= ? AND status IN ? AND deposit_due_cents > amount_paid_cents",
+				from, []string{models.StatusPending, models.StatusConfirmed}).
+			Scan(&deposits).Error
+	})
+	return deposits, err
+}
+
+func (s Gorm) CreatedBetween(ctx context.Context, from, to time.Time) ([]time.Time, error) {
+	var made []time.Time
+	err := s.retry(ctx, func(conn *gorm.DB) error {
+		made = nil
+		return conn.Model(&models.Booking{}).
+			Where("created_at >= ? AND created_at < ?", from, to).
+			Pluck("created_at", &made).Error
+	})
+	return made, err
+}
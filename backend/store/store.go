@@ -0,0 +1,222 @@
+// Package store persists bookings. Handlers depend on the BookingStore interface
+// rather than on the database directly.
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"miniparty-backend/models"
+)
+
+var (
+	// ErrNotFound is returned when no booking has the requested ID.
+	ErrNotFound = errors.New("booking not found")
+
+	// ErrSlotTaken is returned when the database's slot index rejects a second booking for the same start time.
+	ErrSlotTaken = errors.New("slot already taken")
+
+	// ErrKeyInFlight is returned when another request holding the same idempotency key hasn't finished.
+	ErrKeyInFlight = errors.New("idempotency key in use")
+
+	// ErrKeyMismatch is returned when an idempotency key is reused with a different request body.
+	ErrKeyMismatch = errors.New("idempotency key reused with a different request")
+)
+
+// ConflictError is returned when a booking overlaps other active bookings.
+type ConflictError struct {
+	Conflicts []models.Booking
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("booking overlaps %d existing booking(s)", len(e.Conflicts))
+}
+
+// Filter narrows a booking list. Empty fields match everything.
+type Filter struct {
+	// Query is a lowercased substring matched against name and email.
+	Query string
+	// PhoneQuery is matched against the phone number; usually Query reduced to its digits.
+	PhoneQuery string
+	// Reference, when Query reads as a booking reference, also matches that booking exactly.
+	// Without Query it limits the list to that booking.
+	Reference string
+	Status    string
+	// Statuses limits the list to bookings in any of these states.
+	Statuses []string
+	// Active drops cancelled bookings.
+	Active bool
+	// IDs limits the list to these bookings.
+	IDs       []int64
+	RoomID    uint
+	PackageID uint
+	// SeriesID limits the list to one recurring series.
+	SeriesID string
+	// Email limits the list to one customer's bookings, matched ignoring case, along with
+	// those made with Phone when that is set too.
+	Email, Phone string
+	// CheckedIn, when set, keeps only the bookings that have (true) or haven't (false) checked in.
+	CheckedIn *bool
+	// Flagged, when set, keeps only the bookings that are (true) or aren't (false) flagged
+	// for their customer's no-shows.
+	Flagged *bool
+	// PaymentStatus, when set, keeps only the bookings in that models.Payment* state.
+	PaymentStatus string
+	// PaymentIntentID limits the list to the booking whose deposit is that online payment.
+	PaymentIntentID string
+	From, To        string // inclusive ISO dates
+	// Time limits the list to bookings starting at this HH:MM.
+	Time string
+}
+
+// Sort orders a booking list by Field, one of SortFields, then by ID in the same direction
+// so that ties don't shuffle between pages. The zero Sort is by date and time, oldest first.
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// SortFields are the fields a booking list can be sorted by.
+var SortFields = []string{"date", "created_at", "guests", "name"}
+
+// ListOptions selects a page of a filtered, sorted list.
+type ListOptions struct {
+	Filter
+	Sort          Sort
+	Offset, Limit int
+	// IncludeDeleted lists soft-deleted bookings alongside live ones.
+	IncludeDeleted bool
+}
+
+// BookingStore reads and writes bookings.
+type BookingStore interface {
+	// Create inserts b, assigning its ID. It fails with *ConflictError or ErrSlotTaken
+	// if b overlaps an active booking.
+	Create(ctx context.Context, b *models.Booking) error
+	GetByID(ctx context.Context, id int64) (models.Booking, error)
+	// List returns one page of matching bookings and the total number of matches.
+	List(ctx context.Context, opts ListOptions) ([]models.Booking, int64, error)
+	// Count returns the number of matching bookings, ignoring the page.
+	Count(ctx context.Context, opts ListOptions) (int64, error)
+	// Each calls fn with every matching booking in order, reading them one at a time rather
+	// than all at once. It stops at fn's first error and returns it.
+	Each(ctx context.Context, opts ListOptions, fn func(models.Booking) error) error
+	// Update saves all of b's fields, with the same overlap checks as Create.
+	Update(ctx context.Context, b *models.Booking) error
+	// Modify reads a booking, holding it against concurrent changes, and saves what change
+	// does to it. It is for changes that leave the booking where it is, such as its status:
+	// overlaps aren't checked again, though the slot is, when a cancelled booking comes back.
+	// Nothing is written if change leaves the booking as it was. If change fails, Modify
+	// returns its error along with the booking as it was read.
+	Modify(ctx context.Context, id int64, change func(b *models.Booking) error) (models.Booking, error)
+	// Delete soft-deletes a booking, freeing its slot. Restore undoes it, failing like Create
+	// if the slot has been taken since.
+	Delete(ctx context.Context, id int64) error
+	Restore(ctx context.Context, id int64) (models.Booking, error)
+	// Purge removes a booking, soft-deleted or not, for good and returns what was removed.
+	Purge(ctx context.Context, id int64) (models.Booking, error)
+}
+
+// BookingQueries are the narrower reads and writes the handlers' jobs and reports make on
+// bookings, kept beside BookingStore so that SQL on the bookings table stays in this package.
+type BookingQueries interface {
+	// ModifyAny is Modify for a booking that may be soft-deleted; it stays deleted.
+	ModifyAny(ctx context.Context, id int64, change func(b *models.Booking) error) (models.Booking, error)
+	// ExpiredHolds returns the pending, unpaid bookings whose deposit hold ran out before at.
+	ExpiredHolds(ctx context.Context, at time.Time) ([]models.Booking, error)
+	// DueReminders returns the confirmed bookings not yet reminded that start after from
+	// and no later than to, soonest first.
+	DueReminders(ctx context.Context, from, to time.Time) ([]models.Booking, error)
+	// ClaimReminder marks a booking reminded at at, reporting false if it already was.
+	// ReleaseReminder undoes it, so the reminder is tried again.
+	ClaimReminder(ctx context.Context, id int64, at time.Time) (bool, error)
+	ReleaseReminder(ctx context.Context, id int64) error
+	// CalendarDue returns up to limit bookings, soft-deleted ones included, whose calendar
+	// event is out of date as of at: an upcoming confirmed booking without one, a cancelled
+	// or deleted booking that still has one, and one changed since its event was written.
+	CalendarDue(ctx context.Context, at time.Time, limit int) ([]models.Booking, error)
+	// CalendarBookings returns the bookings with a calendar event and the upcoming confirmed
+	// ones that should have one, by ID.
+	CalendarBookings(ctx context.Context, at time.Time) ([]models.Booking, error)
+	// SetCalendarEvent replaces a booking's calendar event ID from with to, and its sync time
+	// with syncedAt unless that is nil. It reports false, changing nothing, if the booking's
+	// event is no longer from. MarkCalendarSynced records that its event was written at at.
+	SetCalendarEvent(ctx context.Context, id int64, from, to string, syncedAt *time.Time) (bool, error)
+	MarkCalendarSynced(ctx context.Context, id int64, at time.Time) error
+	// CountRetained and RetainedIDs count and list, by ID, the bookings r selects.
+	CountRetained(ctx context.Context, r Retained) (int64, error)
+	RetainedIDs(ctx context.Context, r Retained, limit int) ([]int64, error)
+	// DeleteBookings removes bookings for good, with their add-ons and payments.
+	// AnonymizeBookings redacts their customer's details, payment references included.
+	DeleteBookings(ctx context.Context, ids []int64) error
+	AnonymizeBookings(ctx context.Context, ids []int64) error
+	// ArchiveBookings copies bookings to bookings_archive, stamped at, and removes them.
+	ArchiveBookings(ctx context.Context, ids []int64, at time.Time) error
+	// RoomDays totals the active bookings per room and day between the ISO dates from and to.
+	RoomDays(ctx context.Context, from, to string) ([]RoomDay, error)
+	// SetSMSStatus records how the last text about a booking went.
+	SetSMSStatus(ctx context.Context, id int64, status string) error
+	// LockCustomer makes anyone else checking the same customer's bookings, by email or
+	// phone, wait until the transaction from WithTx in ctx ends.
+	LockCustomer(ctx context.Context, email, phone string) error
+	// CustomerSummary totals the bookings f matches, as of the ISO date today.
+	CustomerSummary(ctx context.Context, f Filter, today string) (CustomerSummary, error)
+	// MarkPastNoShows marks the confirmed bookings dated before the ISO date before that
+	// never checked in as no-shows, and returns how many it marked.
+	MarkPastNoShows(ctx context.Context, before string) (int64, error)
+	// DayStats totals the bookings from one ISO date to another, inclusive, per day, leaving
+	// out days without any. today decides which unchecked-in bookings count as no-shows.
+	DayStats(ctx context.Context, from, to, today string) ([]DayStats, error)
+	// BusiestSlots counts the active bookings between two dates by start time, busiest
+	// first, and returns up to limit of them. BookingsBySource counts them by source.
+	BusiestSlots(ctx context.Context, from, to string, limit int) ([]SlotCount, error)
+	BookingsBySource(ctx context.Context, from, to string) ([]SourceCount, error)
+	// OutstandingDeposits totals the deposits still owed by the pending and confirmed
+	// bookings dated from onwards.
+	OutstandingDeposits(ctx context.Context, from string) (OutstandingDeposits, error)
+	// CreatedBetween returns when each booking made from from up to to was made.
+	CreatedBetween(ctx context.Context, from, to time.Time) ([]time.Time, error)
+}
+
+// Retained selects the bookings, soft-deleted ones included, dated before Before that the
+// retention job hasn't dealt with yet; Held picks the ones on hold instead. Anonymized
+// leaves out those already anonymized.
+type Retained struct {
+	Before     string
+	Held       bool
+	Anonymized bool
+}
+
+// CustomerSummary totals a customer's bookings for the header of their history. Guests
+// counts those hosted: the confirmed parties that have checked in or whose day has passed,
+// as counted at the door where they were.
+type CustomerSummary struct {
+	Bookings      int64 `json:"bookings"`
+	Guests        int64 `json:"guests"`
+	Cancellations int64 `json:"cancellations"`
+	NoShows       int64 `json:"no_shows"`
+}
+
+// RoomDay totals the active bookings in one room on one day.
+type RoomDay struct {
+	Date     string
+	RoomID   *uint
+	Bookings int64
+	Hours    int64
+	FullDays int64
+}
+
+// IdempotencyStore remembers responses by Idempotency-Key.
+type IdempotencyStore interface {
+	// ClaimKey reserves key for a request whose body hashes to requestHash. If the key has
+	// already completed it returns the stored record instead; it fails with ErrKeyInFlight
+	// while another request holds the key and ErrKeyMismatch if the hash differs.
+	// Expired keys are purged along the way.
+	ClaimKey(ctx context.Context, key, requestHash string, now time.Time) (*models.IdempotencyKey, error)
+	// CompleteKey stores the response for a claimed key.
+	CompleteKey(ctx context.Context, key string, status int, response []byte) error
+	// ReleaseKey drops a claim whose request failed, so the client can retry with the same key.
+	ReleaseKey(ctx context.Context, key string) error
+}
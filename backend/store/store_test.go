@@ -0,0 +1,286 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"miniparty-backend/db/dbtest"
+	"miniparty-backend/models"
+)
+
+// stores runs test against each BookingStore, Gorm on a fresh SQLite database, so that
+// Memory keeps behaving like the real thing.
+func stores(t *testing.T, test func(t *testing.T, s BookingStore)) {
+	t.Run("gorm", func(t *testing.T) {
+		dbtest.Init(t)
+		test(t, Gorm{})
+	})
+	t.Run("memory", func(t *testing.T) { test(t, NewMemory()) })
+}
+
+var bookingCount int
+
+// booking is a pending party in room 1 on date at clock for hours, with unique codes.
+func booking(date, clock string, hours int) *models.Booking {
+	bookingCount++
+	room := uint(1)
+	start, _ := time.Parse("2006-01-02 15:04", date+" "+clock)
+	return &models.Booking{
+		Name: fmt.Sprintf("Customer %d", bookingCount), Email: fmt.Sprintf("c%d@example.com", bookingCount),
+		Phone: "+14155550100", Date: date, Time: clock, Duration: hours, Guests: 4,
+		Status: models.StatusPending, RoomID: &room, StartsAt: &start,
+		Reference: fmt.Sprintf("MP-S%05d", bookingCount), CancelToken: fmt.Sprint("token", bookingCount),
+		SlotKey: models.SlotKey(date, clock, &room),
+	}
+}
+
+func TestStoreCreateConflicts(t *testing.T) {
+	stores(t, func(t *testing.T, s BookingStore) {
+		ctx := context.Background()
+		first := booking("2026-07-10", "14:00", 2)
+		if err := s.Create(ctx, first); err != nil || first.ID == 0 {
+			t.Fatalf("create = %v, id %d", err, first.ID)
+		}
+
+		if err := s.Create(ctx, booking("2026-07-10", "14:00", 1)); !errors.Is(err, ErrSlotTaken) {
+			t.Errorf("same start: err = %v, want ErrSlotTaken", err)
+		}
+		var conflict *ConflictError
+		if err := s.Create(ctx, booking("2026-07-10", "15:00", 2)); !errors.As(err, &conflict) || len(conflict.Conflicts) != 1 || conflict.Conflicts[0].ID != first.ID {
+			t.Errorf("overlap: err = %v, want a conflict with booking %d", err, first.ID)
+		}
+		if err := s.Create(ctx, booking("2026-07-10", "16:00", 2)); err != nil {
+			t.Errorf("booking starting as the other ends: err = %v", err)
+		}
+		other := booking("2026-07-10", "15:00", 2)
+		room := uint(2)
+		other.RoomID = &room
+		if err := s.Create(ctx, other); err != nil {
+			t.Errorf("same time in another room: err = %v", err)
+		}
+		fullDay := booking("2026-07-10", "09:00", 1)
+		fullDay.FullDay, fullDay.RoomID, fullDay.SlotKey = true, nil, nil
+		if err := s.Create(ctx, fullDay); !errors.As(err, &conflict) || len(conflict.Conflicts) != 3 {
+			t.Errorf("full day: err = %v, want a conflict with all three bookings", err)
+		}
+	})
+}
+
+func TestStoreUpdateDeleteRestore(t *testing.T) {
+	stores(t, func(t *testing.T, s BookingStore) {
+		ctx := context.Background()
+		a, b := booking("2026-07-11", "12:00", 2), booking("2026-07-11", "15:00", 2)
+		for _, x := range []*models.Booking{a, b} {
+			if err := s.Create(ctx, x); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		moved := b.StartsAt.Add(-2 * time.Hour)
+		b.Time, b.StartsAt = "13:00", &moved
+		var conflict *ConflictError
+		if err := s.Update(ctx, b); !errors.As(err, &conflict) {
+			t.Errorf("update onto another booking: err = %v, want a conflict", err)
+		}
+		moved = moved.Add(2 * time.Hour)
+		b.Time, b.StartsAt, b.Guests = "15:00", &moved, 9
+		if err := s.Update(ctx, b); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		if got, err := s.GetByID(ctx, b.ID); err != nil || got.Guests != 9 {
+			t.Errorf("get after update = %+v, %v", got, err)
+		}
+
+		if err := s.Delete(ctx, a.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.GetByID(ctx, a.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("get deleted: err = %v, want ErrNotFound", err)
+		}
+		if err := s.Delete(ctx, a.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("delete twice: err = %v, want ErrNotFound", err)
+		}
+		taker := booking("2026-07-11", "12:00", 2)
+		if err := s.Create(ctx, taker); err != nil {
+			t.Fatalf("rebook a deleted slot: %v", err)
+		}
+		if _, err := s.Restore(ctx, a.ID); err == nil {
+			t.Error("restore into a taken slot succeeded")
+		}
+		if _, err := s.Purge(ctx, taker.ID); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := s.Restore(ctx, a.ID); err != nil || got.ID != a.ID {
+			t.Errorf("restore = %+v, %v", got, err)
+		}
+		if _, err := s.Purge(ctx, 9999); !errors.Is(err, ErrNotFound) {
+			t.Errorf("purge missing: err = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestStoreList(t *testing.T) {
+	stores(t, func(t *testing.T, s BookingStore) {
+		ctx := context.Background()
+		var ids []int64
+		for i, date := range []string{"2026-07-14", "2026-07-12", "2026-07-13"} {
+			b := booking(date, "10:00", 1)
+			b.Guests = 10 + i
+			if i == 1 {
+				b.Status = models.StatusConfirmed
+				b.Name = "Alice Example"
+			}
+			if err := s.Create(ctx, b); err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, b.ID)
+		}
+
+		list := func(opts ListOptions) ([]int64, int64) {
+			t.Helper()
+			if opts.Limit == 0 {
+				opts.Limit = 50
+			}
+			page, total, err := s.List(ctx, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []int64{}
+			for _, b := range page {
+				got = append(got, b.ID)
+			}
+			return got, total
+		}
+		check := func(name string, opts ListOptions, want []int64, wantTotal int64) {
+			t.Helper()
+			got, total := list(opts)
+			if fmt.Sprint(got) != fmt.Sprint(want) || total != wantTotal {
+				t.Errorf("%s: got %v of %d, want %v of %d", name, got, total, want, wantTotal)
+			}
+		}
+
+		check("by date", ListOptions{}, []int64{ids[1], ids[2], ids[0]}, 3)
+		check("guests desc", ListOptions{Sort: Sort{Field: "guests", Desc: true}}, []int64{ids[2], ids[1], ids[0]}, 3)
+		check("page", ListOptions{Offset: 1, Limit: 1}, []int64{ids[2]}, 3)
+		check("status", ListOptions{Filter: Filter{Status: models.StatusConfirmed}}, []int64{ids[1]}, 1)
+		check("query", ListOptions{Filter: Filter{Query: "alice"}}, []int64{ids[1]}, 1)
+		check("range", ListOptions{Filter: Filter{From: "2026-07-13", To: "2026-07-13"}}, []int64{ids[2]}, 1)
+		check("statuses", ListOptions{Filter: Filter{Statuses: []string{models.StatusPending, models.StatusCancelled}}}, []int64{ids[2], ids[0]}, 2)
+		check("ids", ListOptions{Filter: Filter{IDs: []int64{ids[0], ids[1]}}}, []int64{ids[1], ids[0]}, 2)
+		check("no ids", ListOptions{Filter: Filter{IDs: []int64{}}}, []int64{}, 0)
+		check("reference", ListOptions{Filter: Filter{Reference: fmt.Sprintf("MP-S%05d", bookingCount)}}, []int64{ids[2]}, 1)
+		check("time", ListOptions{Filter: Filter{Time: "11:00"}}, []int64{}, 0)
+
+		if n, err := s.Count(ctx, ListOptions{Filter: Filter{Status: models.StatusPending}, Limit: 1}); err != nil || n != 2 {
+			t.Errorf("count = %d, %v, want 2", n, err)
+		}
+		var each []int64
+		err := s.Each(ctx, ListOptions{Sort: Sort{Field: "guests"}}, func(b models.Booking) error {
+			each = append(each, b.ID)
+			return nil
+		})
+		if want := []int64{ids[0], ids[1], ids[2]}; err != nil || fmt.Sprint(each) != fmt.Sprint(want) {
+			t.Errorf("each = %v, %v, want %v", each, err, want)
+		}
+		stop := errors.New("stop")
+		if err := s.Each(ctx, ListOptions{}, func(models.Booking) error { return stop }); err != stop {
+			t.Errorf("each stopped = %v, want fn's error", err)
+		}
+
+		if _, err := s.Modify(ctx, ids[2], func(b *models.Booking) error {
+			b.Status = models.StatusCancelled
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		check("active", ListOptions{Filter: Filter{Active: true}}, []int64{ids[1], ids[0]}, 2)
+
+		if err := s.Delete(ctx, ids[0]); err != nil {
+			t.Fatal(err)
+		}
+		check("without deleted", ListOptions{}, []int64{ids[1], ids[2]}, 2)
+		check("with deleted", ListOptions{IncludeDeleted: true}, []int64{ids[1], ids[2], ids[0]}, 3)
+	})
+}
+
+func TestStoreModify(t *testing.T) {
+	stores(t, func(t *testing.T, s BookingStore) {
+		ctx := context.Background()
+		b := booking("2026-07-16", "10:00", 2)
+		if err := s.Create(ctx, b); err != nil {
+			t.Fatal(err)
+		}
+
+		cancelled, err := s.Modify(ctx, b.ID, func(b *models.Booking) error {
+			b.Status = models.StatusCancelled
+			return nil
+		})
+		if err != nil || cancelled.Status != models.StatusCancelled {
+			t.Fatalf("cancel = %v, status %q", err, cancelled.Status)
+		}
+		// The cancelled booking's slot is free again.
+		taker := booking("2026-07-16", "10:00", 1)
+		if err := s.Create(ctx, taker); err != nil {
+			t.Fatalf("rebook freed slot: %v", err)
+		}
+		if _, err := s.Modify(ctx, b.ID, func(b *models.Booking) error {
+			b.Status = models.StatusConfirmed
+			return nil
+		}); !errors.Is(err, ErrSlotTaken) {
+			t.Errorf("confirm into a taken slot: err = %v, want ErrSlotTaken", err)
+		}
+
+		refused := errors.New("refused")
+		got, err := s.Modify(ctx, b.ID, func(b *models.Booking) error {
+			b.Guests = 99
+			return refused
+		})
+		if err != refused || got.Guests != 4 {
+			t.Errorf("failed change = %v, guests %d, want its error and the booking as read", err, got.Guests)
+		}
+		if stored, _ := s.GetByID(ctx, b.ID); stored.Guests != 4 || stored.Status != models.StatusCancelled {
+			t.Errorf("after failed changes: guests %d, status %q, want them untouched", stored.Guests, stored.Status)
+		}
+
+		if err := s.Delete(ctx, b.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Modify(ctx, b.ID, func(*models.Booking) error { return nil }); !errors.Is(err, ErrNotFound) {
+			t.Errorf("modify deleted: err = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestStoreTimestamps(t *testing.T) {
+	stores(t, func(t *testing.T, s BookingStore) {
+		ctx := context.Background()
+		before := time.Now().UTC().Add(-time.Second)
+		b := booking("2026-07-15", "10:00", 2)
+		if err := s.Create(ctx, b); err != nil {
+			t.Fatal(err)
+		}
+		created, err := s.GetByID(ctx, b.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if created.CreatedAt.Before(before) || created.UpdatedAt.Before(created.CreatedAt) || created.CreatedAt.Location() != time.UTC {
+			t.Errorf("after create: created_at %v, updated_at %v, want both now in UTC", created.CreatedAt, created.UpdatedAt)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		created.Guests = 6
+		if err := s.Update(ctx, &created); err != nil {
+			t.Fatal(err)
+		}
+		updated, err := s.GetByID(ctx, b.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !updated.CreatedAt.Equal(created.CreatedAt) || !updated.UpdatedAt.After(created.CreatedAt) {
+			t.Errorf("after update: created_at %v, updated_at %v, want created_at kept and updated_at later", updated.CreatedAt, updated.UpdatedAt)
+		}
+	})
+}
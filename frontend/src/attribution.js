@@ -0,0 +1,27 @@
+// Where the visitor came from, remembered from the page they landed on so the booking form
+// can send it even after they have clicked through the site.
+const KEY = 'attribution'
+const PARAMS = ['utm_source', 'utm_medium', 'utm_campaign']
+
+export function rememberAttribution() {
+  if (sessionStorage.getItem(KEY)) return
+  const query = new URLSearchParams(window.location.search)
+  const found = {}
+  for (const p of PARAMS) {
+    if (query.get(p)) found[p] = query.get(p)
+  }
+  if (query.get('source')) found.source = query.get('source')
+  if (!found.source && !found.utm_source && document.referrer) {
+    const host = new URL(document.referrer).hostname
+    if (host !== window.location.hostname) found.source = host.replace(/^www\./, '')
+  }
+  sessionStorage.setItem(KEY, JSON.stringify(found))
+}
+
+export function attribution() {
+  try {
+    return JSON.parse(sessionStorage.getItem(KEY)) || {}
+  } catch {
+    return {}
+  }
+}
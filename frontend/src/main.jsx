@@ -3,6 +3,9 @@ import { createRoot } from 'react-dom/client'
 import { BrowserRouter } from 'react-router-dom'
 import './index.css'
 import App from './App.jsx'
+import { rememberAttribution } from './attribution.js'
+
+rememberAttribution()
 
 createRoot(document.getElementById('root')).render(
   <StrictMode>
@@ -18,7 +18,8 @@ const EyeOffIcon = () => (
 
 export default function AdminDashboard() {
   const [token, setToken] = useState('')
-  const [showToken, setShowToken] = useState(false)
+  const [password, setPassword] = useState('')
+  const [showPassword, setShowPassword] = useState(false)
   const [authenticated, setAuthenticated] = useState(false)
   const [bookings, setBookings] = useState([])
   const [loading, setLoading] = useState(false)
@@ -38,14 +39,14 @@ export default function AdminDashboard() {
     setLoading(true)
 
     try {
-      const res = await fetch(`${import.meta.env.VITE_API_URL}/bookings`, {
-        headers: { 'X-Admin-Token': adminToken },
+      const res = await fetch(`${import.meta.env.VITE_API_URL}/api/v1/bookings`, {
+        headers: { Authorization: `Bearer ${adminToken}` },
       })
 
       if (res.status === 401) {
         sessionStorage.removeItem('adminToken')
         setAuthenticated(false)
-        setError('Invalid token. Access denied.')
+        setError('Your session has expired. Please sign in again.')
         setLoading(false)
         return
       }
@@ -57,7 +58,7 @@ export default function AdminDashboard() {
       }
 
       const data = await res.json()
-      setBookings(data || [])
+      setBookings(data.bookings || [])
       setAuthenticated(true)
       sessionStorage.setItem('adminToken', adminToken)
     } catch {
@@ -67,9 +68,32 @@ export default function AdminDashboard() {
     }
   }
 
-  const handleLogin = (e) => {
+  const handleLogin = async (e) => {
     e.preventDefault()
-    fetchBookings(token)
+    setError('')
+    setLoading(true)
+
+    try {
+      const res = await fetch(`${import.meta.env.VITE_API_URL}/api/v1/admin/login`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ password }),
+      })
+      const data = await res.json()
+
+      if (!res.ok) {
+        setError(data.message || data.error || 'Login failed.')
+        setLoading(false)
+        return
+      }
+
+      setPassword('')
+      setToken(data.token)
+      fetchBookings(data.token)
+    } catch {
+      setError('Could not connect to server.')
+      setLoading(false)
+    }
   }
 
   const handleLogout = () => {
@@ -77,20 +101,21 @@ export default function AdminDashboard() {
     setAuthenticated(false)
     setBookings([])
     setToken('')
+    setPassword('')
   }
 
   const handleDelete = async (id, name) => {
     if (!window.confirm(`Are you sure you want to delete the booking for "${name}"?`)) return
 
     try {
-      const res = await fetch(`${import.meta.env.VITE_API_URL}/bookings/${id}`, {
+      const res = await fetch(`${import.meta.env.VITE_API_URL}/api/v1/bookings/${id}`, {
         method: 'DELETE',
-        headers: { 'X-Admin-Token': token },
+        headers: { Authorization: `Bearer ${token}` },
       })
 
       if (!res.ok) {
         const data = await res.json()
-        alert(data.error || 'Failed to delete booking.')
+        alert(data.message || data.error || 'Failed to delete booking.')
         return
       }
 
@@ -115,7 +140,7 @@ export default function AdminDashboard() {
               </svg>
             </div>
             <h1 className="text-2xl font-bold text-gray-900">Admin Access</h1>
-            <p className="text-gray-500 text-sm mt-1">Enter your secret token to continue</p>
+            <p className="text-gray-500 text-sm mt-1">Enter the admin password to continue</p>
           </div>
 
           {error && (
@@ -126,27 +151,27 @@ export default function AdminDashboard() {
 
           <form onSubmit={handleLogin} className="space-y-4">
             <div>
-              <label htmlFor="token" className="block text-sm font-medium text-gray-700 mb-1">
-                Secret Token
+              <label htmlFor="password" className="block text-sm font-medium text-gray-700 mb-1">
+                Password
               </label>
               <div className="relative">
                 <input
-                  id="token"
-                  type={showToken ? 'text' : 'password'}
-                  value={token}
-                  onChange={(e) => setToken(e.target.value)}
+                  id="password"
+                  type={showPassword ? 'text' : 'password'}
+                  value={password}
+                  onChange={(e) => setPassword(e.target.value)}
                   required
-                  autoComplete="off"
-                  placeholder="Enter admin token"
+                  autoComplete="current-password"
+                  placeholder="Enter admin password"
                   className="w-full border border-gray-300 rounded-lg px-4 py-2.5 pr-11 focus:ring-2 focus:ring-purple-500 focus:border-transparent outline-none"
                 />
                 <button
                   type="button"
-                  onClick={() => setShowToken(!showToken)}
+                  onClick={() => setShowPassword(!showPassword)}
                   className="absolute right-3 top-1/2 -translate-y-1/2 text-gray-400 hover:text-gray-600 transition"
-                  aria-label={showToken ? 'Hide token' : 'Show token'}
+                  aria-label={showPassword ? 'Hide password' : 'Show password'}
                 >
-                  {showToken ? <EyeOffIcon /> : <EyeIcon />}
+                  {showPassword ? <EyeOffIcon /> : <EyeIcon />}
                 </button>
               </div>
             </div>
@@ -223,13 +248,25 @@ export default function AdminDashboard() {
                       i % 2 === 0 ? 'bg-white' : 'bg-purple-50'
                     } hover:bg-purple-100 transition`}
                   >
-                    <td className="px-4 py-3 font-semibold text-gray-900 whitespace-nowrap">{b.name}</td>
+                    <td className="px-4 py-3 font-semibold text-gray-900 whitespace-nowrap">
+                      {b.name}
+                      {b.series_id && (
+                        <span
+                          className="ml-2 px-2 py-0.5 text-xs font-medium text-purple-700 bg-purple-100 rounded-full"
+                          title={`Recurring series ${b.series_id}`}
+                        >
+                          Weekly
+                        </span>
+                      )}
+                    </td>
                     <td className="px-4 py-3 text-gray-900 font-medium whitespace-nowrap">{b.date}</td>
                     <td className="px-4 py-3 text-gray-900 font-medium">{b.time}</td>
                     <td className="px-4 py-3 text-purple-700 font-bold text-center">{b.guests}</td>
                     <td className="px-4 py-3 text-gray-700 hidden sm:table-cell">{b.email}</td>
                     <td className="px-4 py-3 text-gray-700 hidden sm:table-cell">{b.phone}</td>
-                    <td className="px-4 py-3 text-gray-700 hidden sm:table-cell">{b.duration}h</td>
+                    <td className="px-4 py-3 text-gray-700 hidden sm:table-cell">
+                      {b.full_day ? 'Full day' : `${b.duration}h`}
+                    </td>
                     <td className="px-4 py-3 text-center">
                       <button
                         onClick={() => handleDelete(b.id, b.name)}
@@ -1,5 +1,6 @@
 import { useState } from 'react'
 import { useNavigate } from 'react-router-dom'
+import { attribution } from '../attribution.js'
 
 export default function BookingForm() {
   const navigate = useNavigate()
@@ -11,6 +12,7 @@ export default function BookingForm() {
     time: '',
     duration: 2,
     guests: 10,
+    website: '',
   })
   const [errors, setErrors] = useState([])
   const [submitting, setSubmitting] = useState(false)
@@ -26,10 +28,11 @@ export default function BookingForm() {
     setSubmitting(true)
 
     try {
-      const res = await fetch(`${import.meta.env.VITE_API_URL}/book`, {
+      const res = await fetch(`${import.meta.env.VITE_API_URL}/api/v1/book`, {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({
+          ...attribution(),
           ...form,
           duration: Number(form.duration),
           guests: Number(form.guests),
@@ -39,7 +42,7 @@ export default function BookingForm() {
       const data = await res.json()
 
       if (!res.ok) {
-        setErrors(data.errors || [data.error || 'Something went wrong'])
+        setErrors(data.fields ? Object.values(data.fields).flat() : [data.message || data.error || 'Something went wrong'])
         return
       }
 
@@ -73,6 +76,11 @@ export default function BookingForm() {
       )}
 
       <form onSubmit={handleSubmit} className="bg-white rounded-2xl shadow-sm p-8 space-y-6">
+        {/* Honeypot: hidden from people, so only bots fill it in */}
+        <div aria-hidden="true" className="absolute -left-[9999px] h-0 w-0 overflow-hidden">
+          <label htmlFor="website">Website</label>
+          <input id="website" name="website" type="text" tabIndex={-1} autoComplete="off" value={form.website} onChange={handleChange} />
+        </div>
         {/* Contact Info */}
         <div className="grid md:grid-cols-2 gap-4">
           <Field label="Full Name" name="name" value={form.name} onChange={handleChange} placeholder="John Doe" />
@@ -13,7 +13,7 @@ export default function Confirmation() {
         <div className="text-6xl mb-6">🎉</div>
         <h1 className="text-3xl font-bold text-gray-900 mb-2">Booking Confirmed!</h1>
         <p className="text-gray-600 mb-8">
-          Thanks, {booking.name}! Your party is booked. Here are the details:
+          Thanks, {booking.name}! We've received your booking and will call you to confirm. Here are the details:
         </p>
 
         <div className="bg-purple-50 rounded-xl p-6 text-left space-y-3 mb-4">
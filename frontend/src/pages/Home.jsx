@@ -10,7 +10,7 @@ export default function Home() {
     const maxAttempts = 6 // retry up to 6 times (covers ~90s of cold start)
 
     const ping = () => {
-      fetch(`${import.meta.env.VITE_API_URL}/health`)
+      fetch(`${import.meta.env.VITE_API_URL}/api/v1/health`)
         .then((res) => {
           if (cancelled) return
           if (res.ok) setBackendStatus('ready')